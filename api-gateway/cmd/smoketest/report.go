@@ -0,0 +1,122 @@
+package main
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Outcomes of a step
+const (
+	statusPassed  = "passed"
+	statusFailed  = "failed"
+	statusSkipped = "skipped"
+)
+
+// stepResult is the outcome of one step of the scenario
+type stepResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"` // passed, failed or skipped
+	Error      string `json:"error,omitempty"`
+	Detail     string `json:"detail,omitempty"` // e.g. the repair ID a step created
+	DurationMs int64  `json:"durationMs"`
+}
+
+// report is the result of a smoke test run
+type report struct {
+	Target     string       `json:"target"`
+	OK         bool         `json:"ok"`
+	StartedAt  time.Time    `json:"startedAt"`
+	DurationMs int64        `json:"durationMs"`
+	RepairID   string       `json:"repairID,omitempty"`
+	Steps      []stepResult `json:"steps"`
+}
+
+func (r *report) count(status string) int {
+	n := 0
+	for _, step := range r.Steps {
+		if step.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+func writeJSON(w io.Writer, r *report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// JUnit XML as read by CI servers: one test suite with a test case per step
+type junitSuites struct {
+	XMLName xml.Name     `xml:"testsuites"`
+	Suites  []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr"`
+	Props     []junitProperty `xml:"properties>property"`
+	Cases     []junitCase     `xml:"testcase"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+}
+
+func seconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
+
+func writeJUnit(w io.Writer, r *report) error {
+	suite := junitSuite{
+		Name:      "smoketest",
+		Tests:     len(r.Steps),
+		Failures:  r.count(statusFailed),
+		Skipped:   r.count(statusSkipped),
+		Time:      seconds(r.DurationMs),
+		Timestamp: r.StartedAt.UTC().Format(time.RFC3339),
+		Props:     []junitProperty{{Name: "target", Value: r.Target}, {Name: "repairID", Value: r.RepairID}},
+	}
+	for _, step := range r.Steps {
+		c := junitCase{Name: step.Name, Classname: "smoketest", Time: seconds(step.DurationMs), SystemOut: step.Detail}
+		switch step.Status {
+		case statusFailed:
+			c.Failure = &junitMessage{Message: step.Error}
+		case statusSkipped:
+			c.Skipped = &junitMessage{Message: step.Error}
+		}
+		suite.Cases = append(suite.Cases, c)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitSuites{Suites: []junitSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,426 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Caller roles, as RBAC names them
+const (
+	roleUser     = "user"
+	roleMechanic = "mechanic"
+	roleAdmin    = "admin"
+)
+
+// pollInterval is how often the asynchronous effects of a step are checked
+const pollInterval = 500 * time.Millisecond
+
+// config is the target environment and the actors the scenario plays
+type config struct {
+	baseURL    string
+	userID     string // needs a verified phone while PHONE_VERIFICATION_REQUIRED is on
+	mechanicID string // should be online, qualified for repairType and near the location
+	repairType string
+	latitude   float64
+	longitude  float64
+
+	adminToken    string // ADMIN_API_TOKEN; the Kafka steps are skipped without it
+	userToken     string // JWTs for environments with JWT_AUTH on; X-Actor headers otherwise
+	mechanicToken string
+
+	requestTimeout time.Duration // bounds each request
+	waitTimeout    time.Duration // bounds the wait for each asynchronous effect
+	cleanup        bool          // cancel the repair at the end
+}
+
+// httpError is a response outside 2xx
+type httpError struct {
+	status int
+	body   string
+}
+
+func (e *httpError) Error() string {
+	return fmt.Sprintf("status %d: %s", e.status, e.body)
+}
+
+func isStatus(err error, status int) bool {
+	var he *httpError
+	return errors.As(err, &he) && he.status == status
+}
+
+// statusUpdate is the part of the gateway's WebSocket messages the scenario
+// checks
+type statusUpdate struct {
+	RepairID string `json:"repairID"`
+	Status   string `json:"status"`
+}
+
+// tappedEvent is the part of a record of GET /admin/events/tap the scenario
+// checks
+type tappedEvent struct {
+	Key     string            `json:"key"`
+	Headers map[string]string `json:"headers"`
+}
+
+// smokeTest runs the scenario against one environment and records each step
+type smokeTest struct {
+	cfg    config
+	client *http.Client
+	report *report
+	failed string // the first failed step; later steps are skipped
+
+	estimate map[string]any // passed back unchanged to create the repair
+	repairID string
+	ws       *websocket.Conn
+	updates  chan statusUpdate
+}
+
+func newSmokeTest(cfg config) *smokeTest {
+	return &smokeTest{
+		cfg:    cfg,
+		client: &http.Client{Timeout: cfg.requestTimeout},
+		report: &report{Target: cfg.baseURL, StartedAt: time.Now().UTC()},
+	}
+}
+
+// run plays the scenario: a user connects to /ws and books a repair from an
+// estimate, the mechanic claims and starts it, and the user sees the status
+// change over the WebSocket while repair-events carries the Kafka events
+func (t *smokeTest) run(ctx context.Context) *report {
+	start := time.Now()
+	t.step(ctx, "health", false, t.health)
+	t.step(ctx, "websocket_connect", false, t.connectWebSocket)
+	t.step(ctx, "estimate", false, t.estimateRepair)
+	t.step(ctx, "create_repair", false, t.createRepair)
+	t.step(ctx, "kafka_repair_created", false, t.tapFunc("RepairCreated"))
+	t.step(ctx, "assign_repair", false, t.assignRepair)
+	t.step(ctx, "verify_assigned", false, t.verifyRepair("", t.cfg.mechanicID))
+	t.step(ctx, "start_repair", false, t.updateStatusFunc("in_progress"))
+	t.step(ctx, "websocket_update", false, t.awaitUpdate("in_progress"))
+	t.step(ctx, "kafka_repair_updated", false, t.tapFunc("RepairUpdated"))
+	t.step(ctx, "verify_in_progress", false, t.verifyRepair("in_progress", t.cfg.mechanicID))
+	if t.cfg.cleanup {
+		t.step(ctx, "cancel_repair", true, t.cancelRepair)
+	}
+	if t.ws != nil {
+		t.ws.Close()
+	}
+
+	t.report.OK = t.failed == ""
+	t.report.RepairID = t.repairID
+	t.report.DurationMs = time.Since(start).Milliseconds()
+	return t.report
+}
+
+// step runs fn unless an earlier step failed; always runs it regardless, for
+// cleanup. fn returns a detail for the report or an error.
+func (t *smokeTest) step(ctx context.Context, name string, always bool, fn func(ctx context.Context) (string, error)) {
+	result := stepResult{Name: name}
+	if t.failed != "" && !always {
+		result.Status = statusSkipped
+		result.Error = "skipped after " + t.failed + " failed"
+		t.report.Steps = append(t.report.Steps, result)
+		return
+	}
+	started := time.Now()
+	detail, err := fn(ctx)
+	result.DurationMs = time.Since(started).Milliseconds()
+	result.Detail = detail
+	switch {
+	case errors.Is(err, errSkip):
+		result.Status = statusSkipped
+		result.Error = err.Error()
+	case err != nil:
+		result.Status = statusFailed
+		result.Error = err.Error()
+		if t.failed == "" {
+			t.failed = name
+		}
+	default:
+		result.Status = statusPassed
+	}
+	t.report.Steps = append(t.report.Steps, result)
+}
+
+// errSkip marks a step that cannot run in the environment
+var errSkip = errors.New("skipped")
+
+func (t *smokeTest) health(ctx context.Context) (string, error) {
+	return "", t.call(ctx, http.MethodGet, "/health", "", nil, nil)
+}
+
+// connectWebSocket connects to /ws as the user, with the user's JWT or a
+// WebSocket token from /ws/token, and starts reading its status updates
+func (t *smokeTest) connectWebSocket(ctx context.Context) (string, error) {
+	token := t.cfg.userToken
+	if token == "" {
+		var issued struct {
+			Token string `json:"token"`
+		}
+		if err := t.call(ctx, http.MethodPost, "/ws/token", roleUser, nil, &issued); err != nil {
+			return "", fmt.Errorf("failed to get a WebSocket token: %w", err)
+		}
+		token = issued.Token
+	}
+	wsURL, err := url.Parse(t.cfg.baseURL + "/ws")
+	if err != nil {
+		return "", err
+	}
+	wsURL.Scheme = strings.Replace(wsURL.Scheme, "http", "ws", 1)
+
+	dialCtx, cancel := context.WithTimeout(ctx, t.cfg.requestTimeout)
+	defer cancel()
+	header := http.Header{"Authorization": {"Bearer " + token}, "X-Device-ID": {"smoketest"}}
+	conn, resp, err := websocket.DefaultDialer.DialContext(dialCtx, wsURL.String(), header)
+	if err != nil {
+		if resp != nil {
+			return "", fmt.Errorf("failed to connect to %s: %w (status %d)", wsURL.Redacted(), err, resp.StatusCode)
+		}
+		return "", fmt.Errorf("failed to connect to %s: %w", wsURL.Redacted(), err)
+	}
+	t.ws = conn
+	t.updates = make(chan statusUpdate, 64)
+	go func() {
+		defer close(t.updates)
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var update statusUpdate
+			if json.Unmarshal(data, &update) == nil && update.RepairID != "" {
+				t.updates <- update
+			}
+		}
+	}()
+	return "", nil
+}
+
+func (t *smokeTest) estimateRepair(ctx context.Context) (string, error) {
+	body := map[string]any{
+		"repairType": t.cfg.repairType,
+		"userID":     t.cfg.userID,
+		"location":   map[string]float64{"longitude": t.cfg.longitude, "latitude": t.cfg.latitude},
+	}
+	if err := t.call(ctx, http.MethodPost, "/repairs/estimate", roleUser, body, &t.estimate); err != nil {
+		return "", err
+	}
+	costID, _ := t.estimate["id"].(string)
+	if costID == "" {
+		return "", errors.New("estimate has no id")
+	}
+	mechanics, _ := t.estimate["mechanics"].([]any)
+	return fmt.Sprintf("costID=%s mechanics=%d", costID, len(mechanics)), nil
+}
+
+func (t *smokeTest) createRepair(ctx context.Context) (string, error) {
+	var repair struct {
+		ID     string `json:"id"`
+		Status string `json:"status"`
+	}
+	if err := t.call(ctx, http.MethodPost, "/repairs", roleUser, t.estimate, &repair); err != nil {
+		if isStatus(err, http.StatusForbidden) {
+			return "", fmt.Errorf("%w (does %s have a verified phone?)", err, t.cfg.userID)
+		}
+		return "", err
+	}
+	if repair.ID == "" {
+		return "", errors.New("created repair has no id")
+	}
+	t.repairID = repair.ID
+	if repair.Status != "pending" {
+		return "repairID=" + repair.ID, fmt.Errorf("created repair is %q, expected pending", repair.Status)
+	}
+	return "repairID=" + repair.ID, nil
+}
+
+// tapFunc waits until repair-events holds an event of eventType for the
+// repair, read through the admin tap
+func (t *smokeTest) tapFunc(eventType string) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		if t.cfg.adminToken == "" {
+			return "", fmt.Errorf("%w: no admin token to tap repair-events", errSkip)
+		}
+		err := t.eventually(ctx, func() error {
+			var tap struct {
+				Bus    string        `json:"bus"`
+				Events []tappedEvent `json:"events"`
+			}
+			if err := t.call(ctx, http.MethodGet, "/admin/events/tap?limit=200", roleAdmin, nil, &tap); err != nil {
+				return err
+			}
+			for _, event := range tap.Events {
+				if event.Key == t.repairID && event.Headers["event_type"] == eventType {
+					return nil
+				}
+			}
+			return fmt.Errorf("no %s event for repair %s among the last %d on the %s bus", eventType, t.repairID, len(tap.Events), tap.Bus)
+		}, nil)
+		return "", err
+	}
+}
+
+// assignRepair claims the repair as the mechanic, retrying while
+// mechanic-service does not know it yet
+func (t *smokeTest) assignRepair(ctx context.Context) (string, error) {
+	body := map[string]string{"mechanicID": t.cfg.mechanicID}
+	err := t.eventually(ctx, func() error {
+		return t.call(ctx, http.MethodPost, "/repairs/"+url.PathEscape(t.repairID)+"/assign", roleMechanic, body, nil)
+	}, func(err error) bool { return isStatus(err, http.StatusNotFound) })
+	return "mechanicID=" + t.cfg.mechanicID, err
+}
+
+// verifyRepair waits until the user sees the repair in status, any when
+// empty, and assigned to mechanicID
+func (t *smokeTest) verifyRepair(status, mechanicID string) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		var repair struct {
+			Status     string `json:"status"`
+			AssignedTo string `json:"assignedTo"`
+		}
+		err := t.eventually(ctx, func() error {
+			if err := t.call(ctx, http.MethodGet, "/repairs/"+url.PathEscape(t.repairID), roleUser, nil, &repair); err != nil {
+				return err
+			}
+			if status != "" && repair.Status != status {
+				return fmt.Errorf("repair is %q, expected %q", repair.Status, status)
+			}
+			if repair.AssignedTo != mechanicID {
+				return fmt.Errorf("repair is assigned to %q, expected %q", repair.AssignedTo, mechanicID)
+			}
+			return nil
+		}, nil)
+		return "status=" + repair.Status, err
+	}
+}
+
+func (t *smokeTest) updateStatusFunc(status string) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		body := map[string]string{"status": status}
+		return "", t.call(ctx, http.MethodPut, "/repairs/"+url.PathEscape(t.repairID), roleMechanic, body, nil)
+	}
+}
+
+// awaitUpdate waits for the user's WebSocket to deliver the repair's status
+func (t *smokeTest) awaitUpdate(status string) func(ctx context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		timer := time.NewTimer(t.cfg.waitTimeout)
+		defer timer.Stop()
+		var seen []string
+		for {
+			select {
+			case update, ok := <-t.updates:
+				if !ok {
+					return "", errors.New("WebSocket closed before the update arrived")
+				}
+				if update.RepairID != t.repairID {
+					continue
+				}
+				if update.Status == status {
+					return "", nil
+				}
+				seen = append(seen, update.Status)
+			case <-timer.C:
+				return "", fmt.Errorf("no %q update for repair %s within %s (saw %v)", status, t.repairID, t.cfg.waitTimeout, seen)
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+	}
+}
+
+// cancelRepair cancels the repair the scenario created, if any, so smoke
+// runs leave no open repairs behind
+func (t *smokeTest) cancelRepair(ctx context.Context) (string, error) {
+	if t.repairID == "" {
+		return "", fmt.Errorf("%w: no repair was created", errSkip)
+	}
+	return t.updateStatusFunc("cancelled")(ctx)
+}
+
+// eventually retries check every pollInterval until it succeeds or the wait
+// timeout passes; with retryable set, only errors it accepts are retried
+func (t *smokeTest) eventually(ctx context.Context, check func() error, retryable func(error) bool) error {
+	deadline := time.Now().Add(t.cfg.waitTimeout)
+	for {
+		err := check()
+		if err == nil {
+			return nil
+		}
+		if (retryable != nil && !retryable(err)) || time.Now().After(deadline) {
+			return err
+		}
+		select {
+		case <-time.After(pollInterval):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+// call sends a request as role and decodes a 2xx response into out, if set.
+// Users and mechanics are identified by their JWT when given, else by the
+// X-Actor headers the gateway trusts while JWT_AUTH is off.
+func (t *smokeTest) call(ctx context.Context, method, path, role string, body, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, t.cfg.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch role {
+	case roleAdmin:
+		req.Header.Set("Authorization", "Bearer "+t.cfg.adminToken)
+	case roleUser:
+		t.identify(req, role, t.cfg.userID, t.cfg.userToken)
+	case roleMechanic:
+		t.identify(req, role, t.cfg.mechanicID, t.cfg.mechanicToken)
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s %s: failed to read response: %w", method, path, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s: %w", method, path, &httpError{status: resp.StatusCode, body: strings.TrimSpace(string(data))})
+	}
+	if out != nil && len(bytes.TrimSpace(data)) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("%s %s: failed to decode response: %w", method, path, err)
+		}
+	}
+	return nil
+}
+
+func (t *smokeTest) identify(req *http.Request, role, id, token string) {
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return
+	}
+	req.Header.Set("X-Actor-Role", role)
+	req.Header.Set("X-Actor-ID", id)
+}
@@ -0,0 +1,296 @@
+// Package config holds the gateway's reloadable tunables. Each is read from,
+// in increasing precedence, its built-in default, the environment, the file
+// at CONFIG_FILE and Consul KV under CONFIG_KV_PREFIX. The file is read again
+// on SIGHUP and the KV prefix is watched, so timeouts, access logging,
+// request validation and the log level change without a restart.
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Sources of an effective value, in increasing precedence
+const (
+	SourceDefault = "default"
+	SourceEnv     = "env"
+	SourceFile    = "file"
+	SourceConsul  = "consul"
+)
+
+// DefaultKVPrefix is the Consul KV prefix of the tunables unless
+// CONFIG_KV_PREFIX is set; keys are the tunables' names, e.g.
+// gateway/config/REQUEST_TIMEOUT_MS
+const DefaultKVPrefix = "gateway/config/"
+
+// Tunable is a setting that can change while the gateway runs
+type Tunable struct {
+	Key     string
+	Default string // as documented by the component reading it
+}
+
+// Tunables are the settings the store serves; other keys in the file or
+// Consul are ignored
+var Tunables = []Tunable{
+	{Key: "LOG_LEVEL", Default: "info"},
+	{Key: "REQUEST_TIMEOUT_MS", Default: "10000"},
+	{Key: "REQUEST_ROUTE_TIMEOUTS", Default: ""},
+	{Key: "ACCESS_LOG_BODY_SAMPLE_RATE", Default: "0"},
+	{Key: "ACCESS_LOG_ROUTE_SAMPLE_RATES", Default: ""},
+	{Key: "ACCESS_LOG_DISABLED_ROUTES", Default: ""},
+	{Key: "ACCESS_LOG_MAX_BODY_BYTES", Default: "2048"},
+	{Key: "REQUEST_VALIDATION", Default: "warn"},
+	{Key: "REQUEST_VALIDATION_ROUTES", Default: ""},
+}
+
+// Setting is the effective value of a tunable and where it comes from
+type Setting struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+// Snapshot is the effective configuration
+type Snapshot struct {
+	Version    int       `json:"version"` // incremented by every reload that changed a value
+	ReloadedAt time.Time `json:"reloadedAt"`
+	File       string    `json:"file,omitempty"`
+	KVPrefix   string    `json:"kvPrefix,omitempty"`
+	Settings   []Setting `json:"settings"`
+}
+
+// Store tracks the tunables' values per source and notifies subscribers of
+// changes
+type Store struct {
+	file        string
+	kv          *api.KV // nil without Consul
+	prefix      string
+	logger      *slog.Logger
+	mu          sync.RWMutex
+	env         map[string]string
+	fromFile    map[string]string
+	fromConsul  map[string]string
+	version     int
+	reloadedAt  time.Time
+	subscribers []func(get func(string) string)
+}
+
+// NewStore creates the store, reading the environment and CONFIG_FILE now.
+// With a Consul client it also loads and then watches CONFIG_KV_PREFIX.
+func NewStore(consul *api.Client, logger *slog.Logger) *Store {
+	s := &Store{
+		file:       os.Getenv("CONFIG_FILE"),
+		prefix:     os.Getenv("CONFIG_KV_PREFIX"),
+		logger:     logger,
+		env:        map[string]string{},
+		fromFile:   map[string]string{},
+		fromConsul: map[string]string{},
+		reloadedAt: time.Now(),
+	}
+	if s.prefix == "" {
+		s.prefix = DefaultKVPrefix
+	}
+	for _, t := range Tunables {
+		if v, ok := os.LookupEnv(t.Key); ok {
+			s.env[t.Key] = v
+		}
+	}
+	if s.file != "" {
+		values, err := s.readFile()
+		if err != nil {
+			logger.Error("Failed to read config file, using the environment", "file", s.file, "error", err, "app", "api-gateway")
+		} else {
+			s.fromFile = values
+		}
+	}
+	if consul != nil {
+		s.kv = consul.KV()
+		if pairs, _, err := s.kv.List(s.prefix, nil); err == nil {
+			s.fromConsul = s.consulValues(pairs)
+		} else {
+			logger.Warn("Failed to read config from Consul KV", "prefix", s.prefix, "error", err, "app", "api-gateway")
+		}
+		go s.watch()
+	}
+	logger.Info("Loaded configuration", "file", s.file, "fileKeys", len(s.fromFile), "kvPrefix", s.prefix, "consulKeys", len(s.fromConsul), "app", "api-gateway")
+	return s
+}
+
+// Get returns the effective value of key, empty when unset
+func (s *Store) Get(key string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, _ := s.lookup(key)
+	return value
+}
+
+// lookup returns the effective value of key and its source. Callers hold s.mu.
+func (s *Store) lookup(key string) (string, string) {
+	if v, ok := s.fromConsul[key]; ok {
+		return v, SourceConsul
+	}
+	if v, ok := s.fromFile[key]; ok {
+		return v, SourceFile
+	}
+	if v, ok := s.env[key]; ok {
+		return v, SourceEnv
+	}
+	return "", SourceDefault
+}
+
+// Subscribe calls apply now and again after every reload that changed a
+// value. apply reads the settings through get, which returns the effective
+// value of a key or empty when it is unset everywhere, like os.Getenv.
+func (s *Store) Subscribe(apply func(get func(string) string)) {
+	s.mu.Lock()
+	s.subscribers = append(s.subscribers, apply)
+	s.mu.Unlock()
+	apply(s.Get)
+}
+
+// Snapshot returns the effective value and source of every tunable
+func (s *Store) Snapshot() Snapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	snapshot := Snapshot{Version: s.version, ReloadedAt: s.reloadedAt, File: s.file, Settings: make([]Setting, 0, len(Tunables))}
+	if s.kv != nil {
+		snapshot.KVPrefix = s.prefix
+	}
+	for _, t := range Tunables {
+		value, source := s.lookup(t.Key)
+		if source == SourceDefault {
+			value = t.Default
+		}
+		snapshot.Settings = append(snapshot.Settings, Setting{Key: t.Key, Value: value, Source: source})
+	}
+	return snapshot
+}
+
+// Reload reads CONFIG_FILE again, e.g. on SIGHUP. A file that cannot be read
+// keeps the values read before.
+func (s *Store) Reload() error {
+	if s.file == "" {
+		return fmt.Errorf("CONFIG_FILE is not set")
+	}
+	values, err := s.readFile()
+	if err != nil {
+		s.logger.Error("Failed to reload config file, keeping the last values", "file", s.file, "error", err, "app", "api-gateway")
+		return err
+	}
+	s.update(SourceFile, values)
+	return nil
+}
+
+// watch reloads the Consul values whenever a key under the prefix changes
+func (s *Store) watch() {
+	var index uint64
+	for {
+		pairs, meta, err := s.kv.List(s.prefix, &api.QueryOptions{WaitIndex: index, WaitTime: 5 * time.Minute})
+		if err != nil {
+			s.logger.Error("Failed to watch config in Consul KV, keeping the last values", "error", err, "app", "api-gateway")
+			time.Sleep(2 * time.Second)
+			continue
+		}
+		if meta.LastIndex < index {
+			// Consul's index went backwards (e.g. a restore); start over
+			index = 0
+			continue
+		}
+		index = meta.LastIndex
+		s.update(SourceConsul, s.consulValues(pairs))
+	}
+}
+
+// update replaces the values of one source and, when an effective value
+// changed, notifies the subscribers
+func (s *Store) update(source string, values map[string]string) {
+	s.mu.Lock()
+	before := make(map[string]string, len(Tunables))
+	for _, t := range Tunables {
+		before[t.Key], _ = s.lookup(t.Key)
+	}
+	if source == SourceFile {
+		s.fromFile = values
+	} else {
+		s.fromConsul = values
+	}
+	var changed []string
+	for _, t := range Tunables {
+		if v, _ := s.lookup(t.Key); v != before[t.Key] {
+			changed = append(changed, t.Key)
+		}
+	}
+	s.reloadedAt = time.Now()
+	if len(changed) > 0 {
+		s.version++
+	}
+	subscribers := slices.Clone(s.subscribers)
+	s.mu.Unlock()
+
+	if len(changed) == 0 {
+		s.logger.Info("Reloaded configuration, nothing changed", "source", source, "app", "api-gateway")
+		return
+	}
+	s.logger.Warn("Reloaded configuration", "source", source, "changed", changed, "app", "api-gateway")
+	for _, apply := range subscribers {
+		apply(s.Get)
+	}
+}
+
+// readFile parses CONFIG_FILE, KEY=VALUE lines like an env file; blank lines
+// and lines starting with # are skipped
+func (s *Store) readFile() (map[string]string, error) {
+	data, err := os.ReadFile(s.file)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", s.file, n)
+		}
+		key = strings.TrimSpace(key)
+		if !isTunable(key) {
+			s.logger.Warn("Ignoring config key that cannot be reloaded", "key", key, "file", s.file, "app", "api-gateway")
+			continue
+		}
+		values[key] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return values, scanner.Err()
+}
+
+// consulValues maps the KV pairs under the prefix to tunables
+func (s *Store) consulValues(pairs api.KVPairs) map[string]string {
+	values := map[string]string{}
+	for _, pair := range pairs {
+		key := strings.TrimPrefix(pair.Key, s.prefix)
+		if key == "" {
+			continue // the prefix folder itself
+		}
+		if !isTunable(key) {
+			s.logger.Warn("Ignoring config key that cannot be reloaded", "key", pair.Key, "app", "api-gateway")
+			continue
+		}
+		values[key] = strings.TrimSpace(string(pair.Value))
+	}
+	return values
+}
+
+func isTunable(key string) bool {
+	return slices.ContainsFunc(Tunables, func(t Tunable) bool { return t.Key == key })
+}
@@ -0,0 +1,311 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"api-gateway/middleware"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PartialResponse is the answer of the aggregate endpoints, which combine
+// several downstream fetches into one view. A section that could not be
+// fetched does not fail the request: it is served from the last good copy
+// when one is cached, null otherwise, and named in Warnings either way.
+type PartialResponse struct {
+	Data      map[string]json.RawMessage  `json:"data"`      // each section; null when it failed without a cached copy or does not exist
+	Warnings  []SectionWarning            `json:"warnings"`  // empty when every section was fetched
+	Freshness map[string]SectionFreshness `json:"freshness"` // of each section served
+}
+
+// SectionWarning names a section of a PartialResponse that could not be fetched
+type SectionWarning struct {
+	Section string `json:"section"`
+	Error   string `json:"error"`
+	Status  int    `json:"status,omitempty"` // the downstream status; 0 when the service was not reached
+	Stale   bool   `json:"stale"`            // a cached copy is served instead
+}
+
+// SectionFreshness tells how current a section of a PartialResponse is
+type SectionFreshness struct {
+	FetchedAt  time.Time `json:"fetchedAt"`
+	Stale      bool      `json:"stale"`      // served from the cache after a failed fetch
+	AgeSeconds int       `json:"ageSeconds"` // since the section was fetched
+}
+
+// aggregateSection is one downstream fetch of an aggregate endpoint
+type aggregateSection struct {
+	name        string
+	baseURL     string
+	path        string
+	header      http.Header // e.g. the caller, for services that check it
+	absentOn404 bool        // a 404 means the section does not exist yet, e.g. a receipt before completion
+}
+
+// cachedSection is the last good copy of a section
+type cachedSection struct {
+	body      json.RawMessage
+	fetchedAt time.Time
+}
+
+// sectionCache keeps the last good copy of each aggregate section for
+// AGGREGATE_STALE_SECONDS, served when a fetch fails. It holds at most
+// maxEntries copies; a full cache drops expired copies first, then any.
+type sectionCache struct {
+	mu         sync.Mutex
+	entries    map[string]cachedSection
+	maxAge     time.Duration
+	maxEntries int
+}
+
+func newSectionCache(maxAge time.Duration, maxEntries int) *sectionCache {
+	return &sectionCache{entries: make(map[string]cachedSection), maxAge: maxAge, maxEntries: max(maxEntries, 1)}
+}
+
+func (c *sectionCache) get(key string, now time.Time) (cachedSection, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if ok && now.Sub(entry.fetchedAt) > c.maxAge {
+		delete(c.entries, key)
+		return cachedSection{}, false
+	}
+	return entry, ok
+}
+
+func (c *sectionCache) put(key string, entry cachedSection) {
+	if c.maxAge <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; !ok && len(c.entries) >= c.maxEntries {
+		for k, e := range c.entries {
+			if entry.fetchedAt.Sub(e.fetchedAt) > c.maxAge {
+				delete(c.entries, k)
+			}
+		}
+		for k := range c.entries {
+			if len(c.entries) < c.maxEntries {
+				break
+			}
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = entry
+}
+
+// sectionError is a failed section fetch
+type sectionError struct {
+	status int // the downstream status; 0 when the service was not reached
+	err    error
+}
+
+func (e *sectionError) Error() string {
+	return e.err.Error()
+}
+
+// fetchSections fetches the sections concurrently into a PartialResponse
+func (h *RepairHandler) fetchSections(ctx context.Context, span trace.Span, sections []aggregateSection) *PartialResponse {
+	resp := &PartialResponse{
+		Data:      make(map[string]json.RawMessage, len(sections)),
+		Warnings:  []SectionWarning{},
+		Freshness: make(map[string]SectionFreshness, len(sections)),
+	}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, s := range sections {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key := s.baseURL + s.path
+			body, err := h.fetchSection(ctx, s)
+			now := time.Now()
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				resp.Data[s.name] = body
+				if body != nil {
+					h.sections.put(key, cachedSection{body: body, fetchedAt: now})
+					resp.Freshness[s.name] = SectionFreshness{FetchedAt: now}
+				}
+				return
+			}
+			warning := SectionWarning{Section: s.name, Error: err.Error(), Status: err.status}
+			// A refusal or a missing resource is the answer; only outages
+			// fall back to the cached copy
+			clientError := err.status >= 400 && err.status < 500
+			if cached, ok := h.sections.get(key, now); ok && !clientError {
+				warning.Stale = true
+				resp.Data[s.name] = cached.body
+				resp.Freshness[s.name] = SectionFreshness{
+					FetchedAt:  cached.fetchedAt,
+					Stale:      true,
+					AgeSeconds: int(now.Sub(cached.fetchedAt).Seconds()),
+				}
+			} else {
+				resp.Data[s.name] = nil
+			}
+			resp.Warnings = append(resp.Warnings, warning)
+			h.logger.Warn("Aggregate section failed", "section", s.name, "error", err, "status", err.status, "stale", warning.Stale)
+		}()
+	}
+	wg.Wait()
+
+	failed := make([]string, 0, len(resp.Warnings))
+	for _, w := range resp.Warnings {
+		failed = append(failed, w.Section)
+	}
+	span.SetAttributes(attribute.StringSlice("failedSections", failed))
+	if len(failed) > 0 {
+		span.SetStatus(codes.Error, "Some sections could not be fetched")
+	}
+	return resp
+}
+
+// fetchSection fetches one section; a nil body is a section that does not exist
+func (h *RepairHandler) fetchSection(ctx context.Context, s aggregateSection) (json.RawMessage, *sectionError) {
+	ctx, span := h.tracer.Start(ctx, "FetchSection")
+	defer span.End()
+	span.SetAttributes(attribute.String("section", s.name))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.baseURL+s.path, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to create request")
+		return nil, &sectionError{err: err}
+	}
+	for name, values := range s.header {
+		req.Header[name] = values
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to contact service")
+		return nil, &sectionError{err: err}
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to read response body")
+		return nil, &sectionError{status: resp.StatusCode, err: err}
+	}
+	span.SetAttributes(attribute.Int("statusCode", resp.StatusCode))
+	switch {
+	case resp.StatusCode == http.StatusNotFound && s.absentOn404:
+		return nil, nil
+	case resp.StatusCode != http.StatusOK:
+		err := fmt.Errorf("%s answered %d", s.name, resp.StatusCode)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, &sectionError{status: resp.StatusCode, err: err}
+	case !json.Valid(body):
+		err := fmt.Errorf("%s answered invalid JSON", s.name)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, &sectionError{status: resp.StatusCode, err: err}
+	}
+	return body, nil
+}
+
+// writePartialResponse answers 200 with the response, partial or not
+func writePartialResponse(w http.ResponseWriter, resp *PartialResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// GetRepairFullView returns a repair with its amendments and receipt in one
+// response. The repair itself is required: when it cannot be served, fresh
+// or cached, its error is answered. Users only see their own repairs and
+// mechanics the ones assigned to them, as for GET /repairs/{repairID}.
+func (h *RepairHandler) GetRepairFullView(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "GetRepairFullView")
+	defer span.End()
+
+	repairID := url.PathEscape(mux.Vars(r)["repairID"])
+	span.SetAttributes(attribute.String("repairID", repairID))
+	resp := h.fetchSections(ctx, span, []aggregateSection{
+		{name: "repair", baseURL: h.repairService.URL(), path: "/repairs/" + repairID},
+		{name: "amendments", baseURL: h.repairService.URL(), path: "/repairs/" + repairID + "/amendments"},
+		{name: "receipt", baseURL: h.repairService.URL(), path: "/repairs/" + repairID + "/receipt", absentOn404: true},
+	})
+
+	if resp.Data["repair"] == nil {
+		// Client errors such as 404 pass through; anything else is the
+		// gateway's failure to reach the repair
+		status := http.StatusBadGateway
+		for _, warning := range resp.Warnings {
+			if warning.Section == "repair" && warning.Status >= 400 && warning.Status < 500 {
+				status = warning.Status
+			}
+		}
+		http.Error(w, "Failed to get repair", status)
+		return
+	}
+	var repair RepairModel
+	if err := json.Unmarshal(resp.Data["repair"], &repair); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode repair")
+		h.logger.Error("Failed to decode repair", "error", err, "repairID", repairID)
+		http.Error(w, "Failed to decode repair", http.StatusInternalServerError)
+		return
+	}
+	if caller, ok := middleware.CallerFrom(ctx); ok &&
+		((caller.Role == middleware.RoleUser && repair.UserID != caller.ID) ||
+			(caller.Role == middleware.RoleMechanic && repair.AssignedTo != caller.ID)) {
+		span.SetStatus(codes.Error, "Repair belongs to another caller")
+		h.logger.Warn("Rejected repair view of another caller", "repairID", repairID, "role", caller.Role, "callerID", caller.ID)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	writePartialResponse(w, resp)
+}
+
+// GetMechanicHome returns what a mechanic's home screen shows in one
+// response: the repairs nearby, the route through their open repairs, their
+// absences, their notification preferences and where to wait for work.
+// Mechanics only see their own home.
+func (h *RepairHandler) GetMechanicHome(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "GetMechanicHome")
+	defer span.End()
+
+	mechanicID := mux.Vars(r)["mechanicID"]
+	span.SetAttributes(attribute.String("mechanicID", mechanicID))
+	caller, hasCaller := middleware.CallerFrom(ctx)
+	if hasCaller && caller.Role == middleware.RoleMechanic && caller.ID != mechanicID {
+		span.SetStatus(codes.Error, "Mechanics may only see their own home")
+		h.logger.Warn("Rejected home of another mechanic", "mechanicID", mechanicID, "callerID", caller.ID)
+		http.Error(w, "Mechanics may only see their own home", http.StatusForbidden)
+		return
+	}
+	var header http.Header
+	if hasCaller {
+		header = caller.Header()
+	}
+
+	escaped := url.PathEscape(mechanicID)
+	resp := h.fetchSections(ctx, span, []aggregateSection{
+		{name: "nearbyRepairs", baseURL: h.mechanicService.URL(), path: "/repairs/nearby?" + url.Values{"mechanicID": {mechanicID}}.Encode(), header: header},
+		{name: "route", baseURL: h.mechanicService.URL(), path: "/mechanics/" + escaped + "/route", header: header, absentOn404: true},
+		{name: "absences", baseURL: h.mechanicService.URL(), path: "/mechanics/" + escaped + "/absences", header: header},
+		{name: "notificationPreferences", baseURL: h.mechanicService.URL(), path: "/mechanics/" + escaped + "/notification-preferences", header: header},
+		{name: "positioning", baseURL: h.repairService.URL(), path: "/mechanics/" + escaped + "/positioning", header: header},
+	})
+	writePartialResponse(w, resp)
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Amendment mirrors repair-service's domain.Amendment
+type Amendment struct {
+	ID          string     `json:"id"`
+	RepairID    string     `json:"repairID"`
+	UserID      string     `json:"userID"`
+	MechanicID  string     `json:"mechanicID"`
+	Description string     `json:"description"`
+	Amount      float64    `json:"amount"`
+	Status      string     `json:"status"`
+	RequestedAt time.Time  `json:"requestedAt"`
+	DecidedAt   *time.Time `json:"decidedAt,omitempty"`
+}
+
+// ListAmendments lists a repair's price amendments
+func (h *RepairHandler) ListAmendments(w http.ResponseWriter, r *http.Request) {
+	repairID := url.PathEscape(mux.Vars(r)["repairID"])
+	h.proxyRequest(w, r, "ListAmendments", h.repairService.URL(), "/repairs/"+repairID+"/amendments")
+}
+
+// DecideAmendment approves or rejects a pending amendment for the user
+func (h *RepairHandler) DecideAmendment(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	path := "/repairs/" + url.PathEscape(vars["repairID"]) + "/amendments/" + url.PathEscape(vars["amendmentID"])
+	h.proxyRequest(w, r, "DecideAmendment", h.repairService.URL(), path)
+}
+
+// RequestAmendment forwards a mechanic's request for extra work to
+// repair-service and queues the approval request for the user's WebSocket
+// clients and long polls
+func (h *RepairHandler) RequestAmendment(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "RequestAmendment")
+	defer span.End()
+
+	repairID := mux.Vars(r)["repairID"]
+	span.SetAttributes(attribute.String("repairID", repairID))
+
+	target := h.repairService.URL() + "/repairs/" + url.PathEscape(repairID) + "/amendments"
+	req, err := http.NewRequestWithContext(ctx, "POST", target, r.Body)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to create request")
+		h.logger.Error("Failed to create request", "error", err)
+		http.Error(w, "Failed to create request", http.StatusInternalServerError)
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to contact repair service")
+		h.logger.Error("Failed to contact repair service", "error", err, "url", req.URL.String())
+		http.Error(w, "Failed to contact repair service", downstreamStatus(w, err))
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to read response body")
+		h.logger.Error("Failed to read response body", "error", err)
+		http.Error(w, "Failed to read response", http.StatusInternalServerError)
+		return
+	}
+
+	if resp.StatusCode == http.StatusCreated {
+		var amendment Amendment
+		if err := json.Unmarshal(body, &amendment); err != nil {
+			h.logger.Warn("Failed to decode amendment, not notifying user", "error", err, "repairID", repairID)
+		} else {
+			// Amendments are only requested on in-progress repairs
+			h.enqueueBroadcast(ctx, broadcastJob{
+				update: StatusUpdate{
+					RepairID:  repairID,
+					UserID:    amendment.UserID,
+					Status:    "in_progress",
+					Amendment: &amendment,
+				},
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.StatusCode)
+	w.Write(body)
+}
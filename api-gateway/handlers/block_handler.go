@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"api-gateway/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// ListBlocks returns the mechanics a user blocked, plus any admin blacklist
+func (h *RepairHandler) ListBlocks(w http.ResponseWriter, r *http.Request) {
+	userID := url.PathEscape(mux.Vars(r)["userID"])
+	h.proxyRequest(w, r, "ListBlocks", h.repairService.URL(), "/users/"+userID+"/blocks")
+}
+
+// BlockMechanic (PUT) and UnblockMechanic (DELETE) manage a user's block on a mechanic
+func (h *RepairHandler) BlockMechanic(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	path := "/users/" + url.PathEscape(vars["userID"]) + "/blocks/" + url.PathEscape(vars["mechanicID"])
+	h.proxyRequest(w, r, "BlockMechanic", h.repairService.URL(), path)
+}
+
+// ClaimQuote binds an anonymous estimate to a user; unclaimed anonymous quotes
+// cannot be turned into repairs
+func (h *RepairHandler) ClaimQuote(w http.ResponseWriter, r *http.Request) {
+	costID := url.PathEscape(mux.Vars(r)["costID"])
+	h.proxyRequest(w, r, "ClaimQuote", h.repairService.URL(), "/repairs/cost/"+costID+"/claim")
+}
+
+// BlacklistUser (PUT) and RemoveBlacklist (DELETE) manage the admin blacklist.
+// Only admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) BlacklistUser(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	userID := url.PathEscape(mux.Vars(r)["userID"])
+	h.proxyRequest(w, r, "BlacklistUser", h.repairService.URL(), "/admin/users/"+userID+"/blacklist")
+}
+
+// ExportUserData returns everything stored about a user as a JSON bundle, to
+// the user themself or an admin holding ADMIN_API_TOKEN. The caller is passed
+// on to repair-service, which checks it again.
+func (h *RepairHandler) ExportUserData(w http.ResponseWriter, r *http.Request) {
+	userID := mux.Vars(r)["userID"]
+	caller, ok := h.requestCaller(r)
+	if !ok {
+		h.logger.Warn("Rejected unauthenticated user data export", "path", r.URL.Path)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if caller.Role != middleware.RoleAdmin && (caller.Role != middleware.RoleUser || caller.ID != userID) {
+		h.logger.Warn("Rejected user data export for another user", "path", r.URL.Path, "role", caller.Role)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+	h.proxyRequestWithHeaders(w, r, "ExportUserData", h.repairService.URL(), "/users/"+url.PathEscape(userID)+"/export", caller.Header())
+}
+
+// requestCaller returns who the request acts for: an admin holding
+// ADMIN_API_TOKEN, the caller RBAC admitted or the user of the JWT that
+// JWTAuth verified
+func (h *RepairHandler) requestCaller(r *http.Request) (middleware.Caller, bool) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && h.adminToken != "" &&
+		subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) == 1 {
+		return middleware.Caller{Role: middleware.RoleAdmin}, true
+	}
+	if caller, ok := middleware.CallerFrom(r.Context()); ok {
+		return caller, true
+	}
+	if id, ok := middleware.IdentityFrom(r.Context()); ok {
+		caller := middleware.Caller{Role: middleware.RoleUser, ID: id.UserID}
+		if id.Role == middleware.RoleMechanic {
+			caller.Role = middleware.RoleMechanic
+		}
+		return caller, true
+	}
+	return middleware.Caller{}, false
+}
+
+// EraseUser erases a user's personal data (POST) or lists the erasure reports
+// of a user ID (GET). Only admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) EraseUser(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	userID := url.PathEscape(mux.Vars(r)["userID"])
+	h.proxyRequest(w, r, "EraseUser", h.repairService.URL(), "/admin/users/"+userID+"/erasure")
+}
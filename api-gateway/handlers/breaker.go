@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Circuit breaker states
+const (
+	BreakerClosed   = "closed"    // requests go through
+	BreakerOpen     = "open"      // requests fail at once with 503
+	BreakerHalfOpen = "half_open" // one probe request goes through; its outcome closes or reopens
+)
+
+// circuitOpenError is returned for requests to a service whose circuit is
+// open, without contacting it
+type circuitOpenError struct {
+	service    string
+	retryAfter time.Duration
+}
+
+func (e *circuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for %s is open", e.service)
+}
+
+// circuitBreaker stops calls to a service after BREAKER_FAILURES consecutive
+// failures (unreachable, timed out, or 502/503/504), so requests answer 503 at
+// once instead of each waiting for the client timeout. After
+// BREAKER_OPEN_SECONDS one probe request is let through; it closes the circuit
+// when it succeeds and reopens it otherwise.
+type circuitBreaker struct {
+	service   string
+	threshold int // 0 disables the breaker
+	openFor   time.Duration
+	logger    *slog.Logger
+
+	mu       sync.Mutex
+	state    string
+	failures int // consecutive, while closed
+	openedAt time.Time
+	probing  bool // a half-open probe is in flight
+	trips    int64
+	rejected int64
+}
+
+func newCircuitBreaker(service string, logger *slog.Logger) *circuitBreaker {
+	return &circuitBreaker{
+		service:   service,
+		threshold: envInt("BREAKER_FAILURES", 5),
+		openFor:   time.Duration(envInt("BREAKER_OPEN_SECONDS", 30)) * time.Second,
+		logger:    logger,
+		state:     BreakerClosed,
+	}
+}
+
+// allow admits a request or returns a circuitOpenError. An admitted request
+// must be followed by exactly one call to done.
+func (b *circuitBreaker) allow(now time.Time) error {
+	if b.threshold <= 0 {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerOpen:
+		if wait := b.openFor - now.Sub(b.openedAt); wait > 0 {
+			b.rejected++
+			return &circuitOpenError{service: b.service, retryAfter: wait}
+		}
+		b.transition(BreakerHalfOpen)
+		b.probing = true
+	case BreakerHalfOpen:
+		if b.probing {
+			b.rejected++
+			return &circuitOpenError{service: b.service, retryAfter: time.Second}
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// done records the outcome of an admitted request; neutral outcomes, such as
+// the caller going away, count as neither success nor failure
+func (b *circuitBreaker) done(now time.Time, failed, neutral bool) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case BreakerClosed:
+		if neutral {
+			return
+		}
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open(now)
+		}
+	case BreakerHalfOpen:
+		// Requests admitted before the circuit opened may finish now; only
+		// the probe decides
+		if !b.probing {
+			return
+		}
+		b.probing = false
+		switch {
+		case neutral:
+		case failed:
+			b.open(now)
+		default:
+			b.failures = 0
+			b.transition(BreakerClosed)
+		}
+	}
+}
+
+// open opens the circuit; the caller holds the lock
+func (b *circuitBreaker) open(now time.Time) {
+	b.openedAt = now
+	b.trips++
+	b.transition(BreakerOpen)
+}
+
+// transition changes the state and logs it; the caller holds the lock
+func (b *circuitBreaker) transition(state string) {
+	if b.state == state {
+		return
+	}
+	level := slog.LevelInfo
+	if state == BreakerOpen {
+		level = slog.LevelWarn
+	}
+	b.logger.Log(context.Background(), level, "Circuit breaker "+state, "service", b.service, "from", b.state, "failures", b.failures, "openSeconds", b.openFor.Seconds())
+	b.state = state
+}
+
+// BreakerStatus is a service's circuit breaker as shown on
+// /admin/metrics/breakers
+type BreakerStatus struct {
+	Service  string     `json:"service"`
+	State    string     `json:"state"`
+	Failures int        `json:"failures"`           // consecutive failures while closed
+	OpenedAt *time.Time `json:"openedAt,omitempty"` // last time the circuit opened
+	Trips    int64      `json:"trips"`              // times the circuit opened since start
+	Rejected int64      `json:"rejected"`           // requests answered without contacting the service
+	Enabled  bool       `json:"enabled"`
+}
+
+func (b *circuitBreaker) status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	status := BreakerStatus{
+		Service:  b.service,
+		State:    b.state,
+		Failures: b.failures,
+		Trips:    b.trips,
+		Rejected: b.rejected,
+		Enabled:  b.threshold > 0,
+	}
+	if !b.openedAt.IsZero() {
+		openedAt := b.openedAt.UTC()
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// breakerTransport guards the requests to each service with the service's
+// circuit breaker. It wraps failoverTransport, so a request that failed over
+// to another instance and succeeded counts as a success.
+type breakerTransport struct {
+	next      http.RoundTripper
+	resolvers []*serviceResolver
+}
+
+func (t *breakerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var breaker *circuitBreaker
+	for _, s := range t.resolvers {
+		if s.has(req.URL.Host) {
+			breaker = s.breaker
+			break
+		}
+	}
+	if breaker == nil {
+		return t.next.RoundTrip(req)
+	}
+	if err := breaker.allow(time.Now()); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	neutral := err != nil && errors.Is(req.Context().Err(), context.Canceled)
+	// repair-service's read-only mode rejects writes with 503 while it is
+	// healthy; those must not open the circuit for its reads
+	failed := err != nil || resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusGatewayTimeout ||
+		(resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get("X-Read-Only") == "")
+	breaker.done(time.Now(), failed, neutral)
+	return resp, err
+}
+
+// downstreamStatus is the status to answer when a call to a service failed
+// with err: 503 with Retry-After while its circuit is open, else 500
+func downstreamStatus(w http.ResponseWriter, err error) int {
+	var open *circuitOpenError
+	if errors.As(err, &open) {
+		w.Header().Set("Retry-After", strconv.Itoa(int(open.retryAfter.Seconds())+1))
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// CircuitBreakers returns the state of the circuit breaker of each service.
+// Only admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) CircuitBreakers(w http.ResponseWriter, r *http.Request) {
+	_, span := h.tracer.Start(r.Context(), "CircuitBreakers")
+	defer span.End()
+
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	statuses := []BreakerStatus{h.repairService.breaker.status(), h.mechanicService.breaker.status()}
+	open := 0
+	for _, s := range statuses {
+		if s.State != BreakerClosed {
+			open++
+		}
+	}
+	span.SetAttributes(attribute.Int("openBreakers", open))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
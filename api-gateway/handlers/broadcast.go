@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// broadcastJob is a status update waiting to be pushed to WebSocket clients
+type broadcastJob struct {
+	update   StatusUpdate
+	spanCtx  trace.SpanContext
+	attempts int
+}
+
+// Policies for a WebSocket client whose send queue is full
+const (
+	OverflowDropOldest = "drop_oldest" // discard the oldest queued message to make room
+	OverflowDisconnect = "disconnect"  // close the connection; the client reconnects and refetches
+)
+
+// wsMessage is a message queued for a WebSocket client; seq is set on status
+// updates kept in the offline queue
+type wsMessage struct {
+	data []byte
+	seq  int64
+}
+
+// wsClient is a registered WebSocket connection. Broadcasts are queued on send
+// and written by the connection's own writer goroutine, which is also the only
+// writer gorilla/websocket allows, so a slow client only fills its own queue.
+type wsClient struct {
+	conn      *websocket.Conn
+	send      chan wsMessage
+	backlog   []wsMessage // undelivered messages written before anything queued on send
+	done      chan struct{}
+	closeOnce sync.Once
+	mechanic  bool // connected with role=mechanic; receives positioning hints
+
+	deviceID     string // the connecting device; its next connection replaces this one
+	connectedAt  time.Time
+	pingInterval time.Duration // 0 sends no pings
+}
+
+func newWSClient(conn *websocket.Conn, queueSize int) *wsClient {
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	return &wsClient{conn: conn, send: make(chan wsMessage, queueSize), done: make(chan struct{}), connectedAt: time.Now()}
+}
+
+// enqueue queues a message without blocking. When the queue is full it applies
+// policy and returns how many queued messages were dropped, or false if the
+// client was disconnected.
+func (c *wsClient) enqueue(message []byte, policy string) (dropped int, ok bool) {
+	return c.enqueueMessage(wsMessage{data: message}, policy)
+}
+
+// enqueueMessage is enqueue for a message that may carry a seq
+func (c *wsClient) enqueueMessage(message wsMessage, policy string) (dropped int, ok bool) {
+	for {
+		select {
+		case <-c.done:
+			return dropped, false
+		case c.send <- message:
+			return dropped, true
+		default:
+		}
+		if policy == OverflowDisconnect {
+			c.close()
+			return dropped, false
+		}
+		select {
+		case <-c.send:
+			dropped++
+		default:
+		}
+	}
+}
+
+// writePump writes the backlog, then queued messages, and pings when the
+// client has a ping interval, until the client is closed. Queued messages the
+// backlog already covered are skipped. A write that does not complete within
+// timeout closes the connection.
+func (c *wsClient) writePump(timeout time.Duration, logger *slog.Logger, userID string) {
+	var replayed int64
+	for _, message := range c.backlog {
+		if !c.write(message.data, timeout, logger, userID) {
+			return
+		}
+		replayed = message.seq
+	}
+	c.backlog = nil
+
+	var ping <-chan time.Time
+	if c.pingInterval > 0 {
+		ticker := time.NewTicker(c.pingInterval)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ping:
+			if err := c.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(timeout)); err != nil {
+				logger.Warn("Failed to ping WebSocket client", "error", err, "userID", userID)
+				c.close()
+				return
+			}
+		case message := <-c.send:
+			if message.seq != 0 && message.seq <= replayed {
+				continue
+			}
+			if !c.write(message.data, timeout, logger, userID) {
+				return
+			}
+		}
+	}
+}
+
+// write writes one message, closing the client when it fails
+func (c *wsClient) write(message []byte, timeout time.Duration, logger *slog.Logger, userID string) bool {
+	err := c.conn.SetWriteDeadline(time.Now().Add(timeout))
+	if err == nil {
+		err = c.conn.WriteMessage(websocket.TextMessage, message)
+	}
+	if err != nil {
+		logger.Error("Failed to send WebSocket message", "error", err, "userID", userID)
+		c.close()
+		return false
+	}
+	return true
+}
+
+// close stops the writer and closes the connection, which also ends the
+// read loop in HandleWebSocket and unregisters the client
+func (c *wsClient) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// closeWith tells the client why it is being disconnected with a close frame,
+// then closes it
+func (c *wsClient) closeWith(code int, reason string) {
+	c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(time.Second))
+	c.close()
+}
+
+// enqueueBroadcast hands a status update to the broadcast worker without blocking the request
+func (h *RepairHandler) enqueueBroadcast(ctx context.Context, job broadcastJob) {
+	job.spanCtx = trace.SpanContextFromContext(ctx)
+	select {
+	case h.broadcastQueue <- job:
+	default:
+		h.logger.Error("Broadcast queue full, dropping status update", "repairID", job.update.RepairID, "status", job.update.Status)
+	}
+}
+
+// runBroadcastWorker resolves missing user IDs and broadcasts queued status updates
+func (h *RepairHandler) runBroadcastWorker() {
+	for job := range h.broadcastQueue {
+		ctx := trace.ContextWithRemoteSpanContext(context.Background(), job.spanCtx)
+		if job.update.UserID == "" {
+			repair, err := h.fetchRepair(ctx, job.update.RepairID)
+			if err != nil {
+				job.attempts++
+				if job.attempts >= h.broadcastRetries {
+					h.logger.Error("Giving up on broadcast after retries", "repairID", job.update.RepairID, "attempts", job.attempts, "error", err)
+					continue
+				}
+				h.logger.Warn("Failed to fetch repair for broadcast, retrying", "repairID", job.update.RepairID, "attempt", job.attempts, "error", err)
+				go h.retryBroadcast(job)
+				continue
+			}
+			job.update.UserID = repair.UserID
+		}
+		h.broadcastStatusUpdate(ctx, job.update)
+	}
+}
+
+// retryBroadcast re-queues a job after an exponential backoff
+func (h *RepairHandler) retryBroadcast(job broadcastJob) {
+	time.Sleep(time.Duration(1<<job.attempts) * 250 * time.Millisecond)
+	select {
+	case h.broadcastQueue <- job:
+	default:
+		h.logger.Error("Broadcast queue full, dropping retried status update", "repairID", job.update.RepairID)
+	}
+}
+
+// fetchRepair retrieves a repair from repair-service
+func (h *RepairHandler) fetchRepair(ctx context.Context, repairID string) (*RepairModel, error) {
+	ctx, span := otel.Tracer("api-gateway").Start(ctx, "FetchRepairForBroadcast")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", repairID))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", h.repairService.URL()+"/repairs/"+repairID, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to create request")
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to contact repair service")
+		return nil, fmt.Errorf("failed to contact repair service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("repair service returned status %d", resp.StatusCode)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	var repair RepairModel
+	if err := json.NewDecoder(resp.Body).Decode(&repair); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode repair")
+		return nil, fmt.Errorf("failed to decode repair: %w", err)
+	}
+	return &repair, nil
+}
+
+// envInt reads an integer environment variable, falling back to def when unset or invalid
+func envInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return def
+	}
+	return n
+}
@@ -0,0 +1,13 @@
+package handlers
+
+import "net/http"
+
+// BulkRepairStatus forwards a bulk status update to repair-service, which
+// checks every repair's transition and reports its outcome. Only admins
+// holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) BulkRepairStatus(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "BulkRepairStatus", h.repairService.URL(), "/admin/repairs/bulk-status")
+}
@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+)
+
+// RepairBundles creates or lists bundles of repairs at one location, which
+// are dispatched to one mechanic together. Only admins holding ADMIN_API_TOKEN
+// may call it.
+func (h *RepairHandler) RepairBundles(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "RepairBundles", h.repairService.URL(), "/admin/bundles")
+}
+
+// DissolveRepairBundle releases the repairs of a bundle no mechanic took yet.
+// Only admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) DissolveRepairBundle(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	bundleID := url.PathEscape(mux.Vars(r)["bundleID"])
+	h.proxyRequest(w, r, "DissolveRepairBundle", h.repairService.URL(), "/admin/bundles/"+bundleID)
+}
+
+// GetRepairBundle returns a bundle with the status of each of its repairs
+func (h *RepairHandler) GetRepairBundle(w http.ResponseWriter, r *http.Request) {
+	bundleID := url.PathEscape(mux.Vars(r)["bundleID"])
+	h.proxyRequest(w, r, "GetRepairBundle", h.repairService.URL(), "/bundles/"+bundleID)
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"api-gateway/config"
+)
+
+// SetConfig connects the admin config route to the store of reloadable
+// settings
+func (h *RepairHandler) SetConfig(store *config.Store) {
+	h.config = store
+}
+
+// Config shows the effective value of every reloadable setting and its
+// source: default, env, file (CONFIG_FILE, re-read on SIGHUP) or consul
+// (CONFIG_KV_PREFIX). Only admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) Config(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.config.Snapshot())
+}
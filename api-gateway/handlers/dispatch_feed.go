@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// dispatchDefaultTypes are the ops events a dispatch console gets unless it
+// asks for others with ?types=
+var dispatchDefaultTypes = []string{OpsRepairCreated, OpsRepairStatusChanged, OpsRepairAssigned}
+
+// dispatchFilter is the server-side filter of one dispatch console connection
+type dispatchFilter struct {
+	types    map[string]bool
+	statuses map[string]bool // empty allows every status
+	region   *[4]float64     // minLon, minLat, maxLon, maxLat; nil allows everywhere
+}
+
+// parseDispatchFilter reads ?types=, ?status= (comma separated) and
+// ?region=minLon,minLat,maxLon,maxLat
+func parseDispatchFilter(r *http.Request) (*dispatchFilter, error) {
+	query := r.URL.Query()
+	f := &dispatchFilter{types: make(map[string]bool), statuses: make(map[string]bool)}
+	for _, t := range strings.Split(query.Get("types"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			f.types[t] = true
+		}
+	}
+	if len(f.types) == 0 {
+		for _, t := range dispatchDefaultTypes {
+			f.types[t] = true
+		}
+	}
+	for _, s := range strings.Split(query.Get("status"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			f.statuses[s] = true
+		}
+	}
+	if region := query.Get("region"); region != "" {
+		parts := strings.Split(region, ",")
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("region must be minLon,minLat,maxLon,maxLat")
+		}
+		var box [4]float64
+		for i, part := range parts {
+			v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid region value %q", part)
+			}
+			box[i] = v
+		}
+		if box[0] > box[2] || box[1] > box[3] {
+			return nil, fmt.Errorf("region minimums must not exceed maximums")
+		}
+		f.region = &box
+	}
+	return f, nil
+}
+
+// matches reports whether the console should get the event. Status and region
+// filters only apply to events carrying a status or a location.
+func (f *dispatchFilter) matches(event OpsEvent) bool {
+	if !f.types[event.Type] {
+		return false
+	}
+	if status, ok := event.Data["status"].(string); ok && len(f.statuses) > 0 && !f.statuses[status] {
+		return false
+	}
+	if f.region != nil {
+		lat, hasLat := event.Data["latitude"].(float64)
+		lon, hasLon := event.Data["longitude"].(float64)
+		if hasLat && hasLon && (lon < f.region[0] || lat < f.region[1] || lon > f.region[2] || lat > f.region[3]) {
+			return false
+		}
+	}
+	return true
+}
+
+// StreamDispatchFeed streams every repair creation, status change and
+// assignment, across all users, to the live dispatch console over WebSocket.
+// ?types=, ?status= and ?region= filter on the server; ?lastEventID= replays
+// missed events after a reconnect. Browsers cannot set headers on WebSocket
+// handshakes, so the admin token may also be passed as ?access_token=. Only
+// admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) StreamDispatchFeed(w http.ResponseWriter, r *http.Request) {
+	_, span := h.tracer.Start(r.Context(), "StreamDispatchFeed")
+	defer span.End()
+
+	if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	filter, err := parseDispatchFilter(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	lastID, _ := strconv.ParseInt(r.URL.Query().Get("lastEventID"), 10, 64)
+	span.SetAttributes(
+		attribute.Int("typeFilterCount", len(filter.types)),
+		attribute.Int("statusFilterCount", len(filter.statuses)),
+		attribute.Bool("regionFilter", filter.region != nil),
+		attribute.Int64("lastEventID", lastID),
+	)
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to upgrade to WebSocket")
+		h.logger.Error("Failed to upgrade dispatch feed to WebSocket", "error", err)
+		return
+	}
+	client := newWSClient(conn, h.sendQueueSize)
+	go client.writePump(h.writeTimeout, h.logger, "dispatch-console")
+
+	events, replay := h.ops.subscribe(lastID)
+	defer h.ops.unsubscribe(events)
+	defer client.close()
+	h.logger.Info("Dispatch console connected", "lastEventID", lastID, "replayed", len(replay))
+
+	send := func(event OpsEvent) bool {
+		if !filter.matches(event) {
+			return true
+		}
+		message, err := json.Marshal(event)
+		if err != nil {
+			h.logger.Error("Failed to marshal dispatch event", "error", err)
+			return true
+		}
+		if dropped, ok := client.enqueue(message, h.overflowPolicy); !ok {
+			return false
+		} else if dropped > 0 {
+			h.logger.Warn("Dispatch console send queue full, dropped oldest events", "dropped", dropped)
+		}
+		return true
+	}
+	for _, event := range replay {
+		if !send(event) {
+			return
+		}
+	}
+
+	// The console only listens; reading detects when it goes away
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				client.close()
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-client.done:
+			h.logger.Info("Dispatch console disconnected")
+			return
+		case event := <-events:
+			if !send(event) {
+				h.logger.Info("Dispatch console disconnected on send queue overflow")
+				return
+			}
+		}
+	}
+}
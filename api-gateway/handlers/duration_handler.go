@@ -0,0 +1,15 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+// RepairDurations reports the repair durations learned from completed
+// repairs, per repair type and mechanic, against the catalog's estimates.
+// Only admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) RepairDurations(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "RepairDurations", h.repairService.URL(), "/admin/repair-durations")
+}
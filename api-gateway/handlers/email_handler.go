@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+)
+
+// EmailPreferences reads or sets a user's email address and whether they
+// opted out of transactional email
+func (h *RepairHandler) EmailPreferences(w http.ResponseWriter, r *http.Request) {
+	userID := url.PathEscape(mux.Vars(r)["userID"])
+	h.proxyRequest(w, r, "EmailPreferences", h.repairService.URL(), "/users/"+userID+"/email")
+}
+
+// EmailTemplates lists the transactional email templates. Only admins holding
+// ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) EmailTemplates(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "EmailTemplates", h.repairService.URL(), "/admin/email/templates")
+}
+
+// SaveEmailTemplate replaces a transactional email template. Only admins
+// holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) SaveEmailTemplate(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	name := url.PathEscape(mux.Vars(r)["name"])
+	h.proxyRequest(w, r, "SaveEmailTemplate", h.repairService.URL(), "/admin/email/templates/"+name)
+}
+
+// EmailDeliveries lists transactional emails and their delivery status. Only
+// admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) EmailDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "EmailDeliveries", h.repairService.URL(), "/admin/email/deliveries")
+}
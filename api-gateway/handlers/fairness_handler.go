@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+)
+
+// FairnessPolicy reads or sets the policy that rotates offers among nearby
+// mechanics. Only admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) FairnessPolicy(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "FairnessPolicy", h.repairService.URL(), "/admin/dispatch/fairness")
+}
+
+// AssignmentCounts lists each mechanic's assignments in the fairness policy's
+// window. Only admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) AssignmentCounts(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "AssignmentCounts", h.repairService.URL(), "/admin/dispatch/assignments")
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// CreateFleetAccount creates a fleet account whose admins set their members'
+// monthly spending limits and allowed repair types. Only admins holding
+// ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) CreateFleetAccount(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "CreateFleetAccount", h.repairService.URL(), "/admin/fleets")
+}
+
+// GetFleetAccount returns a fleet account to one of its admins or a platform
+// admin
+func (h *RepairHandler) GetFleetAccount(w http.ResponseWriter, r *http.Request) {
+	actor, ok := h.fleetActor(w, r)
+	if !ok {
+		return
+	}
+	fleetID := url.PathEscape(mux.Vars(r)["fleetID"])
+	h.proxyRequestWithHeaders(w, r, "GetFleetAccount", h.repairService.URL(), "/fleets/"+fleetID, actor)
+}
+
+// FleetMember sets (PUT) a member's monthly limit and allowed repair types,
+// or removes the member (DELETE)
+func (h *RepairHandler) FleetMember(w http.ResponseWriter, r *http.Request) {
+	actor, ok := h.fleetActor(w, r)
+	if !ok {
+		return
+	}
+	vars := mux.Vars(r)
+	path := "/fleets/" + url.PathEscape(vars["fleetID"]) + "/members/" + url.PathEscape(vars["userID"])
+	h.proxyRequestWithHeaders(w, r, "FleetMember", h.repairService.URL(), path, actor)
+}
+
+// FleetUsage returns each member's spending against their limit in
+// ?month=YYYY-MM, the current month by default
+func (h *RepairHandler) FleetUsage(w http.ResponseWriter, r *http.Request) {
+	actor, ok := h.fleetActor(w, r)
+	if !ok {
+		return
+	}
+	fleetID := url.PathEscape(mux.Vars(r)["fleetID"])
+	h.proxyRequestWithHeaders(w, r, "FleetUsage", h.repairService.URL(), "/fleets/"+fleetID+"/usage", actor)
+}
+
+// FleetInvoices lists a fleet's monthly invoices, latest month first
+func (h *RepairHandler) FleetInvoices(w http.ResponseWriter, r *http.Request) {
+	actor, ok := h.fleetActor(w, r)
+	if !ok {
+		return
+	}
+	fleetID := url.PathEscape(mux.Vars(r)["fleetID"])
+	h.proxyRequestWithHeaders(w, r, "FleetInvoices", h.repairService.URL(), "/fleets/"+fleetID+"/invoices", actor)
+}
+
+// FleetInvoice returns a fleet's invoice for a month (YYYY-MM)
+func (h *RepairHandler) FleetInvoice(w http.ResponseWriter, r *http.Request) {
+	actor, ok := h.fleetActor(w, r)
+	if !ok {
+		return
+	}
+	vars := mux.Vars(r)
+	path := "/fleets/" + url.PathEscape(vars["fleetID"]) + "/invoices/" + url.PathEscape(vars["month"])
+	h.proxyRequestWithHeaders(w, r, "FleetInvoice", h.repairService.URL(), path, actor)
+}
+
+// IssueFleetInvoice issues a fleet's invoice for an ended month now instead
+// of waiting for the invoice job. Only admins holding ADMIN_API_TOKEN may
+// call it.
+func (h *RepairHandler) IssueFleetInvoice(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	vars := mux.Vars(r)
+	path := "/admin/fleets/" + url.PathEscape(vars["fleetID"]) + "/invoices/" + url.PathEscape(vars["month"])
+	h.proxyRequest(w, r, "IssueFleetInvoice", h.repairService.URL(), path)
+}
+
+// fleetActor returns the X-Actor-Role and X-Actor-ID headers of a fleet
+// request: the admin token makes a platform admin, anyone else must name
+// the user they act as, whom the repair service checks against the fleet's
+// admins
+func (h *RepairHandler) fleetActor(w http.ResponseWriter, r *http.Request) (http.Header, bool) {
+	actor := http.Header{}
+	if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		// Only the admin token makes an admin; a wrong token is not downgraded
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if h.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+			h.logger.Warn("Rejected unauthorized fleet request", "path", r.URL.Path, "remoteAddr", r.RemoteAddr)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return nil, false
+		}
+		actor.Set("X-Actor-Role", "admin")
+		return actor, true
+	}
+	if r.Header.Get("X-Actor-Role") != "user" || r.Header.Get("X-Actor-ID") == "" {
+		http.Error(w, "X-Actor-Role must be user with an X-Actor-ID, or use the admin token", http.StatusForbidden)
+		return nil, false
+	}
+	actor.Set("X-Actor-Role", "user")
+	actor.Set("X-Actor-ID", r.Header.Get("X-Actor-ID"))
+	return actor, true
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"api-gateway/slowlog"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// sequencedUpdate is a status update with its position in the update log
+type sequencedUpdate struct {
+	seq    int64
+	update StatusUpdate
+}
+
+// updateLog keeps the most recent status updates that went through the
+// broadcast bus so clients without WebSocket can long-poll for them. Waiters
+// block on notify, which is closed and replaced on every append.
+type updateLog struct {
+	mu      sync.Mutex
+	lastSeq int64
+	recent  []sequencedUpdate
+	size    int
+	notify  chan struct{}
+}
+
+func newUpdateLog(size int) *updateLog {
+	if size < 1 {
+		size = 1
+	}
+	return &updateLog{size: size, notify: make(chan struct{})}
+}
+
+// append records an update and wakes every waiting poll
+func (l *updateLog) append(update StatusUpdate) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastSeq++
+	l.recent = append(l.recent, sequencedUpdate{seq: l.lastSeq, update: update})
+	if len(l.recent) > l.size {
+		l.recent = l.recent[len(l.recent)-l.size:]
+	}
+	close(l.notify)
+	l.notify = make(chan struct{})
+}
+
+// since returns the updates of repairID after cursor, the cursor to poll from
+// next, whether updates after cursor were already evicted, and a channel
+// closed on the next append
+func (l *updateLog) since(repairID string, cursor int64) (updates []StatusUpdate, next int64, gap bool, wait <-chan struct{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if cursor < 0 || cursor > l.lastSeq {
+		cursor = l.lastSeq // unknown cursor, e.g. from before a gateway restart
+	}
+	if len(l.recent) > 0 && l.recent[0].seq > cursor+1 {
+		gap = true
+	}
+	for _, u := range l.recent {
+		if u.seq > cursor && u.update.RepairID == repairID {
+			updates = append(updates, u.update)
+		}
+	}
+	return updates, l.lastSeq, gap, l.notify
+}
+
+// RepairUpdates is the long-poll response for one repair
+type RepairUpdates struct {
+	Updates []StatusUpdate `json:"updates"`
+	Cursor  string         `json:"cursor"`        // pass as ?since= on the next poll
+	Gap     bool           `json:"gap,omitempty"` // updates were missed; refetch the repair
+}
+
+// PollRepairUpdates serves GET /repairs/{repairID}/updates?since=<cursor> for
+// clients that cannot hold a WebSocket. It answers at once when the repair
+// changed after the cursor, otherwise waits up to ?wait= seconds (default and
+// maximum LONGPOLL_MAX_WAIT_SECONDS) and answers with the updates seen or none.
+// Without since, it waits for the next change. Cursors are only valid on the
+// gateway instance that issued them; any other cursor is treated as current.
+func (h *RepairHandler) PollRepairUpdates(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "PollRepairUpdates")
+	defer span.End()
+	slowlog.Untimed(r.Context())
+
+	repairID := mux.Vars(r)["repairID"]
+	cursor := int64(-1)
+	if v := r.URL.Query().Get("since"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			span.SetStatus(codes.Error, "Invalid cursor")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid since cursor"})
+			return
+		}
+		cursor = n
+	}
+	wait := h.longPollMaxWait
+	if v, err := strconv.Atoi(r.URL.Query().Get("wait")); err == nil && v >= 0 && time.Duration(v)*time.Second < wait {
+		wait = time.Duration(v) * time.Second
+	}
+	span.SetAttributes(
+		attribute.String("repairID", repairID),
+		attribute.Int64("cursor", cursor),
+		attribute.Int64("waitSeconds", int64(wait/time.Second)),
+	)
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	updates, next, gap, changed := h.updates.since(repairID, cursor)
+poll:
+	for len(updates) == 0 && !gap {
+		select {
+		case <-changed:
+			updates, next, gap, changed = h.updates.since(repairID, next)
+		case <-timer.C:
+			break poll
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	if updates == nil {
+		updates = []StatusUpdate{}
+	}
+	span.SetAttributes(
+		attribute.Int("updateCount", len(updates)),
+		attribute.Bool("gap", gap),
+	)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(RepairUpdates{Updates: updates, Cursor: strconv.FormatInt(next, 10), Gap: gap})
+}
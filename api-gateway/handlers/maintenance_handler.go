@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"api-gateway/middleware"
+
+	"github.com/hashicorp/consul/api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// ConsulClient returns the Consul client the handler registered with
+func (h *RepairHandler) ConsulClient() *api.Client {
+	return h.consulClient
+}
+
+// SetMaintenance connects the admin maintenance routes to the middleware
+// enforcing the switches
+func (h *RepairHandler) SetMaintenance(m *middleware.Maintenance) {
+	h.maintenance = m
+}
+
+// Maintenance shows (GET), enables (PUT) or lifts (DELETE) maintenance mode
+// for the whole gateway. Only admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) Maintenance(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	if r.Method == http.MethodGet {
+		global, routes := h.maintenance.State()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{"global": global, "routes": routes})
+		return
+	}
+	h.setMaintenanceSwitch(w, r, "")
+}
+
+// RouteMaintenance enables (PUT) or lifts (DELETE) the kill switch of the
+// route template given in ?route=, e.g. ?route=/repairs/{repairID}. Only
+// admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) RouteMaintenance(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	route := r.URL.Query().Get("route")
+	if route == "" {
+		http.Error(w, "route is required", http.StatusBadRequest)
+		return
+	}
+	h.setMaintenanceSwitch(w, r, route)
+}
+
+// setMaintenanceSwitch writes or deletes a switch in Consul KV; every gateway
+// instance picks the change up from there
+func (h *RepairHandler) setMaintenanceSwitch(w http.ResponseWriter, r *http.Request, route string) {
+	_, span := h.tracer.Start(r.Context(), "SetMaintenanceSwitch")
+	defer span.End()
+
+	key := h.maintenance.Prefix() + middleware.MaintenanceKey(route)
+	span.SetAttributes(
+		attribute.String("route", route),
+		attribute.String("method", r.Method),
+		attribute.String("key", key),
+	)
+
+	if r.Method == http.MethodDelete {
+		if _, err := h.consulClient.KV().Delete(key, nil); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to delete maintenance switch")
+			h.logger.Error("Failed to delete maintenance switch", "error", err, "key", key)
+			http.Error(w, "Failed to update maintenance switch", http.StatusBadGateway)
+			return
+		}
+		h.logger.Warn("Lifted maintenance switch", "route", route, "remoteAddr", r.RemoteAddr)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var rule middleware.MaintenanceRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Invalid request body")
+		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	rule.Route = route
+	rule.EnabledAt = time.Now().UTC()
+	value, err := json.Marshal(rule)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to encode maintenance switch")
+		http.Error(w, "Failed to encode maintenance switch", http.StatusInternalServerError)
+		return
+	}
+	if _, err := h.consulClient.KV().Put(&api.KVPair{Key: key, Value: value}, nil); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to write maintenance switch")
+		h.logger.Error("Failed to write maintenance switch", "error", err, "key", key)
+		http.Error(w, "Failed to update maintenance switch", http.StatusBadGateway)
+		return
+	}
+	h.logger.Warn("Enabled maintenance switch", "route", route, "methods", rule.Methods, "until", rule.Until, "enabledBy", rule.EnabledBy, "remoteAddr", r.RemoteAddr)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(rule)
+}
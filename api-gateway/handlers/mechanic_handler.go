@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"api-gateway/middleware"
+
+	"github.com/gorilla/mux"
+)
+
+// ImportMechanics forwards a CSV or JSON mechanic import to mechanic-service.
+// Only admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) ImportMechanics(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "ImportMechanics", h.mechanicService.URL(), "/admin/mechanics/import")
+}
+
+// TapEvents returns the last records of repair-events, or its dead letter
+// topic, decoded by mechanic-service for incident triage. Only admins holding
+// ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) TapEvents(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "TapEvents", h.mechanicService.URL(), "/admin/events/tap")
+}
+
+// AssignRepair forwards a mechanic's claim of a repair to mechanic-service,
+// passing the caller RBAC admitted so mechanics only claim for themselves
+func (h *RepairHandler) AssignRepair(w http.ResponseWriter, r *http.Request) {
+	repairID := url.PathEscape(mux.Vars(r)["repairID"])
+	var actor http.Header
+	if caller, ok := middleware.CallerFrom(r.Context()); ok {
+		actor = caller.Header()
+	}
+	h.proxyRequestWithHeaders(w, r, "AssignRepair", h.mechanicService.URL(), "/repairs/"+repairID+"/assign", actor)
+}
+
+// SearchMechanics returns a page of mechanic-service's mechanic directory,
+// which users browse to choose a mechanic by name, rating, skills and distance
+func (h *RepairHandler) SearchMechanics(w http.ResponseWriter, r *http.Request) {
+	h.proxyRequest(w, r, "SearchMechanics", h.mechanicService.URL(), "/mechanics/search")
+}
+
+// GetPositioningHints returns the busiest demand cells in a mechanic's service
+// area for the current hour
+func (h *RepairHandler) GetPositioningHints(w http.ResponseWriter, r *http.Request) {
+	mechanicID := url.PathEscape(mux.Vars(r)["mechanicID"])
+	h.proxyRequest(w, r, "GetPositioningHints", h.repairService.URL(), "/mechanics/"+mechanicID+"/positioning")
+}
+
+// MechanicAbsences lists (GET) or schedules (POST) a mechanic's absences in
+// mechanic-service
+func (h *RepairHandler) MechanicAbsences(w http.ResponseWriter, r *http.Request) {
+	mechanicID := url.PathEscape(mux.Vars(r)["mechanicID"])
+	h.proxyRequest(w, r, "MechanicAbsences", h.mechanicService.URL(), "/mechanics/"+mechanicID+"/absences")
+}
+
+// DeleteMechanicAbsence cancels one of a mechanic's absences
+func (h *RepairHandler) DeleteMechanicAbsence(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	path := "/mechanics/" + url.PathEscape(vars["mechanicID"]) + "/absences/" + url.PathEscape(vars["absenceID"])
+	h.proxyRequest(w, r, "DeleteMechanicAbsence", h.mechanicService.URL(), path)
+}
+
+// GetMechanicRoute returns a mechanic's open repairs ordered into a route by
+// mechanic-service, with an ETA per stop
+func (h *RepairHandler) GetMechanicRoute(w http.ResponseWriter, r *http.Request) {
+	mechanicID := url.PathEscape(mux.Vars(r)["mechanicID"])
+	h.proxyRequest(w, r, "GetMechanicRoute", h.mechanicService.URL(), "/mechanics/"+mechanicID+"/route")
+}
+
+// UpdateMechanicLocation forwards a position reported by a mechanic's device
+// to mechanic-service, which throttles the events it emits
+func (h *RepairHandler) UpdateMechanicLocation(w http.ResponseWriter, r *http.Request) {
+	mechanicID := url.PathEscape(mux.Vars(r)["mechanicID"])
+	var actor http.Header
+	if caller, ok := middleware.CallerFrom(r.Context()); ok {
+		actor = caller.Header()
+	}
+	h.proxyRequestWithHeaders(w, r, "UpdateMechanicLocation", h.mechanicService.URL(), "/mechanics/"+mechanicID+"/location", actor)
+}
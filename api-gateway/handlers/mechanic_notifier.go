@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// MechanicNotificationPrefs mirrors mechanic-service's domain.NotificationPrefs
+type MechanicNotificationPrefs struct {
+	MechanicID      string `json:"mechanicID"`
+	QuietHoursStart string `json:"quietHoursStart,omitempty"` // "HH:MM" in Timezone
+	QuietHoursEnd   string `json:"quietHoursEnd,omitempty"`
+	Timezone        string `json:"timezone"`
+	DigestFrequency string `json:"digestFrequency"` // off, hourly or daily
+}
+
+// inQuietHours reports whether t falls within the quiet hours, which may wrap
+// past midnight
+func (p *MechanicNotificationPrefs) inQuietHours(t time.Time) bool {
+	if p.QuietHoursStart == "" || p.QuietHoursEnd == "" {
+		return false
+	}
+	start, err1 := time.Parse("15:04", p.QuietHoursStart)
+	end, err2 := time.Parse("15:04", p.QuietHoursEnd)
+	loc, err3 := time.LoadLocation(p.Timezone)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return false
+	}
+	local := t.In(loc)
+	now := local.Hour()*60 + local.Minute()
+	from, to := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	if from <= to {
+		return now >= from && now < to
+	}
+	return now >= from || now < to
+}
+
+// digestInterval is how long non-urgent notifications are batched outside
+// quiet hours; zero delivers them right away
+func (p *MechanicNotificationPrefs) digestInterval() time.Duration {
+	switch p.DigestFrequency {
+	case "hourly":
+		return time.Hour
+	case "daily":
+		return 24 * time.Hour
+	}
+	return 0
+}
+
+// NotificationDigest delivers the non-urgent notifications batched for a
+// mechanic in one WebSocket message
+type NotificationDigest struct {
+	Type        string            `json:"type"` // always "notification_digest"
+	MechanicID  string            `json:"mechanicID"`
+	Count       int               `json:"count"`
+	Dropped     int               `json:"dropped,omitempty"` // oldest items discarded over MECHANIC_DIGEST_MAX_ITEMS
+	Items       []json.RawMessage `json:"items"`
+	Since       time.Time         `json:"since"`
+	GeneratedAt time.Time         `json:"generatedAt"`
+}
+
+// RepairAssignedNotification tells a mechanic a repair was assigned to them.
+// It is urgent and breaks through quiet hours and digests.
+type RepairAssignedNotification struct {
+	Type       string  `json:"type"` // always "repair_assigned"
+	RepairID   string  `json:"repairID"`
+	MechanicID string  `json:"mechanicID"`
+	Status     string  `json:"status"`
+	Latitude   float64 `json:"latitude,omitempty"`
+	Longitude  float64 `json:"longitude,omitempty"`
+}
+
+// pendingDigest is a mechanic's batch of non-urgent notifications
+type pendingDigest struct {
+	items   []json.RawMessage
+	dropped int
+	since   time.Time
+}
+
+// cachedPrefs is a mechanic's preferences as fetched from mechanic-service
+type cachedPrefs struct {
+	prefs     *MechanicNotificationPrefs
+	fetchedAt time.Time
+}
+
+// mechanicNotifier applies mechanics' quiet hours and digest frequency to
+// WebSocket notifications. Digests are held in memory by the gateway the
+// mechanic is connected to.
+type mechanicNotifier struct {
+	mu         sync.Mutex
+	prefs      map[string]cachedPrefs
+	pending    map[string]*pendingDigest
+	lastDigest map[string]time.Time
+	cacheTTL   time.Duration
+	maxItems   int
+}
+
+func newMechanicNotifier(cacheTTL time.Duration, maxItems int) *mechanicNotifier {
+	if maxItems < 1 {
+		maxItems = 1
+	}
+	return &mechanicNotifier{
+		prefs:      make(map[string]cachedPrefs),
+		pending:    make(map[string]*pendingDigest),
+		lastDigest: make(map[string]time.Time),
+		cacheTTL:   cacheTTL,
+		maxItems:   maxItems,
+	}
+}
+
+// forget drops the cached preferences of a mechanic so the next notification refetches them
+func (n *mechanicNotifier) forget(mechanicID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.prefs, mechanicID)
+}
+
+// batch adds a notification to the mechanic's digest, dropping the oldest
+// item once the digest is full
+func (n *mechanicNotifier) batch(mechanicID string, message []byte, now time.Time) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	digest, ok := n.pending[mechanicID]
+	if !ok {
+		digest = &pendingDigest{since: now}
+		n.pending[mechanicID] = digest
+		if _, seen := n.lastDigest[mechanicID]; !seen {
+			n.lastDigest[mechanicID] = now
+		}
+	}
+	if len(digest.items) >= n.maxItems {
+		digest.items = digest.items[1:]
+		digest.dropped++
+	}
+	digest.items = append(digest.items, json.RawMessage(message))
+}
+
+// restore puts back a digest that could not be delivered, ahead of anything
+// batched since it was taken
+func (n *mechanicNotifier) restore(mechanicID string, digest *pendingDigest) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if newer, ok := n.pending[mechanicID]; ok {
+		digest.items = append(digest.items, newer.items...)
+		digest.dropped += newer.dropped
+		if extra := len(digest.items) - n.maxItems; extra > 0 {
+			digest.items = digest.items[extra:]
+			digest.dropped += extra
+		}
+	}
+	n.pending[mechanicID] = digest
+}
+
+// notifyMechanic delivers a notification to the mechanic's WebSocket
+// connections. Urgent notifications always go out at once; others are batched
+// into a digest during quiet hours or when the mechanic chose hourly or daily digests.
+func (h *RepairHandler) notifyMechanic(ctx context.Context, mechanicID string, message []byte, urgent bool) {
+	if urgent {
+		h.deliverToMechanic(mechanicID, message)
+		return
+	}
+	prefs := h.mechanicPrefs(ctx, mechanicID)
+	now := time.Now()
+	if prefs.inQuietHours(now) || prefs.digestInterval() > 0 {
+		h.notifier.batch(mechanicID, message, now)
+		return
+	}
+	h.deliverToMechanic(mechanicID, message)
+}
+
+// deliverToMechanic queues a message on the mechanic's WebSocket connections
+// opened with role=mechanic and returns how many took it
+func (h *RepairHandler) deliverToMechanic(mechanicID string, message []byte) int {
+	h.clientsMutex.Lock()
+	clients := append([]*wsClient(nil), h.clients[mechanicID]...)
+	h.clientsMutex.Unlock()
+	delivered := 0
+	for _, client := range clients {
+		if !client.mechanic {
+			continue
+		}
+		if _, ok := client.enqueue(message, h.overflowPolicy); !ok {
+			h.logger.Warn("WebSocket client closed or disconnected on send queue overflow", "userID", mechanicID)
+			continue
+		}
+		delivered++
+	}
+	return delivered
+}
+
+// mechanicPrefs returns a mechanic's notification preferences, cached for
+// MECHANIC_PREFS_CACHE_SECONDS. When mechanic-service cannot be reached the
+// defaults apply and notifications are delivered right away.
+func (h *RepairHandler) mechanicPrefs(ctx context.Context, mechanicID string) *MechanicNotificationPrefs {
+	h.notifier.mu.Lock()
+	cached, ok := h.notifier.prefs[mechanicID]
+	h.notifier.mu.Unlock()
+	if ok && time.Since(cached.fetchedAt) < h.notifier.cacheTTL {
+		return cached.prefs
+	}
+
+	prefs, err := h.fetchMechanicPrefs(ctx, mechanicID)
+	if err != nil {
+		h.logger.Warn("Failed to fetch notification preferences, using the last known or default ones", "error", err, "mechanicID", mechanicID)
+		if ok {
+			return cached.prefs
+		}
+		return &MechanicNotificationPrefs{MechanicID: mechanicID, Timezone: "UTC", DigestFrequency: "off"}
+	}
+	h.notifier.mu.Lock()
+	h.notifier.prefs[mechanicID] = cachedPrefs{prefs: prefs, fetchedAt: time.Now()}
+	h.notifier.mu.Unlock()
+	return prefs
+}
+
+// fetchMechanicPrefs retrieves a mechanic's notification preferences from mechanic-service
+func (h *RepairHandler) fetchMechanicPrefs(ctx context.Context, mechanicID string) (*MechanicNotificationPrefs, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", h.mechanicService.URL()+"/mechanics/"+url.PathEscape(mechanicID)+"/notification-preferences", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to contact mechanic service: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("mechanic service returned status %d", resp.StatusCode)
+	}
+
+	prefs := &MechanicNotificationPrefs{}
+	if err := json.NewDecoder(resp.Body).Decode(prefs); err != nil {
+		return nil, fmt.Errorf("failed to decode notification preferences: %w", err)
+	}
+	return prefs, nil
+}
+
+// runNotificationDigests delivers batched notifications once a mechanic's
+// quiet hours are over and their digest interval has passed. Digests of
+// mechanics who are not connected wait until they are.
+func (h *RepairHandler) runNotificationDigests(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.notifier.mu.Lock()
+		mechanicIDs := make([]string, 0, len(h.notifier.pending))
+		for id := range h.notifier.pending {
+			mechanicIDs = append(mechanicIDs, id)
+		}
+		h.notifier.mu.Unlock()
+
+		for _, id := range mechanicIDs {
+			h.flushDigest(context.Background(), id)
+		}
+	}
+}
+
+// flushDigest delivers a mechanic's digest if it is due
+func (h *RepairHandler) flushDigest(ctx context.Context, mechanicID string) {
+	ctx, span := h.tracer.Start(ctx, "FlushNotificationDigest")
+	defer span.End()
+	span.SetAttributes(attribute.String("mechanicID", mechanicID))
+
+	prefs := h.mechanicPrefs(ctx, mechanicID)
+	now := time.Now()
+	if prefs.inQuietHours(now) {
+		return
+	}
+
+	h.notifier.mu.Lock()
+	digest := h.notifier.pending[mechanicID]
+	due := digest != nil && now.Sub(h.notifier.lastDigest[mechanicID]) >= prefs.digestInterval()
+	if due {
+		delete(h.notifier.pending, mechanicID)
+	}
+	h.notifier.mu.Unlock()
+	if !due {
+		return
+	}
+
+	message, err := json.Marshal(NotificationDigest{
+		Type:        "notification_digest",
+		MechanicID:  mechanicID,
+		Count:       len(digest.items),
+		Dropped:     digest.dropped,
+		Items:       digest.items,
+		Since:       digest.since,
+		GeneratedAt: now.UTC(),
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to marshal notification digest")
+		h.logger.Error("Failed to marshal notification digest", "error", err, "mechanicID", mechanicID)
+		h.notifier.restore(mechanicID, digest)
+		return
+	}
+	if h.deliverToMechanic(mechanicID, message) == 0 {
+		h.notifier.restore(mechanicID, digest)
+		return
+	}
+
+	h.notifier.mu.Lock()
+	h.notifier.lastDigest[mechanicID] = now
+	h.notifier.mu.Unlock()
+	span.SetAttributes(attribute.Int("itemCount", len(digest.items)))
+	h.logger.Info("Delivered notification digest", "mechanicID", mechanicID, "items", len(digest.items), "dropped", digest.dropped)
+}
+
+// runUrgentMechanicNotifications pushes assignments from the ops feed to the
+// assigned mechanic at once, regardless of quiet hours and digests
+func (h *RepairHandler) runUrgentMechanicNotifications() {
+	events, _ := h.ops.subscribe(math.MaxInt64)
+	defer h.ops.unsubscribe(events)
+	for event := range events {
+		if event.Type != OpsRepairAssigned {
+			continue
+		}
+		mechanicID, _ := event.Data["mechanicID"].(string)
+		if mechanicID == "" {
+			continue
+		}
+		notification := RepairAssignedNotification{Type: "repair_assigned", MechanicID: mechanicID}
+		notification.RepairID, _ = event.Data["repairID"].(string)
+		notification.Status, _ = event.Data["status"].(string)
+		notification.Latitude, _ = event.Data["latitude"].(float64)
+		notification.Longitude, _ = event.Data["longitude"].(float64)
+		message, err := json.Marshal(notification)
+		if err != nil {
+			h.logger.Error("Failed to marshal assignment notification", "error", err, "mechanicID", mechanicID)
+			continue
+		}
+		h.notifyMechanic(context.Background(), mechanicID, message, true)
+	}
+}
+
+// MechanicNotificationPreferences forwards a mechanic's notification
+// preferences request (GET or PUT) to mechanic-service
+func (h *RepairHandler) MechanicNotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	mechanicID := mux.Vars(r)["mechanicID"]
+	h.proxyRequest(w, r, "MechanicNotificationPreferences", h.mechanicService.URL(), "/mechanics/"+url.PathEscape(mechanicID)+"/notification-preferences")
+	if r.Method == http.MethodPut {
+		h.notifier.forget(mechanicID)
+	}
+}
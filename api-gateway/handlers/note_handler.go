@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+)
+
+// ListRepairs lists repairs for staff a page at a time (limit and offset),
+// filtered by ?status=, ?repairType=, ?userID=, ?tag= (repeatable) and ?bbox=;
+// fields, sort and readPreference narrow the result. Only admins holding
+// ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) ListRepairs(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "ListRepairs", h.repairService.URL(), "/repairs")
+}
+
+// RepairTags lists (GET) a repair's tags. Admin only.
+func (h *RepairHandler) RepairTags(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	repairID := url.PathEscape(mux.Vars(r)["repairID"])
+	h.proxyRequest(w, r, "RepairTags", h.repairService.URL(), "/admin/repairs/"+repairID+"/tags")
+}
+
+// RepairTag adds (PUT) or removes (DELETE) a repair tag. Admin only.
+func (h *RepairHandler) RepairTag(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	vars := mux.Vars(r)
+	path := "/admin/repairs/" + url.PathEscape(vars["repairID"]) + "/tags/" + url.PathEscape(vars["tag"])
+	h.proxyRequest(w, r, "RepairTag", h.repairService.URL(), path)
+}
+
+// RepairNotes lists (GET) or adds (POST) internal staff notes on a repair.
+// Notes are only served on these admin routes, never with the repair itself.
+func (h *RepairHandler) RepairNotes(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	repairID := url.PathEscape(mux.Vars(r)["repairID"])
+	h.proxyRequest(w, r, "RepairNotes", h.repairService.URL(), "/admin/repairs/"+repairID+"/notes")
+}
+
+// DeleteRepairNote removes an internal note. Admin only.
+func (h *RepairHandler) DeleteRepairNote(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	vars := mux.Vars(r)
+	path := "/admin/repairs/" + url.PathEscape(vars["repairID"]) + "/notes/" + url.PathEscape(vars["noteID"])
+	h.proxyRequest(w, r, "DeleteRepairNote", h.repairService.URL(), path)
+}
@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// offlineQueueTimeout bounds each offline queue operation
+const offlineQueueTimeout = 5 * time.Second
+
+// wsAck is sent by WebSocket clients to confirm every status update up to Seq
+type wsAck struct {
+	Type string `json:"type"` // always "ack"
+	Seq  int64  `json:"seq"`
+}
+
+// undeliveredMessage is a status update kept in ws_undelivered until its user
+// acknowledges it or it expires
+type undeliveredMessage struct {
+	UserID    string    `bson:"userID"`
+	Seq       int64     `bson:"seq"`
+	Key       string    `bson:"key,omitempty"`
+	Message   string    `bson:"message"` // the status update as sent on the WebSocket
+	CreatedAt time.Time `bson:"createdAt"`
+}
+
+// offlineQueue keeps every status update per user, numbered in order, until
+// the user's client acknowledges it, so updates sent while the user is
+// disconnected are replayed when they reconnect. Messages left unacknowledged
+// expire after WS_OFFLINE_TTL_HOURS. A nil queue keeps nothing.
+type offlineQueue struct {
+	messages    *mongo.Collection // ws_undelivered
+	sequences   *mongo.Collection // ws_sequences, the last seq per user
+	replayLimit int
+	logger      *slog.Logger
+}
+
+func newOfflineQueue(db *mongo.Database, replayLimit int, logger *slog.Logger) *offlineQueue {
+	return &offlineQueue{
+		messages:    db.Collection("ws_undelivered"),
+		sequences:   db.Collection("ws_sequences"),
+		replayLimit: replayLimit,
+		logger:      logger,
+	}
+}
+
+// store numbers update with the user's next seq and keeps it until acked. ETA
+// events are broadcast by every gateway instance, so they are stored once per
+// event and the other instances get the seq already assigned.
+func (q *offlineQueue) store(ctx context.Context, update *StatusUpdate) error {
+	if q == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, offlineQueueTimeout)
+	defer cancel()
+
+	key := ""
+	if e := update.ETA; e != nil {
+		key = fmt.Sprintf("eta:%s:%s:%d:%d:%d", e.RepairID, e.Type, e.CommittedETA.Unix(), e.MinutesLeft, e.MinutesLate)
+		var existing undeliveredMessage
+		err := q.messages.FindOne(ctx, bson.M{"userID": update.UserID, "key": key}).Decode(&existing)
+		if err == nil {
+			update.Seq = existing.Seq
+			return nil
+		}
+		if err != mongo.ErrNoDocuments {
+			return fmt.Errorf("failed to look up stored ETA event: %w", err)
+		}
+	}
+
+	var counter struct {
+		Seq int64 `bson:"seq"`
+	}
+	err := q.sequences.FindOneAndUpdate(ctx,
+		bson.M{"_id": update.UserID},
+		bson.M{"$inc": bson.M{"seq": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return fmt.Errorf("failed to number status update: %w", err)
+	}
+	update.Seq = counter.Seq
+
+	message, err := json.Marshal(update)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status update: %w", err)
+	}
+	_, err = q.messages.InsertOne(ctx, undeliveredMessage{
+		UserID:    update.UserID,
+		Seq:       update.Seq,
+		Key:       key,
+		Message:   string(message),
+		CreatedAt: time.Now(),
+	})
+	if mongo.IsDuplicateKeyError(err) && key != "" {
+		// Another instance stored the same ETA event first
+		var existing undeliveredMessage
+		if err := q.messages.FindOne(ctx, bson.M{"userID": update.UserID, "key": key}).Decode(&existing); err == nil {
+			update.Seq = existing.Seq
+		}
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to store status update: %w", err)
+	}
+	return nil
+}
+
+// pending returns the user's unacknowledged status updates after seq, oldest
+// first, at most replayLimit of them
+func (q *offlineQueue) pending(ctx context.Context, userID string, after int64) ([]undeliveredMessage, error) {
+	if q == nil {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, offlineQueueTimeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}})
+	if q.replayLimit > 0 {
+		opts.SetLimit(int64(q.replayLimit))
+	}
+	cursor, err := q.messages.Find(ctx, bson.M{"userID": userID, "seq": bson.M{"$gt": after}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load undelivered messages: %w", err)
+	}
+	var messages []undeliveredMessage
+	if err := cursor.All(ctx, &messages); err != nil {
+		return nil, fmt.Errorf("failed to decode undelivered messages: %w", err)
+	}
+	return messages, nil
+}
+
+// ack removes the user's status updates up to and including seq
+func (q *offlineQueue) ack(ctx context.Context, userID string, seq int64) (int64, error) {
+	if q == nil || seq <= 0 {
+		return 0, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, offlineQueueTimeout)
+	defer cancel()
+
+	result, err := q.messages.DeleteMany(ctx, bson.M{"userID": userID, "seq": bson.M{"$lte": seq}})
+	if err != nil {
+		return 0, fmt.Errorf("failed to trim undelivered messages: %w", err)
+	}
+	return result.DeletedCount, nil
+}
+
+// replayOffline queues the user's unacknowledged status updates after seq on
+// a client that is registered but whose writer has not started, so they go
+// out before any live update. Live updates already queued are skipped by the
+// writer when the replay covered them.
+func (h *RepairHandler) replayOffline(ctx context.Context, userID string, client *wsClient, after int64) {
+	if after > 0 {
+		if _, err := h.offline.ack(ctx, userID, after); err != nil {
+			h.logger.Warn("Failed to trim acknowledged messages", "error", err, "userID", userID, "seq", after)
+		}
+	}
+	messages, err := h.offline.pending(ctx, userID, after)
+	if err != nil {
+		h.logger.Error("Failed to replay undelivered messages", "error", err, "userID", userID)
+		return
+	}
+	client.backlog = make([]wsMessage, len(messages))
+	for i, m := range messages {
+		client.backlog[i] = wsMessage{data: []byte(m.Message), seq: m.Seq}
+	}
+	if len(messages) > 0 {
+		h.logger.Info("Replaying undelivered messages", "userID", userID, "count", len(messages), "fromSeq", messages[0].Seq, "toSeq", messages[len(messages)-1].Seq)
+	}
+}
+
+// handleClientMessage applies a message read from a user's WebSocket; only
+// acks are understood, anything else is ignored
+func (h *RepairHandler) handleClientMessage(ctx context.Context, userID string, data []byte) {
+	var ack wsAck
+	if err := json.Unmarshal(data, &ack); err != nil || ack.Type != "ack" {
+		return
+	}
+	trimmed, err := h.offline.ack(ctx, userID, ack.Seq)
+	if err != nil {
+		h.logger.Warn("Failed to apply WebSocket ack", "error", err, "userID", userID, "seq", ack.Seq)
+		return
+	}
+	h.logger.Debug("Applied WebSocket ack", "userID", userID, "seq", ack.Seq, "trimmed", trimmed)
+}
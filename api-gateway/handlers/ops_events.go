@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Ops event types streamed on /admin/events
+const (
+	OpsRepairCreated       = "repair_created"
+	OpsRepairAssigned      = "repair_assigned"
+	OpsRepairStatusChanged = "repair_status_changed"
+	OpsRepairRunningLate   = "repair_running_late"
+	OpsSLABreach           = "sla_breach"
+	OpsOutboxStuck         = "outbox_stuck"
+	OpsConsumerLag         = "consumer_lag"
+)
+
+// opsHeartbeatInterval keeps idle SSE connections open through proxies
+const opsHeartbeatInterval = 15 * time.Second
+
+// OpsEvent is a significant system event for the operations feed
+type OpsEvent struct {
+	ID      int64          `json:"id"`
+	Type    string         `json:"type"`
+	Time    time.Time      `json:"time"`
+	Message string         `json:"message"`
+	Data    map[string]any `json:"data,omitempty"`
+}
+
+// opsFeed fans ops events out to SSE subscribers and keeps the most recent
+// ones so reconnecting clients can resume from Last-Event-ID
+type opsFeed struct {
+	mu          sync.Mutex
+	nextID      int64
+	recent      []OpsEvent
+	replaySize  int
+	subscribers map[chan OpsEvent]struct{}
+}
+
+func newOpsFeed(replaySize int) *opsFeed {
+	return &opsFeed{replaySize: replaySize, subscribers: make(map[chan OpsEvent]struct{})}
+}
+
+// publish stamps the event and delivers it to every subscriber. Subscribers
+// that are not keeping up miss the event rather than blocking the publisher.
+func (f *opsFeed) publish(event OpsEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	event.ID = f.nextID
+	event.Time = time.Now().UTC()
+	f.recent = append(f.recent, event)
+	if len(f.recent) > f.replaySize {
+		f.recent = f.recent[len(f.recent)-f.replaySize:]
+	}
+	for ch := range f.subscribers {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// subscribe registers a subscriber and returns the buffered events after lastID
+func (f *opsFeed) subscribe(lastID int64) (chan OpsEvent, []OpsEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	ch := make(chan OpsEvent, 64)
+	f.subscribers[ch] = struct{}{}
+	var replay []OpsEvent
+	for _, event := range f.recent {
+		if event.ID > lastID {
+			replay = append(replay, event)
+		}
+	}
+	return ch, replay
+}
+
+func (f *opsFeed) unsubscribe(ch chan OpsEvent) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.subscribers, ch)
+}
+
+// StreamOpsEvents streams the live operations feed as Server-Sent Events.
+// ?types=sla_breach,outbox_stuck limits the event types; Last-Event-ID resumes
+// after a reconnect. Only admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) StreamOpsEvents(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "StreamOpsEvents")
+	defer span.End()
+
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		span.SetStatus(codes.Error, "Streaming unsupported")
+		h.logger.Error("Response writer does not support flushing, cannot stream ops events")
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	types := make(map[string]bool)
+	for _, t := range strings.Split(r.URL.Query().Get("types"), ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			types[t] = true
+		}
+	}
+	lastID, _ := strconv.ParseInt(r.Header.Get("Last-Event-ID"), 10, 64)
+	span.SetAttributes(
+		attribute.Int("typeFilterCount", len(types)),
+		attribute.Int64("lastEventID", lastID),
+	)
+
+	events, replay := h.ops.subscribe(lastID)
+	defer h.ops.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	h.logger.Info("Ops event stream opened", "lastEventID", lastID, "replayed", len(replay))
+
+	send := func(event OpsEvent) error {
+		if len(types) > 0 && !types[event.Type] {
+			return nil
+		}
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("failed to marshal ops event: %w", err)
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	for _, event := range replay {
+		if err := send(event); err != nil {
+			span.RecordError(err)
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(opsHeartbeatInterval)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			h.logger.Info("Ops event stream closed")
+			return
+		case event := <-events:
+			if err := send(event); err != nil {
+				span.RecordError(err)
+				h.logger.Info("Ops event stream write failed, closing", "error", err)
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
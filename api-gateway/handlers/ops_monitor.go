@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"api-gateway/telemetry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// repairChange is the part of a repairs change stream event the ops feed uses
+type repairChange struct {
+	OperationType string `bson:"operationType"`
+	FullDocument  struct {
+		ID         string `bson:"_id"`
+		UserID     string `bson:"userID"`
+		Status     string `bson:"status"`
+		AssignedTo string `bson:"assignedTo"`
+		RepairCost struct {
+			RepairType   string    `bson:"repairType"`
+			UserLocation *Location `bson:"userLocation"`
+		} `bson:"repairCost"`
+		ETAEvent *ETAEvent `bson:"etaEvent"` // latest ETA countdown or late event
+	} `bson:"fullDocument"`
+	UpdateDescription struct {
+		UpdatedFields bson.M `bson:"updatedFields"`
+	} `bson:"updateDescription"`
+}
+
+// connectMongo connects to the repairdb database at MONGO_URI
+func (h *RepairHandler) connectMongo() (*mongo.Database, error) {
+	mongoURI := os.Getenv("MONGO_URI")
+	if mongoURI == "" {
+		mongoURI = "mongodb://mongodb:27017/repairdb?replicaSet=rs0"
+	}
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(mongoURI).SetMonitor(telemetry.MongoMonitor(h.slow.MongoMonitor())))
+	if err != nil {
+		return nil, err
+	}
+	return client.Database("repairdb"), nil
+}
+
+// startOpsMonitors feeds the ops event stream: a change stream on repairs for
+// creations and assignments, and a periodic check for SLA breaches, stuck
+// outboxes and consumer lag. The change stream also carries mechanic-service's
+// ETA events to users. Every gateway instance runs its own monitors.
+func (h *RepairHandler) startOpsMonitors(db *mongo.Database) {
+	go h.watchRepairChanges(db.Collection("repairs"))
+	go h.runOpsChecks(db, time.Duration(envInt("OPS_MONITOR_INTERVAL_SECONDS", 30))*time.Second)
+}
+
+// watchRepairChanges publishes repair creations, status changes and
+// assignments, and broadcasts ETA events to the repair's user, resuming the
+// change stream after errors
+func (h *RepairHandler) watchRepairChanges(repairs *mongo.Collection) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"$or": bson.A{
+			bson.M{"operationType": "insert"},
+			bson.M{"operationType": "update", "updateDescription.updatedFields.assignedTo": bson.M{"$exists": true}},
+			bson.M{"operationType": "update", "updateDescription.updatedFields.status": bson.M{"$exists": true}},
+			bson.M{"operationType": "update", "updateDescription.updatedFields.etaEvent": bson.M{"$exists": true}},
+		}}}},
+	}
+	var resumeToken bson.Raw
+	for {
+		opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+		if resumeToken != nil {
+			opts.SetResumeAfter(resumeToken)
+		}
+		stream, err := repairs.Watch(context.Background(), pipeline, opts)
+		if err != nil {
+			h.logger.Error("Failed to watch repairs for ops feed, retrying", "error", err)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		for stream.Next(context.Background()) {
+			resumeToken = stream.ResumeToken()
+			var change repairChange
+			if err := stream.Decode(&change); err != nil {
+				h.logger.Error("Failed to decode repair change", "error", err)
+				continue
+			}
+			doc := change.FullDocument
+			data := map[string]any{"repairID": doc.ID, "userID": doc.UserID, "status": doc.Status}
+			if loc := doc.RepairCost.UserLocation; loc != nil {
+				data["latitude"], data["longitude"] = loc.Latitude, loc.Longitude
+			}
+			if change.OperationType == "insert" {
+				data["repairType"] = doc.RepairCost.RepairType
+				h.ops.publish(OpsEvent{Type: OpsRepairCreated, Message: fmt.Sprintf("Repair %s created", doc.ID), Data: data})
+				continue
+			}
+			updated := change.UpdateDescription.UpdatedFields
+			if _, ok := updated["assignedTo"]; ok && doc.AssignedTo != "" {
+				data["mechanicID"] = doc.AssignedTo
+				h.ops.publish(OpsEvent{Type: OpsRepairAssigned, Message: fmt.Sprintf("Repair %s assigned to %s", doc.ID, doc.AssignedTo), Data: data})
+			}
+			if _, ok := updated["status"]; ok {
+				h.ops.publish(OpsEvent{Type: OpsRepairStatusChanged, Message: fmt.Sprintf("Repair %s is now %s", doc.ID, doc.Status), Data: data})
+			}
+			if _, ok := updated["etaEvent"]; ok && doc.ETAEvent != nil {
+				h.broadcastETAEvent(doc.UserID, doc.Status, doc.ETAEvent, data)
+			}
+		}
+		if err := stream.Err(); err != nil {
+			h.logger.Error("Repair change stream for ops feed failed, resuming", "error", err)
+		}
+		stream.Close(context.Background())
+		time.Sleep(time.Second)
+	}
+}
+
+// broadcastETAEvent tells the user their mechanic is about to arrive or is
+// running late; late events also go to the ops feed
+func (h *RepairHandler) broadcastETAEvent(userID, status string, event *ETAEvent, data map[string]any) {
+	switch event.Type {
+	case ETACountdown:
+		event.Message = fmt.Sprintf("Your mechanic arrives in about %d minutes", event.MinutesLeft)
+	case ETALate:
+		event.Message = "Your mechanic is running late"
+		if event.MinutesLate > 0 {
+			event.Message = fmt.Sprintf("Your mechanic is running %d minutes late", event.MinutesLate)
+		}
+		data["mechanicID"] = event.MechanicID
+		data["minutesLate"] = event.MinutesLate
+		h.ops.publish(OpsEvent{Type: OpsRepairRunningLate, Message: fmt.Sprintf("Mechanic %s is %d minutes late for repair %s", event.MechanicID, event.MinutesLate, event.RepairID), Data: data})
+	default:
+		return
+	}
+	h.enqueueBroadcast(context.Background(), broadcastJob{update: StatusUpdate{
+		RepairID: event.RepairID,
+		UserID:   userID,
+		Status:   status,
+		ETA:      event,
+	}})
+}
+
+// runOpsChecks periodically looks for conditions operations must act on. Each
+// condition is published once when it starts, not on every check.
+func (h *RepairHandler) runOpsChecks(db *mongo.Database, interval time.Duration) {
+	slaLimit := time.Duration(envInt("OPS_SLA_UNASSIGNED_SECONDS", 900)) * time.Second
+	outboxLimit := time.Duration(envInt("OPS_OUTBOX_STUCK_SECONDS", 120)) * time.Second
+	lagWarn := int64(envInt("OPS_CONSUMER_LAG_WARN", 1000))
+	h.logger.Info("Ops monitors started", "interval", interval, "slaLimit", slaLimit, "outboxLimit", outboxLimit, "lagWarn", lagWarn)
+
+	breached := make(map[string]bool)
+	stuck := make(map[string]bool)
+	lagging := false
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		breached = h.checkSLABreaches(ctx, db.Collection("repairs"), slaLimit, breached)
+		for _, name := range []string{"repair_outbox", "mechanic_outbox"} {
+			stuck[name] = h.checkOutboxStuck(ctx, db.Collection(name), outboxLimit, stuck[name])
+		}
+		lagging = h.checkConsumerLag(ctx, lagWarn, lagging)
+		cancel()
+	}
+}
+
+// checkSLABreaches publishes pending repairs left unassigned longer than limit.
+// It returns the breached repair IDs so each one is only reported once.
+func (h *RepairHandler) checkSLABreaches(ctx context.Context, repairs *mongo.Collection, limit time.Duration, reported map[string]bool) map[string]bool {
+	filter := bson.M{
+		"status":     "pending",
+		"assignedTo": bson.M{"$in": bson.A{nil, ""}},
+		"createdAt":  bson.M{"$lt": time.Now().Add(-limit)},
+	}
+	cursor, err := repairs.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1, "userID": 1, "createdAt": 1}))
+	if err != nil {
+		h.logger.Error("Failed to check repair SLAs", "error", err)
+		return reported
+	}
+	var overdue []struct {
+		ID        string    `bson:"_id"`
+		UserID    string    `bson:"userID"`
+		CreatedAt time.Time `bson:"createdAt"`
+	}
+	if err := cursor.All(ctx, &overdue); err != nil {
+		h.logger.Error("Failed to decode overdue repairs", "error", err)
+		return reported
+	}
+
+	current := make(map[string]bool, len(overdue))
+	for _, repair := range overdue {
+		current[repair.ID] = true
+		if reported[repair.ID] {
+			continue
+		}
+		waiting := time.Since(repair.CreatedAt).Round(time.Second)
+		h.ops.publish(OpsEvent{
+			Type:    OpsSLABreach,
+			Message: fmt.Sprintf("Repair %s unassigned for %s", repair.ID, waiting),
+			Data:    map[string]any{"repairID": repair.ID, "userID": repair.UserID, "waitingSeconds": int64(waiting.Seconds())},
+		})
+	}
+	return current
+}
+
+// checkOutboxStuck publishes when unprocessed events older than limit appear
+// in an outbox and returns whether the outbox is currently stuck
+func (h *RepairHandler) checkOutboxStuck(ctx context.Context, outbox *mongo.Collection, limit time.Duration, wasStuck bool) bool {
+	count, err := outbox.CountDocuments(ctx, bson.M{"processed": false, "created_at": bson.M{"$lt": time.Now().Add(-limit)}})
+	if err != nil {
+		h.logger.Error("Failed to check outbox", "error", err, "outbox", outbox.Name())
+		return wasStuck
+	}
+	if count > 0 && !wasStuck {
+		h.ops.publish(OpsEvent{
+			Type:    OpsOutboxStuck,
+			Message: fmt.Sprintf("%d events in %s unprocessed for over %s", count, outbox.Name(), limit),
+			Data:    map[string]any{"outbox": outbox.Name(), "stuckEvents": count},
+		})
+	}
+	return count > 0
+}
+
+// checkConsumerLag publishes when mechanic-service's consumer lag crosses the
+// warning threshold and returns whether it is currently above it
+func (h *RepairHandler) checkConsumerLag(ctx context.Context, warn int64, wasLagging bool) bool {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.mechanicService.URL()+"/metrics/consumer-lag", nil)
+	if err != nil {
+		h.logger.Error("Failed to create consumer lag request", "error", err)
+		return wasLagging
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		h.logger.Error("Failed to fetch consumer lag", "error", err)
+		return wasLagging
+	}
+	defer resp.Body.Close()
+	var lag struct {
+		Topic    string `json:"topic"`
+		TotalLag int64  `json:"totalLag"`
+	}
+	if resp.StatusCode != http.StatusOK {
+		h.logger.Error("Consumer lag endpoint returned an error", "statusCode", resp.StatusCode)
+		return wasLagging
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&lag); err != nil {
+		h.logger.Error("Failed to decode consumer lag", "error", err)
+		return wasLagging
+	}
+	lagging := lag.TotalLag >= warn
+	if lagging && !wasLagging {
+		h.ops.publish(OpsEvent{
+			Type:    OpsConsumerLag,
+			Message: fmt.Sprintf("mechanic-service is %d messages behind on %s", lag.TotalLag, lag.Topic),
+			Data:    map[string]any{"service": "mechanic-service", "topic": lag.Topic, "totalLag": lag.TotalLag},
+		})
+	}
+	return lagging
+}
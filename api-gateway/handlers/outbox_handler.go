@@ -0,0 +1,21 @@
+package handlers
+
+import "net/http"
+
+// RedriveOutbox forwards an outbox redrive to repair-service. Only admins
+// holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) RedriveOutbox(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "RedriveOutbox", h.repairService.URL(), "/admin/outbox/redrive")
+}
+
+// OutboxRedrives lists the audit records of past outbox redrives. Only admins
+// holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) OutboxRedrives(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "OutboxRedrives", h.repairService.URL(), "/admin/outbox/redrives")
+}
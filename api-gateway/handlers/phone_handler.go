@@ -0,0 +1,27 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+)
+
+// GetPhoneVerification reports whether a user's phone number is verified
+func (h *RepairHandler) GetPhoneVerification(w http.ResponseWriter, r *http.Request) {
+	userID := url.PathEscape(mux.Vars(r)["userID"])
+	h.proxyRequest(w, r, "GetPhoneVerification", h.repairService.URL(), "/users/"+userID+"/phone")
+}
+
+// StartPhoneVerification texts a one-time code to the phone number in the body
+func (h *RepairHandler) StartPhoneVerification(w http.ResponseWriter, r *http.Request) {
+	userID := url.PathEscape(mux.Vars(r)["userID"])
+	h.proxyRequest(w, r, "StartPhoneVerification", h.repairService.URL(), "/users/"+userID+"/phone/verification")
+}
+
+// ConfirmPhoneVerification verifies the phone number with the texted code;
+// repairs can only be created once it succeeds
+func (h *RepairHandler) ConfirmPhoneVerification(w http.ResponseWriter, r *http.Request) {
+	userID := url.PathEscape(mux.Vars(r)["userID"])
+	h.proxyRequest(w, r, "ConfirmPhoneVerification", h.repairService.URL(), "/users/"+userID+"/phone/verification/confirm")
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// DemandCell is a geohash cell ranked by recent demand, as returned by repair-service
+type DemandCell struct {
+	Geohash        string  `json:"geohash"`
+	Demand         int     `json:"demand"`
+	Latitude       float64 `json:"latitude"`
+	Longitude      float64 `json:"longitude"`
+	DistanceMeters float64 `json:"distanceMeters"`
+}
+
+// PositioningHint is pushed to idle mechanics on the WebSocket, suggesting
+// where to wait for work in the current hour
+type PositioningHint struct {
+	Type         string       `json:"type"` // always "positioning_hint"
+	MechanicID   string       `json:"mechanicID"`
+	Idle         bool         `json:"idle"`
+	Hour         int          `json:"hour"`
+	LookbackDays int          `json:"lookbackDays"`
+	RadiusMeters float64      `json:"radiusMeters"`
+	Cells        []DemandCell `json:"cells"`
+	GeneratedAt  time.Time    `json:"generatedAt"`
+}
+
+// runPositioningHints pushes positioning hints to every mechanic connected
+// with role=mechanic once per interval. Busy mechanics and mechanics without
+// nearby demand get nothing.
+func (h *RepairHandler) runPositioningHints(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.clientsMutex.Lock()
+		var mechanicIDs []string
+		for id, clients := range h.clients {
+			for _, c := range clients {
+				if c.mechanic {
+					mechanicIDs = append(mechanicIDs, id)
+					break
+				}
+			}
+		}
+		h.clientsMutex.Unlock()
+
+		for _, id := range mechanicIDs {
+			h.pushPositioningHint(context.Background(), id)
+		}
+	}
+}
+
+// pushPositioningHint fetches a mechanic's hints from repair-service and
+// notifies the mechanic
+func (h *RepairHandler) pushPositioningHint(ctx context.Context, mechanicID string) {
+	ctx, span := h.tracer.Start(ctx, "PushPositioningHint")
+	defer span.End()
+	span.SetAttributes(attribute.String("mechanicID", mechanicID))
+
+	hint, err := h.fetchPositioningHint(ctx, mechanicID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to fetch positioning hints")
+		h.logger.Warn("Failed to fetch positioning hints", "error", err, "mechanicID", mechanicID)
+		return
+	}
+	span.SetAttributes(attribute.Bool("idle", hint.Idle), attribute.Int("cellCount", len(hint.Cells)))
+	if !hint.Idle || len(hint.Cells) == 0 {
+		return
+	}
+
+	message, err := json.Marshal(hint)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to marshal positioning hint")
+		h.logger.Error("Failed to marshal positioning hint", "error", err)
+		return
+	}
+	// Hints are not urgent; quiet hours and digests apply
+	h.notifyMechanic(ctx, mechanicID, message, false)
+}
+
+// fetchPositioningHint retrieves a mechanic's positioning hints from repair-service
+func (h *RepairHandler) fetchPositioningHint(ctx context.Context, mechanicID string) (*PositioningHint, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", h.repairService.URL()+"/mechanics/"+url.PathEscape(mechanicID)+"/positioning", nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to contact repair service: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("repair service returned status %d", resp.StatusCode)
+	}
+
+	hint := &PositioningHint{}
+	if err := json.NewDecoder(resp.Body).Decode(hint); err != nil {
+		return nil, fmt.Errorf("failed to decode positioning hints: %w", err)
+	}
+	hint.Type = "positioning_hint"
+	return hint, nil
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/consul/api"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// PresenceKVPrefix is the Consul KV prefix holding WebSocket presence. Each
+// gateway instance writes presence/<escaped userID>/<gatewayID> while the user has at
+// least one connection to it; the keys are bound to a TTL session so they
+// disappear if the gateway dies. Services treat a user as connected when any
+// key exists under presence/<escaped userID>/. User IDs are escaped with
+// url.PathEscape so they cannot add key segments.
+const PresenceKVPrefix = "presence/"
+
+// presenceRecord is the value stored for a connected user on one gateway
+type presenceRecord struct {
+	UserID      string    `json:"userID"`
+	Gateway     string    `json:"gateway"`
+	Connections int       `json:"connections"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// PresenceStatus answers whether a user or mechanic is reachable over WebSocket
+type PresenceStatus struct {
+	UserID           string   `json:"userID"`
+	Connected        bool     `json:"connected"`
+	LocalConnections int      `json:"localConnections"`
+	Gateways         []string `json:"gateways,omitempty"`
+}
+
+// startPresence creates the Consul session that owns this gateway's presence
+// keys and keeps it alive. Presence falls back to the local clients map if
+// Consul is unavailable.
+func (h *RepairHandler) startPresence() {
+	gatewayID, err := os.Hostname()
+	if err != nil || gatewayID == "" {
+		gatewayID = "api-gateway"
+	}
+	h.gatewayID = gatewayID
+
+	ttl := time.Duration(envInt("PRESENCE_SESSION_TTL_SECONDS", 30)) * time.Second
+	sessionID, _, err := h.consulClient.Session().Create(&api.SessionEntry{
+		Name:     "api-gateway-presence-" + gatewayID,
+		TTL:      ttl.String(),
+		Behavior: api.SessionBehaviorDelete,
+	}, nil)
+	if err != nil {
+		h.logger.Error("Failed to create presence session, presence is local only", "error", err)
+		return
+	}
+	h.presenceSession = sessionID
+	h.logger.Info("Presence session created", "sessionID", sessionID, "gateway", gatewayID, "ttl", ttl)
+
+	go func() {
+		if err := h.consulClient.Session().RenewPeriodic(ttl.String(), sessionID, nil, nil); err != nil {
+			h.logger.Error("Presence session renewal stopped", "error", err, "sessionID", sessionID)
+		}
+	}()
+}
+
+// syncPresence mirrors the local connection count for userID to Consul.
+// Updates are serialized so a connect and a disconnect racing each other
+// cannot leave a stale count behind.
+func (h *RepairHandler) syncPresence(userID string) {
+	if h.presenceSession == "" {
+		return
+	}
+	h.presenceMu.Lock()
+	defer h.presenceMu.Unlock()
+
+	h.clientsMutex.Lock()
+	connections := len(h.clients[userID])
+	h.clientsMutex.Unlock()
+
+	key := presencePrefix(userID) + h.gatewayID
+	if connections == 0 {
+		if _, err := h.consulClient.KV().Delete(key, nil); err != nil {
+			h.logger.Error("Failed to clear presence", "error", err, "userID", userID)
+		}
+		return
+	}
+	value, err := json.Marshal(presenceRecord{
+		UserID:      userID,
+		Gateway:     h.gatewayID,
+		Connections: connections,
+		UpdatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		h.logger.Error("Failed to encode presence", "error", err, "userID", userID)
+		return
+	}
+	acquired, _, err := h.consulClient.KV().Acquire(&api.KVPair{Key: key, Value: value, Session: h.presenceSession}, nil)
+	if err != nil || !acquired {
+		h.logger.Error("Failed to publish presence", "error", err, "acquired", acquired, "userID", userID)
+	}
+}
+
+// presence combines local connections with presence published by other gateways
+func (h *RepairHandler) presence(userID string) (PresenceStatus, error) {
+	h.clientsMutex.Lock()
+	local := len(h.clients[userID])
+	h.clientsMutex.Unlock()
+
+	status := PresenceStatus{UserID: userID, LocalConnections: local, Connected: local > 0}
+	if local > 0 {
+		status.Gateways = append(status.Gateways, h.gatewayID)
+	}
+	if h.presenceSession == "" {
+		return status, nil
+	}
+
+	prefix := presencePrefix(userID)
+	keys, _, err := h.consulClient.KV().Keys(prefix, "", nil)
+	if err != nil {
+		return status, fmt.Errorf("failed to read presence from Consul: %w", err)
+	}
+	for _, key := range keys {
+		gateway := strings.TrimPrefix(key, prefix)
+		if gateway != h.gatewayID {
+			status.Gateways = append(status.Gateways, gateway)
+			status.Connected = true
+		}
+	}
+	return status, nil
+}
+
+// presencePrefix returns the Consul KV prefix of userID's presence keys
+func presencePrefix(userID string) string {
+	return PresenceKVPrefix + url.PathEscape(userID) + "/"
+}
+
+// GetPresence reports whether a user or mechanic currently has a WebSocket
+// connection on any gateway. Internal callers must hold ADMIN_API_TOKEN.
+func (h *RepairHandler) GetPresence(w http.ResponseWriter, r *http.Request) {
+	_, span := h.tracer.Start(r.Context(), "GetPresence")
+	defer span.End()
+
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	userID := mux.Vars(r)["userID"]
+	span.SetAttributes(attribute.String("userID", userID))
+
+	status, err := h.presence(userID)
+	if err != nil {
+		// Local presence is still accurate for this gateway; report it rather than failing
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to read cluster presence")
+		h.logger.Error("Failed to read cluster presence", "error", err, "userID", userID)
+	}
+	span.SetAttributes(attribute.Bool("connected", status.Connected))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+)
+
+// PricingRules lists the pricing rules. Only admins holding ADMIN_API_TOKEN
+// may call it.
+func (h *RepairHandler) PricingRules(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "PricingRules", h.repairService.URL(), "/admin/pricing/rules")
+}
+
+// PricingRule creates, replaces or deletes one pricing rule. Only admins
+// holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) PricingRule(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	ruleID := mux.Vars(r)["ruleID"]
+	h.proxyRequest(w, r, "PricingRule", h.repairService.URL(), "/admin/pricing/rules/"+url.PathEscape(ruleID))
+}
+
+// EvaluatePricing dry-runs the pricing rules, or candidate rules from the
+// body, against a pricing context. Only admins holding ADMIN_API_TOKEN may
+// call it.
+func (h *RepairHandler) EvaluatePricing(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "EvaluatePricing", h.repairService.URL(), "/admin/pricing/evaluate")
+}
+
+// WeatherFlag reads or sets the weather flag pricing rules match on. Only
+// admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) WeatherFlag(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "WeatherFlag", h.repairService.URL(), "/admin/pricing/weather")
+}
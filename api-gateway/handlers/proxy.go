@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// proxyRequest forwards the incoming request (method, query and body) to
+// baseURL+path and copies the downstream response back unchanged. It is used
+// for endpoints where the gateway has no transformation to apply.
+func (h *RepairHandler) proxyRequest(w http.ResponseWriter, r *http.Request, spanName, baseURL, path string) {
+	h.proxyRequestWithHeaders(w, r, spanName, baseURL, path, nil)
+}
+
+// proxyRequestWithHeaders is proxyRequest setting header on the downstream
+// request, e.g. the caller the gateway authenticated
+func (h *RepairHandler) proxyRequestWithHeaders(w http.ResponseWriter, r *http.Request, spanName, baseURL, path string, header http.Header) {
+	ctx, span := h.tracer.Start(r.Context(), spanName)
+	defer span.End()
+
+	target := baseURL + path
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	span.SetAttributes(attribute.String("target", target))
+
+	req, err := http.NewRequestWithContext(ctx, r.Method, target, r.Body)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to create request")
+		h.logger.Error("Failed to create request", "error", err, "url", target)
+		http.Error(w, "Failed to create request", http.StatusInternalServerError)
+		return
+	}
+	if ct := r.Header.Get("Content-Type"); ct != "" {
+		req.Header.Set("Content-Type", ct)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to contact downstream service")
+		h.logger.Error("Failed to contact downstream service", "error", err, "url", target)
+		http.Error(w, "Failed to contact downstream service", downstreamStatus(w, err))
+		return
+	}
+	defer resp.Body.Close()
+
+	span.SetAttributes(attribute.Int("statusCode", resp.StatusCode))
+	if ct := resp.Header.Get("Content-Type"); ct != "" {
+		w.Header().Set("Content-Type", ct)
+	}
+	w.WriteHeader(resp.StatusCode)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		span.RecordError(err)
+		h.logger.Error("Failed to copy downstream response", "error", err, "url", target)
+	}
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// SymptomAnswer mirrors repair-service's domain.SymptomAnswer
+type SymptomAnswer struct {
+	QuestionID string `json:"questionID"`
+	Question   string `json:"question,omitempty"`
+	Answer     string `json:"answer"`
+}
+
+// GetQuestionnaire returns the intake questionnaire for a repair type
+func (h *RepairHandler) GetQuestionnaire(w http.ResponseWriter, r *http.Request) {
+	repairType := mux.Vars(r)["repairType"]
+	h.proxyRequest(w, r, "GetQuestionnaire", h.repairService.URL(), "/questionnaires/"+repairType)
+}
+
+// SaveQuestionnaire creates or replaces the intake questionnaire for a repair type.
+// Only admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) SaveQuestionnaire(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	repairType := mux.Vars(r)["repairType"]
+	h.proxyRequest(w, r, "SaveQuestionnaire", h.repairService.URL(), "/admin/questionnaires/"+repairType)
+}
+
+// authorizeAdmin checks the request carries the admin bearer token and writes an
+// error response if not. Admin routes are disabled when ADMIN_API_TOKEN is unset.
+func (h *RepairHandler) authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if h.adminToken == "" {
+		h.logger.Warn("Admin route called but ADMIN_API_TOKEN is not configured", "path", r.URL.Path)
+		http.Error(w, "Admin API is disabled", http.StatusForbidden)
+		return false
+	}
+	token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+		h.logger.Warn("Rejected unauthorized admin request", "path", r.URL.Path, "remoteAddr", r.RemoteAddr)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
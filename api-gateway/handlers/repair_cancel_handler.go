@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"api-gateway/logging"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// CancelRepair forwards a repair's cancellation to repair-service, which
+// lets the repair's user, its assigned mechanic and admins cancel a repair
+// that is not completed. Callers authenticate like PATCH /repairs/{repairID};
+// the cancellation is broadcast to the user like other status changes.
+func (h *RepairHandler) CancelRepair(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "CancelRepair")
+	defer span.End()
+
+	repairID := mux.Vars(r)["repairID"]
+	span.SetAttributes(attribute.String("repairID", repairID))
+	actor, ok := h.repairActor(w, r)
+	if !ok {
+		span.SetStatus(codes.Error, "Caller not authorized")
+		return
+	}
+	span.SetAttributes(attribute.String("actorRole", actor.Get("X-Actor-Role")))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.repairService.URL()+"/repairs/"+url.PathEscape(repairID)+"/cancel", nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to create request")
+		h.logger.Error("Failed to create request", "error", err)
+		http.Error(w, "Failed to create request", http.StatusInternalServerError)
+		return
+	}
+	for name, values := range actor {
+		req.Header[name] = values
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to contact repair service")
+		h.logger.Error("Failed to contact repair service", "error", err, "url", req.URL.String())
+		http.Error(w, "Failed to contact repair service", downstreamStatus(w, err))
+		return
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to read response body")
+		h.logger.Error("Failed to read response body", "error", err)
+		http.Error(w, "Failed to read response", http.StatusInternalServerError)
+		return
+	}
+	span.SetAttributes(attribute.Int("statusCode", resp.StatusCode))
+	if resp.StatusCode != http.StatusOK {
+		span.RecordError(fmt.Errorf("repair service error: %s", string(bodyBytes)))
+		span.SetStatus(codes.Error, "Failed to cancel repair")
+		h.logger.Error("Repair service error", "status", resp.StatusCode, "response", logging.Redact(string(bodyBytes)))
+	} else {
+		var repair RepairModel
+		if err := json.Unmarshal(bodyBytes, &repair); err != nil {
+			h.logger.Warn("Failed to decode cancelled repair, deferring lookup to broadcast worker", "error", err, "repairID", repairID)
+		}
+		h.enqueueBroadcast(ctx, broadcastJob{update: StatusUpdate{
+			RepairID: repairID,
+			UserID:   repair.UserID,
+			Status:   "cancelled",
+		}})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.StatusCode)
+	w.Write(bodyBytes)
+}
+
+// DeleteRepair forwards a repair's deletion to repair-service, which lets
+// the repair's user and admins delete a pending or cancelled repair outside
+// a bundle. Callers authenticate like PATCH /repairs/{repairID}.
+func (h *RepairHandler) DeleteRepair(w http.ResponseWriter, r *http.Request) {
+	actor, ok := h.repairActor(w, r)
+	if !ok {
+		return
+	}
+	repairID := url.PathEscape(mux.Vars(r)["repairID"])
+	h.proxyRequestWithHeaders(w, r, "DeleteRepair", h.repairService.URL(), "/repairs/"+repairID, actor)
+}
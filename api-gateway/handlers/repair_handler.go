@@ -1,10 +1,18 @@
 package handlers
 
 import (
+	"api-gateway/config"
+	"api-gateway/connect"
 	"api-gateway/logging"
+	"api-gateway/middleware"
+	"api-gateway/openapi"
+	"api-gateway/proto"
+	"api-gateway/slowlog"
+	"api-gateway/telemetry"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
@@ -16,8 +24,11 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"strconv"
 	"sync"
 	"time"
 )
@@ -30,6 +41,27 @@ type RepairCostModel struct {
 	TotalPrice   float64        `json:"totalPrice"`
 	UserLocation *Location      `json:"userLocation,omitempty"`
 	Mechanics    []MechanicInfo `json:"mechanics,omitempty"`
+	Availability *Availability  `json:"availability,omitempty"`
+	Anonymous    bool           `json:"anonymous,omitempty"`
+	Accuracy     string         `json:"accuracy,omitempty"`
+	// Pricing is the pricing rule trace, passed through untouched for debugging
+	Pricing json.RawMessage `json:"pricing,omitempty"`
+	// Ranking is how the mechanics are ordered, passed through untouched
+	Ranking json.RawMessage `json:"ranking,omitempty"`
+	// EstimatedMinutes is how long the repair takes, learned from completed repairs
+	EstimatedMinutes float64 `json:"estimatedMinutes,omitempty"`
+	// Preference is the user's preferred mechanic, passed through untouched
+	// so repair-service checks it again on creation
+	Preference json.RawMessage `json:"preference,omitempty"`
+}
+
+// Availability mirrors repair-service's domain.Availability
+type Availability struct {
+	RadiusMeters       float64 `json:"radiusMeters"`
+	OnlineMechanics    int     `json:"onlineMechanics"`
+	AvailableMechanics int     `json:"availableMechanics"`
+	WaitBucket         string  `json:"waitBucket"`
+	Confidence         string  `json:"confidence"`
 }
 
 // Location mirrors repair-service's domain.Location
@@ -44,6 +76,13 @@ type MechanicInfo struct {
 	Name     string   `json:"name"`
 	Location Location `json:"location"`
 	Distance float64  `json:"distance"`
+	// Inputs and result of repair-service's weighted score
+	Rating         *float64        `json:"rating,omitempty"`
+	AcceptanceRate *float64        `json:"acceptanceRate,omitempty"`
+	OpenRepairs    int             `json:"openRepairs"`
+	Score          json.RawMessage `json:"score,omitempty"`
+	// EstimatedMinutes is the repair's duration learned for the mechanic
+	EstimatedMinutes float64 `json:"estimatedMinutes,omitempty"`
 }
 
 // RepairModel mirrors repair-service's domain.RepairModel and mechanic-service's Repair
@@ -53,26 +92,71 @@ type RepairModel struct {
 	Status     string           `json:"status"`
 	RepairCost *RepairCostModel `json:"repairCost"`
 	AssignedTo string           `json:"assignedTo,omitempty"`
+	Symptoms   []SymptomAnswer  `json:"symptoms,omitempty"`
+	Tags       []string         `json:"tags,omitempty"`
+	Region     string           `json:"region,omitempty"`
 }
 
 // WebSocket message for status updates
 type StatusUpdate struct {
-	RepairID string `json:"repairID"`
-	UserID   string `json:"userID"`
-	Status   string `json:"status"`
+	RepairID  string     `json:"repairID"`
+	UserID    string     `json:"userID"`
+	Status    string     `json:"status"`
+	Amendment *Amendment `json:"amendment,omitempty"` // set when the mechanic asks the user to approve a price increase
+	ETA       *ETAEvent  `json:"eta,omitempty"`       // set when the mechanic is about to arrive or is late
+	Seq       int64      `json:"seq,omitempty"`       // the user's message number; clients ack it to trim the offline queue
+}
+
+// ETAEvent mirrors mechanic-service's domain.ETAEvent, the countdown or late
+// event of the ETA a mechanic committed to when claiming a repair
+type ETAEvent struct {
+	Type         string    `json:"type" bson:"type"` // repair_eta_countdown or repair_eta_late
+	RepairID     string    `json:"repairID" bson:"repairID"`
+	MechanicID   string    `json:"mechanicID" bson:"mechanicID"`
+	CommittedETA time.Time `json:"committedETA" bson:"committedETA"`
+	MinutesLeft  int       `json:"minutesLeft,omitempty" bson:"minutesLeft,omitempty"`
+	MinutesLate  int       `json:"minutesLate,omitempty" bson:"minutesLate,omitempty"`
+	Message      string    `json:"message" bson:"-"`
 }
 
+// ETA event types
+const (
+	ETACountdown = "repair_eta_countdown"
+	ETALate      = "repair_eta_late"
+)
+
 // RepairHandler handles HTTP and WebSocket requests for repair operations
 type RepairHandler struct {
-	client             *http.Client
-	consulClient       *api.Client
-	repairServiceURL   string
-	mechanicServiceURL string
-	upgrader           websocket.Upgrader
-	clients            map[string][]*websocket.Conn // Map of userID to WebSocket connections
-	clientsMutex       sync.Mutex
-	tracer             trace.Tracer
-	logger             *slog.Logger
+	client           *http.Client
+	slow             *slowlog.Recorder
+	consulClient     *api.Client
+	repairService    *serviceResolver
+	mechanicService  *serviceResolver
+	upgrader         websocket.Upgrader
+	clients          map[string][]*wsClient // Map of userID to WebSocket connections
+	clientsMutex     sync.Mutex
+	tracer           trace.Tracer
+	logger           *slog.Logger
+	broadcastQueue   chan broadcastJob
+	broadcastRetries int
+	writeTimeout     time.Duration
+	sendQueueSize    int    // messages buffered per WebSocket connection
+	overflowPolicy   string // OverflowDropOldest or OverflowDisconnect
+	adminToken       string
+	gatewayID        string     // identifies this instance in presence keys
+	presenceSession  string     // Consul session owning this gateway's presence keys
+	presenceMu       sync.Mutex // serializes presence updates to Consul
+	ops              *opsFeed   // live operations feed served on /admin/events
+	wsLimits         wsLimits   // per-device replacement, per-user cap and keepalive
+	wsAuth           *wsAuth    // identifies /ws connections
+	notifier         *mechanicNotifier
+	maintenance      *middleware.Maintenance
+	config           *config.Store             // reloadable settings shown on /admin/config
+	updates          *updateLog                // recent status updates served to long polls
+	longPollMaxWait  time.Duration             // longest a long poll waits for an update
+	repairStream     proto.RepairServiceClient // nil unless REPAIR_GRPC_ADDRESS is set
+	offline          *offlineQueue             // status updates kept until acked; nil without MongoDB
+	sections         *sectionCache             // last good sections of the aggregate endpoints
 }
 
 // NewRepairHandler creates a new RepairHandler with Consul integration
@@ -112,6 +196,7 @@ func NewRepairHandler() *RepairHandler {
 		Name:    serviceName,
 		Port:    8085,
 		Address: "api-gateway",
+		Meta:    map[string]string{MetaRegion: os.Getenv("SERVICE_REGION"), MetaZone: os.Getenv("SERVICE_ZONE")},
 		Check: &api.AgentServiceCheck{
 			HTTP:     "http://api-gateway:8085/health",
 			Interval: "10s",
@@ -123,66 +208,128 @@ func NewRepairHandler() *RepairHandler {
 		os.Exit(1)
 	}
 
-	// Discover repair-service
-	repairServiceURL := ""
-	for {
-		services, _, err := consulClient.Health().Service("repair-service", "", true, nil)
-		if err != nil {
-			logger.Error("Failed to discover repair-service", "error", err)
-			time.Sleep(2 * time.Second)
-			continue
-		}
-		if len(services) > 0 {
-			repairServiceURL = fmt.Sprintf("http://%s:%d", services[0].Service.Address, services[0].Service.Port)
-			logger.Info("Discovered repair-service at", "url", repairServiceURL)
-			break
-		}
-		logger.Info("Waiting for repair-service to be registered")
-		time.Sleep(2 * time.Second)
-	}
-
-	// Discover mechanic-service
-	mechanicServiceURL := ""
-	for {
-		services, _, err := consulClient.Health().Service("mechanic-service", "", true, nil)
-		if err != nil {
-			logger.Error("Failed to discover mechanic-service", "error", err)
-			time.Sleep(2 * time.Second)
-			continue
+	// With CONNECT_NATIVE=true the gateway calls the services over Consul
+	// Connect mTLS as this service's identity, which intentions must allow
+	var mesh *connect.Service
+	if connect.Enabled() {
+		if mesh, err = connect.New(consulClient, serviceName, logger); err != nil {
+			logger.Error("Failed to set up Consul Connect", "error", err)
+			os.Exit(1)
 		}
-		if len(services) > 0 {
-			mechanicServiceURL = fmt.Sprintf("http://%s:%d", services[0].Service.Address, services[0].Service.Port)
-			logger.Info("Discovered mechanic-service at", "url", mechanicServiceURL)
-			break
+		logger.Info("Consul Connect native enabled", "service", serviceName)
+	}
+
+	// Discover repair-service and mechanic-service and spread requests over
+	// their healthy instances, preferring those in this gateway's zone and region
+	region, zone := os.Getenv("SERVICE_REGION"), os.Getenv("SERVICE_ZONE")
+	repairService := newServiceResolver(consulClient, "repair-service", region, zone, logger)
+	repairService.connect = mesh != nil
+	repairService.resolve()
+	mechanicService := newServiceResolver(consulClient, "mechanic-service", region, zone, logger)
+	mechanicService.connect = mesh != nil
+	mechanicService.resolve()
+	resolvers := []*serviceResolver{repairService, mechanicService}
+	serviceAt := func(host string) string {
+		for _, s := range resolvers {
+			if s.has(host) {
+				return s.name
+			}
 		}
-		logger.Info("Waiting for mechanic-service to be registered")
-		time.Sleep(2 * time.Second)
+		return ""
 	}
 
 	tracer := otel.Tracer("api-gateway")
 
-	// Create HTTP client with OpenTelemetry instrumentation
+	// Log and count slow downstream calls, Mongo commands and handlers
+	slow := slowlog.New(logger)
+
+	// Create HTTP client with OpenTelemetry instrumentation; peer.service is
+	// the Consul service of the instance a request goes to. Requests carry
+	// the time left before the incoming request's deadline, fail over to
+	// another instance when theirs is unreachable, are retried with backoff
+	// when idempotent, and fail at once while the service's circuit breaker
+	// is open.
+	transport := &http.Transport{}
+	if mesh != nil {
+		transport.DialTLSContext = mesh.DialTLS(serviceAt)
+	}
 	client := &http.Client{
 		Timeout: 10 * time.Second,
-		Transport: &http.Transport{},
+		Transport: telemetry.Transport(slow.Transport(middleware.PropagateDeadline(&breakerTransport{next: newRetryTransport(&failoverTransport{next: transport, resolvers: resolvers}, logger), resolvers: resolvers})), func(req *http.Request) string {
+			return serviceAt(req.URL.Host)
+		}),
 	}
 
-	return &RepairHandler{
-		client:             client,
-		consulClient:       consulClient,
-		repairServiceURL:   repairServiceURL,
-		mechanicServiceURL: mechanicServiceURL,
+	h := &RepairHandler{
+		client:          client,
+		slow:            slow,
+		consulClient:    consulClient,
+		repairService:   repairService,
+		mechanicService: mechanicService,
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
 			CheckOrigin: func(r *http.Request) bool {
-				return true // Allow all origins for simplicity
+				return true // Origins are enforced by the CORS middleware when configured
 			},
 		},
-		clients: make(map[string][]*websocket.Conn),
-		tracer:  tracer,
-		logger:  logger,
+		clients:          make(map[string][]*wsClient),
+		tracer:           tracer,
+		logger:           logger,
+		broadcastQueue:   make(chan broadcastJob, envInt("BROADCAST_QUEUE_SIZE", 256)),
+		broadcastRetries: envInt("BROADCAST_MAX_RETRIES", 3),
+		writeTimeout:     time.Duration(envInt("BROADCAST_WRITE_TIMEOUT_MS", 5000)) * time.Millisecond,
+		sendQueueSize:    envInt("WS_SEND_QUEUE_SIZE", 16),
+		overflowPolicy:   OverflowDropOldest,
+		adminToken:       os.Getenv("ADMIN_API_TOKEN"),
+		ops:              newOpsFeed(envInt("OPS_EVENT_REPLAY_SIZE", 100)),
+		updates:          newUpdateLog(envInt("LONGPOLL_BUFFER_SIZE", 1000)),
+		longPollMaxWait:  time.Duration(envInt("LONGPOLL_MAX_WAIT_SECONDS", 30)) * time.Second,
+		notifier:         newMechanicNotifier(time.Duration(envInt("MECHANIC_PREFS_CACHE_SECONDS", 60))*time.Second, envInt("MECHANIC_DIGEST_MAX_ITEMS", 50)),
+		repairStream:     newRepairStreamClient(mesh, logger),
+		sections:         newSectionCache(time.Duration(envInt("AGGREGATE_STALE_SECONDS", 300))*time.Second, envInt("AGGREGATE_CACHE_ENTRIES", 10000)),
+	}
+
+	h.wsLimits.maxPerUser = envInt("WS_MAX_CONNECTIONS_PER_USER", 5)
+	h.wsLimits.pingInterval = time.Duration(envInt("WS_PING_INTERVAL_SECONDS", 30)) * time.Second
+	h.wsAuth = newWSAuth(logger)
+
+	if os.Getenv("WS_OVERFLOW_POLICY") == OverflowDisconnect {
+		h.overflowPolicy = OverflowDisconnect
+	}
+
+	// Keep status updates until users ack them and feed the ops event stream,
+	// both from MongoDB
+	db, err := h.connectMongo()
+	if err != nil {
+		h.logger.Error("Failed to connect to MongoDB, offline WebSocket delivery and the ops event feed are disabled", "error", err)
+	} else if envInt("WS_OFFLINE_QUEUE", 1) > 0 {
+		h.offline = newOfflineQueue(db, envInt("WS_OFFLINE_REPLAY_LIMIT", 500), logger)
+	}
+
+	// Publish WebSocket presence to Consul so services can pick a delivery channel
+	h.startPresence()
+
+	// Start the asynchronous broadcast worker
+	go h.runBroadcastWorker()
+
+	// Periodically push positioning hints to idle mechanics on the WebSocket
+	if interval := envInt("POSITIONING_HINT_INTERVAL_SECONDS", 300); interval > 0 {
+		go h.runPositioningHints(time.Duration(interval) * time.Second)
 	}
+
+	// Apply mechanics' quiet hours and digests; assignments always go out at once
+	go h.runNotificationDigests(time.Duration(envInt("MECHANIC_DIGEST_CHECK_SECONDS", 60)) * time.Second)
+	go h.runUrgentMechanicNotifications()
+
+	// Watch for significant system events to stream to operations
+	if db != nil {
+		h.startOpsMonitors(db)
+	}
+
+	h.registerWebSocketMetrics()
+
+	return h
 }
 
 // HealthCheck provides a health endpoint for Consul
@@ -194,13 +341,24 @@ func (h *RepairHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintln(w, "OK")
 }
 
+// OpenAPISpec serves the OpenAPI spec request bodies are validated against
+func (h *RepairHandler) OpenAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openapi.SpecJSON)
+}
+
 // CreateRepair forwards a repair creation request to repair-service
 func (h *RepairHandler) CreateRepair(w http.ResponseWriter, r *http.Request) {
 	ctx, span := h.tracer.Start(r.Context(), "CreateRepair")
 	defer span.End()
 
-	var cost RepairCostModel
-	if err := json.NewDecoder(r.Body).Decode(&cost); err != nil {
+	var input struct {
+		RepairCostModel
+		IntakeAnswers             []SymptomAnswer `json:"intakeAnswers,omitempty"`
+		PreferredMechanicID       string          `json:"preferredMechanicID,omitempty"`
+		PreferredMechanicFallback string          `json:"preferredMechanicFallback,omitempty"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Invalid request body")
 		h.logger.Error("Invalid request body", "error", err)
@@ -208,11 +366,12 @@ func (h *RepairHandler) CreateRepair(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	span.SetAttributes(
-		attribute.String("userID", cost.UserID),
-		attribute.String("repairType", cost.RepairType),
+		attribute.String("userID", input.UserID),
+		attribute.String("repairType", input.RepairType),
+		attribute.Int("intakeAnswerCount", len(input.IntakeAnswers)),
 	)
 
-	body, err := json.Marshal(cost)
+	body, err := json.Marshal(input)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to marshal request")
@@ -221,7 +380,7 @@ func (h *RepairHandler) CreateRepair(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", h.repairServiceURL+"/repairs", bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", h.repairService.URL()+"/repairs", bytes.NewBuffer(body))
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to create request")
@@ -236,8 +395,8 @@ func (h *RepairHandler) CreateRepair(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to contact repair service")
-		h.logger.Error("Failed to contact repair service", "error", err, "url", h.repairServiceURL)
-		http.Error(w, "Failed to contact repair service", http.StatusInternalServerError)
+		h.logger.Error("Failed to contact repair service", "error", err, "url", req.URL.String())
+		http.Error(w, "Failed to contact repair service", downstreamStatus(w, err))
 		return
 	}
 	defer resp.Body.Close()
@@ -250,7 +409,6 @@ func (h *RepairHandler) CreateRepair(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to read response", http.StatusInternalServerError)
 		return
 	}
-	h.logger.Info("Repair service response", "response", string(bodyBytes))
 	resp.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
 	var repair RepairModel
@@ -276,6 +434,11 @@ func (h *RepairHandler) EstimateRepairCost(w http.ResponseWriter, r *http.Reques
 		RepairType string   `json:"repairType"`
 		UserID     string   `json:"userID"`
 		Location   Location `json:"location"`
+		Sort       string   `json:"sort,omitempty"` // distance, score, rating, acceptance or load
+		// A mechanic to pin the estimate to, and nearby or none when they
+		// cannot take the repair
+		PreferredMechanicID       string `json:"preferredMechanicID,omitempty"`
+		PreferredMechanicFallback string `json:"preferredMechanicFallback,omitempty"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		span.RecordError(err)
@@ -298,7 +461,7 @@ func (h *RepairHandler) EstimateRepairCost(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", h.repairServiceURL+"/repairs/estimate", bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, "POST", h.repairService.URL()+"/repairs/estimate", bytes.NewBuffer(body))
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to create request")
@@ -313,8 +476,8 @@ func (h *RepairHandler) EstimateRepairCost(w http.ResponseWriter, r *http.Reques
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to contact repair service")
-		h.logger.Error("Failed to contact repair service", "error", err, "url", h.repairServiceURL)
-		http.Error(w, "Failed to contact repair service", http.StatusInternalServerError)
+		h.logger.Error("Failed to contact repair service", "error", err, "url", req.URL.String())
+		http.Error(w, "Failed to contact repair service", downstreamStatus(w, err))
 		return
 	}
 	defer resp.Body.Close()
@@ -327,7 +490,6 @@ func (h *RepairHandler) EstimateRepairCost(w http.ResponseWriter, r *http.Reques
 		http.Error(w, "Failed to read response", http.StatusInternalServerError)
 		return
 	}
-	h.logger.Info("Repair service response", "response", string(bodyBytes))
 	resp.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
 
 	var cost RepairCostModel
@@ -357,7 +519,7 @@ func (h *RepairHandler) GetRepairCost(w http.ResponseWriter, r *http.Request) {
 		attribute.String("userID", userID),
 	)
 
-	req, err := http.NewRequestWithContext(ctx, "GET", h.repairServiceURL+"/repairs/cost/"+costID+"?userID="+userID, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", h.repairService.URL()+"/repairs/cost/"+costID+"?userID="+userID, nil)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to create request")
@@ -371,8 +533,8 @@ func (h *RepairHandler) GetRepairCost(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to contact repair service")
-		h.logger.Error("Failed to contact repair service", "error", err, "url", h.repairServiceURL)
-		http.Error(w, "Failed to contact repair service", http.StatusInternalServerError)
+		h.logger.Error("Failed to contact repair service", "error", err, "url", req.URL.String())
+		http.Error(w, "Failed to contact repair service", downstreamStatus(w, err))
 		return
 	}
 	defer resp.Body.Close()
@@ -391,7 +553,8 @@ func (h *RepairHandler) GetRepairCost(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(cost)
 }
 
-// GetRepair retrieves a repair by ID
+// GetRepair retrieves a repair by ID; with ?asOf=<RFC 3339 time> it returns
+// the repair's state at that moment instead, its status, assignment and price
 func (h *RepairHandler) GetRepair(w http.ResponseWriter, r *http.Request) {
 	ctx, span := h.tracer.Start(r.Context(), "GetRepair")
 	defer span.End()
@@ -400,7 +563,13 @@ func (h *RepairHandler) GetRepair(w http.ResponseWriter, r *http.Request) {
 	repairID := vars["repairID"]
 	span.SetAttributes(attribute.String("repairID", repairID))
 
-	req, err := http.NewRequestWithContext(ctx, "GET", h.repairServiceURL+"/repairs/"+repairID, nil)
+	target := h.repairService.URL() + "/repairs/" + repairID
+	asOf := r.URL.Query().Get("asOf")
+	if asOf != "" {
+		span.SetAttributes(attribute.String("asOf", asOf))
+		target += "?asOf=" + url.QueryEscape(asOf)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to create request")
@@ -414,14 +583,24 @@ func (h *RepairHandler) GetRepair(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to contact repair service")
-		h.logger.Error("Failed to contact repair service", "error", err, "url", h.repairServiceURL)
-		http.Error(w, "Failed to contact repair service", http.StatusInternalServerError)
+		h.logger.Error("Failed to contact repair service", "error", err, "url", req.URL.String())
+		http.Error(w, "Failed to contact repair service", downstreamStatus(w, err))
 		return
 	}
 	defer resp.Body.Close()
 
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to read response body")
+		h.logger.Error("Failed to read response body", "error", err)
+		http.Error(w, "Failed to read response", http.StatusInternalServerError)
+		return
+	}
+	// A repair state as of a moment carries the repair's user and mechanic
+	// under the same names as the repair
 	var repair RepairModel
-	if err := json.NewDecoder(resp.Body).Decode(&repair); err != nil {
+	if err := json.Unmarshal(bodyBytes, &repair); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to decode response")
 		h.logger.Error("Failed to decode response", "error", err)
@@ -429,12 +608,41 @@ func (h *RepairHandler) GetRepair(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Users only see their own repairs and mechanics the ones assigned to them
+	if caller, ok := middleware.CallerFrom(ctx); ok && resp.StatusCode == http.StatusOK &&
+		((caller.Role == middleware.RoleUser && repair.UserID != caller.ID) ||
+			(caller.Role == middleware.RoleMechanic && repair.AssignedTo != caller.ID)) {
+		span.SetStatus(codes.Error, "Repair belongs to another caller")
+		h.logger.Warn("Rejected repair of another caller", "repairID", repairID, "role", caller.Role, "callerID", caller.ID)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(resp.StatusCode)
+	if asOf != "" {
+		w.Write(bodyBytes)
+		return
+	}
 	json.NewEncoder(w).Encode(repair)
 }
 
-// UpdateRepair updates a repair's status and broadcasts to WebSocket clients
+// GetReceipt retrieves the receipt of a completed repair; ?format=pdf returns it as PDF
+func (h *RepairHandler) GetReceipt(w http.ResponseWriter, r *http.Request) {
+	repairID := url.PathEscape(mux.Vars(r)["repairID"])
+	h.proxyRequest(w, r, "GetReceipt", h.repairService.URL(), "/repairs/"+repairID+"/receipt")
+}
+
+// GetRepairMap returns clustered repair markers for the operations console map.
+// Only admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) GetRepairMap(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	h.proxyRequest(w, r, "GetRepairMap", h.repairService.URL(), "/admin/repairs/map")
+}
+
+// UpdateRepair updates a repair's status and queues a broadcast to WebSocket clients
 func (h *RepairHandler) UpdateRepair(w http.ResponseWriter, r *http.Request) {
 	ctx, span := h.tracer.Start(r.Context(), "UpdateRepair")
 	defer span.End()
@@ -444,7 +652,9 @@ func (h *RepairHandler) UpdateRepair(w http.ResponseWriter, r *http.Request) {
 	span.SetAttributes(attribute.String("repairID", repairID))
 
 	var input struct {
-		Status string `json:"status"`
+		Status           string   `json:"status"`
+		PaymentReference string   `json:"paymentReference,omitempty"`
+		FinalAmount      *float64 `json:"finalAmount,omitempty"` // required when completing with approved amendments
 	}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		span.RecordError(err)
@@ -464,7 +674,7 @@ func (h *RepairHandler) UpdateRepair(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "PUT", h.repairServiceURL+"/repairs/"+repairID, bytes.NewBuffer(body))
+	req, err := http.NewRequestWithContext(ctx, "PUT", h.repairService.URL()+"/repairs/"+repairID, bytes.NewBuffer(body))
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to create request")
@@ -479,59 +689,54 @@ func (h *RepairHandler) UpdateRepair(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to contact repair service")
-		h.logger.Error("Failed to contact repair service", "error", err, "url", h.repairServiceURL)
-		http.Error(w, "Failed to contact repair service", http.StatusInternalServerError)
+		h.logger.Error("Failed to contact repair service", "error", err, "url", req.URL.String())
+		http.Error(w, "Failed to contact repair service", downstreamStatus(w, err))
 		return
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		bodyBytes, _ := io.ReadAll(resp.Body)
-		span.RecordError(fmt.Errorf("repair service error: %s", string(bodyBytes)))
-		span.SetStatus(codes.Error, "Failed to update repair")
-		h.logger.Error("Repair service error", "response", string(bodyBytes))
-		http.Error(w, "Failed to update repair", resp.StatusCode)
-		return
-	}
-
-	// Get the repair to obtain userID for broadcasting
-	repairReq, err := http.NewRequestWithContext(ctx, "GET", h.repairServiceURL+"/repairs/"+repairID, nil)
+	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
 		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to create request for broadcasting")
-		h.logger.Error("Failed to create request for broadcasting", "error", err)
-		http.Error(w, "Failed to create request for broadcasting", http.StatusInternalServerError)
+		span.SetStatus(codes.Error, "Failed to read response body")
+		h.logger.Error("Failed to read response body", "error", err)
+		http.Error(w, "Failed to read response", http.StatusInternalServerError)
 		return
 	}
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(repairReq.Header))
-	repairResp, err := h.client.Do(repairReq)
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to fetch repair for broadcasting")
-		h.logger.Error("Failed to fetch repair for broadcasting", "error", err, "url", h.repairServiceURL)
-		http.Error(w, "Failed to fetch repair for broadcasting", http.StatusInternalServerError)
+
+	if resp.StatusCode != http.StatusOK {
+		span.RecordError(fmt.Errorf("repair service error: %s", string(bodyBytes)))
+		span.SetStatus(codes.Error, "Failed to update repair")
+		h.logger.Error("Repair service error", "status", resp.StatusCode, "response", logging.Redact(string(bodyBytes)))
+		http.Error(w, "Failed to update repair", resp.StatusCode)
 		return
 	}
-	defer repairResp.Body.Close()
 
+	// Use the updated repair returned by the PUT when available; otherwise the
+	// broadcast worker fetches it after we have responded to the client
 	var repair RepairModel
-	if err := json.NewDecoder(repairResp.Body).Decode(&repair); err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to decode repair for broadcasting")
-		h.logger.Error("Failed to decode repair for broadcasting", "error", err)
-		http.Error(w, "Failed to decode repair for broadcasting", http.StatusInternalServerError)
-		return
+	if len(bytes.TrimSpace(bodyBytes)) > 0 {
+		if err := json.Unmarshal(bodyBytes, &repair); err != nil {
+			h.logger.Warn("Failed to decode updated repair, deferring lookup to broadcast worker", "error", err, "repairID", repairID)
+			repair = RepairModel{}
+		}
 	}
 
-	// Broadcast status update to clients
-	update := StatusUpdate{
-		RepairID: repairID,
-		UserID:   repair.UserID,
-		Status:   input.Status,
-	}
-	h.broadcastStatusUpdate(update)
+	h.enqueueBroadcast(ctx, broadcastJob{
+		update: StatusUpdate{
+			RepairID: repairID,
+			UserID:   repair.UserID,
+			Status:   input.Status,
+		},
+	})
 
+	if repair.ID == "" {
+		w.WriteHeader(resp.StatusCode)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(resp.StatusCode)
+	json.NewEncoder(w).Encode(repair)
 }
 
 // ListNearbyRepairs forwards a request to mechanic-service to list nearby repairs
@@ -552,9 +757,22 @@ func (h *RepairHandler) ListNearbyRepairs(w http.ResponseWriter, r *http.Request
 		return
 	}
 	span.SetAttributes(attribute.String("mechanicID", mechanicID))
+	caller, hasCaller := middleware.CallerFrom(ctx)
+	if hasCaller && caller.Role == middleware.RoleMechanic && caller.ID != mechanicID {
+		span.SetStatus(codes.Error, "Mechanics may only list their own nearby repairs")
+		h.logger.Warn("Rejected nearby repairs of another mechanic", "mechanicID", mechanicID, "callerID", caller.ID)
+		http.Error(w, "Mechanics may only list their own nearby repairs", http.StatusForbidden)
+		return
+	}
 
-	h.logger.Info("Creating request to mechanic-service", "url", h.mechanicServiceURL+"/repairs/nearby?mechanicID="+mechanicID)
-	req, err := http.NewRequestWithContext(ctx, "GET", h.mechanicServiceURL+"/repairs/nearby?mechanicID="+mechanicID, nil)
+	// mechanic-service checks the bounds of the radius and result limit
+	query := url.Values{"mechanicID": {mechanicID}}
+	for _, name := range []string{"radiusKm", "maxResults"} {
+		if v := r.URL.Query().Get(name); v != "" {
+			query.Set(name, v)
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", h.mechanicService.URL()+"/repairs/nearby?"+query.Encode(), nil)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to create request")
@@ -562,6 +780,12 @@ func (h *RepairHandler) ListNearbyRepairs(w http.ResponseWriter, r *http.Request
 		http.Error(w, "Failed to create request", http.StatusInternalServerError)
 		return
 	}
+	h.logger.Info("Creating request to mechanic-service", "url", req.URL.String())
+	if hasCaller {
+		for name, values := range caller.Header() {
+			req.Header[name] = values
+		}
+	}
 	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
 	h.logger.Info("Request headers", "headers", req.Header)
 
@@ -570,8 +794,8 @@ func (h *RepairHandler) ListNearbyRepairs(w http.ResponseWriter, r *http.Request
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to contact mechanic service")
-		h.logger.Error("Failed to contact mechanic service", "error", err, "url", h.mechanicServiceURL)
-		http.Error(w, "Failed to contact mechanic service", http.StatusInternalServerError)
+		h.logger.Error("Failed to contact mechanic service", "error", err, "url", req.URL.String())
+		http.Error(w, "Failed to contact mechanic service", downstreamStatus(w, err))
 		return
 	}
 	defer resp.Body.Close()
@@ -581,7 +805,7 @@ func (h *RepairHandler) ListNearbyRepairs(w http.ResponseWriter, r *http.Request
 		bodyBytes, _ := io.ReadAll(resp.Body)
 		span.RecordError(fmt.Errorf("mechanic service error: %s", string(bodyBytes)))
 		span.SetStatus(codes.Error, "Mechanic service returned non-OK status")
-		h.logger.Error("Mechanic service error", "response", string(bodyBytes))
+		h.logger.Error("Mechanic service error", "status", resp.StatusCode, "response", logging.Redact(string(bodyBytes)))
 		http.Error(w, fmt.Sprintf("Mechanic service error: %s", string(bodyBytes)), resp.StatusCode)
 		return
 	}
@@ -594,7 +818,6 @@ func (h *RepairHandler) ListNearbyRepairs(w http.ResponseWriter, r *http.Request
 		http.Error(w, "Failed to read response", http.StatusInternalServerError)
 		return
 	}
-	h.logger.Info("Mechanic service response", "response", string(bodyBytes))
 
 	if len(bodyBytes) == 0 {
 		span.RecordError(fmt.Errorf("empty response from mechanic service"))
@@ -637,20 +860,34 @@ func (h *RepairHandler) ListNearbyRepairs(w http.ResponseWriter, r *http.Request
 
 // HandleWebSocket manages WebSocket connections
 func (h *RepairHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
-	_, span := h.tracer.Start(r.Context(), "HandleWebSocket")
+	ctx, span := h.tracer.Start(r.Context(), "HandleWebSocket")
 	defer span.End()
 
-	userID := r.URL.Query().Get("userID")
-	if userID == "" {
-		span.RecordError(fmt.Errorf("userID is required"))
-		span.SetStatus(codes.Error, "userID is required")
-		h.logger.Error("userID is required")
-		http.Error(w, "userID is required", http.StatusBadRequest)
+	// Identify the connection by the JWT the gateway verified, else by a
+	// WebSocket token or the first message; ?userID= only while
+	// WS_QUERY_IDENTITY allows it
+	var identity *wsIdentity
+	var viaQuery bool
+	var err error
+	if id, ok := middleware.IdentityFrom(ctx); ok {
+		identity = &wsIdentity{UserID: id.UserID, Role: id.Role}
+	} else {
+		identity, viaQuery, err = h.wsAuth.fromRequest(r)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		h.logger.Warn("Rejected WebSocket connection", "error", err, "remoteAddr", r.RemoteAddr)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
 		return
 	}
-	span.SetAttributes(attribute.String("userID", userID))
+	var responseHeader http.Header
+	if viaQuery {
+		responseHeader = http.Header{"Deprecation": {"true"}}
+		h.logger.Warn("WebSocket client identified by deprecated query parameters", "userID", identity.UserID, "userAgent", r.UserAgent())
+	}
 
-	conn, err := h.upgrader.Upgrade(w, r, nil)
+	conn, err := h.upgrader.Upgrade(w, r, responseHeader)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to upgrade to WebSocket")
@@ -658,45 +895,71 @@ func (h *RepairHandler) HandleWebSocket(w http.ResponseWriter, r *http.Request)
 		http.Error(w, "Failed to upgrade to WebSocket", http.StatusInternalServerError)
 		return
 	}
-
-	// Register client
-	h.clientsMutex.Lock()
-	h.clients[userID] = append(h.clients[userID], conn)
-	h.clientsMutex.Unlock()
-	h.logger.Info("WebSocket client connected", "userID", userID)
+	if identity == nil {
+		id, err := h.wsAuth.handshake(conn)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "WebSocket authentication failed")
+			h.logger.Warn("WebSocket authentication failed", "error", err, "remoteAddr", r.RemoteAddr)
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(CloseUnauthorized, "unauthorized"), time.Now().Add(time.Second))
+			conn.Close()
+			return
+		}
+		identity = &id
+	}
+	userID := identity.UserID
+	span.SetAttributes(attribute.String("userID", userID), attribute.Bool("queryIdentity", viaQuery))
+
+	// Register client, replacing older connections of the same device
+	client := newWSClient(conn, h.sendQueueSize)
+	client.mechanic = identity.Role == "mechanic"
+	client.deviceID = r.URL.Query().Get("deviceID")
+	if client.deviceID == "" {
+		client.deviceID = r.Header.Get("X-Device-ID")
+	}
+	client.pingInterval = h.wsLimits.pingInterval
+	h.keepAlive(conn)
+	// Register before loading the offline queue so no update falls between
+	// the replay and live delivery; the writer starts after the replay
+	h.registerClient(userID, client)
+	lastSeq, _ := strconv.ParseInt(r.URL.Query().Get("lastSeq"), 10, 64)
+	h.replayOffline(ctx, userID, client, lastSeq)
+	go client.writePump(h.writeTimeout, h.logger, userID)
+	h.syncPresence(userID)
+	h.logger.Info("WebSocket client connected", "userID", userID, "deviceID", client.deviceID)
 
 	// Handle client disconnection
 	defer func() {
-		h.clientsMutex.Lock()
-		clients := h.clients[userID]
-		for i, c := range clients {
-			if c == conn {
-				h.clients[userID] = append(clients[:i], clients[i+1:]...)
-				break
-			}
-		}
-		if len(h.clients[userID]) == 0 {
-			delete(h.clients, userID)
-		}
-		h.clientsMutex.Unlock()
-		conn.Close()
-		h.logger.Info("WebSocket client disconnected", "userID", userID)
+		h.unregisterClient(userID, client)
+		h.syncPresence(userID)
+		client.close()
+		h.logger.Info("WebSocket client disconnected", "userID", userID, "deviceID", client.deviceID)
 	}()
 
-	// Keep connection alive
+	// Read acks until the connection closes
 	for {
-		_, _, err := conn.ReadMessage()
+		_, data, err := conn.ReadMessage()
 		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				h.wsLimits.timedOut.Add(1)
+				h.logger.Warn("WebSocket client stopped answering pings", "userID", userID, "deviceID", client.deviceID)
+				break
+			}
 			span.RecordError(err)
 			h.logger.Error("WebSocket read error", "error", err)
 			break
 		}
+		h.handleClientMessage(ctx, userID, data)
 	}
 }
 
-// broadcastStatusUpdate sends status updates to all clients subscribed to the userID
-func (h *RepairHandler) broadcastStatusUpdate(update StatusUpdate) {
-	_, span := h.tracer.Start(context.Background(), "BroadcastStatusUpdate")
+// broadcastStatusUpdate queues status updates for all clients subscribed to the
+// userID. It never waits on a connection: each client's writer drains its own
+// queue, and a full queue drops the oldest message or disconnects the client
+// according to WS_OVERFLOW_POLICY.
+func (h *RepairHandler) broadcastStatusUpdate(ctx context.Context, update StatusUpdate) {
+	ctx, span := h.tracer.Start(ctx, "BroadcastStatusUpdate")
 	defer span.End()
 	span.SetAttributes(
 		attribute.String("repairID", update.RepairID),
@@ -704,11 +967,20 @@ func (h *RepairHandler) broadcastStatusUpdate(update StatusUpdate) {
 		attribute.String("status", update.Status),
 	)
 
-	h.clientsMutex.Lock()
-	defer h.clientsMutex.Unlock()
+	// Keep the update until the user acks it, connected or not
+	if err := h.offline.store(ctx, &update); err != nil {
+		span.RecordError(err)
+		h.logger.Error("Failed to keep status update for offline delivery", "error", err, "userID", update.UserID, "repairID", update.RepairID)
+	}
+	span.SetAttributes(attribute.Int64("seq", update.Seq))
 
-	clients, exists := h.clients[update.UserID]
-	if !exists {
+	// Long polls read the same bus as the WebSocket clients
+	h.updates.append(update)
+
+	h.clientsMutex.Lock()
+	clients := append([]*wsClient(nil), h.clients[update.UserID]...)
+	h.clientsMutex.Unlock()
+	if len(clients) == 0 {
 		return
 	}
 
@@ -720,12 +992,21 @@ func (h *RepairHandler) broadcastStatusUpdate(update StatusUpdate) {
 		return
 	}
 
-	for _, conn := range clients {
-		err := conn.WriteMessage(websocket.TextMessage, message)
-		if err != nil {
-			span.RecordError(err)
-			h.logger.Error("Failed to send WebSocket message", "error", err)
-			conn.Close()
+	droppedTotal, disconnected := 0, 0
+	for _, client := range clients {
+		dropped, ok := client.enqueueMessage(wsMessage{data: message, seq: update.Seq}, h.overflowPolicy)
+		droppedTotal += dropped
+		if dropped > 0 {
+			h.logger.Warn("WebSocket send queue full, dropped oldest messages", "userID", update.UserID, "dropped", dropped)
+		}
+		if !ok {
+			disconnected++
+			h.logger.Warn("WebSocket client closed or disconnected on send queue overflow", "userID", update.UserID, "repairID", update.RepairID)
 		}
 	}
+	span.SetAttributes(
+		attribute.Int("clientCount", len(clients)),
+		attribute.Int("droppedMessages", droppedTotal),
+		attribute.Int("disconnectedClients", disconnected),
+	)
 }
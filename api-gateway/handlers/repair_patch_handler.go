@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"api-gateway/logging"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// PatchRepair forwards a JSON merge patch of a repair's mutable fields to
+// repair-service, which checks the caller may change each field. Callers
+// holding ADMIN_API_TOKEN patch as admin; others name themselves with
+// X-Actor-Role (user or mechanic) and X-Actor-ID. Status changes are
+// broadcast like those of PUT /repairs/{repairID}.
+func (h *RepairHandler) PatchRepair(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "PatchRepair")
+	defer span.End()
+
+	repairID := mux.Vars(r)["repairID"]
+	span.SetAttributes(attribute.String("repairID", repairID))
+
+	actor, ok := h.repairActor(w, r)
+	if !ok {
+		span.SetStatus(codes.Error, "Caller not authorized")
+		return
+	}
+	span.SetAttributes(attribute.String("actorRole", actor.Get("X-Actor-Role")))
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Invalid request body")
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	var patch struct {
+		Status *string `json:"status"`
+	}
+	if err := json.Unmarshal(body, &patch); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Invalid merge patch")
+		http.Error(w, "Invalid merge patch", http.StatusBadRequest)
+		return
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, h.repairService.URL()+"/repairs/"+repairID, bytes.NewReader(body))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to create request")
+		h.logger.Error("Failed to create request", "error", err)
+		http.Error(w, "Failed to create request", http.StatusInternalServerError)
+		return
+	}
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	for name, values := range actor {
+		req.Header[name] = values
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to contact repair service")
+		h.logger.Error("Failed to contact repair service", "error", err, "url", req.URL.String())
+		http.Error(w, "Failed to contact repair service", downstreamStatus(w, err))
+		return
+	}
+	defer resp.Body.Close()
+
+	bodyBytes, err := io.ReadAll(resp.Body)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to read response body")
+		h.logger.Error("Failed to read response body", "error", err)
+		http.Error(w, "Failed to read response", http.StatusInternalServerError)
+		return
+	}
+	span.SetAttributes(attribute.Int("statusCode", resp.StatusCode))
+	if resp.StatusCode != http.StatusOK {
+		span.RecordError(fmt.Errorf("repair service error: %s", string(bodyBytes)))
+		span.SetStatus(codes.Error, "Failed to patch repair")
+		h.logger.Error("Repair service error", "status", resp.StatusCode, "response", logging.Redact(string(bodyBytes)))
+	} else if patch.Status != nil {
+		var repair RepairModel
+		if err := json.Unmarshal(bodyBytes, &repair); err != nil {
+			h.logger.Warn("Failed to decode patched repair, deferring lookup to broadcast worker", "error", err, "repairID", repairID)
+		}
+		h.enqueueBroadcast(ctx, broadcastJob{update: StatusUpdate{
+			RepairID: repairID,
+			UserID:   repair.UserID,
+			Status:   *patch.Status,
+		}})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(resp.StatusCode)
+	w.Write(bodyBytes)
+}
+
+// repairActor returns the X-Actor-Role and X-Actor-ID headers of a request
+// acting on a repair: the admin token makes an admin, anyone else must name
+// themselves as a user or mechanic, whom repair-service checks against the
+// repair
+func (h *RepairHandler) repairActor(w http.ResponseWriter, r *http.Request) (http.Header, bool) {
+	actor := http.Header{}
+	if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+		// Only the admin token makes an admin; a wrong token is not downgraded
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if h.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.adminToken)) != 1 {
+			h.logger.Warn("Rejected unauthorized repair request", "method", r.Method, "path", r.URL.Path, "remoteAddr", r.RemoteAddr)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return nil, false
+		}
+		actor.Set("X-Actor-Role", "admin")
+		return actor, true
+	}
+	role := r.Header.Get("X-Actor-Role")
+	if role != "user" && role != "mechanic" {
+		http.Error(w, "X-Actor-Role must be user or mechanic, or use the admin token", http.StatusForbidden)
+		return nil, false
+	}
+	actor.Set("X-Actor-Role", role)
+	if id := r.Header.Get("X-Actor-ID"); id != "" {
+		actor.Set("X-Actor-ID", id)
+	}
+	return actor, true
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-gateway/connect"
+	"api-gateway/proto"
+
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+// newRepairStreamClient connects to repair-service's gRPC server at
+// REPAIR_GRPC_ADDRESS. It returns nil when the address is not configured, which
+// leaves the repair stream bridge disabled. With mesh, it connects over Consul
+// Connect mTLS.
+func newRepairStreamClient(mesh *connect.Service, logger *slog.Logger) proto.RepairServiceClient {
+	addr := os.Getenv("REPAIR_GRPC_ADDRESS")
+	if addr == "" {
+		return nil
+	}
+	creds := insecure.NewCredentials()
+	if mesh != nil {
+		creds = credentials.NewTLS(mesh.ClientTLSConfig("repair-service"))
+	}
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		logger.Error("Failed to create repair-service gRPC client, repair stream bridge disabled", "address", addr, "error", err)
+		return nil
+	}
+	logger.Info("Enabled repair stream bridge", "address", addr)
+	return proto.NewRepairServiceClient(conn)
+}
+
+// parseStreamRepairsRequest reads ?status= (comma separated), ?userID=,
+// ?bbox=minLon,minLat,maxLon,maxLat and ?since= (Unix milliseconds) into a
+// StreamAllRepairs request
+func parseStreamRepairsRequest(r *http.Request) (*proto.StreamRepairsRequest, error) {
+	query := r.URL.Query()
+	req := &proto.StreamRepairsRequest{UserId: query.Get("userID")}
+	for _, s := range strings.Split(query.Get("status"), ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			req.Statuses = append(req.Statuses, s)
+		}
+	}
+	if bbox := query.Get("bbox"); bbox != "" {
+		parts := strings.Split(bbox, ",")
+		if len(parts) != 4 {
+			return nil, fmt.Errorf("bbox must be minLon,minLat,maxLon,maxLat")
+		}
+		var box [4]float64
+		for i, part := range parts {
+			v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid bbox value %q", part)
+			}
+			box[i] = v
+		}
+		req.Bbox = &proto.BoundingBox{MinLongitude: box[0], MinLatitude: box[1], MaxLongitude: box[2], MaxLatitude: box[3]}
+	}
+	if since := query.Get("since"); since != "" {
+		ms, err := strconv.ParseInt(since, 10, 64)
+		if err != nil || ms < 0 {
+			return nil, fmt.Errorf("since must be Unix milliseconds")
+		}
+		req.SinceUnixMs = ms
+	}
+	return req, nil
+}
+
+// repairFromProto converts a streamed repair to the gateway's JSON model
+func repairFromProto(p *proto.Repair) RepairModel {
+	repair := RepairModel{ID: p.GetId(), UserID: p.GetUserId(), Status: p.GetStatus(), AssignedTo: p.GetAssignedTo(), Region: p.GetRegion()}
+	if c := p.GetRepairCost(); c != nil {
+		repair.RepairCost = &RepairCostModel{
+			ID:         c.GetId(),
+			UserID:     c.GetUserId(),
+			RepairType: c.GetRepairType(),
+			TotalPrice: c.GetTotalPrice(),
+		}
+		if loc := c.GetUserLocation(); loc != nil {
+			repair.RepairCost.UserLocation = &Location{Longitude: loc.GetLongitude(), Latitude: loc.GetLatitude()}
+		}
+		for _, m := range c.GetMechanics() {
+			repair.RepairCost.Mechanics = append(repair.RepairCost.Mechanics, MechanicInfo{
+				ID:       m.GetId(),
+				Name:     m.GetName(),
+				Location: Location{Longitude: m.GetLocation().GetLongitude(), Latitude: m.GetLocation().GetLatitude()},
+				Distance: m.GetDistance(),
+			})
+		}
+	}
+	return repair
+}
+
+// StreamRepairs bridges repair-service's StreamAllRepairs gRPC stream to a
+// browser WebSocket, so the admin console gets the matching repairs followed
+// by newly created ones without a gRPC proxy. ?status=, ?userID=, ?bbox= and
+// ?since= are passed on as the stream filter. Like the dispatch feed, the admin
+// token may be passed as ?access_token=. Only admins holding ADMIN_API_TOKEN
+// may call it.
+func (h *RepairHandler) StreamRepairs(w http.ResponseWriter, r *http.Request) {
+	_, span := h.tracer.Start(r.Context(), "StreamRepairs")
+	defer span.End()
+
+	if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	if h.repairStream == nil {
+		span.SetStatus(codes.Error, "Repair stream bridge disabled")
+		http.Error(w, "Repair stream is not configured (REPAIR_GRPC_ADDRESS)", http.StatusServiceUnavailable)
+		return
+	}
+	req, err := parseStreamRepairsRequest(r)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	span.SetAttributes(
+		attribute.StringSlice("filter.statuses", req.Statuses),
+		attribute.String("filter.userID", req.UserId),
+		attribute.Bool("filter.bbox", req.Bbox != nil),
+		attribute.Int64("filter.sinceUnixMs", req.SinceUnixMs),
+	)
+
+	// The stream outlives the handshake request, so it gets its own context,
+	// cancelled when the console goes away
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := h.repairStream.StreamAllRepairs(ctx, req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to open repair stream")
+		h.logger.Error("Failed to open repair stream", "error", err)
+		http.Error(w, "Failed to open repair stream", http.StatusBadGateway)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to upgrade to WebSocket")
+		h.logger.Error("Failed to upgrade repair stream to WebSocket", "error", err)
+		return
+	}
+	client := newWSClient(conn, h.sendQueueSize)
+	go client.writePump(h.writeTimeout, h.logger, "repair-stream")
+	defer client.close()
+	h.logger.Info("Repair stream console connected", "statuses", req.Statuses, "userID", req.UserId)
+
+	// The console only listens; reading detects when it goes away
+	go func() {
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				client.close()
+				return
+			}
+		}
+	}()
+	go func() {
+		<-client.done
+		cancel()
+	}()
+
+	sent := 0
+	for {
+		p, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				h.logger.Info("Repair stream console disconnected", "sent", sent)
+				break
+			}
+			// Tell the console why the stream ended, e.g. an invalid filter
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Repair stream ended")
+			h.logger.Error("Repair stream ended", "error", err, "sent", sent)
+			reason := status.Convert(err).Message()
+			if len(reason) > 120 {
+				reason = reason[:120] // close frame payloads are limited to 125 bytes
+			}
+			closeCode := websocket.CloseInternalServerErr
+			if status.Code(err) == grpccodes.InvalidArgument {
+				closeCode = websocket.ClosePolicyViolation
+			}
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(closeCode, reason), time.Now().Add(h.writeTimeout))
+			break
+		}
+		message, err := json.Marshal(repairFromProto(p))
+		if err != nil {
+			h.logger.Error("Failed to marshal streamed repair", "error", err)
+			continue
+		}
+		if dropped, ok := client.enqueue(message, h.overflowPolicy); !ok {
+			h.logger.Info("Repair stream console disconnected on send queue overflow", "sent", sent)
+			break
+		} else if dropped > 0 {
+			h.logger.Warn("Repair stream send queue full, dropped oldest repairs", "dropped", dropped)
+		}
+		sent++
+	}
+	span.SetAttributes(attribute.Int("sentRepairs", sent))
+}
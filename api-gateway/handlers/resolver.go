@@ -0,0 +1,305 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Consul service metadata keys describing where an instance runs; every
+// service registers them from SERVICE_REGION and SERVICE_ZONE
+const (
+	MetaRegion = "region"
+	MetaZone   = "zone"
+)
+
+// Localities of a chosen instance relative to the gateway
+const (
+	LocalityZone   = "zone"   // same zone
+	LocalityRegion = "region" // another zone of the same region
+	LocalityRemote = "remote" // another region, or locality unknown
+)
+
+// serviceResolver keeps the healthy instances of a Consul service in the
+// gateway's zone, then its region, and spreads requests over them round-robin.
+// It only falls back to farther instances while no healthy one is closer, and
+// moves back once the local pool recovers. Instances that refuse connections
+// are ejected from the rotation for a while, before Consul's health checks
+// mark them critical.
+type serviceResolver struct {
+	consul   *api.Client
+	name     string
+	connect  bool // discover Connect native instances and reach them over https
+	region   string
+	zone     string
+	ejectFor time.Duration // how long an unreachable instance stays out of the rotation
+	logger   *slog.Logger
+	mu       sync.RWMutex
+	urls     []string // the closest healthy instances
+	ejected  map[string]time.Time
+	locality string
+	next     atomic.Uint64
+	breaker  *circuitBreaker
+}
+
+func newServiceResolver(consul *api.Client, name, region, zone string, logger *slog.Logger) *serviceResolver {
+	return &serviceResolver{
+		consul:   consul,
+		name:     name,
+		region:   region,
+		zone:     zone,
+		ejectFor: time.Duration(envInt("LB_EJECT_SECONDS", 30)) * time.Second,
+		logger:   logger,
+		ejected:  make(map[string]time.Time),
+		breaker:  newCircuitBreaker(name, logger),
+	}
+}
+
+// URL returns the base URL of the next instance in the rotation, skipping
+// ejected ones while any other is left
+func (s *serviceResolver) URL() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.pick("")
+}
+
+// pick returns the next instance other than skip that is not ejected, else
+// the next one at all. The caller holds the lock.
+func (s *serviceResolver) pick(skip string) string {
+	if len(s.urls) == 0 {
+		return ""
+	}
+	now := time.Now()
+	start := s.next.Add(1)
+	for i := range uint64(len(s.urls)) {
+		u := s.urls[(start+i)%uint64(len(s.urls))]
+		if u != skip && !now.Before(s.ejected[u]) {
+			return u
+		}
+	}
+	return s.urls[start%uint64(len(s.urls))]
+}
+
+// has reports whether host is the host of one of the instances
+func (s *serviceResolver) has(host string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, instance := range s.urls {
+		if u, err := url.Parse(instance); err == nil && u.Host == host {
+			return true
+		}
+	}
+	return false
+}
+
+// eject takes the instance at host out of the rotation and returns another
+// one to fail over to, or "" when there is none
+func (s *serviceResolver) eject(host string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var failed string
+	for _, instance := range s.urls {
+		if u, err := url.Parse(instance); err == nil && u.Host == host {
+			failed = instance
+		}
+	}
+	if failed == "" {
+		return ""
+	}
+	if time.Now().After(s.ejected[failed]) {
+		s.logger.Warn("Ejected unreachable "+s.name+" instance", "url", failed, "for", s.ejectFor, "instances", len(s.urls))
+	}
+	s.ejected[failed] = time.Now().Add(s.ejectFor)
+	if next := s.pick(failed); next != failed {
+		return next
+	}
+	return ""
+}
+
+// resolve blocks until a healthy instance is registered, then keeps following
+// Consul's health changes in the background
+func (s *serviceResolver) resolve() {
+	var index uint64
+	for {
+		entries, meta, err := s.instances(nil)
+		if err != nil {
+			s.logger.Error("Failed to discover "+s.name, "error", err)
+			time.Sleep(2 * time.Second)
+			continue
+		}
+		if len(entries) > 0 {
+			s.update(entries)
+			index = meta.LastIndex
+			break
+		}
+		s.logger.Info("Waiting for " + s.name + " to be registered")
+		time.Sleep(2 * time.Second)
+	}
+	go s.watch(index)
+}
+
+// instances returns the healthy instances, only the Connect native ones in
+// Connect mode
+func (s *serviceResolver) instances(q *api.QueryOptions) ([]*api.ServiceEntry, *api.QueryMeta, error) {
+	if s.connect {
+		return s.consul.Health().Connect(s.name, "", true, q)
+	}
+	return s.consul.Health().Service(s.name, "", true, q)
+}
+
+// instanceURLs returns the instances in the rotation
+func (s *serviceResolver) instanceURLs() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.urls)
+}
+
+// watch replaces the rotation whenever the set of healthy instances changes,
+// using Consul blocking queries
+func (s *serviceResolver) watch(index uint64) {
+	for {
+		entries, meta, err := s.instances(&api.QueryOptions{WaitIndex: index, WaitTime: 5 * time.Minute})
+		if err != nil {
+			s.logger.Error("Failed to watch "+s.name+" instances, retrying", "error", err)
+			time.Sleep(2 * time.Second)
+			continue
+		}
+		if meta.LastIndex < index {
+			// Consul's index went backwards (e.g. a restore); start over
+			index = 0
+			continue
+		}
+		index = meta.LastIndex
+		if len(entries) == 0 {
+			s.logger.Warn("No healthy "+s.name+" instances, keeping the last ones", "urls", s.instanceURLs())
+			continue
+		}
+		s.update(entries)
+	}
+}
+
+// update replaces the rotation with the closest healthy instances
+func (s *serviceResolver) update(entries []*api.ServiceEntry) {
+	candidates, locality := closestInstances(entries, s.region, s.zone)
+	urls := make([]string, len(candidates))
+	for i, entry := range candidates {
+		urls[i] = s.instanceURL(entry)
+	}
+	sort.Strings(urls)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if slices.Equal(urls, s.urls) {
+		s.locality = locality
+		return
+	}
+	previous, previousLocality := s.urls, s.locality
+	s.urls, s.locality = urls, locality
+	for instance := range s.ejected {
+		if !slices.Contains(urls, instance) {
+			delete(s.ejected, instance)
+		}
+	}
+	if len(previous) == 0 {
+		s.logger.Info("Discovered "+s.name+" at", "urls", urls, "locality", locality)
+		return
+	}
+	if locality != previousLocality {
+		s.logger.Warn("Switched "+s.name+" instances", "from", previous, "fromLocality", previousLocality, "to", urls, "locality", locality)
+		return
+	}
+	s.logger.Info("Updated "+s.name+" instances", "from", previous, "to", urls, "locality", locality)
+}
+
+// closestInstances returns the healthy instances in the nearest locality that
+// has any: the gateway's zone, then its region, then everything else
+func closestInstances(entries []*api.ServiceEntry, region, zone string) ([]*api.ServiceEntry, string) {
+	var sameZone, sameRegion []*api.ServiceEntry
+	for _, entry := range entries {
+		meta := entry.Service.Meta
+		if region != "" && meta[MetaRegion] != region {
+			continue
+		}
+		if zone != "" && meta[MetaZone] == zone {
+			sameZone = append(sameZone, entry)
+		} else if region != "" {
+			sameRegion = append(sameRegion, entry)
+		}
+	}
+	switch {
+	case len(sameZone) > 0:
+		return sameZone, LocalityZone
+	case len(sameRegion) > 0:
+		return sameRegion, LocalityRegion
+	default:
+		return entries, LocalityRemote
+	}
+}
+
+func (s *serviceResolver) instanceURL(entry *api.ServiceEntry) string {
+	address := entry.Service.Address
+	if address == "" {
+		address = entry.Node.Address
+	}
+	scheme := "http"
+	if s.connect {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s:%d", scheme, address, entry.Service.Port)
+}
+
+// failoverTransport retries requests whose connection to a service instance
+// could not be established on another instance of the service, ejecting the
+// unreachable one. Such requests never reached a server, so retrying them is
+// safe whatever their method.
+type failoverTransport struct {
+	next      http.RoundTripper
+	resolvers []*serviceResolver
+}
+
+func (t *failoverTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err == nil || !isDialError(err) {
+		return resp, err
+	}
+	for _, s := range t.resolvers {
+		if !s.has(req.URL.Host) {
+			continue
+		}
+		next := s.eject(req.URL.Host)
+		if next == "" || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+			return resp, err
+		}
+		target, parseErr := url.Parse(next)
+		if parseErr != nil {
+			return resp, err
+		}
+		retry := req.Clone(req.Context())
+		retry.URL.Scheme, retry.URL.Host, retry.Host = target.Scheme, target.Host, ""
+		if req.GetBody != nil {
+			if retry.Body, parseErr = req.GetBody(); parseErr != nil {
+				return resp, err
+			}
+		}
+		s.logger.Info("Failing over to another "+s.name+" instance", "from", req.URL.Host, "to", target.Host, "error", err)
+		return t.next.RoundTrip(retry)
+	}
+	return resp, err
+}
+
+// isDialError reports whether err happened before the request was sent:
+// connecting to the instance failed
+func isDialError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
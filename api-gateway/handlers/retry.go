@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"time"
+)
+
+// retryTransport retries idempotent requests (GET, HEAD, OPTIONS, PUT and
+// DELETE) that failed with a transport error or a 5xx answer, waiting an
+// exponentially growing, jittered backoff between attempts, so a transient
+// hiccup of a service does not reach users as a 500. Requests whose body
+// cannot be replayed, and attempts that would outlive the request's deadline,
+// are not retried.
+type retryTransport struct {
+	next        http.RoundTripper
+	maxAttempts int           // including the first; 1 disables retries
+	backoff     time.Duration // before the first retry, doubling up to maxBackoff
+	maxBackoff  time.Duration
+	logger      *slog.Logger
+}
+
+// newRetryTransport reads RETRY_MAX_ATTEMPTS (default 3), RETRY_BACKOFF_MS
+// (default 100) and RETRY_MAX_BACKOFF_MS (default 1000)
+func newRetryTransport(next http.RoundTripper, logger *slog.Logger) *retryTransport {
+	return &retryTransport{
+		next:        next,
+		maxAttempts: max(envInt("RETRY_MAX_ATTEMPTS", 3), 1),
+		backoff:     time.Duration(max(envInt("RETRY_BACKOFF_MS", 100), 1)) * time.Millisecond,
+		maxBackoff:  time.Duration(max(envInt("RETRY_MAX_BACKOFF_MS", 1000), 1)) * time.Millisecond,
+		logger:      logger,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.maxAttempts < 2 || !isIdempotent(req.Method) || (req.Body != nil && req.Body != http.NoBody && req.GetBody == nil) {
+		return t.next.RoundTrip(req)
+	}
+	ctx := req.Context()
+	backoff := t.backoff
+	for attempt := 1; ; attempt++ {
+		resp, err := t.next.RoundTrip(req)
+		if attempt >= t.maxAttempts || !isRetryable(resp, err) || ctx.Err() != nil {
+			return resp, err
+		}
+
+		// Full jitter spreads the retries of many requests failing at once
+		wait := rand.N(backoff) + 1
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= wait {
+			return resp, err
+		}
+		retry := req.Clone(ctx)
+		if req.GetBody != nil {
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, err
+			}
+			retry.Body = body
+		}
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+		}
+		t.logger.Info("Retrying downstream request", "method", req.Method, "url", req.URL.String(), "attempt", attempt+1, "status", status, "error", err, "backoff", wait)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+		req = retry
+		backoff = min(backoff*2, t.maxBackoff)
+	}
+}
+
+// isIdempotent reports whether a request with method may be sent twice
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// isRetryable reports whether an attempt failed transiently: a transport
+// error or a 5xx answer other than 501 and repair-service's read-only 503
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	if resp.StatusCode == http.StatusServiceUnavailable && resp.Header.Get("X-Read-Only") != "" {
+		return false
+	}
+	return resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"api-gateway/slowlog"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SlowLog returns the recorder timing the gateway's handlers, downstream calls
+// and Mongo commands
+func (h *RepairHandler) SlowLog() *slowlog.Recorder {
+	return h.slow
+}
+
+// SlowOperations returns the operations that exceeded their SLOW_* threshold,
+// most frequent first. Only admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) SlowOperations(w http.ResponseWriter, r *http.Request) {
+	_, span := h.tracer.Start(r.Context(), "SlowOperations")
+	defer span.End()
+
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+	counts := h.slow.Counts()
+	span.SetAttributes(attribute.Int("operationCount", len(counts)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(counts)
+}
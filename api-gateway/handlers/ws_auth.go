@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// CloseUnauthorized closes /ws connections that did not identify with a valid
+// token in time; clients should fetch a new token before reconnecting
+const CloseUnauthorized = 4003
+
+var errInvalidWSToken = errors.New("invalid or expired WebSocket token")
+
+// wsIdentity is who a /ws connection belongs to
+type wsIdentity struct {
+	UserID string
+	Role   string // user or mechanic
+}
+
+// wsTokenClaims are the signed contents of a WebSocket token
+type wsTokenClaims struct {
+	Sub  string `json:"sub"`
+	Role string `json:"role"`
+	Exp  int64  `json:"exp"` // Unix seconds
+}
+
+// wsAuthMessage is the first message of a connection opened without a token
+// header: {"type":"auth","token":"..."}
+type wsAuthMessage struct {
+	Type  string `json:"type"`
+	Token string `json:"token"`
+}
+
+// wsAuth identifies /ws connections by short-lived tokens signed with
+// WS_TOKEN_SECRET, sent as a bearer header or as the first message, so user
+// and mechanic IDs stay out of URLs, access logs and proxy logs. The legacy
+// ?userID=&role= identification is accepted while WS_QUERY_IDENTITY is on,
+// and counted so its remaining clients can be found before it is turned off.
+type wsAuth struct {
+	secret           []byte
+	ttl              time.Duration // lifetime of issued tokens
+	handshakeTimeout time.Duration // wait for the first message
+	allowQuery       bool
+	queryConnections atomic.Int64
+}
+
+func newWSAuth(logger *slog.Logger) *wsAuth {
+	a := &wsAuth{
+		secret:           []byte(os.Getenv("WS_TOKEN_SECRET")),
+		ttl:              time.Duration(envInt("WS_TOKEN_TTL_SECONDS", 300)) * time.Second,
+		handshakeTimeout: time.Duration(envInt("WS_AUTH_TIMEOUT_SECONDS", 10)) * time.Second,
+		allowQuery:       os.Getenv("WS_QUERY_IDENTITY") != "false",
+	}
+	if len(a.secret) == 0 {
+		// Tokens then only verify on the instance that issued them
+		a.secret = make([]byte, 32)
+		rand.Read(a.secret)
+		logger.Warn("WS_TOKEN_SECRET is not set, WebSocket tokens are signed with a per-instance key")
+	}
+	if a.allowQuery {
+		logger.Warn("WS_QUERY_IDENTITY is on: /ws still accepts the deprecated ?userID= identification")
+	}
+	return a
+}
+
+// issue signs a token for id, valid for the configured lifetime
+func (a *wsAuth) issue(id wsIdentity, now time.Time) (string, time.Time) {
+	expires := now.Add(a.ttl)
+	payload, _ := json.Marshal(wsTokenClaims{Sub: id.UserID, Role: id.Role, Exp: expires.Unix()})
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + a.sign(encoded), expires
+}
+
+// verify returns the identity of a token that is correctly signed and not
+// expired
+func (a *wsAuth) verify(token string, now time.Time) (wsIdentity, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || subtle.ConstantTimeCompare([]byte(signature), []byte(a.sign(encoded))) != 1 {
+		return wsIdentity{}, errInvalidWSToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return wsIdentity{}, errInvalidWSToken
+	}
+	var claims wsTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Sub == "" || now.Unix() >= claims.Exp {
+		return wsIdentity{}, errInvalidWSToken
+	}
+	return wsIdentity{UserID: claims.Sub, Role: claims.Role}, nil
+}
+
+func (a *wsAuth) sign(encoded string) string {
+	mac := hmac.New(sha256.New, a.secret)
+	mac.Write([]byte(encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// fromRequest identifies a /ws request before the upgrade: by its bearer
+// token, else by the deprecated query parameters when allowed. A nil identity
+// without error leaves it to the first message.
+func (a *wsAuth) fromRequest(r *http.Request) (*wsIdentity, bool, error) {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		id, err := a.verify(token, time.Now())
+		if err != nil {
+			return nil, false, err
+		}
+		return &id, false, nil
+	}
+	query := r.URL.Query()
+	if userID := query.Get("userID"); userID != "" {
+		if !a.allowQuery {
+			return nil, true, errors.New("?userID= is no longer accepted; identify with a WebSocket token")
+		}
+		a.queryConnections.Add(1)
+		role := "user"
+		if query.Get("role") == "mechanic" {
+			role = "mechanic"
+		}
+		return &wsIdentity{UserID: userID, Role: role}, true, nil
+	}
+	return nil, false, nil
+}
+
+// handshake reads the first message of conn, which must carry a valid token,
+// and confirms the identity to the client
+func (a *wsAuth) handshake(conn *websocket.Conn) (wsIdentity, error) {
+	conn.SetReadDeadline(time.Now().Add(a.handshakeTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+	var msg wsAuthMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		return wsIdentity{}, fmt.Errorf("no auth message: %w", err)
+	}
+	if msg.Type != "auth" {
+		return wsIdentity{}, errors.New(`the first message must be {"type":"auth","token":...}`)
+	}
+	id, err := a.verify(msg.Token, time.Now())
+	if err != nil {
+		return wsIdentity{}, err
+	}
+	err = conn.WriteJSON(map[string]string{"type": "authenticated", "userID": id.UserID, "role": id.Role})
+	return id, err
+}
+
+// IssueWebSocketToken returns a short-lived token identifying the caller on
+// /ws. The caller names themselves with X-Actor-Role (user or mechanic) and
+// X-Actor-ID, as on the other user routes.
+func (h *RepairHandler) IssueWebSocketToken(w http.ResponseWriter, r *http.Request) {
+	_, span := h.tracer.Start(r.Context(), "IssueWebSocketToken")
+	defer span.End()
+
+	role, actorID := r.Header.Get("X-Actor-Role"), r.Header.Get("X-Actor-ID")
+	if (role != "user" && role != "mechanic") || actorID == "" {
+		span.SetStatus(codes.Error, "X-Actor-Role and X-Actor-ID are required")
+		http.Error(w, "X-Actor-Role must be user or mechanic, with an X-Actor-ID", http.StatusForbidden)
+		return
+	}
+	span.SetAttributes(attribute.String("actorRole", role), attribute.String("userID", actorID))
+
+	token, expires := h.wsAuth.issue(wsIdentity{UserID: actorID, Role: role}, time.Now())
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]any{"token": token, "expiresAt": expires.UTC()})
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Close codes sent to WebSocket connections the gateway drops in favour of
+// newer ones; clients should not reconnect on them
+const (
+	CloseReplaced = 4000 // the same device opened a newer connection
+	CloseEvicted  = 4001 // the user exceeded WS_MAX_CONNECTIONS_PER_USER
+)
+
+// wsLimits keeps rapid reconnects from piling up connections for one user:
+// a device's new connection replaces its old one, a user's oldest connection
+// is evicted past the cap, and pings find connections that died silently
+type wsLimits struct {
+	maxPerUser   int           // 0 means no cap
+	pingInterval time.Duration // 0 disables pings and read deadlines
+	replaced     atomic.Int64
+	evicted      atomic.Int64
+	timedOut     atomic.Int64
+}
+
+// registerClient adds a user's connection. Older connections of the same
+// device, and the oldest ones beyond the per-user cap, are unregistered and
+// closed.
+func (h *RepairHandler) registerClient(userID string, client *wsClient) {
+	h.clientsMutex.Lock()
+	kept := make([]*wsClient, 0, len(h.clients[userID])+1)
+	var replaced, evicted []*wsClient
+	for _, c := range h.clients[userID] {
+		if client.deviceID != "" && c.deviceID == client.deviceID {
+			replaced = append(replaced, c)
+			continue
+		}
+		kept = append(kept, c)
+	}
+	kept = append(kept, client)
+	if max := h.wsLimits.maxPerUser; max > 0 && len(kept) > max {
+		// Connections are appended as they open, so the oldest come first
+		evicted = append(evicted, kept[:len(kept)-max]...)
+		kept = kept[len(kept)-max:]
+	}
+	h.clients[userID] = kept
+	h.clientsMutex.Unlock()
+
+	for _, c := range replaced {
+		h.wsLimits.replaced.Add(1)
+		c.closeWith(CloseReplaced, "replaced by a newer connection from this device")
+		h.logger.Info("Replaced WebSocket connection from the same device", "userID", userID, "deviceID", c.deviceID, "age", time.Since(c.connectedAt))
+	}
+	for _, c := range evicted {
+		h.wsLimits.evicted.Add(1)
+		c.closeWith(CloseEvicted, "too many connections")
+		h.logger.Warn("Evicted oldest WebSocket connection", "userID", userID, "deviceID", c.deviceID, "maxPerUser", h.wsLimits.maxPerUser)
+	}
+}
+
+// unregisterClient removes a user's connection if it is still registered;
+// replaced and evicted connections were already removed
+func (h *RepairHandler) unregisterClient(userID string, client *wsClient) {
+	h.clientsMutex.Lock()
+	defer h.clientsMutex.Unlock()
+	clients := h.clients[userID]
+	for i, c := range clients {
+		if c == client {
+			h.clients[userID] = append(clients[:i:i], clients[i+1:]...)
+			break
+		}
+	}
+	if len(h.clients[userID]) == 0 {
+		delete(h.clients, userID)
+	}
+}
+
+// keepAlive expires the connection's read deadline unless the client answers
+// the pings its writer sends, so half-open connections are unregistered
+func (h *RepairHandler) keepAlive(conn *websocket.Conn) {
+	if h.wsLimits.pingInterval <= 0 {
+		return
+	}
+	wait := 2 * h.wsLimits.pingInterval
+	conn.SetReadDeadline(time.Now().Add(wait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wait))
+	})
+}
+
+// registerWebSocketMetrics exposes the connection counts to Prometheus,
+// read when scraped
+func (h *RepairHandler) registerWebSocketMetrics() {
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "websocket_connections",
+		Help: "Open user WebSocket connections on this gateway",
+	}, func() float64 {
+		h.clientsMutex.Lock()
+		defer h.clientsMutex.Unlock()
+		connections := 0
+		for _, clients := range h.clients {
+			connections += len(clients)
+		}
+		return float64(connections)
+	})
+	promauto.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: "websocket_users",
+		Help: "Users with at least one open WebSocket connection on this gateway",
+	}, func() float64 {
+		h.clientsMutex.Lock()
+		defer h.clientsMutex.Unlock()
+		return float64(len(h.clients))
+	})
+	for reason, count := range map[string]*atomic.Int64{
+		"replaced":  &h.wsLimits.replaced,
+		"evicted":   &h.wsLimits.evicted,
+		"timed_out": &h.wsLimits.timedOut,
+	} {
+		promauto.NewCounterFunc(prometheus.CounterOpts{
+			Name:        "websocket_connections_dropped_total",
+			Help:        "WebSocket connections the gateway closed, by reason",
+			ConstLabels: prometheus.Labels{"reason": reason},
+		}, func() float64 {
+			return float64(count.Load())
+		})
+	}
+}
+
+// WebSocketMetrics are the gateway's user WebSocket connection counts
+type WebSocketMetrics struct {
+	Connections   int            `json:"connections"`
+	Users         int            `json:"users"`
+	MaxPerUser    int            `json:"maxPerUser"`
+	Replaced      int64          `json:"replaced"`      // since start, by a newer connection of the same device
+	Evicted       int64          `json:"evicted"`       // since start, over the per-user cap
+	TimedOut      int64          `json:"timedOut"`      // since start, missed pings
+	ByConnections map[int]int    `json:"byConnections"` // number of users by their connection count
+	TopUsers      map[string]int `json:"topUsers"`      // users with the most connections
+	WithoutDevice int            `json:"withoutDevice"` // connections opened without a deviceID
+	QueryIdentity int64          `json:"queryIdentity"` // since start, identified by the deprecated ?userID=
+	CollectedAt   time.Time      `json:"collectedAt"`
+}
+
+// WebSocketConnections reports this gateway's WebSocket connection counts.
+// Only admins holding ADMIN_API_TOKEN may call it.
+func (h *RepairHandler) WebSocketConnections(w http.ResponseWriter, r *http.Request) {
+	_, span := h.tracer.Start(r.Context(), "WebSocketConnections")
+	defer span.End()
+
+	if !h.authorizeAdmin(w, r) {
+		return
+	}
+
+	metrics := WebSocketMetrics{
+		MaxPerUser:    h.wsLimits.maxPerUser,
+		Replaced:      h.wsLimits.replaced.Load(),
+		Evicted:       h.wsLimits.evicted.Load(),
+		TimedOut:      h.wsLimits.timedOut.Load(),
+		QueryIdentity: h.wsAuth.queryConnections.Load(),
+		ByConnections: map[int]int{},
+		TopUsers:      map[string]int{},
+		CollectedAt:   time.Now().UTC(),
+	}
+	type userCount struct {
+		userID string
+		count  int
+	}
+	var counts []userCount
+	h.clientsMutex.Lock()
+	for userID, clients := range h.clients {
+		metrics.Users++
+		metrics.Connections += len(clients)
+		metrics.ByConnections[len(clients)]++
+		counts = append(counts, userCount{userID, len(clients)})
+		for _, c := range clients {
+			if c.deviceID == "" {
+				metrics.WithoutDevice++
+			}
+		}
+	}
+	h.clientsMutex.Unlock()
+
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].count != counts[j].count {
+			return counts[i].count > counts[j].count
+		}
+		return counts[i].userID < counts[j].userID
+	})
+	for i := 0; i < len(counts) && i < 10; i++ {
+		metrics.TopUsers[counts[i].userID] = counts[i].count
+	}
+
+	span.SetAttributes(
+		attribute.Int("connections", metrics.Connections),
+		attribute.Int("users", metrics.Users),
+	)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(metrics)
+}
@@ -4,8 +4,28 @@ import (
 	"log/slog"
 	"os"
 	"context"
+	"fmt"
+	"strings"
 )
 
+// level is the minimum level of both handlers, changed at runtime by SetLevel
+var level = new(slog.LevelVar)
+
+// SetLevel sets the minimum level logged, debug, info, warn or error; an
+// empty name means info
+func SetLevel(name string) error {
+	if strings.TrimSpace(name) == "" {
+		level.Set(slog.LevelInfo)
+		return nil
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return fmt.Errorf("invalid log level %q: %w", name, err)
+	}
+	level.Set(l)
+	return nil
+}
+
 // multiHandler is a custom slog.Handler that combines multiple handlers
 type multiHandler []slog.Handler
 
@@ -54,11 +74,11 @@ func NewLogger() (*slog.Logger, *os.File, error) {
 
 	fileHandler := slog.NewJSONHandler(logFile, &slog.HandlerOptions{
 		AddSource: true,
-		Level:     slog.LevelInfo,
+		Level:     level,
 	})
 	terminalHandler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
 		AddSource: true,
-		Level:     slog.LevelInfo,
+		Level:     level,
 	})
 
 	logger := slog.New(multiHandler{fileHandler, terminalHandler})
@@ -0,0 +1,33 @@
+package logging
+
+import "regexp"
+
+// redactedValue replaces sensitive values in log output
+const redactedValue = "[REDACTED]"
+
+var (
+	// sensitiveJSONField matches string values of JSON fields that carry
+	// credentials or phone numbers
+	sensitiveJSONField = regexp.MustCompile(`(?i)("(?:[a-z_]*token|password|secret|authorization|api_?key|phone[a-z_]*)"\s*:\s*)"[^"]*"`)
+	// sensitiveQueryParam matches credential query parameters
+	sensitiveQueryParam = regexp.MustCompile(`(?i)\b((?:[a-z_]*token|password|secret|api_?key)=)[^&\s]+`)
+	// bearerToken matches bearer credentials wherever they appear
+	bearerToken = regexp.MustCompile(`(?i)\bBearer\s+[A-Za-z0-9\-._~+/]+=*`)
+	// jwt matches JSON Web Tokens (three base64url segments, header starting with eyJ)
+	jwt = regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	// phoneNumber matches international (+49 30 1234567) and North American
+	// ((555) 123-4567, 555-123-4567) phone numbers; plain decimals such as
+	// coordinates and prices are left alone
+	phoneNumber = regexp.MustCompile(`\+\d{1,3}[\s.-]?(?:\(\d{1,4}\)[\s.-]?)?\d{2,4}(?:[\s.-]?\d{2,4}){1,4}|\(\d{3}\)\s?\d{3}[\s.-]\d{4}\b|\b\d{3}[\s.-]\d{3}[\s.-]\d{4}\b`)
+)
+
+// Redact masks tokens, credentials and phone numbers in s so request and
+// response bodies can be logged without leaking personal data
+func Redact(s string) string {
+	s = sensitiveJSONField.ReplaceAllString(s, `$1"`+redactedValue+`"`)
+	s = sensitiveQueryParam.ReplaceAllString(s, "${1}"+redactedValue)
+	s = bearerToken.ReplaceAllString(s, "Bearer "+redactedValue)
+	s = jwt.ReplaceAllString(s, redactedValue)
+	s = phoneNumber.ReplaceAllString(s, redactedValue)
+	return s
+}
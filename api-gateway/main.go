@@ -1,16 +1,24 @@
 package main
 
 import (
+	"api-gateway/config"
 	"api-gateway/handlers"
 	"api-gateway/logging"
+	"api-gateway/metrics"
+	"api-gateway/middleware"
 	"context"
+	"flag"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -25,6 +33,12 @@ import (
 )
 
 func main() {
+	selfTest := flag.Bool("selftest", false, "check connectivity to dependencies, print a JSON report and exit")
+	flag.Parse()
+	if *selfTest {
+		os.Exit(runSelfTest())
+	}
+
 	// Initialize structured logging
 	logger, logFile, err := logging.NewLogger()
 	if err != nil {
@@ -60,19 +74,149 @@ func main() {
 	// Add OpenTelemetry middleware
 	r.Use(otelmux.Middleware("api-gateway"))
 
+	// Log and count handlers slower than SLOW_HANDLER_MS
+	r.Use(repairHandler.SlowLog().Middleware)
+
+	// Count and time requests per route for Prometheus
+	r.Use(metrics.Middleware)
+
+	// Reloadable settings: environment, CONFIG_FILE (re-read on SIGHUP) and
+	// Consul KV under CONFIG_KV_PREFIX
+	settings := config.NewStore(repairHandler.ConsulClient(), logger)
+	repairHandler.SetConfig(settings)
+	settings.Subscribe(func(get func(string) string) {
+		if err := logging.SetLevel(get("LOG_LEVEL")); err != nil {
+			logger.Error("Ignoring invalid LOG_LEVEL", "error", err, "app", "api-gateway")
+		}
+	})
+	go func() {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		for range hup {
+			logger.Info("Received SIGHUP, reloading configuration", "app", "api-gateway")
+			settings.Reload()
+		}
+	}()
+
+	// Structured access logging with sampled, redacted bodies
+	accessLogger := middleware.NewAccessLogger(logger)
+	settings.Subscribe(accessLogger.Configure)
+	r.Use(accessLogger.Middleware)
+
+	// Adapt request/response shapes for legacy app versions
+	r.Use(middleware.NewLegacyAdapter(logger).Middleware)
+
+	// Maintenance mode and per-route kill switches, controlled through Consul KV
+	maintenance := middleware.NewMaintenance(repairHandler.ConsulClient(), logger)
+	repairHandler.SetMaintenance(maintenance)
+	r.Use(maintenance.Middleware)
+
+	// Overall request deadlines, propagated to the services as a time budget
+	deadline := middleware.NewDeadline(logger)
+	settings.Subscribe(deadline.Configure)
+	r.Use(deadline.Middleware)
+
+	// Require a JWT on the routes in JWT_ROUTES and bind its user to the request
+	jwtAuth := middleware.NewJWTAuth(logger)
+	r.Use(jwtAuth.Middleware)
+
+	// Admit only mechanics to the mechanic routes and users to their own
+	// repairs, verifying the JWTs of routes outside JWT_ROUTES
+	r.Use(middleware.NewRBAC(logger, jwtAuth).Middleware)
+
+	// Reject bodies that do not match the OpenAPI spec, see REQUEST_VALIDATION
+	validation := middleware.NewRequestValidation(logger)
+	settings.Subscribe(validation.Configure)
+	r.Use(validation.Middleware)
+
 	// Define endpoints
 	r.HandleFunc("/health", repairHandler.HealthCheck).Methods("GET")
+	r.HandleFunc("/openapi.json", repairHandler.OpenAPISpec).Methods("GET")
+	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
 	r.HandleFunc("/repairs", repairHandler.CreateRepair).Methods("POST")
 	r.HandleFunc("/repairs/estimate", repairHandler.EstimateRepairCost).Methods("POST")
 	r.HandleFunc("/repairs/nearby", repairHandler.ListNearbyRepairs).Methods("GET")
+	r.HandleFunc("/repairs/{repairID}/assign", repairHandler.AssignRepair).Methods("POST")
 	r.HandleFunc("/repairs/cost/{costID}", repairHandler.GetRepairCost).Methods("GET")
+	r.HandleFunc("/repairs/cost/{costID}/claim", repairHandler.ClaimQuote).Methods("POST")
 	r.HandleFunc("/repairs/{repairID}", repairHandler.GetRepair).Methods("GET")
 	r.HandleFunc("/repairs/{repairID}", repairHandler.UpdateRepair).Methods("PUT")
+	r.HandleFunc("/repairs/{repairID}", repairHandler.PatchRepair).Methods("PATCH")
+	r.HandleFunc("/repairs/{repairID}", repairHandler.DeleteRepair).Methods("DELETE")
+	r.HandleFunc("/repairs/{repairID}/cancel", repairHandler.CancelRepair).Methods("POST")
+	r.HandleFunc("/repairs/{repairID}/receipt", repairHandler.GetReceipt).Methods("GET")
+	r.HandleFunc("/repairs/{repairID}/full", repairHandler.GetRepairFullView).Methods("GET")
+	r.HandleFunc("/repairs/{repairID}/updates", repairHandler.PollRepairUpdates).Methods("GET")
+	r.HandleFunc("/repairs/{repairID}/amendments", repairHandler.ListAmendments).Methods("GET")
+	r.HandleFunc("/repairs/{repairID}/amendments", repairHandler.RequestAmendment).Methods("POST")
+	r.HandleFunc("/repairs/{repairID}/amendments/{amendmentID}", repairHandler.DecideAmendment).Methods("PUT")
+	r.HandleFunc("/questionnaires/{repairType}", repairHandler.GetQuestionnaire).Methods("GET")
+	r.HandleFunc("/bundles/{bundleID}", repairHandler.GetRepairBundle).Methods("GET")
+	r.HandleFunc("/fleets/{fleetID}", repairHandler.GetFleetAccount).Methods("GET")
+	r.HandleFunc("/fleets/{fleetID}/members/{userID}", repairHandler.FleetMember).Methods("PUT", "DELETE")
+	r.HandleFunc("/fleets/{fleetID}/usage", repairHandler.FleetUsage).Methods("GET")
+	r.HandleFunc("/fleets/{fleetID}/invoices", repairHandler.FleetInvoices).Methods("GET")
+	r.HandleFunc("/fleets/{fleetID}/invoices/{month}", repairHandler.FleetInvoice).Methods("GET")
+	r.HandleFunc("/mechanics/search", repairHandler.SearchMechanics).Methods("GET")
+	r.HandleFunc("/mechanics/{mechanicID}/positioning", repairHandler.GetPositioningHints).Methods("GET")
+	r.HandleFunc("/mechanics/{mechanicID}/notification-preferences", repairHandler.MechanicNotificationPreferences).Methods("GET", "PUT")
+	r.HandleFunc("/mechanics/{mechanicID}/absences", repairHandler.MechanicAbsences).Methods("GET", "POST")
+	r.HandleFunc("/mechanics/{mechanicID}/absences/{absenceID}", repairHandler.DeleteMechanicAbsence).Methods("DELETE")
+	r.HandleFunc("/mechanics/{mechanicID}/route", repairHandler.GetMechanicRoute).Methods("GET")
+	r.HandleFunc("/mechanics/{mechanicID}/home", repairHandler.GetMechanicHome).Methods("GET")
+	r.HandleFunc("/mechanics/{mechanicID}/location", repairHandler.UpdateMechanicLocation).Methods("PUT")
+	r.HandleFunc("/users/{userID}/phone", repairHandler.GetPhoneVerification).Methods("GET")
+	r.HandleFunc("/users/{userID}/phone/verification", repairHandler.StartPhoneVerification).Methods("POST")
+	r.HandleFunc("/users/{userID}/phone/verification/confirm", repairHandler.ConfirmPhoneVerification).Methods("POST")
+	r.HandleFunc("/users/{userID}/email", repairHandler.EmailPreferences).Methods("GET", "PUT")
+	r.HandleFunc("/users/{userID}/blocks", repairHandler.ListBlocks).Methods("GET")
+	r.HandleFunc("/users/{userID}/blocks/{mechanicID}", repairHandler.BlockMechanic).Methods("PUT", "DELETE")
+	r.HandleFunc("/admin/users/{userID}/blacklist", repairHandler.BlacklistUser).Methods("PUT", "DELETE")
+	r.HandleFunc("/users/{userID}/export", repairHandler.ExportUserData).Methods("GET")
+	r.HandleFunc("/admin/users/{userID}/erasure", repairHandler.EraseUser).Methods("GET", "POST")
+	r.HandleFunc("/admin/outbox/redrive", repairHandler.RedriveOutbox).Methods("POST")
+	r.HandleFunc("/admin/outbox/redrives", repairHandler.OutboxRedrives).Methods("GET")
+	r.HandleFunc("/admin/bundles", repairHandler.RepairBundles).Methods("GET", "POST")
+	r.HandleFunc("/admin/bundles/{bundleID}", repairHandler.DissolveRepairBundle).Methods("DELETE")
+	r.HandleFunc("/admin/fleets", repairHandler.CreateFleetAccount).Methods("POST")
+	r.HandleFunc("/admin/fleets/{fleetID}/invoices/{month}", repairHandler.IssueFleetInvoice).Methods("POST")
+	r.HandleFunc("/admin/email/templates", repairHandler.EmailTemplates).Methods("GET")
+	r.HandleFunc("/admin/email/templates/{name}", repairHandler.SaveEmailTemplate).Methods("PUT")
+	r.HandleFunc("/admin/email/deliveries", repairHandler.EmailDeliveries).Methods("GET")
+	r.HandleFunc("/admin/questionnaires/{repairType}", repairHandler.SaveQuestionnaire).Methods("PUT")
+	r.HandleFunc("/admin/pricing/rules", repairHandler.PricingRules).Methods("GET")
+	r.HandleFunc("/admin/pricing/rules/{ruleID}", repairHandler.PricingRule).Methods("PUT", "DELETE")
+	r.HandleFunc("/admin/pricing/evaluate", repairHandler.EvaluatePricing).Methods("POST")
+	r.HandleFunc("/admin/pricing/weather", repairHandler.WeatherFlag).Methods("GET", "PUT")
+	r.HandleFunc("/admin/dispatch/fairness", repairHandler.FairnessPolicy).Methods("GET", "PUT")
+	r.HandleFunc("/admin/dispatch/assignments", repairHandler.AssignmentCounts).Methods("GET")
+	r.HandleFunc("/admin/repair-durations", repairHandler.RepairDurations).Methods("GET")
+	r.HandleFunc("/admin/repairs/map", repairHandler.GetRepairMap).Methods("GET")
+	r.HandleFunc("/admin/repairs", repairHandler.ListRepairs).Methods("GET")
+	r.HandleFunc("/admin/repairs/bulk-status", repairHandler.BulkRepairStatus).Methods("POST")
+	r.HandleFunc("/admin/repairs/stream", repairHandler.StreamRepairs).Methods("GET")
+	r.HandleFunc("/admin/repairs/{repairID}/tags", repairHandler.RepairTags).Methods("GET")
+	r.HandleFunc("/admin/repairs/{repairID}/tags/{tag}", repairHandler.RepairTag).Methods("PUT", "DELETE")
+	r.HandleFunc("/admin/repairs/{repairID}/notes", repairHandler.RepairNotes).Methods("GET", "POST")
+	r.HandleFunc("/admin/repairs/{repairID}/notes/{noteID}", repairHandler.DeleteRepairNote).Methods("DELETE")
+	r.HandleFunc("/admin/mechanics/import", repairHandler.ImportMechanics).Methods("POST")
+	r.HandleFunc("/admin/events", repairHandler.StreamOpsEvents).Methods("GET")
+	r.HandleFunc("/admin/events/tap", repairHandler.TapEvents).Methods("GET")
+	r.HandleFunc("/admin/ws", repairHandler.StreamDispatchFeed).Methods("GET")
+	r.HandleFunc("/admin/metrics/slow", repairHandler.SlowOperations).Methods("GET")
+	r.HandleFunc("/admin/metrics/websockets", repairHandler.WebSocketConnections).Methods("GET")
+	r.HandleFunc("/admin/metrics/breakers", repairHandler.CircuitBreakers).Methods("GET")
+	r.HandleFunc("/admin/config", repairHandler.Config).Methods("GET")
+	r.HandleFunc("/admin/maintenance", repairHandler.Maintenance).Methods("GET", "PUT", "DELETE")
+	r.HandleFunc("/admin/maintenance/routes", repairHandler.RouteMaintenance).Methods("PUT", "DELETE")
+	r.HandleFunc("/internal/presence/{userID}", repairHandler.GetPresence).Methods("GET")
+	r.HandleFunc("/ws/token", repairHandler.IssueWebSocketToken).Methods("POST")
 	r.HandleFunc("/ws", repairHandler.HandleWebSocket).Methods("GET")
 
 	// Start server
 	slog.Info("API Gateway running on port 8085")
-	if err := http.ListenAndServe(":8085", r); err != nil {
+	// CORS wraps the router so preflight requests are answered before routing
+	if err := http.ListenAndServe(":8085", middleware.NewCORS(logger).Handler(r)); err != nil {
 		slog.Error("Failed to start server", "error", err)
 		os.Exit(1)
 	}
@@ -226,6 +370,11 @@ func initMongoDB() error {
 	if err := mechanicsColl.Drop(ctx); err != nil {
 		slog.Warn("Failed to drop mechanics collection (may not exist)", "error", err)
 	}
+
+	// Install validators after the drop so the seed data is validated too
+	if err := applySchemaValidators(ctx, client.Database("repairdb")); err != nil {
+		return err
+	}
 	_, err = mechanicsColl.InsertMany(ctx, mechanics)
 	if err != nil {
 		slog.Error("failed to insert mechanics", slog.String("error", err.Error()))
@@ -233,6 +382,26 @@ func initMongoDB() error {
 	}
 	slog.Info("Inserted mechanics data successfully")
 
+	// Estimates look up the mechanics in a bounding box around the user
+	_, err = mechanicsColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "location.latitude", Value: 1}, {Key: "location.longitude", Value: 1}},
+	})
+	if err != nil {
+		slog.Error("failed to create location index on mechanics", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create location index on mechanics: %v", err)
+	}
+	// The mechanic directory searches names through a text index and filters
+	// by rating and skills
+	_, err = mechanicsColl.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "name", Value: "text"}}},
+		{Keys: bson.D{{Key: "rating", Value: -1}}},
+		{Keys: bson.D{{Key: "skills", Value: 1}, {Key: "rating", Value: -1}}},
+	})
+	if err != nil {
+		slog.Error("failed to create directory indexes on mechanics", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create directory indexes on mechanics: %v", err)
+	}
+
 	// Create index on mechanic_outbox
 	outboxColl := client.Database("repairdb").Collection("mechanic_outbox")
 	indexModel := mongo.IndexModel{
@@ -250,6 +419,221 @@ func initMongoDB() error {
 	}
 	slog.Info("Created index on mechanic_outbox successfully")
 
+	// mechanic-service publishes assignment_outbox events oldest first; the
+	// TTL index removes published events once their expire_at passes, which
+	// repair_assigned events, the repairs' assignment history, never get
+	_, err = client.Database("repairdb").Collection("assignment_outbox").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "processed", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "aggregate_id", Value: 1}, {Key: "event_type", Value: 1}, {Key: "created_at", Value: 1}}},
+		{Keys: bson.D{{Key: "expire_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)},
+	})
+	if err != nil {
+		slog.Error("failed to create indexes on assignment_outbox", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create indexes on assignment_outbox: %v", err)
+	}
+	slog.Info("Created indexes on assignment_outbox successfully")
+
+	// One block relation per user and mechanic
+	blocksColl := client.Database("repairdb").Collection("blocks")
+	_, err = blocksColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "userID", Value: 1}, {Key: "mechanicID", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		slog.Error("failed to create index on blocks", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create index on blocks: %v", err)
+	}
+	slog.Info("Created index on blocks successfully")
+
+	// Anonymous quotes expire after ANONYMOUS_QUOTE_TTL_SECONDS, claimed or not
+	quoteTTL := 86400
+	if v, err := strconv.Atoi(os.Getenv("ANONYMOUS_QUOTE_TTL_SECONDS")); err == nil && v > 0 {
+		quoteTTL = v
+	}
+	quotesColl := client.Database("repairdb").Collection("anonymous_quotes")
+	_, err = quotesColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "createdAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(quoteTTL)),
+	})
+	if err != nil {
+		slog.Error("failed to create index on anonymous_quotes", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create index on anonymous_quotes: %v", err)
+	}
+	slog.Info("Created index on anonymous_quotes successfully")
+
+	// Consumed event IDs are kept for PROCESSED_EVENT_TTL_HOURS to drop redeliveries
+	processedTTL := 168
+	if v, err := strconv.Atoi(os.Getenv("PROCESSED_EVENT_TTL_HOURS")); err == nil && v > 0 {
+		processedTTL = v
+	}
+	_, err = client.Database("repairdb").Collection("processed_events").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "processedAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(processedTTL * 3600)),
+	})
+	if err != nil {
+		slog.Error("failed to create index on processed_events", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create index on processed_events: %v", err)
+	}
+	slog.Info("Created index on processed_events successfully")
+
+	// Mongo event bus (EVENT_BUS=mongo): records are read in offset order per
+	// topic and kept for EVENT_BUS_RETENTION_HOURS, like Kafka retention
+	busRetention := 168
+	if v, err := strconv.Atoi(os.Getenv("EVENT_BUS_RETENTION_HOURS")); err == nil && v > 0 {
+		busRetention = v
+	}
+	_, err = client.Database("repairdb").Collection("event_bus").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "topic", Value: 1}, {Key: "offset", Value: 1}}},
+		{Keys: bson.D{{Key: "timestamp", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(busRetention * 3600))},
+	})
+	if err != nil {
+		slog.Error("failed to create indexes on event_bus", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create indexes on event_bus: %v", err)
+	}
+	slog.Info("Created indexes on event_bus successfully")
+
+	// Tag filters on repairs, and a repair's notes in order
+	_, err = client.Database("repairdb").Collection("repairs").Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "tags", Value: 1}}})
+	if err != nil {
+		slog.Error("failed to create tags index on repairs", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create tags index on repairs: %v", err)
+	}
+	// Repair searches by bounding box range over the user location
+	_, err = client.Database("repairdb").Collection("repairs").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "repairCost.userLocation.longitude", Value: 1}, {Key: "repairCost.userLocation.latitude", Value: 1}},
+	})
+	if err != nil {
+		slog.Error("failed to create user location index on repairs", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create user location index on repairs: %v", err)
+	}
+	// Repair listings page in ID order through a user's repairs and by status
+	// and repair type
+	_, err = client.Database("repairdb").Collection("repairs").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userID", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "repairCost.repairType", Value: 1}, {Key: "_id", Value: 1}}},
+	})
+	if err != nil {
+		slog.Error("failed to create listing indexes on repairs", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create listing indexes on repairs: %v", err)
+	}
+	// Bundled repairs are looked up by bundle when one is assigned or dissolved
+	_, err = client.Database("repairdb").Collection("repairs").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "bundleID", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		slog.Error("failed to create bundleID index on repairs", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create bundleID index on repairs: %v", err)
+	}
+	// mechanic-service's ETA monitor looks for committed ETAs coming due
+	_, err = client.Database("repairdb").Collection("repairs").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "eta.at", Value: 1}},
+		Options: options.Index().SetSparse(true),
+	})
+	if err != nil {
+		slog.Error("failed to create eta index on repairs", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create eta index on repairs: %v", err)
+	}
+	notesColl := client.Database("repairdb").Collection("repair_notes")
+	_, err = notesColl.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "repairID", Value: 1}, {Key: "createdAt", Value: 1}},
+	})
+	if err != nil {
+		slog.Error("failed to create index on repair_notes", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create index on repair_notes: %v", err)
+	}
+	slog.Info("Created indexes for repair tags and notes successfully")
+
+	// Amendments are listed per repair and exported or anonymized per user
+	_, err = client.Database("repairdb").Collection("repair_amendments").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "repairID", Value: 1}, {Key: "requestedAt", Value: 1}}},
+		{Keys: bson.D{{Key: "userID", Value: 1}}},
+	})
+	if err != nil {
+		slog.Error("failed to create indexes on repair_amendments", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create indexes on repair_amendments: %v", err)
+	}
+	slog.Info("Created indexes on repair_amendments successfully")
+
+	// Assignment counters are summed per mechanic over the fairness window and
+	// expire after the longest window
+	_, err = client.Database("repairdb").Collection("assignment_counters").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "mechanicID", Value: 1}, {Key: "day", Value: 1}}},
+		{Keys: bson.D{{Key: "day", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(91 * 24 * 3600))},
+	})
+	if err != nil {
+		slog.Error("failed to create indexes on assignment_counters", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create indexes on assignment_counters: %v", err)
+	}
+	slog.Info("Created indexes on assignment_counters successfully")
+
+	// Erasure reports are looked up by the hash of the erased user ID
+	_, err = client.Database("repairdb").Collection("erasure_reports").Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "userIDHash", Value: 1}}})
+	if err != nil {
+		slog.Error("failed to create index on erasure_reports", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create index on erasure_reports: %v", err)
+	}
+	slog.Info("Created index on erasure_reports successfully")
+
+	// Due emails are claimed by status and next attempt; admins list them per
+	// user or repair
+	_, err = client.Database("repairdb").Collection("email_deliveries").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "status", Value: 1}, {Key: "nextAttemptAt", Value: 1}, {Key: "createdAt", Value: 1}}},
+		{Keys: bson.D{{Key: "userID", Value: 1}, {Key: "createdAt", Value: -1}}},
+		{Keys: bson.D{{Key: "repairID", Value: 1}}},
+	})
+	if err != nil {
+		slog.Error("failed to create indexes on email_deliveries", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create indexes on email_deliveries: %v", err)
+	}
+	slog.Info("Created indexes on email_deliveries successfully")
+
+	// Duration estimates average the latest samples per repair type and per
+	// mechanic and repair type
+	_, err = client.Database("repairdb").Collection("repair_durations").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "repairType", Value: 1}, {Key: "completedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "mechanicID", Value: 1}, {Key: "repairType", Value: 1}, {Key: "completedAt", Value: -1}}},
+	})
+	if err != nil {
+		slog.Error("failed to create indexes on repair_durations", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create indexes on repair_durations: %v", err)
+	}
+	slog.Info("Created indexes on repair_durations successfully")
+
+	// Undelivered WebSocket messages are replayed per user in seq order, ETA
+	// events are stored once per event, and unacked messages expire after
+	// WS_OFFLINE_TTL_HOURS
+	offlineTTL := 72
+	if v, err := strconv.Atoi(os.Getenv("WS_OFFLINE_TTL_HOURS")); err == nil && v > 0 {
+		offlineTTL = v
+	}
+	_, err = client.Database("repairdb").Collection("ws_undelivered").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userID", Value: 1}, {Key: "seq", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "userID", Value: 1}, {Key: "key", Value: 1}}, Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"key": bson.M{"$exists": true}})},
+		{Keys: bson.D{{Key: "createdAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(offlineTTL * 3600))},
+	})
+	if err != nil {
+		slog.Error("failed to create indexes on ws_undelivered", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create indexes on ws_undelivered: %v", err)
+	}
+	slog.Info("Created indexes on ws_undelivered successfully")
+
+	// Raw mechanic positions are read latest first per mechanic and expire
+	// after MECHANIC_POSITION_TTL_SECONDS; only throttled updates go out as events
+	positionTTL := 600
+	if v, err := strconv.Atoi(os.Getenv("MECHANIC_POSITION_TTL_SECONDS")); err == nil && v > 0 {
+		positionTTL = v
+	}
+	_, err = client.Database("repairdb").Collection("mechanic_positions").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "mechanicID", Value: 1}, {Key: "recordedAt", Value: -1}}},
+		{Keys: bson.D{{Key: "receivedAt", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(int32(positionTTL))},
+	})
+	if err != nil {
+		slog.Error("failed to create indexes on mechanic_positions", slog.String("error", err.Error()))
+		return fmt.Errorf("failed to create indexes on mechanic_positions: %v", err)
+	}
+	slog.Info("Created indexes on mechanic_positions successfully")
+
 	return nil
 }
 
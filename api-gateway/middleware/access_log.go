@@ -0,0 +1,222 @@
+package middleware
+
+import (
+	"api-gateway/logging"
+	"bufio"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// AccessLogger writes one structured log line per request. Request and response
+// bodies are only logged for a sampled share of requests, redacted and truncated.
+type AccessLogger struct {
+	logger   *slog.Logger
+	settings atomic.Pointer[accessLogSettings]
+}
+
+// accessLogSettings are the reloadable settings of AccessLogger
+type accessLogSettings struct {
+	bodySampleRate  float64
+	routeSampleRate map[string]float64 // per-route body sampling overrides, keyed by route template
+	disabledRoutes  map[string]bool    // routes without access logging
+	maxBodyBytes    int
+}
+
+// NewAccessLogger creates an AccessLogger configured from the environment:
+//   - ACCESS_LOG_BODY_SAMPLE_RATE: share of requests (0-1) whose bodies are logged, default 0
+//   - ACCESS_LOG_ROUTE_SAMPLE_RATES: per-route overrides, e.g. "/repairs/estimate=0.1,/repairs=1"
+//   - ACCESS_LOG_DISABLED_ROUTES: routes not logged at all, e.g. "/health,/ws"
+//   - ACCESS_LOG_MAX_BODY_BYTES: bytes kept per logged body, default 2048
+func NewAccessLogger(logger *slog.Logger) *AccessLogger {
+	a := &AccessLogger{logger: logger}
+	a.Configure(os.Getenv)
+	return a
+}
+
+// Configure replaces the access log settings with the ones read through get,
+// which takes the same keys as the environment
+func (a *AccessLogger) Configure(get func(string) string) {
+	settings := &accessLogSettings{
+		routeSampleRate: make(map[string]float64),
+		disabledRoutes:  make(map[string]bool),
+		maxBodyBytes:    2048,
+	}
+	if v, err := strconv.ParseFloat(get("ACCESS_LOG_BODY_SAMPLE_RATE"), 64); err == nil && v >= 0 && v <= 1 {
+		settings.bodySampleRate = v
+	}
+	for _, entry := range strings.Split(get("ACCESS_LOG_ROUTE_SAMPLE_RATES"), ",") {
+		route, rate, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		v, err := strconv.ParseFloat(rate, 64)
+		if err != nil || v < 0 || v > 1 {
+			a.logger.Warn("Ignoring invalid access log sample rate", "route", route, "rate", rate, "app", "api-gateway")
+			continue
+		}
+		settings.routeSampleRate[route] = v
+	}
+	for _, route := range strings.Split(get("ACCESS_LOG_DISABLED_ROUTES"), ",") {
+		if route = strings.TrimSpace(route); route != "" {
+			settings.disabledRoutes[route] = true
+		}
+	}
+	if v, err := strconv.Atoi(get("ACCESS_LOG_MAX_BODY_BYTES")); err == nil && v > 0 {
+		settings.maxBodyBytes = v
+	}
+	a.settings.Store(settings)
+	a.logger.Info("Access logging enabled", "bodySampleRate", settings.bodySampleRate, "routeSampleRates", settings.routeSampleRate, "disabledRoutes", len(settings.disabledRoutes), "app", "api-gateway")
+}
+
+// Middleware logs method, route, status, size and latency of every request
+func (a *AccessLogger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		settings := a.settings.Load()
+		if settings.disabledRoutes[route] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		rate, ok := settings.routeSampleRate[route]
+		if !ok {
+			rate = settings.bodySampleRate
+		}
+		sampled := rate > 0 && rand.Float64() < rate && !isWebSocketUpgrade(r)
+
+		var reqBody, respBody *cappedBuffer
+		if sampled {
+			reqBody = &cappedBuffer{limit: settings.maxBodyBytes}
+			respBody = &cappedBuffer{limit: settings.maxBodyBytes}
+			if r.Body != nil {
+				r.Body = readCloser{io.TeeReader(r.Body, reqBody), r.Body}
+			}
+		}
+		rec := &accessRecorder{ResponseWriter: w, status: http.StatusOK, body: respBody}
+
+		start := time.Now()
+		next.ServeHTTP(rec, r)
+
+		attrs := []any{
+			"method", r.Method,
+			"route", route,
+			"path", logging.Redact(r.URL.RequestURI()),
+			"status", rec.status,
+			"bytes", rec.bytes,
+			"durationMs", time.Since(start).Milliseconds(),
+			"remoteAddr", r.RemoteAddr,
+			"userAgent", r.UserAgent(),
+		}
+		if sampled {
+			attrs = append(attrs,
+				"requestBody", reqBody.String(),
+				"responseBody", respBody.String(),
+			)
+		}
+		attrs = append(attrs, "app", "api-gateway")
+
+		level := slog.LevelInfo
+		if rec.status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		} else if rec.status >= http.StatusBadRequest {
+			level = slog.LevelWarn
+		}
+		a.logger.Log(r.Context(), level, "HTTP request", attrs...)
+	})
+}
+
+// cappedBuffer keeps the first limit bytes written to it and counts the rest
+type cappedBuffer struct {
+	limit     int
+	data      []byte
+	truncated bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - len(b.data); room > 0 {
+		if len(p) > room {
+			b.data = append(b.data, p[:room]...)
+			b.truncated = true
+		} else {
+			b.data = append(b.data, p...)
+		}
+	} else if len(p) > 0 {
+		b.truncated = true
+	}
+	return len(p), nil
+}
+
+// String returns the captured body with sensitive values redacted
+func (b *cappedBuffer) String() string {
+	s := logging.Redact(string(b.data))
+	if b.truncated {
+		s += "...(truncated)"
+	}
+	return s
+}
+
+// readCloser pairs a tee'd request body reader with the original closer
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// accessRecorder captures the status, size and (when sampled) body of a response
+type accessRecorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int
+	body        *cappedBuffer
+	wroteHeader bool
+}
+
+func (r *accessRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *accessRecorder) Write(p []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(p)
+	r.bytes += n
+	if r.body != nil {
+		r.body.Write(p[:n])
+	}
+	return n, err
+}
+
+// Hijack lets WebSocket upgrades through the recorder
+func (r *accessRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("response writer does not support hijacking")
+	}
+	r.status = http.StatusSwitchingProtocols
+	return hijacker.Hijack()
+}
+
+// Flush forwards streaming flushes to the underlying writer
+func (r *accessRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// CORS answers preflight requests and adds CORS headers for the configured
+// browser origins. WebSocket handshakes from other origins are refused, since
+// browsers do not apply CORS to them. Without CORS_ALLOWED_ORIGINS it is a no-op.
+type CORS struct {
+	allowAll         bool
+	origins          map[string]bool
+	methods          string
+	headers          string
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+	logger           *slog.Logger
+}
+
+// NewCORS creates a CORS middleware configured from the environment:
+//   - CORS_ALLOWED_ORIGINS: e.g. "https://dispatch.example.com,http://localhost:3000", or "*"
+//   - CORS_ALLOWED_METHODS: default "GET,POST,PUT,PATCH,DELETE,OPTIONS"
+//   - CORS_ALLOWED_HEADERS: default "Authorization,Content-Type,X-App-Version,Last-Event-ID,X-Actor-Role,X-Actor-ID"
+//   - CORS_EXPOSED_HEADERS: response headers scripts may read, default none
+//   - CORS_ALLOW_CREDENTIALS: "true" to allow cookies and Authorization, default false
+//   - CORS_MAX_AGE_SECONDS: how long browsers cache a preflight, default 600
+func NewCORS(logger *slog.Logger) *CORS {
+	c := &CORS{
+		origins:          make(map[string]bool),
+		methods:          csvEnv("CORS_ALLOWED_METHODS", "GET,POST,PUT,PATCH,DELETE,OPTIONS"),
+		headers:          csvEnv("CORS_ALLOWED_HEADERS", "Authorization,Content-Type,X-App-Version,Last-Event-ID,X-Actor-Role,X-Actor-ID"),
+		exposedHeaders:   csvEnv("CORS_EXPOSED_HEADERS", ""),
+		allowCredentials: os.Getenv("CORS_ALLOW_CREDENTIALS") == "true",
+		maxAge:           "600",
+		logger:           logger,
+	}
+	for _, origin := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		switch origin {
+		case "":
+		case "*":
+			c.allowAll = true
+		default:
+			c.origins[strings.ToLower(origin)] = true
+		}
+	}
+	if v, err := strconv.Atoi(os.Getenv("CORS_MAX_AGE_SECONDS")); err == nil && v >= 0 {
+		c.maxAge = strconv.Itoa(v)
+	}
+	if c.enabled() {
+		logger.Info("CORS enabled", "allowAll", c.allowAll, "origins", len(c.origins), "methods", c.methods, "allowCredentials", c.allowCredentials, "app", "api-gateway")
+	}
+	return c
+}
+
+// csvEnv reads a comma separated list from env and normalizes its spacing, or returns def
+func csvEnv(env, def string) string {
+	value := os.Getenv(env)
+	if value == "" {
+		return def
+	}
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return strings.Join(items, ",")
+}
+
+func (c *CORS) enabled() bool {
+	return c.allowAll || len(c.origins) > 0
+}
+
+// allowed reports whether a browser on origin may call the gateway
+func (c *CORS) allowed(origin string) bool {
+	return c.allowAll || c.origins[strings.ToLower(origin)]
+}
+
+// Handler wraps the whole router rather than being added with Use, because
+// mux only runs middleware for matched routes and preflight OPTIONS requests
+// match none of them
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	if !c.enabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			// Not a cross-origin browser request
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+		if !c.allowed(origin) {
+			if preflight || isWebSocketUpgrade(r) {
+				c.logger.Warn("Rejected cross-origin request", "origin", origin, "method", r.Method, "path", r.URL.Path, "app", "api-gateway")
+				http.Error(w, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+			// Simple requests still run; the browser hides the response
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// A wildcard cannot be combined with credentials, so echo the origin then
+		if c.allowAll && !c.allowCredentials {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		if c.allowCredentials {
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			w.Header().Set("Access-Control-Allow-Methods", c.methods)
+			w.Header().Set("Access-Control-Allow-Headers", c.headers)
+			w.Header().Set("Access-Control-Max-Age", c.maxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if c.exposedHeaders != "" {
+			w.Header().Set("Access-Control-Expose-Headers", c.exposedHeaders)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
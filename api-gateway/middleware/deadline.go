@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// DeadlineHeader carries the time a request has left, in milliseconds, to the
+// services it calls. It is relative so clock skew between hosts does not
+// matter; each hop subtracts the time it spent.
+const DeadlineHeader = "X-Request-Timeout-Ms"
+
+// Deadline gives every request an overall deadline, so a slow Mongo query or
+// OSRM call downstream answers 504 instead of hanging the client. The
+// deadline is set on the request context, which the gateway's downstream
+// calls use, and PropagateDeadline sends what is left of it to the services.
+// WebSocket upgrades and Server-Sent Events streams are long-lived and never
+// get a deadline.
+type Deadline struct {
+	settings atomic.Pointer[deadlineSettings]
+	logger   *slog.Logger
+}
+
+// deadlineSettings are the reloadable settings of Deadline
+type deadlineSettings struct {
+	timeout time.Duration
+	routes  map[string]time.Duration // by "METHOD route template" or route template
+}
+
+// NewDeadline creates the deadline middleware configured from the environment:
+//   - REQUEST_TIMEOUT_MS: deadline of every route, default 10000; 0 disables
+//   - REQUEST_ROUTE_TIMEOUTS: per-route overrides as "route=ms" or
+//     "METHOD route=ms", e.g. "POST /repairs/estimate=3000,/admin/repairs/map=20000";
+//     0 exempts the route. The long poll /repairs/{repairID}/updates is exempt
+//     by default, it has its own LONGPOLL_MAX_WAIT_SECONDS.
+func NewDeadline(logger *slog.Logger) *Deadline {
+	d := &Deadline{logger: logger}
+	d.Configure(os.Getenv)
+	return d
+}
+
+// Configure replaces the deadlines with the ones read through get, which
+// takes the same keys as the environment. Requests already running keep
+// their deadline.
+func (d *Deadline) Configure(get func(string) string) {
+	settings := &deadlineSettings{
+		timeout: 10 * time.Second,
+		routes:  map[string]time.Duration{"/repairs/{repairID}/updates": 0},
+	}
+	if v, err := strconv.Atoi(get("REQUEST_TIMEOUT_MS")); err == nil && v >= 0 {
+		settings.timeout = time.Duration(v) * time.Millisecond
+	}
+	for _, entry := range strings.Split(get("REQUEST_ROUTE_TIMEOUTS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, "=")
+		ms, err := strconv.Atoi(strings.TrimSpace(entry[i+1:]))
+		if i <= 0 || err != nil || ms < 0 {
+			d.logger.Error("Ignoring invalid route timeout", "entry", entry, "app", "api-gateway")
+			continue
+		}
+		route := strings.TrimSpace(entry[:i])
+		if method, path, ok := strings.Cut(route, " "); ok {
+			route = strings.ToUpper(method) + " " + strings.TrimSpace(path)
+		}
+		settings.routes[route] = time.Duration(ms) * time.Millisecond
+	}
+	d.settings.Store(settings)
+	d.logger.Info("Request deadlines enabled", "timeout", settings.timeout, "routeOverrides", len(settings.routes), "app", "api-gateway")
+}
+
+// timeoutFor returns the deadline of a route, 0 for none
+func (d *Deadline) timeoutFor(method, route string) time.Duration {
+	settings := d.settings.Load()
+	if t, ok := settings.routes[method+" "+route]; ok {
+		return t
+	}
+	if t, ok := settings.routes[route]; ok {
+		return t
+	}
+	return settings.timeout
+}
+
+// Middleware runs the handler with the route's deadline and answers 504 when
+// the deadline expires before the handler could answer
+func (d *Deadline) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		timeout := d.timeoutFor(r.Method, route)
+		if timeout <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		dw := &deadlineWriter{ResponseWriter: w, ctx: ctx, timeout: timeout}
+		next.ServeHTTP(dw, r.WithContext(ctx))
+		if !dw.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			dw.writeDeadlineExceeded()
+		}
+		if dw.exceeded {
+			d.logger.Warn("Request deadline exceeded", "method", r.Method, "route", route, "timeout", timeout, "app", "api-gateway")
+		}
+	})
+}
+
+// PropagateDeadline sends the time left before the request context's deadline
+// in DeadlineHeader, and fails calls whose deadline has already passed
+// without sending them
+func PropagateDeadline(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		deadline, ok := req.Context().Deadline()
+		if !ok {
+			return base.RoundTrip(req)
+		}
+		left := time.Until(deadline).Milliseconds()
+		if left <= 0 {
+			return nil, context.DeadlineExceeded
+		}
+		req = req.Clone(req.Context())
+		req.Header.Set(DeadlineHeader, strconv.FormatInt(left, 10))
+		return base.RoundTrip(req)
+	})
+}
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// deadlineWriter replaces the 5xx a handler answers after its deadline expired,
+// typically "failed to contact downstream service", with a 504
+type deadlineWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	timeout     time.Duration
+	wroteHeader bool
+	exceeded    bool // the 504 was written; the handler's own body is dropped
+}
+
+func (w *deadlineWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	if status >= http.StatusInternalServerError && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.writeDeadlineExceeded()
+		return
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *deadlineWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.exceeded {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush forwards streaming flushes to the underlying writer
+func (w *deadlineWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// writeDeadlineExceeded answers 504 with the deadline_exceeded error code
+func (w *deadlineWriter) writeDeadlineExceeded() {
+	w.wroteHeader, w.exceeded = true, true
+	w.Header().Del("X-Content-Type-Options")
+	w.Header().Set("Content-Type", "application/json")
+	w.ResponseWriter.WriteHeader(http.StatusGatewayTimeout)
+	json.NewEncoder(w.ResponseWriter).Encode(map[string]interface{}{
+		"error":     "deadline_exceeded",
+		"message":   "The request did not complete within its deadline",
+		"timeoutMs": w.timeout.Milliseconds(),
+	})
+}
@@ -0,0 +1,303 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// defaultJWTRoutes are the routes that require a token unless JWT_ROUTES
+// lists others
+const defaultJWTRoutes = "POST /repairs,POST /repairs/estimate,GET /ws,GET /users/{userID}/export"
+
+// jwtLeeway absorbs clock skew between the token issuer and the gateway
+const jwtLeeway = 30 * time.Second
+
+var errInvalidJWT = errors.New("invalid token")
+
+// Identity is the caller a JWT identifies
+type Identity struct {
+	UserID string
+	Role   string // the role claim, empty when the token has none
+}
+
+type identityKey struct{}
+
+// IdentityFrom returns the identity JWTAuth verified for the request, if any
+func IdentityFrom(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// JWTAuth requires a valid JWT on the protected routes, from the
+// Authorization bearer header or, on WebSocket handshakes that browsers
+// cannot add headers to, ?access_token=. The user ID of the token's claims
+// replaces the userID of JSON request bodies, so callers cannot act for
+// other users, and is available to handlers through IdentityFrom. Admins
+// holding ADMIN_API_TOKEN pass without a JWT; RBAC and the handlers tell
+// them apart.
+type JWTAuth struct {
+	enabled    bool
+	hmacKey    []byte         // HS256
+	rsaKey     *rsa.PublicKey // RS256
+	issuer     string
+	audience   string
+	userClaim  string
+	roleClaim  string
+	routes     map[string]bool // "METHOD route template"
+	adminToken string
+	logger     *slog.Logger
+}
+
+// NewJWTAuth creates the JWT middleware configured from the environment:
+//   - JWT_AUTH: true enables it; off by default so existing clients keep working
+//   - JWT_SECRET (HS256) and/or JWT_PUBLIC_KEY (PEM, RS256): verification keys
+//   - JWT_ISSUER, JWT_AUDIENCE: required iss and aud when set
+//   - JWT_USER_CLAIM (default sub) and JWT_ROLE_CLAIM (default role)
+//   - JWT_ROUTES: protected routes as "METHOD route template", comma separated
+//
+// Enabled without a usable key, it rejects every protected request.
+func NewJWTAuth(logger *slog.Logger) *JWTAuth {
+	a := &JWTAuth{
+		enabled:    os.Getenv("JWT_AUTH") == "true",
+		hmacKey:    []byte(os.Getenv("JWT_SECRET")),
+		issuer:     os.Getenv("JWT_ISSUER"),
+		audience:   os.Getenv("JWT_AUDIENCE"),
+		userClaim:  "sub",
+		roleClaim:  "role",
+		routes:     map[string]bool{},
+		adminToken: os.Getenv("ADMIN_API_TOKEN"),
+		logger:     logger,
+	}
+	if !a.enabled {
+		return a
+	}
+	if v := os.Getenv("JWT_USER_CLAIM"); v != "" {
+		a.userClaim = v
+	}
+	if v := os.Getenv("JWT_ROLE_CLAIM"); v != "" {
+		a.roleClaim = v
+	}
+	if pemKey := os.Getenv("JWT_PUBLIC_KEY"); pemKey != "" {
+		key, err := parseRSAPublicKey(pemKey)
+		if err != nil {
+			logger.Error("Ignoring invalid JWT_PUBLIC_KEY", "error", err, "app", "api-gateway")
+		}
+		a.rsaKey = key
+	}
+	if len(a.hmacKey) == 0 && a.rsaKey == nil {
+		logger.Error("JWT_AUTH is on without JWT_SECRET or JWT_PUBLIC_KEY, protected routes reject every request", "app", "api-gateway")
+	}
+	routes := os.Getenv("JWT_ROUTES")
+	if routes == "" {
+		routes = defaultJWTRoutes
+	}
+	for _, route := range strings.Split(routes, ",") {
+		method, path, ok := strings.Cut(strings.TrimSpace(route), " ")
+		if !ok {
+			logger.Error("Ignoring invalid JWT route", "route", route, "app", "api-gateway")
+			continue
+		}
+		a.routes[strings.ToUpper(method)+" "+strings.TrimSpace(path)] = true
+	}
+	logger.Info("JWT authentication enabled", "routes", len(a.routes), "hs256", len(a.hmacKey) > 0, "rs256", a.rsaKey != nil, "app", "api-gateway")
+	return a
+}
+
+func parseRSAPublicKey(pemKey string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, errors.New("no PEM block")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := key.(*rsa.PublicKey)
+	if !ok {
+		return nil, errors.New("not an RSA public key")
+	}
+	return rsaKey, nil
+}
+
+// Middleware rejects protected requests without a valid token with 401 and
+// binds the token's user to the others
+func (a *JWTAuth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		if !a.routes[r.Method+" "+route] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok && isWebSocketUpgrade(r) {
+			token = r.URL.Query().Get("access_token")
+		}
+		if token == "" {
+			a.reject(w, r, route, errors.New("a bearer token is required"))
+			return
+		}
+		if a.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.adminToken)) == 1 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		id, err := a.verify(token, time.Now())
+		if err != nil {
+			a.reject(w, r, route, err)
+			return
+		}
+		if err := bindUserID(r, id.UserID); err != nil {
+			a.logger.Warn("Failed to bind token user to request body", "error", err, "route", route, "app", "api-gateway")
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, id)))
+	})
+}
+
+func (a *JWTAuth) reject(w http.ResponseWriter, r *http.Request, route string, err error) {
+	a.logger.Info("Rejected unauthenticated request", "method", r.Method, "route", route, "reason", err.Error(), "app", "api-gateway")
+	w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(map[string]string{"error": "unauthorized", "message": err.Error()})
+}
+
+// verify checks the token's signature, expiry, issuer and audience and
+// returns the identity in its claims
+func (a *JWTAuth) verify(token string, now time.Time) (Identity, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Identity{}, errInvalidJWT
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Identity{}, errInvalidJWT
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Identity{}, errInvalidJWT
+	}
+	signed := []byte(parts[0] + "." + parts[1])
+	digest := sha256.Sum256(signed)
+	switch {
+	case header.Alg == "HS256" && len(a.hmacKey) > 0:
+		mac := hmac.New(sha256.New, a.hmacKey)
+		mac.Write(signed)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return Identity{}, fmt.Errorf("%w signature", errInvalidJWT)
+		}
+	case header.Alg == "RS256" && a.rsaKey != nil:
+		if rsa.VerifyPKCS1v15(a.rsaKey, crypto.SHA256, digest[:], signature) != nil {
+			return Identity{}, fmt.Errorf("%w signature", errInvalidJWT)
+		}
+	default:
+		return Identity{}, fmt.Errorf("%w: unsupported algorithm %q", errInvalidJWT, header.Alg)
+	}
+
+	var claims map[string]any
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Identity{}, errInvalidJWT
+	}
+	exp, ok := claims["exp"].(float64)
+	if !ok || now.After(time.Unix(int64(exp), 0).Add(jwtLeeway)) {
+		return Identity{}, errors.New("token expired")
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now.Add(jwtLeeway).Before(time.Unix(int64(nbf), 0)) {
+		return Identity{}, errors.New("token not valid yet")
+	}
+	if a.issuer != "" && claims["iss"] != a.issuer {
+		return Identity{}, errors.New("unexpected token issuer")
+	}
+	if a.audience != "" && !hasAudience(claims["aud"], a.audience) {
+		return Identity{}, errors.New("unexpected token audience")
+	}
+	userID, _ := claims[a.userClaim].(string)
+	if userID == "" {
+		return Identity{}, fmt.Errorf("token has no %s claim", a.userClaim)
+	}
+	role, _ := claims[a.roleClaim].(string)
+	return Identity{UserID: userID, Role: role}, nil
+}
+
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// hasAudience reports whether aud, a string or an array of strings, names
+// audience
+func hasAudience(aud any, audience string) bool {
+	switch v := aud.(type) {
+	case string:
+		return v == audience
+	case []any:
+		return slices.Contains(v, any(audience))
+	}
+	return false
+}
+
+// bindUserID sets the userID field of a JSON object body to userID, whatever
+// the caller sent
+func bindUserID(r *http.Request, userID string) error {
+	contentType := r.Header.Get("Content-Type")
+	if r.Body == nil || r.Body == http.NoBody || isWebSocketUpgrade(r) || (contentType != "" && !strings.HasPrefix(contentType, "application/json")) {
+		return nil
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		return nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		// Not an object; the handler reports the malformed body
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		return nil
+	}
+	fields["userID"], _ = json.Marshal(userID)
+	body, err = json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	return nil
+}
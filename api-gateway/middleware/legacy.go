@@ -0,0 +1,252 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// legacyCoordinateKeys are request fields that older app versions send as strings
+var legacyCoordinateKeys = map[string]bool{
+	"latitude":  true,
+	"longitude": true,
+}
+
+// legacyPriceKeys are fields that older app versions send and expect as strings
+var legacyPriceKeys = map[string]bool{
+	"totalPrice": true,
+}
+
+// LegacyAdapter normalizes request and response bodies for old app versions
+type LegacyAdapter struct {
+	maxLegacyVersion []int
+	logger           *slog.Logger
+}
+
+// NewLegacyAdapter creates a LegacyAdapter. Clients sending an X-App-Version
+// lower than LEGACY_APP_VERSION (default 2.0.0) are treated as legacy.
+func NewLegacyAdapter(logger *slog.Logger) *LegacyAdapter {
+	threshold := os.Getenv("LEGACY_APP_VERSION")
+	if threshold == "" {
+		threshold = "2.0.0"
+	}
+	maxVersion, ok := parseVersion(threshold)
+	if !ok {
+		logger.Warn("Invalid LEGACY_APP_VERSION, using default", "value", threshold, "app", "api-gateway")
+		threshold = "2.0.0"
+		maxVersion, _ = parseVersion(threshold)
+	}
+	logger.Info("Legacy client adapter enabled", "legacyBelowVersion", threshold, "app", "api-gateway")
+	return &LegacyAdapter{
+		maxLegacyVersion: maxVersion,
+		logger:           logger,
+	}
+}
+
+// Middleware rewrites legacy request bodies before they reach the handlers and
+// re-serializes JSON responses into the shape legacy clients expect
+func (a *LegacyAdapter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		appVersion := r.Header.Get("X-App-Version")
+		if !a.isLegacy(appVersion) || isWebSocketUpgrade(r) || isEventStream(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, span := otel.Tracer("api-gateway").Start(r.Context(), "LegacyAdapter")
+		defer span.End()
+		span.SetAttributes(attribute.String("appVersion", appVersion))
+		r = r.WithContext(ctx)
+
+		if r.Body != nil && (r.Method == http.MethodPost || r.Method == http.MethodPut) {
+			body, err := io.ReadAll(r.Body)
+			r.Body.Close()
+			if err != nil {
+				a.logger.Error("Failed to read legacy request body", "error", err, "appVersion", appVersion, "app", "api-gateway")
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			normalized, err := normalizeLegacyRequest(body)
+			if err != nil {
+				// Not JSON we understand; forward untouched and let the handler reject it
+				normalized = body
+			}
+			r.Body = io.NopCloser(bytes.NewReader(normalized))
+			r.ContentLength = int64(len(normalized))
+			r.Header.Set("Content-Length", strconv.Itoa(len(normalized)))
+		}
+
+		rec := &bufferedResponseWriter{header: make(http.Header), status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		body := rec.body.Bytes()
+		if strings.HasPrefix(rec.header.Get("Content-Type"), "application/json") {
+			if converted, err := legacyResponse(body); err == nil {
+				body = converted
+			} else {
+				a.logger.Warn("Failed to adapt response for legacy client", "error", err, "appVersion", appVersion, "app", "api-gateway")
+			}
+		}
+		for k, v := range rec.header {
+			w.Header()[k] = v
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(rec.status)
+		w.Write(body)
+	})
+}
+
+// isLegacy reports whether the given app version is older than the threshold.
+// Requests without a version header, or with one that cannot be parsed, are
+// assumed to be current clients.
+func (a *LegacyAdapter) isLegacy(appVersion string) bool {
+	v, ok := parseVersion(appVersion)
+	if !ok {
+		return false
+	}
+	for i := 0; i < len(a.maxLegacyVersion); i++ {
+		if v[i] != a.maxLegacyVersion[i] {
+			return v[i] < a.maxLegacyVersion[i]
+		}
+	}
+	return false
+}
+
+// parseVersion parses "major.minor.patch" into three integers. Missing minor or
+// patch parts count as zero and pre-release or build suffixes ("2.0.0-beta")
+// are ignored. It reports false when no numeric major version is present.
+func parseVersion(s string) ([]int, bool) {
+	out := make([]int, 3)
+	parts := strings.SplitN(strings.TrimPrefix(strings.TrimSpace(s), "v"), ".", 3)
+	for i, p := range parts {
+		end := 0
+		for end < len(p) && p[end] >= '0' && p[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			if i == 0 {
+				return nil, false
+			}
+			break
+		}
+		n, err := strconv.Atoi(p[:end])
+		if err != nil {
+			return nil, false
+		}
+		out[i] = n
+		if end < len(p) {
+			break
+		}
+	}
+	return out, true
+}
+
+// normalizeLegacyRequest converts string coordinates and prices into numbers
+func normalizeLegacyRequest(body []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return body, nil
+	}
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	doc = walkJSON(doc, func(key string, v interface{}) interface{} {
+		if !legacyCoordinateKeys[key] && !legacyPriceKeys[key] {
+			return v
+		}
+		if s, ok := v.(string); ok {
+			if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+				return f
+			}
+		}
+		return v
+	})
+	return json.Marshal(doc)
+}
+
+// legacyResponse converts numeric prices into strings for legacy clients
+func legacyResponse(body []byte) ([]byte, error) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return body, nil
+	}
+	var doc interface{}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	doc = walkJSON(doc, func(key string, v interface{}) interface{} {
+		if !legacyPriceKeys[key] {
+			return v
+		}
+		if n, ok := v.(json.Number); ok {
+			if f, err := n.Float64(); err == nil {
+				return strconv.FormatFloat(f, 'f', 2, 64)
+			}
+		}
+		return v
+	})
+	out, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return append(out, '\n'), nil
+}
+
+// walkJSON applies fn to every object member of a decoded JSON document
+func walkJSON(v interface{}, fn func(key string, v interface{}) interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			t[k] = fn(k, walkJSON(child, fn))
+		}
+		return t
+	case []interface{}:
+		for i, child := range t {
+			t[i] = walkJSON(child, fn)
+		}
+		return t
+	default:
+		return v
+	}
+}
+
+// isWebSocketUpgrade reports whether the request is a WebSocket handshake
+func isWebSocketUpgrade(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// isEventStream reports whether r asks for a Server-Sent Events stream, which
+// must not be buffered
+func isEventStream(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// bufferedResponseWriter captures a handler's response so it can be rewritten
+type bufferedResponseWriter struct {
+	header http.Header
+	body   bytes.Buffer
+	status int
+}
+
+func (b *bufferedResponseWriter) Header() http.Header {
+	return b.header
+}
+
+func (b *bufferedResponseWriter) Write(p []byte) (int, error) {
+	return b.body.Write(p)
+}
+
+func (b *bufferedResponseWriter) WriteHeader(status int) {
+	b.status = status
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIsLegacy(t *testing.T) {
+	a := &LegacyAdapter{maxLegacyVersion: []int{2, 0, 0}}
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"", false},
+		{"1.9.9", true},
+		{"1.10.0", true},
+		{"v1.2.3", true},
+		{"1.9", true},
+		{"1", true},
+		{"2.0.0", false},
+		{"2.0", false},
+		{"2.0.0-beta", false},
+		{"1.9.9-rc1", true},
+		{"2.1.0", false},
+		{"10.0.0", false},
+		{"abc", false},
+		{"latest", false},
+	}
+	for _, tt := range tests {
+		if got := a.isLegacy(tt.version); got != tt.want {
+			t.Errorf("isLegacy(%q) = %v, want %v", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeLegacyRequest(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"string coordinates", `{"latitude":"52.5","longitude":" 13.4 "}`, `{"latitude":52.5,"longitude":13.4}`},
+		{"numbers untouched", `{"latitude":52.5,"longitude":13.4}`, `{"latitude":52.5,"longitude":13.4}`},
+		{"nested price", `{"repairCost":{"totalPrice":"12.50"}}`, `{"repairCost":{"totalPrice":12.5}}`},
+		{"non-numeric string kept", `{"latitude":"north","totalPrice":"free"}`, `{"latitude":"north","totalPrice":"free"}`},
+		{"other fields kept", `{"userID":"42","repairType":"flat"}`, `{"repairType":"flat","userID":"42"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeLegacyRequest([]byte(tt.in))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+
+	if _, err := normalizeLegacyRequest([]byte("not json")); err == nil {
+		t.Error("expected error for invalid JSON")
+	}
+}
+
+func TestLegacyResponse(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"top level", `{"totalPrice":12.5}`, `{"totalPrice":"12.50"}`},
+		{"nested", `{"repairCost":{"totalPrice":7}}`, `{"repairCost":{"totalPrice":"7.00"}}`},
+		{"array", `[{"totalPrice":1.005},{"totalPrice":3}]`, `[{"totalPrice":"1.00"},{"totalPrice":"3.00"}]`},
+		{"string untouched", `{"totalPrice":"9.99"}`, `{"totalPrice":"9.99"}`},
+		{"coordinates untouched", `{"latitude":52.5}`, `{"latitude":52.5}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := legacyResponse([]byte(tt.in))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			assertJSONEqual(t, got, tt.want)
+		})
+	}
+}
+
+func TestMiddlewareRoundTrip(t *testing.T) {
+	a := &LegacyAdapter{maxLegacyVersion: []int{2, 0, 0}, logger: slog.New(slog.NewTextHandler(io.Discard, nil))}
+	var received string
+	handler := a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"repairCost":{"totalPrice":42}}`))
+	}))
+
+	req := httptest.NewRequest(http.MethodPost, "/repairs/estimate", strings.NewReader(`{"latitude":"1.5","longitude":"2"}`))
+	req.Header.Set("X-App-Version", "1.4.0")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assertJSONEqual(t, []byte(received), `{"latitude":1.5,"longitude":2}`)
+	if rec.Code != http.StatusCreated {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	assertJSONEqual(t, rec.Body.Bytes(), `{"repairCost":{"totalPrice":"42.00"}}`)
+
+	req = httptest.NewRequest(http.MethodPost, "/repairs/estimate", strings.NewReader(`{"latitude":"1.5"}`))
+	req.Header.Set("X-App-Version", "2.0.0")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if received != `{"latitude":"1.5"}` {
+		t.Errorf("current client body was rewritten: %s", received)
+	}
+	assertJSONEqual(t, rec.Body.Bytes(), `{"repairCost":{"totalPrice":42}}`)
+}
+
+func assertJSONEqual(t *testing.T, got []byte, want string) {
+	t.Helper()
+	var g, w interface{}
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("invalid JSON %q: %v", got, err)
+	}
+	if err := json.Unmarshal([]byte(want), &w); err != nil {
+		t.Fatalf("invalid expected JSON %q: %v", want, err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
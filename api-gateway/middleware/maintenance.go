@@ -0,0 +1,241 @@
+package middleware
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/consul/api"
+)
+
+// Consul KV keys of the maintenance control plane, relative to
+// MAINTENANCE_KV_PREFIX. The global switch lives at MaintenanceGlobalKey and
+// each route kill switch at MaintenanceRoutesKey + url.PathEscape(route
+// template). Values are JSON MaintenanceRules; deleting a key lifts it.
+const (
+	DefaultMaintenanceKVPrefix = "gateway/maintenance/"
+	MaintenanceGlobalKey       = "global"
+	MaintenanceRoutesKey       = "routes/"
+)
+
+// MaintenanceRule takes the whole gateway, or one route, out of service
+type MaintenanceRule struct {
+	Route             string    `json:"route,omitempty"`   // mux route template, e.g. "/repairs/{repairID}"; empty for the global switch
+	Methods           []string  `json:"methods,omitempty"` // methods affected; empty means all
+	Message           string    `json:"message"`
+	RetryAfterSeconds int       `json:"retryAfterSeconds,omitempty"`
+	Until             time.Time `json:"until,omitempty"` // expected end, used for Retry-After when set
+	EnabledBy         string    `json:"enabledBy,omitempty"`
+	EnabledAt         time.Time `json:"enabledAt"`
+}
+
+// MaintenanceKey returns the KV key, relative to the prefix, of a route kill switch
+func MaintenanceKey(route string) string {
+	if route == "" {
+		return MaintenanceGlobalKey
+	}
+	return MaintenanceRoutesKey + url.PathEscape(route)
+}
+
+// appliesTo reports whether the rule covers the request method
+func (m *MaintenanceRule) appliesTo(method string) bool {
+	if len(m.Methods) == 0 {
+		return true
+	}
+	for _, allowed := range m.Methods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryAfter returns the Retry-After delay in seconds
+func (m *MaintenanceRule) retryAfter(fallback int) int {
+	if !m.Until.IsZero() {
+		if left := int(math.Ceil(time.Until(m.Until).Seconds())); left > 0 {
+			return left
+		}
+	}
+	if m.RetryAfterSeconds > 0 {
+		return m.RetryAfterSeconds
+	}
+	return fallback
+}
+
+// Maintenance answers 503 with Retry-After for the whole gateway or single
+// routes while their switch is set in Consul KV, so operators can drain
+// traffic during Mongo or Kafka maintenance without redeploying. Every gateway
+// instance follows the keys with blocking queries and keeps the last known
+// state while Consul is unreachable. Health checks and the maintenance admin
+// routes are never blocked.
+type Maintenance struct {
+	kv         *api.KV
+	prefix     string
+	retryAfter int // default Retry-After in seconds
+	exempt     map[string]bool
+	logger     *slog.Logger
+	mu         sync.RWMutex
+	global     *MaintenanceRule
+	routes     map[string]*MaintenanceRule // keyed by route template
+}
+
+// NewMaintenance creates the maintenance middleware and starts following Consul KV:
+//   - MAINTENANCE_KV_PREFIX: KV prefix of the switches, default "gateway/maintenance/"
+//   - MAINTENANCE_RETRY_AFTER_SECONDS: Retry-After when a rule sets neither until nor retryAfterSeconds, default 300
+//   - MAINTENANCE_EXEMPT_ROUTES: route templates never blocked, default "/health,/metrics,/admin/maintenance,/admin/maintenance/routes"
+func NewMaintenance(consul *api.Client, logger *slog.Logger) *Maintenance {
+	m := &Maintenance{
+		kv:         consul.KV(),
+		prefix:     os.Getenv("MAINTENANCE_KV_PREFIX"),
+		retryAfter: 300,
+		exempt:     make(map[string]bool),
+		logger:     logger,
+		routes:     make(map[string]*MaintenanceRule),
+	}
+	if m.prefix == "" {
+		m.prefix = DefaultMaintenanceKVPrefix
+	}
+	if v, err := strconv.Atoi(os.Getenv("MAINTENANCE_RETRY_AFTER_SECONDS")); err == nil && v > 0 {
+		m.retryAfter = v
+	}
+	for _, route := range strings.Split(csvEnv("MAINTENANCE_EXEMPT_ROUTES", "/health,/metrics,/admin/maintenance,/admin/maintenance/routes"), ",") {
+		if route = strings.TrimSpace(route); route != "" {
+			m.exempt[route] = true
+		}
+	}
+	logger.Info("Maintenance switches enabled", "prefix", m.prefix, "retryAfter", m.retryAfter, "exemptRoutes", len(m.exempt), "app", "api-gateway")
+	go m.watch()
+	return m
+}
+
+// Prefix returns the Consul KV prefix the switches are read from
+func (m *Maintenance) Prefix() string {
+	return m.prefix
+}
+
+// State returns the active global switch, if any, and the route kill switches
+func (m *Maintenance) State() (*MaintenanceRule, []*MaintenanceRule) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	routes := make([]*MaintenanceRule, 0, len(m.routes))
+	for _, rule := range m.routes {
+		routes = append(routes, rule)
+	}
+	return m.global, routes
+}
+
+// watch reloads the switches whenever a key under the prefix changes
+func (m *Maintenance) watch() {
+	var index uint64
+	for {
+		pairs, meta, err := m.kv.List(m.prefix, &api.QueryOptions{WaitIndex: index, WaitTime: 5 * time.Minute})
+		if err != nil {
+			m.logger.Error("Failed to watch maintenance switches, keeping the last state", "error", err, "app", "api-gateway")
+			time.Sleep(2 * time.Second)
+			continue
+		}
+		if meta.LastIndex < index {
+			// Consul's index went backwards (e.g. a restore); start over
+			index = 0
+			continue
+		}
+		index = meta.LastIndex
+		m.load(pairs)
+	}
+}
+
+// load replaces the switches with the rules stored in pairs, logging changes
+func (m *Maintenance) load(pairs api.KVPairs) {
+	var global *MaintenanceRule
+	routes := make(map[string]*MaintenanceRule)
+	for _, pair := range pairs {
+		key := strings.TrimPrefix(pair.Key, m.prefix)
+		rule := &MaintenanceRule{}
+		if err := json.Unmarshal(pair.Value, rule); err != nil {
+			m.logger.Error("Ignoring invalid maintenance switch", "key", pair.Key, "error", err, "app", "api-gateway")
+			continue
+		}
+		switch {
+		case key == MaintenanceGlobalKey:
+			rule.Route = ""
+			global = rule
+		case strings.HasPrefix(key, MaintenanceRoutesKey):
+			route, err := url.PathUnescape(strings.TrimPrefix(key, MaintenanceRoutesKey))
+			if err != nil || route == "" {
+				m.logger.Error("Ignoring maintenance switch with an invalid route key", "key", pair.Key, "app", "api-gateway")
+				continue
+			}
+			rule.Route = route
+			routes[route] = rule
+		}
+	}
+
+	m.mu.Lock()
+	wasGlobal := m.global != nil
+	m.global, m.routes = global, routes
+	m.mu.Unlock()
+
+	switch {
+	case global != nil && !wasGlobal:
+		m.logger.Warn("Gateway entered maintenance mode", "message", global.Message, "until", global.Until, "enabledBy", global.EnabledBy, "app", "api-gateway")
+	case global == nil && wasGlobal:
+		m.logger.Warn("Gateway left maintenance mode", "app", "api-gateway")
+	}
+	m.logger.Info("Loaded maintenance switches", "global", global != nil, "routes", len(routes), "app", "api-gateway")
+}
+
+// Middleware rejects requests to switched-off routes with 503
+func (m *Maintenance) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		if m.exempt[route] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		m.mu.RLock()
+		rule, scope := m.global, "global"
+		if rule == nil || !rule.appliesTo(r.Method) {
+			rule, scope = m.routes[route], "route"
+		}
+		m.mu.RUnlock()
+		if rule == nil || !rule.appliesTo(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		retryAfter := rule.retryAfter(m.retryAfter)
+		message := rule.Message
+		if message == "" {
+			message = "The service is down for maintenance"
+		}
+		body := map[string]interface{}{
+			"error":             "maintenance",
+			"message":           message,
+			"scope":             scope,
+			"route":             route,
+			"retryAfterSeconds": retryAfter,
+		}
+		if !rule.Until.IsZero() {
+			body["until"] = rule.Until
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(body)
+	})
+}
@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// Caller roles
+const (
+	RoleUser     = "user"
+	RoleMechanic = "mechanic"
+	RoleAdmin    = "admin"
+)
+
+// rbacRoutes are the roles each protected route admits besides admins; the
+// handlers then check that users only see their own repairs and mechanics
+// only act for themselves
+var rbacRoutes = map[string][]string{
+	"GET /repairs/nearby":                  {RoleMechanic},
+	"POST /repairs/{repairID}/assign":      {RoleMechanic},
+	"GET /repairs/{repairID}":              {RoleUser, RoleMechanic},
+	"GET /repairs/{repairID}/full":         {RoleUser, RoleMechanic},
+	"PUT /mechanics/{mechanicID}/location": {RoleMechanic},
+	"GET /mechanics/{mechanicID}/home":     {RoleMechanic},
+	"GET /users/{userID}/export":           {RoleUser},
+}
+
+// Caller is who a request on an RBAC route acts for
+type Caller struct {
+	Role string // user, mechanic or admin
+	ID   string // the user or mechanic ID; empty for admins
+}
+
+// Header returns the X-Actor-Role and X-Actor-ID headers that pass the
+// caller on to the services
+func (c Caller) Header() http.Header {
+	header := http.Header{}
+	header.Set("X-Actor-Role", c.Role)
+	if c.ID != "" {
+		header.Set("X-Actor-ID", c.ID)
+	}
+	return header
+}
+
+type callerKey struct{}
+
+// CallerFrom returns the caller RBAC admitted to the request, if any
+func CallerFrom(ctx context.Context) (Caller, bool) {
+	caller, ok := ctx.Value(callerKey{}).(Caller)
+	return caller, ok
+}
+
+// RBAC admits callers to the mechanic and repair owner routes by role. The
+// caller is an admin holding ADMIN_API_TOKEN, the user of the request's JWT,
+// or, while JWT_AUTH is off, whoever X-Actor-Role and X-Actor-ID name, as on
+// the other user routes. RBAC routes need not be in JWT_ROUTES: tokens
+// JWTAuth did not verify already are verified here.
+type RBAC struct {
+	enabled      bool
+	adminToken   string
+	trustHeaders bool
+	jwt          *JWTAuth // verifies bearer tokens while JWT_AUTH is on
+	logger       *slog.Logger
+}
+
+// NewRBAC creates the RBAC middleware; RBAC_ENABLED=true turns it on, off by
+// default so existing clients keep working
+func NewRBAC(logger *slog.Logger, jwt *JWTAuth) *RBAC {
+	a := &RBAC{
+		enabled:      os.Getenv("RBAC_ENABLED") == "true",
+		adminToken:   os.Getenv("ADMIN_API_TOKEN"),
+		trustHeaders: !jwt.enabled,
+		jwt:          jwt,
+		logger:       logger,
+	}
+	if a.enabled {
+		logger.Info("RBAC enabled", "routes", len(rbacRoutes), "trustActorHeaders", a.trustHeaders, "app", "api-gateway")
+	}
+	return a
+}
+
+// Middleware answers 403 to callers whose role the route does not admit and
+// passes the others on with their Caller
+func (a *RBAC) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		roles, ok := rbacRoutes[r.Method+" "+route]
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		caller, ok, err := a.caller(r)
+		if err != nil {
+			a.jwt.reject(w, r, route, err)
+			return
+		}
+		if !ok {
+			a.reject(w, r, route, caller, "a caller role is required")
+			return
+		}
+		if caller.Role != RoleAdmin && !slices.Contains(roles, caller.Role) {
+			a.reject(w, r, route, caller, "role "+caller.Role+" may not call this route")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), callerKey{}, caller)))
+	})
+}
+
+// caller identifies who the request acts for; the error is that of a bearer
+// token that failed verification
+func (a *RBAC) caller(r *http.Request) (Caller, bool, error) {
+	token, bearer := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if bearer && a.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.adminToken)) == 1 {
+		return Caller{Role: RoleAdmin}, true, nil
+	}
+	id, ok := IdentityFrom(r.Context())
+	if !ok && a.jwt.enabled && bearer {
+		var err error
+		if id, err = a.jwt.verify(token, time.Now()); err != nil {
+			return Caller{}, false, err
+		}
+		ok = true
+	}
+	if ok {
+		// Tokens without a role claim are users', as on /ws
+		caller := Caller{Role: RoleUser, ID: id.UserID}
+		if id.Role == RoleMechanic {
+			caller.Role = RoleMechanic
+		}
+		return caller, true, nil
+	}
+	if !a.trustHeaders {
+		return Caller{}, false, nil
+	}
+	caller := Caller{Role: r.Header.Get("X-Actor-Role"), ID: r.Header.Get("X-Actor-ID")}
+	return caller, (caller.Role == RoleUser || caller.Role == RoleMechanic) && caller.ID != "", nil
+}
+
+func (a *RBAC) reject(w http.ResponseWriter, r *http.Request, route string, caller Caller, reason string) {
+	a.logger.Warn("Rejected request by role", "method", r.Method, "route", route, "role", caller.Role, "reason", reason, "app", "api-gateway")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{"error": "forbidden", "message": reason})
+}
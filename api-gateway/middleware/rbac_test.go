@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func signTestJWT(t *testing.T, secret string, claims map[string]any) string {
+	t.Helper()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(signed))
+	return signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// TestRBACWithJWTDefaults runs JWTAuth and RBAC as the gateway does with
+// JWT_AUTH and RBAC_ENABLED on and the default JWT_ROUTES: callers with a
+// valid token reach every RBAC route their role admits
+func TestRBACWithJWTDefaults(t *testing.T) {
+	t.Setenv("JWT_AUTH", "true")
+	t.Setenv("JWT_SECRET", "test-secret")
+	t.Setenv("JWT_ROUTES", "")
+	t.Setenv("RBAC_ENABLED", "true")
+	t.Setenv("ADMIN_API_TOKEN", "admin-token")
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	jwt := NewJWTAuth(logger)
+	router := mux.NewRouter()
+	router.Use(jwt.Middleware)
+	router.Use(NewRBAC(logger, jwt).Middleware)
+	var admitted Caller
+	// Literal paths go first, as in main.go, so /repairs/nearby is not taken
+	// for a repair ID
+	routes := slices.Collect(maps.Keys(rbacRoutes))
+	slices.SortFunc(routes, func(a, b string) int {
+		return strings.Count(a, "{") - strings.Count(b, "{")
+	})
+	for _, route := range routes {
+		method, path, _ := strings.Cut(route, " ")
+		router.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			admitted, _ = CallerFrom(r.Context())
+			w.WriteHeader(http.StatusOK)
+		}).Methods(method)
+	}
+
+	exp := float64(time.Now().Add(time.Hour).Unix())
+	tokens := map[string]string{
+		RoleUser:     signTestJWT(t, "test-secret", map[string]any{"sub": "user1", "exp": exp}),
+		RoleMechanic: signTestJWT(t, "test-secret", map[string]any{"sub": "mechanic1", "role": RoleMechanic, "exp": exp}),
+	}
+	ids := map[string]string{RoleUser: "user1", RoleMechanic: "mechanic1"}
+	vars := regexp.MustCompile(`\{[^}]+\}`)
+
+	serve := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		admitted = Caller{}
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	for route, roles := range rbacRoutes {
+		method, template, _ := strings.Cut(route, " ")
+		for _, role := range []string{RoleUser, RoleMechanic} {
+			path := vars.ReplaceAllString(template, ids[role])
+			code := serve(method, path, tokens[role])
+			switch {
+			case slices.Contains(roles, role) && code != http.StatusOK:
+				t.Errorf("%s as %s: status %d, want 200", route, role, code)
+			case slices.Contains(roles, role) && (admitted.Role != role || admitted.ID != ids[role]):
+				t.Errorf("%s as %s: admitted %+v", route, role, admitted)
+			case !slices.Contains(roles, role) && code != http.StatusForbidden:
+				t.Errorf("%s as %s: status %d, want 403", route, role, code)
+			}
+		}
+
+		path := vars.ReplaceAllString(template, "user1")
+		if code := serve(method, path, "admin-token"); code != http.StatusOK || admitted.Role != RoleAdmin {
+			t.Errorf("%s as admin: status %d, admitted %+v", route, code, admitted)
+		}
+		if code := serve(method, path, tokens[RoleUser]+"x"); code != http.StatusUnauthorized {
+			t.Errorf("%s with a forged token: status %d, want 401", route, code)
+		}
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-Actor-Role", RoleMechanic)
+		req.Header.Set("X-Actor-ID", "mechanic1")
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code == http.StatusOK {
+			t.Errorf("%s with actor headers only: status 200, want a token to be required", route)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"api-gateway/openapi"
+
+	"github.com/gorilla/mux"
+)
+
+// Request validation modes
+const (
+	ValidationOff     = "off"     // requests are not validated
+	ValidationWarn    = "warn"    // violations are logged and the request goes through
+	ValidationEnforce = "enforce" // violations are answered with 400
+)
+
+// maxValidatedBody bounds the bodies that are validated; larger bodies pass
+// through unvalidated and are left to the services' own limits
+const maxValidatedBody = 1 << 20
+
+// RequestValidation checks request bodies against the schemas documented in
+// the gateway's OpenAPI spec, so malformed payloads are rejected before they
+// reach repair-service and mechanic-service. Routes the spec does not
+// document a body for pass through untouched.
+type RequestValidation struct {
+	spec     *openapi.Spec
+	settings atomic.Pointer[validationSettings]
+	logger   *slog.Logger
+}
+
+// validationSettings are the reloadable settings of RequestValidation
+type validationSettings struct {
+	mode   string
+	routes map[string]string // mode by "METHOD route template" or route template
+}
+
+// NewRequestValidation creates the validation middleware configured from the
+// environment:
+//   - REQUEST_VALIDATION: mode of every route, off, warn or enforce; default
+//     warn, so violations can be reviewed in the logs before enforcing
+//   - REQUEST_VALIDATION_ROUTES: per-route overrides as "route=mode" or
+//     "METHOD route=mode", e.g. "POST /repairs=enforce,PATCH /repairs/{repairID}=off"
+//
+// An unreadable spec disables validation rather than failing startup.
+func NewRequestValidation(logger *slog.Logger) *RequestValidation {
+	v := &RequestValidation{logger: logger}
+	spec, err := openapi.Load()
+	if err != nil {
+		logger.Error("Failed to load OpenAPI spec, request validation disabled", "error", err, "app", "api-gateway")
+		v.settings.Store(&validationSettings{mode: ValidationOff})
+		return v
+	}
+	v.spec = spec
+	v.Configure(os.Getenv)
+	return v
+}
+
+// Configure replaces the validation modes with the ones read through get,
+// which takes the same keys as the environment. It has no effect when the
+// spec could not be loaded.
+func (v *RequestValidation) Configure(get func(string) string) {
+	if v.spec == nil {
+		return
+	}
+	settings := &validationSettings{mode: ValidationWarn, routes: map[string]string{}}
+	if mode := strings.ToLower(strings.TrimSpace(get("REQUEST_VALIDATION"))); mode != "" {
+		if isValidationMode(mode) {
+			settings.mode = mode
+		} else {
+			v.logger.Error("Ignoring invalid REQUEST_VALIDATION", "mode", mode, "app", "api-gateway")
+		}
+	}
+	for _, entry := range strings.Split(get("REQUEST_VALIDATION_ROUTES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 || !isValidationMode(strings.ToLower(strings.TrimSpace(entry[i+1:]))) {
+			v.logger.Error("Ignoring invalid route validation mode", "entry", entry, "app", "api-gateway")
+			continue
+		}
+		route := strings.TrimSpace(entry[:i])
+		if method, path, ok := strings.Cut(route, " "); ok {
+			route = strings.ToUpper(method) + " " + strings.TrimSpace(path)
+		}
+		settings.routes[route] = strings.ToLower(strings.TrimSpace(entry[i+1:]))
+	}
+	v.settings.Store(settings)
+	v.logger.Info("Request validation enabled", "mode", settings.mode, "routeOverrides", len(settings.routes), "app", "api-gateway")
+}
+
+func isValidationMode(mode string) bool {
+	return mode == ValidationOff || mode == ValidationWarn || mode == ValidationEnforce
+}
+
+// modeFor returns the validation mode of a route
+func (v *RequestValidation) modeFor(method, route string) string {
+	settings := v.settings.Load()
+	if mode, ok := settings.routes[method+" "+route]; ok {
+		return mode
+	}
+	if mode, ok := settings.routes[route]; ok {
+		return mode
+	}
+	return settings.mode
+}
+
+// Middleware validates the body of documented routes; in enforce mode
+// violations are answered with 400 and a JSON pointer per violation
+func (v *RequestValidation) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v.spec == nil || strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, r)
+			return
+		}
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		mode := v.modeFor(r.Method, route)
+		requestBody := v.spec.RequestBody(r.Method, route)
+		if mode == ValidationOff || requestBody == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		schema, ok := requestBody.Schema(r.Header.Get("Content-Type"))
+		if !ok {
+			v.reject(w, r, mode, route, http.StatusUnsupportedMediaType, "Unsupported content type "+r.Header.Get("Content-Type"), nil, next)
+			return
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxValidatedBody+1))
+		if err != nil {
+			r.Body = readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			v.reject(w, r, mode, route, http.StatusBadRequest, "Failed to read request body", nil, next)
+			return
+		}
+		if len(body) > maxValidatedBody {
+			r.Body = readCloser{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+			next.ServeHTTP(w, r)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		var violations []openapi.Violation
+		if len(bytes.TrimSpace(body)) == 0 {
+			if requestBody.Required {
+				violations = []openapi.Violation{{Pointer: "", Message: "a request body is required"}}
+			}
+		} else {
+			violations = schema.ValidateJSON(body)
+		}
+		if len(violations) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		v.reject(w, r, mode, route, http.StatusBadRequest, "The request body does not match the documented schema", violations, next)
+	})
+}
+
+// reject answers an invalid request in enforce mode; in warn mode it logs the
+// violations and lets the request through
+func (v *RequestValidation) reject(w http.ResponseWriter, r *http.Request, mode, route string, status int, message string, violations []openapi.Violation, next http.Handler) {
+	if mode != ValidationEnforce {
+		v.logger.Warn("Request violates the documented schema", "method", r.Method, "route", route, "message", message, "violations", violations, "app", "api-gateway")
+		next.ServeHTTP(w, r)
+		return
+	}
+	v.logger.Info("Rejected invalid request", "method", r.Method, "route", route, "message", message, "violations", len(violations), "app", "api-gateway")
+	if violations == nil {
+		violations = []openapi.Violation{}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error":   "invalid_request",
+		"message": message,
+		"details": violations,
+	})
+}
@@ -0,0 +1,124 @@
+// Package openapi holds the gateway's OpenAPI spec and validates request
+// bodies against the schemas it documents
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SpecJSON is the gateway's OpenAPI 3.0 spec, served on /openapi.json
+//
+//go:embed openapi.json
+var SpecJSON []byte
+
+// Spec is the part of an OpenAPI 3.0 document request validation uses
+type Spec struct {
+	Paths      map[string]map[string]*Operation `json:"paths"` // by path template, then lower case method
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+// Operation is one method of a path
+type Operation struct {
+	Summary     string       `json:"summary"`
+	RequestBody *RequestBody `json:"requestBody"`
+}
+
+// RequestBody documents the body an operation accepts, by media type
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+// MediaType is the schema of one request content type
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Load parses the embedded spec and resolves its $refs
+func Load() (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(SpecJSON, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI spec: %w", err)
+	}
+	for path, methods := range spec.Paths {
+		for method, op := range methods {
+			if op.RequestBody == nil {
+				continue
+			}
+			for mediaType, content := range op.RequestBody.Content {
+				if err := spec.resolve(content.Schema, 0); err != nil {
+					return nil, fmt.Errorf("%s %s %s: %w", strings.ToUpper(method), path, mediaType, err)
+				}
+			}
+		}
+	}
+	return &spec, nil
+}
+
+// resolve replaces $refs in schema and its subschemas with the components
+// they point to, which are resolved in turn, and compiles their patterns
+func (s *Spec) resolve(schema *Schema, depth int) error {
+	if schema == nil {
+		return nil
+	}
+	if depth > 32 {
+		return fmt.Errorf("schema nests too deep, is a $ref recursive?")
+	}
+	if schema.Ref != "" {
+		name, ok := strings.CutPrefix(schema.Ref, "#/components/schemas/")
+		target := s.Components.Schemas[name]
+		if !ok || target == nil {
+			return fmt.Errorf("unresolved $ref %q", schema.Ref)
+		}
+		if err := s.resolve(target, depth+1); err != nil {
+			return err
+		}
+		*schema = *target
+		return nil
+	}
+	if schema.Pattern != "" && schema.pattern == nil {
+		pattern, err := regexp.Compile(schema.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", schema.Pattern, err)
+		}
+		schema.pattern = pattern
+	}
+	for _, property := range schema.Properties {
+		if err := s.resolve(property, depth+1); err != nil {
+			return err
+		}
+	}
+	return s.resolve(schema.Items, depth+1)
+}
+
+// RequestBody returns the documented body of an operation, or nil when the
+// operation has none or is not documented
+func (s *Spec) RequestBody(method, pathTemplate string) *RequestBody {
+	op := s.Paths[pathTemplate][strings.ToLower(method)]
+	if op == nil {
+		return nil
+	}
+	return op.RequestBody
+}
+
+// Schema returns the body schema for a Content-Type, falling back to the
+// application/json schema for JSON media types the operation does not list
+func (b *RequestBody) Schema(contentType string) (*Schema, bool) {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if content, ok := b.Content[mediaType]; ok {
+		return content.Schema, true
+	}
+	if mediaType == "" || strings.HasSuffix(mediaType, "+json") || mediaType == "application/json" {
+		if content, ok := b.Content["application/json"]; ok {
+			return content.Schema, true
+		}
+	}
+	return nil, false
+}
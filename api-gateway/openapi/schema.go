@@ -0,0 +1,241 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/mail"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Schema is the subset of the OpenAPI 3.0 schema object the spec uses
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Nullable             bool               `json:"nullable"`
+	Enum                 []any              `json:"enum"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*Schema `json:"properties"`
+	AdditionalProperties *bool              `json:"additionalProperties"`
+	MinProperties        *int               `json:"minProperties"`
+	Items                *Schema            `json:"items"`
+	MinItems             *int               `json:"minItems"`
+	MaxItems             *int               `json:"maxItems"`
+	MinLength            *int               `json:"minLength"`
+	MaxLength            *int               `json:"maxLength"`
+	Pattern              string             `json:"pattern"`
+	Minimum              *float64           `json:"minimum"`
+	Maximum              *float64           `json:"maximum"`
+	ExclusiveMinimum     bool               `json:"exclusiveMinimum"`
+	ExclusiveMaximum     bool               `json:"exclusiveMaximum"`
+
+	pattern *regexp.Regexp // Pattern, compiled by Load
+}
+
+// Violation is one way a value breaks its schema. Pointer is the RFC 6901
+// JSON pointer of the offending value, "" for the whole body.
+type Violation struct {
+	Pointer string `json:"pointer"`
+	Message string `json:"message"`
+}
+
+// ValidateJSON decodes a JSON document and validates it against the schema
+func (s *Schema) ValidateJSON(data []byte) []Violation {
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return []Violation{{Pointer: "", Message: "invalid JSON: " + err.Error()}}
+	}
+	if decoder.More() {
+		return []Violation{{Pointer: "", Message: "invalid JSON: unexpected data after the document"}}
+	}
+	var violations []Violation
+	s.validate(value, "", &violations)
+	return violations
+}
+
+// validate appends the violations of value, found at pointer, to violations
+func (s *Schema) validate(value any, pointer string, violations *[]Violation) {
+	fail := func(format string, args ...any) {
+		*violations = append(*violations, Violation{Pointer: pointer, Message: fmt.Sprintf(format, args...)})
+	}
+	if value == nil {
+		if !s.Nullable && s.Type != "" {
+			fail("must be %s, not null", withArticle(s.Type))
+		}
+		return
+	}
+	if s.Type != "" && !hasType(value, s.Type) {
+		fail("must be %s, not %s", withArticle(s.Type), withArticle(jsonType(value)))
+		return
+	}
+	if len(s.Enum) > 0 && !inEnum(value, s.Enum) {
+		fail("must be one of %s", formatEnum(s.Enum))
+	}
+
+	switch v := value.(type) {
+	case string:
+		length := utf8.RuneCountInString(v)
+		if s.MinLength != nil && length < *s.MinLength {
+			if *s.MinLength == 1 {
+				fail("must not be empty")
+			} else {
+				fail("must be at least %d characters", *s.MinLength)
+			}
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("must be at most %d characters", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(v) {
+			fail("must match %s", s.Pattern)
+		}
+		switch s.Format {
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, v); err != nil {
+				fail("must be an RFC 3339 date-time")
+			}
+		case "email":
+			if addr, err := mail.ParseAddress(v); err != nil || addr.Address != v {
+				fail("must be an email address")
+			}
+		}
+	case json.Number:
+		n, _ := v.Float64()
+		if s.Minimum != nil && (n < *s.Minimum || s.ExclusiveMinimum && n == *s.Minimum) {
+			if s.ExclusiveMinimum {
+				fail("must be greater than %v", *s.Minimum)
+			} else {
+				fail("must be at least %v", *s.Minimum)
+			}
+		}
+		if s.Maximum != nil && (n > *s.Maximum || s.ExclusiveMaximum && n == *s.Maximum) {
+			if s.ExclusiveMaximum {
+				fail("must be less than %v", *s.Maximum)
+			} else {
+				fail("must be at most %v", *s.Maximum)
+			}
+		}
+	case []any:
+		if s.MinItems != nil && len(v) < *s.MinItems {
+			fail("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && len(v) > *s.MaxItems {
+			fail("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i, item := range v {
+				s.Items.validate(item, pointer+"/"+strconv.Itoa(i), violations)
+			}
+		}
+	case map[string]any:
+		if s.MinProperties != nil && len(v) < *s.MinProperties {
+			if *s.MinProperties == 1 {
+				fail("must not be empty")
+			} else {
+				fail("must have at least %d properties", *s.MinProperties)
+			}
+		}
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				*violations = append(*violations, Violation{Pointer: pointer + "/" + escapePointer(name), Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			property, ok := s.Properties[name]
+			switch {
+			case ok:
+				property.validate(v[name], pointer+"/"+escapePointer(name), violations)
+			case s.AdditionalProperties != nil && !*s.AdditionalProperties:
+				*violations = append(*violations, Violation{Pointer: pointer + "/" + escapePointer(name), Message: "is not an allowed property"})
+			}
+		}
+	}
+}
+
+// hasType reports whether a decoded JSON value is of an OpenAPI type
+func hasType(value any, typ string) bool {
+	switch typ {
+	case "integer":
+		n, ok := value.(json.Number)
+		if !ok {
+			return false
+		}
+		f, err := n.Float64()
+		return err == nil && f == math.Trunc(f)
+	case "number":
+		_, ok := value.(json.Number)
+		return ok
+	}
+	return jsonType(value) == typ
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
+
+func withArticle(typ string) string {
+	switch typ {
+	case "object", "array", "integer":
+		return "an " + typ
+	case "null":
+		return typ
+	}
+	return "a " + typ
+}
+
+// inEnum compares a decoded value with the spec's enum values; numbers are
+// compared by value
+func inEnum(value any, enum []any) bool {
+	if n, ok := value.(json.Number); ok {
+		f, _ := n.Float64()
+		value = f
+	}
+	for _, allowed := range enum {
+		if reflect.DeepEqual(value, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func formatEnum(enum []any) string {
+	values := make([]string, len(enum))
+	for i, v := range enum {
+		b, _ := json.Marshal(v)
+		values[i] = string(b)
+	}
+	return strings.Join(values, ", ")
+}
+
+// escapePointer escapes a property name for a JSON pointer (RFC 6901)
+func escapePointer(name string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(name, "~", "~0"), "/", "~1")
+}
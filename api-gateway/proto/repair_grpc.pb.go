@@ -0,0 +1,171 @@
+// proto/repair.proto
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v3.21.12
+// source: proto/repair.proto
+
+package proto
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	RepairService_StreamAllRepairs_FullMethodName = "/repair.RepairService/StreamAllRepairs"
+	RepairService_GetRepair_FullMethodName        = "/repair.RepairService/GetRepair"
+)
+
+// RepairServiceClient is the client API for RepairService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type RepairServiceClient interface {
+	// Server-streaming RPC to get all repairs and stream new ones. Filters apply
+	// to both the initial snapshot and newly inserted repairs.
+	StreamAllRepairs(ctx context.Context, in *StreamRepairsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Repair], error)
+	// Looks up one repair by ID; NOT_FOUND when it does not exist
+	GetRepair(ctx context.Context, in *GetRepairRequest, opts ...grpc.CallOption) (*Repair, error)
+}
+
+type repairServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewRepairServiceClient(cc grpc.ClientConnInterface) RepairServiceClient {
+	return &repairServiceClient{cc}
+}
+
+func (c *repairServiceClient) StreamAllRepairs(ctx context.Context, in *StreamRepairsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Repair], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &RepairService_ServiceDesc.Streams[0], RepairService_StreamAllRepairs_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[StreamRepairsRequest, Repair]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RepairService_StreamAllRepairsClient = grpc.ServerStreamingClient[Repair]
+
+func (c *repairServiceClient) GetRepair(ctx context.Context, in *GetRepairRequest, opts ...grpc.CallOption) (*Repair, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Repair)
+	err := c.cc.Invoke(ctx, RepairService_GetRepair_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RepairServiceServer is the server API for RepairService service.
+// All implementations must embed UnimplementedRepairServiceServer
+// for forward compatibility.
+type RepairServiceServer interface {
+	// Server-streaming RPC to get all repairs and stream new ones. Filters apply
+	// to both the initial snapshot and newly inserted repairs.
+	StreamAllRepairs(*StreamRepairsRequest, grpc.ServerStreamingServer[Repair]) error
+	// Looks up one repair by ID; NOT_FOUND when it does not exist
+	GetRepair(context.Context, *GetRepairRequest) (*Repair, error)
+	mustEmbedUnimplementedRepairServiceServer()
+}
+
+// UnimplementedRepairServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedRepairServiceServer struct{}
+
+func (UnimplementedRepairServiceServer) StreamAllRepairs(*StreamRepairsRequest, grpc.ServerStreamingServer[Repair]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamAllRepairs not implemented")
+}
+func (UnimplementedRepairServiceServer) GetRepair(context.Context, *GetRepairRequest) (*Repair, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRepair not implemented")
+}
+func (UnimplementedRepairServiceServer) mustEmbedUnimplementedRepairServiceServer() {}
+func (UnimplementedRepairServiceServer) testEmbeddedByValue()                       {}
+
+// UnsafeRepairServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to RepairServiceServer will
+// result in compilation errors.
+type UnsafeRepairServiceServer interface {
+	mustEmbedUnimplementedRepairServiceServer()
+}
+
+func RegisterRepairServiceServer(s grpc.ServiceRegistrar, srv RepairServiceServer) {
+	// If the following call pancis, it indicates UnimplementedRepairServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&RepairService_ServiceDesc, srv)
+}
+
+func _RepairService_StreamAllRepairs_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamRepairsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(RepairServiceServer).StreamAllRepairs(m, &grpc.GenericServerStream[StreamRepairsRequest, Repair]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type RepairService_StreamAllRepairsServer = grpc.ServerStreamingServer[Repair]
+
+func _RepairService_GetRepair_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRepairRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RepairServiceServer).GetRepair(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RepairService_GetRepair_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RepairServiceServer).GetRepair(ctx, req.(*GetRepairRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// RepairService_ServiceDesc is the grpc.ServiceDesc for RepairService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var RepairService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "repair.RepairService",
+	HandlerType: (*RepairServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetRepair",
+			Handler:    _RepairService_GetRepair_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamAllRepairs",
+			Handler:       _RepairService_StreamAllRepairs_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "proto/repair.proto",
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Schema validation modes selected with MONGO_SCHEMA_VALIDATION
+const (
+	schemaValidationStrict = "strict" // reject invalid writes
+	schemaValidationWarn   = "warn"   // accept invalid writes but log them in the mongod log
+	schemaValidationOff    = "off"    // remove validators
+)
+
+// namespaceNotFoundCode is returned by collMod when the collection does not exist
+const namespaceNotFoundCode = 26
+
+// nullable allows a field to be null as well as the given BSON types; the Go
+// driver encodes nil slices and pointers without omitempty as null
+func nullable(types ...string) bson.A {
+	a := bson.A{"null"}
+	for _, t := range types {
+		a = append(a, t)
+	}
+	return a
+}
+
+// locationSchema validates a {longitude, latitude} document
+var locationSchema = bson.M{
+	"bsonType": "object",
+	"required": bson.A{"longitude", "latitude"},
+	"properties": bson.M{
+		"longitude": bson.M{"bsonType": "number", "minimum": -180, "maximum": 180},
+		"latitude":  bson.M{"bsonType": "number", "minimum": -90, "maximum": 90},
+	},
+}
+
+// repairCostSchema validates a repair cost, stored on its own in repair_costs
+// and embedded in repairs
+var repairCostSchema = bson.M{
+	"bsonType": "object",
+	"required": bson.A{"userID", "repairType", "totalPrice"},
+	"properties": bson.M{
+		"userID":       bson.M{"bsonType": "string", "minLength": 1},
+		"repairType":   bson.M{"bsonType": "string", "minLength": 1},
+		"totalPrice":   bson.M{"bsonType": "number", "minimum": 0},
+		"userLocation": bson.M{"oneOf": bson.A{bson.M{"bsonType": "null"}, locationSchema}},
+		"mechanics":    bson.M{"bsonType": nullable("array")},
+	},
+}
+
+// outboxSchema validates the fields shared by the outbox collections
+func outboxSchema(extraRequired bson.A, extraProperties bson.M) bson.M {
+	properties := bson.M{
+		"event_type":   bson.M{"bsonType": "string", "minLength": 1},
+		"payload":      bson.M{"bsonType": "binData"},
+		"created_at":   bson.M{"bsonType": "date"},
+		"processed":    bson.M{"bsonType": "bool"},
+		"processed_at": bson.M{"bsonType": nullable("date")},
+	}
+	for k, v := range extraProperties {
+		properties[k] = v
+	}
+	return bson.M{
+		"bsonType":   "object",
+		"required":   append(bson.A{"event_type", "payload", "created_at", "processed"}, extraRequired...),
+		"properties": properties,
+	}
+}
+
+// collectionSchemas maps each validated repairdb collection to its JSON schema.
+// Schemas only cover fields every writer sets, so unknown fields stay allowed.
+var collectionSchemas = map[string]bson.M{
+	"repairs": {
+		"bsonType": "object",
+		"required": bson.A{"_id", "userID", "status"},
+		"properties": bson.M{
+			"_id":        bson.M{"bsonType": "string", "minLength": 1},
+			"userID":     bson.M{"bsonType": "string", "minLength": 1},
+			"status":     bson.M{"enum": bson.A{"pending", "accepted", "in_progress", "completed", "cancelled"}},
+			"repairCost": bson.M{"oneOf": bson.A{bson.M{"bsonType": "null"}, repairCostSchema}},
+			"assignedTo": bson.M{"bsonType": "string"},
+			"tags":       bson.M{"bsonType": "array", "items": bson.M{"bsonType": "string", "minLength": 1, "maxLength": 50}},
+			"createdAt":  bson.M{"bsonType": "date"},
+			"region":     bson.M{"bsonType": "string", "minLength": 1},
+		},
+	},
+	"repair_notes": {
+		"bsonType": "object",
+		"required": bson.A{"repairID", "author", "body", "createdAt"},
+		"properties": bson.M{
+			"repairID":  bson.M{"bsonType": "string", "minLength": 1},
+			"author":    bson.M{"bsonType": "string", "minLength": 1},
+			"body":      bson.M{"bsonType": "string", "minLength": 1},
+			"createdAt": bson.M{"bsonType": "date"},
+		},
+	},
+	"repair_costs": repairCostSchema,
+	"erasure_reports": {
+		"bsonType": "object",
+		"required": bson.A{"userIDHash", "pseudonym", "anonymized", "deleted", "tombstones", "requestedAt", "completedAt"},
+		"properties": bson.M{
+			"userIDHash":  bson.M{"bsonType": "string", "minLength": 64, "maxLength": 64},
+			"pseudonym":   bson.M{"bsonType": "string", "minLength": 1},
+			"anonymized":  bson.M{"bsonType": "object"},
+			"deleted":     bson.M{"bsonType": "object"},
+			"tombstones":  bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 0},
+			"requestedAt": bson.M{"bsonType": "date"},
+			"completedAt": bson.M{"bsonType": "date"},
+		},
+	},
+	"phone_verifications": {
+		"bsonType": "object",
+		"required": bson.A{"phone", "verified", "updatedAt"},
+		"properties": bson.M{
+			"phone":     bson.M{"bsonType": "string", "pattern": `^\+[1-9][0-9]{6,14}$`},
+			"verified":  bson.M{"bsonType": "bool"},
+			"attempts":  bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 0},
+			"sentAt":    bson.M{"bsonType": "array", "items": bson.M{"bsonType": "date"}},
+			"updatedAt": bson.M{"bsonType": "date"},
+		},
+	},
+	"mechanics": {
+		"bsonType": "object",
+		"required": bson.A{"_id", "name", "location"},
+		"properties": bson.M{
+			"_id":      bson.M{"bsonType": "string", "minLength": 1},
+			"name":     bson.M{"bsonType": "string", "minLength": 1},
+			"location": locationSchema,
+			"status":   bson.M{"enum": bson.A{"online", "offline"}},
+			"skills":   bson.M{"bsonType": "array", "items": bson.M{"bsonType": "string"}},
+			"region":   bson.M{"bsonType": "string", "minLength": 1},
+		},
+	},
+	"blocks": {
+		"bsonType": "object",
+		"required": bson.A{"userID", "mechanicID", "blockedBy", "createdAt"},
+		"properties": bson.M{
+			"userID":     bson.M{"bsonType": "string", "minLength": 1},
+			"mechanicID": bson.M{"bsonType": "string", "minLength": 1},
+			"blockedBy":  bson.M{"enum": bson.A{"user", "admin"}},
+			"createdAt":  bson.M{"bsonType": "date"},
+		},
+	},
+	"anonymous_quotes": {
+		"bsonType": "object",
+		"required": bson.A{"repairType", "totalPrice", "anonymous", "createdAt"},
+		"properties": bson.M{
+			"repairType": bson.M{"bsonType": "string", "minLength": 1},
+			"totalPrice": bson.M{"bsonType": "number", "minimum": 0},
+			"anonymous":  bson.M{"enum": bson.A{true}},
+			"createdAt":  bson.M{"bsonType": "date"},
+			"claimedBy":  bson.M{"bsonType": "string", "minLength": 1},
+		},
+	},
+	"receipts": {
+		"bsonType": "object",
+		"required": bson.A{"number", "userID", "repairType", "currency", "lineItems", "subtotal", "taxes", "total", "issuedAt"},
+		"properties": bson.M{
+			"number":     bson.M{"bsonType": "string", "minLength": 1},
+			"userID":     bson.M{"bsonType": "string", "minLength": 1},
+			"repairType": bson.M{"bsonType": "string", "minLength": 1},
+			"currency":   bson.M{"bsonType": "string", "minLength": 1},
+			"lineItems": bson.M{
+				"bsonType": "array",
+				"minItems": 1,
+				"items": bson.M{
+					"bsonType": "object",
+					"required": bson.A{"description", "quantity", "unitPrice", "amount"},
+					"properties": bson.M{
+						"quantity":  bson.M{"bsonType": bson.A{"int", "long"}, "minimum": 1},
+						"unitPrice": bson.M{"bsonType": "number", "minimum": 0},
+						"amount":    bson.M{"bsonType": "number", "minimum": 0},
+					},
+				},
+			},
+			"subtotal": bson.M{"bsonType": "number", "minimum": 0},
+			"taxes":    bson.M{"bsonType": "array"},
+			"total":    bson.M{"bsonType": "number", "minimum": 0},
+			"issuedAt": bson.M{"bsonType": "date"},
+		},
+	},
+	"repair_outbox": outboxSchema(nil, bson.M{
+		"redrive_of": bson.M{"bsonType": "string", "minLength": 1},
+		"topic":      bson.M{"bsonType": "string", "minLength": 1},
+	}),
+	"assignment_outbox": outboxSchema(bson.A{"aggregate_id"}, bson.M{
+		"aggregate_id": bson.M{"bsonType": "string", "minLength": 1},
+		"expire_at":    bson.M{"bsonType": "date"},
+	}),
+	"mechanic_outbox": outboxSchema(bson.A{"kafka_topic", "kafka_partition", "kafka_offset"}, bson.M{
+		"kafka_topic":     bson.M{"bsonType": "string", "minLength": 1},
+		"kafka_partition": bson.M{"bsonType": "int"},
+		"kafka_offset":    bson.M{"bsonType": "long"},
+	}),
+}
+
+// applySchemaValidators installs the JSON schema validators on the repairdb
+// collections, creating collections that do not exist yet. The mode comes from
+// MONGO_SCHEMA_VALIDATION (strict, warn or off; default strict).
+func applySchemaValidators(ctx context.Context, db *mongo.Database) error {
+	mode := os.Getenv("MONGO_SCHEMA_VALIDATION")
+	if mode == "" {
+		mode = schemaValidationStrict
+	}
+	var level, action string
+	switch mode {
+	case schemaValidationStrict:
+		level, action = "strict", "error"
+	case schemaValidationWarn:
+		level, action = "strict", "warn"
+	case schemaValidationOff:
+		level, action = "off", "error"
+	default:
+		return fmt.Errorf("invalid MONGO_SCHEMA_VALIDATION %q: must be strict, warn or off", mode)
+	}
+
+	for name, schema := range collectionSchemas {
+		validator := bson.M{"$jsonSchema": schema}
+		if mode == schemaValidationOff {
+			validator = bson.M{}
+		}
+		collMod := bson.D{
+			{Key: "collMod", Value: name},
+			{Key: "validator", Value: validator},
+			{Key: "validationLevel", Value: level},
+			{Key: "validationAction", Value: action},
+		}
+		err := db.RunCommand(ctx, collMod).Err()
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && cmdErr.Code == namespaceNotFoundCode {
+			create := bson.D{
+				{Key: "create", Value: name},
+				{Key: "validator", Value: validator},
+				{Key: "validationLevel", Value: level},
+				{Key: "validationAction", Value: action},
+			}
+			err = db.RunCommand(ctx, create).Err()
+		}
+		if err != nil {
+			slog.Error("failed to apply schema validator", "collection", name, "error", err)
+			return fmt.Errorf("failed to apply schema validator to %s: %w", name, err)
+		}
+	}
+	slog.Info("Applied MongoDB schema validators", "mode", mode, "collections", len(collectionSchemas))
+	return nil
+}
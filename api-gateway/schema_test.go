@@ -0,0 +1,25 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TestRepairStatusSchema checks the repairs validator accepts every status a
+// service stores: repair-service's status transitions (domain/bulk_status.go)
+// and the open statuses mechanic-service assigns and reschedules
+// (domain/mechanic.go, domain/absence.go)
+func TestRepairStatusSchema(t *testing.T) {
+	properties := collectionSchemas["repairs"]["properties"].(bson.M)
+	enum := properties["status"].(bson.M)["enum"].(bson.A)
+	for _, status := range []string{"pending", "accepted", "in_progress", "completed", "cancelled"} {
+		if !slices.Contains(enum, any(status)) {
+			t.Errorf("repairs status enum %v rejects %q", enum, status)
+		}
+	}
+	if slices.Contains(enum, any("deleted")) {
+		t.Errorf("repairs status enum %v accepts %q, which is only an event status", enum, "deleted")
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// selfTestTimeout bounds each individual self-test check
+const selfTestTimeout = 5 * time.Second
+
+// selfTestCheck is the outcome of one self-test check
+type selfTestCheck struct {
+	Name       string `json:"name"`
+	Target     string `json:"target"`
+	OK         bool   `json:"ok"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// selfTestReport is printed to stdout by --selftest
+type selfTestReport struct {
+	Service string          `json:"service"`
+	OK      bool            `json:"ok"`
+	Checks  []selfTestCheck `json:"checks"`
+}
+
+// runSelfTest checks every dependency api-gateway needs, prints a JSON
+// report and returns the process exit code (0 when all checks pass)
+func runSelfTest() int {
+	consulAddr := os.Getenv("CONSUL_ADDRESS")
+	if consulAddr == "" {
+		consulAddr = "consul:8500"
+	}
+	mongoURI := "mongodb://mongodb:27017/?directConnection=true"
+
+	report := selfTestReport{Service: "api-gateway", OK: true}
+	run := func(name, target string, check func(ctx context.Context) error) {
+		ctx, cancel := context.WithTimeout(context.Background(), selfTestTimeout)
+		defer cancel()
+		start := time.Now()
+		result := selfTestCheck{Name: name, Target: target, OK: true}
+		if err := check(ctx); err != nil {
+			result.OK = false
+			result.Error = err.Error()
+			report.OK = false
+		}
+		result.DurationMs = time.Since(start).Milliseconds()
+		report.Checks = append(report.Checks, result)
+	}
+
+	run("mongodb", mongoURI, func(ctx context.Context) error {
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+		if err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		defer client.Disconnect(context.Background())
+		if err := client.Ping(ctx, nil); err != nil {
+			return fmt.Errorf("failed to ping: %w", err)
+		}
+		return nil
+	})
+	run("consul", consulAddr, func(ctx context.Context) error {
+		config := api.DefaultConfig()
+		config.Address = consulAddr
+		client, err := api.NewClient(config)
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+		leader, err := client.Status().LeaderWithQueryOptions((&api.QueryOptions{}).WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("failed to query leader: %w", err)
+		}
+		if leader == "" {
+			return fmt.Errorf("cluster has no leader")
+		}
+		return nil
+	})
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,173 @@
+// Package telemetry adds client spans following the OpenTelemetry semantic
+// conventions to MongoDB commands and downstream HTTP calls, with peer.service
+// set so Jaeger and Grafana service graphs draw an edge to every dependency
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer is the instrumentation scope of the client spans
+var tracer = otel.Tracer("api-gateway")
+
+// MongoPeerService is the peer.service of MongoDB command spans
+const MongoPeerService = "mongodb"
+
+// commandKey identifies an in-flight command; request IDs are only unique
+// per connection
+type commandKey struct {
+	connection string
+	requestID  int64
+}
+
+// MongoMonitor starts a client span per command, named "<command>
+// <collection>", with the db.* and server.* attributes, then calls the
+// callbacks of next (e.g. the slowlog monitor) when it is not nil. Set it with
+// options.Client().SetMonitor.
+func MongoMonitor(next *event.CommandMonitor) *event.CommandMonitor {
+	var spans sync.Map // commandKey -> trace.Span
+	finish := func(evt event.CommandFinishedEvent, failure string) {
+		value, ok := spans.LoadAndDelete(commandKey{evt.ConnectionID, evt.RequestID})
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+		if failure != "" {
+			span.RecordError(errors.New(failure))
+			span.SetStatus(codes.Error, failure)
+		}
+		span.End()
+	}
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, evt *event.CommandStartedEvent) {
+			attrs := []attribute.KeyValue{
+				semconv.DBSystemMongoDB,
+				semconv.DBNamespace(evt.DatabaseName),
+				semconv.DBOperationName(evt.CommandName),
+				semconv.PeerService(MongoPeerService),
+			}
+			name := evt.CommandName
+			if elem, err := evt.Command.IndexErr(0); err == nil {
+				if coll, ok := elem.Value().StringValueOK(); ok {
+					name += " " + coll
+					attrs = append(attrs, semconv.DBCollectionName(coll))
+				}
+			}
+			attrs = append(attrs, serverAttributes(mongoAddress(evt.ConnectionID))...)
+			_, span := tracer.Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+			spans.Store(commandKey{evt.ConnectionID, evt.RequestID}, span)
+			if next != nil && next.Started != nil {
+				next.Started(ctx, evt)
+			}
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			finish(evt.CommandFinishedEvent, "")
+			if next != nil && next.Succeeded != nil {
+				next.Succeeded(ctx, evt)
+			}
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			finish(evt.CommandFinishedEvent, evt.Failure)
+			if next != nil && next.Failed != nil {
+				next.Failed(ctx, evt)
+			}
+		},
+	}
+}
+
+// mongoAddress returns the host:port of a driver connection ID such as
+// "mongodb:27017[-5]"
+func mongoAddress(connectionID string) string {
+	if i := strings.LastIndex(connectionID, "["); i > 0 {
+		return connectionID[:i]
+	}
+	return connectionID
+}
+
+// serverAttributes returns server.address and, when present, server.port of hostport
+func serverAttributes(hostport string) []attribute.KeyValue {
+	host, port, err := net.SplitHostPort(hostport)
+	if err != nil {
+		return []attribute.KeyValue{semconv.ServerAddress(hostport)}
+	}
+	attrs := []attribute.KeyValue{semconv.ServerAddress(host)}
+	if p, err := strconv.Atoi(port); err == nil {
+		attrs = append(attrs, semconv.ServerPort(p))
+	}
+	return attrs
+}
+
+// PeerServiceFunc names the service a request goes to for peer.service; an
+// empty name falls back to the request host
+type PeerServiceFunc func(req *http.Request) string
+
+// PeerService names every request after service
+func PeerService(service string) PeerServiceFunc {
+	return func(*http.Request) string { return service }
+}
+
+// Transport wraps base (http.DefaultTransport when nil) so every request runs
+// in a client span named after its method, carrying the http.*, url.full,
+// server.* and peer.service attributes, and propagates the span's trace
+// context downstream. Responses of 400 and above mark the span as failed.
+func Transport(base http.RoundTripper, peer PeerServiceFunc) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripper{base: base, peer: peer}
+}
+
+type roundTripper struct {
+	base http.RoundTripper
+	peer PeerServiceFunc
+}
+
+func (t roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	peerService := ""
+	if t.peer != nil {
+		peerService = t.peer(req)
+	}
+	if peerService == "" {
+		peerService = req.URL.Hostname()
+	}
+	// Query strings may carry tokens, so url.full stops at the path
+	full := req.URL.Scheme + "://" + req.URL.Host + req.URL.EscapedPath()
+	attrs := []attribute.KeyValue{
+		semconv.HTTPRequestMethodKey.String(req.Method),
+		semconv.URLFull(full),
+		semconv.PeerService(peerService),
+	}
+	attrs = append(attrs, serverAttributes(req.URL.Host)...)
+	ctx, span := tracer.Start(req.Context(), req.Method, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	defer span.End()
+
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(semconv.ErrorTypeOther)
+		return nil, err
+	}
+	span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+	if resp.StatusCode >= http.StatusBadRequest {
+		span.SetStatus(codes.Error, resp.Status)
+		span.SetAttributes(semconv.ErrorTypeKey.String(strconv.Itoa(resp.StatusCode)))
+	}
+	return resp, nil
+}
@@ -7,7 +7,8 @@ db.mechanics.insertMany([
         "location": {
             "longitude": 13.388860,
             "latitude": 52.517037
-        }
+        },
+        "status": "online"
     },
     {
         "_id": "mechanic2",
@@ -15,7 +16,9 @@ db.mechanics.insertMany([
         "location": {
             "longitude": 13.397634,
             "latitude": 52.529407
-        }
+        },
+        "status": "online",
+        "skills": ["flat_tire", "chain_replacement"]
     },
     {
         "_id": "mechanic3",
@@ -23,7 +26,8 @@ db.mechanics.insertMany([
         "location": {
             "longitude": 13.428555,
             "latitude": 52.523219
-        }
+        },
+        "status": "offline"
     }
 ])
 rs.initiate({
@@ -36,3 +40,10 @@ db.mechanic_outbox.createIndex(
   { "kafka_topic": 1, "kafka_partition": 1, "kafka_offset": 1 },
   { unique: true }
 )
+db.repairs.createIndex({ "assignedTo": 1, "status": 1 })
+db.fleet_accounts.createIndex(
+  { "members.userID": 1 },
+  { unique: true, partialFilterExpression: { "members.userID": { $exists: true } } }
+)
+db.receipts.createIndex({ "userID": 1, "completedAt": 1 })
+db.fleet_invoices.createIndex({ "fleetID": 1, "month": -1 })
@@ -36,3 +36,10 @@ db.mechanic_outbox.createIndex(
   { "kafka_topic": 1, "kafka_partition": 1, "kafka_offset": 1 },
   { unique: true }
 )
+db.repairs.createIndex({ "assignedTo": 1, "status": 1 })
+db.fleet_accounts.createIndex(
+  { "members.userID": 1 },
+  { unique: true, partialFilterExpression: { "members.userID": { $exists: true } } }
+)
+db.receipts.createIndex({ "userID": 1, "completedAt": 1 })
+db.fleet_invoices.createIndex({ "fleetID": 1, "month": -1 })
@@ -0,0 +1,130 @@
+package cdc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mechanic-service/domain"
+	"mechanic-service/proto"
+	"mechanic-service/region"
+	"mechanic-service/repairquery"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CatchUp bootstraps the repairs view from repair-service's Mongo change stream,
+// exposed through the StreamAllRepairs gRPC stream, while Kafka is unavailable.
+// Repairs it inserts are marked with domain.RepairSourceCDC so the Kafka events
+// replace them once the consumer catches up.
+type CatchUp struct {
+	client proto.RepairServiceClient
+	repo   domain.MechanicRepository
+	logger *slog.Logger
+	tracer trace.Tracer
+	window time.Duration
+	scope  region.Scope
+}
+
+// NewCatchUp creates a CatchUp that reads the stream for at most window per run
+func NewCatchUp(conn grpc.ClientConnInterface, repo domain.MechanicRepository, logger *slog.Logger, window time.Duration) *CatchUp {
+	return &CatchUp{
+		client: proto.NewRepairServiceClient(conn),
+		repo:   repo,
+		logger: logger,
+		tracer: otel.Tracer("mechanic-service"),
+		window: window,
+		scope:  region.ScopeFromEnv(),
+	}
+}
+
+// Run streams repairs created at or after since until the window elapses and
+// inserts those missing from the repairs view, skipping repairs of regions
+// outside REGION_SCOPE. It returns the number of repairs inserted.
+func (c *CatchUp) Run(ctx context.Context, since time.Time) (int, error) {
+	ctx, span := c.tracer.Start(ctx, "CDCCatchUp")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("since", since.Format(time.RFC3339)),
+		attribute.String("window", c.window.String()),
+	)
+
+	streamCtx, cancel := context.WithTimeout(ctx, c.window)
+	defer cancel()
+
+	stream, err := c.client.StreamAllRepairs(streamCtx, &proto.StreamRepairsRequest{SinceUnixMs: since.UnixMilli()})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to open repair stream")
+		return 0, fmt.Errorf("failed to open repair stream: %w", err)
+	}
+
+	inserted := 0
+	for {
+		msg, err := stream.Recv()
+		if err != nil {
+			// The window elapsing is the normal way a catch-up run ends
+			if errors.Is(err, io.EOF) || status.Code(err) == grpccodes.DeadlineExceeded {
+				break
+			}
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Repair stream failed")
+			return inserted, fmt.Errorf("failed to receive repair: %w", err)
+		}
+
+		if !c.scope.Allows(msg.GetRegion()) {
+			continue
+		}
+		ok, err := c.insertMissing(ctx, fromProto(msg))
+		if err != nil {
+			span.RecordError(err)
+			c.logger.Error("Failed to insert catch-up repair", "repairID", msg.GetId(), "error", err, "app", "mechanic-service")
+			continue
+		}
+		if ok {
+			inserted++
+		}
+	}
+
+	span.SetAttributes(attribute.Int("insertedCount", inserted))
+	return inserted, nil
+}
+
+// insertMissing inserts the repair unless the view already has it
+func (c *CatchUp) insertMissing(ctx context.Context, repair *domain.Repair) (bool, error) {
+	inserted := false
+	err := c.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		exists, err := c.repo.CheckRepairExists(tx, repair.ID)
+		if err != nil {
+			return fmt.Errorf("failed to check existing repair: %w", err)
+		}
+		if exists {
+			return nil
+		}
+		if err := c.repo.InsertRepair(tx, repair); err != nil {
+			return fmt.Errorf("failed to insert repair: %w", err)
+		}
+		inserted = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return inserted, nil
+}
+
+// fromProto converts a streamed repair into the repairs view shape, marked as
+// inserted by the catch-up
+func fromProto(msg *proto.Repair) *domain.Repair {
+	repair := repairquery.FromProto(msg)
+	repair.Source = domain.RepairSourceCDC
+	return repair
+}
@@ -0,0 +1,246 @@
+// Package connect makes a service Consul Connect native: it serves and calls
+// other services over mTLS with certificates issued by the local Consul agent,
+// and authorizes callers against Consul intentions, without Envoy sidecars.
+package connect
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Enabled reports whether CONNECT_NATIVE turns Connect on
+func Enabled() bool {
+	return os.Getenv("CONNECT_NATIVE") == "true"
+}
+
+// Service holds a service's Connect identity: its leaf certificate and the CA
+// roots, both kept current with Consul blocking queries
+type Service struct {
+	consul *api.Client
+	name   string // the identity in certificates and intentions
+	logger *slog.Logger
+
+	mu    sync.RWMutex
+	leaf  *tls.Certificate
+	roots *x509.CertPool
+}
+
+// New fetches the leaf certificate of service name and the CA roots from the
+// local agent, then follows their rotation in the background
+func New(consul *api.Client, name string, logger *slog.Logger) (*Service, error) {
+	s := &Service{consul: consul, name: name, logger: logger}
+	rootsIndex, err := s.fetchRoots(0)
+	if err != nil {
+		return nil, err
+	}
+	leafIndex, err := s.fetchLeaf(0)
+	if err != nil {
+		return nil, err
+	}
+	go s.watch("CA roots", s.fetchRoots, rootsIndex)
+	go s.watch("leaf certificate", s.fetchLeaf, leafIndex)
+	return s, nil
+}
+
+// Name returns the service's Connect identity
+func (s *Service) Name() string {
+	return s.name
+}
+
+// fetchRoots replaces the CA roots once they change after index
+func (s *Service) fetchRoots(index uint64) (uint64, error) {
+	list, meta, err := s.consul.Agent().ConnectCARoots(&api.QueryOptions{WaitIndex: index, WaitTime: 10 * time.Minute})
+	if err != nil {
+		return index, fmt.Errorf("failed to fetch Connect CA roots: %w", err)
+	}
+	pool := x509.NewCertPool()
+	for _, root := range list.Roots {
+		if !pool.AppendCertsFromPEM([]byte(root.RootCertPEM)) {
+			return index, fmt.Errorf("invalid Connect CA root %s", root.ID)
+		}
+	}
+	s.mu.Lock()
+	s.roots = pool
+	s.mu.Unlock()
+	return meta.LastIndex, nil
+}
+
+// fetchLeaf replaces the leaf certificate once it changes after index; the
+// agent renews it before it expires
+func (s *Service) fetchLeaf(index uint64) (uint64, error) {
+	leaf, meta, err := s.consul.Agent().ConnectCALeaf(s.name, &api.QueryOptions{WaitIndex: index, WaitTime: 10 * time.Minute})
+	if err != nil {
+		return index, fmt.Errorf("failed to fetch Connect leaf certificate: %w", err)
+	}
+	cert, err := tls.X509KeyPair([]byte(leaf.CertPEM), []byte(leaf.PrivateKeyPEM))
+	if err != nil {
+		return index, fmt.Errorf("invalid Connect leaf certificate: %w", err)
+	}
+	s.mu.Lock()
+	s.leaf = &cert
+	s.mu.Unlock()
+	if index != 0 {
+		s.logger.Info("Rotated Connect leaf certificate", "service", s.name, "serial", leaf.SerialNumber, "validBefore", leaf.ValidBefore)
+	}
+	return meta.LastIndex, nil
+}
+
+// watch keeps calling fetch with the last index, backing off after errors
+func (s *Service) watch(what string, fetch func(uint64) (uint64, error), index uint64) {
+	for {
+		next, err := fetch(index)
+		if err != nil {
+			s.logger.Error("Failed to watch Connect "+what+", retrying", "error", err, "service", s.name)
+			time.Sleep(5 * time.Second)
+			continue
+		}
+		if next < index {
+			next = 0 // Consul's index went backwards; start over
+		}
+		index = next
+	}
+}
+
+func (s *Service) certificate() (*tls.Certificate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.leaf, nil
+}
+
+// verifyChain checks that rawCerts chain to a Connect CA root and returns the
+// SPIFFE ID and serial number of the leaf
+func (s *Service) verifyChain(rawCerts [][]byte) (*url.URL, string, error) {
+	if len(rawCerts) == 0 {
+		return nil, "", errors.New("no certificate presented")
+	}
+	certs := make([]*x509.Certificate, len(rawCerts))
+	for i, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid certificate: %w", err)
+		}
+		certs[i] = cert
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	s.mu.RLock()
+	roots := s.roots
+	s.mu.RUnlock()
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("certificate not issued by the Connect CA: %w", err)
+	}
+	if len(certs[0].URIs) != 1 || certs[0].URIs[0].Scheme != "spiffe" {
+		return nil, "", errors.New("certificate carries no SPIFFE ID")
+	}
+	return certs[0].URIs[0], serialHex(certs[0].SerialNumber.Bytes()), nil
+}
+
+// ServerTLSConfig requires callers to present a Connect certificate and lets
+// them in only when an intention allows them to call this service
+func (s *Service) ServerTLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ClientAuth: tls.RequireAnyClientCert,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return s.certificate()
+		},
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			id, serial, err := s.verifyChain(rawCerts)
+			if err != nil {
+				return err
+			}
+			auth, err := s.consul.Agent().ConnectAuthorize(&api.AgentAuthorizeParams{
+				Target:           s.name,
+				ClientCertURI:    id.String(),
+				ClientCertSerial: serial,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to authorize %s: %w", id, err)
+			}
+			if !auth.Authorized {
+				s.logger.Warn("Connect intention denied caller", "caller", id.String(), "reason", auth.Reason, "service", s.name)
+				return fmt.Errorf("%s may not call %s: %s", id, s.name, auth.Reason)
+			}
+			return nil
+		},
+	}
+}
+
+// ClientTLSConfig presents this service's certificate and accepts only a
+// server holding target's Connect identity
+func (s *Service) ClientTLSConfig(target string) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return s.certificate()
+		},
+		// Connect certificates name services, not hosts; the chain and the
+		// SPIFFE ID are checked below instead
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			id, _, err := s.verifyChain(rawCerts)
+			if err != nil {
+				return err
+			}
+			if service := serviceOf(id); service != target {
+				return fmt.Errorf("expected %s but the server is %s", target, id)
+			}
+			return nil
+		},
+	}
+}
+
+// DialTLS returns a DialTLSContext for http.Transport that connects over mTLS
+// to the service target returns for an address
+func (s *Service) DialTLS(target func(addr string) string) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		raw, err := (&net.Dialer{Timeout: 10 * time.Second}).DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		conn := tls.Client(raw, s.ClientTLSConfig(target(addr)))
+		if err := conn.HandshakeContext(ctx); err != nil {
+			raw.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// serviceOf returns the service name of a SPIFFE ID
+// spiffe://<trust domain>/ns/<namespace>/dc/<datacenter>/svc/<service>
+func serviceOf(id *url.URL) string {
+	if i := strings.LastIndex(id.Path, "/svc/"); i >= 0 {
+		return id.Path[i+len("/svc/"):]
+	}
+	return ""
+}
+
+// serialHex formats a certificate serial number like Consul does, as
+// colon-separated hex bytes
+func serialHex(b []byte) string {
+	parts := make([]string, len(b))
+	for i, v := range b {
+		parts[i] = fmt.Sprintf("%02x", v)
+	}
+	return strings.Join(parts, ":")
+}
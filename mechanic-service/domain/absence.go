@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrMechanicAbsent marks assignments to a mechanic during one of their
+// absences; handlers map it to 409
+var ErrMechanicAbsent = errors.New("mechanic is absent")
+
+// OpenStatuses are the statuses of repairs a mechanic still has to work on;
+// assigned open repairs must be reassigned before the mechanic is away
+var OpenStatuses = []string{"pending", "accepted", "in_progress"}
+
+// Absence is a vacation or other leave of a mechanic. Absent mechanics are
+// left out of estimates and cannot be assigned repairs from Start until End.
+// Absences are stored in the shared mechanic_absences collection, which
+// repair-service reads.
+type Absence struct {
+	ID         string    `json:"id" bson:"_id"`
+	MechanicID string    `json:"mechanicID" bson:"mechanicID"`
+	Start      time.Time `json:"start" bson:"start"`
+	End        time.Time `json:"end" bson:"end"`
+	Reason     string    `json:"reason,omitempty" bson:"reason,omitempty"`
+	CreatedAt  time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// Validate checks the absence window and reason
+func (a *Absence) Validate() error {
+	if a.Start.IsZero() || a.End.IsZero() {
+		return fmt.Errorf("%w: start and end are required", ErrInvalidInput)
+	}
+	if !a.End.After(a.Start) {
+		return fmt.Errorf("%w: end must be after start", ErrInvalidInput)
+	}
+	if a.End.Before(time.Now()) {
+		return fmt.Errorf("%w: absence is already over", ErrInvalidInput)
+	}
+	if len(a.Reason) > 200 {
+		return fmt.Errorf("%w: reason must be at most 200 characters", ErrInvalidInput)
+	}
+	return nil
+}
+
+// AbsenceResult is a created absence with the open repairs assigned to the
+// mechanic that need reassignment because the absence starts soon
+type AbsenceResult struct {
+	Absence           *Absence `json:"absence"`
+	RepairsToReassign []string `json:"repairsToReassign,omitempty"`
+	Warning           string   `json:"warning,omitempty"`
+}
@@ -0,0 +1,152 @@
+package domain
+
+import (
+	"fmt"
+	"math"
+)
+
+// ServiceRadiusKm is how far from their location mechanics take repairs: the
+// radius of nearby repair listings and of the service area the directory shows
+const ServiceRadiusKm = 10.0
+
+// MaxSearchRadiusKm bounds the radius of directory searches and nearby repair
+// listings
+const MaxSearchRadiusKm = 100.0
+
+// Orders of the mechanic directory (GET /mechanics/search)
+const (
+	DirectorySortRating   = "rating"   // best rated first, the default
+	DirectorySortDistance = "distance" // nearest first, needs a point to search near
+	DirectorySortName     = "name"
+)
+
+// Page sizes of the mechanic directory
+const (
+	DefaultDirectoryPageSize = 20
+	MaxDirectoryPageSize     = 100
+)
+
+// MechanicSearch selects and orders the mechanics of the directory; zero
+// fields do not filter
+type MechanicSearch struct {
+	Name      string    // words of the name, matched through the mechanics' text index
+	MinRating float64   // unrated mechanics never match a minimum
+	Skills    []string  // mechanics must have every one
+	Near      *Location // keeps mechanics within RadiusKm of the point and sets their distance
+	RadiusKm  float64   // ServiceRadiusKm when 0
+	Sort      string    // a DirectorySort order, DirectorySortRating when empty
+	Page      int       // from 1
+	PageSize  int
+}
+
+// Validate checks the search and fills in the defaults of the radius, order
+// and paging
+func (q *MechanicSearch) Validate() error {
+	if !(q.MinRating >= 0 && q.MinRating <= MaxRating) {
+		return fmt.Errorf("%w: minRating must be between 0 and %g", ErrInvalidInput, MaxRating)
+	}
+	if q.Near != nil && !(math.Abs(q.Near.Latitude) <= 90 && math.Abs(q.Near.Longitude) <= 180) {
+		return fmt.Errorf("%w: latitude must be within ±90 and longitude within ±180", ErrInvalidInput)
+	}
+	if !(q.RadiusKm >= 0 && q.RadiusKm <= MaxSearchRadiusKm) {
+		return fmt.Errorf("%w: radiusKm must be between 0 and %g", ErrInvalidInput, MaxSearchRadiusKm)
+	}
+	if q.RadiusKm == 0 {
+		q.RadiusKm = ServiceRadiusKm
+	}
+	switch q.Sort {
+	case "":
+		q.Sort = DirectorySortRating
+	case DirectorySortRating, DirectorySortName:
+	case DirectorySortDistance:
+		if q.Near == nil {
+			return fmt.Errorf("%w: sorting by distance needs latitude and longitude", ErrInvalidInput)
+		}
+	default:
+		return fmt.Errorf("%w: sort must be %s, %s or %s", ErrInvalidInput, DirectorySortRating, DirectorySortDistance, DirectorySortName)
+	}
+	if q.Page < 0 || q.PageSize < 0 || q.PageSize > MaxDirectoryPageSize {
+		return fmt.Errorf("%w: page must not be negative and pageSize must be at most %d", ErrInvalidInput, MaxDirectoryPageSize)
+	}
+	if q.Page == 0 {
+		q.Page = 1
+	}
+	if q.PageSize == 0 {
+		q.PageSize = DefaultDirectoryPageSize
+	}
+	return nil
+}
+
+// MechanicFilter selects mechanics in the repository; a nil Box does not
+// filter by location
+type MechanicFilter struct {
+	Name      string
+	MinRating float64
+	Skills    []string
+	Box       *BoundingBox
+}
+
+// BoundingBox is a rectangle of coordinates, edges included
+type BoundingBox struct {
+	MinLatitude  float64
+	MaxLatitude  float64
+	MinLongitude float64
+	MaxLongitude float64
+}
+
+// ServiceArea is where a mechanic takes repairs. The center is the mechanic's
+// location rounded to about a kilometre, so the directory does not track
+// mechanics on the road.
+type ServiceArea struct {
+	Center   Location `json:"center"`
+	RadiusKm float64  `json:"radiusKm"`
+}
+
+// MechanicProfile is a mechanic as the directory shows them to users
+type MechanicProfile struct {
+	ID               string      `json:"id"`
+	Name             string      `json:"name"`
+	Status           string      `json:"status"`
+	Skills           []string    `json:"skills"`
+	Region           string      `json:"region,omitempty"`
+	Rating           *float64    `json:"rating,omitempty"` // 0 to MaxRating, missing until rated
+	CompletedRepairs int         `json:"completedRepairs"`
+	ServiceArea      ServiceArea `json:"serviceArea"`
+	DistanceKm       *float64    `json:"distanceKm,omitempty"` // from the point searched near
+}
+
+// NewMechanicProfile returns the directory profile of a mechanic
+func NewMechanicProfile(m *Mechanic, completedRepairs int) *MechanicProfile {
+	status := m.Status
+	if status == "" {
+		status = MechanicOnline
+	}
+	skills := m.Skills
+	if skills == nil {
+		skills = []string{}
+	}
+	return &MechanicProfile{
+		ID:               m.ID,
+		Name:             m.Name,
+		Status:           status,
+		Skills:           skills,
+		Region:           m.Region,
+		Rating:           m.Rating,
+		CompletedRepairs: completedRepairs,
+		ServiceArea: ServiceArea{
+			Center: Location{
+				Latitude:  math.Round(m.Location.Latitude*100) / 100,
+				Longitude: math.Round(m.Location.Longitude*100) / 100,
+			},
+			RadiusKm: ServiceRadiusKm,
+		},
+	}
+}
+
+// MechanicPage is one page of the mechanic directory
+type MechanicPage struct {
+	Mechanics []*MechanicProfile `json:"mechanics"`
+	Page      int                `json:"page"`
+	PageSize  int                `json:"pageSize"`
+	Total     int64              `json:"total"` // matching mechanics on every page
+}
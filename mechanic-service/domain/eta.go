@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+// assignment events published when an assigned mechanic is about to
+// arrive or is late, relative to the ETA committed on assignment
+const (
+	EventRepairETACountdown = "repair_eta_countdown"
+	EventRepairETALate      = "repair_eta_late"
+)
+
+// ETACommitment is the arrival time a mechanic committed to when claiming a
+// repair, stored on the repair as eta. The ETA monitor records on it which
+// countdown and late events it sent.
+type ETACommitment struct {
+	At              time.Time  `json:"at" bson:"at"`
+	CommittedAt     time.Time  `json:"committedAt" bson:"committedAt"`
+	Estimated       bool       `json:"estimated,omitempty" bson:"estimated,omitempty"` // derived from the distance, not given by the mechanic
+	CountdownSentAt *time.Time `json:"countdownSentAt,omitempty" bson:"countdownSentAt,omitempty"`
+	LateNotifiedAt  *time.Time `json:"lateNotifiedAt,omitempty" bson:"lateNotifiedAt,omitempty"`
+	MinutesLate     int        `json:"minutesLate,omitempty" bson:"minutesLate,omitempty"`
+}
+
+// ETAEvent is the payload of a countdown or late event. The latest one is also
+// set on the repair as etaEvent, which the gateway streams to the user.
+type ETAEvent struct {
+	Type         string    `json:"type" bson:"type"`
+	RepairID     string    `json:"repairID" bson:"repairID"`
+	UserID       string    `json:"userID" bson:"userID"`
+	MechanicID   string    `json:"mechanicID" bson:"mechanicID"`
+	CommittedETA time.Time `json:"committedETA" bson:"committedETA"`
+	MinutesLeft  int       `json:"minutesLeft,omitempty" bson:"minutesLeft,omitempty"` // countdown events
+	MinutesLate  int       `json:"minutesLate,omitempty" bson:"minutesLate,omitempty"` // late events
+	At           time.Time `json:"at" bson:"at"`
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventMechanicLocationUpdated is the assignment event published when a
+// mechanic's location update passes the emission throttle
+const EventMechanicLocationUpdated = "mechanic_location_updated"
+
+// LocationUpdate is a position a mechanic's device reports, about once a
+// second while driving
+type LocationUpdate struct {
+	MechanicID string    `json:"mechanicID"`
+	Location   Location  `json:"location"`
+	RecordedAt time.Time `json:"recordedAt"` // device time, now when not given
+}
+
+// Validate checks the reported coordinates
+func (u *LocationUpdate) Validate() error {
+	if u.Location.Latitude < -90 || u.Location.Latitude > 90 || u.Location.Longitude < -180 || u.Location.Longitude > 180 {
+		return fmt.Errorf("%w: latitude must be within [-90, 90] and longitude within [-180, 180]", ErrInvalidInput)
+	}
+	if u.RecordedAt.After(time.Now().Add(time.Minute)) {
+		return fmt.Errorf("%w: recordedAt is in the future", ErrInvalidInput)
+	}
+	return nil
+}
+
+// MechanicPosition is a raw reported position, kept in mechanic_positions for
+// MECHANIC_POSITION_TTL_SECONDS so ETAs and routes start from where the
+// mechanic is rather than from the last emitted location
+type MechanicPosition struct {
+	ID         string    `json:"id" bson:"_id"`
+	MechanicID string    `json:"mechanicID" bson:"mechanicID"`
+	Location   Location  `json:"location" bson:"location"`
+	RecordedAt time.Time `json:"recordedAt" bson:"recordedAt"`
+	ReceivedAt time.Time `json:"receivedAt" bson:"receivedAt"` // the TTL index expires positions by it
+}
+
+// MechanicLocationEvent is the payload of a mechanic_location_updated event
+type MechanicLocationEvent struct {
+	MechanicID  string    `json:"mechanicID"`
+	Location    Location  `json:"location"`
+	MovedMeters float64   `json:"movedMeters"` // since the previous event, 0 for the first
+	RecordedAt  time.Time `json:"recordedAt"`
+}
+
+// LocationResult tells the device whether its update went out as an event
+type LocationResult struct {
+	Emitted bool   `json:"emitted"`
+	Reason  string `json:"reason"` // first, moved, heartbeat or throttled
+}
@@ -1,14 +1,86 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrInvalidInput marks errors caused by bad client input; handlers map it to 400
+var ErrInvalidInput = errors.New("invalid input")
+
+// ErrBlocked marks matches between a user and a mechanic that a block relation
+// forbids; handlers map it to 403
+var ErrBlocked = errors.New("user and mechanic are blocked from matching")
+
+// ErrAssignmentConflict marks assignments lost to another mechanic, or to a
+// repair no longer open for assignment; handlers map it to 409
+var ErrAssignmentConflict = errors.New("repair is already assigned or no longer assignable")
+
+// AssignableStatuses are the repair statuses a mechanic may still claim
+var AssignableStatuses = []string{"pending", "accepted"}
+
+// EventRepairAssigned is the assignment_outbox event written when a mechanic claims a repair
+const EventRepairAssigned = "repair_assigned"
+
+// AllMechanics is the mechanicID of an admin blacklist, which blocks the user
+// from every mechanic
+const AllMechanics = "*"
+
+// Block prevents a user and a mechanic from being matched. Blocks are created
+// by repair-service in the shared blocks collection; mechanic-service only
+// reads them.
+type Block struct {
+	ID         string    `json:"id" bson:"_id"`
+	UserID     string    `json:"userID" bson:"userID"`
+	MechanicID string    `json:"mechanicID" bson:"mechanicID"`
+	BlockedBy  string    `json:"blockedBy" bson:"blockedBy"`
+	CreatedAt  time.Time `json:"createdAt" bson:"createdAt"`
+}
 
 // Repair represents a repair request
 type Repair struct {
-	ID         string       `json:"id" bson:"_id"`
-	UserID     string       `json:"userID" bson:"userID"`
-	Status     string       `json:"status" bson:"status"`
-	RepairCost *RepairCost  `json:"repairCost" bson:"repairCost"`
-	AssignedTo string       `json:"assignedTo" bson:"assignedTo,omitempty"`
+	ID         string          `json:"id" bson:"_id"`
+	UserID     string          `json:"userID" bson:"userID"`
+	Status     string          `json:"status" bson:"status"`
+	RepairCost *RepairCost     `json:"repairCost" bson:"repairCost"`
+	AssignedTo string          `json:"assignedTo" bson:"assignedTo,omitempty"`
+	Symptoms   []SymptomAnswer `json:"symptoms,omitempty" bson:"symptoms,omitempty"`
+	Source     string          `json:"-" bson:"source,omitempty"`
+	Region     string          `json:"region,omitempty" bson:"region,omitempty"`
+	BundleID   string          `json:"bundleID,omitempty" bson:"bundleID,omitempty"` // set by repair-service; the bundle is assigned as a unit
+	ETA        *ETACommitment  `json:"eta,omitempty" bson:"eta,omitempty"`           // committed on assignment
+	// GeoLocation is the user location as GeoJSON, for the 2dsphere index
+	// nearby repairs are found through; the repository derives it on writes
+	GeoLocation *GeoPoint `json:"-" bson:"geoLocation,omitempty"`
+}
+
+// EventRepairErased is the event type of the tombstone repair-service
+// publishes, with a null value, for each repair of a user it erased
+const EventRepairErased = "RepairErased"
+
+// EventRepairCancelled is the event type repair-service publishes when a
+// repair is cancelled, with status cancelled, or deleted, with status
+// RepairStatusDeleted
+const EventRepairCancelled = "RepairCancelled"
+
+// RepairStatusDeleted is the status of RepairCancelled events of deleted
+// repairs
+const RepairStatusDeleted = "deleted"
+
+// ErasedUserID replaces the user ID on repairs whose user was erased, unless
+// repair-service already set its own "erased-" pseudonym
+const ErasedUserID = "erased"
+
+// RepairSourceCDC marks repairs ingested from repair-service's change stream
+// while Kafka was unavailable; the Kafka event replaces them once it arrives
+const RepairSourceCDC = "cdc"
+
+// SymptomAnswer is a structured intake answer so mechanics know what to bring
+type SymptomAnswer struct {
+	QuestionID string `json:"questionID" bson:"questionID"`
+	Question   string `json:"question" bson:"question"`
+	Answer     string `json:"answer" bson:"answer"`
 }
 
 // RepairCost represents the cost details of a repair
@@ -19,6 +91,39 @@ type RepairCost struct {
 	TotalPrice   float64        `json:"totalPrice" bson:"totalPrice"`
 	UserLocation *Location      `json:"userLocation" bson:"userLocation,omitempty"`
 	Mechanics    []MechanicInfo `json:"mechanics" bson:"mechanics,omitempty"`
+	// Preference is the mechanic the user asked for, set by repair-service
+	Preference *MechanicPreference `json:"preference,omitempty" bson:"preference,omitempty"`
+}
+
+// MechanicPreference is a user's preferred mechanic. A pinned repair is
+// listed to and claimable by that mechanic alone until HoldUntil, or for good
+// without one.
+type MechanicPreference struct {
+	MechanicID string     `json:"mechanicID" bson:"mechanicID"`
+	Pinned     bool       `json:"pinned" bson:"pinned"`
+	HoldUntil  *time.Time `json:"holdUntil,omitempty" bson:"holdUntil,omitempty"`
+}
+
+// PinnedTo returns the mechanic the repair is reserved for at now, or ""
+// when any mechanic may take it
+func (r *Repair) PinnedTo(now time.Time) string {
+	if r.RepairCost == nil || r.RepairCost.Preference == nil || !r.RepairCost.Preference.Pinned {
+		return ""
+	}
+	if hold := r.RepairCost.Preference.HoldUntil; hold != nil && !now.Before(*hold) {
+		return ""
+	}
+	return r.RepairCost.Preference.MechanicID
+}
+
+// PriceFromMinor converts a price in minor currency units (cents) to the major
+// unit amount stored on repairs. Producers predating minor units send 0, in
+// which case the major amount is rounded to the nearest cent instead.
+func PriceFromMinor(minor int64, major float64) float64 {
+	if minor == 0 {
+		return math.Round(major*100) / 100
+	}
+	return float64(minor) / 100
 }
 
 // Location represents geographic coordinates
@@ -27,13 +132,39 @@ type Location struct {
 	Longitude float64 `json:"longitude" bson:"longitude"`
 }
 
+// GeoPoint is a GeoJSON point, its coordinates longitude first
+type GeoPoint struct {
+	Type        string    `json:"type" bson:"type"`
+	Coordinates []float64 `json:"coordinates" bson:"coordinates"`
+}
+
+// NewGeoPoint returns the GeoJSON point of loc
+func NewGeoPoint(loc Location) *GeoPoint {
+	return &GeoPoint{Type: "Point", Coordinates: []float64{loc.Longitude, loc.Latitude}}
+}
+
+// Mechanic statuses; mechanics without a status are treated as online
+const (
+	MechanicOnline  = "online"
+	MechanicOffline = "offline"
+)
+
 // Mechanic represents a mechanic
 type Mechanic struct {
 	ID       string   `json:"id" bson:"_id"`
 	Name     string   `json:"name" bson:"name"`
 	Location Location `json:"location" bson:"location"`
+	Status   string   `json:"status,omitempty" bson:"status,omitempty"`
+	Skills   []string `json:"skills,omitempty" bson:"skills,omitempty"`
+	Region   string   `json:"region,omitempty" bson:"region,omitempty"` // derived from the location on every upsert
+	// Inputs of repair-service's mechanic scoring in estimates, nil until known
+	Rating         *float64 `json:"rating,omitempty" bson:"rating,omitempty"`                 // 0 to MaxRating
+	AcceptanceRate *float64 `json:"acceptanceRate,omitempty" bson:"acceptanceRate,omitempty"` // share of offered repairs accepted, 0 to 1
 }
 
+// MaxRating is the best rating a mechanic can have
+const MaxRating = 5.0
+
 // MechanicInfo represents a mechanic with distance from user
 type MechanicInfo struct {
 	ID       string   `json:"id" bson:"id"`
@@ -46,6 +177,7 @@ type MechanicInfo struct {
 type OutboxEvent struct {
 	ID             string     `bson:"_id" json:"id"`
 	EventType      string     `bson:"event_type" json:"event_type"`
+	AggregateID    string     `bson:"aggregate_id,omitempty" json:"aggregate_id,omitempty"`
 	Payload        []byte     `bson:"payload" json:"payload"`
 	CreatedAt      time.Time  `bson:"created_at" json:"created_at"`
 	Processed      bool       `bson:"processed" json:"processed"`
@@ -53,4 +185,50 @@ type OutboxEvent struct {
 	KafkaTopic     string     `bson:"kafka_topic" json:"kafka_topic"`
 	KafkaPartition int32      `bson:"kafka_partition" json:"kafka_partition"`
 	KafkaOffset    int64      `bson:"kafka_offset" json:"kafka_offset"`
+	KafkaTimestamp time.Time  `bson:"kafka_timestamp,omitempty" json:"kafka_timestamp,omitempty"`
+	EventID        string     `bson:"event_id,omitempty" json:"event_id,omitempty"` // producer's event ID, see ProcessedEvent
+}
+
+// ProcessedEvent records a consumed event ID in processed_events so redeliveries
+// of the event are skipped. A TTL index on ProcessedAt (PROCESSED_EVENT_TTL_HOURS)
+// bounds how long duplicates are recognized.
+type ProcessedEvent struct {
+	ID          string    `bson:"_id" json:"id"`
+	ProcessedAt time.Time `bson:"processedAt" json:"processedAt"`
+}
+
+// AssignmentEvent is an outgoing event in the assignment_outbox collection,
+// written in the same transaction as the change it describes. The assignment
+// outbox processor publishes it to the assignment-events topic and marks it
+// processed. Published repair_assigned events are kept as the assignment
+// history repair-service rebuilds past repair states from; the others get an
+// ExpireAt, by which a TTL index removes them.
+type AssignmentEvent struct {
+	ID          string     `bson:"_id" json:"id"`
+	EventType   string     `bson:"event_type" json:"event_type"`
+	AggregateID string     `bson:"aggregate_id" json:"aggregate_id"`
+	Payload     []byte     `bson:"payload" json:"payload"` // JSON, e.g. a RepairAssignment
+	CreatedAt   time.Time  `bson:"created_at" json:"created_at"`
+	Processed   bool       `bson:"processed" json:"processed"`
+	ProcessedAt *time.Time `bson:"processed_at,omitempty" json:"processed_at,omitempty"`
+	ExpireAt    *time.Time `bson:"expire_at,omitempty" json:"expire_at,omitempty"`
+}
+
+// RepairAssignment is the payload of a repair_assigned event
+type RepairAssignment struct {
+	RepairID     string    `json:"repairID"`
+	UserID       string    `json:"userID"`
+	MechanicID   string    `json:"mechanicID"`
+	AssignedAt   time.Time `json:"assignedAt"`
+	CommittedETA time.Time `json:"committedETA"`
+}
+
+// AssignmentCounter counts a mechanic's assignments on one UTC day. repair-service
+// sums the counters of recent days to rotate offers among nearby mechanics.
+type AssignmentCounter struct {
+	ID             string    `bson:"_id"` // <mechanicID>:<YYYY-MM-DD>
+	MechanicID     string    `bson:"mechanicID"`
+	Day            time.Time `bson:"day"`
+	Count          int       `bson:"count"`
+	LastAssignedAt time.Time `bson:"lastAssignedAt"`
 }
@@ -0,0 +1,31 @@
+package domain
+
+import "fmt"
+
+// Result limits of nearby repair listings (GET /repairs/nearby)
+const (
+	DefaultNearbyResults = 100
+	MaxNearbyResults     = 500
+)
+
+// NearbySearch sets the range and size of a nearby repair listing; zero
+// fields take the defaults
+type NearbySearch struct {
+	RadiusKm   float64 // the service's NEARBY_RADIUS_KM when 0
+	MaxResults int     // DefaultNearbyResults when 0; the nearest are kept
+}
+
+// Validate checks the bounds of the search and fills in the default result
+// limit; the default radius is the service's
+func (q *NearbySearch) Validate() error {
+	if !(q.RadiusKm >= 0 && q.RadiusKm <= MaxSearchRadiusKm) {
+		return fmt.Errorf("%w: radiusKm must be between 0 and %g", ErrInvalidInput, MaxSearchRadiusKm)
+	}
+	if q.MaxResults < 0 || q.MaxResults > MaxNearbyResults {
+		return fmt.Errorf("%w: maxResults must be between 1 and %d", ErrInvalidInput, MaxNearbyResults)
+	}
+	if q.MaxResults == 0 {
+		q.MaxResults = DefaultNearbyResults
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// Digest frequencies of non-urgent mechanic notifications
+const (
+	DigestOff    = "off"    // delivered right away, batched only during quiet hours
+	DigestHourly = "hourly" // batched and delivered at most once an hour
+	DigestDaily  = "daily"  // batched and delivered at most once a day
+)
+
+// NotificationPrefs are a mechanic's notification preferences. Quiet hours
+// are "HH:MM" wall-clock times in Timezone and may wrap past midnight; leaving
+// both empty disables them. Urgent notifications ignore both settings.
+type NotificationPrefs struct {
+	MechanicID      string    `json:"mechanicID" bson:"_id"`
+	QuietHoursStart string    `json:"quietHoursStart,omitempty" bson:"quietHoursStart,omitempty"`
+	QuietHoursEnd   string    `json:"quietHoursEnd,omitempty" bson:"quietHoursEnd,omitempty"`
+	Timezone        string    `json:"timezone" bson:"timezone"`
+	DigestFrequency string    `json:"digestFrequency" bson:"digestFrequency"`
+	UpdatedAt       time.Time `json:"updatedAt,omitempty" bson:"updatedAt,omitempty"`
+}
+
+// DefaultNotificationPrefs are the preferences of mechanics who never set any
+func DefaultNotificationPrefs(mechanicID string) *NotificationPrefs {
+	return &NotificationPrefs{MechanicID: mechanicID, Timezone: "UTC", DigestFrequency: DigestOff}
+}
+
+// Validate fills in defaults and checks the quiet hours, timezone and digest frequency
+func (p *NotificationPrefs) Validate() error {
+	if p.Timezone == "" {
+		p.Timezone = "UTC"
+	}
+	if _, err := time.LoadLocation(p.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInvalidInput, p.Timezone)
+	}
+	if p.DigestFrequency == "" {
+		p.DigestFrequency = DigestOff
+	}
+	switch p.DigestFrequency {
+	case DigestOff, DigestHourly, DigestDaily:
+	default:
+		return fmt.Errorf("%w: digestFrequency must be %s, %s or %s", ErrInvalidInput, DigestOff, DigestHourly, DigestDaily)
+	}
+	if (p.QuietHoursStart == "") != (p.QuietHoursEnd == "") {
+		return fmt.Errorf("%w: quietHoursStart and quietHoursEnd must be set together", ErrInvalidInput)
+	}
+	for _, clock := range []string{p.QuietHoursStart, p.QuietHoursEnd} {
+		if clock == "" {
+			continue
+		}
+		if _, err := time.Parse("15:04", clock); err != nil {
+			return fmt.Errorf("%w: quiet hours must be HH:MM, got %q", ErrInvalidInput, clock)
+		}
+	}
+	return nil
+}
@@ -2,11 +2,15 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"mechanic-service/region"
+
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -15,15 +19,53 @@ import (
 // MechanicRepository defines the data access methods for mechanics
 type MechanicRepository interface {
 	GetMechanicByID(ctx context.Context, id string) (*Mechanic, error)
-	GetAllRepairs(ctx context.Context) ([]*Repair, error)
-	AssignRepair(ctx context.Context, repairID, mechanicID string) (*Repair, error)
-	SaveOutboxEvent(ctx context.Context, session mongo.SessionContext, event *OutboxEvent) error
+	UpsertMechanics(ctx context.Context, mechanics []*Mechanic) (map[int]error, error)
+	GetAllRepairs(ctx context.Context, opts *QueryOptions) ([]*Repair, error)
+	NearbyRepairs(ctx context.Context, near Location, radiusKm float64, opts *QueryOptions) ([]*Repair, error)
+	EnsureRepairGeoIndex(ctx context.Context) (int64, error)
+	GetRepairByID(ctx context.Context, id string) (*Repair, error)
+	BlockedUserIDs(ctx context.Context, mechanicID string) (map[string]bool, error)
+	AssignRepair(ctx context.Context, repairID, mechanicID string, eta *ETACommitment) (*Repair, error)
+	SaveAssignmentEvent(ctx context.Context, event *AssignmentEvent) error
+	GetUnprocessedAssignmentEvents(ctx context.Context, limit int) ([]*AssignmentEvent, error)
+	MarkAssignmentEventProcessed(ctx context.Context, eventID string, expireAt *time.Time) error
+	IncrementAssignmentCounter(ctx context.Context, mechanicID string, at time.Time) error
+	SaveOutboxEvent(ctx context.Context, event *OutboxEvent) error
 	GetUnprocessedOutboxEvents(ctx context.Context) ([]*OutboxEvent, error)
+	CountUnprocessedOutboxEvents(ctx context.Context) (int64, error)
 	MarkOutboxEventProcessed(ctx context.Context, eventID string) error
-	InsertRepair(ctx context.Context, session mongo.SessionContext, repair *Repair) error
-	GetMongoClient(ctx context.Context) *mongo.Client
-	CheckRepairExists(ctx context.Context, session mongo.SessionContext, repairID string) (bool, error)
-	CheckOutboxEventExists(ctx context.Context, session mongo.SessionContext, topic string, partition int32, offset int64) (bool, error)
+	InsertRepair(ctx context.Context, repair *Repair) error
+	ReplaceCDCRepair(ctx context.Context, repair *Repair) (bool, error)
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	CheckRepairExists(ctx context.Context, repairID string) (bool, error)
+	CheckOutboxEventExists(ctx context.Context, topic string, partition int32, offset int64) (bool, error)
+	IsEventProcessed(ctx context.Context, eventID string) (bool, error)
+	MarkEventProcessed(ctx context.Context, eventID string) error
+	AnonymizeRepair(ctx context.Context, repairID string) error
+	CancelRepair(ctx context.Context, repairID string) error
+	DeleteRepair(ctx context.Context, repairID string) error
+	GetNotificationPrefs(ctx context.Context, mechanicID string) (*NotificationPrefs, error)
+	SaveNotificationPrefs(ctx context.Context, prefs *NotificationPrefs) error
+	CreateAbsence(ctx context.Context, absence *Absence) error
+	ListAbsences(ctx context.Context, mechanicID string, endingAfter time.Time) ([]*Absence, error)
+	DeleteAbsence(ctx context.Context, mechanicID, absenceID string) error
+	IsAbsent(ctx context.Context, mechanicID string, at time.Time) (bool, error)
+	OpenAssignedRepairIDs(ctx context.Context, mechanicID string) ([]string, error)
+	BundledRepairs(ctx context.Context, bundleID string) ([]*Repair, error)
+	DueETARepairs(ctx context.Context, now time.Time, countdownLead, lateRepeat time.Duration, limit int) ([]*Repair, error)
+	RecordETAEvent(ctx context.Context, repair *Repair, event *ETAEvent) (bool, error)
+	OpenAssignedRepairs(ctx context.Context, mechanicID string) ([]*Repair, error)
+	GetMechanicRoute(ctx context.Context, mechanicID string) (*MechanicRoute, error)
+	SaveMechanicRoute(ctx context.Context, route *MechanicRoute) error
+	RoutedMechanicIDs(ctx context.Context, repairID string) ([]string, error)
+	WatchAssignedRepairs(ctx context.Context) (*mongo.ChangeStream, error)
+	LearnedRepairMinutes(ctx context.Context, mechanicID string, repairTypes []string) (map[string]float64, error)
+	SavePosition(ctx context.Context, position *MechanicPosition) error
+	LatestPosition(ctx context.Context, mechanicID string) (*MechanicPosition, error)
+	UpdateMechanicLocation(ctx context.Context, mechanicID string, location Location) error
+	FindMechanics(ctx context.Context, filter MechanicFilter, opts *QueryOptions) ([]*Mechanic, error)
+	CountMechanics(ctx context.Context, filter MechanicFilter) (int64, error)
+	CompletedRepairCounts(ctx context.Context, mechanicIDs []string) (map[string]int, error)
 }
 
 // MongoRepository implements the MechanicRepository interface
@@ -31,7 +73,18 @@ type MongoRepository struct {
 	MechanicCollection *mongo.Collection
 	RepairCollection   *mongo.Collection
 	OutboxCollection   *mongo.Collection
+	BlockCollection    *mongo.Collection
+	AssignmentOutbox   *mongo.Collection
+	NotificationPrefs  *mongo.Collection
+	AbsenceCollection  *mongo.Collection
+	ProcessedEvents    *mongo.Collection
+	AssignmentCounters *mongo.Collection
+	RouteCollection    *mongo.Collection
+	DurationEstimates  *mongo.Collection
+	PositionCollection *mongo.Collection
 	client             *mongo.Client
+
+	retryAttempts int // of reads and transactions failing transiently, see NewRepository
 }
 
 // NewMongoRepository creates a new MongoRepository
@@ -40,6 +93,15 @@ func NewMongoRepository(client *mongo.Client) *MongoRepository {
 		MechanicCollection: client.Database("repairdb").Collection("mechanics"),
 		RepairCollection:   client.Database("repairdb").Collection("repairs"),
 		OutboxCollection:   client.Database("repairdb").Collection("mechanic_outbox"),
+		BlockCollection:    client.Database("repairdb").Collection("blocks"),
+		AssignmentOutbox:   client.Database("repairdb").Collection("assignment_outbox"),
+		NotificationPrefs:  client.Database("repairdb").Collection("mechanic_notification_prefs"),
+		AbsenceCollection:  client.Database("repairdb").Collection("mechanic_absences"),
+		ProcessedEvents:    client.Database("repairdb").Collection("processed_events"),
+		AssignmentCounters: client.Database("repairdb").Collection("assignment_counters"),
+		RouteCollection:    client.Database("repairdb").Collection("mechanic_routes"),
+		DurationEstimates:  client.Database("repairdb").Collection("repair_duration_estimates"),
+		PositionCollection: client.Database("repairdb").Collection("mechanic_positions"),
 		client:             client,
 	}
 }
@@ -49,17 +111,55 @@ func (r *MongoRepository) GetMongoClient(ctx context.Context) *mongo.Client {
 	return r.client
 }
 
+// RunInTransaction runs fn in a MongoDB transaction, committing when it
+// succeeds and aborting when it fails. The context fn gets carries the
+// session; the repository writes fn makes with it join the transaction.
+// Transactions the server aborted transiently, e.g. on a write conflict or a
+// primary election, run again from the start, so fn must not keep state
+// between runs; a commit whose outcome is unknown is retried on its own.
+func (r *MongoRepository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := r.client.StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start MongoDB session: %w", classify(err))
+	}
+	defer session.EndSession(ctx)
+
+	transientTransaction := func(err error) bool { return hasErrorLabel(err, "TransientTransactionError") }
+	unknownCommit := func(err error) bool { return hasErrorLabel(err, "UnknownTransactionCommitResult") }
+	return retry(ctx, r.retryAttempts, transientTransaction, func() error {
+		if err := session.StartTransaction(); err != nil {
+			return fmt.Errorf("failed to start transaction: %w", classify(err))
+		}
+		err := mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
+			return fn(sc)
+		})
+		if err != nil {
+			session.AbortTransaction(ctx)
+			return err
+		}
+		err = retry(ctx, r.retryAttempts, unknownCommit, func() error {
+			return session.CommitTransaction(ctx)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", classify(err))
+		}
+		return nil
+	})
+}
+
 // GetMechanicByID retrieves a mechanic by ID
 func (r *MongoRepository) GetMechanicByID(ctx context.Context, id string) (*Mechanic, error) {
 	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoGetMechanicByID")
 	defer span.End()
 
 	var mechanic Mechanic
-	err := r.MechanicCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&mechanic)
+	err := retry(ctx, r.retryAttempts, isTransient, func() error {
+		return r.MechanicCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&mechanic)
+	})
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to find mechanic")
-		return nil, fmt.Errorf("failed to find mechanic: %v", err)
+		return nil, fmt.Errorf("failed to find mechanic: %w", classify(err))
 	}
 	span.SetAttributes(
 		attribute.String("mechanicID", id),
@@ -68,17 +168,51 @@ func (r *MongoRepository) GetMechanicByID(ctx context.Context, id string) (*Mech
 	return &mechanic, nil
 }
 
+// UpsertMechanics replaces or inserts the given mechanics in a single unordered
+// bulk write, deriving each mechanic's region from its location. Per-document failures are returned keyed by their index in
+// mechanics; the error is set only when the whole write failed.
+func (r *MongoRepository) UpsertMechanics(ctx context.Context, mechanics []*Mechanic) (map[int]error, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoUpsertMechanics")
+	defer span.End()
+
+	models := make([]mongo.WriteModel, len(mechanics))
+	for i, m := range mechanics {
+		m.Region = region.Default().Of(m.Location.Longitude, m.Location.Latitude)
+		models[i] = mongo.NewReplaceOneModel().
+			SetFilter(bson.M{"_id": m.ID}).
+			SetReplacement(m).
+			SetUpsert(true)
+	}
+	span.SetAttributes(attribute.Int("mechanicCount", len(mechanics)))
+
+	failed := make(map[int]error)
+	_, err := r.MechanicCollection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	if err != nil {
+		var bulkErr mongo.BulkWriteException
+		if !errors.As(err, &bulkErr) || bulkErr.WriteConcernError != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to upsert mechanics")
+			return nil, fmt.Errorf("failed to upsert mechanics: %w", classify(err))
+		}
+		for _, we := range bulkErr.WriteErrors {
+			failed[we.Index] = fmt.Errorf("failed to upsert mechanic: %s", we.Message)
+		}
+		span.SetAttributes(attribute.Int("failedCount", len(failed)))
+	}
+	return failed, nil
+}
+
 // GetAllRepairs retrieves all repairs
-func (r *MongoRepository) GetAllRepairs(ctx context.Context) ([]*Repair, error) {
+func (r *MongoRepository) GetAllRepairs(ctx context.Context, opts *QueryOptions) ([]*Repair, error) {
 	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoGetAllRepairs")
 	defer span.End()
 
 	var repairs []*Repair
-	cursor, err := r.RepairCollection.Find(ctx, bson.M{})
+	cursor, err := opts.find(ctx, r.retryAttempts, r.RepairCollection, bson.M{}, nil)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to find repairs")
-		return nil, fmt.Errorf("failed to find repairs: %v", err)
+		return nil, fmt.Errorf("failed to find repairs: %w", classify(err))
 	}
 	defer cursor.Close(ctx)
 
@@ -89,7 +223,7 @@ func (r *MongoRepository) GetAllRepairs(ctx context.Context) ([]*Repair, error)
 			chSpan.RecordError(err)
 			chSpan.SetStatus(codes.Error, "Failed to decode repair")
 			chSpan.End()
-			return nil, fmt.Errorf("failed to decode repair: %v", err)
+			return nil, fmt.Errorf("failed to decode repair: %w", classify(err))
 		}
 		chSpan.End()
 		repairs = append(repairs, &repair)
@@ -97,7 +231,7 @@ func (r *MongoRepository) GetAllRepairs(ctx context.Context) ([]*Repair, error)
 	if err := cursor.Err(); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Cursor error")
-		return nil, fmt.Errorf("cursor error: %v", err)
+		return nil, fmt.Errorf("cursor error: %w", classify(err))
 	}
 
 	span.SetAttributes(
@@ -106,43 +240,199 @@ func (r *MongoRepository) GetAllRepairs(ctx context.Context) ([]*Repair, error)
 	return repairs, nil
 }
 
-// AssignRepair assigns a mechanic to a repair
-func (r *MongoRepository) AssignRepair(ctx context.Context, repairID, mechanicID string) (*Repair, error) {
-	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoAssignRepair")
+// GetRepairByID retrieves a repair by ID
+func (r *MongoRepository) GetRepairByID(ctx context.Context, id string) (*Repair, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoGetRepairByID")
 	defer span.End()
 
 	var repair Repair
-	if err := r.RepairCollection.FindOne(ctx, bson.M{"_id": repairID}).Decode(&repair); err != nil {
+	err := retry(ctx, r.retryAttempts, isTransient, func() error {
+		return r.RepairCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&repair)
+	})
+	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to find repair")
-		return nil, fmt.Errorf("failed to find repair: %v", err)
+		return nil, fmt.Errorf("failed to find repair: %w", classify(err))
 	}
+	span.SetAttributes(attribute.String("repairID", id))
+	return &repair, nil
+}
+
+// BlockedUserIDs returns the users who blocked mechanicID or were blacklisted by an admin
+func (r *MongoRepository) BlockedUserIDs(ctx context.Context, mechanicID string) (map[string]bool, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoBlockedUserIDs")
+	defer span.End()
 
-	update := bson.M{"$set": bson.M{"assignedTo": mechanicID}}
-	if _, err := r.RepairCollection.UpdateOne(ctx, bson.M{"_id": repairID}, update); err != nil {
+	filter := bson.M{"mechanicID": bson.M{"$in": []string{mechanicID, AllMechanics}}}
+	cursor, err := r.BlockCollection.Find(ctx, filter, options.Find().SetProjection(bson.M{"userID": 1}))
+	if err != nil {
 		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to assign repair")
-		return nil, fmt.Errorf("failed to assign repair: %v", err)
+		span.SetStatus(codes.Error, "Failed to find blocks")
+		return nil, fmt.Errorf("failed to find blocks: %w", classify(err))
 	}
+	defer cursor.Close(ctx)
+
+	var blocks []Block
+	if err := cursor.All(ctx, &blocks); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode blocks")
+		return nil, fmt.Errorf("failed to decode blocks: %w", classify(err))
+	}
+	blocked := make(map[string]bool, len(blocks))
+	for _, block := range blocks {
+		blocked[block.UserID] = true
+	}
+	span.SetAttributes(
+		attribute.String("mechanicID", mechanicID),
+		attribute.Int("blockedUserCount", len(blocked)),
+	)
+	return blocked, nil
+}
 
-	repair.AssignedTo = mechanicID
+// AssignRepair claims a repair for mechanicID only if it is unassigned and in
+// an assignable status, so concurrent claims cannot both succeed. It returns
+// ErrAssignmentConflict when the repair exists but cannot be claimed.
+func (r *MongoRepository) AssignRepair(ctx context.Context, repairID, mechanicID string, eta *ETACommitment) (*Repair, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoAssignRepair")
+	defer span.End()
 	span.SetAttributes(
 		attribute.String("repairID", repairID),
 		attribute.String("mechanicID", mechanicID),
 	)
+
+	filter := bson.M{
+		"_id":        repairID,
+		"assignedTo": bson.M{"$in": bson.A{nil, ""}},
+		"status":     bson.M{"$in": AssignableStatuses},
+	}
+	set := bson.M{"assignedTo": mechanicID}
+	if eta != nil {
+		set["eta"] = eta
+	}
+	update := bson.M{"$set": set}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var repair Repair
+	err := r.RepairCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&repair)
+	if err == mongo.ErrNoDocuments {
+		// Tell a missing repair apart from one someone else already claimed
+		if err := r.RepairCollection.FindOne(ctx, bson.M{"_id": repairID}).Err(); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to find repair")
+			return nil, fmt.Errorf("failed to find repair: %w", classify(err))
+		}
+		span.SetStatus(codes.Error, "Assignment conflict")
+		return nil, ErrAssignmentConflict
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to assign repair")
+		return nil, fmt.Errorf("failed to assign repair: %w", classify(err))
+	}
 	return &repair, nil
 }
 
+// SaveAssignmentEvent saves an event to the assignment outbox
+func (r *MongoRepository) SaveAssignmentEvent(ctx context.Context, event *AssignmentEvent) error {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoSaveAssignmentEvent")
+	defer span.End()
+
+	if _, err := r.AssignmentOutbox.InsertOne(ctx, event); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save assignment event")
+		return classify(err)
+	}
+	span.SetAttributes(
+		attribute.String("eventID", event.ID),
+		attribute.String("repairID", event.AggregateID),
+	)
+	return nil
+}
+
+// GetUnprocessedAssignmentEvents returns up to limit unpublished assignment
+// events, oldest first
+func (r *MongoRepository) GetUnprocessedAssignmentEvents(ctx context.Context, limit int) ([]*AssignmentEvent, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoGetUnprocessedAssignmentEvents")
+	defer span.End()
+
+	var events []*AssignmentEvent
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(int64(limit))
+	err := retry(ctx, r.retryAttempts, isTransient, func() error {
+		events = nil
+		cursor, err := r.AssignmentOutbox.Find(ctx, bson.M{"processed": false}, opts)
+		if err != nil {
+			return classify(err)
+		}
+		defer cursor.Close(ctx)
+		if err := cursor.All(ctx, &events); err != nil {
+			return classify(err)
+		}
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find unprocessed assignment events")
+		return nil, fmt.Errorf("failed to find unprocessed assignment events: %w", err)
+	}
+	span.SetAttributes(attribute.Int("eventCount", len(events)))
+	return events, nil
+}
+
+// MarkAssignmentEventProcessed marks an assignment event as published; a
+// non-nil expireAt lets the TTL index remove it then
+func (r *MongoRepository) MarkAssignmentEventProcessed(ctx context.Context, eventID string, expireAt *time.Time) error {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoMarkAssignmentEventProcessed")
+	defer span.End()
+	span.SetAttributes(attribute.String("eventID", eventID))
+
+	set := bson.M{"processed": true, "processed_at": time.Now()}
+	if expireAt != nil {
+		set["expire_at"] = *expireAt
+	}
+	if _, err := r.AssignmentOutbox.UpdateOne(ctx, bson.M{"_id": eventID}, bson.M{"$set": set}); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to mark assignment event as processed")
+		return classify(err)
+	}
+	return nil
+}
+
+// IncrementAssignmentCounter counts an assignment on the mechanic's counter for
+// the UTC day of at
+func (r *MongoRepository) IncrementAssignmentCounter(ctx context.Context, mechanicID string, at time.Time) error {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoIncrementAssignmentCounter")
+	defer span.End()
+	span.SetAttributes(attribute.String("mechanicID", mechanicID))
+
+	at = at.UTC()
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+	_, err := r.AssignmentCounters.UpdateOne(ctx,
+		bson.M{"_id": mechanicID + ":" + day.Format("2006-01-02")},
+		bson.M{
+			"$inc":         bson.M{"count": 1},
+			"$max":         bson.M{"lastAssignedAt": at},
+			"$setOnInsert": bson.M{"mechanicID": mechanicID, "day": day},
+		},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to increment assignment counter")
+		return fmt.Errorf("failed to increment assignment counter: %w", classify(err))
+	}
+	return nil
+}
+
 // SaveOutboxEvent saves an event to the outbox collection
-func (r *MongoRepository) SaveOutboxEvent(ctx context.Context, session mongo.SessionContext, event *OutboxEvent) error {
+func (r *MongoRepository) SaveOutboxEvent(ctx context.Context, event *OutboxEvent) error {
 	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoSaveOutboxEvent")
 	defer span.End()
 
-	_, err := r.OutboxCollection.InsertOne(session, event)
+	_, err := r.OutboxCollection.InsertOne(ctx, event)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to save outbox event")
-		return err
+		return classify(err)
 	}
 	span.SetAttributes(
 		attribute.String("eventID", event.ID),
@@ -151,17 +441,33 @@ func (r *MongoRepository) SaveOutboxEvent(ctx context.Context, session mongo.Ses
 	return nil
 }
 
+// CountUnprocessedOutboxEvents counts the outbox events not yet processed
+func (r *MongoRepository) CountUnprocessedOutboxEvents(ctx context.Context) (int64, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoCountUnprocessedOutboxEvents")
+	defer span.End()
+
+	count, err := r.OutboxCollection.CountDocuments(ctx, bson.M{"processed": false})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to count unprocessed outbox events")
+		return 0, fmt.Errorf("failed to count unprocessed outbox events: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int64("unprocessed", count))
+	return count, nil
+}
+
 // GetUnprocessedOutboxEvents retrieves unprocessed outbox events
 func (r *MongoRepository) GetUnprocessedOutboxEvents(ctx context.Context) ([]*OutboxEvent, error) {
 	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoGetUnprocessedOutboxEvents")
 	defer span.End()
 
 	var events []*OutboxEvent
-	cursor, err := r.OutboxCollection.Find(ctx, bson.M{"processed": false})
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.OutboxCollection.Find(ctx, bson.M{"processed": false}, opts)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to find unprocessed outbox events")
-		return nil, fmt.Errorf("failed to find unprocessed outbox events: %v", err)
+		return nil, fmt.Errorf("failed to find unprocessed outbox events: %w", classify(err))
 	}
 	defer cursor.Close(ctx)
 
@@ -170,14 +476,14 @@ func (r *MongoRepository) GetUnprocessedOutboxEvents(ctx context.Context) ([]*Ou
 		if err := cursor.Decode(&event); err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, "Failed to decode outbox event")
-			return nil, fmt.Errorf("failed to decode outbox event: %v", err)
+			return nil, fmt.Errorf("failed to decode outbox event: %w", classify(err))
 		}
 		events = append(events, &event)
 	}
 	if err := cursor.Err(); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Cursor error")
-		return nil, fmt.Errorf("cursor error: %v", err)
+		return nil, fmt.Errorf("cursor error: %w", classify(err))
 	}
 
 	span.SetAttributes(
@@ -201,7 +507,7 @@ func (r *MongoRepository) MarkOutboxEventProcessed(ctx context.Context, eventID
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to mark outbox event as processed")
-		return err
+		return classify(err)
 	}
 	span.SetAttributes(
 		attribute.String("eventID", eventID),
@@ -210,15 +516,16 @@ func (r *MongoRepository) MarkOutboxEventProcessed(ctx context.Context, eventID
 }
 
 // InsertRepair inserts a repair into the repairs collection
-func (r *MongoRepository) InsertRepair(ctx context.Context, session mongo.SessionContext, repair *Repair) error {
+func (r *MongoRepository) InsertRepair(ctx context.Context, repair *Repair) error {
 	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoInsertRepair")
 	defer span.End()
 
-	_, err := r.RepairCollection.InsertOne(session, repair)
+	repair.GeoLocation = repairGeoLocation(repair)
+	_, err := r.RepairCollection.InsertOne(ctx, repair)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to insert repair")
-		return err
+		return classify(err)
 	}
 	span.SetAttributes(
 		attribute.String("repairID", repair.ID),
@@ -228,20 +535,323 @@ func (r *MongoRepository) InsertRepair(ctx context.Context, session mongo.Sessio
 	return nil
 }
 
+// ReplaceCDCRepair replaces a repair previously ingested from the change stream
+// with the given one and reports whether such a repair existed
+func (r *MongoRepository) ReplaceCDCRepair(ctx context.Context, repair *Repair) (bool, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoReplaceCDCRepair")
+	defer span.End()
+
+	repair.GeoLocation = repairGeoLocation(repair)
+	result, err := r.RepairCollection.ReplaceOne(ctx, bson.M{"_id": repair.ID, "source": RepairSourceCDC}, repair)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to replace catch-up repair")
+		return false, classify(err)
+	}
+	span.SetAttributes(
+		attribute.String("repairID", repair.ID),
+		attribute.Bool("replaced", result.MatchedCount > 0),
+	)
+	return result.MatchedCount > 0, nil
+}
+
+// repairGeoLocation is the GeoJSON point of a repair's user location, nil
+// without one
+func repairGeoLocation(repair *Repair) *GeoPoint {
+	if repair.RepairCost == nil || repair.RepairCost.UserLocation == nil {
+		return nil
+	}
+	return NewGeoPoint(*repair.RepairCost.UserLocation)
+}
+
+// NearbyRepairs finds the repairs whose user location lies within radiusKm
+// of near, nearest first, through the 2dsphere index on geoLocation
+func (r *MongoRepository) NearbyRepairs(ctx context.Context, near Location, radiusKm float64, opts *QueryOptions) ([]*Repair, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoNearbyRepairs")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Float64("latitude", near.Latitude),
+		attribute.Float64("longitude", near.Longitude),
+		attribute.Float64("radiusKm", radiusKm),
+	)
+
+	filter := bson.M{"geoLocation": bson.M{"$nearSphere": bson.M{
+		"$geometry":    NewGeoPoint(near),
+		"$maxDistance": radiusKm * 1000,
+	}}}
+	// $nearSphere orders by distance; no other sort is applied
+	cursor, err := opts.find(ctx, r.retryAttempts, r.RepairCollection, filter, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find nearby repairs")
+		return nil, fmt.Errorf("failed to find nearby repairs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	repairs := []*Repair{}
+	if err := cursor.All(ctx, &repairs); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode nearby repairs")
+		return nil, fmt.Errorf("failed to decode nearby repairs: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("repairCount", len(repairs)))
+	return repairs, nil
+}
+
+// EnsureRepairGeoIndex creates the 2dsphere index on the repairs' geoLocation
+// and derives it for the repairs stored without one, e.g. before the index
+// existed; it returns how many repairs it backfilled
+func (r *MongoRepository) EnsureRepairGeoIndex(ctx context.Context) (int64, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoEnsureRepairGeoIndex")
+	defer span.End()
+
+	result, err := r.RepairCollection.UpdateMany(ctx, bson.M{
+		"geoLocation":                       bson.M{"$exists": false},
+		"repairCost.userLocation.longitude": bson.M{"$type": "number"},
+		"repairCost.userLocation.latitude":  bson.M{"$type": "number"},
+	}, mongo.Pipeline{{{Key: "$set", Value: bson.M{"geoLocation": bson.M{
+		"type":        "Point",
+		"coordinates": bson.A{"$repairCost.userLocation.longitude", "$repairCost.userLocation.latitude"},
+	}}}}})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to backfill repair geo locations")
+		return 0, fmt.Errorf("failed to backfill repair geo locations: %w", classify(err))
+	}
+	if _, err := r.RepairCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "geoLocation", Value: "2dsphere"}},
+	}); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to create repair geo index")
+		return result.ModifiedCount, fmt.Errorf("failed to create repair geo index: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int64("backfilled", result.ModifiedCount))
+	return result.ModifiedCount, nil
+}
+
+// AnonymizeRepair strips the personal data of an erased user from a repair:
+// the location and intake answers are removed and the user ID is replaced
+func (r *MongoRepository) AnonymizeRepair(ctx context.Context, repairID string) error {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoAnonymizeRepair")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", repairID))
+
+	_, err := r.RepairCollection.UpdateOne(ctx, bson.M{"_id": repairID}, bson.M{
+		"$set":   bson.M{"repairCost.userLocation": nil},
+		"$unset": bson.M{"symptoms": "", "geoLocation": ""},
+	})
+	if err == nil {
+		// Keep a pseudonym repair-service already assigned
+		_, err = r.RepairCollection.UpdateOne(ctx,
+			bson.M{"_id": repairID, "userID": bson.M{"$not": bson.M{"$regex": "^" + ErasedUserID}}},
+			bson.M{"$set": bson.M{"userID": ErasedUserID, "repairCost.userID": ErasedUserID}},
+		)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to anonymize repair")
+		return fmt.Errorf("failed to anonymize repair: %w", classify(err))
+	}
+	return nil
+}
+
+// CancelRepair marks a repair cancelled, so it is no longer listed nearby or
+// assignable; a missing repair is left missing
+func (r *MongoRepository) CancelRepair(ctx context.Context, repairID string) error {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoCancelRepair")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", repairID))
+
+	if _, err := r.RepairCollection.UpdateOne(ctx, bson.M{"_id": repairID}, bson.M{"$set": bson.M{"status": "cancelled"}}); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to cancel repair")
+		return fmt.Errorf("failed to cancel repair: %w", classify(err))
+	}
+	return nil
+}
+
+// DeleteRepair removes a repair; deleting a missing repair succeeds
+func (r *MongoRepository) DeleteRepair(ctx context.Context, repairID string) error {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoDeleteRepair")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", repairID))
+
+	if _, err := r.RepairCollection.DeleteOne(ctx, bson.M{"_id": repairID}); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to delete repair")
+		return fmt.Errorf("failed to delete repair: %w", classify(err))
+	}
+	return nil
+}
+
+// GetNotificationPrefs retrieves a mechanic's notification preferences;
+// mongo.ErrNoDocuments means the mechanic never set any
+func (r *MongoRepository) GetNotificationPrefs(ctx context.Context, mechanicID string) (*NotificationPrefs, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoGetNotificationPrefs")
+	defer span.End()
+	span.SetAttributes(attribute.String("mechanicID", mechanicID))
+
+	var prefs NotificationPrefs
+	if err := r.NotificationPrefs.FindOne(ctx, bson.M{"_id": mechanicID}).Decode(&prefs); err != nil {
+		if err != mongo.ErrNoDocuments {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to find notification preferences")
+		}
+		return nil, fmt.Errorf("failed to find notification preferences: %w", classify(err))
+	}
+	return &prefs, nil
+}
+
+// SaveNotificationPrefs replaces a mechanic's notification preferences
+func (r *MongoRepository) SaveNotificationPrefs(ctx context.Context, prefs *NotificationPrefs) error {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoSaveNotificationPrefs")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("mechanicID", prefs.MechanicID),
+		attribute.String("digestFrequency", prefs.DigestFrequency),
+	)
+
+	_, err := r.NotificationPrefs.ReplaceOne(ctx, bson.M{"_id": prefs.MechanicID}, prefs, options.Replace().SetUpsert(true))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save notification preferences")
+		return fmt.Errorf("failed to save notification preferences: %w", classify(err))
+	}
+	return nil
+}
+
+// CreateAbsence stores a new absence
+func (r *MongoRepository) CreateAbsence(ctx context.Context, absence *Absence) error {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoCreateAbsence")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("mechanicID", absence.MechanicID),
+		attribute.String("absenceID", absence.ID),
+	)
+
+	if _, err := r.AbsenceCollection.InsertOne(ctx, absence); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to create absence")
+		return fmt.Errorf("failed to create absence: %w", classify(err))
+	}
+	return nil
+}
+
+// ListAbsences returns a mechanic's absences ending after the given time, earliest first
+func (r *MongoRepository) ListAbsences(ctx context.Context, mechanicID string, endingAfter time.Time) ([]*Absence, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoListAbsences")
+	defer span.End()
+
+	filter := bson.M{"mechanicID": mechanicID, "end": bson.M{"$gt": endingAfter}}
+	cursor, err := r.AbsenceCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "start", Value: 1}}))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find absences")
+		return nil, fmt.Errorf("failed to find absences: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	absences := []*Absence{}
+	if err := cursor.All(ctx, &absences); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode absences")
+		return nil, fmt.Errorf("failed to decode absences: %w", classify(err))
+	}
+	span.SetAttributes(
+		attribute.String("mechanicID", mechanicID),
+		attribute.Int("absenceCount", len(absences)),
+	)
+	return absences, nil
+}
+
+// DeleteAbsence removes one of a mechanic's absences; mongo.ErrNoDocuments
+// means it does not exist
+func (r *MongoRepository) DeleteAbsence(ctx context.Context, mechanicID, absenceID string) error {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoDeleteAbsence")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("mechanicID", mechanicID),
+		attribute.String("absenceID", absenceID),
+	)
+
+	result, err := r.AbsenceCollection.DeleteOne(ctx, bson.M{"_id": absenceID, "mechanicID": mechanicID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to delete absence")
+		return fmt.Errorf("failed to delete absence: %w", classify(err))
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("failed to delete absence: %w", mongo.ErrNoDocuments)
+	}
+	return nil
+}
+
+// IsAbsent reports whether the mechanic has an absence covering the given time
+func (r *MongoRepository) IsAbsent(ctx context.Context, mechanicID string, at time.Time) (bool, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoIsAbsent")
+	defer span.End()
+
+	filter := bson.M{"mechanicID": mechanicID, "start": bson.M{"$lte": at}, "end": bson.M{"$gt": at}}
+	count, err := r.AbsenceCollection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to check absences")
+		return false, fmt.Errorf("failed to check absences: %w", classify(err))
+	}
+	span.SetAttributes(
+		attribute.String("mechanicID", mechanicID),
+		attribute.Bool("absent", count > 0),
+	)
+	return count > 0, nil
+}
+
+// OpenAssignedRepairIDs returns the open repairs assigned to the mechanic
+func (r *MongoRepository) OpenAssignedRepairIDs(ctx context.Context, mechanicID string) ([]string, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoOpenAssignedRepairIDs")
+	defer span.End()
+
+	filter := bson.M{"assignedTo": mechanicID, "status": bson.M{"$in": OpenStatuses}}
+	cursor, err := r.RepairCollection.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find assigned repairs")
+		return nil, fmt.Errorf("failed to find assigned repairs: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		ID string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode assigned repairs")
+		return nil, fmt.Errorf("failed to decode assigned repairs: %w", classify(err))
+	}
+	ids := make([]string, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
+	span.SetAttributes(
+		attribute.String("mechanicID", mechanicID),
+		attribute.Int("repairCount", len(ids)),
+	)
+	return ids, nil
+}
+
 // CheckRepairExists checks if a repair exists by ID
-func (r *MongoRepository) CheckRepairExists(ctx context.Context, session mongo.SessionContext, repairID string) (bool, error) {
+func (r *MongoRepository) CheckRepairExists(ctx context.Context, repairID string) (bool, error) {
 	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoCheckRepairExists")
 	defer span.End()
 
 	var repair Repair
-	err := r.RepairCollection.FindOne(session, bson.M{"_id": repairID}).Decode(&repair)
+	err := r.RepairCollection.FindOne(ctx, bson.M{"_id": repairID}).Decode(&repair)
 	if err == mongo.ErrNoDocuments {
 		return false, nil
 	}
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to check repair existence")
-		return false, fmt.Errorf("failed to check repair existence: %v", err)
+		return false, fmt.Errorf("failed to check repair existence: %w", classify(err))
 	}
 	span.SetAttributes(
 		attribute.String("repairID", repairID),
@@ -250,13 +860,51 @@ func (r *MongoRepository) CheckRepairExists(ctx context.Context, session mongo.S
 	return true, nil
 }
 
+// IsEventProcessed reports whether a consumed event ID is in processed_events.
+// Pass the context of RunInTransaction to read inside a transaction.
+func (r *MongoRepository) IsEventProcessed(ctx context.Context, eventID string) (bool, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoIsEventProcessed")
+	defer span.End()
+	span.SetAttributes(attribute.String("eventID", eventID))
+
+	count, err := r.ProcessedEvents.CountDocuments(ctx, bson.M{"_id": eventID}, options.Count().SetLimit(1))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to check processed event")
+		return false, fmt.Errorf("failed to check processed event: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Bool("processed", count > 0))
+	return count > 0, nil
+}
+
+// MarkEventProcessed records a consumed event ID in processed_events; marking
+// an event twice keeps the first time. Pass the context of RunInTransaction
+// to write inside a transaction.
+func (r *MongoRepository) MarkEventProcessed(ctx context.Context, eventID string) error {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoMarkEventProcessed")
+	defer span.End()
+	span.SetAttributes(attribute.String("eventID", eventID))
+
+	_, err := r.ProcessedEvents.UpdateOne(ctx,
+		bson.M{"_id": eventID},
+		bson.M{"$setOnInsert": bson.M{"processedAt": time.Now().UTC()}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to mark event processed")
+		return fmt.Errorf("failed to mark event processed: %w", classify(err))
+	}
+	return nil
+}
+
 // CheckOutboxEventExists checks if an outbox event exists by Kafka metadata
-func (r *MongoRepository) CheckOutboxEventExists(ctx context.Context, session mongo.SessionContext, topic string, partition int32, offset int64) (bool, error) {
+func (r *MongoRepository) CheckOutboxEventExists(ctx context.Context, topic string, partition int32, offset int64) (bool, error) {
 	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoCheckOutboxEventExists")
 	defer span.End()
 
 	var event OutboxEvent
-	err := r.OutboxCollection.FindOne(session, bson.M{
+	err := r.OutboxCollection.FindOne(ctx, bson.M{
 		"kafka_topic":     topic,
 		"kafka_partition": partition,
 		"kafka_offset":    offset,
@@ -267,7 +915,7 @@ func (r *MongoRepository) CheckOutboxEventExists(ctx context.Context, session mo
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to check outbox event existence")
-		return false, fmt.Errorf("failed to check outbox event existence: %v", err)
+		return false, fmt.Errorf("failed to check outbox event existence: %w", classify(err))
 	}
 	span.SetAttributes(
 		attribute.String("kafka_topic", topic),
@@ -277,3 +925,407 @@ func (r *MongoRepository) CheckOutboxEventExists(ctx context.Context, session mo
 	)
 	return true, nil
 }
+
+// BundledRepairs returns the ID, user, status and assignee of the repairs in
+// a bundle
+func (r *MongoRepository) BundledRepairs(ctx context.Context, bundleID string) ([]*Repair, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoBundledRepairs")
+	defer span.End()
+	span.SetAttributes(attribute.String("bundleID", bundleID))
+
+	projection := bson.M{"_id": 1, "userID": 1, "status": 1, "assignedTo": 1, "bundleID": 1}
+	cursor, err := r.RepairCollection.Find(ctx, bson.M{"bundleID": bundleID}, options.Find().SetProjection(projection))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find bundled repairs")
+		return nil, fmt.Errorf("failed to find bundled repairs: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	var repairs []*Repair
+	if err := cursor.All(ctx, &repairs); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode bundled repairs")
+		return nil, fmt.Errorf("failed to decode bundled repairs: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("repairCount", len(repairs)))
+	return repairs, nil
+}
+
+// DueETARepairs returns assigned, not yet started repairs whose committed ETA
+// is within countdownLead without a countdown sent, or has passed without a
+// late notice in the last lateRepeat
+func (r *MongoRepository) DueETARepairs(ctx context.Context, now time.Time, countdownLead, lateRepeat time.Duration, limit int) ([]*Repair, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoDueETARepairs")
+	defer span.End()
+
+	filter := bson.M{
+		"assignedTo": bson.M{"$nin": bson.A{nil, ""}},
+		"status":     bson.M{"$in": AssignableStatuses},
+		"$or": bson.A{
+			bson.M{
+				"eta.at":              bson.M{"$gt": now, "$lte": now.Add(countdownLead)},
+				"eta.countdownSentAt": bson.M{"$exists": false},
+			},
+			bson.M{
+				"eta.at": bson.M{"$lte": now},
+				"$or": bson.A{
+					bson.M{"eta.lateNotifiedAt": bson.M{"$exists": false}},
+					bson.M{"eta.lateNotifiedAt": bson.M{"$lte": now.Add(-lateRepeat)}},
+				},
+			},
+		},
+	}
+	projection := bson.M{"_id": 1, "userID": 1, "status": 1, "assignedTo": 1, "eta": 1}
+	opts := options.Find().SetProjection(projection).SetSort(bson.M{"eta.at": 1}).SetLimit(int64(limit))
+	cursor, err := r.RepairCollection.Find(ctx, filter, opts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find repairs with due ETA events")
+		return nil, fmt.Errorf("failed to find repairs with due ETA events: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	var repairs []*Repair
+	if err := cursor.All(ctx, &repairs); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode repairs with due ETA events")
+		return nil, fmt.Errorf("failed to decode repairs with due ETA events: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("repairCount", len(repairs)))
+	return repairs, nil
+}
+
+// RecordETAEvent marks a countdown or late event as sent on the repair and
+// sets it as the repair's etaEvent. It returns false when the repair changed
+// since it was read: it started, was reassigned, or another instance sent the
+// event first.
+func (r *MongoRepository) RecordETAEvent(ctx context.Context, repair *Repair, event *ETAEvent) (bool, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoRecordETAEvent")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("repairID", repair.ID),
+		attribute.String("eventType", event.Type),
+	)
+
+	filter := bson.M{
+		"_id":        repair.ID,
+		"assignedTo": event.MechanicID,
+		"status":     bson.M{"$in": AssignableStatuses},
+		"eta.at":     event.CommittedETA,
+	}
+	set := bson.M{"etaEvent": event}
+	if event.Type == EventRepairETACountdown {
+		filter["eta.countdownSentAt"] = bson.M{"$exists": false}
+		set["eta.countdownSentAt"] = event.At
+	} else {
+		if repair.ETA.LateNotifiedAt == nil {
+			filter["eta.lateNotifiedAt"] = bson.M{"$exists": false}
+		} else {
+			filter["eta.lateNotifiedAt"] = *repair.ETA.LateNotifiedAt
+		}
+		set["eta.lateNotifiedAt"] = event.At
+		set["eta.minutesLate"] = event.MinutesLate
+	}
+	result, err := r.RepairCollection.UpdateOne(ctx, filter, bson.M{"$set": set})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to record ETA event")
+		return false, fmt.Errorf("failed to record ETA event: %w", classify(err))
+	}
+	return result.MatchedCount == 1, nil
+}
+
+// OpenAssignedRepairs returns the open repairs assigned to a mechanic
+func (r *MongoRepository) OpenAssignedRepairs(ctx context.Context, mechanicID string) ([]*Repair, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoOpenAssignedRepairs")
+	defer span.End()
+	span.SetAttributes(attribute.String("mechanicID", mechanicID))
+
+	filter := bson.M{"assignedTo": mechanicID, "status": bson.M{"$in": OpenStatuses}}
+	cursor, err := r.RepairCollection.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find assigned repairs")
+		return nil, fmt.Errorf("failed to find assigned repairs: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	repairs := []*Repair{}
+	if err := cursor.All(ctx, &repairs); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode assigned repairs")
+		return nil, fmt.Errorf("failed to decode assigned repairs: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("repairCount", len(repairs)))
+	return repairs, nil
+}
+
+// GetMechanicRoute retrieves a mechanic's stored route
+func (r *MongoRepository) GetMechanicRoute(ctx context.Context, mechanicID string) (*MechanicRoute, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoGetMechanicRoute")
+	defer span.End()
+	span.SetAttributes(attribute.String("mechanicID", mechanicID))
+
+	var route MechanicRoute
+	if err := r.RouteCollection.FindOne(ctx, bson.M{"_id": mechanicID}).Decode(&route); err != nil {
+		if err != mongo.ErrNoDocuments {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to find mechanic route")
+		}
+		return nil, fmt.Errorf("failed to find mechanic route: %w", classify(err))
+	}
+	return &route, nil
+}
+
+// SaveMechanicRoute replaces a mechanic's stored route
+func (r *MongoRepository) SaveMechanicRoute(ctx context.Context, route *MechanicRoute) error {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoSaveMechanicRoute")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("mechanicID", route.MechanicID),
+		attribute.Int("stopCount", len(route.Stops)),
+	)
+
+	if _, err := r.RouteCollection.ReplaceOne(ctx, bson.M{"_id": route.MechanicID}, route, options.Replace().SetUpsert(true)); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save mechanic route")
+		return fmt.Errorf("failed to save mechanic route: %w", classify(err))
+	}
+	return nil
+}
+
+// LearnedRepairMinutes returns the minutes repair-service learned for each
+// of repairTypes, the mechanic's own where learned; types without a learned
+// duration are missing
+func (r *MongoRepository) LearnedRepairMinutes(ctx context.Context, mechanicID string, repairTypes []string) (map[string]float64, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoLearnedRepairMinutes")
+	defer span.End()
+	span.SetAttributes(attribute.String("mechanicID", mechanicID), attribute.StringSlice("repairTypes", repairTypes))
+
+	cursor, err := r.DurationEstimates.Find(ctx, bson.M{
+		"repairType": bson.M{"$in": repairTypes},
+		"learned":    true,
+		"$or":        bson.A{bson.M{"mechanicID": bson.M{"$exists": false}}, bson.M{"mechanicID": mechanicID}},
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find duration estimates")
+		return nil, fmt.Errorf("failed to find duration estimates: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	var estimates []DurationEstimate
+	if err := cursor.All(ctx, &estimates); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode duration estimates")
+		return nil, fmt.Errorf("failed to decode duration estimates: %w", classify(err))
+	}
+	minutes := make(map[string]float64, len(estimates))
+	own := map[string]bool{}
+	for _, e := range estimates {
+		if own[e.RepairType] {
+			continue
+		}
+		minutes[e.RepairType] = e.AverageMinutes
+		own[e.RepairType] = e.MechanicID != ""
+	}
+	return minutes, nil
+}
+
+// RoutedMechanicIDs returns the mechanics whose stored route holds a repair
+func (r *MongoRepository) RoutedMechanicIDs(ctx context.Context, repairID string) ([]string, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoRoutedMechanicIDs")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", repairID))
+
+	ids, err := r.RouteCollection.Distinct(ctx, "_id", bson.M{"repairIDs": repairID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find routes of repair")
+		return nil, fmt.Errorf("failed to find routes of repair: %w", classify(err))
+	}
+	mechanicIDs := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if s, ok := id.(string); ok {
+			mechanicIDs = append(mechanicIDs, s)
+		}
+	}
+	return mechanicIDs, nil
+}
+
+// WatchAssignedRepairs opens a change stream for repairs inserted, replaced,
+// deleted, or updated in their status or assignee. Events carry the
+// documentKey and the repair's current assignedTo, none once deleted.
+func (r *MongoRepository) WatchAssignedRepairs(ctx context.Context) (*mongo.ChangeStream, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoWatchAssignedRepairs")
+	defer span.End()
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{"$or": bson.A{
+			bson.M{"operationType": bson.M{"$in": bson.A{"insert", "replace", "delete"}}},
+			bson.M{"updateDescription.updatedFields.status": bson.M{"$exists": true}},
+			bson.M{"updateDescription.updatedFields.assignedTo": bson.M{"$exists": true}},
+			bson.M{"updateDescription.removedFields": "assignedTo"},
+		}}}},
+		bson.D{{Key: "$project", Value: bson.M{"_id": 1, "documentKey": 1, "fullDocument.assignedTo": 1}}},
+	}
+	changeStream, err := r.RepairCollection.Watch(ctx, pipeline, options.ChangeStream().SetFullDocument(options.UpdateLookup))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to open repair change stream")
+		return nil, fmt.Errorf("failed to open repair change stream: %w", classify(err))
+	}
+	return changeStream, nil
+}
+
+// SavePosition stores a raw reported position
+func (r *MongoRepository) SavePosition(ctx context.Context, position *MechanicPosition) error {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoSavePosition")
+	defer span.End()
+	span.SetAttributes(attribute.String("mechanicID", position.MechanicID))
+
+	if _, err := r.PositionCollection.InsertOne(ctx, position); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save position")
+		return fmt.Errorf("failed to save position: %w", classify(err))
+	}
+	return nil
+}
+
+// LatestPosition returns the mechanic's most recently recorded raw position
+// that has not expired, or mongo.ErrNoDocuments when there is none
+func (r *MongoRepository) LatestPosition(ctx context.Context, mechanicID string) (*MechanicPosition, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoLatestPosition")
+	defer span.End()
+	span.SetAttributes(attribute.String("mechanicID", mechanicID))
+
+	var position MechanicPosition
+	opts := options.FindOne().SetSort(bson.D{{Key: "recordedAt", Value: -1}})
+	if err := r.PositionCollection.FindOne(ctx, bson.M{"mechanicID": mechanicID}, opts).Decode(&position); err != nil {
+		if err != mongo.ErrNoDocuments {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to find latest position")
+		}
+		return nil, fmt.Errorf("failed to find latest position: %w", classify(err))
+	}
+	return &position, nil
+}
+
+// UpdateMechanicLocation sets the mechanic's location and the region derived
+// from it
+func (r *MongoRepository) UpdateMechanicLocation(ctx context.Context, mechanicID string, location Location) error {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoUpdateMechanicLocation")
+	defer span.End()
+	span.SetAttributes(attribute.String("mechanicID", mechanicID))
+
+	result, err := r.MechanicCollection.UpdateOne(ctx, bson.M{"_id": mechanicID}, bson.M{"$set": bson.M{
+		"location": location,
+		"region":   region.Default().Of(location.Longitude, location.Latitude),
+	}})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to update mechanic location")
+		return fmt.Errorf("failed to update mechanic location: %w", classify(err))
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("failed to update mechanic location: %w", mongo.ErrNoDocuments)
+	}
+	return nil
+}
+
+// mechanicQuery returns the query document of a mechanic filter
+func mechanicQuery(filter MechanicFilter) bson.M {
+	query := bson.M{}
+	if filter.Name != "" {
+		query["$text"] = bson.M{"$search": filter.Name}
+	}
+	if filter.MinRating > 0 {
+		query["rating"] = bson.M{"$gte": filter.MinRating}
+	}
+	if len(filter.Skills) > 0 {
+		query["skills"] = bson.M{"$all": filter.Skills}
+	}
+	if box := filter.Box; box != nil {
+		query["location.latitude"] = bson.M{"$gte": box.MinLatitude, "$lte": box.MaxLatitude}
+		query["location.longitude"] = bson.M{"$gte": box.MinLongitude, "$lte": box.MaxLongitude}
+	}
+	return query
+}
+
+// FindMechanics returns the mechanics matching filter, best rated first
+// unless opts sorts them otherwise
+func (r *MongoRepository) FindMechanics(ctx context.Context, filter MechanicFilter, opts *QueryOptions) ([]*Mechanic, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoFindMechanics")
+	defer span.End()
+
+	cursor, err := opts.find(ctx, r.retryAttempts, r.MechanicCollection, mechanicQuery(filter), bson.D{{Key: "rating", Value: -1}, {Key: "_id", Value: 1}})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find mechanics")
+		return nil, fmt.Errorf("failed to find mechanics: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	mechanics := []*Mechanic{}
+	if err := cursor.All(ctx, &mechanics); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode mechanics")
+		return nil, fmt.Errorf("failed to decode mechanics: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("mechanicCount", len(mechanics)))
+	return mechanics, nil
+}
+
+// CountMechanics counts the mechanics matching filter
+func (r *MongoRepository) CountMechanics(ctx context.Context, filter MechanicFilter) (int64, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoCountMechanics")
+	defer span.End()
+
+	var count int64
+	err := retry(ctx, r.retryAttempts, isTransient, func() (err error) {
+		count, err = r.MechanicCollection.CountDocuments(ctx, mechanicQuery(filter))
+		return err
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to count mechanics")
+		return 0, fmt.Errorf("failed to count mechanics: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int64("mechanicCount", count))
+	return count, nil
+}
+
+// CompletedRepairCounts returns how many repairs each of mechanicIDs
+// completed; mechanics without one are missing
+func (r *MongoRepository) CompletedRepairCounts(ctx context.Context, mechanicIDs []string) (map[string]int, error) {
+	_, span := otel.Tracer("mechanic-service").Start(ctx, "MongoCompletedRepairCounts")
+	defer span.End()
+	span.SetAttributes(attribute.Int("mechanicCount", len(mechanicIDs)))
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"assignedTo": bson.M{"$in": mechanicIDs}, "status": "completed"}}},
+		{{Key: "$group", Value: bson.M{"_id": "$assignedTo", "count": bson.M{"$sum": 1}}}},
+	}
+	var results []struct {
+		MechanicID string `bson:"_id"`
+		Count      int    `bson:"count"`
+	}
+	err := retry(ctx, r.retryAttempts, isTransient, func() error {
+		cursor, err := r.RepairCollection.Aggregate(ctx, pipeline)
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, &results)
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to count completed repairs")
+		return nil, fmt.Errorf("failed to count completed repairs: %w", classify(err))
+	}
+	counts := make(map[string]int, len(results))
+	for _, result := range results {
+		counts[result.MechanicID] = result.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// Route optimizers, recorded on a MechanicRoute
+const (
+	OptimizerOSRMTrip        = "osrm-trip"        // OSRM trip service over road travel times
+	OptimizerNearestNeighbor = "nearest-neighbor" // next closest stop by straight line, OSRM unavailable
+)
+
+// MechanicRoute orders a mechanic's open repairs into one trip from the
+// mechanic's location. It is stored in mechanic_routes and recomputed when
+// the open repairs change.
+type MechanicRoute struct {
+	MechanicID    string      `json:"mechanicID" bson:"_id"`
+	Origin        Location    `json:"origin" bson:"origin"`                         // mechanic location the route starts at
+	RepairIDs     []string    `json:"-" bson:"repairIDs"`                           // sorted, to tell when the open repairs changed
+	Stops         []RouteStop `json:"stops" bson:"stops"`                           // in visiting order
+	Unrouted      []string    `json:"unrouted,omitempty" bson:"unrouted,omitempty"` // open repairs without a location
+	TotalDuration float64     `json:"totalDurationSeconds" bson:"totalDuration"`    // driving and service time to the last stop
+	Optimizer     string      `json:"optimizer" bson:"optimizer"`
+	ComputedAt    time.Time   `json:"computedAt" bson:"computedAt"`
+}
+
+// RouteStop is one repair of a route
+type RouteStop struct {
+	Sequence    int       `json:"sequence" bson:"sequence"` // from 1
+	RepairID    string    `json:"repairID" bson:"repairID"`
+	RepairType  string    `json:"repairType,omitempty" bson:"repairType,omitempty"`
+	Status      string    `json:"status" bson:"status"`
+	Location    Location  `json:"location" bson:"location"`
+	LegDuration float64   `json:"legDurationSeconds" bson:"legDuration"` // driving from the previous stop
+	ETA         time.Time `json:"eta" bson:"eta"`                        // arrival, after the service time of earlier stops
+	// ServiceDuration is the time the repair takes: its type's learned
+	// duration, the mechanic's own when learned, or the configured service time
+	ServiceDuration float64 `json:"serviceDurationSeconds" bson:"serviceDuration"`
+}
+
+// DurationEstimate is the part of repair-service's learned repair durations,
+// in repair_duration_estimates, routes read
+type DurationEstimate struct {
+	RepairType     string  `bson:"repairType"`
+	MechanicID     string  `bson:"mechanicID,omitempty"` // empty for the type's estimate over every mechanic
+	AverageMinutes float64 `bson:"averageMinutes"`
+	Learned        bool    `bson:"learned"`
+}
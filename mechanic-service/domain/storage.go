@@ -0,0 +1,183 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Storage backends, chosen with STORAGE_BACKEND
+const (
+	StorageMongo    = "mongo"    // the default
+	StoragePostgres = "postgres" // planned: outbox events through logical decoding
+	StorageSQLite   = "sqlite"   // planned: single-process local development
+)
+
+// ErrStorageNotImplemented is returned for storage backends that are named
+// but not built yet
+var ErrStorageNotImplemented = errors.New("storage backend not implemented")
+
+// StorageConfig selects the storage backend behind the repository
+type StorageConfig struct {
+	Backend       string        // one of the Storage* backends; empty means StorageMongo
+	MongoClient   *mongo.Client // a connected client, for StorageMongo
+	RetryAttempts int           // attempts of reads and transactions failing transiently; 0 means 3, 1 disables retries
+}
+
+// NewRepository returns the repository of the configured storage backend
+func NewRepository(cfg StorageConfig) (MechanicRepository, error) {
+	switch cfg.Backend {
+	case "", StorageMongo:
+		if cfg.MongoClient == nil {
+			return nil, errors.New("the mongo storage backend needs a connected client")
+		}
+		repo := NewMongoRepository(cfg.MongoClient)
+		repo.retryAttempts = cfg.RetryAttempts
+		if repo.retryAttempts <= 0 {
+			repo.retryAttempts = 3
+		}
+		return repo, nil
+	case StoragePostgres, StorageSQLite:
+		return nil, fmt.Errorf("%w: %s", ErrStorageNotImplemented, cfg.Backend)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Backend)
+	}
+}
+
+// MongoBacked is implemented by repositories stored in MongoDB. Features
+// built on MongoDB itself, such as the Mongo event bus and the
+// projection snapshots, need it.
+type MongoBacked interface {
+	GetMongoClient(ctx context.Context) *mongo.Client
+}
+
+// Storage error classes. Errors the repository gets from the database are
+// returned wrapped in one of them, so callers can tell a missing document
+// from a conflicting write or an outage with errors.Is. The driver's error
+// stays in the chain: errors.Is(err, mongo.ErrNoDocuments) still holds.
+var (
+	ErrNotFound  = errors.New("not found")                       // no document matched; handlers map it to 404
+	ErrConflict  = errors.New("conflicting write")               // e.g. a duplicate key; handlers map it to 409
+	ErrTransient = errors.New("storage temporarily unavailable") // worth retrying; handlers map it to 503
+	ErrFatal     = errors.New("storage failure")                 // anything else; handlers map it to 500
+)
+
+// storageError carries the class of a database error next to the error
+type storageError struct {
+	class error
+	err   error
+}
+
+func (e *storageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *storageError) Unwrap() []error {
+	return []error{e.class, e.err}
+}
+
+// classify wraps a database error in its class. Errors classified already
+// and cancellations by the caller are returned as they are.
+func classify(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+	for _, class := range []error{ErrNotFound, ErrConflict, ErrTransient, ErrFatal} {
+		if errors.Is(err, class) {
+			return err
+		}
+	}
+	return &storageError{class: classOf(err), err: err}
+}
+
+// classOf returns the class of a database error
+func classOf(err error) error {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		return ErrNotFound
+	case mongo.IsTimeout(err), mongo.IsNetworkError(err), hasErrorLabel(err, "TransientTransactionError"),
+		hasErrorLabel(err, "RetryableWriteError"), hasTransientCode(err):
+		return ErrTransient
+	case mongo.IsDuplicateKeyError(err), hasConflictCode(err):
+		return ErrConflict
+	}
+	return ErrFatal
+}
+
+// isTransient reports whether err is a database error worth retrying
+func isTransient(err error) bool {
+	return errors.Is(err, ErrTransient) || (err != nil && !errors.Is(err, context.Canceled) && classOf(err) == ErrTransient)
+}
+
+func hasErrorLabel(err error, label string) bool {
+	var labeled mongo.LabeledError
+	return errors.As(err, &labeled) && labeled.HasErrorLabel(label)
+}
+
+// transientCodes are the server errors of a replica set electing a new
+// primary or a node shutting down, which the driver retries once at most
+var transientCodes = []int{
+	6,     // HostUnreachable
+	7,     // HostNotFound
+	89,    // NetworkTimeout
+	91,    // ShutdownInProgress
+	189,   // PrimarySteppedDown
+	262,   // ExceededTimeLimit
+	9001,  // SocketException
+	10107, // NotWritablePrimary
+	11600, // InterruptedAtShutdown
+	11602, // InterruptedDueToReplStateChange
+	13435, // NotPrimaryNoSecondaryOk
+	13436, // NotPrimaryOrSecondary
+}
+
+func hasTransientCode(err error) bool {
+	var server mongo.ServerError
+	if !errors.As(err, &server) {
+		return false
+	}
+	for _, code := range transientCodes {
+		if server.HasErrorCode(code) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasConflictCode(err error) bool {
+	var server mongo.ServerError
+	return errors.As(err, &server) && server.HasErrorCode(112) // WriteConflict
+}
+
+// retryBackoff is the longest wait before the first retry; it doubles for
+// every further one
+const retryBackoff = 50 * time.Millisecond
+
+// retry runs op until it succeeds, fails with an error retryable does not
+// accept, or has run attempts times, waiting a jittered, doubling backoff
+// between attempts. Inside a transaction op runs once: the server aborted the
+// transaction, so RunInTransaction retries all of it instead.
+func retry(ctx context.Context, attempts int, retryable func(error) bool, op func() error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		attempts = 1
+	}
+	backoff := retryBackoff
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt >= attempts || !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+		timer := time.NewTimer(rand.N(backoff) + 1)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		backoff *= 2
+	}
+}
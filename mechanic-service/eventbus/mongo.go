@@ -0,0 +1,258 @@
+// Package eventbus reads and writes the Mongo event bus repair-service
+// publishes to when EVENT_BUS=mongo, so single-node installs run without Kafka
+// and schema-registry. Records are kept in the event_bus collection with a
+// per-topic offset; a consumer group has handled a record once its name is in
+// the record's committedBy list.
+package eventbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"mechanic-service/kafka/consume"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Collections of the event bus
+const (
+	RecordCollection  = "event_bus"
+	OffsetsCollection = "event_bus_offsets" // last offset per topic
+)
+
+// Header mirrors repair-service's eventbus.Header
+type Header struct {
+	Key   string `bson:"key"`
+	Value string `bson:"value"`
+}
+
+// Record mirrors repair-service's eventbus.Record
+type Record struct {
+	ID          string    `bson:"_id"` // <topic>/<offset>
+	Topic       string    `bson:"topic"`
+	Offset      int64     `bson:"offset"`
+	Key         []byte    `bson:"key,omitempty"`
+	Value       []byte    `bson:"value"`
+	Headers     []Header  `bson:"headers"`
+	Timestamp   time.Time `bson:"timestamp"`
+	CommittedBy []string  `bson:"committedBy"`
+}
+
+// message converts the record to the Kafka message handlers expect, on
+// partition 0 at the record's offset
+func (r *Record) message() *kafka.Message {
+	topic := r.Topic
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: 0, Offset: kafka.Offset(r.Offset)},
+		Key:            r.Key,
+		Value:          r.Value,
+		Timestamp:      r.Timestamp,
+	}
+	if len(msg.Value) == 0 {
+		msg.Value = nil // tombstone
+	}
+	for _, h := range r.Headers {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: h.Key, Value: []byte(h.Value)})
+	}
+	return msg
+}
+
+func recordID(topic string, offset int64) string {
+	return fmt.Sprintf("%s/%d", topic, offset)
+}
+
+// Source reads a topic of the event bus as a consumer group. Uncommitted
+// records are read in offset order, so a failed record is read again until it
+// is committed.
+type Source struct {
+	records *mongo.Collection
+	group   string
+	topic   string
+}
+
+// NewSource creates a Source for groupID in db
+func NewSource(db *mongo.Database, groupID string) *Source {
+	return &Source{records: db.Collection(RecordCollection), group: groupID}
+}
+
+// Subscribe selects the topic to read
+func (s *Source) Subscribe(topic string) error {
+	s.topic = topic
+	return nil
+}
+
+func (s *Source) pending() bson.M {
+	return bson.M{"topic": s.topic, "committedBy": bson.M{"$ne": s.group}}
+}
+
+// Read returns the oldest record the group has not committed, waiting for
+// timeout before returning nil when there is none
+func (s *Source) Read(timeout time.Duration) (*kafka.Message, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout+5*time.Second)
+	defer cancel()
+	var record Record
+	err := s.records.FindOne(ctx, s.pending(), options.FindOne().SetSort(bson.D{{Key: "offset", Value: 1}})).Decode(&record)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		time.Sleep(timeout)
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event bus record: %w", err)
+	}
+	return record.message(), nil
+}
+
+// Commit adds the group to the record's committedBy list
+func (s *Source) Commit(record *kafka.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	id := recordID(*record.TopicPartition.Topic, int64(record.TopicPartition.Offset))
+	if _, err := s.records.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$addToSet": bson.M{"committedBy": s.group}}); err != nil {
+		return fmt.Errorf("failed to commit event bus record %s: %w", id, err)
+	}
+	return nil
+}
+
+// Rewind is a no-op: an uncommitted record is read again anyway
+func (s *Source) Rewind(record *kafka.Message) error {
+	return nil
+}
+
+// Ping checks that MongoDB is reachable
+func (s *Source) Ping(topic string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := s.records.Database().Client().Ping(ctx, nil); err != nil {
+		return fmt.Errorf("failed to ping MongoDB: %w", err)
+	}
+	return nil
+}
+
+// Lag counts the records of topic the group has not committed, reported as
+// partition 0
+func (s *Source) Lag(topic string, timeout time.Duration) (*consume.ConsumerLag, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	lag := &consume.ConsumerLag{Topic: topic, Partitions: []consume.PartitionLag{}}
+	var last Record
+	err := s.records.FindOne(ctx, bson.M{"topic": topic}, options.FindOne().SetSort(bson.D{{Key: "offset", Value: -1}})).Decode(&last)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return lag, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find last event bus record: %w", err)
+	}
+	pending, err := s.records.CountDocuments(ctx, bson.M{"topic": topic, "committedBy": bson.M{"$ne": s.group}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count pending event bus records: %w", err)
+	}
+	high := last.Offset + 1
+	lag.TotalLag = pending
+	lag.Partitions = append(lag.Partitions, consume.PartitionLag{Partition: 0, Committed: high - pending, HighWatermark: high, Lag: pending})
+	return lag, nil
+}
+
+// Close is a no-op; the MongoDB client is closed by main
+func (s *Source) Close() error {
+	return nil
+}
+
+// Tail returns the last limit records of topic, oldest first, without
+// committing them for any group
+func Tail(ctx context.Context, db *mongo.Database, topic string, limit int) ([]*kafka.Message, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "offset", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := db.Collection(RecordCollection).Find(ctx, bson.M{"topic": topic}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event bus records: %w", err)
+	}
+	var records []Record
+	if err := cursor.All(ctx, &records); err != nil {
+		return nil, fmt.Errorf("failed to decode event bus records: %w", err)
+	}
+	messages := make([]*kafka.Message, len(records))
+	for i := range records {
+		messages[len(records)-1-i] = records[i].message()
+	}
+	return messages, nil
+}
+
+// Producer appends records to the event bus, e.g. dead letters
+type Producer struct {
+	records *mongo.Collection
+	offsets *mongo.Collection
+}
+
+// NewProducer creates a Producer writing to db
+func NewProducer(db *mongo.Database) *Producer {
+	return &Producer{records: db.Collection(RecordCollection), offsets: db.Collection(OffsetsCollection)}
+}
+
+// Produce stores msg at the next offset of its topic and reports the
+// delivery on deliveryChan, when given
+func (p *Producer) Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	topic := *msg.TopicPartition.Topic
+
+	var counter struct {
+		Offset int64 `bson:"offset"`
+	}
+	err := p.offsets.FindOneAndUpdate(ctx,
+		bson.M{"_id": topic},
+		bson.M{"$inc": bson.M{"offset": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		return fmt.Errorf("failed to allocate offset: %w", err)
+	}
+
+	record := &Record{
+		ID:          recordID(topic, counter.Offset),
+		Topic:       topic,
+		Offset:      counter.Offset,
+		Key:         msg.Key,
+		Value:       msg.Value,
+		Headers:     []Header{},
+		Timestamp:   time.Now(),
+		CommittedBy: []string{},
+	}
+	for _, h := range msg.Headers {
+		record.Headers = append(record.Headers, Header{Key: h.Key, Value: string(h.Value)})
+	}
+	if _, err := p.records.InsertOne(ctx, record); err != nil {
+		return fmt.Errorf("failed to insert event bus record: %w", err)
+	}
+
+	if deliveryChan != nil {
+		report := record.message()
+		go func() { deliveryChan <- report }()
+	}
+	return nil
+}
+
+// Flush returns 0; Produce stores records before it returns
+func (p *Producer) Flush(timeoutMs int) int {
+	return 0
+}
+
+// Close is a no-op; the MongoDB client is closed by main
+func (p *Producer) Close() {}
+
+// RewindGroup makes groupID read the records of topic after offset again by
+// taking it off their committedBy lists; a negative offset rewinds the whole
+// topic. It returns the number of records rewound.
+func RewindGroup(ctx context.Context, db *mongo.Database, topic, groupID string, offset int64) (int64, error) {
+	result, err := db.Collection(RecordCollection).UpdateMany(ctx,
+		bson.M{"topic": topic, "offset": bson.M{"$gt": offset}, "committedBy": groupID},
+		bson.M{"$pull": bson.M{"committedBy": groupID}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to rewind group %s: %w", groupID, err)
+	}
+	return result.ModifiedCount, nil
+}
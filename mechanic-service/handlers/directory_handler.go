@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"mechanic-service/domain"
+
+	"go.opentelemetry.io/otel/codes"
+)
+
+// SearchMechanics returns a page of the public mechanic directory, e.g.
+// ?name=auto&minRating=4&skills=flat_tire,chain_replacement&latitude=52.52&longitude=13.40&radiusKm=5&sort=distance&page=2&pageSize=10.
+// Every parameter is optional; latitude and longitude go together.
+func (h *MechanicHandler) SearchMechanics(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "SearchMechanics")
+	defer span.End()
+
+	h.logger.Info("Received GET /mechanics/search request", "query", r.URL.RawQuery, "app", "mechanic-service")
+	w.Header().Set("Content-Type", "application/json")
+	search, err := parseMechanicSearch(r.URL.Query())
+	if err == nil {
+		var page *domain.MechanicPage
+		if page, err = h.service.SearchMechanics(ctx, search); err == nil {
+			json.NewEncoder(w).Encode(page)
+			return
+		}
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	if errors.Is(err, domain.ErrInvalidInput) {
+		w.WriteHeader(http.StatusBadRequest)
+	} else {
+		h.logger.Error("Failed to search mechanics", "error", err, "app", "mechanic-service")
+		w.WriteHeader(storageErrorStatus(w, err))
+	}
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// parseMechanicSearch reads the directory's query parameters
+func parseMechanicSearch(query url.Values) (*domain.MechanicSearch, error) {
+	search := &domain.MechanicSearch{Name: strings.TrimSpace(query.Get("name")), Sort: query.Get("sort")}
+	for _, skill := range strings.Split(query.Get("skills"), ",") {
+		if skill = strings.TrimSpace(skill); skill != "" {
+			search.Skills = append(search.Skills, skill)
+		}
+	}
+	floats := map[string]*float64{"minRating": &search.MinRating, "radiusKm": &search.RadiusKm}
+	if lat, lon := query.Get("latitude"), query.Get("longitude"); lat != "" || lon != "" {
+		if lat == "" || lon == "" {
+			return nil, fmt.Errorf("%w: latitude and longitude go together", domain.ErrInvalidInput)
+		}
+		search.Near = &domain.Location{}
+		floats["latitude"], floats["longitude"] = &search.Near.Latitude, &search.Near.Longitude
+	}
+	for name, dst := range floats {
+		if v := query.Get(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid %s %q", domain.ErrInvalidInput, name, v)
+			}
+			*dst = f
+		}
+	}
+	for name, dst := range map[string]*int{"page": &search.Page, "pageSize": &search.PageSize} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid %s %q", domain.ErrInvalidInput, name, v)
+			}
+			*dst = n
+		}
+	}
+	return search, nil
+}
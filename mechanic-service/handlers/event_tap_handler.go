@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"mechanic-service/kafka"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// TapEvents returns the last ?limit= (default 20, at most 500) records of
+// ?topic=, repair-events by default or its dead letter topic, decoded via
+// schema-registry
+func (h *MechanicHandler) TapEvents(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "TapEvents")
+	defer span.End()
+
+	w.Header().Set("Content-Type", "application/json")
+	topic := r.URL.Query().Get("topic")
+	if topic == "" {
+		topic = kafka.RepairEventsTopic
+	}
+	if topic != kafka.RepairEventsTopic && topic != kafka.DLQTopic() {
+		err := fmt.Errorf("unknown topic %q, expected %s or %s", topic, kafka.RepairEventsTopic, kafka.DLQTopic())
+		span.SetStatus(codes.Error, err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	limit := 20
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 500 {
+		limit = v
+	}
+	span.SetAttributes(attribute.String("topic", topic), attribute.Int("limit", limit))
+
+	h.logger.Info("Received GET /admin/events/tap request", "topic", topic, "limit", limit, "app", "mechanic-service")
+	tap, err := h.service.TapEvents(ctx, topic, limit)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(tap)
+}
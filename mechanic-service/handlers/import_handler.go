@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mechanic-service/domain"
+	"mechanic-service/service"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// maxImportSize bounds the size of an uploaded mechanic import file
+const maxImportSize = 10 << 20
+
+// ImportMechanics bulk upserts mechanics from a CSV or JSON file and returns a
+// per-row report. The file is sent either as the "file" field of a multipart
+// form or as the raw request body; the format is taken from the ?format= query
+// parameter, the file extension or the content type, in that order.
+func (h *MechanicHandler) ImportMechanics(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "ImportMechanics")
+	defer span.End()
+
+	h.logger.Info("Received POST /admin/mechanics/import request", "app", "mechanic-service")
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportSize)
+
+	var file io.Reader = r.Body
+	filename := ""
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType == "multipart/form-data" {
+		part, header, err := r.FormFile("file")
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Missing import file")
+			h.logger.Error("Failed to read import file", "error", err, "app", "mechanic-service")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Missing import file: " + err.Error()})
+			return
+		}
+		defer part.Close()
+		file = part
+		filename = header.Filename
+		contentType, _, _ = mime.ParseMediaType(header.Header.Get("Content-Type"))
+	}
+
+	format := importFormat(r.URL.Query().Get("format"), filename, contentType)
+	span.SetAttributes(attribute.String("format", format))
+
+	report, err := h.service.ImportMechanics(ctx, format, file)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		h.logger.Error("Failed to import mechanics", "error", err, "format", format, "app", "mechanic-service")
+		status := http.StatusInternalServerError
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			status = http.StatusRequestEntityTooLarge
+		case errors.Is(err, domain.ErrInvalidInput):
+			status = http.StatusBadRequest
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	span.SetAttributes(
+		attribute.Int("applied", report.Applied),
+		attribute.Int("skipped", report.Skipped),
+		attribute.Int("errors", report.Errors),
+	)
+	h.logger.Info("Successfully imported mechanics", "applied", report.Applied, "skipped", report.Skipped, "errors", report.Errors, "app", "mechanic-service")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// importFormat picks the import format from an explicit value, the file
+// extension or the content type, defaulting to CSV
+func importFormat(explicit, filename, contentType string) string {
+	if explicit != "" {
+		return strings.ToLower(explicit)
+	}
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return service.ImportFormatJSON
+	case ".csv":
+		return service.ImportFormatCSV
+	}
+	if contentType == "application/json" {
+		return service.ImportFormatJSON
+	}
+	return service.ImportFormatCSV
+}
@@ -2,28 +2,44 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
+	"mechanic-service/domain"
 	"mechanic-service/service"
+	"mechanic-service/slowlog"
 	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
 )
 
+// maxETAMinutes bounds the ETA a mechanic can commit to when claiming a repair
+const maxETAMinutes = 24 * 60
+
 // MechanicHandler handles mechanic service requests
 type MechanicHandler struct {
 	service *service.Service
+	slow    *slowlog.Recorder
+	rbac    bool // RBAC_ENABLED: nearby repairs and claims only for the calling mechanic
 	tracer  trace.Tracer
 	logger  *slog.Logger
 }
 
 // NewMechanicHandler creates a new MechanicHandler
-func NewMechanicHandler(service *service.Service, logger *slog.Logger) *MechanicHandler {
+func NewMechanicHandler(service *service.Service, slow *slowlog.Recorder, logger *slog.Logger) *MechanicHandler {
 	return &MechanicHandler{
 		service: service,
+		slow:    slow,
+		rbac:    os.Getenv("RBAC_ENABLED") == "true",
 		tracer:  otel.Tracer("mechanic-service"),
 		logger:  logger,
 	}
@@ -39,7 +55,23 @@ func (h *MechanicHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("OK"))
 }
 
-// ListNearbyRepairs lists repairs within 10km of a specified mechanic's location
+// Readiness reports whether the Kafka consumer and outbox processor are
+// running, with the health of each supervised loop
+func (h *MechanicHandler) Readiness(w http.ResponseWriter, r *http.Request) {
+	_, span := h.tracer.Start(r.Context(), "Readiness")
+	defer span.End()
+
+	ready := h.service.Ready()
+	span.SetAttributes(attribute.Bool("ready", ready))
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{"ready": ready, "loops": h.service.LoopHealth()})
+}
+
+// ListNearbyRepairs lists the repairs near a mechanic's location, nearest
+// first, within ?radiusKm= or NEARBY_RADIUS_KM and at most ?maxResults= of them
 func (h *MechanicHandler) ListNearbyRepairs(w http.ResponseWriter, r *http.Request) {
 	ctx, span := h.tracer.Start(r.Context(), "ListNearbyRepairs")
 	defer span.End()
@@ -54,14 +86,30 @@ func (h *MechanicHandler) ListNearbyRepairs(w http.ResponseWriter, r *http.Reque
 		json.NewEncoder(w).Encode(map[string]string{"error": "Mechanic ID is required"})
 		return
 	}
+	if !h.authorizeMechanic(w, r, span, mechanicID) {
+		return
+	}
+
+	search, err := parseNearbySearch(r.URL.Query())
+	if err != nil {
+		span.SetStatus(codes.Error, err.Error())
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
 
-	nearby, err := h.service.ListNearbyRepairs(ctx, mechanicID)
+	nearby, err := h.service.ListNearbyRepairs(ctx, mechanicID, search)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		h.logger.Error("Failed to list nearby repairs", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
+		if errors.Is(err, domain.ErrInvalidInput) {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			h.logger.Error("Failed to list nearby repairs", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
+			w.WriteHeader(storageErrorStatus(w, err))
+		}
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
@@ -79,6 +127,26 @@ func (h *MechanicHandler) ListNearbyRepairs(w http.ResponseWriter, r *http.Reque
 	}
 }
 
+// parseNearbySearch reads the range and size of a nearby repair listing
+func parseNearbySearch(query url.Values) (domain.NearbySearch, error) {
+	var search domain.NearbySearch
+	if v := query.Get("radiusKm"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return search, fmt.Errorf("%w: invalid radiusKm %q", domain.ErrInvalidInput, v)
+		}
+		search.RadiusKm = f
+	}
+	if v := query.Get("maxResults"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return search, fmt.Errorf("%w: invalid maxResults %q", domain.ErrInvalidInput, v)
+		}
+		search.MaxResults = n
+	}
+	return search, nil
+}
+
 // AssignRepair assigns a mechanic to a repair
 func (h *MechanicHandler) AssignRepair(w http.ResponseWriter, r *http.Request) {
 	ctx, span := h.tracer.Start(r.Context(), "AssignRepair")
@@ -90,6 +158,7 @@ func (h *MechanicHandler) AssignRepair(w http.ResponseWriter, r *http.Request) {
 
 	var input struct {
 		MechanicID string `json:"mechanicID"`
+		ETAMinutes int    `json:"etaMinutes"` // optional, estimated from the distance when 0
 	}
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		span.RecordError(err)
@@ -100,14 +169,35 @@ func (h *MechanicHandler) AssignRepair(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
 		return
 	}
+	if input.ETAMinutes < 0 || input.ETAMinutes > maxETAMinutes {
+		err := fmt.Errorf("etaMinutes must be between 0 and %d", maxETAMinutes)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if !h.authorizeMechanic(w, r, span, input.MechanicID) {
+		return
+	}
 
-	repair, err := h.service.AssignRepair(ctx, repairID, input.MechanicID)
+	repair, err := h.service.AssignRepair(ctx, repairID, input.MechanicID, time.Duration(input.ETAMinutes)*time.Minute)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		h.logger.Error("Failed to assign repair", "error", err, "repairID", repairID, "mechanicID", input.MechanicID, "app", "mechanic-service")
 		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
+		switch {
+		case errors.Is(err, domain.ErrBlocked):
+			w.WriteHeader(http.StatusForbidden)
+		case errors.Is(err, domain.ErrAssignmentConflict), errors.Is(err, domain.ErrMechanicAbsent):
+			w.WriteHeader(http.StatusConflict)
+		case errors.Is(err, mongo.ErrNoDocuments):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(storageErrorStatus(w, err))
+		}
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
 		return
 	}
@@ -122,3 +212,304 @@ func (h *MechanicHandler) AssignRepair(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(repair)
 }
+
+// authorizeMechanic lets a request act for mechanicID when RBAC is off, or
+// when the gateway passed that mechanic, or an admin, as X-Actor-Role and
+// X-Actor-ID. Otherwise it answers 403.
+func (h *MechanicHandler) authorizeMechanic(w http.ResponseWriter, r *http.Request, span trace.Span, mechanicID string) bool {
+	role, actorID := r.Header.Get("X-Actor-Role"), r.Header.Get("X-Actor-ID")
+	if !h.rbac || role == "admin" || (role == "mechanic" && actorID == mechanicID) {
+		return true
+	}
+	span.SetStatus(codes.Error, "Caller may not act for the mechanic")
+	h.logger.Warn("Rejected request for another mechanic", "path", r.URL.Path, "mechanicID", mechanicID, "role", role, "actorID", actorID, "app", "mechanic-service")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{"error": "only the mechanic themselves may do this"})
+	return false
+}
+
+// OutboxStats returns per-worker counters of the outbox processor
+func (h *MechanicHandler) OutboxStats(w http.ResponseWriter, r *http.Request) {
+	_, span := h.tracer.Start(r.Context(), "OutboxStats")
+	defer span.End()
+
+	stats := h.service.OutboxStats()
+	span.SetAttributes(attribute.Int("workerCount", len(stats)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// DeliveryMetrics reports per event type latency from Kafka delivery to persistence
+func (h *MechanicHandler) DeliveryMetrics(w http.ResponseWriter, r *http.Request) {
+	_, span := h.tracer.Start(r.Context(), "DeliveryMetrics")
+	defer span.End()
+
+	stats := h.service.DeliveryLatency()
+	span.SetAttributes(attribute.Int("eventTypeCount", len(stats)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
+
+// ConsumerLag reports how far the repair-events consumer is behind the topic
+func (h *MechanicHandler) ConsumerLag(w http.ResponseWriter, r *http.Request) {
+	_, span := h.tracer.Start(r.Context(), "ConsumerLag")
+	defer span.End()
+
+	lag, err := h.service.ConsumerLag()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		h.logger.Error("Failed to get consumer lag", "error", err, "app", "mechanic-service")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	span.SetAttributes(attribute.Int64("totalLag", lag.TotalLag))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(lag)
+}
+
+// ConsumerMetrics returns handled, failed, retried and dead-lettered message counts per topic
+func (h *MechanicHandler) ConsumerMetrics(w http.ResponseWriter, r *http.Request) {
+	_, span := h.tracer.Start(r.Context(), "ConsumerMetrics")
+	defer span.End()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.service.ConsumerMetrics())
+}
+
+// SlowOperations returns the operations that exceeded their SLOW_* threshold, most frequent first
+func (h *MechanicHandler) SlowOperations(w http.ResponseWriter, r *http.Request) {
+	_, span := h.tracer.Start(r.Context(), "SlowOperations")
+	defer span.End()
+
+	counts := h.slow.Counts()
+	span.SetAttributes(attribute.Int("operationCount", len(counts)))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(counts)
+}
+
+// NotificationPreferences returns (GET) or replaces (PUT) a mechanic's quiet
+// hours and digest frequency
+func (h *MechanicHandler) NotificationPreferences(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "NotificationPreferences")
+	defer span.End()
+
+	mechanicID := mux.Vars(r)["mechanicID"]
+	span.SetAttributes(
+		attribute.String("mechanicID", mechanicID),
+		attribute.String("method", r.Method),
+	)
+	h.logger.Info("Received notification preferences request", "method", r.Method, "mechanicID", mechanicID, "app", "mechanic-service")
+
+	var (
+		prefs *domain.NotificationPrefs
+		err   error
+	)
+	if r.Method == http.MethodPut {
+		input := &domain.NotificationPrefs{}
+		if err := json.NewDecoder(r.Body).Decode(input); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Invalid request body")
+			h.logger.Error("Failed to decode request body", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		input.MechanicID = mechanicID
+		prefs, err = h.service.UpdateNotificationPrefs(ctx, input)
+	} else {
+		prefs, err = h.service.GetNotificationPrefs(ctx, mechanicID)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		h.logger.Error("Failed to handle notification preferences", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, domain.ErrInvalidInput) {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(storageErrorStatus(w, err))
+		}
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(prefs)
+}
+
+// Absences lists (GET) a mechanic's current and upcoming absences or schedules
+// (POST) a new one. The created absence comes with the open repairs to
+// reassign when it starts soon.
+func (h *MechanicHandler) Absences(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "Absences")
+	defer span.End()
+
+	mechanicID := mux.Vars(r)["mechanicID"]
+	span.SetAttributes(
+		attribute.String("mechanicID", mechanicID),
+		attribute.String("method", r.Method),
+	)
+	h.logger.Info("Received absences request", "method", r.Method, "mechanicID", mechanicID, "app", "mechanic-service")
+
+	var (
+		response interface{}
+		err      error
+	)
+	status := http.StatusOK
+	if r.Method == http.MethodPost {
+		input := &domain.Absence{}
+		if err := json.NewDecoder(r.Body).Decode(input); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Invalid request body")
+			h.logger.Error("Failed to decode request body", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		input.MechanicID = mechanicID
+		response, err = h.service.CreateAbsence(ctx, input)
+		status = http.StatusCreated
+	} else {
+		response, err = h.service.ListAbsences(ctx, mechanicID)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		h.logger.Error("Failed to handle absences", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, domain.ErrInvalidInput) {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(storageErrorStatus(w, err))
+		}
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// DeleteAbsence cancels one of a mechanic's absences
+func (h *MechanicHandler) DeleteAbsence(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "DeleteAbsence")
+	defer span.End()
+
+	vars := mux.Vars(r)
+	mechanicID, absenceID := vars["mechanicID"], vars["absenceID"]
+	span.SetAttributes(
+		attribute.String("mechanicID", mechanicID),
+		attribute.String("absenceID", absenceID),
+	)
+
+	if err := h.service.DeleteAbsence(ctx, mechanicID, absenceID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(storageErrorStatus(w, err))
+		}
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UpdateLocation records a position reported by a mechanic's device. It
+// answers 202 whether or not the update went out as an event; the body says
+// which.
+func (h *MechanicHandler) UpdateLocation(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "UpdateLocation")
+	defer span.End()
+
+	mechanicID := mux.Vars(r)["mechanicID"]
+	span.SetAttributes(attribute.String("mechanicID", mechanicID))
+	if !h.authorizeMechanic(w, r, span, mechanicID) {
+		return
+	}
+
+	update := &domain.LocationUpdate{}
+	if err := json.NewDecoder(r.Body).Decode(update); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Invalid request body")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+		return
+	}
+	update.MechanicID = mechanicID
+
+	result, err := h.service.UpdateLocation(ctx, update)
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		switch {
+		case errors.Is(err, domain.ErrInvalidInput):
+			w.WriteHeader(http.StatusBadRequest)
+		case errors.Is(err, mongo.ErrNoDocuments):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(storageErrorStatus(w, err))
+		}
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(result)
+}
+
+// MechanicRoute returns a mechanic's open repairs ordered into a route with
+// an ETA per stop; ?refresh=true recomputes it
+func (h *MechanicHandler) MechanicRoute(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "MechanicRoute")
+	defer span.End()
+
+	mechanicID := mux.Vars(r)["mechanicID"]
+	refresh := r.URL.Query().Get("refresh") == "true"
+	span.SetAttributes(attribute.String("mechanicID", mechanicID))
+	h.logger.Info("Received GET /mechanics/{mechanicID}/route request", "mechanicID", mechanicID, "refresh", refresh, "app", "mechanic-service")
+
+	route, err := h.service.MechanicRoute(ctx, mechanicID, refresh)
+	w.Header().Set("Content-Type", "application/json")
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		h.logger.Error("Failed to get mechanic route", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			w.WriteHeader(storageErrorStatus(w, err))
+		}
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	span.SetAttributes(attribute.Int("stopCount", len(route.Stops)))
+	json.NewEncoder(w).Encode(route)
+}
+
+// storageErrorStatus is the status to answer for an error the handlers map
+// no other way, from its storage error class: 404 for a missing document,
+// 409 for a conflicting write, 503 with Retry-After for a transient failure
+// the repository's retries did not overcome, and 500 otherwise
+func storageErrorStatus(w http.ResponseWriter, err error) int {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrTransient):
+		w.Header().Set("Retry-After", "1")
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// ProjectionSnapshots lists the snapshots of the repairs view, newest first
+// (GET, ?limit= defaults to 20), or takes one now (POST)
+func (h *MechanicHandler) ProjectionSnapshots(w http.ResponseWriter, r *http.Request) {
+	ctx, span := h.tracer.Start(r.Context(), "ProjectionSnapshots")
+	defer span.End()
+	span.SetAttributes(attribute.String("method", r.Method))
+
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method == http.MethodPost {
+		h.logger.Info("Received POST /admin/projection/snapshots request", "app", "mechanic-service")
+		snapshot, err := h.service.TakeProjectionSnapshot(ctx)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(snapshot)
+		return
+	}
+
+	limit := 20
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 && v <= 100 {
+		limit = v
+	}
+	snapshots, err := h.service.ProjectionSnapshots(ctx, limit)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		h.logger.Error("Failed to list projection snapshots", "error", err, "app", "mechanic-service")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	span.SetAttributes(attribute.Int("snapshotCount", len(snapshots)))
+	json.NewEncoder(w).Encode(snapshots)
+}
@@ -0,0 +1,176 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"mechanic-service/domain"
+	"mechanic-service/kafka/consume"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// assignmentBatchSize bounds the assignment events read per poll
+const assignmentBatchSize = 500
+
+// AssignmentEventStore is the part of the repository the assignment outbox
+// processor reads and marks events in
+type AssignmentEventStore interface {
+	GetUnprocessedAssignmentEvents(ctx context.Context, limit int) ([]*domain.AssignmentEvent, error)
+	MarkAssignmentEventProcessed(ctx context.Context, eventID string, expireAt *time.Time) error
+}
+
+// AssignmentOutboxProcessor publishes the events of assignment_outbox, such as
+// repair assignments, throttled mechanic locations and ETA notices, to the
+// assignment-events topic as JSON, keyed by their aggregate. Events go out in
+// the order they were written; a failed publish stops the batch so later
+// events of the same aggregate are not published before it. Consumers drop
+// redeliveries by the event_id header.
+type AssignmentOutboxProcessor struct {
+	store        AssignmentEventStore
+	producer     consume.Producer
+	topic        string
+	logger       *slog.Logger
+	pollInterval time.Duration
+	retention    time.Duration // how long published events other than repair_assigned are kept
+}
+
+// NewAssignmentOutboxProcessor creates an AssignmentOutboxProcessor publishing
+// to topic through producer, e.g. a *kafka.Producer or the Mongo event bus
+func NewAssignmentOutboxProcessor(store AssignmentEventStore, producer consume.Producer, topic string, logger *slog.Logger, pollInterval, retention time.Duration) *AssignmentOutboxProcessor {
+	return &AssignmentOutboxProcessor{
+		store:        store,
+		producer:     producer,
+		topic:        topic,
+		logger:       logger,
+		pollInterval: pollInterval,
+		retention:    retention,
+	}
+}
+
+// NewEventProducer creates the Kafka producer of the assignment events
+func NewEventProducer(bootstrapServers string) (*kafka.Producer, error) {
+	config, err := ClientConfig(bootstrapServers, nil)
+	if err != nil {
+		return nil, err
+	}
+	producer, err := kafka.NewProducer(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create event producer: %w", err)
+	}
+	return producer, nil
+}
+
+// Start publishes assignment events every poll interval until ctx is done
+func (p *AssignmentOutboxProcessor) Start(ctx context.Context) error {
+	p.logger.Info("Assignment outbox processor started", "topic", p.topic, "pollInterval", p.pollInterval, "retention", p.retention, "app", "mechanic-service")
+	ticker := time.NewTicker(p.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := p.PublishPending(ctx); err != nil {
+			p.logger.Error("Failed to publish assignment events", "error", err, "app", "mechanic-service")
+		}
+		select {
+		case <-ctx.Done():
+			p.logger.Info("Stopping assignment outbox processor", "app", "mechanic-service")
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// PublishPending publishes the unprocessed assignment events, oldest first,
+// and returns how many went out
+func (p *AssignmentOutboxProcessor) PublishPending(ctx context.Context) (int, error) {
+	ctx, span := otel.Tracer("mechanic-service").Start(ctx, "PublishAssignmentEvents")
+	defer span.End()
+
+	published := 0
+	for {
+		events, err := p.store.GetUnprocessedAssignmentEvents(ctx, assignmentBatchSize)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to get unprocessed assignment events")
+			return published, err
+		}
+		for _, event := range events {
+			if err := p.publish(ctx, event); err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, "Failed to publish assignment event")
+				span.SetAttributes(attribute.Int("publishedEventCount", published))
+				return published, err
+			}
+			published++
+		}
+		if len(events) < assignmentBatchSize {
+			break
+		}
+	}
+	span.SetAttributes(attribute.Int("publishedEventCount", published))
+	return published, nil
+}
+
+// publish sends one assignment event, waits for its delivery and marks it
+// processed
+func (p *AssignmentOutboxProcessor) publish(ctx context.Context, event *domain.AssignmentEvent) error {
+	ctx, span := otel.Tracer("mechanic-service").Start(ctx, "PublishAssignmentEvent",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			attribute.String("eventID", event.ID),
+			attribute.String("eventType", event.EventType),
+			attribute.String("aggregateID", event.AggregateID),
+			attribute.String("messaging.destination.name", p.topic),
+		),
+	)
+	defer span.End()
+
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &p.topic, Partition: kafka.PartitionAny},
+		Key:            []byte(event.AggregateID),
+		Value:          event.Payload,
+		Headers: []kafka.Header{
+			{Key: eventTypeHeader, Value: []byte(event.EventType)},
+			{Key: eventIDHeader, Value: []byte(event.ID)},
+		},
+	}
+	delivery := make(chan kafka.Event, 1)
+	err := p.producer.Produce(msg, delivery)
+	if err == nil {
+		select {
+		case e := <-delivery:
+			if m, ok := e.(*kafka.Message); ok && m.TopicPartition.Error != nil {
+				err = m.TopicPartition.Error
+			}
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to publish assignment event")
+		p.logger.Error("Failed to publish assignment event", "eventID", event.ID, "eventType", event.EventType, "error", err, "app", "mechanic-service")
+		return fmt.Errorf("failed to publish assignment event %s: %w", event.ID, err)
+	}
+
+	// repair_assigned events stay as the repairs' assignment history
+	var expireAt *time.Time
+	if event.EventType != domain.EventRepairAssigned {
+		at := time.Now().Add(p.retention)
+		expireAt = &at
+	}
+	if err := p.store.MarkAssignmentEventProcessed(ctx, event.ID, expireAt); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to mark assignment event as processed")
+		p.logger.Error("Failed to mark assignment event as processed", "eventID", event.ID, "error", err, "app", "mechanic-service")
+		return err
+	}
+	p.logger.Debug("Published assignment event", "eventID", event.ID, "eventType", event.EventType, "topic", p.topic, "app", "mechanic-service")
+	return nil
+}
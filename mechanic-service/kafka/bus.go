@@ -0,0 +1,22 @@
+package kafka
+
+import "os"
+
+// Event bus implementations, selected with EVENT_BUS like in repair-service
+const (
+	BusKafka = "kafka" // Kafka with schema-registry, the default
+	BusMongo = "mongo" // the event_bus collection of the shared MongoDB, for single-node installs
+)
+
+// LocalSchemaID frames Avro payloads published without schema-registry; they
+// are decoded with the local reader schema
+const LocalSchemaID = 0
+
+// EventBus returns the configured event bus: BusMongo when EVENT_BUS is
+// mongo, otherwise BusKafka
+func EventBus() string {
+	if os.Getenv("EVENT_BUS") == BusMongo {
+		return BusMongo
+	}
+	return BusKafka
+}
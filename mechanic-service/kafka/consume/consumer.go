@@ -0,0 +1,232 @@
+// Package consume is a small Kafka consumer framework: it runs the
+// poll, decode, handle, commit loop and lets callers compose handler
+// middleware for tracing, metrics, retries and dead-lettering. Records come
+// from a Source, a Kafka consumer group unless another event bus is plugged in.
+package consume
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Message is a consumed record together with its decoded value
+type Message[T any] struct {
+	Record  *kafka.Message
+	Event   T
+	EventID string // set by Dedup
+}
+
+// Header returns the value of the record header key, or "" when absent
+func (m *Message[T]) Header(key string) string {
+	for _, h := range m.Record.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Handler processes one message. Returning nil commits its offset.
+type Handler[T any] func(ctx context.Context, msg *Message[T]) error
+
+// Middleware wraps a Handler, e.g. to trace, retry or dead-letter it
+type Middleware[T any] func(next Handler[T]) Handler[T]
+
+// DecodeFunc decodes a record value into T
+type DecodeFunc[T any] func(payload []byte) (T, error)
+
+// PayloadDecoder decodes a payload into v, e.g. kafka.SchemaResolver
+type PayloadDecoder interface {
+	Decode(payload []byte, v interface{}) error
+}
+
+// Avro decodes Schema Registry framed Avro payloads into T
+func Avro[T any](decoder PayloadDecoder) DecodeFunc[T] {
+	return func(payload []byte) (T, error) {
+		var v T
+		err := decoder.Decode(payload, &v)
+		return v, err
+	}
+}
+
+// permanentError marks failures that retrying cannot fix
+type permanentError struct{ err error }
+
+func (e permanentError) Error() string { return e.err.Error() }
+func (e permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying; decode failures always are
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent
+func IsPermanent(err error) bool {
+	var p permanentError
+	return errors.As(err, &p)
+}
+
+// Config configures a Consumer
+type Config struct {
+	Topic          string
+	App            string        // service name used in logs
+	PollTimeout    time.Duration // how often the loop checks for shutdown, default 500ms
+	FailureBackoff time.Duration // wait before redelivering a failed message, default 1s
+}
+
+// Consumer runs a Handler over every record of one topic, committing offsets
+// only after the handler succeeded. A failed message is redelivered, so
+// handlers that must not block the partition should end in DeadLetter.
+type Consumer[T any] struct {
+	source        Source
+	cfg           Config
+	handler       Handler[T]
+	logger        *slog.Logger
+	mu            sync.Mutex
+	stopped       chan struct{} // closed when the current Run returns
+	lastMessageAt time.Time     // timestamp of the last committed message
+}
+
+// New creates a Consumer reading from source. Middleware is applied in order,
+// so the first one is the outermost; decoding happens inside all of them.
+func New[T any](cfg Config, source Source, decode DecodeFunc[T], handler Handler[T], logger *slog.Logger, middleware ...Middleware[T]) *Consumer[T] {
+	if cfg.PollTimeout <= 0 {
+		cfg.PollTimeout = 500 * time.Millisecond
+	}
+	if cfg.FailureBackoff <= 0 {
+		cfg.FailureBackoff = time.Second
+	}
+
+	h := func(ctx context.Context, msg *Message[T]) error {
+		event, err := decode(msg.Record.Value)
+		if err != nil {
+			return Permanent(fmt.Errorf("failed to decode message: %w", err))
+		}
+		msg.Event = event
+		return handler(ctx, msg)
+	}
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+
+	stopped := make(chan struct{})
+	close(stopped) // not running yet
+	return &Consumer[T]{
+		source:  source,
+		cfg:     cfg,
+		handler: h,
+		logger:  logger,
+		stopped: stopped,
+	}
+}
+
+// Run consumes until ctx is canceled. The message in flight is finished and
+// committed before Run returns. Run may be called again after it returned an
+// error, e.g. by a supervisor.
+func (c *Consumer[T]) Run(ctx context.Context) error {
+	stopped := make(chan struct{})
+	c.mu.Lock()
+	c.stopped = stopped
+	c.mu.Unlock()
+	defer close(stopped)
+
+	if err := c.source.Subscribe(c.cfg.Topic); err != nil {
+		c.logger.Error("Failed to subscribe to topic", "topic", c.cfg.Topic, "error", err, "app", c.cfg.App)
+		return fmt.Errorf("failed to subscribe to topic: %w", err)
+	}
+	c.logger.Info("Subscribed to Kafka topic", "topic", c.cfg.Topic, "app", c.cfg.App)
+
+	for {
+		if ctx.Err() != nil {
+			c.logger.Info("Context canceled, stopping Kafka consumer", "topic", c.cfg.Topic, "app", c.cfg.App)
+			return ctx.Err()
+		}
+		record, err := c.source.Read(c.cfg.PollTimeout)
+		if err != nil {
+			c.logger.Error("Error reading Kafka message", "error", err, "app", c.cfg.App)
+			continue
+		}
+		if record == nil {
+			continue
+		}
+
+		if err := c.handler(ctx, &Message[T]{Record: record}); err != nil {
+			c.logger.Error("Failed to handle message, redelivering",
+				"topic", *record.TopicPartition.Topic,
+				"partition", record.TopicPartition.Partition,
+				"offset", record.TopicPartition.Offset,
+				"error", err,
+				"app", c.cfg.App)
+			c.redeliver(ctx, record)
+			continue
+		}
+
+		if err := c.source.Commit(record); err != nil {
+			c.logger.Error("Failed to commit Kafka offset",
+				"topic", *record.TopicPartition.Topic,
+				"partition", record.TopicPartition.Partition,
+				"offset", record.TopicPartition.Offset,
+				"error", err,
+				"app", c.cfg.App)
+			continue
+		}
+		c.mu.Lock()
+		c.lastMessageAt = record.Timestamp
+		c.mu.Unlock()
+	}
+}
+
+// redeliver rewinds the partition to record so it is read again after a backoff
+func (c *Consumer[T]) redeliver(ctx context.Context, record *kafka.Message) {
+	if err := c.source.Rewind(record); err != nil {
+		c.logger.Error("Failed to seek back to failed message", "offset", record.TopicPartition.Offset, "error", err, "app", c.cfg.App)
+	}
+	select {
+	case <-ctx.Done():
+	case <-time.After(c.cfg.FailureBackoff):
+	}
+}
+
+// LastMessageTime returns the timestamp of the last committed message, or the
+// zero time if nothing has been consumed since startup
+func (c *Consumer[T]) LastMessageTime() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lastMessageAt
+}
+
+// Ping checks that the event bus is reachable
+func (c *Consumer[T]) Ping(timeout time.Duration) error {
+	return c.source.Ping(c.cfg.Topic, timeout)
+}
+
+// Lag returns the consumer lag of the topic across the assigned partitions
+func (c *Consumer[T]) Lag(timeout time.Duration) (*ConsumerLag, error) {
+	return c.source.Lag(c.cfg.Topic, timeout)
+}
+
+// Close waits up to timeout for Run to finish its in-flight message, then
+// closes the underlying consumer. Cancel Run's context first.
+func (c *Consumer[T]) Close(timeout time.Duration) {
+	c.mu.Lock()
+	stopped := c.stopped
+	c.mu.Unlock()
+	select {
+	case <-stopped:
+	case <-time.After(timeout):
+		c.logger.Warn("Kafka consumer did not stop in time, closing anyway", "topic", c.cfg.Topic, "app", c.cfg.App)
+	}
+	c.logger.Info("Closing Kafka consumer", "topic", c.cfg.Topic, "app", c.cfg.App)
+	if err := c.source.Close(); err != nil {
+		c.logger.Error("Failed to close consumer source", "topic", c.cfg.Topic, "error", err, "app", c.cfg.App)
+	}
+}
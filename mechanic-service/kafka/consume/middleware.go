@@ -0,0 +1,285 @@
+package consume
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing runs each message in a consumer span continuing the trace context
+// in its headers, with the messaging.* attributes of system (e.g. kafka) and
+// the consumer group
+func Tracing[T any](tracer trace.Tracer, spanName, system, group string) Middleware[T] {
+	return func(next Handler[T]) Handler[T] {
+		return func(ctx context.Context, msg *Message[T]) error {
+			ctx = otel.GetTextMapPropagator().Extract(ctx, headerCarrier{record: msg.Record})
+			tp := msg.Record.TopicPartition
+			ctx, span := tracer.Start(ctx, spanName,
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(
+					semconv.MessagingSystemKey.String(system),
+					semconv.MessagingOperationTypeDeliver,
+					semconv.MessagingOperationName("process"),
+					semconv.MessagingDestinationName(*tp.Topic),
+					semconv.MessagingDestinationPartitionID(strconv.Itoa(int(tp.Partition))),
+					semconv.MessagingKafkaMessageOffset(int(tp.Offset)),
+					semconv.MessagingKafkaConsumerGroup(group),
+					semconv.MessagingMessageBodySize(len(msg.Record.Value)),
+				),
+			)
+			defer span.End()
+			if len(msg.Record.Key) > 0 {
+				span.SetAttributes(semconv.MessagingKafkaMessageKey(string(msg.Record.Key)))
+			}
+			err := next(ctx, msg)
+			if msg.EventID != "" {
+				span.SetAttributes(semconv.MessagingMessageID(msg.EventID))
+			}
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, "Failed to handle message")
+			}
+			return err
+		}
+	}
+}
+
+// headerCarrier reads and writes the trace context in record headers
+type headerCarrier struct {
+	record *kafka.Message
+}
+
+func (c headerCarrier) Get(key string) string {
+	for _, h := range c.record.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c.record.Headers = append(c.record.Headers, kafka.Header{Key: key, Value: []byte(value)})
+}
+
+func (c headerCarrier) Keys() []string {
+	keys := make([]string, 0, len(c.record.Headers))
+	for _, h := range c.record.Headers {
+		keys = append(keys, h.Key)
+	}
+	return keys
+}
+
+// Counts are the handler outcomes recorded by Metrics
+type Counts struct {
+	Handled      int64     `json:"handled"`
+	Failed       int64     `json:"failed"`
+	Retried      int64     `json:"retried"`
+	DeadLettered int64     `json:"deadLettered"`
+	Duplicates   int64     `json:"duplicates"`
+	OtherRegion  int64     `json:"otherRegion"` // skipped because another region's instances handle them
+	LastHandled  time.Time `json:"lastHandled,omitempty"`
+	LastError    string    `json:"lastError,omitempty"`
+}
+
+// Metrics counts handler outcomes per topic. One Metrics can be shared by the
+// Instrument, Retry and DeadLetter middleware of several consumers.
+type Metrics struct {
+	mu      sync.Mutex
+	byTopic map[string]*Counts
+}
+
+// NewMetrics creates an empty Metrics
+func NewMetrics() *Metrics {
+	return &Metrics{byTopic: make(map[string]*Counts)}
+}
+
+func (m *Metrics) update(topic string, fn func(*Counts)) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	c, ok := m.byTopic[topic]
+	if !ok {
+		c = &Counts{}
+		m.byTopic[topic] = c
+	}
+	fn(c)
+}
+
+// Snapshot returns a copy of the counts per topic
+func (m *Metrics) Snapshot() map[string]Counts {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	snapshot := make(map[string]Counts, len(m.byTopic))
+	for topic, c := range m.byTopic {
+		snapshot[topic] = *c
+	}
+	return snapshot
+}
+
+// Instrument records in m whether each message was handled or failed
+func Instrument[T any](m *Metrics) Middleware[T] {
+	return func(next Handler[T]) Handler[T] {
+		return func(ctx context.Context, msg *Message[T]) error {
+			err := next(ctx, msg)
+			m.update(*msg.Record.TopicPartition.Topic, func(c *Counts) {
+				if err != nil {
+					c.Failed++
+					c.LastError = err.Error()
+					return
+				}
+				c.Handled++
+				c.LastHandled = time.Now()
+			})
+			return err
+		}
+	}
+}
+
+// DedupStore remembers the IDs of events whose handling succeeded, e.g. in a
+// collection with a TTL index
+type DedupStore interface {
+	IsEventProcessed(ctx context.Context, eventID string) (bool, error)
+	MarkEventProcessed(ctx context.Context, eventID string) error
+}
+
+// Dedup skips messages whose event ID, read from header, the store already
+// holds, and records the ID once the handler succeeded, so every event takes
+// effect once however often it is delivered. Records without the header are
+// identified by topic, partition and offset. msg.EventID is set for handlers
+// that record the ID with their own writes in one transaction.
+func Dedup[T any](store DedupStore, header string, m *Metrics, logger *slog.Logger, app string) Middleware[T] {
+	return func(next Handler[T]) Handler[T] {
+		return func(ctx context.Context, msg *Message[T]) error {
+			tp := msg.Record.TopicPartition
+			msg.EventID = msg.Header(header)
+			if msg.EventID == "" {
+				msg.EventID = fmt.Sprintf("%s/%d/%d", *tp.Topic, tp.Partition, tp.Offset)
+			}
+			processed, err := store.IsEventProcessed(ctx, msg.EventID)
+			if err != nil {
+				return fmt.Errorf("failed to check processed event: %w", err)
+			}
+			if processed {
+				m.update(*tp.Topic, func(c *Counts) { c.Duplicates++ })
+				logger.Info("Skipping duplicate event", "eventID", msg.EventID, "topic", *tp.Topic, "partition", tp.Partition, "offset", tp.Offset, "app", app)
+				return nil
+			}
+			if err := next(ctx, msg); err != nil {
+				return err
+			}
+			if err := store.MarkEventProcessed(ctx, msg.EventID); err != nil {
+				return fmt.Errorf("failed to mark event processed: %w", err)
+			}
+			return nil
+		}
+	}
+}
+
+// Region skips records whose header names a region outside regions, so
+// region-scoped instances can share topics with other regions. Records
+// without the header predate regions and are handled by every instance; an
+// empty regions handles every record.
+func Region[T any](header string, regions map[string]bool, m *Metrics, logger *slog.Logger, app string) Middleware[T] {
+	return func(next Handler[T]) Handler[T] {
+		return func(ctx context.Context, msg *Message[T]) error {
+			region := msg.Header(header)
+			if len(regions) == 0 || region == "" || regions[region] {
+				return next(ctx, msg)
+			}
+			tp := msg.Record.TopicPartition
+			m.update(*tp.Topic, func(c *Counts) { c.OtherRegion++ })
+			logger.Debug("Skipping event of another region", "region", region, "topic", *tp.Topic, "partition", tp.Partition, "offset", tp.Offset, "app", app)
+			return nil
+		}
+	}
+}
+
+// Retry calls the handler up to attempts times with a linearly growing
+// backoff. Permanent errors are not retried.
+func Retry[T any](attempts int, backoff time.Duration, m *Metrics) Middleware[T] {
+	return func(next Handler[T]) Handler[T] {
+		return func(ctx context.Context, msg *Message[T]) error {
+			var err error
+			for attempt := 1; attempt <= attempts; attempt++ {
+				if err = next(ctx, msg); err == nil || IsPermanent(err) || attempt == attempts {
+					return err
+				}
+				m.update(*msg.Record.TopicPartition.Topic, func(c *Counts) { c.Retried++ })
+				select {
+				case <-ctx.Done():
+					return err
+				case <-time.After(backoff * time.Duration(attempt)):
+				}
+			}
+			return err
+		}
+	}
+}
+
+// Dead letter record headers describing where and why a message failed
+const (
+	HeaderDLQTopic     = "dlq_original_topic"
+	HeaderDLQPartition = "dlq_original_partition"
+	HeaderDLQOffset    = "dlq_original_offset"
+	HeaderDLQError     = "dlq_error"
+)
+
+// DeadLetter publishes messages that failed permanently (undecodable, or
+// marked with Permanent) to dlqTopic with the original key, value and headers
+// plus the failure, and lets the consumer commit past them. Other failures are
+// returned so the message is redelivered once the dependency recovers, and so
+// is the original error if publishing to dlqTopic fails.
+func DeadLetter[T any](producer Producer, dlqTopic string, m *Metrics, logger *slog.Logger, app string) Middleware[T] {
+	return func(next Handler[T]) Handler[T] {
+		return func(ctx context.Context, msg *Message[T]) error {
+			err := next(ctx, msg)
+			if err == nil || !IsPermanent(err) || ctx.Err() != nil {
+				return err
+			}
+			tp := msg.Record.TopicPartition
+			headers := append([]kafka.Header{}, msg.Record.Headers...)
+			headers = append(headers,
+				kafka.Header{Key: HeaderDLQTopic, Value: []byte(*tp.Topic)},
+				kafka.Header{Key: HeaderDLQPartition, Value: []byte(strconv.Itoa(int(tp.Partition)))},
+				kafka.Header{Key: HeaderDLQOffset, Value: []byte(strconv.FormatInt(int64(tp.Offset), 10))},
+				kafka.Header{Key: HeaderDLQError, Value: []byte(err.Error())},
+			)
+			delivery := make(chan kafka.Event, 1)
+			dlqErr := producer.Produce(&kafka.Message{
+				TopicPartition: kafka.TopicPartition{Topic: &dlqTopic, Partition: kafka.PartitionAny},
+				Key:            msg.Record.Key,
+				Value:          msg.Record.Value,
+				Headers:        headers,
+			}, delivery)
+			if dlqErr == nil {
+				select {
+				case e := <-delivery:
+					if report, ok := e.(*kafka.Message); ok && report.TopicPartition.Error != nil {
+						dlqErr = report.TopicPartition.Error
+					}
+				case <-ctx.Done():
+					dlqErr = ctx.Err()
+				}
+			}
+			if dlqErr != nil {
+				logger.Error("Failed to dead-letter message", "topic", *tp.Topic, "partition", tp.Partition, "offset", tp.Offset, "error", dlqErr, "app", app)
+				return fmt.Errorf("%w (dead-lettering failed: %v)", err, dlqErr)
+			}
+			m.update(*tp.Topic, func(c *Counts) { c.DeadLettered++ })
+			logger.Warn("Dead-lettered message", "topic", *tp.Topic, "partition", tp.Partition, "offset", tp.Offset, "dlqTopic", dlqTopic, "error", err, "app", app)
+			return nil
+		}
+	}
+}
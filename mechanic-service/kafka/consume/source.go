@@ -0,0 +1,149 @@
+package consume
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Source delivers the records of one topic to a Consumer and keeps track of
+// what was committed, e.g. a Kafka consumer group or the Mongo event bus
+type Source interface {
+	Subscribe(topic string) error
+	// Read returns the next record, or nil when none arrived within timeout
+	Read(timeout time.Duration) (*kafka.Message, error)
+	// Commit marks record handled so it is not delivered again
+	Commit(record *kafka.Message) error
+	// Rewind makes record the next one Read returns
+	Rewind(record *kafka.Message) error
+	Ping(topic string, timeout time.Duration) error
+	Lag(topic string, timeout time.Duration) (*ConsumerLag, error)
+	Close() error
+}
+
+// Producer publishes records, e.g. a *kafka.Producer or the Mongo event bus.
+// A delivery report is sent to deliveryChan once the record is stored.
+type Producer interface {
+	Produce(msg *kafka.Message, deliveryChan chan kafka.Event) error
+}
+
+// PartitionLag is the consumer lag of one assigned partition
+type PartitionLag struct {
+	Partition     int32 `json:"partition"`
+	Committed     int64 `json:"committed"` // -1 when the group has no committed offset
+	HighWatermark int64 `json:"highWatermark"`
+	Lag           int64 `json:"lag"`
+}
+
+// ConsumerLag is the lag of a consumer across its assigned partitions
+type ConsumerLag struct {
+	Topic      string         `json:"topic"`
+	TotalLag   int64          `json:"totalLag"`
+	Partitions []PartitionLag `json:"partitions"`
+}
+
+// KafkaSource reads a topic as a member of a Kafka consumer group, committing
+// offsets only when asked to
+type KafkaSource struct {
+	consumer *kafka.Consumer
+}
+
+// NewKafkaSource creates a Source joining groupID with the client config, which
+// holds the brokers and their security settings
+func NewKafkaSource(config *kafka.ConfigMap, groupID string) (*KafkaSource, error) {
+	consumerConfig := kafka.ConfigMap{
+		"group.id":           groupID,
+		"auto.offset.reset":  "earliest",
+		"enable.auto.commit": false, // offsets are committed after the handler succeeds
+	}
+	for k, v := range *config {
+		if _, ok := consumerConfig[k]; !ok {
+			consumerConfig[k] = v
+		}
+	}
+	kc, err := kafka.NewConsumer(&consumerConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka consumer: %w", err)
+	}
+	return &KafkaSource{consumer: kc}, nil
+}
+
+// Subscribe joins the group's subscription of topic
+func (s *KafkaSource) Subscribe(topic string) error {
+	return s.consumer.SubscribeTopics([]string{topic}, nil)
+}
+
+// Read polls the next record; poll timeouts return nil
+func (s *KafkaSource) Read(timeout time.Duration) (*kafka.Message, error) {
+	record, err := s.consumer.ReadMessage(timeout)
+	if err != nil {
+		var kerr kafka.Error
+		if errors.As(err, &kerr) && kerr.Code() == kafka.ErrTimedOut {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return record, nil
+}
+
+// Commit commits the offset after record
+func (s *KafkaSource) Commit(record *kafka.Message) error {
+	_, err := s.consumer.CommitMessage(record)
+	return err
+}
+
+// Rewind seeks the partition back to record
+func (s *KafkaSource) Rewind(record *kafka.Message) error {
+	return s.consumer.Seek(record.TopicPartition, 0)
+}
+
+// Ping checks that the brokers are reachable by fetching the topic metadata
+func (s *KafkaSource) Ping(topic string, timeout time.Duration) error {
+	if _, err := s.consumer.GetMetadata(&topic, false, int(timeout.Milliseconds())); err != nil {
+		return fmt.Errorf("failed to fetch Kafka metadata: %w", err)
+	}
+	return nil
+}
+
+// Lag compares the committed offsets of the assigned partitions with their
+// high watermarks. Partitions without a committed offset count from the low watermark.
+func (s *KafkaSource) Lag(topic string, timeout time.Duration) (*ConsumerLag, error) {
+	assignment, err := s.consumer.Assignment()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get assignment: %w", err)
+	}
+	lag := &ConsumerLag{Topic: topic, Partitions: []PartitionLag{}}
+	if len(assignment) == 0 {
+		return lag, nil
+	}
+	committed, err := s.consumer.Committed(assignment, int(timeout.Milliseconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get committed offsets: %w", err)
+	}
+	for _, tp := range committed {
+		if tp.Topic == nil {
+			continue
+		}
+		low, high, err := s.consumer.QueryWatermarkOffsets(*tp.Topic, tp.Partition, int(timeout.Milliseconds()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to query watermarks of partition %d: %w", tp.Partition, err)
+		}
+		p := PartitionLag{Partition: tp.Partition, Committed: int64(tp.Offset), HighWatermark: high}
+		from := int64(tp.Offset)
+		if from < 0 {
+			p.Committed = -1
+			from = low
+		}
+		p.Lag = max(high-from, 0)
+		lag.TotalLag += p.Lag
+		lag.Partitions = append(lag.Partitions, p)
+	}
+	return lag, nil
+}
+
+// Close leaves the group and closes the Kafka consumer
+func (s *KafkaSource) Close() error {
+	return s.consumer.Close()
+}
@@ -2,33 +2,33 @@ package kafka
 
 import (
 	"context"
-	"encoding/binary"
+	"errors"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
-	"mechanic-service/domain"
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
-	"github.com/hamba/avro/v2"
-	"github.com/riferrei/srclient"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"log/slog"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/attribute"
-	"go.opentelemetry.io/otel/codes"
-	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"mechanic-service/domain"
+	"mechanic-service/kafka/consume"
+	"mechanic-service/region"
 )
 
 // RepairEvent mirrors the Avro schema from repair-service
 type RepairEvent struct {
-	ID          string         `avro:"id"`
-	UserID      string         `avro:"user_id"`
-	Status      string         `avro:"status"`
-	RepairType  string         `avro:"repair_type"`
-	TotalPrice  float64        `avro:"total_price"`
-	UserLocation *Location      `avro:"user_location"`
-	Mechanics   []MechanicInfo `avro:"mechanics"`
+	ID              string         `avro:"id"`
+	UserID          string         `avro:"user_id"`
+	Status          string         `avro:"status"`
+	RepairType      string         `avro:"repair_type"`
+	TotalPrice      float64        `avro:"total_price"`
+	TotalPriceMinor int64          `avro:"total_price_minor"` // 0 from producers predating minor units
+	UserLocation    *Location      `avro:"user_location"`
+	Mechanics       []MechanicInfo `avro:"mechanics"`
+	Symptoms        []Symptom      `avro:"symptoms"`
+	Region          string         `avro:"region"` // empty from producers predating regions
 }
 
 type Location struct {
@@ -43,208 +43,183 @@ type MechanicInfo struct {
 	Distance float64  `avro:"distance"`
 }
 
+// Symptom is a structured intake answer attached to a repair
+type Symptom struct {
+	QuestionID string `avro:"question_id"`
+	Question   string `avro:"question"`
+	Answer     string `avro:"answer"`
+}
+
+// Headers set by repair-service: the outbox event type, and a unique event ID
+// used to drop duplicate deliveries
+const (
+	eventTypeHeader = "event_type"
+	eventIDHeader   = "event_id"
+)
+
+// DeadLetterProducer publishes events that failed permanently to the dead
+// letter topic, e.g. a *kafka.Producer or the Mongo event bus
+type DeadLetterProducer interface {
+	consume.Producer
+	Flush(timeoutMs int) int
+	Close()
+}
+
+// Consumer stores repair events from the event bus in mechanic_outbox, where
+// the outbox processor applies them. Events already in processed_events are
+// skipped; undecodable events and events the database rejects go to the dead
+// letter topic.
 type Consumer struct {
-	kafkaConsumer *kafka.Consumer
-	srClient      *srclient.SchemaRegistryClient
-	schema        avro.Schema
-	topic         string
-	logger        *slog.Logger
-	tracer        trace.Tracer
-	repo          domain.MechanicRepository
+	*consume.Consumer[RepairEvent]
+	Metrics *consume.Metrics
+	dlq     DeadLetterProducer
+	repo    domain.MechanicRepository
+	logger  *slog.Logger
 }
 
-func NewConsumer(bootstrapServers, schemaRegistryURL, topic, groupID string, logger *slog.Logger, repo domain.MechanicRepository) (*Consumer, error) {
-	// Initialize Kafka consumer
-	config := &kafka.ConfigMap{
-		"bootstrap.servers":  bootstrapServers,
-		"group.id":           groupID,
-		"auto.offset.reset":  "earliest",
-		"enable.auto.commit": false, // Disable auto-commit to control commits
-	}
-	c, err := kafka.NewConsumer(config)
+// NewKafkaConsumer creates the repair events consumer for a Kafka consumer
+// group, dead-lettering to Kafka
+func NewKafkaConsumer(bootstrapServers, topic, groupID string, schemas *SchemaResolver, logger *slog.Logger, repo domain.MechanicRepository) (*Consumer, error) {
+	config, err := ClientConfig(bootstrapServers, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create Kafka consumer: %w", err)
+		return nil, err
 	}
-
-	// Initialize Schema Registry client
-	srClient := srclient.CreateSchemaRegistryClient(schemaRegistryURL)
-
-	// Load Avro schema
-	schemaBytes, err := os.ReadFile("repair_event.avsc")
+	source, err := consume.NewKafkaSource(config, groupID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read schema file: %w", err)
+		return nil, err
 	}
-	schema, err := avro.Parse(string(schemaBytes))
+	dlq, err := kafka.NewProducer(config)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse schema: %w", err)
+		source.Close()
+		return nil, fmt.Errorf("failed to create dead letter producer: %w", err)
+	}
+	return NewConsumer(source, dlq, topic, groupID, schemas, logger, repo), nil
+}
+
+// NewConsumer creates the repair events consumer reading topic from source as
+// groupID.
+// Retries and dead-lettering are configured with CONSUMER_MAX_ATTEMPTS
+// (default 3), CONSUMER_RETRY_BACKOFF_MS (default 200) and KAFKA_DLQ_TOPIC
+// (default <topic>-dlq); KAFKA_DLQ_TOPIC is a logical name and gets the
+// environment prefix of TopicName. With REGION_SCOPE set, events of other
+// regions are committed without being stored.
+func NewConsumer(source consume.Source, dlq DeadLetterProducer, topic, groupID string, schemas *SchemaResolver, logger *slog.Logger, repo domain.MechanicRepository) *Consumer {
+	maxAttempts := 3
+	if v, err := strconv.Atoi(os.Getenv("CONSUMER_MAX_ATTEMPTS")); err == nil && v > 0 {
+		maxAttempts = v
 	}
+	retryBackoff := 200 * time.Millisecond
+	if v, err := strconv.Atoi(os.Getenv("CONSUMER_RETRY_BACKOFF_MS")); err == nil && v >= 0 {
+		retryBackoff = time.Duration(v) * time.Millisecond
+	}
+	dlqTopic := topic + "-dlq"
+	if v := os.Getenv("KAFKA_DLQ_TOPIC"); v != "" {
+		dlqTopic = TopicName(v)
+	}
+
+	scope := region.ScopeFromEnv()
+
+	c := &Consumer{
+		Metrics: consume.NewMetrics(),
+		dlq:     dlq,
+		repo:    repo,
+		logger:  logger,
+	}
+	c.Consumer = consume.New(
+		consume.Config{Topic: topic, App: "mechanic-service"},
+		source,
+		decodeRepairEvent(schemas),
+		c.saveOutboxEvent,
+		logger,
+		consume.Tracing[RepairEvent](otel.Tracer("mechanic-service"), "ProcessKafkaMessage", EventBus(), groupID),
+		consume.Region[RepairEvent](region.Header, scope, c.Metrics, logger, "mechanic-service"),
+		consume.Instrument[RepairEvent](c.Metrics),
+		consume.Dedup[RepairEvent](repo, eventIDHeader, c.Metrics, logger, "mechanic-service"),
+		consume.DeadLetter[RepairEvent](dlq, dlqTopic, c.Metrics, logger, "mechanic-service"),
+		consume.Retry[RepairEvent](maxAttempts, retryBackoff, c.Metrics),
+	)
+	logger.Info("Configured Kafka consumer", "topic", topic, "maxAttempts", maxAttempts, "retryBackoff", retryBackoff, "dlqTopic", dlqTopic, "regions", scope.Names(), "app", "mechanic-service")
+	return c
+}
 
-	return &Consumer{
-		kafkaConsumer: c,
-		srClient:      srClient,
-		schema:        schema,
-		topic:         topic,
-		logger:        logger,
-		tracer:        otel.Tracer("mechanic-service"),
-		repo:          repo,
-	}, nil
+// decodeRepairEvent decodes Avro repair events; tombstones of erased repairs
+// have no value and decode to an empty event
+func decodeRepairEvent(schemas *SchemaResolver) consume.DecodeFunc[RepairEvent] {
+	decode := consume.Avro[RepairEvent](schemas)
+	return func(payload []byte) (RepairEvent, error) {
+		if len(payload) == 0 {
+			return RepairEvent{}, nil
+		}
+		return decode(payload)
+	}
 }
 
-// Start begins consuming messages from the Kafka topic
-func (c *Consumer) Start(ctx context.Context) error {
-	_, span := c.tracer.Start(ctx, "KafkaConsumerStart")
-	defer span.End()
+// saveOutboxEvent stores the record in mechanic_outbox and marks its event ID
+// processed in one transaction, unless an earlier delivery of the same topic,
+// partition and offset already stored it
+func (c *Consumer) saveOutboxEvent(ctx context.Context, msg *consume.Message[RepairEvent]) error {
+	record := msg.Record
+	tp := record.TopicPartition
+
+	err := c.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		// Check if outbox event already exists
+		exists, err := c.repo.CheckOutboxEventExists(tx, *tp.Topic, tp.Partition, int64(tp.Offset))
+		if err != nil {
+			return fmt.Errorf("failed to check outbox event existence: %w", err)
+		}
+		if exists {
+			c.logger.Info("Outbox event already exists, skipping", "topic", *tp.Topic, "partition", tp.Partition, "offset", tp.Offset, "app", "mechanic-service")
+			return nil
+		}
 
-	// Subscribe to the topic
-	err := c.kafkaConsumer.SubscribeTopics([]string{c.topic}, nil)
+		// Save the outbox event, keeping the producer's event type and the
+		// broker timestamp for delivery metrics
+		eventType := msg.Header(eventTypeHeader)
+		payload := record.Value
+		switch {
+		case len(payload) == 0:
+			eventType = domain.EventRepairErased
+			payload = []byte{} // stored as empty binary rather than null
+		case eventType == "":
+			eventType = "RepairEvent"
+		}
+		outboxEvent := &domain.OutboxEvent{
+			ID:             primitive.NewObjectID().Hex(),
+			EventType:      eventType,
+			AggregateID:    string(record.Key),
+			Payload:        payload,
+			CreatedAt:      time.Now(),
+			Processed:      false,
+			KafkaTopic:     *tp.Topic,
+			KafkaPartition: tp.Partition,
+			KafkaOffset:    int64(tp.Offset),
+			KafkaTimestamp: record.Timestamp,
+			EventID:        msg.EventID,
+		}
+		if err := c.repo.SaveOutboxEvent(tx, outboxEvent); err != nil {
+			return fmt.Errorf("failed to save outbox event: %w", err)
+		}
+		if err := c.repo.MarkEventProcessed(tx, msg.EventID); err != nil {
+			return err
+		}
+		c.logger.Info("Saved outbox event in transaction", "eventID", outboxEvent.ID, "repairID", msg.Event.ID, "topic", outboxEvent.KafkaTopic, "partition", outboxEvent.KafkaPartition, "offset", outboxEvent.KafkaOffset, "app", "mechanic-service")
+		return nil
+	})
 	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to subscribe to topic")
-		c.logger.Error("Failed to subscribe to topic", "topic", c.topic, "error", err, "app", "mechanic-service")
-		return fmt.Errorf("failed to subscribe to topic: %w", err)
-	}
-	c.logger.Info("Subscribed to Kafka topic", "topic", c.topic, "app", "mechanic-service")
-
-	for {
-		select {
-		case <-ctx.Done():
-			c.logger.Info("Context canceled, stopping Kafka consumer", "app", "mechanic-service")
-			return ctx.Err()
-		default:
-			msg, err := c.kafkaConsumer.ReadMessage(-1)
-			if err != nil {
-				c.logger.Error("Error reading Kafka message", "error", err, "app", "mechanic-service")
-				continue
-			}
-
-			_, span := c.tracer.Start(ctx, "ProcessKafkaMessage")
-			// Deserialize Avro message
-			if len(msg.Value) < 5 {
-				span.RecordError(fmt.Errorf("invalid message length"))
-				span.SetStatus(codes.Error, "Invalid message length")
-				c.logger.Error("Invalid message length", "length", len(msg.Value), "app", "mechanic-service")
-				span.End()
-				continue
-			}
-
-			// Extract schema ID (skip magic byte)
-			schemaID := int(binary.BigEndian.Uint32(msg.Value[1:5]))
-			span.SetAttributes(
-				attribute.String("topic", *msg.TopicPartition.Topic),
-				attribute.Int("partition", int(msg.TopicPartition.Partition)),
-				attribute.Int64("offset", int64(msg.TopicPartition.Offset)),
-				attribute.Int("schemaID", schemaID),
-			)
-
-			// Fetch schema if not already loaded
-			if c.schema == nil {
-				schemaObj, err := c.srClient.GetSchema(schemaID)
-				if err != nil {
-					span.RecordError(err)
-					span.SetStatus(codes.Error, "Failed to fetch schema")
-					c.logger.Error("Failed to fetch schema", "schemaID", schemaID, "error", err, "app", "mechanic-service")
-					span.End()
-					continue
-				}
-				c.schema, err = avro.Parse(schemaObj.Schema())
-				if err != nil {
-					span.RecordError(err)
-					span.SetStatus(codes.Error, "Failed to parse schema")
-					c.logger.Error("Failed to parse schema", "schemaID", schemaID, "error", err, "app", "mechanic-service")
-					span.End()
-					continue
-				}
-			}
-
-			// Start a transaction to check and save outbox event
-			session, err := c.repo.GetMongoClient(ctx).StartSession()
-			if err != nil {
-				span.RecordError(err)
-				span.SetStatus(codes.Error, "Failed to start MongoDB session")
-				c.logger.Error("Failed to start MongoDB session", "error", err, "app", "mechanic-service")
-				span.End()
-				continue
-			}
-			defer session.EndSession(ctx)
-
-			err = session.StartTransaction()
-			if err != nil {
-				span.RecordError(err)
-				span.SetStatus(codes.Error, "Failed to start transaction")
-				c.logger.Error("Failed to start transaction", "error", err, "app", "mechanic-service")
-				span.End()
-				continue
-			}
-
-			err = mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
-				// Check if outbox event already exists
-				exists, err := c.repo.CheckOutboxEventExists(ctx, sc, *msg.TopicPartition.Topic, msg.TopicPartition.Partition, int64(msg.TopicPartition.Offset))
-				if err != nil {
-					c.logger.Error("Failed to check outbox event existence", "topic", *msg.TopicPartition.Topic, "partition", msg.TopicPartition.Partition, "offset", msg.TopicPartition.Offset, "error", err, "app", "mechanic-service")
-					return fmt.Errorf("failed to check outbox event existence: %w", err)
-				}
-				if exists {
-					c.logger.Info("Outbox event already exists, skipping", "topic", *msg.TopicPartition.Topic, "partition", msg.TopicPartition.Partition, "offset", msg.TopicPartition.Offset, "app", "mechanic-service")
-					return nil
-				}
-
-				// Save the outbox event
-				outboxEvent := &domain.OutboxEvent{
-					ID:             primitive.NewObjectID().Hex(),
-					EventType:      "RepairEvent",
-					Payload:        msg.Value,
-					CreatedAt:      time.Now(),
-					Processed:      false,
-					KafkaTopic:     *msg.TopicPartition.Topic,
-					KafkaPartition: msg.TopicPartition.Partition,
-					KafkaOffset:    int64(msg.TopicPartition.Offset),
-				}
-				if err := c.repo.SaveOutboxEvent(ctx, sc, outboxEvent); err != nil {
-					return fmt.Errorf("failed to save outbox event: %w", err)
-				}
-				c.logger.Info("Saved outbox event in transaction", "eventID", outboxEvent.ID, "topic", outboxEvent.KafkaTopic, "partition", outboxEvent.KafkaPartition, "offset", outboxEvent.KafkaOffset, "app", "mechanic-service")
-				return nil
-			})
-			if err != nil {
-				span.RecordError(err)
-				span.SetStatus(codes.Error, "Transaction failed")
-				c.logger.Error("Transaction failed", "error", err, "app", "mechanic-service")
-				session.AbortTransaction(ctx)
-				span.End()
-				continue
-			}
-
-			if err := session.CommitTransaction(ctx); err != nil {
-				span.RecordError(err)
-				span.SetStatus(codes.Error, "Failed to commit transaction")
-				c.logger.Error("Failed to commit transaction", "error", err, "app", "mechanic-service")
-				span.End()
-				continue
-			}
-
-			// Commit Kafka offset
-			_, err = c.kafkaConsumer.CommitMessage(msg)
-			if err != nil {
-				span.RecordError(err)
-				span.SetStatus(codes.Error, "Failed to commit Kafka offset")
-				c.logger.Error("Failed to commit Kafka offset", "topic", *msg.TopicPartition.Topic, "partition", msg.TopicPartition.Partition, "offset", msg.TopicPartition.Offset, "error", err, "app", "mechanic-service")
-				span.End()
-				continue
-			}
-
-			c.logger.Info("Committed Kafka message and outbox event",
-				"topic", *msg.TopicPartition.Topic,
-				"partition", msg.TopicPartition.Partition,
-				"offset", msg.TopicPartition.Offset,
-				"app", "mechanic-service")
-			span.End()
+		// Redelivering cannot store a record the database rejects, e.g. one
+		// over the document size limit; transient failures are redelivered
+		if errors.Is(err, domain.ErrFatal) {
+			return consume.Permanent(fmt.Errorf("transaction failed: %w", err))
 		}
+		return fmt.Errorf("transaction failed: %w", err)
 	}
+	return nil
 }
 
-// Close shuts down the Kafka consumer
+// Close stops consuming once the in-flight message is committed and flushes
+// the dead letter producer. Cancel the context passed to Run first.
 func (c *Consumer) Close() {
-	c.logger.Info("Closing Kafka consumer", "app", "mechanic-service")
-	c.kafkaConsumer.Close()
+	c.Consumer.Close(10 * time.Second)
+	c.dlq.Flush(5000)
+	c.dlq.Close()
 }
-
@@ -5,37 +5,54 @@ import (
 	"fmt"
 	"time"
 
-	"mechanic-service/domain"
-	"github.com/hamba/avro/v2"
-	"log/slog"
-	"go.mongodb.org/mongo-driver/mongo"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"log/slog"
+	"mechanic-service/domain"
 )
 
 // OutboxProcessor processes events from the outbox collection
 type OutboxProcessor struct {
-	repo   domain.MechanicRepository
-	logger *slog.Logger
-	schema avro.Schema
+	repo    domain.MechanicRepository
+	logger  *slog.Logger
+	schemas *SchemaResolver
+	pool    *keyedWorkerPool
+	latency *DeliveryMetrics // Kafka delivery to repair persistence
 }
 
-// NewOutboxProcessor creates a new OutboxProcessor
-func NewOutboxProcessor(repo domain.MechanicRepository, logger *slog.Logger, schema avro.Schema) *OutboxProcessor {
+// NewOutboxProcessor creates a new OutboxProcessor that applies events with
+// the given number of parallel workers and per-worker queue depth
+func NewOutboxProcessor(repo domain.MechanicRepository, logger *slog.Logger, schemas *SchemaResolver, concurrency, queueDepth int) *OutboxProcessor {
 	return &OutboxProcessor{
-		repo:   repo,
-		logger: logger,
-		schema: schema,
+		repo:    repo,
+		logger:  logger,
+		schemas: schemas,
+		pool:    newKeyedWorkerPool(concurrency, queueDepth),
+		latency: NewDeliveryMetrics(),
 	}
 }
 
+// WorkerStats returns per-worker processing counters
+func (p *OutboxProcessor) WorkerStats() []WorkerStats {
+	return p.pool.Stats()
+}
+
+// PersistenceLatency returns, per event type, the latency from the Kafka
+// message timestamp to the repair being persisted
+func (p *OutboxProcessor) PersistenceLatency() map[string]LatencyStats {
+	return p.latency.Snapshot()
+}
+
 // Start begins processing outbox events
 func (p *OutboxProcessor) Start(ctx context.Context) error {
 	_, span := otel.Tracer("mechanic-service").Start(ctx, "OutboxProcessorStart")
 	defer span.End()
 
-	p.logger.Info("Outbox processor started", "app", "mechanic-service")
+	p.pool.start()
+	defer p.pool.stop()
+	p.logger.Info("Outbox processor started", "workers", len(p.pool.workers), "app", "mechanic-service")
 	ticker := time.NewTicker(5 * time.Second)
 	defer ticker.Stop()
 
@@ -55,7 +72,7 @@ func (p *OutboxProcessor) Start(ctx context.Context) error {
 
 // processOutboxEvents retrieves and processes unprocessed outbox events
 func (p *OutboxProcessor) processOutboxEvents(ctx context.Context) error {
-	_, span := otel.Tracer("mechanic-service").Start(ctx, "ProcessOutboxEvents")
+	ctx, span := otel.Tracer("mechanic-service").Start(ctx, "ProcessOutboxEvents")
 	defer span.End()
 
 	events, err := p.repo.GetUnprocessedOutboxEvents(ctx)
@@ -72,143 +89,212 @@ func (p *OutboxProcessor) processOutboxEvents(ctx context.Context) error {
 
 	p.logger.Info("Found unprocessed outbox events", "count", len(events), "app", "mechanic-service")
 	for _, event := range events {
-		_, eventSpan := otel.Tracer("mechanic-service").Start(ctx, "ProcessOutboxEvent")
-		eventSpan.SetAttributes(
-			attribute.String("eventID", event.ID),
-			attribute.String("eventType", event.EventType),
-		)
-
-		// Deserialize the event payload
-		var repairEvent RepairEvent
-		if len(event.Payload) < 5 {
-			err := fmt.Errorf("invalid payload length: %d", len(event.Payload))
-			eventSpan.RecordError(err)
-			eventSpan.SetStatus(codes.Error, "Invalid payload length")
-			p.logger.Error("Invalid payload length", "eventID", event.ID, "length", len(event.Payload), "app", "mechanic-service")
-			eventSpan.End()
-			continue
-		}
-		err := avro.Unmarshal(p.schema, event.Payload[5:], &repairEvent)
-		if err != nil {
-			eventSpan.RecordError(err)
-			eventSpan.SetStatus(codes.Error, "Failed to deserialize event")
-			p.logger.Error("Failed to deserialize event", "eventID", event.ID, "error", err, "payload", fmt.Sprintf("%x", event.Payload), "app", "mechanic-service")
-			eventSpan.End()
-			continue
+		key := event.AggregateID
+		if key == "" {
+			key = event.ID
 		}
+		p.pool.submit(key, func() error {
+			return p.processEvent(ctx, event)
+		})
+	}
+	p.pool.wait()
 
-		// Convert RepairEvent to domain.Repair
-		var userLocation *domain.Location
-		if repairEvent.UserLocation != nil {
-			userLocation = &domain.Location{
-				Longitude: repairEvent.UserLocation.Longitude,
-				Latitude:  repairEvent.UserLocation.Latitude,
-			}
-		}
-		mechanics := make([]domain.MechanicInfo, len(repairEvent.Mechanics))
-		for i, m := range repairEvent.Mechanics {
-			mechanics[i] = domain.MechanicInfo{
-				ID:       m.ID,
-				Name:     m.Name,
-				Location: domain.Location{
-					Longitude: m.Location.Longitude,
-					Latitude:  m.Location.Latitude,
-				},
-				Distance: m.Distance,
-			}
+	span.SetAttributes(
+		attribute.Int("processedEventCount", len(events)),
+	)
+	return nil
+}
+
+// processEvent applies a single outbox event to the repairs projection
+func (p *OutboxProcessor) processEvent(ctx context.Context, event *domain.OutboxEvent) error {
+	ctx, eventSpan := otel.Tracer("mechanic-service").Start(ctx, "ProcessOutboxEvent")
+	eventSpan.SetAttributes(
+		attribute.String("eventID", event.ID),
+		attribute.String("eventType", event.EventType),
+	)
+
+	// Tombstones of erased repairs carry no payload
+	if len(event.Payload) == 0 {
+		defer eventSpan.End()
+		return p.eraseRepair(ctx, event)
+	}
+
+	// Deserialize the event payload
+	var repairEvent RepairEvent
+	err := p.schemas.Decode(event.Payload, &repairEvent)
+	if err != nil {
+		eventSpan.RecordError(err)
+		eventSpan.SetStatus(codes.Error, "Failed to deserialize event")
+		p.logger.Error("Failed to deserialize event", "eventID", event.ID, "error", err, "payload", fmt.Sprintf("%x", event.Payload), "app", "mechanic-service")
+		eventSpan.End()
+		return err
+	}
+
+	// Cancelled and deleted repairs are marked or removed, not inserted
+	if event.EventType == domain.EventRepairCancelled {
+		defer eventSpan.End()
+		return p.cancelRepair(ctx, event, &repairEvent)
+	}
+
+	// Convert RepairEvent to domain.Repair
+	var userLocation *domain.Location
+	if repairEvent.UserLocation != nil {
+		userLocation = &domain.Location{
+			Longitude: repairEvent.UserLocation.Longitude,
+			Latitude:  repairEvent.UserLocation.Latitude,
 		}
-		repair := &domain.Repair{
-			ID:     repairEvent.ID,
-			UserID: repairEvent.UserID,
-			Status: repairEvent.Status,
-			RepairCost: &domain.RepairCost{
-				ID:           repairEvent.ID, // Assuming same ID for simplicity
-				UserID:       repairEvent.UserID,
-				RepairType:   repairEvent.RepairType,
-				TotalPrice:   repairEvent.TotalPrice,
-				UserLocation: userLocation,
-				Mechanics:    mechanics,
+	}
+	mechanics := make([]domain.MechanicInfo, len(repairEvent.Mechanics))
+	for i, m := range repairEvent.Mechanics {
+		mechanics[i] = domain.MechanicInfo{
+			ID:   m.ID,
+			Name: m.Name,
+			Location: domain.Location{
+				Longitude: m.Location.Longitude,
+				Latitude:  m.Location.Latitude,
 			},
+			Distance: m.Distance,
 		}
+	}
+	var symptoms []domain.SymptomAnswer
+	for _, sym := range repairEvent.Symptoms {
+		symptoms = append(symptoms, domain.SymptomAnswer{
+			QuestionID: sym.QuestionID,
+			Question:   sym.Question,
+			Answer:     sym.Answer,
+		})
+	}
+	repair := &domain.Repair{
+		ID:       repairEvent.ID,
+		UserID:   repairEvent.UserID,
+		Status:   repairEvent.Status,
+		Symptoms: symptoms,
+		Region:   repairEvent.Region,
+		RepairCost: &domain.RepairCost{
+			ID:           repairEvent.ID, // Assuming same ID for simplicity
+			UserID:       repairEvent.UserID,
+			RepairType:   repairEvent.RepairType,
+			TotalPrice:   domain.PriceFromMinor(repairEvent.TotalPriceMinor, repairEvent.TotalPrice),
+			UserLocation: userLocation,
+			Mechanics:    mechanics,
+		},
+	}
 
-		// Start a transaction to check and insert repair
-		session, err := p.repo.GetMongoClient(ctx).StartSession()
-		if err != nil {
-			eventSpan.RecordError(err)
-			eventSpan.SetStatus(codes.Error, "Failed to start MongoDB session")
-			p.logger.Error("Failed to start MongoDB session", "eventID", event.ID, "error", err, "app", "mechanic-service")
-			eventSpan.End()
-			continue
-		}
-		defer session.EndSession(ctx)
-
-		err = session.StartTransaction()
+	// Start a transaction to check and insert repair
+	err = p.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		// Check if repair already exists
+		exists, err := p.repo.CheckRepairExists(tx, repair.ID)
 		if err != nil {
-			eventSpan.RecordError(err)
-			eventSpan.SetStatus(codes.Error, "Failed to start transaction")
-			p.logger.Error("Failed to start transaction", "eventID", event.ID, "error", err, "app", "mechanic-service")
-			eventSpan.End()
-			continue
+			p.logger.Error("Failed to check repair existence", "repairID", repair.ID, "error", err, "app", "mechanic-service")
+			return fmt.Errorf("failed to check existing repair: %w", err)
 		}
-
-		err = mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
-			// Check if repair already exists
-			exists, err := p.repo.CheckRepairExists(ctx, sc, repair.ID)
+		if exists {
+			// Kafka is the primary path: a copy bridged from the change stream
+			// while Kafka was down is replaced by the full event
+			replaced, err := p.repo.ReplaceCDCRepair(tx, repair)
 			if err != nil {
-				p.logger.Error("Failed to check repair existence", "repairID", repair.ID, "error", err, "app", "mechanic-service")
-				return fmt.Errorf("failed to check existing repair: %w", err)
+				p.logger.Error("Failed to replace catch-up repair", "repairID", repair.ID, "error", err, "app", "mechanic-service")
+				return fmt.Errorf("failed to replace catch-up repair: %w", err)
 			}
-			if exists {
+			if replaced {
+				p.logger.Info("Replaced catch-up repair with Kafka event", "repairID", repair.ID, "app", "mechanic-service")
+			} else {
 				p.logger.Info("Repair already exists, skipping insert", "repairID", repair.ID, "app", "mechanic-service")
-				// Mark the outbox event as processed even if repair exists
-				if err := p.repo.MarkOutboxEventProcessed(ctx, event.ID); err != nil {
-					p.logger.Error("Failed to mark outbox event as processed", "eventID", event.ID, "error", err, "app", "mechanic-service")
-					return fmt.Errorf("failed to mark outbox event as processed: %w", err)
-				}
-				p.logger.Info("Marked outbox event as processed in transaction", "eventID", event.ID, "app", "mechanic-service")
-				return nil
 			}
-
-			// Insert the repair
-			if err := p.repo.InsertRepair(ctx, sc, repair); err != nil {
-				p.logger.Error("Failed to insert repair", "repairID", repair.ID, "error", err, "app", "mechanic-service")
-				return fmt.Errorf("failed to insert repair: %w", err)
-			}
-			p.logger.Info("Inserted repair in transaction", "repairID", repair.ID, "app", "mechanic-service")
-
-			// Mark the outbox event as processed
+			// Mark the outbox event as processed even if repair exists
 			if err := p.repo.MarkOutboxEventProcessed(ctx, event.ID); err != nil {
 				p.logger.Error("Failed to mark outbox event as processed", "eventID", event.ID, "error", err, "app", "mechanic-service")
 				return fmt.Errorf("failed to mark outbox event as processed: %w", err)
 			}
 			p.logger.Info("Marked outbox event as processed in transaction", "eventID", event.ID, "app", "mechanic-service")
-
 			return nil
-		})
-		if err != nil {
-			eventSpan.RecordError(err)
-			eventSpan.SetStatus(codes.Error, "Transaction failed")
-			p.logger.Error("Transaction failed", "eventID", event.ID, "error", err, "app", "mechanic-service")
-			session.AbortTransaction(ctx)
-			eventSpan.End()
-			continue
 		}
 
-		if err := session.CommitTransaction(ctx); err != nil {
-			eventSpan.RecordError(err)
-			eventSpan.SetStatus(codes.Error, "Failed to commit transaction")
-			p.logger.Error("Failed to commit transaction", "eventID", event.ID, "error", err, "app", "mechanic-service")
-			eventSpan.End()
-			continue
+		// Insert the repair
+		if err := p.repo.InsertRepair(tx, repair); err != nil {
+			p.logger.Error("Failed to insert repair", "repairID", repair.ID, "error", err, "app", "mechanic-service")
+			return fmt.Errorf("failed to insert repair: %w", err)
+		}
+		p.logger.Info("Inserted repair in transaction", "repairID", repair.ID, "app", "mechanic-service")
+
+		// Mark the outbox event as processed
+		if err := p.repo.MarkOutboxEventProcessed(ctx, event.ID); err != nil {
+			p.logger.Error("Failed to mark outbox event as processed", "eventID", event.ID, "error", err, "app", "mechanic-service")
+			return fmt.Errorf("failed to mark outbox event as processed: %w", err)
 		}
+		p.logger.Info("Marked outbox event as processed in transaction", "eventID", event.ID, "app", "mechanic-service")
 
-		p.logger.Info("Committed transaction for outbox event", "eventID", event.ID, "repairID", repair.ID, "app", "mechanic-service")
+		return nil
+	})
+	if err != nil {
+		eventSpan.RecordError(err)
+		eventSpan.SetStatus(codes.Error, "Transaction failed")
+		p.logger.Error("Transaction failed", "eventID", event.ID, "error", err, "app", "mechanic-service")
 		eventSpan.End()
+		return err
 	}
 
-	span.SetAttributes(
-		attribute.Int("processedEventCount", len(events)),
-	)
+	if !event.KafkaTimestamp.IsZero() {
+		p.latency.Observe(event.EventType, time.Since(event.KafkaTimestamp))
+	}
+	p.logger.Info("Committed transaction for outbox event", "eventID", event.ID, "repairID", repair.ID, "app", "mechanic-service")
+	eventSpan.End()
+	return nil
+}
+
+// cancelRepair marks the repair of a RepairCancelled event cancelled, or
+// removes it when it was deleted, and marks the event processed in one
+// transaction
+func (p *OutboxProcessor) cancelRepair(ctx context.Context, event *domain.OutboxEvent, repairEvent *RepairEvent) error {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("repairID", repairEvent.ID), attribute.String("status", repairEvent.Status))
+
+	deleted := repairEvent.Status == domain.RepairStatusDeleted
+	err := p.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		var err error
+		if deleted {
+			err = p.repo.DeleteRepair(tx, repairEvent.ID)
+		} else {
+			err = p.repo.CancelRepair(tx, repairEvent.ID)
+		}
+		if err != nil {
+			return err
+		}
+		return p.repo.MarkOutboxEventProcessed(tx, event.ID)
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to cancel repair")
+		p.logger.Error("Failed to cancel repair", "eventID", event.ID, "repairID", repairEvent.ID, "deleted", deleted, "error", err, "app", "mechanic-service")
+		return err
+	}
+	if !event.KafkaTimestamp.IsZero() {
+		p.latency.Observe(event.EventType, time.Since(event.KafkaTimestamp))
+	}
+	p.logger.Info("Applied repair cancellation", "eventID", event.ID, "repairID", repairEvent.ID, "deleted", deleted, "app", "mechanic-service")
 	return nil
 }
 
+// eraseRepair anonymizes the repair of a tombstone event and marks the event
+// processed in one transaction
+func (p *OutboxProcessor) eraseRepair(ctx context.Context, event *domain.OutboxEvent) error {
+	span := trace.SpanFromContext(ctx)
+	span.SetAttributes(attribute.String("repairID", event.AggregateID))
+
+	err := p.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		if event.AggregateID != "" {
+			if err := p.repo.AnonymizeRepair(tx, event.AggregateID); err != nil {
+				return err
+			}
+		}
+		return p.repo.MarkOutboxEventProcessed(ctx, event.ID)
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to erase repair")
+		p.logger.Error("Failed to erase repair", "eventID", event.ID, "repairID", event.AggregateID, "error", err, "app", "mechanic-service")
+		return err
+	}
+	p.logger.Info("Anonymized repair of erased user", "eventID", event.ID, "repairID", event.AggregateID, "app", "mechanic-service")
+	return nil
+}
@@ -0,0 +1,171 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// RetentionPolicy is the cleanup configuration of the repair-events topic.
+// Events only need to be kept until a projection snapshot covers them, so
+// the topic can be compacted or expire events once snapshots are taken.
+type RetentionPolicy struct {
+	CleanupPolicy    string        // "delete", "compact" or "compact,delete"; empty leaves the topic alone
+	Retention        time.Duration // retention.ms of deleting policies
+	SnapshotInterval time.Duration // how often projection snapshots are taken
+}
+
+// RetentionPolicyFromEnv reads REPAIR_EVENTS_CLEANUP_POLICY and
+// REPAIR_EVENTS_RETENTION_HOURS for snapshots taken every snapshotInterval
+func RetentionPolicyFromEnv(snapshotInterval time.Duration) RetentionPolicy {
+	policy := RetentionPolicy{
+		CleanupPolicy:    strings.ReplaceAll(strings.ToLower(os.Getenv("REPAIR_EVENTS_CLEANUP_POLICY")), " ", ""),
+		SnapshotInterval: snapshotInterval,
+	}
+	if v, err := strconv.Atoi(os.Getenv("REPAIR_EVENTS_RETENTION_HOURS")); err == nil && v > 0 {
+		policy.Retention = time.Duration(v) * time.Hour
+	}
+	return policy
+}
+
+// Enabled reports whether the policy changes the topic
+func (p RetentionPolicy) Enabled() bool {
+	return p.CleanupPolicy != ""
+}
+
+// safeLag is how long events must survive: two snapshot intervals, so the
+// tail after the latest snapshot is kept even when one snapshot fails
+func (p RetentionPolicy) safeLag() time.Duration {
+	return 2 * p.SnapshotInterval
+}
+
+// Check rejects policies that could drop events after the latest snapshot:
+// deleting needs snapshots and a retention of at least two snapshot intervals
+func (p RetentionPolicy) Check() error {
+	switch p.CleanupPolicy {
+	case "", "compact", "delete", "compact,delete", "delete,compact":
+	default:
+		return fmt.Errorf("invalid cleanup policy %q, expected delete, compact or compact,delete", p.CleanupPolicy)
+	}
+	if p.Enabled() && p.SnapshotInterval <= 0 {
+		return fmt.Errorf("cleanup policy %q needs projection snapshots, set PROJECTION_SNAPSHOT_INTERVAL_MINUTES", p.CleanupPolicy)
+	}
+	if strings.Contains(p.CleanupPolicy, "delete") {
+		if p.Retention <= 0 {
+			return fmt.Errorf("cleanup policy %q needs REPAIR_EVENTS_RETENTION_HOURS", p.CleanupPolicy)
+		}
+		if p.Retention < p.safeLag() {
+			return fmt.Errorf("retention %s is shorter than two snapshot intervals (%s), events after the latest snapshot could be deleted", p.Retention, p.safeLag())
+		}
+	}
+	return nil
+}
+
+// configEntries returns the topic configuration of the policy. Compaction
+// leaves records younger than two snapshot intervals alone and keeps
+// tombstones as long, so a restore replays every change after its snapshot.
+func (p RetentionPolicy) configEntries() []kafka.ConfigEntry {
+	set := func(name string, value string) kafka.ConfigEntry {
+		return kafka.ConfigEntry{Name: name, Value: value, IncrementalOperation: kafka.AlterConfigOpTypeSet}
+	}
+	entries := []kafka.ConfigEntry{set("cleanup.policy", strings.ReplaceAll(p.CleanupPolicy, "delete,compact", "compact,delete"))}
+	if strings.Contains(p.CleanupPolicy, "delete") {
+		entries = append(entries, set("retention.ms", strconv.FormatInt(p.Retention.Milliseconds(), 10)))
+	}
+	if strings.Contains(p.CleanupPolicy, "compact") {
+		lag := strconv.FormatInt(p.safeLag().Milliseconds(), 10)
+		entries = append(entries, set("min.compaction.lag.ms", lag), set("delete.retention.ms", lag))
+	}
+	return entries
+}
+
+// ConfigureRetention applies the policy to the physical topic of a logical
+// topic, after checking it is safe. Call it once a snapshot has been taken.
+func ConfigureRetention(ctx context.Context, bootstrapServers, logical string, policy RetentionPolicy, logger *slog.Logger) error {
+	if err := policy.Check(); err != nil {
+		return err
+	}
+	if !policy.Enabled() {
+		return nil
+	}
+	config, err := ClientConfig(bootstrapServers, nil)
+	if err != nil {
+		return err
+	}
+	admin, err := kafka.NewAdminClient(config)
+	if err != nil {
+		return fmt.Errorf("failed to create Kafka admin client: %w", err)
+	}
+	defer admin.Close()
+
+	topic := TopicName(logical)
+	entries := policy.configEntries()
+	results, err := admin.IncrementalAlterConfigs(ctx, []kafka.ConfigResource{{Type: kafka.ResourceTopic, Name: topic, Config: entries}})
+	if err != nil {
+		return fmt.Errorf("failed to configure Kafka topic %s: %w", topic, err)
+	}
+	for _, result := range results {
+		if result.Error.Code() != kafka.ErrNoError {
+			return fmt.Errorf("failed to configure Kafka topic %s: %w", result.Name, result.Error)
+		}
+	}
+	attrs := []any{"topic", topic, "app", "mechanic-service"}
+	for _, entry := range entries {
+		attrs = append(attrs, entry.Name, entry.Value)
+	}
+	logger.Info("Configured Kafka topic retention", attrs...)
+	return nil
+}
+
+// RewindGroup commits the offset after each partition's entry in applied for
+// groupID, so the group consumes the topic again from there; partitions
+// without an entry restart at their first retained event. It fails when the
+// topic no longer holds the events right after applied. Members of the group
+// must be stopped.
+func RewindGroup(bootstrapServers, logical, groupID string, applied map[int32]int64, timeout time.Duration) ([]kafka.TopicPartition, error) {
+	config, err := ClientConfig(bootstrapServers, kafka.ConfigMap{"group.id": groupID, "enable.auto.commit": false})
+	if err != nil {
+		return nil, err
+	}
+	consumer, err := kafka.NewConsumer(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kafka consumer: %w", err)
+	}
+	defer consumer.Close()
+
+	topic := TopicName(logical)
+	metadata, err := consumer.GetMetadata(&topic, false, int(timeout.Milliseconds()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch Kafka metadata: %w", err)
+	}
+	partitions := metadata.Topics[topic].Partitions
+	if len(partitions) == 0 {
+		return nil, fmt.Errorf("topic %s has no partitions", topic)
+	}
+
+	offsets := make([]kafka.TopicPartition, 0, len(partitions))
+	for _, p := range partitions {
+		low, _, err := consumer.QueryWatermarkOffsets(topic, p.ID, int(timeout.Milliseconds()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to query watermarks of partition %d: %w", p.ID, err)
+		}
+		next := low
+		if offset, ok := applied[p.ID]; ok {
+			next = offset + 1
+		}
+		if next < low {
+			return nil, fmt.Errorf("partition %d starts at offset %d, the events from %d on were already deleted; restore a newer snapshot", p.ID, low, next)
+		}
+		offsets = append(offsets, kafka.TopicPartition{Topic: &topic, Partition: p.ID, Offset: kafka.Offset(next)})
+	}
+	if _, err := consumer.CommitOffsets(offsets); err != nil {
+		return nil, fmt.Errorf("failed to commit offsets of group %s: %w", groupID, err)
+	}
+	return offsets, nil
+}
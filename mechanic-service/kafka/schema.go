@@ -0,0 +1,90 @@
+package kafka
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hamba/avro/v2"
+	"github.com/riferrei/srclient"
+)
+
+// Sources LoadSchema reports the schema came from
+const (
+	SchemaFromRegistry = "registry"
+	SchemaFromCache    = "cache"    // the copy of the last registry lookup in SCHEMA_CACHE_DIR
+	SchemaEmbedded     = "embedded" // repair_event.avsc built into the binary
+)
+
+//go:embed repair_event.avsc
+var embeddedSchema string
+
+// LoadedSchema is a repair event schema and where it was loaded from
+type LoadedSchema struct {
+	Schema avro.Schema
+	Source string
+	Err    error // why the registry was not used, nil when it was
+}
+
+// LoadSchema returns the latest version of subject from schema-registry and
+// caches it in SCHEMA_CACHE_DIR. While the registry is unreachable the cached
+// copy is used, and without one the schema embedded in the binary, so the
+// service never depends on a schema file in its working directory. An empty
+// registryURL returns the embedded schema: the Mongo event bus frames payloads
+// with LocalSchemaID, written with the producer's embedded schema.
+func LoadSchema(registryURL, subject string) (*LoadedSchema, error) {
+	var registryErr error
+	if registryURL != "" {
+		latest, err := srclient.CreateSchemaRegistryClient(registryURL).GetLatestSchema(subject)
+		if err == nil {
+			schema, err := avro.Parse(latest.Schema())
+			if err == nil {
+				storeLatestSchema(subject, latest.Schema())
+				return &LoadedSchema{Schema: schema, Source: SchemaFromRegistry}, nil
+			}
+			registryErr = fmt.Errorf("failed to parse latest schema of %s: %w", subject, err)
+		} else {
+			registryErr = fmt.Errorf("failed to fetch latest schema of %s: %w", subject, err)
+		}
+	}
+
+	if path := latestSchemaPath(subject); path != "" && registryURL != "" {
+		// An unreadable or corrupt cache file falls through to the embedded schema
+		if text, err := os.ReadFile(path); err == nil {
+			if schema, err := avro.Parse(string(text)); err == nil {
+				return &LoadedSchema{Schema: schema, Source: SchemaFromCache, Err: registryErr}, nil
+			}
+		}
+	}
+	schema, err := avro.Parse(embeddedSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse embedded schema: %w", err)
+	}
+	return &LoadedSchema{Schema: schema, Source: SchemaEmbedded, Err: registryErr}, nil
+}
+
+func latestSchemaPath(subject string) string {
+	dir := os.Getenv("SCHEMA_CACHE_DIR")
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, subject+".latest.avsc")
+}
+
+// storeLatestSchema writes the latest schema of subject to the disk cache;
+// failures only cost the fallback to the embedded schema, so they are ignored
+func storeLatestSchema(subject, text string) {
+	path := latestSchemaPath(subject)
+	if path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(text), 0o644); err != nil {
+		return
+	}
+	os.Rename(tmp, path)
+}
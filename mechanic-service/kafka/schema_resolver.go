@@ -0,0 +1,205 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hamba/avro/v2"
+	"github.com/riferrei/srclient"
+)
+
+// SchemaResolver decodes Schema Registry framed Avro payloads. The writer schema
+// is looked up by the ID embedded in each payload and resolved against the local
+// reader schema, so events written with an older or newer schema version still
+// decode (e.g. added fields fall back to their defaults).
+//
+// Writer schemas are cached in memory and, with SCHEMA_CACHE_DIR set, on disk,
+// so schemas seen before keep decoding while schema-registry is down. Failed
+// lookups are not retried against the registry until a backoff passes.
+type SchemaResolver struct {
+	srClient   *srclient.SchemaRegistryClient
+	reader     avro.Schema
+	compat     *avro.SchemaCompatibility
+	cacheDir   string        // empty disables the disk cache
+	backoff    time.Duration // first wait after a failed lookup, doubling per failure
+	maxBackoff time.Duration
+	mu         sync.Mutex
+	resolved   map[int]avro.Schema
+	failures   map[int]*schemaFailure
+}
+
+// schemaFailure is a failed registry lookup of one schema ID
+type schemaFailure struct {
+	err      error
+	attempts int
+	retryAt  time.Time
+}
+
+// NewSchemaResolver creates a SchemaResolver for the given reader schema.
+// SCHEMA_CACHE_DIR enables the disk cache; SCHEMA_FETCH_BACKOFF_MS (default
+// 1000) and SCHEMA_FETCH_MAX_BACKOFF_MS (default 60000) bound failed lookups.
+func NewSchemaResolver(schemaRegistryURL string, reader avro.Schema) *SchemaResolver {
+	r := &SchemaResolver{
+		srClient:   srclient.CreateSchemaRegistryClient(schemaRegistryURL),
+		reader:     reader,
+		compat:     avro.NewSchemaCompatibility(),
+		cacheDir:   os.Getenv("SCHEMA_CACHE_DIR"),
+		backoff:    time.Second,
+		maxBackoff: time.Minute,
+		resolved:   make(map[int]avro.Schema),
+		failures:   make(map[int]*schemaFailure),
+	}
+	if v, err := strconv.Atoi(os.Getenv("SCHEMA_FETCH_BACKOFF_MS")); err == nil && v > 0 {
+		r.backoff = time.Duration(v) * time.Millisecond
+	}
+	if v, err := strconv.Atoi(os.Getenv("SCHEMA_FETCH_MAX_BACKOFF_MS")); err == nil && v > 0 {
+		r.maxBackoff = time.Duration(v) * time.Millisecond
+	}
+	return r
+}
+
+// Decode unmarshals a payload in Schema Registry wire format (magic byte,
+// 4-byte schema ID, Avro body) into v
+func (r *SchemaResolver) Decode(payload []byte, v interface{}) error {
+	if len(payload) < 5 {
+		return fmt.Errorf("invalid payload length: %d", len(payload))
+	}
+	if payload[0] != 0 {
+		return fmt.Errorf("unknown magic byte: %d", payload[0])
+	}
+	schemaID := int(binary.BigEndian.Uint32(payload[1:5]))
+	schema, err := r.schemaFor(schemaID)
+	if err != nil {
+		return err
+	}
+	if err := avro.Unmarshal(schema, payload[5:], v); err != nil {
+		return fmt.Errorf("failed to deserialize payload with schema %d: %w", schemaID, err)
+	}
+	return nil
+}
+
+// schemaFor returns the writer schema for schemaID resolved against the reader
+// schema, looking it up in memory, then on disk, then in the registry.
+// LocalSchemaID payloads are written without a registry and use the reader schema.
+func (r *SchemaResolver) schemaFor(schemaID int) (avro.Schema, error) {
+	if schemaID == LocalSchemaID {
+		return r.reader, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if schema, ok := r.resolved[schemaID]; ok {
+		return schema, nil
+	}
+	if path := r.cachePath(schemaID); path != "" {
+		// An unreadable or corrupt cache file falls through to the registry
+		if text, err := os.ReadFile(path); err == nil {
+			if schema, err := r.resolve(schemaID, string(text)); err == nil {
+				return schema, nil
+			}
+		}
+	}
+
+	if failure, ok := r.failures[schemaID]; ok && time.Now().Before(failure.retryAt) {
+		return nil, fmt.Errorf("schema %d unavailable until %s: %w", schemaID, failure.retryAt.Format(time.RFC3339), failure.err)
+	}
+	schemaObj, err := r.srClient.GetSchema(schemaID)
+	if err != nil {
+		r.recordFailure(schemaID, err)
+		return nil, fmt.Errorf("failed to fetch schema %d: %w", schemaID, err)
+	}
+	delete(r.failures, schemaID)
+	r.store(schemaID, schemaObj.Schema())
+	return r.resolve(schemaID, schemaObj.Schema())
+}
+
+// resolve parses a writer schema, resolves it against the reader schema and
+// caches the result in memory. Callers hold r.mu.
+func (r *SchemaResolver) resolve(schemaID int, text string) (avro.Schema, error) {
+	writer, err := avro.Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema %d: %w", schemaID, err)
+	}
+	schema, err := r.compat.Resolve(r.reader, writer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve schema %d against reader schema: %w", schemaID, err)
+	}
+	r.resolved[schemaID] = schema
+	return schema, nil
+}
+
+// recordFailure backs off further lookups of schemaID, doubling the wait per
+// consecutive failure. Callers hold r.mu.
+func (r *SchemaResolver) recordFailure(schemaID int, err error) {
+	failure, ok := r.failures[schemaID]
+	if !ok {
+		failure = &schemaFailure{}
+		r.failures[schemaID] = failure
+	}
+	failure.err = err
+	failure.attempts++
+	wait := r.backoff << min(failure.attempts-1, 16)
+	if wait > r.maxBackoff || wait <= 0 {
+		wait = r.maxBackoff
+	}
+	failure.retryAt = time.Now().Add(wait)
+}
+
+func (r *SchemaResolver) cachePath(schemaID int) string {
+	if r.cacheDir == "" {
+		return ""
+	}
+	return filepath.Join(r.cacheDir, strconv.Itoa(schemaID)+".avsc")
+}
+
+// store writes a writer schema to the disk cache. Registry schemas are
+// immutable per ID, so cached files never go stale. Failures only cost a
+// registry lookup after the next restart, so they are ignored.
+func (r *SchemaResolver) store(schemaID int, text string) {
+	path := r.cachePath(schemaID)
+	if path == "" {
+		return
+	}
+	if err := os.MkdirAll(r.cacheDir, 0o755); err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(text), 0o644); err != nil {
+		return
+	}
+	os.Rename(tmp, path)
+}
+
+// Prewarm loads the latest versions of subject into the cache, so events
+// written with them decode even if schema-registry goes down later. It returns
+// how many schemas were loaded.
+func (r *SchemaResolver) Prewarm(subject string, latest int) (int, error) {
+	versions, err := r.srClient.GetSchemaVersions(subject)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list versions of %s: %w", subject, err)
+	}
+	if len(versions) > latest {
+		versions = versions[len(versions)-latest:]
+	}
+	loaded := 0
+	for _, version := range versions {
+		schemaObj, err := r.srClient.GetSchemaByVersion(subject, version)
+		if err != nil {
+			return loaded, fmt.Errorf("failed to fetch %s version %d: %w", subject, version, err)
+		}
+		r.mu.Lock()
+		r.store(schemaObj.ID(), schemaObj.Schema())
+		_, err = r.resolve(schemaObj.ID(), schemaObj.Schema())
+		r.mu.Unlock()
+		if err != nil {
+			return loaded, err
+		}
+		loaded++
+	}
+	return loaded, nil
+}
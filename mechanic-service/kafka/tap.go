@@ -0,0 +1,144 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// TappedEvent is a record read by the event tap, its value decoded with the
+// writer schema from schema-registry and resolved against the reader schema
+type TappedEvent struct {
+	Partition   int32             `json:"partition"`
+	Offset      int64             `json:"offset"`
+	Timestamp   time.Time         `json:"timestamp"`
+	Key         string            `json:"key,omitempty"`
+	Headers     map[string]string `json:"headers,omitempty"`
+	SchemaID    *int              `json:"schemaID,omitempty"`
+	Tombstone   bool              `json:"tombstone,omitempty"`
+	Value       map[string]any    `json:"value,omitempty"`
+	DecodeError string            `json:"decodeError,omitempty"`
+	Raw         []byte            `json:"raw,omitempty"` // undecodable values, base64
+}
+
+// EventTap is the result of tapping a topic
+type EventTap struct {
+	Topic  string        `json:"topic"`
+	Bus    string        `json:"bus"`
+	Events []TappedEvent `json:"events"`
+	// Incomplete is set when the tap timed out before reading every record
+	// up to the high watermarks it started with
+	Incomplete bool `json:"incomplete,omitempty"`
+}
+
+// TailTopic reads the last limit records of each partition of the physical
+// topic, up to the high watermarks when it starts, and returns the newest
+// limit of them in timestamp order. It uses a throwaway consumer group
+// that never commits, so no consumer group is affected. The bool result
+// reports whether every record was read before timeout.
+func TailTopic(bootstrapServers, topic string, limit int, timeout time.Duration) ([]*kafka.Message, bool, error) {
+	groupID := fmt.Sprintf("mechanic-service-tap-%d", time.Now().UnixNano())
+	config, err := ClientConfig(bootstrapServers, kafka.ConfigMap{"group.id": groupID, "enable.auto.commit": false, "enable.partition.eof": false})
+	if err != nil {
+		return nil, false, err
+	}
+	consumer, err := kafka.NewConsumer(config)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create Kafka consumer: %w", err)
+	}
+	defer consumer.Close()
+
+	deadline := time.Now().Add(timeout)
+	metadata, err := consumer.GetMetadata(&topic, false, int(timeout.Milliseconds()))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to fetch Kafka metadata: %w", err)
+	}
+	partitions := metadata.Topics[topic].Partitions
+	if len(partitions) == 0 {
+		return nil, false, fmt.Errorf("topic %s has no partitions", topic)
+	}
+
+	var assigned []kafka.TopicPartition
+	ends := make(map[int32]int64, len(partitions)) // high watermark per partition still being read
+	for _, p := range partitions {
+		low, high, err := consumer.QueryWatermarkOffsets(topic, p.ID, int(timeout.Milliseconds()))
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to query watermarks of partition %d: %w", p.ID, err)
+		}
+		start := max(low, high-int64(limit))
+		if start >= high {
+			continue
+		}
+		assigned = append(assigned, kafka.TopicPartition{Topic: &topic, Partition: p.ID, Offset: kafka.Offset(start)})
+		ends[p.ID] = high
+	}
+	if len(assigned) == 0 {
+		return nil, true, nil
+	}
+	if err := consumer.Assign(assigned); err != nil {
+		return nil, false, fmt.Errorf("failed to assign partitions of %s: %w", topic, err)
+	}
+
+	var messages []*kafka.Message
+	for len(ends) > 0 && time.Now().Before(deadline) {
+		msg, err := consumer.ReadMessage(time.Until(deadline))
+		var kerr kafka.Error
+		if errors.As(err, &kerr) && kerr.IsTimeout() {
+			break
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read %s: %w", topic, err)
+		}
+		messages = append(messages, msg)
+		partition := msg.TopicPartition.Partition
+		if end, ok := ends[partition]; ok && int64(msg.TopicPartition.Offset)+1 >= end {
+			delete(ends, partition)
+		}
+	}
+
+	slices.SortStableFunc(messages, func(a, b *kafka.Message) int {
+		return a.Timestamp.Compare(b.Timestamp)
+	})
+	if len(messages) > limit {
+		messages = messages[len(messages)-limit:]
+	}
+	return messages, len(ends) == 0, nil
+}
+
+// DecodeTapped decodes tapped records with schemas. Records that fail to
+// decode keep their raw value and the decoding error.
+func DecodeTapped(messages []*kafka.Message, schemas *SchemaResolver) []TappedEvent {
+	events := make([]TappedEvent, 0, len(messages))
+	for _, msg := range messages {
+		event := TappedEvent{
+			Partition: msg.TopicPartition.Partition,
+			Offset:    int64(msg.TopicPartition.Offset),
+			Timestamp: msg.Timestamp,
+			Key:       string(msg.Key),
+			Tombstone: len(msg.Value) == 0,
+		}
+		if len(msg.Headers) > 0 {
+			event.Headers = make(map[string]string, len(msg.Headers))
+			for _, h := range msg.Headers {
+				event.Headers[h.Key] = string(h.Value)
+			}
+		}
+		if !event.Tombstone {
+			if len(msg.Value) >= 5 && msg.Value[0] == 0 {
+				schemaID := int(binary.BigEndian.Uint32(msg.Value[1:5]))
+				event.SchemaID = &schemaID
+			}
+			if err := schemas.Decode(msg.Value, &event.Value); err != nil {
+				event.Value = nil
+				event.DecodeError = err.Error()
+				event.Raw = msg.Value
+			}
+		}
+		events = append(events, event)
+	}
+	return events
+}
@@ -0,0 +1,110 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// Logical topic names; TopicName maps them to the topics of this deployment
+const (
+	RepairEventsTopic     = "repair-events"
+	RepairEventsDLQTopic  = "repair-events-dlq"
+	AssignmentEventsTopic = "assignment-events" // JSON events of assignment_outbox
+)
+
+// TopicName returns the physical topic of a logical topic so several
+// environments or tenants can share one cluster. KAFKA_TOPIC_PREFIX is
+// prepended as is when set; otherwise KAFKA_ENV and KAFKA_TENANT, when set, are
+// joined with dots, e.g. "staging.acme.repair-events". Without any of them the
+// logical name is used unchanged.
+func TopicName(logical string) string {
+	return topicPrefix() + logical
+}
+
+// DLQTopic returns the logical dead letter topic, KAFKA_DLQ_TOPIC or
+// repair-events-dlq
+func DLQTopic() string {
+	if topic := os.Getenv("KAFKA_DLQ_TOPIC"); topic != "" {
+		return topic
+	}
+	return RepairEventsDLQTopic
+}
+
+// SchemaSubject returns the Schema Registry value subject of a logical topic
+// under the default TopicNameStrategy
+func SchemaSubject(logical string) string {
+	return TopicName(logical) + "-value"
+}
+
+// topicPrefix resolves the prefix of every topic of this deployment
+func topicPrefix() string {
+	if prefix := os.Getenv("KAFKA_TOPIC_PREFIX"); prefix != "" {
+		return prefix
+	}
+	var parts []string
+	for _, env := range []string{"KAFKA_ENV", "KAFKA_TENANT"} {
+		if v := strings.TrimSpace(os.Getenv(env)); v != "" {
+			parts = append(parts, v)
+		}
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, ".") + "."
+}
+
+// EnsureTopics creates the physical topics of the given logical topics when
+// they do not exist yet; existing topics are left untouched. New topics get
+// KAFKA_TOPIC_PARTITIONS partitions (default 3) and
+// KAFKA_TOPIC_REPLICATION_FACTOR replicas (default 1). Setting
+// KAFKA_PROVISION_TOPICS=false leaves provisioning to the cluster operator.
+func EnsureTopics(ctx context.Context, bootstrapServers string, logical []string, logger *slog.Logger) error {
+	if provision, err := strconv.ParseBool(os.Getenv("KAFKA_PROVISION_TOPICS")); err == nil && !provision {
+		logger.Info("Kafka topic provisioning disabled", "app", "mechanic-service")
+		return nil
+	}
+	partitions := 3
+	if v, err := strconv.Atoi(os.Getenv("KAFKA_TOPIC_PARTITIONS")); err == nil && v > 0 {
+		partitions = v
+	}
+	replication := 1
+	if v, err := strconv.Atoi(os.Getenv("KAFKA_TOPIC_REPLICATION_FACTOR")); err == nil && v > 0 {
+		replication = v
+	}
+
+	config, err := ClientConfig(bootstrapServers, nil)
+	if err != nil {
+		return err
+	}
+	admin, err := kafka.NewAdminClient(config)
+	if err != nil {
+		return fmt.Errorf("failed to create Kafka admin client: %w", err)
+	}
+	defer admin.Close()
+
+	specs := make([]kafka.TopicSpecification, 0, len(logical))
+	for _, name := range logical {
+		specs = append(specs, kafka.TopicSpecification{Topic: TopicName(name), NumPartitions: partitions, ReplicationFactor: replication})
+	}
+	results, err := admin.CreateTopics(ctx, specs, kafka.SetAdminOperationTimeout(30*time.Second))
+	if err != nil {
+		return fmt.Errorf("failed to create Kafka topics: %w", err)
+	}
+	for _, result := range results {
+		switch result.Error.Code() {
+		case kafka.ErrNoError:
+			logger.Info("Created Kafka topic", "topic", result.Topic, "partitions", partitions, "replicationFactor", replication, "app", "mechanic-service")
+		case kafka.ErrTopicAlreadyExists:
+		default:
+			return fmt.Errorf("failed to create Kafka topic %s: %w", result.Topic, result.Error)
+		}
+	}
+	return nil
+}
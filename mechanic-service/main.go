@@ -2,22 +2,32 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
+	"mechanic-service/connect"
+	"mechanic-service/deadline"
 	"mechanic-service/domain"
 	"mechanic-service/handlers"
+	"mechanic-service/lifecycle"
 	"mechanic-service/logging"
+	"mechanic-service/metrics"
 	"mechanic-service/service"
+	"mechanic-service/simulator"
+	"mechanic-service/slowlog"
+	"mechanic-service/telemetry"
 
 	"log/slog"
 
 	"github.com/gorilla/mux"
 	"github.com/hashicorp/consul/api"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.opentelemetry.io/otel"
@@ -92,6 +102,20 @@ func initTracer(logger *slog.Logger) (func(), error) {
 }
 
 func main() {
+	selfTest := flag.Bool("selftest", false, "check connectivity to dependencies, print a JSON report and exit")
+	restoreSnapshot := flag.String("restore-snapshot", "", "restore the repairs view from a projection snapshot (an ID or \"latest\"), rewind the consumer group to replay the events after it, print a JSON report and exit")
+	replayDLQ := flag.Int("replay-dlq", 0, "republish up to this many dead-lettered events (-1 for all) to the topic they failed on, print a JSON report and exit")
+	flag.Parse()
+	if *selfTest {
+		os.Exit(runSelfTest())
+	}
+	if *restoreSnapshot != "" {
+		os.Exit(runRestoreSnapshot(*restoreSnapshot))
+	}
+	if *replayDLQ != 0 {
+		os.Exit(runReplayDLQ(*replayDLQ))
+	}
+
 	// Initialize structured logging
 	logger, logFile, err := logging.NewLogger()
 	if err != nil {
@@ -104,13 +128,19 @@ func main() {
 	// Log startup
 	logger.Info("Starting mechanic-service", "app", "mechanic-service", "timestamp", time.Now().Unix())
 
+	// Components registered below are stopped in reverse order on shutdown
+	components := lifecycle.New(logger)
+
 	// Initialize tracer
 	shutdownTracer, err := initTracer(logger)
 	if err != nil {
 		logger.Error("Failed to initialize tracer", "error", err, "app", "mechanic-service")
 		os.Exit(1)
 	}
-	defer shutdownTracer()
+	components.Add("tracer", nil, func(ctx context.Context) error {
+		shutdownTracer()
+		return nil
+	})
 
 	// Initialize Consul client and register service
 	consulAddr := os.Getenv("CONSUL_ADDRESS")
@@ -139,49 +169,122 @@ func main() {
 		Name:    serviceName,
 		Port:    8086,
 		Address: "mechanic-service",
+		Meta:    map[string]string{"region": os.Getenv("SERVICE_REGION"), "zone": os.Getenv("SERVICE_ZONE")}, // used by the gateway to prefer nearby instances
 		Check: &api.AgentServiceCheck{
 			HTTP:     fmt.Sprintf("http://mechanic-service:%s/health", servicePort),
 			Interval: "10s",
 			Timeout:  "5s",
 		},
 	}
+	// Connect native (CONNECT_NATIVE=true): services reach this one over mTLS
+	// on CONNECT_PORT as Consul intentions allow; the health check stays on
+	// the plain port, which serves nothing else
+	connectPort := 9086
+	if v, err := strconv.Atoi(os.Getenv("CONNECT_PORT")); err == nil && v > 0 {
+		connectPort = v
+	}
+	if connect.Enabled() {
+		registration.Port = connectPort
+		registration.Connect = &api.AgentServiceConnect{Native: true}
+	}
 	if err := consulClient.Agent().ServiceRegister(registration); err != nil {
 		logger.Error("Failed to register with Consul", "error", err, "app", "mechanic-service")
 		os.Exit(1)
 	}
 	logger.Info("Registered with Consul", "service_id", serviceID, "app", "mechanic-service")
+	var mesh *connect.Service
+	if connect.Enabled() {
+		if mesh, err = connect.New(consulClient, serviceName, logger); err != nil {
+			logger.Error("Failed to set up Consul Connect", "error", err, "app", "mechanic-service")
+			os.Exit(1)
+		}
+		logger.Info("Consul Connect native enabled", "port", connectPort, "app", "mechanic-service")
+	}
 
 	// Initialize MongoDB
 	mongoURI := os.Getenv("MONGO_URI")
 	if mongoURI == "" {
 		mongoURI = "mongodb://mongodb:27017/repairdb?replicaSet=rs0"
 	}
-	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(mongoURI))
+	// Log and count slow Mongo commands and handlers
+	slow := slowlog.New(logger)
+
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(mongoURI).SetMonitor(telemetry.MongoMonitor(slow.MongoMonitor())))
 	if err != nil {
 		logger.Error("Failed to connect to MongoDB", "error", err, "app", "mechanic-service")
 		os.Exit(1)
 	}
-	defer func() {
-		if err := client.Disconnect(context.Background()); err != nil {
-			logger.Error("Failed to disconnect from MongoDB", "error", err, "app", "mechanic-service")
-		}
-	}()
+	components.Add("mongodb", nil, client.Disconnect)
 	logger.Info("Connected to MongoDB", "uri", mongoURI, "app", "mechanic-service")
 
 	// Initialize repository and service
-	repo := domain.NewMongoRepository(client)
-	svc := service.NewService(repo, logger)
+	// STORAGE_RETRY_ATTEMPTS caps the attempts of reads and transactions
+	// failing transiently (default 3; 1 disables retries)
+	retryAttempts, _ := strconv.Atoi(os.Getenv("STORAGE_RETRY_ATTEMPTS"))
+	repo, err := domain.NewRepository(domain.StorageConfig{Backend: os.Getenv("STORAGE_BACKEND"), MongoClient: client, RetryAttempts: retryAttempts})
+	if err != nil {
+		logger.Error("Failed to create repository", "error", err, "backend", os.Getenv("STORAGE_BACKEND"), "app", "mechanic-service")
+		os.Exit(1)
+	}
+	svc := service.NewService(repo, mesh, logger)
+	components.Add("service", nil, svc.Shutdown)
+
+	// Start the virtual mechanic fleet when simulation mode is enabled
+	var sim *simulator.Simulator
+	if cfg, enabled := simulator.ConfigFromEnv(); enabled {
+		sim = simulator.New(cfg, svc, repo, logger)
+		if err := sim.Start(); err != nil {
+			logger.Error("Failed to start mechanic simulator", "error", err, "app", "mechanic-service")
+			sim = nil
+		}
+	}
+	if sim != nil {
+		// Stopped before the service it dispatches through
+		components.Add("simulator", nil, func(ctx context.Context) error {
+			sim.Stop()
+			return nil
+		})
+	}
+
+	// Prometheus metrics read when scraped: consumed events by outcome and the
+	// backlog of consumed events not yet applied
+	metrics.RegisterConsumer(svc.ConsumerMetrics, repo, logger)
 
 	// Initialize handler with service
-	handler := handlers.NewMechanicHandler(svc, logger)
+	handler := handlers.NewMechanicHandler(svc, slow, logger)
 
 	// Initialize router
 	r := mux.NewRouter()
 
+	// Time handlers against SLOW_HANDLER_MS
+	r.Use(slow.Middleware)
+
+	// Count and time requests per route for Prometheus
+	r.Use(metrics.Middleware)
+
+	// Honor the time budget the gateway propagates
+	r.Use(deadline.Middleware(logger))
+
 	// Define endpoints
 	r.HandleFunc("/health", handler.HealthCheck).Methods("GET")
+	r.HandleFunc("/ready", handler.Readiness).Methods("GET")
 	r.HandleFunc("/repairs/nearby", handler.ListNearbyRepairs).Methods("GET")
 	r.HandleFunc("/repairs/{repairID}/assign", handler.AssignRepair).Methods("POST")
+	r.HandleFunc("/outbox/stats", handler.OutboxStats).Methods("GET")
+	r.HandleFunc("/metrics/delivery", handler.DeliveryMetrics).Methods("GET")
+	r.HandleFunc("/metrics/consumer-lag", handler.ConsumerLag).Methods("GET")
+	r.HandleFunc("/metrics/consumer", handler.ConsumerMetrics).Methods("GET")
+	r.HandleFunc("/metrics/slow", handler.SlowOperations).Methods("GET")
+	r.HandleFunc("/mechanics/search", handler.SearchMechanics).Methods("GET")
+	r.HandleFunc("/mechanics/{mechanicID}/notification-preferences", handler.NotificationPreferences).Methods("GET", "PUT")
+	r.HandleFunc("/mechanics/{mechanicID}/absences", handler.Absences).Methods("GET", "POST")
+	r.HandleFunc("/mechanics/{mechanicID}/absences/{absenceID}", handler.DeleteAbsence).Methods("DELETE")
+	r.HandleFunc("/mechanics/{mechanicID}/route", handler.MechanicRoute).Methods("GET")
+	r.HandleFunc("/mechanics/{mechanicID}/location", handler.UpdateLocation).Methods("PUT")
+	r.HandleFunc("/admin/mechanics/import", handler.ImportMechanics).Methods("POST")
+	r.HandleFunc("/admin/projection/snapshots", handler.ProjectionSnapshots).Methods("GET", "POST")
+	r.HandleFunc("/admin/events/tap", handler.TapEvents).Methods("GET")
+	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
 
 	// Create HTTP server
 	server := &http.Server{
@@ -189,36 +292,40 @@ func main() {
 		Handler: r,
 	}
 
-	// Start server in a goroutine
-	go func() {
-		logger.Info("Starting mechanic-service", "port", servicePort, "app", "mechanic-service")
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			logger.Error("Failed to start server", "error", err, "app", "mechanic-service")
-			os.Exit(1)
-		}
-	}()
-
-	// Handle graceful shutdown
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
-	logger.Info("Received shutdown signal, shutting down gracefully", "app", "mechanic-service")
+	if mesh != nil {
+		plain := http.NewServeMux()
+		plain.Handle("/health", r)
+		server.Handler = plain
 
-	// Create a context with timeout for shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+		connectServer := &http.Server{Addr: fmt.Sprintf(":%d", connectPort), Handler: r, TLSConfig: mesh.ServerTLSConfig()}
+		components.Add("connect-server", func() error {
+			logger.Info("Starting mechanic-service Connect listener", "port", connectPort, "app", "mechanic-service")
+			if err := connectServer.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
+				return err
+			}
+			return nil
+		}, connectServer.Shutdown)
+	}
 
-	// Shutdown the service (cancels Kafka consumer and outbox processor)
-	svc.Shutdown()
+	components.Add("http-server", func() error {
+		logger.Info("Starting mechanic-service", "port", servicePort, "app", "mechanic-service")
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	}, server.Shutdown)
 
-	// Shutdown the HTTP server
-	if err := server.Shutdown(ctx); err != nil {
-		logger.Error("Failed to shutdown server", "error", err, "app", "mechanic-service")
-	}
+	// Leave Consul first so the gateway stops routing here while requests drain
+	components.Add("consul-registration", nil, func(ctx context.Context) error {
+		return consulClient.Agent().ServiceDeregister(serviceID)
+	})
 
-	// Deregister from Consul
-	if err := consulClient.Agent().ServiceDeregister(serviceID); err != nil {
-		logger.Error("Failed to deregister from Consul", "error", err, "app", "mechanic-service")
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := components.Run(ctx); err != nil {
+		logger.Error("mechanic-service stopped", "error", err, "app", "mechanic-service")
+		logFile.Close()
+		os.Exit(1)
 	}
 	logger.Info("Service shutdown complete", "app", "mechanic-service")
 }
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"mechanic-service/kafka/consume"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapeTimeout bounds the queries collectors run when scraped
+const scrapeTimeout = 5 * time.Second
+
+// OutboxCounter counts the consumed repair events not yet applied
+type OutboxCounter interface {
+	CountUnprocessedOutboxEvents(ctx context.Context) (int64, error)
+}
+
+var (
+	consumedMessages = prometheus.NewDesc("kafka_messages_consumed_total",
+		"Repair events consumed, by topic and outcome", []string{"topic", "outcome"}, nil)
+	outboxUnprocessed = prometheus.NewDesc("mechanic_outbox_unprocessed_events",
+		"Consumed repair events not yet applied to the mechanic-service store", nil, nil)
+)
+
+// consumerCollector reports the consumers' counts and the outbox backlog,
+// read when scraped; a failed backlog read leaves the gauge out of the scrape
+type consumerCollector struct {
+	counts func() map[string]consume.Counts
+	outbox OutboxCounter
+	logger *slog.Logger
+}
+
+// RegisterConsumer exposes the per topic consumer counts and the outbox
+// backlog to Prometheus
+func RegisterConsumer(counts func() map[string]consume.Counts, outbox OutboxCounter, logger *slog.Logger) {
+	prometheus.MustRegister(&consumerCollector{counts: counts, outbox: outbox, logger: logger})
+}
+
+func (c *consumerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- consumedMessages
+	ch <- outboxUnprocessed
+}
+
+func (c *consumerCollector) Collect(ch chan<- prometheus.Metric) {
+	for topic, counts := range c.counts() {
+		for outcome, count := range map[string]int64{
+			"handled":       counts.Handled,
+			"failed":        counts.Failed,
+			"retried":       counts.Retried,
+			"dead_lettered": counts.DeadLettered,
+			"duplicate":     counts.Duplicates,
+			"other_region":  counts.OtherRegion,
+		} {
+			ch <- prometheus.MustNewConstMetric(consumedMessages, prometheus.CounterValue, float64(count), topic, outcome)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+	count, err := c.outbox.CountUnprocessedOutboxEvents(ctx)
+	if err != nil {
+		c.logger.Error("Failed to count outbox backlog for metrics", "error", err, "app", "mechanic-service")
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(outboxUnprocessed, prometheus.GaugeValue, float64(count))
+}
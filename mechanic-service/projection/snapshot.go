@@ -0,0 +1,454 @@
+// Package projection snapshots the repairs view mechanic-service builds from
+// repair events, together with the offset of the last event applied on each
+// partition, and restores a snapshot so a new or rebuilt instance replays
+// only the tail of the repair-events topic. With snapshots in place the topic
+// can be compacted or given a retention, see kafka.ConfigureRetention.
+package projection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Collections of the snapshots
+const (
+	SnapshotCollection       = "projection_snapshots"
+	SnapshotRepairCollection = "projection_snapshot_repairs" // one document per repair per snapshot
+)
+
+// Snapshot statuses; only complete snapshots are restored
+const (
+	StatusInProgress = "in_progress"
+	StatusComplete   = "complete"
+)
+
+// copyBatchSize is the number of repairs written to a snapshot at a time
+const copyBatchSize = 500
+
+// PartitionOffset is the offset of the last event of a partition applied to
+// the repairs view
+type PartitionOffset struct {
+	Partition int32 `bson:"partition" json:"partition"`
+	Offset    int64 `bson:"offset" json:"offset"`
+}
+
+// Snapshot describes a copy of the repairs view. Every event up to Offsets
+// is reflected in the copy; later events may be too, replaying them is
+// harmless since the view only inserts repairs it does not have yet.
+type Snapshot struct {
+	ID          string            `bson:"_id" json:"id"`
+	Topic       string            `bson:"topic" json:"topic"`
+	Offsets     []PartitionOffset `bson:"offsets" json:"offsets"` // partitions without events applied are absent
+	RepairCount int               `bson:"repairCount" json:"repairCount"`
+	Status      string            `bson:"status" json:"status"`
+	StartedAt   time.Time         `bson:"startedAt" json:"startedAt"`
+	CompletedAt time.Time         `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+}
+
+// Offset returns the last offset of partition covered by the snapshot
+func (s *Snapshot) Offset(partition int32) (int64, bool) {
+	for _, o := range s.Offsets {
+		if o.Partition == partition {
+			return o.Offset, true
+		}
+	}
+	return 0, false
+}
+
+// snapshotRepair is a repair document as it was when the snapshot was taken
+type snapshotRepair struct {
+	ID         string   `bson:"_id"` // <snapshot ID>/<repair ID>
+	SnapshotID string   `bson:"snapshotID"`
+	Repair     bson.Raw `bson:"repair"`
+}
+
+// RestoreResult reports what restoring a snapshot changed
+type RestoreResult struct {
+	SnapshotID      string            `json:"snapshotID"`
+	Offsets         []PartitionOffset `json:"offsets"`
+	RepairsInserted int               `json:"repairsInserted"` // repairs the view was missing
+	EventsReplayed  int64             `json:"eventsReplayed"`  // stored events after the snapshot marked for reprocessing
+}
+
+// Store takes, lists and restores snapshots of the repairs view in db
+type Store struct {
+	snapshots *mongo.Collection
+	copies    *mongo.Collection
+	view      *mongo.Collection // the repairs view
+	outbox    *mongo.Collection // mechanic_outbox, where consumed events wait to be applied
+	topic     string
+	keep      int
+	logger    *slog.Logger
+	tracer    trace.Tracer
+}
+
+// NewStore creates a Store for the view of topic, keeping the keep newest
+// complete snapshots
+func NewStore(db *mongo.Database, topic string, keep int, logger *slog.Logger) *Store {
+	return &Store{
+		snapshots: db.Collection(SnapshotCollection),
+		copies:    db.Collection(SnapshotRepairCollection),
+		view:      db.Collection("repairs"),
+		outbox:    db.Collection("mechanic_outbox"),
+		topic:     topic,
+		keep:      max(keep, 1),
+		logger:    logger,
+		tracer:    otel.Tracer("mechanic-service"),
+	}
+}
+
+// EnsureIndexes creates the indexes snapshots are read and pruned by
+func (s *Store) EnsureIndexes(ctx context.Context) error {
+	if _, err := s.copies.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "snapshotID", Value: 1}}}); err != nil {
+		return fmt.Errorf("failed to create snapshot repair index: %w", err)
+	}
+	if _, err := s.snapshots.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.D{{Key: "status", Value: 1}, {Key: "startedAt", Value: -1}}}); err != nil {
+		return fmt.Errorf("failed to create snapshot index: %w", err)
+	}
+	return nil
+}
+
+// Take snapshots the repairs view. The applied offsets are read before the
+// view is copied, so the copy covers at least every event up to them.
+// Snapshots beyond the newest keep complete ones are deleted afterwards.
+func (s *Store) Take(ctx context.Context) (*Snapshot, error) {
+	ctx, span := s.tracer.Start(ctx, "TakeProjectionSnapshot")
+	defer span.End()
+
+	offsets, err := s.appliedOffsets(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to read applied offsets")
+		return nil, err
+	}
+	snapshot := &Snapshot{
+		ID:        primitive.NewObjectID().Hex(),
+		Topic:     s.topic,
+		Offsets:   offsets,
+		Status:    StatusInProgress,
+		StartedAt: time.Now().UTC(),
+	}
+	span.SetAttributes(attribute.String("snapshotID", snapshot.ID))
+	if _, err := s.snapshots.InsertOne(ctx, snapshot); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save snapshot")
+		return nil, fmt.Errorf("failed to save snapshot: %w", err)
+	}
+
+	count, err := s.copyView(ctx, snapshot.ID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to copy repairs view")
+		s.discard(snapshot.ID)
+		return nil, err
+	}
+	snapshot.RepairCount = count
+	snapshot.Status = StatusComplete
+	snapshot.CompletedAt = time.Now().UTC()
+	_, err = s.snapshots.UpdateOne(ctx, bson.M{"_id": snapshot.ID}, bson.M{"$set": bson.M{
+		"repairCount": snapshot.RepairCount,
+		"status":      snapshot.Status,
+		"completedAt": snapshot.CompletedAt,
+	}})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to complete snapshot")
+		s.discard(snapshot.ID)
+		return nil, fmt.Errorf("failed to complete snapshot: %w", err)
+	}
+	span.SetAttributes(attribute.Int("repairCount", count))
+
+	if err := s.prune(ctx); err != nil {
+		s.logger.Warn("Failed to prune projection snapshots", "error", err, "app", "mechanic-service")
+	}
+	return snapshot, nil
+}
+
+// appliedOffsets returns, per partition of the topic, the offset below which
+// every consumed event has been applied to the view: the offset before the
+// oldest unprocessed event, or the newest event when all are processed
+func (s *Store) appliedOffsets(ctx context.Context) ([]PartitionOffset, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"kafka_topic": s.topic}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":       "$kafka_partition",
+			"newest":    bson.M{"$max": "$kafka_offset"},
+			"unapplied": bson.M{"$min": bson.M{"$cond": bson.A{"$processed", nil, "$kafka_offset"}}},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	}
+	cursor, err := s.outbox.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("failed to aggregate applied offsets: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	offsets := []PartitionOffset{}
+	for cursor.Next(ctx) {
+		var row struct {
+			Partition int32  `bson:"_id"`
+			Newest    int64  `bson:"newest"`
+			Unapplied *int64 `bson:"unapplied"`
+		}
+		if err := cursor.Decode(&row); err != nil {
+			return nil, fmt.Errorf("failed to decode applied offset: %w", err)
+		}
+		offset := row.Newest
+		if row.Unapplied != nil {
+			offset = *row.Unapplied - 1
+		}
+		if offset >= 0 {
+			offsets = append(offsets, PartitionOffset{Partition: row.Partition, Offset: offset})
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read applied offsets: %w", err)
+	}
+	return offsets, nil
+}
+
+// copyView copies every repair of the view into the snapshot
+func (s *Store) copyView(ctx context.Context, snapshotID string) (int, error) {
+	cursor, err := s.view.Find(ctx, bson.M{}, options.Find().SetBatchSize(copyBatchSize))
+	if err != nil {
+		return 0, fmt.Errorf("failed to read repairs view: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	count := 0
+	batch := make([]interface{}, 0, copyBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := s.copies.InsertMany(ctx, batch); err != nil {
+			return fmt.Errorf("failed to write snapshot repairs: %w", err)
+		}
+		count += len(batch)
+		batch = batch[:0]
+		return nil
+	}
+	for cursor.Next(ctx) {
+		raw := make(bson.Raw, len(cursor.Current))
+		copy(raw, cursor.Current)
+		repairID, _ := raw.Lookup("_id").StringValueOK()
+		batch = append(batch, snapshotRepair{ID: snapshotID + "/" + repairID, SnapshotID: snapshotID, Repair: raw})
+		if len(batch) == copyBatchSize {
+			if err := flush(); err != nil {
+				return count, err
+			}
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return count, fmt.Errorf("failed to read repairs view: %w", err)
+	}
+	return count, flush()
+}
+
+// discard deletes a snapshot that could not be completed
+func (s *Store) discard(snapshotID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if _, err := s.copies.DeleteMany(ctx, bson.M{"snapshotID": snapshotID}); err != nil {
+		s.logger.Warn("Failed to delete repairs of failed snapshot", "snapshotID", snapshotID, "error", err, "app", "mechanic-service")
+		return
+	}
+	s.snapshots.DeleteOne(ctx, bson.M{"_id": snapshotID})
+}
+
+// prune deletes the snapshots older than the newest keep complete ones,
+// including snapshots left in progress by a crash
+func (s *Store) prune(ctx context.Context) error {
+	opts := options.Find().SetSort(bson.D{{Key: "startedAt", Value: -1}}).SetSkip(int64(s.keep - 1)).SetLimit(1)
+	cursor, err := s.snapshots.Find(ctx, bson.M{"status": StatusComplete}, opts)
+	if err != nil {
+		return fmt.Errorf("failed to find oldest kept snapshot: %w", err)
+	}
+	var oldest []Snapshot
+	if err := cursor.All(ctx, &oldest); err != nil {
+		return fmt.Errorf("failed to decode oldest kept snapshot: %w", err)
+	}
+	if len(oldest) == 0 {
+		return nil
+	}
+
+	cursor, err = s.snapshots.Find(ctx, bson.M{"startedAt": bson.M{"$lt": oldest[0].StartedAt}}, options.Find().SetProjection(bson.M{"_id": 1}))
+	if err != nil {
+		return fmt.Errorf("failed to find expired snapshots: %w", err)
+	}
+	var expired []Snapshot
+	if err := cursor.All(ctx, &expired); err != nil {
+		return fmt.Errorf("failed to decode expired snapshots: %w", err)
+	}
+	for _, snapshot := range expired {
+		if _, err := s.copies.DeleteMany(ctx, bson.M{"snapshotID": snapshot.ID}); err != nil {
+			return fmt.Errorf("failed to delete repairs of snapshot %s: %w", snapshot.ID, err)
+		}
+		if _, err := s.snapshots.DeleteOne(ctx, bson.M{"_id": snapshot.ID}); err != nil {
+			return fmt.Errorf("failed to delete snapshot %s: %w", snapshot.ID, err)
+		}
+		s.logger.Info("Deleted expired projection snapshot", "snapshotID", snapshot.ID, "app", "mechanic-service")
+	}
+	return nil
+}
+
+// List returns the snapshots of the topic, newest first
+func (s *Store) List(ctx context.Context, limit int) ([]Snapshot, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "startedAt", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := s.snapshots.Find(ctx, bson.M{"topic": s.topic}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	snapshots := []Snapshot{}
+	if err := cursor.All(ctx, &snapshots); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshots: %w", err)
+	}
+	return snapshots, nil
+}
+
+// Latest returns the newest complete snapshot of the topic, or
+// mongo.ErrNoDocuments when there is none
+func (s *Store) Latest(ctx context.Context) (*Snapshot, error) {
+	var snapshot Snapshot
+	err := s.snapshots.FindOne(ctx,
+		bson.M{"topic": s.topic, "status": StatusComplete},
+		options.FindOne().SetSort(bson.D{{Key: "startedAt", Value: -1}}),
+	).Decode(&snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Get returns a complete snapshot by ID, or the newest one for "latest"
+func (s *Store) Get(ctx context.Context, id string) (*Snapshot, error) {
+	if id == "latest" {
+		return s.Latest(ctx)
+	}
+	var snapshot Snapshot
+	if err := s.snapshots.FindOne(ctx, bson.M{"_id": id}).Decode(&snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot.Status != StatusComplete {
+		return nil, fmt.Errorf("snapshot %s is %s", id, snapshot.Status)
+	}
+	return &snapshot, nil
+}
+
+// Restore inserts the snapshot's repairs the view is missing and marks the
+// stored events after the snapshot's offsets unprocessed, so the outbox
+// processor applies them again. Repairs already in the view are left alone;
+// restored ones get the geoLocation copies taken before it existed lack.
+// Events after the offsets that were never stored are replayed by rewinding
+// the consumer group, see kafka.RewindGroup and eventbus.Rewind.
+func (s *Store) Restore(ctx context.Context, snapshot *Snapshot) (*RestoreResult, error) {
+	ctx, span := s.tracer.Start(ctx, "RestoreProjectionSnapshot")
+	defer span.End()
+	span.SetAttributes(attribute.String("snapshotID", snapshot.ID))
+
+	result := &RestoreResult{SnapshotID: snapshot.ID, Offsets: snapshot.Offsets}
+	cursor, err := s.copies.Find(ctx, bson.M{"snapshotID": snapshot.ID}, options.Find().SetBatchSize(copyBatchSize))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to read snapshot")
+		return nil, fmt.Errorf("failed to read snapshot repairs: %w", err)
+	}
+	defer cursor.Close(ctx)
+	for cursor.Next(ctx) {
+		var copied snapshotRepair
+		if err := cursor.Decode(&copied); err != nil {
+			return nil, fmt.Errorf("failed to decode snapshot repair: %w", err)
+		}
+		repair, err := withGeoLocation(copied.Repair)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode snapshot repair %s: %w", copied.ID, err)
+		}
+		_, err = s.view.InsertOne(ctx, repair)
+		switch {
+		case err == nil:
+			result.RepairsInserted++
+		case mongo.IsDuplicateKeyError(err):
+		default:
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to restore repair")
+			return nil, fmt.Errorf("failed to restore repair %s: %w", copied.ID, err)
+		}
+	}
+	if err := cursor.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read snapshot repairs: %w", err)
+	}
+
+	// Stored events after the snapshot are applied again; partitions the
+	// snapshot has no offset for are replayed from their first event
+	after := bson.A{}
+	for _, o := range snapshot.Offsets {
+		after = append(after, bson.M{"kafka_partition": o.Partition, "kafka_offset": bson.M{"$gt": o.Offset}})
+	}
+	partitions := make(bson.A, 0, len(snapshot.Offsets))
+	for _, o := range snapshot.Offsets {
+		partitions = append(partitions, o.Partition)
+	}
+	after = append(after, bson.M{"kafka_partition": bson.M{"$nin": partitions}})
+	update, err := s.outbox.UpdateMany(ctx,
+		bson.M{"kafka_topic": snapshot.Topic, "processed": true, "$or": after},
+		bson.M{"$set": bson.M{"processed": false}, "$unset": bson.M{"processed_at": ""}},
+	)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to replay stored events")
+		return nil, fmt.Errorf("failed to mark stored events for replay: %w", err)
+	}
+	result.EventsReplayed = update.ModifiedCount
+	span.SetAttributes(
+		attribute.Int("repairsInserted", result.RepairsInserted),
+		attribute.Int64("eventsReplayed", result.EventsReplayed),
+	)
+	return result, nil
+}
+
+// withGeoLocation returns a copied repair with the geoLocation nearby repairs
+// are found through, derived from its user location as the repository does
+// on writes when the copy predates the field
+func withGeoLocation(repair bson.Raw) (any, error) {
+	var fields struct {
+		GeoLocation bson.Raw `bson:"geoLocation"`
+		RepairCost  struct {
+			UserLocation *struct {
+				Latitude  float64 `bson:"latitude"`
+				Longitude float64 `bson:"longitude"`
+			} `bson:"userLocation"`
+		} `bson:"repairCost"`
+	}
+	if err := bson.Unmarshal(repair, &fields); err != nil {
+		return nil, err
+	}
+	location := fields.RepairCost.UserLocation
+	if fields.GeoLocation != nil || location == nil {
+		return repair, nil
+	}
+	var doc bson.D
+	if err := bson.Unmarshal(repair, &doc); err != nil {
+		return nil, err
+	}
+	return append(doc, bson.E{Key: "geoLocation", Value: bson.M{
+		"type":        "Point",
+		"coordinates": bson.A{location.Longitude, location.Latitude},
+	}}), nil
+}
+
+// IsNotFound reports whether err means the snapshot does not exist
+func IsNotFound(err error) bool {
+	return errors.Is(err, mongo.ErrNoDocuments)
+}
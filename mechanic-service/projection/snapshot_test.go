@@ -0,0 +1,67 @@
+package projection
+
+import (
+	"bytes"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TestWithGeoLocation checks restored copies taken before geoLocation
+// existed get it derived from the user location
+func TestWithGeoLocation(t *testing.T) {
+	marshal := func(doc bson.M) bson.Raw {
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return raw
+	}
+	geo := func(t *testing.T, repair any) bson.Raw {
+		t.Helper()
+		raw, err := bson.Marshal(repair)
+		if err != nil {
+			t.Fatal(err)
+		}
+		value, err := bson.Raw(raw).LookupErr("geoLocation")
+		if err != nil {
+			return nil
+		}
+		return value.Document()
+	}
+
+	t.Run("derived", func(t *testing.T) {
+		repair, err := withGeoLocation(marshal(bson.M{"_id": "repair1", "repairCost": bson.M{"userLocation": bson.M{"latitude": 52.52, "longitude": int32(13)}}}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		point := geo(t, repair)
+		if point == nil || point.Lookup("type").StringValue() != "Point" {
+			t.Fatalf("geoLocation %v", point)
+		}
+		coordinates, _ := point.Lookup("coordinates").Array().Values()
+		if len(coordinates) != 2 || coordinates[0].Double() != 13 || coordinates[1].Double() != 52.52 {
+			t.Errorf("coordinates %v, want [13 52.52]", coordinates)
+		}
+	})
+	t.Run("kept", func(t *testing.T) {
+		copied := marshal(bson.M{"_id": "repair1", "repairCost": bson.M{"userLocation": bson.M{"latitude": 52.52, "longitude": 13.4}},
+			"geoLocation": bson.M{"type": "Point", "coordinates": bson.A{1.0, 2.0}}})
+		repair, err := withGeoLocation(copied)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if raw, ok := repair.(bson.Raw); !ok || !bytes.Equal(raw, copied) {
+			t.Errorf("repair %v, want the copy unchanged", repair)
+		}
+	})
+	t.Run("no location", func(t *testing.T) {
+		repair, err := withGeoLocation(marshal(bson.M{"_id": "repair1", "repairCost": bson.M{"amount": 10}}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if point := geo(t, repair); point != nil {
+			t.Errorf("geoLocation %v, want none", point)
+		}
+	})
+}
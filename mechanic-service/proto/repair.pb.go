@@ -0,0 +1,751 @@
+// proto/repair.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        v3.21.12
+// source: proto/repair.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Empty message for requests that don't need parameters
+type Empty struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	mi := &file_proto_repair_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_repair_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_proto_repair_proto_rawDescGZIP(), []int{0}
+}
+
+// StreamRepairsRequest filters the repairs sent by StreamAllRepairs; unset
+// fields do not filter
+type StreamRepairsRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Statuses []string               `protobuf:"bytes,1,rep,name=statuses,proto3" json:"statuses,omitempty"`
+	UserId   string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Bbox     *BoundingBox           `protobuf:"bytes,3,opt,name=bbox,proto3" json:"bbox,omitempty"`
+	// Only repairs created at or after this time (Unix milliseconds)
+	SinceUnixMs   int64 `protobuf:"varint,4,opt,name=since_unix_ms,json=sinceUnixMs,proto3" json:"since_unix_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamRepairsRequest) Reset() {
+	*x = StreamRepairsRequest{}
+	mi := &file_proto_repair_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamRepairsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamRepairsRequest) ProtoMessage() {}
+
+func (x *StreamRepairsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_repair_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamRepairsRequest.ProtoReflect.Descriptor instead.
+func (*StreamRepairsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_repair_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *StreamRepairsRequest) GetStatuses() []string {
+	if x != nil {
+		return x.Statuses
+	}
+	return nil
+}
+
+func (x *StreamRepairsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *StreamRepairsRequest) GetBbox() *BoundingBox {
+	if x != nil {
+		return x.Bbox
+	}
+	return nil
+}
+
+func (x *StreamRepairsRequest) GetSinceUnixMs() int64 {
+	if x != nil {
+		return x.SinceUnixMs
+	}
+	return 0
+}
+
+// GetRepairRequest identifies the repair GetRepair returns
+type GetRepairRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRepairRequest) Reset() {
+	*x = GetRepairRequest{}
+	mi := &file_proto_repair_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRepairRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRepairRequest) ProtoMessage() {}
+
+func (x *GetRepairRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_repair_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRepairRequest.ProtoReflect.Descriptor instead.
+func (*GetRepairRequest) Descriptor() ([]byte, []int) {
+	return file_proto_repair_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetRepairRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+// BoundingBox limits repairs to a user location inside the box
+type BoundingBox struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MinLongitude  float64                `protobuf:"fixed64,1,opt,name=min_longitude,json=minLongitude,proto3" json:"min_longitude,omitempty"`
+	MinLatitude   float64                `protobuf:"fixed64,2,opt,name=min_latitude,json=minLatitude,proto3" json:"min_latitude,omitempty"`
+	MaxLongitude  float64                `protobuf:"fixed64,3,opt,name=max_longitude,json=maxLongitude,proto3" json:"max_longitude,omitempty"`
+	MaxLatitude   float64                `protobuf:"fixed64,4,opt,name=max_latitude,json=maxLatitude,proto3" json:"max_latitude,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BoundingBox) Reset() {
+	*x = BoundingBox{}
+	mi := &file_proto_repair_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BoundingBox) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BoundingBox) ProtoMessage() {}
+
+func (x *BoundingBox) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_repair_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BoundingBox.ProtoReflect.Descriptor instead.
+func (*BoundingBox) Descriptor() ([]byte, []int) {
+	return file_proto_repair_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *BoundingBox) GetMinLongitude() float64 {
+	if x != nil {
+		return x.MinLongitude
+	}
+	return 0
+}
+
+func (x *BoundingBox) GetMinLatitude() float64 {
+	if x != nil {
+		return x.MinLatitude
+	}
+	return 0
+}
+
+func (x *BoundingBox) GetMaxLongitude() float64 {
+	if x != nil {
+		return x.MaxLongitude
+	}
+	return 0
+}
+
+func (x *BoundingBox) GetMaxLatitude() float64 {
+	if x != nil {
+		return x.MaxLatitude
+	}
+	return 0
+}
+
+// Repair message mirroring the domain.RepairModel
+type Repair struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId     string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Status     string                 `protobuf:"bytes,3,opt,name=status,proto3" json:"status,omitempty"`
+	RepairCost *RepairCost            `protobuf:"bytes,4,opt,name=repair_cost,json=repairCost,proto3" json:"repair_cost,omitempty"`
+	// Region derived from the user location; empty for repairs predating regions
+	Region string `protobuf:"bytes,5,opt,name=region,proto3" json:"region,omitempty"`
+	// Mechanic the repair is assigned to; empty while unassigned
+	AssignedTo string `protobuf:"bytes,6,opt,name=assigned_to,json=assignedTo,proto3" json:"assigned_to,omitempty"`
+	// Bundle the repair is dispatched with; empty for repairs on their own
+	BundleId string `protobuf:"bytes,7,opt,name=bundle_id,json=bundleId,proto3" json:"bundle_id,omitempty"`
+	// Mechanic the user asked for; unset without a preference
+	PreferredMechanic *PreferredMechanic `protobuf:"bytes,8,opt,name=preferred_mechanic,json=preferredMechanic,proto3" json:"preferred_mechanic,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Repair) Reset() {
+	*x = Repair{}
+	mi := &file_proto_repair_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Repair) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Repair) ProtoMessage() {}
+
+func (x *Repair) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_repair_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Repair.ProtoReflect.Descriptor instead.
+func (*Repair) Descriptor() ([]byte, []int) {
+	return file_proto_repair_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Repair) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Repair) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Repair) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Repair) GetRepairCost() *RepairCost {
+	if x != nil {
+		return x.RepairCost
+	}
+	return nil
+}
+
+func (x *Repair) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *Repair) GetAssignedTo() string {
+	if x != nil {
+		return x.AssignedTo
+	}
+	return ""
+}
+
+func (x *Repair) GetBundleId() string {
+	if x != nil {
+		return x.BundleId
+	}
+	return ""
+}
+
+func (x *Repair) GetPreferredMechanic() *PreferredMechanic {
+	if x != nil {
+		return x.PreferredMechanic
+	}
+	return nil
+}
+
+// PreferredMechanic is the mechanic a user asked to do the repair
+type PreferredMechanic struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	MechanicId string                 `protobuf:"bytes,1,opt,name=mechanic_id,json=mechanicId,proto3" json:"mechanic_id,omitempty"`
+	// Whether only the mechanic may claim the repair
+	Pinned bool `protobuf:"varint,2,opt,name=pinned,proto3" json:"pinned,omitempty"`
+	// When others may claim it too; 0 while it waits for the mechanic for good
+	HoldUntilUnixMs int64 `protobuf:"varint,3,opt,name=hold_until_unix_ms,json=holdUntilUnixMs,proto3" json:"hold_until_unix_ms,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *PreferredMechanic) Reset() {
+	*x = PreferredMechanic{}
+	mi := &file_proto_repair_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PreferredMechanic) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PreferredMechanic) ProtoMessage() {}
+
+func (x *PreferredMechanic) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_repair_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PreferredMechanic.ProtoReflect.Descriptor instead.
+func (*PreferredMechanic) Descriptor() ([]byte, []int) {
+	return file_proto_repair_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PreferredMechanic) GetMechanicId() string {
+	if x != nil {
+		return x.MechanicId
+	}
+	return ""
+}
+
+func (x *PreferredMechanic) GetPinned() bool {
+	if x != nil {
+		return x.Pinned
+	}
+	return false
+}
+
+func (x *PreferredMechanic) GetHoldUntilUnixMs() int64 {
+	if x != nil {
+		return x.HoldUntilUnixMs
+	}
+	return 0
+}
+
+type RepairCost struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId     string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	RepairType string                 `protobuf:"bytes,3,opt,name=repair_type,json=repairType,proto3" json:"repair_type,omitempty"`
+	// Major units rounded to the minor unit; total_price_minor is exact
+	TotalPrice   float64         `protobuf:"fixed64,4,opt,name=total_price,json=totalPrice,proto3" json:"total_price,omitempty"`
+	UserLocation *Location       `protobuf:"bytes,5,opt,name=user_location,json=userLocation,proto3" json:"user_location,omitempty"`
+	Mechanics    []*MechanicInfo `protobuf:"bytes,6,rep,name=mechanics,proto3" json:"mechanics,omitempty"`
+	// Price in minor currency units (cents)
+	TotalPriceMinor int64 `protobuf:"varint,7,opt,name=total_price_minor,json=totalPriceMinor,proto3" json:"total_price_minor,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *RepairCost) Reset() {
+	*x = RepairCost{}
+	mi := &file_proto_repair_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RepairCost) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RepairCost) ProtoMessage() {}
+
+func (x *RepairCost) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_repair_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RepairCost.ProtoReflect.Descriptor instead.
+func (*RepairCost) Descriptor() ([]byte, []int) {
+	return file_proto_repair_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *RepairCost) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RepairCost) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *RepairCost) GetRepairType() string {
+	if x != nil {
+		return x.RepairType
+	}
+	return ""
+}
+
+func (x *RepairCost) GetTotalPrice() float64 {
+	if x != nil {
+		return x.TotalPrice
+	}
+	return 0
+}
+
+func (x *RepairCost) GetUserLocation() *Location {
+	if x != nil {
+		return x.UserLocation
+	}
+	return nil
+}
+
+func (x *RepairCost) GetMechanics() []*MechanicInfo {
+	if x != nil {
+		return x.Mechanics
+	}
+	return nil
+}
+
+func (x *RepairCost) GetTotalPriceMinor() int64 {
+	if x != nil {
+		return x.TotalPriceMinor
+	}
+	return 0
+}
+
+type Location struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Longitude     float64                `protobuf:"fixed64,1,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Latitude      float64                `protobuf:"fixed64,2,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Location) Reset() {
+	*x = Location{}
+	mi := &file_proto_repair_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Location) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Location) ProtoMessage() {}
+
+func (x *Location) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_repair_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Location.ProtoReflect.Descriptor instead.
+func (*Location) Descriptor() ([]byte, []int) {
+	return file_proto_repair_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Location) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *Location) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+type MechanicInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Location      *Location              `protobuf:"bytes,3,opt,name=location,proto3" json:"location,omitempty"`
+	Distance      float64                `protobuf:"fixed64,4,opt,name=distance,proto3" json:"distance,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MechanicInfo) Reset() {
+	*x = MechanicInfo{}
+	mi := &file_proto_repair_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MechanicInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MechanicInfo) ProtoMessage() {}
+
+func (x *MechanicInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_repair_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MechanicInfo.ProtoReflect.Descriptor instead.
+func (*MechanicInfo) Descriptor() ([]byte, []int) {
+	return file_proto_repair_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *MechanicInfo) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *MechanicInfo) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *MechanicInfo) GetLocation() *Location {
+	if x != nil {
+		return x.Location
+	}
+	return nil
+}
+
+func (x *MechanicInfo) GetDistance() float64 {
+	if x != nil {
+		return x.Distance
+	}
+	return 0
+}
+
+var File_proto_repair_proto protoreflect.FileDescriptor
+
+const file_proto_repair_proto_rawDesc = "" +
+	"\n" +
+	"\x12proto/repair.proto\x12\x06repair\"\a\n" +
+	"\x05Empty\"\x98\x01\n" +
+	"\x14StreamRepairsRequest\x12\x1a\n" +
+	"\bstatuses\x18\x01 \x03(\tR\bstatuses\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12'\n" +
+	"\x04bbox\x18\x03 \x01(\v2\x13.repair.BoundingBoxR\x04bbox\x12\"\n" +
+	"\rsince_unix_ms\x18\x04 \x01(\x03R\vsinceUnixMs\"\"\n" +
+	"\x10GetRepairRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x9d\x01\n" +
+	"\vBoundingBox\x12#\n" +
+	"\rmin_longitude\x18\x01 \x01(\x01R\fminLongitude\x12!\n" +
+	"\fmin_latitude\x18\x02 \x01(\x01R\vminLatitude\x12#\n" +
+	"\rmax_longitude\x18\x03 \x01(\x01R\fmaxLongitude\x12!\n" +
+	"\fmax_latitude\x18\x04 \x01(\x01R\vmaxLatitude\"\x9e\x02\n" +
+	"\x06Repair\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06status\x18\x03 \x01(\tR\x06status\x123\n" +
+	"\vrepair_cost\x18\x04 \x01(\v2\x12.repair.RepairCostR\n" +
+	"repairCost\x12\x16\n" +
+	"\x06region\x18\x05 \x01(\tR\x06region\x12\x1f\n" +
+	"\vassigned_to\x18\x06 \x01(\tR\n" +
+	"assignedTo\x12\x1b\n" +
+	"\tbundle_id\x18\a \x01(\tR\bbundleId\x12H\n" +
+	"\x12preferred_mechanic\x18\b \x01(\v2\x19.repair.PreferredMechanicR\x11preferredMechanic\"y\n" +
+	"\x11PreferredMechanic\x12\x1f\n" +
+	"\vmechanic_id\x18\x01 \x01(\tR\n" +
+	"mechanicId\x12\x16\n" +
+	"\x06pinned\x18\x02 \x01(\bR\x06pinned\x12+\n" +
+	"\x12hold_until_unix_ms\x18\x03 \x01(\x03R\x0fholdUntilUnixMs\"\x8e\x02\n" +
+	"\n" +
+	"RepairCost\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x1f\n" +
+	"\vrepair_type\x18\x03 \x01(\tR\n" +
+	"repairType\x12\x1f\n" +
+	"\vtotal_price\x18\x04 \x01(\x01R\n" +
+	"totalPrice\x125\n" +
+	"\ruser_location\x18\x05 \x01(\v2\x10.repair.LocationR\fuserLocation\x122\n" +
+	"\tmechanics\x18\x06 \x03(\v2\x14.repair.MechanicInfoR\tmechanics\x12*\n" +
+	"\x11total_price_minor\x18\a \x01(\x03R\x0ftotalPriceMinor\"D\n" +
+	"\bLocation\x12\x1c\n" +
+	"\tlongitude\x18\x01 \x01(\x01R\tlongitude\x12\x1a\n" +
+	"\blatitude\x18\x02 \x01(\x01R\blatitude\"|\n" +
+	"\fMechanicInfo\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12,\n" +
+	"\blocation\x18\x03 \x01(\v2\x10.repair.LocationR\blocation\x12\x1a\n" +
+	"\bdistance\x18\x04 \x01(\x01R\bdistance2\x8e\x01\n" +
+	"\rRepairService\x12D\n" +
+	"\x10StreamAllRepairs\x12\x1c.repair.StreamRepairsRequest\x1a\x0e.repair.Repair\"\x000\x01\x127\n" +
+	"\tGetRepair\x12\x18.repair.GetRepairRequest\x1a\x0e.repair.Repair\"\x00B\tZ\a./protob\x06proto3"
+
+var (
+	file_proto_repair_proto_rawDescOnce sync.Once
+	file_proto_repair_proto_rawDescData []byte
+)
+
+func file_proto_repair_proto_rawDescGZIP() []byte {
+	file_proto_repair_proto_rawDescOnce.Do(func() {
+		file_proto_repair_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_repair_proto_rawDesc), len(file_proto_repair_proto_rawDesc)))
+	})
+	return file_proto_repair_proto_rawDescData
+}
+
+var file_proto_repair_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_proto_repair_proto_goTypes = []any{
+	(*Empty)(nil),                // 0: repair.Empty
+	(*StreamRepairsRequest)(nil), // 1: repair.StreamRepairsRequest
+	(*GetRepairRequest)(nil),     // 2: repair.GetRepairRequest
+	(*BoundingBox)(nil),          // 3: repair.BoundingBox
+	(*Repair)(nil),               // 4: repair.Repair
+	(*PreferredMechanic)(nil),    // 5: repair.PreferredMechanic
+	(*RepairCost)(nil),           // 6: repair.RepairCost
+	(*Location)(nil),             // 7: repair.Location
+	(*MechanicInfo)(nil),         // 8: repair.MechanicInfo
+}
+var file_proto_repair_proto_depIdxs = []int32{
+	3, // 0: repair.StreamRepairsRequest.bbox:type_name -> repair.BoundingBox
+	6, // 1: repair.Repair.repair_cost:type_name -> repair.RepairCost
+	5, // 2: repair.Repair.preferred_mechanic:type_name -> repair.PreferredMechanic
+	7, // 3: repair.RepairCost.user_location:type_name -> repair.Location
+	8, // 4: repair.RepairCost.mechanics:type_name -> repair.MechanicInfo
+	7, // 5: repair.MechanicInfo.location:type_name -> repair.Location
+	1, // 6: repair.RepairService.StreamAllRepairs:input_type -> repair.StreamRepairsRequest
+	2, // 7: repair.RepairService.GetRepair:input_type -> repair.GetRepairRequest
+	4, // 8: repair.RepairService.StreamAllRepairs:output_type -> repair.Repair
+	4, // 9: repair.RepairService.GetRepair:output_type -> repair.Repair
+	8, // [8:10] is the sub-list for method output_type
+	6, // [6:8] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
+}
+
+func init() { file_proto_repair_proto_init() }
+func file_proto_repair_proto_init() {
+	if File_proto_repair_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_repair_proto_rawDesc), len(file_proto_repair_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_repair_proto_goTypes,
+		DependencyIndexes: file_proto_repair_proto_depIdxs,
+		MessageInfos:      file_proto_repair_proto_msgTypes,
+	}.Build()
+	File_proto_repair_proto = out.File
+	file_proto_repair_proto_goTypes = nil
+	file_proto_repair_proto_depIdxs = nil
+}
@@ -0,0 +1,186 @@
+// Package repairquery reads repairs from repair-service over gRPC, so checks
+// such as assignment validation see repair-service's current state rather
+// than the repairs view built from events
+package repairquery
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"mechanic-service/domain"
+	"mechanic-service/proto"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Client looks up repairs with repair-service's GetRepair and caches them for
+// a short TTL, so bursts of checks on one repair make one call
+type Client struct {
+	client  proto.RepairServiceClient
+	ttl     time.Duration
+	size    int
+	timeout time.Duration
+	logger  *slog.Logger
+	tracer  trace.Tracer
+
+	mu    sync.Mutex
+	cache map[string]cached
+}
+
+type cached struct {
+	repair    *domain.Repair
+	expiresAt time.Time
+}
+
+// New creates a Client caching up to REPAIR_QUERY_CACHE_SIZE repairs (default
+// 1000) for REPAIR_QUERY_CACHE_TTL_MS (default 2000; 0 disables the cache).
+// Calls time out after REPAIR_QUERY_TIMEOUT_MS (default 1000).
+func New(conn grpc.ClientConnInterface, logger *slog.Logger) *Client {
+	c := &Client{
+		client:  proto.NewRepairServiceClient(conn),
+		ttl:     2 * time.Second,
+		size:    1000,
+		timeout: time.Second,
+		logger:  logger,
+		tracer:  otel.Tracer("mechanic-service"),
+		cache:   make(map[string]cached),
+	}
+	if v, err := strconv.Atoi(os.Getenv("REPAIR_QUERY_CACHE_TTL_MS")); err == nil && v >= 0 {
+		c.ttl = time.Duration(v) * time.Millisecond
+	}
+	if v, err := strconv.Atoi(os.Getenv("REPAIR_QUERY_CACHE_SIZE")); err == nil && v > 0 {
+		c.size = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("REPAIR_QUERY_TIMEOUT_MS")); err == nil && v > 0 {
+		c.timeout = time.Duration(v) * time.Millisecond
+	}
+	logger.Info("Configured repair queries over gRPC", "cacheTTL", c.ttl, "cacheSize", c.size, "timeout", c.timeout, "app", "mechanic-service")
+	return c
+}
+
+// GetRepair returns the repair, from the cache when fresh. A repair that does
+// not exist returns mongo.ErrNoDocuments, like the repository.
+func (c *Client) GetRepair(ctx context.Context, id string) (*domain.Repair, error) {
+	ctx, span := c.tracer.Start(ctx, "RepairQueryGetRepair")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", id))
+
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.cache[id]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expiresAt) {
+		span.SetAttributes(attribute.Bool("cacheHit", true))
+		return entry.repair, nil
+	}
+	span.SetAttributes(attribute.Bool("cacheHit", false))
+
+	callCtx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	msg, err := c.client.GetRepair(callCtx, &proto.GetRepairRequest{Id: id})
+	if status.Code(err) == grpccodes.NotFound {
+		span.SetStatus(codes.Error, "Repair not found")
+		return nil, mongo.ErrNoDocuments
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get repair")
+		return nil, err
+	}
+
+	repair := FromProto(msg)
+	if c.ttl > 0 {
+		c.store(id, repair, now.Add(c.ttl))
+	}
+	return repair, nil
+}
+
+// Invalidate drops a cached repair, e.g. after changing it
+func (c *Client) Invalidate(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.cache, id)
+}
+
+// store caches a repair, first dropping expired entries when the cache is
+// full and, if it is still full, an arbitrary one
+func (c *Client) store(id string, repair *domain.Repair, expiresAt time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.cache) >= c.size {
+		now := time.Now()
+		for key, entry := range c.cache {
+			if !now.Before(entry.expiresAt) {
+				delete(c.cache, key)
+			}
+		}
+		for key := range c.cache {
+			if len(c.cache) < c.size {
+				break
+			}
+			delete(c.cache, key)
+		}
+	}
+	c.cache[id] = cached{repair: repair, expiresAt: expiresAt}
+}
+
+// FromProto converts a repair received from repair-service into the repairs
+// view shape
+func FromProto(msg *proto.Repair) *domain.Repair {
+	repair := &domain.Repair{
+		ID:         msg.GetId(),
+		UserID:     msg.GetUserId(),
+		Status:     msg.GetStatus(),
+		AssignedTo: msg.GetAssignedTo(),
+		Region:     msg.GetRegion(),
+		BundleID:   msg.GetBundleId(),
+	}
+	cost := msg.GetRepairCost()
+	if cost == nil {
+		return repair
+	}
+
+	var userLocation *domain.Location
+	if loc := cost.GetUserLocation(); loc != nil {
+		userLocation = &domain.Location{Longitude: loc.GetLongitude(), Latitude: loc.GetLatitude()}
+	}
+	mechanics := make([]domain.MechanicInfo, len(cost.GetMechanics()))
+	for i, m := range cost.GetMechanics() {
+		mechanics[i] = domain.MechanicInfo{
+			ID:   m.GetId(),
+			Name: m.GetName(),
+			Location: domain.Location{
+				Longitude: m.GetLocation().GetLongitude(),
+				Latitude:  m.GetLocation().GetLatitude(),
+			},
+			Distance: m.GetDistance(),
+		}
+	}
+	repair.RepairCost = &domain.RepairCost{
+		ID:           cost.GetId(),
+		UserID:       cost.GetUserId(),
+		RepairType:   cost.GetRepairType(),
+		TotalPrice:   domain.PriceFromMinor(cost.GetTotalPriceMinor(), cost.GetTotalPrice()),
+		UserLocation: userLocation,
+		Mechanics:    mechanics,
+	}
+	if p := msg.GetPreferredMechanic(); p != nil {
+		repair.RepairCost.Preference = &domain.MechanicPreference{MechanicID: p.GetMechanicId(), Pinned: p.GetPinned()}
+		if p.GetHoldUntilUnixMs() > 0 {
+			holdUntil := time.UnixMilli(p.GetHoldUntilUnixMs()).UTC()
+			repair.RepairCost.Preference.HoldUntil = &holdUntil
+		}
+	}
+	return repair
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"mechanic-service/eventbus"
+	eventkafka "mechanic-service/kafka"
+	"mechanic-service/kafka/consume"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// replayTimeout bounds a whole --replay-dlq run
+	replayTimeout = 10 * time.Minute
+	// replayIdleTimeout ends a --replay-dlq run once the dead letter topic
+	// delivered nothing for that long
+	replayIdleTimeout = 10 * time.Second
+	// replayGroupID is the consumer group of the dead letter topic; its
+	// committed offsets keep a dead letter from being replayed twice
+	replayGroupID = "mechanic-service-dlq-replay"
+)
+
+// replayReport is printed to stdout by --replay-dlq
+type replayReport struct {
+	Service    string         `json:"service"`
+	OK         bool           `json:"ok"`
+	Error      string         `json:"error,omitempty"`
+	DLQTopic   string         `json:"dlqTopic"`
+	Replayed   int            `json:"replayed"`
+	ByTopic    map[string]int `json:"byTopic"` // replayed dead letters per topic they were published to
+	DurationMs int64          `json:"durationMs"`
+}
+
+// runReplayDLQ publishes up to limit dead letters (all of them when limit is
+// negative) back to the topic they failed on, without the dead letter
+// headers, so the consumer handles them again; fix the cause of the failure
+// first. Dead letters are committed once republished, so a later run goes on
+// where this one stopped. It prints a JSON report and returns the process
+// exit code.
+func runReplayDLQ(limit int) int {
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	start := time.Now()
+	report := replayReport{Service: "mechanic-service", DLQTopic: eventkafka.TopicName(eventkafka.DLQTopic()), ByTopic: map[string]int{}}
+	err := replayDLQ(limit, logger, &report)
+	report.OK = err == nil
+	if err != nil {
+		report.Error = err.Error()
+	}
+	report.DurationMs = time.Since(start).Milliseconds()
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
+
+func replayDLQ(limit int, logger *slog.Logger, report *replayReport) error {
+	ctx, cancel := context.WithTimeout(context.Background(), replayTimeout)
+	defer cancel()
+
+	var source consume.Source
+	var producer eventkafka.DeadLetterProducer
+	if eventkafka.EventBus() == eventkafka.BusMongo {
+		mongoURI := os.Getenv("MONGO_URI")
+		if mongoURI == "" {
+			mongoURI = "mongodb://mongodb:27017/repairdb?replicaSet=rs0"
+		}
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+		if err != nil {
+			return fmt.Errorf("failed to connect to MongoDB: %w", err)
+		}
+		defer client.Disconnect(context.Background())
+		db := client.Database("repairdb")
+		source = eventbus.NewSource(db, replayGroupID)
+		producer = eventbus.NewProducer(db)
+	} else {
+		config, err := eventkafka.ClientConfig("kafka:9094", nil)
+		if err != nil {
+			return err
+		}
+		kafkaSource, err := consume.NewKafkaSource(config, replayGroupID)
+		if err != nil {
+			return err
+		}
+		kafkaProducer, err := kafka.NewProducer(config)
+		if err != nil {
+			kafkaSource.Close()
+			return fmt.Errorf("failed to create producer: %w", err)
+		}
+		source, producer = kafkaSource, kafkaProducer
+	}
+	defer source.Close()
+	defer func() {
+		producer.Flush(5000)
+		producer.Close()
+	}()
+
+	if err := source.Subscribe(report.DLQTopic); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", report.DLQTopic, err)
+	}
+	lastRead := time.Now()
+	for limit < 0 || report.Replayed < limit {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		record, err := source.Read(time.Second)
+		if err != nil {
+			return fmt.Errorf("failed to read dead letter: %w", err)
+		}
+		if record == nil {
+			// A Kafka consumer group delivers nothing until its partitions
+			// are assigned, so only a longer silence means the end
+			if time.Since(lastRead) >= replayIdleTimeout {
+				break
+			}
+			continue
+		}
+		lastRead = time.Now()
+
+		msg, failure := replayMessage(record)
+		delivery := make(chan kafka.Event, 1)
+		err = producer.Produce(msg, delivery)
+		if err == nil {
+			select {
+			case e := <-delivery:
+				if delivered, ok := e.(*kafka.Message); ok && delivered.TopicPartition.Error != nil {
+					err = delivered.TopicPartition.Error
+				}
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+		}
+		tp := record.TopicPartition
+		if err != nil {
+			return fmt.Errorf("failed to replay dead letter at partition %d offset %d: %w", tp.Partition, tp.Offset, err)
+		}
+		if err := source.Commit(record); err != nil {
+			return fmt.Errorf("failed to commit dead letter at partition %d offset %d: %w", tp.Partition, tp.Offset, err)
+		}
+		report.Replayed++
+		report.ByTopic[*msg.TopicPartition.Topic]++
+		logger.Info("Replayed dead letter", "partition", tp.Partition, "offset", tp.Offset, "topic", *msg.TopicPartition.Topic, "failure", failure, "app", "mechanic-service")
+	}
+	logger.Info("Replayed dead letters", "dlqTopic", report.DLQTopic, "replayed", report.Replayed, "app", "mechanic-service")
+	return nil
+}
+
+// replayMessage returns the record to republish for a dead letter, its key,
+// value and original headers to the topic it failed on, and the recorded
+// failure. Dead letters without the topic header came from repair-events.
+func replayMessage(record *kafka.Message) (*kafka.Message, string) {
+	topic := eventkafka.TopicName(eventkafka.RepairEventsTopic)
+	var failure string
+	headers := make([]kafka.Header, 0, len(record.Headers))
+	for _, h := range record.Headers {
+		switch h.Key {
+		case consume.HeaderDLQTopic:
+			if len(h.Value) > 0 {
+				topic = string(h.Value)
+			}
+		case consume.HeaderDLQError:
+			failure = string(h.Value)
+		case consume.HeaderDLQPartition, consume.HeaderDLQOffset:
+		default:
+			headers = append(headers, h)
+		}
+	}
+	return &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
+		Key:            record.Key,
+		Value:          record.Value,
+		Headers:        headers,
+	}, failure
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"mechanic-service/eventbus"
+	eventkafka "mechanic-service/kafka"
+	"mechanic-service/projection"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// restoreTimeout bounds a whole --restore-snapshot run
+const restoreTimeout = 10 * time.Minute
+
+// restoredPartition is where the consumer group resumes on a partition
+type restoredPartition struct {
+	Partition int32 `json:"partition"`
+	Offset    int64 `json:"offset"` // next offset consumed
+}
+
+// restoreReport is printed to stdout by --restore-snapshot
+type restoreReport struct {
+	Service    string                    `json:"service"`
+	OK         bool                      `json:"ok"`
+	Error      string                    `json:"error,omitempty"`
+	Restore    *projection.RestoreResult `json:"restore,omitempty"`
+	Resume     []restoredPartition       `json:"resume,omitempty"`
+	DurationMs int64                     `json:"durationMs"`
+}
+
+// runRestoreSnapshot bootstraps the repairs view from a projection snapshot:
+// it rewinds the consumer group to the events after the snapshot, then
+// inserts the snapshot's repairs the view is missing and requeues the stored
+// events after it. Run it while no mechanic-service instance is consuming,
+// e.g. as an init container. It prints a JSON report and returns the process
+// exit code.
+func runRestoreSnapshot(id string) int {
+	mongoURI := os.Getenv("MONGO_URI")
+	if mongoURI == "" {
+		mongoURI = "mongodb://mongodb:27017/repairdb?replicaSet=rs0"
+	}
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	start := time.Now()
+	report := restoreReport{Service: "mechanic-service"}
+	err := restoreSnapshot(id, mongoURI, logger, &report)
+	report.OK = err == nil
+	if err != nil {
+		report.Error = err.Error()
+	}
+	report.DurationMs = time.Since(start).Milliseconds()
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
+
+func restoreSnapshot(id, mongoURI string, logger *slog.Logger, report *restoreReport) error {
+	ctx, cancel := context.WithTimeout(context.Background(), restoreTimeout)
+	defer cancel()
+
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+	if err != nil {
+		return fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+	defer client.Disconnect(context.Background())
+	db := client.Database("repairdb")
+
+	topic := eventkafka.TopicName(eventkafka.RepairEventsTopic)
+	store := projection.NewStore(db, topic, 1, logger)
+	snapshot, err := store.Get(ctx, id)
+	if projection.IsNotFound(err) {
+		return fmt.Errorf("no complete snapshot %q of %s", id, topic)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load snapshot: %w", err)
+	}
+
+	// Rewind first: it fails without changing anything when the topic no
+	// longer holds the events right after the snapshot
+	const groupID = "mechanic-service-group"
+	if eventkafka.EventBus() == eventkafka.BusMongo {
+		offset, ok := snapshot.Offset(0)
+		if !ok {
+			offset = -1
+		}
+		if _, err := eventbus.RewindGroup(ctx, db, topic, groupID, offset); err != nil {
+			return err
+		}
+		report.Resume = append(report.Resume, restoredPartition{Partition: 0, Offset: offset + 1})
+	} else {
+		applied := make(map[int32]int64, len(snapshot.Offsets))
+		for _, o := range snapshot.Offsets {
+			applied[o.Partition] = o.Offset
+		}
+		offsets, err := eventkafka.RewindGroup("kafka:9094", eventkafka.RepairEventsTopic, groupID, applied, 30*time.Second)
+		if err != nil {
+			return err
+		}
+		for _, tp := range offsets {
+			report.Resume = append(report.Resume, restoredPartition{Partition: tp.Partition, Offset: int64(tp.Offset)})
+		}
+	}
+
+	result, err := store.Restore(ctx, snapshot)
+	if err != nil {
+		return err
+	}
+	report.Restore = result
+	logger.Info("Restored projection snapshot", "snapshotID", snapshot.ID, "repairsInserted", result.RepairsInserted, "eventsReplayed", result.EventsReplayed, "app", "mechanic-service")
+	return nil
+}
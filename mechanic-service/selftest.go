@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	eventkafka "mechanic-service/kafka"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/hashicorp/consul/api"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// selfTestTimeout bounds each individual self-test check
+const selfTestTimeout = 5 * time.Second
+
+// selfTestCheck is the outcome of one self-test check
+type selfTestCheck struct {
+	Name       string `json:"name"`
+	Target     string `json:"target"`
+	OK         bool   `json:"ok"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// selfTestReport is printed to stdout by --selftest
+type selfTestReport struct {
+	Service string          `json:"service"`
+	OK      bool            `json:"ok"`
+	Checks  []selfTestCheck `json:"checks"`
+}
+
+// runSelfTest checks every dependency mechanic-service needs, prints a JSON
+// report and returns the process exit code (0 when all checks pass)
+func runSelfTest() int {
+	consulAddr := os.Getenv("CONSUL_ADDRESS")
+	if consulAddr == "" {
+		consulAddr = "consul:8500"
+	}
+	mongoURI := os.Getenv("MONGO_URI")
+	if mongoURI == "" {
+		mongoURI = "mongodb://mongodb:27017/repairdb?replicaSet=rs0"
+	}
+	bootstrapServers := "kafka:9094"
+	schemaRegistryURL := "http://schema-registry:8081"
+
+	report := selfTestReport{Service: "mechanic-service", OK: true}
+	run := func(name, target string, check func(ctx context.Context) error) {
+		ctx, cancel := context.WithTimeout(context.Background(), selfTestTimeout)
+		defer cancel()
+		start := time.Now()
+		result := selfTestCheck{Name: name, Target: target, OK: true}
+		if err := check(ctx); err != nil {
+			result.OK = false
+			result.Error = err.Error()
+			report.OK = false
+		}
+		result.DurationMs = time.Since(start).Milliseconds()
+		report.Checks = append(report.Checks, result)
+	}
+
+	run("mongodb", mongoURI, func(ctx context.Context) error {
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(mongoURI))
+		if err != nil {
+			return fmt.Errorf("failed to connect: %w", err)
+		}
+		defer client.Disconnect(context.Background())
+		if err := client.Ping(ctx, nil); err != nil {
+			return fmt.Errorf("failed to ping: %w", err)
+		}
+		return nil
+	})
+	// The Mongo event bus (EVENT_BUS=mongo) needs neither Kafka nor schema-registry
+	if os.Getenv("EVENT_BUS") != "mongo" {
+		run("kafka", bootstrapServers, func(ctx context.Context) error {
+			config, err := eventkafka.ClientConfig(bootstrapServers, nil)
+			if err != nil {
+				return err
+			}
+			admin, err := kafka.NewAdminClient(config)
+			if err != nil {
+				return fmt.Errorf("failed to create admin client: %w", err)
+			}
+			defer admin.Close()
+			if _, err := admin.GetMetadata(nil, false, int(selfTestTimeout.Milliseconds())); err != nil {
+				return fmt.Errorf("failed to fetch metadata: %w", err)
+			}
+			return nil
+		})
+		run("schema-registry", schemaRegistryURL, func(ctx context.Context) error {
+			return httpCheck(ctx, schemaRegistryURL+"/subjects")
+		})
+	}
+	run("consul", consulAddr, func(ctx context.Context) error {
+		config := api.DefaultConfig()
+		config.Address = consulAddr
+		client, err := api.NewClient(config)
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+		leader, err := client.Status().LeaderWithQueryOptions((&api.QueryOptions{}).WithContext(ctx))
+		if err != nil {
+			return fmt.Errorf("failed to query leader: %w", err)
+		}
+		if leader == "" {
+			return fmt.Errorf("cluster has no leader")
+		}
+		return nil
+	})
+	run("schema", eventkafka.SchemaSubject(eventkafka.RepairEventsTopic), func(ctx context.Context) error {
+		registryURL := schemaRegistryURL
+		if os.Getenv("EVENT_BUS") == "mongo" {
+			registryURL = ""
+		}
+		_, err := eventkafka.LoadSchema(registryURL, eventkafka.SchemaSubject(eventkafka.RepairEventsTopic))
+		return err
+	})
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(report)
+	if !report.OK {
+		return 1
+	}
+	return 0
+}
+
+// httpCheck expects a 2xx response from a GET to url
+func httpCheck(ctx context.Context, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"mechanic-service/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// CreateAbsence schedules an absence for a mechanic. When it starts within
+// ABSENCE_REASSIGN_WARNING_HOURS and the mechanic still holds open repairs,
+// those are returned with a warning so dispatch can reassign them.
+func (s *Service) CreateAbsence(ctx context.Context, absence *domain.Absence) (*domain.AbsenceResult, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceCreateAbsence")
+	defer span.End()
+	span.SetAttributes(attribute.String("mechanicID", absence.MechanicID))
+
+	if err := absence.Validate(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if _, err := s.repo.GetMechanicByID(ctx, absence.MechanicID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find mechanic")
+		s.logger.Error("Failed to find mechanic", "error", err, "mechanicID", absence.MechanicID, "app", "mechanic-service")
+		return nil, fmt.Errorf("failed to find mechanic: %w", err)
+	}
+
+	absence.ID = primitive.NewObjectID().Hex()
+	absence.Start = absence.Start.UTC()
+	absence.End = absence.End.UTC()
+	absence.CreatedAt = time.Now().UTC()
+	if err := s.repo.CreateAbsence(ctx, absence); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to create absence")
+		s.logger.Error("Failed to create absence", "error", err, "mechanicID", absence.MechanicID, "app", "mechanic-service")
+		return nil, err
+	}
+	s.logger.Info("Created absence", "absenceID", absence.ID, "mechanicID", absence.MechanicID, "start", absence.Start, "end", absence.End, "app", "mechanic-service")
+
+	result := &domain.AbsenceResult{Absence: absence}
+	if time.Until(absence.Start) > s.absenceWarning {
+		return result, nil
+	}
+	repairIDs, err := s.repo.OpenAssignedRepairIDs(ctx, absence.MechanicID)
+	if err != nil {
+		// The absence is stored; only the warning is missing
+		span.RecordError(err)
+		s.logger.Error("Failed to check repairs to reassign", "error", err, "mechanicID", absence.MechanicID, "app", "mechanic-service")
+		return result, nil
+	}
+	if len(repairIDs) > 0 {
+		result.RepairsToReassign = repairIDs
+		result.Warning = fmt.Sprintf("mechanic has %d open repairs that need reassignment before %s", len(repairIDs), absence.Start.Format(time.RFC3339))
+		s.logger.Warn("Upcoming absence leaves repairs to reassign", "absenceID", absence.ID, "mechanicID", absence.MechanicID, "start", absence.Start, "repairIDs", repairIDs, "app", "mechanic-service")
+	}
+	span.SetAttributes(attribute.Int("repairsToReassign", len(repairIDs)))
+	return result, nil
+}
+
+// ListAbsences returns a mechanic's current and upcoming absences
+func (s *Service) ListAbsences(ctx context.Context, mechanicID string) ([]*domain.Absence, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceListAbsences")
+	defer span.End()
+
+	absences, err := s.repo.ListAbsences(ctx, mechanicID, time.Now())
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to list absences")
+		s.logger.Error("Failed to list absences", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
+		return nil, err
+	}
+	return absences, nil
+}
+
+// DeleteAbsence cancels one of a mechanic's absences
+func (s *Service) DeleteAbsence(ctx context.Context, mechanicID, absenceID string) error {
+	ctx, span := s.tracer.Start(ctx, "ServiceDeleteAbsence")
+	defer span.End()
+
+	if err := s.repo.DeleteAbsence(ctx, mechanicID, absenceID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to delete absence")
+		s.logger.Error("Failed to delete absence", "error", err, "mechanicID", mechanicID, "absenceID", absenceID, "app", "mechanic-service")
+		return err
+	}
+	s.logger.Info("Deleted absence", "absenceID", absenceID, "mechanicID", mechanicID, "app", "mechanic-service")
+	return nil
+}
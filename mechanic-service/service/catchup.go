@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"mechanic-service/cdc"
+	"time"
+)
+
+// kafkaProbeTimeout bounds each broker reachability check
+const kafkaProbeTimeout = 5 * time.Second
+
+// monitorKafka probes the brokers every interval. While they are unreachable
+// it runs time-bounded change stream catch-ups so new repairs still reach the
+// repairs view; once Kafka is back the consumer resumes from its committed
+// offsets and replaces the bridged repairs with the Kafka events.
+func (s *Service) monitorKafka(ctx context.Context, catchUp *cdc.CatchUp, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	down := false
+	bridged := 0
+	var since time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if err := s.KafkaConsumer.Ping(kafkaProbeTimeout); err == nil {
+			if down {
+				s.logger.Info("Kafka reachable again, resuming primary ingestion", "bridgedRepairs", bridged, "app", "mechanic-service")
+				down = false
+				bridged = 0
+			}
+			continue
+		} else if !down {
+			down = true
+			// Start from the last event Kafka delivered; without one, cover the
+			// last interval so repairs created while the probe failed are included
+			since = s.KafkaConsumer.LastMessageTime()
+			if since.IsZero() {
+				since = time.Now().Add(-interval)
+			}
+			s.logger.Warn("Kafka unreachable, starting change stream catch-up", "error", err, "since", since, "app", "mechanic-service")
+		}
+
+		runStart := time.Now()
+		inserted, err := catchUp.Run(ctx, since)
+		bridged += inserted
+		if err != nil {
+			s.logger.Error("Change stream catch-up failed", "error", err, "inserted", inserted, "app", "mechanic-service")
+			continue
+		}
+		s.logger.Info("Change stream catch-up finished", "inserted", inserted, "since", since, "app", "mechanic-service")
+		since = runStart
+	}
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"mechanic-service/domain"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// SearchMechanics returns a page of the mechanic directory users browse to
+// choose a mechanic. Name, rating and skills are filtered in the database;
+// a search near a point narrows the database read to the bounding box of the
+// radius and keeps the mechanics within it, ordering and paging those here.
+func (s *Service) SearchMechanics(ctx context.Context, search *domain.MechanicSearch) (*domain.MechanicPage, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceSearchMechanics")
+	defer span.End()
+
+	if err := search.Validate(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(
+		attribute.String("name", search.Name),
+		attribute.Float64("minRating", search.MinRating),
+		attribute.StringSlice("skills", search.Skills),
+		attribute.Bool("near", search.Near != nil),
+		attribute.String("sort", search.Sort),
+		attribute.Int("page", search.Page),
+	)
+
+	filter := domain.MechanicFilter{Name: search.Name, MinRating: search.MinRating, Skills: search.Skills}
+	var mechanics []*domain.Mechanic
+	var total int64
+	distances := map[string]float64{}
+	if search.Near != nil {
+		box := boxAround(*search.Near, search.RadiusKm)
+		filter.Box = &box
+		candidates, err := s.repo.FindMechanics(ctx, filter, directoryOrder(search.Sort))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to find mechanics")
+			s.logger.Error("Failed to find mechanics", "error", err, "app", "mechanic-service")
+			return nil, err
+		}
+		for _, m := range candidates {
+			if d := s.haversine(*search.Near, m.Location); d <= search.RadiusKm {
+				distances[m.ID] = d
+				mechanics = append(mechanics, m)
+			}
+		}
+		if search.Sort == domain.DirectorySortDistance {
+			sort.SliceStable(mechanics, func(i, j int) bool { return distances[mechanics[i].ID] < distances[mechanics[j].ID] })
+		}
+		total = int64(len(mechanics))
+		from := min((search.Page-1)*search.PageSize, len(mechanics))
+		mechanics = mechanics[from:min(from+search.PageSize, len(mechanics))]
+	} else {
+		var err error
+		total, err = s.repo.CountMechanics(ctx, filter)
+		if err == nil {
+			opts := directoryOrder(search.Sort)
+			opts.Skip, opts.Limit = int64((search.Page-1)*search.PageSize), int64(search.PageSize)
+			mechanics, err = s.repo.FindMechanics(ctx, filter, opts)
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to find mechanics")
+			s.logger.Error("Failed to find mechanics", "error", err, "app", "mechanic-service")
+			return nil, err
+		}
+	}
+
+	page := &domain.MechanicPage{Mechanics: []*domain.MechanicProfile{}, Page: search.Page, PageSize: search.PageSize, Total: total}
+	if len(mechanics) == 0 {
+		return page, nil
+	}
+	ids := make([]string, len(mechanics))
+	for i, m := range mechanics {
+		ids[i] = m.ID
+	}
+	completed, err := s.repo.CompletedRepairCounts(ctx, ids)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to count completed repairs")
+		s.logger.Error("Failed to count completed repairs", "error", err, "app", "mechanic-service")
+		return nil, err
+	}
+	for _, m := range mechanics {
+		profile := domain.NewMechanicProfile(m, completed[m.ID])
+		if d, ok := distances[m.ID]; ok {
+			d = math.Round(d*10) / 10
+			profile.DistanceKm = &d
+		}
+		page.Mechanics = append(page.Mechanics, profile)
+	}
+	span.SetAttributes(attribute.Int64("total", total), attribute.Int("mechanicCount", len(page.Mechanics)))
+	return page, nil
+}
+
+// directoryOrder returns the database order of a directory sort; mechanics
+// sorted by distance are read best rated first and reordered once their
+// distance is known
+func directoryOrder(order string) *domain.QueryOptions {
+	if order == domain.DirectorySortName {
+		return &domain.QueryOptions{Sort: []domain.SortField{{Field: "name"}, {Field: "_id"}}}
+	}
+	return &domain.QueryOptions{Sort: []domain.SortField{{Field: "rating", Descending: true}, {Field: "_id"}}}
+}
+
+// boxAround returns the bounding box of a circle of radius km around loc
+func boxAround(loc domain.Location, radiusKm float64) domain.BoundingBox {
+	const kmPerDegree = 111.32
+	dLat := radiusKm / kmPerDegree
+	dLon := 180.0
+	if cos := math.Cos(loc.Latitude * math.Pi / 180); cos > 1e-6 {
+		dLon = math.Min(radiusKm/(kmPerDegree*cos), 180)
+	}
+	return domain.BoundingBox{
+		MinLatitude:  math.Max(loc.Latitude-dLat, -90),
+		MaxLatitude:  math.Min(loc.Latitude+dLat, 90),
+		MinLongitude: math.Max(loc.Longitude-dLon, -180),
+		MaxLongitude: math.Min(loc.Longitude+dLon, 180),
+	}
+}
@@ -0,0 +1,144 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"mechanic-service/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// etaConfig sets how ETAs are estimated and when countdown and late events go out
+type etaConfig struct {
+	speedKmh      float64       // average travel speed for ETAs the mechanic does not give
+	countdownLead time.Duration // countdown event this long before the ETA
+	lateRepeat    time.Duration // late events repeat this often while the mechanic has not arrived
+	interval      time.Duration
+	batchSize     int
+}
+
+// commitETA returns the ETA commitment of an assignment made at now. A
+// mechanic who gives no ETA gets one from the straight line distance at the
+// average speed. ETAs within the countdown lead get no countdown event.
+func (s *Service) commitETA(mechanic *domain.Mechanic, repair *domain.Repair, eta time.Duration, now time.Time) *domain.ETACommitment {
+	commitment := &domain.ETACommitment{CommittedAt: now}
+	if eta <= 0 {
+		if repair.RepairCost == nil || repair.RepairCost.UserLocation == nil {
+			return nil
+		}
+		km := s.haversine(mechanic.Location, *repair.RepairCost.UserLocation)
+		minutes := math.Max(1, math.Ceil(km/s.eta.speedKmh*60))
+		eta = time.Duration(minutes) * time.Minute
+		commitment.Estimated = true
+	}
+	commitment.At = now.Add(eta)
+	if eta <= s.eta.countdownLead {
+		commitment.CountdownSentAt = &now
+	}
+	return commitment
+}
+
+// runETAMonitor sends countdown and late events for assigned repairs until
+// ctx is canceled
+func (s *Service) runETAMonitor(ctx context.Context) error {
+	ticker := time.NewTicker(s.eta.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			s.sendETAEvents(ctx)
+		}
+	}
+}
+
+// sendETAEvents sends the countdown and late events that are due. Each event
+// is recorded on the repair and queued in assignment_outbox in one
+// transaction, and the assignment outbox processor publishes it to
+// assignment-events; the conditional update lets only one instance send it.
+func (s *Service) sendETAEvents(ctx context.Context) {
+	ctx, span := s.tracer.Start(ctx, "ServiceSendETAEvents")
+	defer span.End()
+
+	now := time.Now().UTC()
+	repairs, err := s.repo.DueETARepairs(ctx, now, s.eta.countdownLead, s.eta.lateRepeat, s.eta.batchSize)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find repairs with due ETA events")
+		s.logger.Error("Failed to find repairs with due ETA events", "error", err, "app", "mechanic-service")
+		return
+	}
+	sent := 0
+	for _, repair := range repairs {
+		if repair.ETA == nil {
+			continue
+		}
+		event := &domain.ETAEvent{
+			RepairID:     repair.ID,
+			UserID:       repair.UserID,
+			MechanicID:   repair.AssignedTo,
+			CommittedETA: repair.ETA.At,
+			At:           now,
+		}
+		if now.Before(repair.ETA.At) {
+			event.Type = domain.EventRepairETACountdown
+			event.MinutesLeft = int(math.Ceil(repair.ETA.At.Sub(now).Minutes()))
+		} else {
+			event.Type = domain.EventRepairETALate
+			event.MinutesLate = int(now.Sub(repair.ETA.At).Minutes())
+		}
+		ok, err := s.recordETAEvent(ctx, repair, event)
+		if err != nil {
+			span.RecordError(err)
+			s.logger.Error("Failed to send ETA event", "error", err, "repairID", repair.ID, "eventType", event.Type, "app", "mechanic-service")
+			continue
+		}
+		if !ok {
+			continue
+		}
+		sent++
+		if event.Type == domain.EventRepairETALate {
+			s.logger.Warn("Mechanic is running late", "repairID", repair.ID, "mechanicID", event.MechanicID, "minutesLate", event.MinutesLate, "app", "mechanic-service")
+		} else {
+			s.logger.Info("Mechanic is arriving soon", "repairID", repair.ID, "mechanicID", event.MechanicID, "minutesLeft", event.MinutesLeft, "app", "mechanic-service")
+		}
+	}
+	span.SetAttributes(
+		attribute.Int("dueCount", len(repairs)),
+		attribute.Int("sentCount", sent),
+	)
+}
+
+// recordETAEvent records the event on the repair and queues it in the
+// assignment outbox, or returns false if the repair no longer needs it
+func (s *Service) recordETAEvent(ctx context.Context, repair *domain.Repair, event *domain.ETAEvent) (bool, error) {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal ETA event: %w", err)
+	}
+	var recorded bool
+	err = s.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		if recorded, err = s.repo.RecordETAEvent(tx, repair, event); err != nil || !recorded {
+			return err
+		}
+		return s.repo.SaveAssignmentEvent(tx, &domain.AssignmentEvent{
+			ID:          primitive.NewObjectID().Hex(),
+			EventType:   event.Type,
+			AggregateID: repair.ID,
+			Payload:     payload,
+			CreatedAt:   time.Now(),
+			Processed:   false,
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+	return recorded, nil
+}
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"mechanic-service/domain"
+	"mechanic-service/eventbus"
+	"mechanic-service/kafka"
+
+	ckafka "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// eventTapTimeout bounds reading a topic for the event tap
+const eventTapTimeout = 10 * time.Second
+
+// TapEvents returns the last limit records of a logical topic decoded for
+// incident triage, without committing offsets for any consumer group
+func (s *Service) TapEvents(ctx context.Context, logical string, limit int) (*kafka.EventTap, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceTapEvents")
+	defer span.End()
+
+	topic := kafka.TopicName(logical)
+	tap := &kafka.EventTap{Topic: topic, Bus: kafka.EventBus()}
+	span.SetAttributes(attribute.String("topic", topic), attribute.String("eventBus", tap.Bus), attribute.Int("limit", limit))
+
+	var messages []*ckafka.Message
+	complete := true
+	var err error
+	mongoRepo, mongoBacked := s.repo.(domain.MongoBacked)
+	switch {
+	case tap.Bus != kafka.BusMongo:
+		messages, complete, err = kafka.TailTopic("kafka:9094", topic, limit, eventTapTimeout)
+	case !mongoBacked:
+		err = errors.New("the Mongo event bus needs the mongo storage backend")
+	default:
+		tailCtx, cancel := context.WithTimeout(ctx, eventTapTimeout)
+		defer cancel()
+		messages, err = eventbus.Tail(tailCtx, mongoRepo.GetMongoClient(ctx).Database("repairdb"), topic, limit)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to tap events")
+		s.logger.Error("Failed to tap events", "error", err, "topic", topic, "app", "mechanic-service")
+		return nil, err
+	}
+	tap.Events = kafka.DecodeTapped(messages, s.schemas)
+	tap.Incomplete = !complete
+	span.SetAttributes(attribute.Int("eventCount", len(tap.Events)), attribute.Bool("incomplete", tap.Incomplete))
+	s.logger.Info("Tapped events", "topic", topic, "count", len(tap.Events), "incomplete", tap.Incomplete, "app", "mechanic-service")
+	return tap, nil
+}
@@ -0,0 +1,330 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mechanic-service/domain"
+	"os"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Import formats accepted by ImportMechanics
+const (
+	ImportFormatCSV  = "csv"
+	ImportFormatJSON = "json"
+)
+
+// Per-row import outcomes
+const (
+	ImportApplied = "applied"
+	ImportSkipped = "skipped"
+	ImportError   = "error"
+)
+
+// ImportRowResult is the outcome of a single imported row. Row is 1-based and
+// counts records, so the first line after a CSV header is row 1.
+type ImportRowResult struct {
+	Row     int    `json:"row"`
+	ID      string `json:"id,omitempty"`
+	Outcome string `json:"outcome"`
+	Message string `json:"message,omitempty"`
+}
+
+// ImportReport summarizes a mechanic import
+type ImportReport struct {
+	Applied int               `json:"applied"`
+	Skipped int               `json:"skipped"`
+	Errors  int               `json:"errors"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+// importRow is a parsed record before validation; coordinates are pointers so
+// missing values can be told apart from zero
+type importRow struct {
+	ID        string
+	Name      string
+	Latitude  *float64
+	Longitude *float64
+	Status    string
+	Skills    []string
+	// Optional; like the coordinates NaN when unparseable
+	Rating         *float64
+	AcceptanceRate *float64
+}
+
+// importBatchSize returns the number of mechanics upserted per bulk write
+func importBatchSize() int {
+	if v, err := strconv.Atoi(os.Getenv("MECHANIC_IMPORT_BATCH_SIZE")); err == nil && v > 0 {
+		return v
+	}
+	return 100
+}
+
+// ImportMechanics parses mechanics from a CSV or JSON file, validates each row
+// and upserts the valid ones in batches. Rows repeating an earlier ID are
+// skipped. A file that cannot be parsed at all yields domain.ErrInvalidInput.
+func (s *Service) ImportMechanics(ctx context.Context, format string, r io.Reader) (*ImportReport, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceImportMechanics")
+	defer span.End()
+	span.SetAttributes(attribute.String("format", format))
+
+	var rows []importRow
+	var err error
+	switch format {
+	case ImportFormatCSV:
+		rows, err = parseMechanicCSV(r)
+	case ImportFormatJSON:
+		rows, err = parseMechanicJSON(r)
+	default:
+		err = fmt.Errorf("%w: unsupported import format %q", domain.ErrInvalidInput, format)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to parse import file")
+		s.logger.Error("Failed to parse import file", "error", err, "format", format, "app", "mechanic-service")
+		return nil, err
+	}
+
+	report := &ImportReport{Rows: make([]ImportRowResult, len(rows))}
+	firstSeen := make(map[string]int)
+	var batch []*domain.Mechanic
+	var batchRows []int
+	batchSize := importBatchSize()
+
+	for i, row := range rows {
+		result := &report.Rows[i]
+		result.Row = i + 1
+		result.ID = row.ID
+
+		mechanic, err := validateImportRow(row)
+		if err != nil {
+			result.Outcome = ImportError
+			result.Message = err.Error()
+			continue
+		}
+		if first, ok := firstSeen[row.ID]; ok {
+			result.Outcome = ImportSkipped
+			result.Message = fmt.Sprintf("duplicate id, first seen on row %d", first)
+			continue
+		}
+		firstSeen[row.ID] = result.Row
+
+		batch = append(batch, mechanic)
+		batchRows = append(batchRows, i)
+		if len(batch) == batchSize {
+			s.upsertImportBatch(ctx, batch, batchRows, report)
+			batch, batchRows = nil, nil
+		}
+	}
+	if len(batch) > 0 {
+		s.upsertImportBatch(ctx, batch, batchRows, report)
+	}
+
+	for _, result := range report.Rows {
+		switch result.Outcome {
+		case ImportApplied:
+			report.Applied++
+		case ImportSkipped:
+			report.Skipped++
+		case ImportError:
+			report.Errors++
+		}
+	}
+	span.SetAttributes(
+		attribute.Int("rowCount", len(rows)),
+		attribute.Int("applied", report.Applied),
+		attribute.Int("skipped", report.Skipped),
+		attribute.Int("errors", report.Errors),
+	)
+	s.logger.Info("Imported mechanics", "rows", len(rows), "applied", report.Applied, "skipped", report.Skipped, "errors", report.Errors, "app", "mechanic-service")
+	return report, nil
+}
+
+// upsertImportBatch writes one batch and records the outcome of every row in it.
+// A failed batch marks its rows as errors without aborting the import.
+func (s *Service) upsertImportBatch(ctx context.Context, batch []*domain.Mechanic, batchRows []int, report *ImportReport) {
+	failed, err := s.repo.UpsertMechanics(ctx, batch)
+	if err != nil {
+		s.logger.Error("Failed to upsert mechanic batch", "error", err, "batchSize", len(batch), "app", "mechanic-service")
+	}
+	for j, idx := range batchRows {
+		result := &report.Rows[idx]
+		switch {
+		case err != nil:
+			result.Outcome = ImportError
+			result.Message = err.Error()
+		case failed[j] != nil:
+			result.Outcome = ImportError
+			result.Message = failed[j].Error()
+		default:
+			result.Outcome = ImportApplied
+		}
+	}
+}
+
+// validateImportRow checks required fields and coordinate ranges
+func validateImportRow(row importRow) (*domain.Mechanic, error) {
+	var problems []string
+	if row.ID == "" {
+		problems = append(problems, "id is required")
+	}
+	if row.Name == "" {
+		problems = append(problems, "name is required")
+	}
+	switch {
+	case row.Latitude == nil:
+		problems = append(problems, "latitude is required")
+	case math.IsNaN(*row.Latitude):
+		problems = append(problems, "latitude must be a number")
+	case *row.Latitude < -90 || *row.Latitude > 90:
+		problems = append(problems, "latitude must be between -90 and 90")
+	}
+	switch {
+	case row.Longitude == nil:
+		problems = append(problems, "longitude is required")
+	case math.IsNaN(*row.Longitude):
+		problems = append(problems, "longitude must be a number")
+	case *row.Longitude < -180 || *row.Longitude > 180:
+		problems = append(problems, "longitude must be between -180 and 180")
+	}
+	if row.Status != "" && row.Status != domain.MechanicOnline && row.Status != domain.MechanicOffline {
+		problems = append(problems, fmt.Sprintf("status must be %q or %q", domain.MechanicOnline, domain.MechanicOffline))
+	}
+	if row.Rating != nil && !(*row.Rating >= 0 && *row.Rating <= domain.MaxRating) {
+		problems = append(problems, fmt.Sprintf("rating must be between 0 and %g", domain.MaxRating))
+	}
+	if row.AcceptanceRate != nil && !(*row.AcceptanceRate >= 0 && *row.AcceptanceRate <= 1) {
+		problems = append(problems, "acceptanceRate must be between 0 and 1")
+	}
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+	return &domain.Mechanic{
+		ID:       row.ID,
+		Name:     row.Name,
+		Location: domain.Location{Latitude: *row.Latitude, Longitude: *row.Longitude},
+		Status:   row.Status,
+		Skills:   row.Skills,
+		// Replacing the document clears a rating the file leaves out
+		Rating:         row.Rating,
+		AcceptanceRate: row.AcceptanceRate,
+	}, nil
+}
+
+// parseMechanicCSV reads a CSV file with a header row naming the columns id,
+// name, latitude, longitude and optionally status, skills (separated by ';'),
+// rating and acceptance_rate
+func parseMechanicCSV(r io.Reader) ([]importRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to read CSV header: %w", domain.ErrInvalidInput, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Spreadsheet exports often prefix the first column with a UTF-8 BOM
+		name = strings.TrimPrefix(name, "\ufeff")
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"id", "name", "latitude", "longitude"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: CSV header is missing column %q", domain.ErrInvalidInput, required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+	// Unparseable numbers become NaN so they are reported as invalid rather
+	// than missing
+	number := func(record []string, name string) *float64 {
+		raw := field(record, name)
+		if raw == "" {
+			return nil
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			v = math.NaN()
+		}
+		return &v
+	}
+
+	var rows []importRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to read CSV: %w", domain.ErrInvalidInput, err)
+		}
+		row := importRow{
+			ID:             field(record, "id"),
+			Name:           field(record, "name"),
+			Latitude:       number(record, "latitude"),
+			Longitude:      number(record, "longitude"),
+			Status:         field(record, "status"),
+			Rating:         number(record, "rating"),
+			AcceptanceRate: number(record, "acceptance_rate"),
+		}
+		for _, skill := range strings.Split(field(record, "skills"), ";") {
+			if skill = strings.TrimSpace(skill); skill != "" {
+				row.Skills = append(row.Skills, skill)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// parseMechanicJSON reads a JSON array of mechanics in the domain.Mechanic shape
+func parseMechanicJSON(r io.Reader) ([]importRow, error) {
+	var records []struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Location *struct {
+			Latitude  *float64 `json:"latitude"`
+			Longitude *float64 `json:"longitude"`
+		} `json:"location"`
+		Status         string   `json:"status"`
+		Skills         []string `json:"skills"`
+		Rating         *float64 `json:"rating"`
+		AcceptanceRate *float64 `json:"acceptanceRate"`
+	}
+	if err := json.NewDecoder(r).Decode(&records); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode JSON: %w", domain.ErrInvalidInput, err)
+	}
+
+	rows := make([]importRow, len(records))
+	for i, rec := range records {
+		rows[i] = importRow{
+			ID:             strings.TrimSpace(rec.ID),
+			Name:           strings.TrimSpace(rec.Name),
+			Status:         rec.Status,
+			Skills:         rec.Skills,
+			Rating:         rec.Rating,
+			AcceptanceRate: rec.AcceptanceRate,
+		}
+		if rec.Location != nil {
+			rows[i].Latitude = rec.Location.Latitude
+			rows[i].Longitude = rec.Location.Longitude
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"mechanic-service/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// locationConfig sets which location updates go out as events. Devices report
+// about once a second; an event goes out only when the mechanic moved far
+// enough since the last one or, while standing still, as a heartbeat.
+type locationConfig struct {
+	minMeters float64       // movement since the last event that emits a new one
+	heartbeat time.Duration // an event goes out at least this often while updates arrive
+}
+
+// locationThrottle remembers the last emitted location of each mechanic. It
+// is per instance; with several instances a mechanic whose updates are spread
+// across them emits at most once per instance and interval.
+type locationThrottle struct {
+	mu   sync.Mutex
+	last map[string]emittedLocation
+}
+
+type emittedLocation struct {
+	location domain.Location
+	at       time.Time
+}
+
+// decide returns whether an update of mechanicID to location at the given
+// time emits an event, why, and how far the mechanic moved since the last
+// event, recording it as emitted when it does
+func (t *locationThrottle) decide(cfg locationConfig, mechanicID string, location domain.Location, at time.Time, distance func(l1, l2 domain.Location) float64) (bool, string, float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	last, ok := t.last[mechanicID]
+	var moved float64
+	reason := "first"
+	if ok {
+		if !at.After(last.at) {
+			return false, "throttled", 0 // out of order or duplicate
+		}
+		moved = distance(last.location, location) * 1000
+		switch {
+		case moved >= cfg.minMeters:
+			reason = "moved"
+		case at.Sub(last.at) >= cfg.heartbeat:
+			reason = "heartbeat"
+		default:
+			return false, "throttled", moved
+		}
+	}
+	t.last[mechanicID] = emittedLocation{location: location, at: at}
+	return true, reason, moved
+}
+
+// forget drops the mechanic's last emitted location so the next update emits
+func (t *locationThrottle) forget(mechanicID string) {
+	t.mu.Lock()
+	delete(t.last, mechanicID)
+	t.mu.Unlock()
+}
+
+// UpdateLocation records a reported position of a mechanic. Every position
+// is kept in mechanic_positions for ETAs and routes; the mechanic's location
+// is updated, and a mechanic_location_updated event queued in
+// assignment_outbox for the assignment outbox processor to publish to
+// assignment-events, only when the throttle lets the update through.
+func (s *Service) UpdateLocation(ctx context.Context, update *domain.LocationUpdate) (*domain.LocationResult, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceUpdateLocation")
+	defer span.End()
+	span.SetAttributes(attribute.String("mechanicID", update.MechanicID))
+
+	now := time.Now().UTC()
+	if update.RecordedAt.IsZero() {
+		update.RecordedAt = now
+	}
+	update.RecordedAt = update.RecordedAt.UTC()
+	if err := update.Validate(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	position := &domain.MechanicPosition{
+		ID:         primitive.NewObjectID().Hex(),
+		MechanicID: update.MechanicID,
+		Location:   update.Location,
+		RecordedAt: update.RecordedAt,
+		ReceivedAt: now,
+	}
+	if err := s.repo.SavePosition(ctx, position); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save position")
+		s.logger.Error("Failed to save position", "error", err, "mechanicID", update.MechanicID, "app", "mechanic-service")
+		return nil, err
+	}
+
+	emit, reason, moved := s.locations.decide(s.location, update.MechanicID, update.Location, update.RecordedAt, s.haversine)
+	span.SetAttributes(attribute.Bool("emitted", emit), attribute.String("reason", reason), attribute.Float64("movedMeters", moved))
+	result := &domain.LocationResult{Emitted: emit, Reason: reason}
+	if !emit {
+		return result, nil
+	}
+
+	payload, err := json.Marshal(&domain.MechanicLocationEvent{
+		MechanicID:  update.MechanicID,
+		Location:    update.Location,
+		MovedMeters: moved,
+		RecordedAt:  update.RecordedAt,
+	})
+	if err != nil {
+		s.locations.forget(update.MechanicID)
+		return nil, fmt.Errorf("failed to marshal location event: %w", err)
+	}
+	err = s.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		if err := s.repo.UpdateMechanicLocation(tx, update.MechanicID, update.Location); err != nil {
+			return err
+		}
+		return s.repo.SaveAssignmentEvent(tx, &domain.AssignmentEvent{
+			ID:          primitive.NewObjectID().Hex(),
+			EventType:   domain.EventMechanicLocationUpdated,
+			AggregateID: update.MechanicID,
+			Payload:     payload,
+			CreatedAt:   now,
+			Processed:   false,
+		})
+	})
+	if err != nil {
+		// Let the next update try again
+		s.locations.forget(update.MechanicID)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to emit location update")
+		s.logger.Error("Failed to emit location update", "error", err, "mechanicID", update.MechanicID, "app", "mechanic-service")
+		return nil, err
+	}
+	s.logger.Debug("Emitted location update", "mechanicID", update.MechanicID, "reason", reason, "movedMeters", moved, "app", "mechanic-service")
+	return result, nil
+}
+
+// currentLocation returns where the mechanic is: the latest raw position while
+// one is kept, else the last emitted location
+func (s *Service) currentLocation(ctx context.Context, mechanic *domain.Mechanic) domain.Location {
+	position, err := s.repo.LatestPosition(ctx, mechanic.ID)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			s.logger.Warn("Failed to find latest position, using the mechanic's location", "error", err, "mechanicID", mechanic.ID, "app", "mechanic-service")
+		}
+		return mechanic.Location
+	}
+	return position.Location
+}
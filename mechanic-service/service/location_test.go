@@ -0,0 +1,207 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"mechanic-service/domain"
+	"mechanic-service/kafka"
+
+	ckafka "github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"go.opentelemetry.io/otel"
+)
+
+// outboxRepo keeps assignment_outbox in memory; the other repository methods
+// are not called by the tested paths
+type outboxRepo struct {
+	domain.MechanicRepository
+	mu      sync.Mutex
+	events  []*domain.AssignmentEvent
+	expires map[string]*time.Time
+	due     []*domain.Repair
+}
+
+func (r *outboxRepo) SavePosition(ctx context.Context, position *domain.MechanicPosition) error {
+	return nil
+}
+
+func (r *outboxRepo) UpdateMechanicLocation(ctx context.Context, mechanicID string, location domain.Location) error {
+	return nil
+}
+
+func (r *outboxRepo) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (r *outboxRepo) DueETARepairs(ctx context.Context, now time.Time, countdownLead, lateRepeat time.Duration, limit int) ([]*domain.Repair, error) {
+	return r.due, nil
+}
+
+func (r *outboxRepo) RecordETAEvent(ctx context.Context, repair *domain.Repair, event *domain.ETAEvent) (bool, error) {
+	return true, nil
+}
+
+func (r *outboxRepo) SaveAssignmentEvent(ctx context.Context, event *domain.AssignmentEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *outboxRepo) GetUnprocessedAssignmentEvents(ctx context.Context, limit int) ([]*domain.AssignmentEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []*domain.AssignmentEvent
+	for _, e := range r.events {
+		if !e.Processed && len(events) < limit {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (r *outboxRepo) MarkAssignmentEventProcessed(ctx context.Context, eventID string, expireAt *time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.events {
+		if e.ID == eventID {
+			e.Processed = true
+			r.expires[eventID] = expireAt
+		}
+	}
+	return nil
+}
+
+// recordingProducer acknowledges every message it is given
+type recordingProducer struct {
+	messages []*ckafka.Message
+}
+
+func (p *recordingProducer) Produce(msg *ckafka.Message, deliveryChan chan ckafka.Event) error {
+	p.messages = append(p.messages, msg)
+	deliveryChan <- msg
+	return nil
+}
+
+func header(msg *ckafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+func newOutboxTest(t *testing.T) (*Service, *outboxRepo, *kafka.AssignmentOutboxProcessor, *recordingProducer) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	repo := &outboxRepo{expires: map[string]*time.Time{}}
+	producer := &recordingProducer{}
+	svc := &Service{
+		repo:      repo,
+		tracer:    otel.Tracer("mechanic-service-test"),
+		logger:    logger,
+		location:  locationConfig{minMeters: 50, heartbeat: 30 * time.Second},
+		locations: &locationThrottle{last: map[string]emittedLocation{}},
+		eta:       etaConfig{countdownLead: 5 * time.Minute, lateRepeat: 5 * time.Minute, batchSize: 100},
+	}
+	relay := kafka.NewAssignmentOutboxProcessor(repo, producer, "assignment-events", logger, time.Second, time.Hour)
+	return svc, repo, relay, producer
+}
+
+// TestLocationUpdatePublished checks a location update the throttle lets
+// through is produced to assignment-events, and a throttled one is not
+func TestLocationUpdatePublished(t *testing.T) {
+	svc, repo, relay, producer := newOutboxTest(t)
+	ctx := context.Background()
+	start := time.Now().Add(-time.Minute)
+
+	updates := []struct {
+		location domain.Location
+		at       time.Time
+		emitted  bool
+	}{
+		{domain.Location{Latitude: 52.52, Longitude: 13.405}, start, true},
+		{domain.Location{Latitude: 52.5201, Longitude: 13.405}, start.Add(time.Second), false}, // about 11 m
+		{domain.Location{Latitude: 52.53, Longitude: 13.405}, start.Add(2 * time.Second), true},
+	}
+	for i, u := range updates {
+		result, err := svc.UpdateLocation(ctx, &domain.LocationUpdate{MechanicID: "mechanic1", Location: u.location, RecordedAt: u.at})
+		if err != nil {
+			t.Fatalf("update %d: %v", i, err)
+		}
+		if result.Emitted != u.emitted {
+			t.Errorf("update %d: emitted %v (%s), want %v", i, result.Emitted, result.Reason, u.emitted)
+		}
+	}
+
+	published, err := relay.PublishPending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if published != 2 || len(producer.messages) != 2 {
+		t.Fatalf("published %d events, produced %d messages, want 2", published, len(producer.messages))
+	}
+	for i, msg := range producer.messages {
+		if *msg.TopicPartition.Topic != "assignment-events" || string(msg.Key) != "mechanic1" {
+			t.Errorf("message %d: topic %s key %s", i, *msg.TopicPartition.Topic, msg.Key)
+		}
+		if got := header(msg, "event_type"); got != domain.EventMechanicLocationUpdated {
+			t.Errorf("message %d: event_type %q", i, got)
+		}
+		if header(msg, "event_id") != repo.events[i].ID {
+			t.Errorf("message %d: event_id %q, want %q", i, header(msg, "event_id"), repo.events[i].ID)
+		}
+		var event domain.MechanicLocationEvent
+		if err := json.Unmarshal(msg.Value, &event); err != nil {
+			t.Fatalf("message %d: %v", i, err)
+		}
+		if event.MechanicID != "mechanic1" || event.Location != updates[i*2].location {
+			t.Errorf("message %d: payload %+v", i, event)
+		}
+		if !repo.events[i].Processed || repo.expires[repo.events[i].ID] == nil {
+			t.Errorf("event %d: processed %v, expires %v", i, repo.events[i].Processed, repo.expires[repo.events[i].ID])
+		}
+	}
+
+	if published, err := relay.PublishPending(ctx); err != nil || published != 0 {
+		t.Errorf("second publish: %d events, %v; want none", published, err)
+	}
+}
+
+// TestETAEventPublished checks a due late notice is produced to
+// assignment-events keyed by the repair
+func TestETAEventPublished(t *testing.T) {
+	svc, repo, relay, producer := newOutboxTest(t)
+	ctx := context.Background()
+	repo.due = []*domain.Repair{{
+		ID:         "repair1",
+		UserID:     "user1",
+		AssignedTo: "mechanic1",
+		ETA:        &domain.ETACommitment{At: time.Now().Add(-10 * time.Minute)},
+	}}
+
+	svc.sendETAEvents(ctx)
+	if _, err := relay.PublishPending(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(producer.messages) != 1 {
+		t.Fatalf("produced %d messages, want 1", len(producer.messages))
+	}
+	msg := producer.messages[0]
+	if string(msg.Key) != "repair1" || header(msg, "event_type") != domain.EventRepairETALate {
+		t.Errorf("key %s event_type %q", msg.Key, header(msg, "event_type"))
+	}
+	var event domain.ETAEvent
+	if err := json.Unmarshal(msg.Value, &event); err != nil {
+		t.Fatal(err)
+	}
+	if event.RepairID != "repair1" || event.UserID != "user1" || event.MinutesLate < 10 {
+		t.Errorf("payload %+v", event)
+	}
+}
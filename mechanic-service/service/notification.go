@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"mechanic-service/domain"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// GetNotificationPrefs returns a mechanic's notification preferences, or the
+// defaults when the mechanic never set any
+func (s *Service) GetNotificationPrefs(ctx context.Context, mechanicID string) (*domain.NotificationPrefs, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceGetNotificationPrefs")
+	defer span.End()
+	span.SetAttributes(attribute.String("mechanicID", mechanicID))
+
+	prefs, err := s.repo.GetNotificationPrefs(ctx, mechanicID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return domain.DefaultNotificationPrefs(mechanicID), nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get notification preferences")
+		s.logger.Error("Failed to get notification preferences", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
+		return nil, err
+	}
+	return prefs, nil
+}
+
+// UpdateNotificationPrefs validates and stores a mechanic's notification preferences
+func (s *Service) UpdateNotificationPrefs(ctx context.Context, prefs *domain.NotificationPrefs) (*domain.NotificationPrefs, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceUpdateNotificationPrefs")
+	defer span.End()
+	span.SetAttributes(attribute.String("mechanicID", prefs.MechanicID))
+
+	if err := prefs.Validate(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if _, err := s.repo.GetMechanicByID(ctx, prefs.MechanicID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find mechanic")
+		s.logger.Error("Failed to find mechanic", "error", err, "mechanicID", prefs.MechanicID, "app", "mechanic-service")
+		return nil, fmt.Errorf("failed to find mechanic: %w", err)
+	}
+
+	prefs.UpdatedAt = time.Now().UTC()
+	if err := s.repo.SaveNotificationPrefs(ctx, prefs); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save notification preferences")
+		s.logger.Error("Failed to save notification preferences", "error", err, "mechanicID", prefs.MechanicID, "app", "mechanic-service")
+		return nil, err
+	}
+	s.logger.Info("Updated notification preferences", "mechanicID", prefs.MechanicID, "quietHoursStart", prefs.QuietHoursStart, "quietHoursEnd", prefs.QuietHoursEnd, "timezone", prefs.Timezone, "digestFrequency", prefs.DigestFrequency, "app", "mechanic-service")
+	return prefs, nil
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"mechanic-service/domain"
+	"mechanic-service/trip"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// routeConfig sets how mechanics' open repairs are ordered into routes
+type routeConfig struct {
+	trip        *trip.Client  // nil orders stops by straight-line distance only
+	serviceTime time.Duration // time spent at a stop whose repair type has no learned duration
+	maxAge      time.Duration // stored routes older than this are recomputed on read
+}
+
+// MechanicRoute returns the mechanic's open repairs ordered into a route with
+// an ETA per stop. The stored route is returned while it covers the same
+// repairs and is younger than the maximum age, unless refresh is set.
+func (s *Service) MechanicRoute(ctx context.Context, mechanicID string, refresh bool) (*domain.MechanicRoute, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceMechanicRoute")
+	defer span.End()
+	span.SetAttributes(attribute.String("mechanicID", mechanicID), attribute.Bool("refresh", refresh))
+
+	repairs, err := s.repo.OpenAssignedRepairs(ctx, mechanicID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find assigned repairs")
+		s.logger.Error("Failed to find assigned repairs", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
+		return nil, err
+	}
+	if !refresh {
+		stored, err := s.repo.GetMechanicRoute(ctx, mechanicID)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to get mechanic route")
+			return nil, err
+		}
+		if stored != nil && slices.Equal(stored.RepairIDs, repairIDs(repairs)) && time.Since(stored.ComputedAt) < s.route.maxAge {
+			span.SetAttributes(attribute.Bool("cached", true))
+			return stored, nil
+		}
+	}
+	return s.updateMechanicRoute(ctx, mechanicID, repairs)
+}
+
+// updateMechanicRoute computes and stores the route through repairs, the
+// mechanic's open repairs
+func (s *Service) updateMechanicRoute(ctx context.Context, mechanicID string, repairs []*domain.Repair) (*domain.MechanicRoute, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceUpdateMechanicRoute")
+	defer span.End()
+	span.SetAttributes(attribute.String("mechanicID", mechanicID), attribute.Int("repairCount", len(repairs)))
+
+	mechanic, err := s.repo.GetMechanicByID(ctx, mechanicID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find mechanic")
+		s.logger.Error("Failed to find mechanic", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
+		return nil, fmt.Errorf("failed to find mechanic: %w", err)
+	}
+
+	mechanic.Location = s.currentLocation(ctx, mechanic)
+	now := time.Now()
+	route := &domain.MechanicRoute{
+		MechanicID: mechanicID,
+		Origin:     mechanic.Location,
+		RepairIDs:  repairIDs(repairs),
+		Stops:      []domain.RouteStop{},
+		Optimizer:  domain.OptimizerOSRMTrip,
+		ComputedAt: now,
+	}
+	var routed []*domain.Repair
+	var stops []domain.Location
+	for _, repair := range repairs {
+		if repair.RepairCost == nil || repair.RepairCost.UserLocation == nil {
+			route.Unrouted = append(route.Unrouted, repair.ID)
+			continue
+		}
+		routed = append(routed, repair)
+		stops = append(stops, *repair.RepairCost.UserLocation)
+	}
+
+	var plan *trip.Plan
+	if s.route.trip != nil && len(stops) > 0 {
+		if plan, err = s.route.trip.Optimize(ctx, mechanic.Location, stops); err != nil {
+			s.logger.Warn("Failed to optimize route with OSRM, ordering stops by distance", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
+		}
+	}
+	if plan == nil {
+		plan = s.nearestNeighborPlan(mechanic.Location, stops)
+		route.Optimizer = domain.OptimizerNearestNeighbor
+	}
+
+	// Each stop takes its repair type's learned duration
+	repairTypes := make([]string, 0, len(routed))
+	for _, repair := range routed {
+		if !slices.Contains(repairTypes, repair.RepairCost.RepairType) {
+			repairTypes = append(repairTypes, repair.RepairCost.RepairType)
+		}
+	}
+	learned, err := s.repo.LearnedRepairMinutes(ctx, mechanicID, repairTypes)
+	if err != nil {
+		s.logger.Warn("Failed to load learned repair durations, using the service time", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
+	}
+
+	elapsed := 0.0
+	for i, index := range plan.Order {
+		if i > 0 {
+			elapsed += route.Stops[i-1].ServiceDuration
+		}
+		elapsed += plan.Legs[i]
+		repair := routed[index]
+		stop := domain.RouteStop{
+			Sequence:        i + 1,
+			RepairID:        repair.ID,
+			RepairType:      repair.RepairCost.RepairType,
+			Status:          repair.Status,
+			Location:        stops[index],
+			LegDuration:     plan.Legs[i],
+			ETA:             now.Add(time.Duration(elapsed * float64(time.Second))).Truncate(time.Second),
+			ServiceDuration: s.route.serviceTime.Seconds(),
+		}
+		if minutes, ok := learned[stop.RepairType]; ok {
+			stop.ServiceDuration = minutes * 60
+		}
+		route.Stops = append(route.Stops, stop)
+	}
+	route.TotalDuration = elapsed
+
+	if err := s.repo.SaveMechanicRoute(ctx, route); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save mechanic route")
+		s.logger.Error("Failed to save mechanic route", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("optimizer", route.Optimizer), attribute.Int("stopCount", len(route.Stops)))
+	s.logger.Info("Updated mechanic route", "mechanicID", mechanicID, "stops", len(route.Stops), "unrouted", len(route.Unrouted), "optimizer", route.Optimizer, "totalDuration", time.Duration(elapsed*float64(time.Second)).Round(time.Second), "app", "mechanic-service")
+	return route, nil
+}
+
+// nearestNeighborPlan visits the closest unvisited stop next, driving at the
+// average ETA speed
+func (s *Service) nearestNeighborPlan(start domain.Location, stops []domain.Location) *trip.Plan {
+	plan := &trip.Plan{}
+	visited := make([]bool, len(stops))
+	at := start
+	for range stops {
+		next, best := -1, math.Inf(1)
+		for i, stop := range stops {
+			if d := s.haversine(at, stop); !visited[i] && d < best {
+				next, best = i, d
+			}
+		}
+		visited[next] = true
+		plan.Order = append(plan.Order, next)
+		plan.Legs = append(plan.Legs, best/s.eta.speedKmh*3600)
+		at = stops[next]
+	}
+	return plan
+}
+
+// repairIDs returns the sorted IDs of repairs
+func repairIDs(repairs []*domain.Repair) []string {
+	ids := make([]string, len(repairs))
+	for i, repair := range repairs {
+		ids[i] = repair.ID
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// runRouteUpdater recomputes stored routes when a repair on them is
+// completed, cancelled or reassigned, or a mechanic with a route is assigned
+// another repair, until ctx is canceled. It runs under the supervisor.
+func (s *Service) runRouteUpdater(ctx context.Context) error {
+	stream, err := s.repo.WatchAssignedRepairs(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var change struct {
+			DocumentKey struct {
+				ID string `bson:"_id"`
+			} `bson:"documentKey"`
+			FullDocument struct {
+				AssignedTo string `bson:"assignedTo"`
+			} `bson:"fullDocument"`
+		}
+		if err := stream.Decode(&change); err != nil {
+			s.logger.Warn("Failed to decode repair change", "error", err, "app", "mechanic-service")
+			continue
+		}
+		mechanicIDs, err := s.repo.RoutedMechanicIDs(ctx, change.DocumentKey.ID)
+		if err != nil {
+			s.logger.Error("Failed to find routes of repair", "error", err, "repairID", change.DocumentKey.ID, "app", "mechanic-service")
+			continue
+		}
+		// Only mechanics who asked for a route get one kept up to date
+		if id := change.FullDocument.AssignedTo; id != "" && !slices.Contains(mechanicIDs, id) {
+			if _, err := s.repo.GetMechanicRoute(ctx, id); err == nil {
+				mechanicIDs = append(mechanicIDs, id)
+			}
+		}
+		for _, mechanicID := range mechanicIDs {
+			repairs, err := s.repo.OpenAssignedRepairs(ctx, mechanicID)
+			if err == nil {
+				_, err = s.updateMechanicRoute(ctx, mechanicID, repairs)
+			}
+			if err != nil {
+				s.logger.Error("Failed to update mechanic route", "error", err, "mechanicID", mechanicID, "repairID", change.DocumentKey.ID, "app", "mechanic-service")
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return nil
+	}
+	return fmt.Errorf("repair change stream closed: %w", stream.Err())
+}
@@ -2,110 +2,401 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
+	"mechanic-service/cdc"
+	"mechanic-service/connect"
 	"mechanic-service/domain"
+	"mechanic-service/eventbus"
 	"mechanic-service/kafka"
+	"mechanic-service/kafka/consume"
+	"mechanic-service/projection"
+	"mechanic-service/repairquery"
+	"mechanic-service/supervisor"
+	"mechanic-service/telemetry"
+	"mechanic-service/trip"
+	"net/http"
 	"os"
+	"slices"
+	"strconv"
+	"time"
 
-	"github.com/hamba/avro/v2"
-	"log/slog"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"log/slog"
 )
 
 // Service implements the business logic for the mechanic service
 type Service struct {
-	repo           domain.MechanicRepository
-	tracer         trace.Tracer
-	logger         *slog.Logger
-	KafkaConsumer  *kafka.Consumer
+	repo            domain.MechanicRepository
+	tracer          trace.Tracer
+	logger          *slog.Logger
+	KafkaConsumer   *kafka.Consumer
 	outboxProcessor *kafka.OutboxProcessor
-	ctx            context.Context // Store context for cancellation
-	cancel         context.CancelFunc
+	producer        kafka.DeadLetterProducer // publishes assignment events
+	assignments     *kafka.AssignmentOutboxProcessor // drains assignment_outbox
+	supervisor      *supervisor.Supervisor // restarts the consumer and outbox processor
+	repairConn      *grpc.ClientConn // repair-service gRPC connection for catch-up and repair queries
+	repairQuery     *repairquery.Client // set with REPAIR_QUERY_MODE=grpc
+	ctx             context.Context // Store context for cancellation
+	cancel          context.CancelFunc
+	absenceWarning  time.Duration // absences starting this soon warn about assigned repairs
+	eta             etaConfig     // committed ETAs and their countdown and late events
+	snapshots       *projection.Store
+	snapshot        snapshotConfig
+	route           routeConfig // multi-stop routes through mechanics' open repairs
+	location        locationConfig // which location updates go out as events
+	locations       *locationThrottle
+	schemas         *kafka.SchemaResolver
+	nearbyRadiusKm  float64 // radius of nearby repair listings that ask for none
 }
 
-// NewService creates a new instance of the mechanic service
-func NewService(repo domain.MechanicRepository, logger *slog.Logger) *Service {
+// NewService creates a new instance of the mechanic service. With mesh, calls
+// to repair-service go over Consul Connect mTLS.
+func NewService(repo domain.MechanicRepository, mesh *connect.Service, logger *slog.Logger) *Service {
 	_, span := otel.Tracer("mechanic-service").Start(context.Background(), "InitializeService")
 	defer span.End()
 
-	// Set Kafka bootstrap servers directly
-	bootstrapServers := "kafka:9094"
-	span.SetAttributes(
-		attribute.String("kafkaServiceName", "kafka"),
-		attribute.String("bootstrapServers", bootstrapServers),
-	)
-	logger.Info("Using Kafka service", "bootstrapServers", bootstrapServers, "app", "mechanic-service")
+	// The projection snapshots, and the Mongo event bus when it is used, are
+	// kept in MongoDB itself
+	mongoRepo, ok := repo.(domain.MongoBacked)
+	if !ok {
+		panic("mechanic-service needs the mongo storage backend")
+	}
 
-	// Load Avro schema for outbox processor
-	schemaBytes, err := os.ReadFile("repair_event.avsc")
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to read schema file")
-		logger.Error("Failed to read schema file", "error", err, "app", "mechanic-service")
-		panic(fmt.Sprintf("failed to read schema file: %v", err))
+	// Load the reader Avro schema for the outbox processor; payloads are resolved
+	// against the writer schema registered under their embedded schema ID. The
+	// latest registered version is preferred over the embedded one.
+	schemaRegistryURL := "http://schema-registry:8081"
+	if kafka.EventBus() == kafka.BusMongo {
+		schemaRegistryURL = ""
 	}
-	schema, err := avro.Parse(string(schemaBytes))
+	loaded, err := kafka.LoadSchema(schemaRegistryURL, kafka.SchemaSubject(kafka.RepairEventsTopic))
 	if err != nil {
 		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to parse schema")
-		logger.Error("Failed to parse schema", "error", err, "app", "mechanic-service")
-		panic(fmt.Sprintf("failed to parse schema: %v", err))
+		span.SetStatus(codes.Error, "Failed to load schema")
+		logger.Error("Failed to load schema", "error", err, "app", "mechanic-service")
+		panic(fmt.Sprintf("failed to load schema: %v", err))
 	}
+	span.SetAttributes(attribute.String("schemaSource", loaded.Source))
+	if loaded.Err != nil {
+		logger.Warn("Using fallback reader schema", "source", loaded.Source, "error", loaded.Err, "app", "mechanic-service")
+	} else {
+		logger.Info("Loaded reader schema", "source", loaded.Source, "app", "mechanic-service")
+	}
+	schema := loaded.Schema
 
-	// Initialize Kafka consumer
-	consumer, err := kafka.NewConsumer(bootstrapServers, "http://schema-registry:8081", "repair-events", "mechanic-service-group", logger, repo)
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to initialize Kafka consumer")
-		logger.Error("Failed to initialize Kafka consumer", "error", err, "app", "mechanic-service")
-		panic(fmt.Sprintf("failed to initialize Kafka consumer: %v", err))
+	// The consumer shares the schema resolver with the outbox processor
+	schemas := kafka.NewSchemaResolver("http://schema-registry:8081", schema)
+	topic := kafka.TopicName(kafka.RepairEventsTopic)
+
+	var consumer *kafka.Consumer
+	var producer kafka.DeadLetterProducer // publishes assignment events
+	if kafka.EventBus() == kafka.BusMongo {
+		// Single-node installs read the event_bus collection repair-service
+		// publishes to instead of Kafka; payloads need no schema-registry
+		span.SetAttributes(attribute.String("eventBus", kafka.BusMongo))
+		logger.Info("Using Mongo event bus", "topic", topic, "app", "mechanic-service")
+		db := mongoRepo.GetMongoClient(context.Background()).Database("repairdb")
+		consumer = kafka.NewConsumer(eventbus.NewSource(db, "mechanic-service-group"), eventbus.NewProducer(db), topic, "mechanic-service-group", schemas, logger, repo)
+		producer = eventbus.NewProducer(db)
+	} else {
+		// Set Kafka bootstrap servers directly
+		bootstrapServers := "kafka:9094"
+		span.SetAttributes(
+			attribute.String("kafkaServiceName", "kafka"),
+			attribute.String("bootstrapServers", bootstrapServers),
+		)
+		logger.Info("Using Kafka service", "bootstrapServers", bootstrapServers, "app", "mechanic-service")
+
+		// Create the topics of this environment, dead letters included, before
+		// subscribing; brokers that auto-create topics still work when this fails
+		provisionCtx, cancelProvision := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := kafka.EnsureTopics(provisionCtx, bootstrapServers, []string{kafka.RepairEventsTopic, kafka.DLQTopic(), kafka.AssignmentEventsTopic}, logger); err != nil {
+			logger.Warn("Failed to provision Kafka topics", "error", err, "app", "mechanic-service")
+		}
+		cancelProvision()
+
+		// Pre-warm the schema cache with the latest writer schemas in the background
+		prewarmVersions := 5
+		if v, err := strconv.Atoi(os.Getenv("SCHEMA_PREWARM_VERSIONS")); err == nil && v >= 0 {
+			prewarmVersions = v
+		}
+		if prewarmVersions > 0 {
+			go func() {
+				loaded, err := schemas.Prewarm(kafka.SchemaSubject(kafka.RepairEventsTopic), prewarmVersions)
+				if err != nil {
+					logger.Warn("Failed to pre-warm schema cache", "error", err, "loaded", loaded, "app", "mechanic-service")
+					return
+				}
+				logger.Info("Pre-warmed schema cache", "subject", kafka.SchemaSubject(kafka.RepairEventsTopic), "loaded", loaded, "app", "mechanic-service")
+			}()
+		}
+
+		var err error
+		consumer, err = kafka.NewKafkaConsumer(bootstrapServers, topic, "mechanic-service-group", schemas, logger, repo)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to initialize Kafka consumer")
+			logger.Error("Failed to initialize Kafka consumer", "error", err, "app", "mechanic-service")
+			panic(fmt.Sprintf("failed to initialize Kafka consumer: %v", err))
+		}
+		producer, err = kafka.NewEventProducer(bootstrapServers)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to initialize Kafka producer")
+			logger.Error("Failed to initialize Kafka producer", "error", err, "app", "mechanic-service")
+			panic(fmt.Sprintf("failed to initialize Kafka producer: %v", err))
+		}
 	}
 
 	// Create a cancellable context for the consumer and outbox processor
 	ctx, cancel := context.WithCancel(context.Background())
 
+	// Number of parallel outbox workers and pending events buffered per worker;
+	// events for the same repair stay ordered
+	outboxConcurrency := 4
+	if v, err := strconv.Atoi(os.Getenv("OUTBOX_CONCURRENCY")); err == nil && v > 0 {
+		outboxConcurrency = v
+	}
+	outboxQueueDepth := 64
+	if v, err := strconv.Atoi(os.Getenv("OUTBOX_QUEUE_DEPTH")); err == nil && v > 0 {
+		outboxQueueDepth = v
+	}
+	logger.Info("Configured outbox processor", "concurrency", outboxConcurrency, "queueDepth", outboxQueueDepth, "app", "mechanic-service")
+
+	// Assignment events are published every ASSIGNMENT_OUTBOX_POLL_INTERVAL_SECONDS;
+	// published events other than repair_assigned, which are the repairs'
+	// assignment history, expire after ASSIGNMENT_EVENT_RETENTION_HOURS
+	assignmentPollInterval := time.Second
+	if v, err := strconv.Atoi(os.Getenv("ASSIGNMENT_OUTBOX_POLL_INTERVAL_SECONDS")); err == nil && v > 0 {
+		assignmentPollInterval = time.Duration(v) * time.Second
+	}
+	assignmentRetention := 168 * time.Hour
+	if v, err := strconv.Atoi(os.Getenv("ASSIGNMENT_EVENT_RETENTION_HOURS")); err == nil && v > 0 {
+		assignmentRetention = time.Duration(v) * time.Hour
+	}
+
+	// Absences starting within this window report the mechanic's open repairs for reassignment
+	absenceWarning := 48 * time.Hour
+	if v, err := strconv.Atoi(os.Getenv("ABSENCE_REASSIGN_WARNING_HOURS")); err == nil && v >= 0 {
+		absenceWarning = time.Duration(v) * time.Hour
+	}
+
+	// Assignments commit an ETA; the ETA monitor tells users when the mechanic
+	// is about to arrive or is late
+	eta := etaConfig{speedKmh: 30, countdownLead: 5 * time.Minute, lateRepeat: 5 * time.Minute, interval: 30 * time.Second, batchSize: 100}
+	if v, err := strconv.Atoi(os.Getenv("ETA_AVERAGE_SPEED_KMH")); err == nil && v > 0 {
+		eta.speedKmh = float64(v)
+	}
+	if v, err := strconv.Atoi(os.Getenv("ETA_COUNTDOWN_MINUTES")); err == nil && v >= 0 {
+		eta.countdownLead = time.Duration(v) * time.Minute
+	}
+	if v, err := strconv.Atoi(os.Getenv("ETA_LATE_REPEAT_MINUTES")); err == nil && v > 0 {
+		eta.lateRepeat = time.Duration(v) * time.Minute
+	}
+	if v, err := strconv.Atoi(os.Getenv("ETA_CHECK_INTERVAL_SECONDS")); err == nil && v > 0 {
+		eta.interval = time.Duration(v) * time.Second
+	}
+
+	// Snapshots of the repairs view let new instances replay only the tail of
+	// repair-events, which can then be compacted or expire safely
+	snapshot := snapshotConfig{interval: 60 * time.Minute}
+	if v, err := strconv.Atoi(os.Getenv("PROJECTION_SNAPSHOT_INTERVAL_MINUTES")); err == nil && v >= 0 {
+		snapshot.interval = time.Duration(v) * time.Minute
+	}
+	snapshotKeep := 3
+	if v, err := strconv.Atoi(os.Getenv("PROJECTION_SNAPSHOT_KEEP")); err == nil && v > 0 {
+		snapshotKeep = v
+	}
+	snapshot.retention = kafka.RetentionPolicyFromEnv(snapshot.interval)
+	if err := snapshot.retention.Check(); err != nil {
+		logger.Error("Ignoring unsafe repair-events retention policy", "error", err, "app", "mechanic-service")
+		snapshot.retention = kafka.RetentionPolicy{}
+	}
+	snapshots := projection.NewStore(mongoRepo.GetMongoClient(context.Background()).Database("repairdb"), topic, snapshotKeep, logger)
+	indexCtx, cancelIndexes := context.WithTimeout(context.Background(), 30*time.Second)
+	if err := snapshots.EnsureIndexes(indexCtx); err != nil {
+		logger.Warn("Failed to create projection snapshot indexes", "error", err, "app", "mechanic-service")
+	}
+	// Nearby repairs are found through a 2dsphere index on their GeoJSON user
+	// location, derived here for repairs stored before it was kept
+	if backfilled, err := repo.EnsureRepairGeoIndex(indexCtx); err != nil {
+		logger.Warn("Failed to create repair geo index", "error", err, "backfilled", backfilled, "app", "mechanic-service")
+	} else if backfilled > 0 {
+		logger.Info("Backfilled repair geo locations", "backfilled", backfilled, "app", "mechanic-service")
+	}
+	cancelIndexes()
+
+	// Nearby repair listings cover NEARBY_RADIUS_KM around the mechanic unless
+	// the request asks for another radius
+	nearbyRadiusKm := domain.ServiceRadiusKm
+	if v, err := strconv.ParseFloat(os.Getenv("NEARBY_RADIUS_KM"), 64); err == nil && v > 0 && v <= domain.MaxSearchRadiusKm {
+		nearbyRadiusKm = v
+	}
+
+	// Mechanics with several open repairs get them ordered into one route by
+	// the OSRM trip service at ROUTE_OSRM_URL (empty orders by straight-line
+	// distance), spending the learned duration of each stop's repair type, or
+	// ROUTE_STOP_SERVICE_MINUTES until one is learned
+	route := routeConfig{serviceTime: 30 * time.Minute, maxAge: 10 * time.Minute}
+	osrmURL, ok := os.LookupEnv("ROUTE_OSRM_URL")
+	if !ok {
+		osrmURL = "http://router.project-osrm.org"
+	}
+	if osrmURL != "" {
+		route.trip = trip.NewClient(osrmURL, &http.Client{Timeout: 10 * time.Second, Transport: telemetry.Transport(nil, telemetry.PeerService("osrm"))})
+	}
+	if v, err := strconv.Atoi(os.Getenv("ROUTE_STOP_SERVICE_MINUTES")); err == nil && v >= 0 {
+		route.serviceTime = time.Duration(v) * time.Minute
+	}
+	if v, err := strconv.Atoi(os.Getenv("ROUTE_MAX_AGE_MINUTES")); err == nil && v > 0 {
+		route.maxAge = time.Duration(v) * time.Minute
+	}
+
+	// Location updates emit a mechanic_location_updated event once the
+	// mechanic moved LOCATION_EMIT_MIN_METERS since the last one, or every
+	// LOCATION_EMIT_INTERVAL_SECONDS while standing still
+	location := locationConfig{minMeters: 50, heartbeat: 30 * time.Second}
+	if v, err := strconv.Atoi(os.Getenv("LOCATION_EMIT_MIN_METERS")); err == nil && v >= 0 {
+		location.minMeters = float64(v)
+	}
+	if v, err := strconv.Atoi(os.Getenv("LOCATION_EMIT_INTERVAL_SECONDS")); err == nil && v > 0 {
+		location.heartbeat = time.Duration(v) * time.Second
+	}
+
 	svc := &Service{
-		repo:           repo,
-		tracer:         otel.Tracer("mechanic-service"),
-		logger:         logger,
-		KafkaConsumer:  consumer,
-		outboxProcessor: kafka.NewOutboxProcessor(repo, logger, schema),
-		ctx:            ctx,
-		cancel:         cancel,
-	}
-
-	// Start Kafka consumer in a separate goroutine
-	go func() {
-		logger.Info("Starting Kafka consumer", "app", "mechanic-service")
-		err := consumer.Start(ctx)
+		repo:            repo,
+		tracer:          otel.Tracer("mechanic-service"),
+		logger:          logger,
+		KafkaConsumer:   consumer,
+		outboxProcessor: kafka.NewOutboxProcessor(repo, logger, schemas, outboxConcurrency, outboxQueueDepth),
+		producer:        producer,
+		assignments:     kafka.NewAssignmentOutboxProcessor(repo, producer, kafka.TopicName(kafka.AssignmentEventsTopic), logger, assignmentPollInterval, assignmentRetention),
+		supervisor:      supervisor.New(logger),
+		absenceWarning:  absenceWarning,
+		eta:             eta,
+		snapshots:       snapshots,
+		snapshot:        snapshot,
+		route:           route,
+		location:        location,
+		locations:       &locationThrottle{last: map[string]emittedLocation{}},
+		schemas:         schemas,
+		nearbyRadiusKm:  nearbyRadiusKm,
+		ctx:             ctx,
+		cancel:          cancel,
+	}
+
+	// Run the Kafka consumer and outbox processor under the supervisor, which
+	// restarts them with backoff if they fail
+	svc.supervisor.Go(ctx, "kafka-consumer", consumer.Run)
+	svc.supervisor.Go(ctx, "outbox-processor", svc.outboxProcessor.Start)
+	svc.supervisor.Go(ctx, "assignment-outbox-processor", svc.assignments.Start)
+	svc.supervisor.Go(ctx, "eta-monitor", svc.runETAMonitor)
+	svc.supervisor.Go(ctx, "route-updater", svc.runRouteUpdater)
+	if snapshot.interval > 0 {
+		svc.supervisor.Go(ctx, "projection-snapshots", svc.runProjectionSnapshots)
+	}
+
+	// repair-service's gRPC server, when its address is configured, backs the
+	// Kafka outage catch-up and optionally the repair lookups of assignments
+	if addr := os.Getenv("REPAIR_GRPC_ADDRESS"); addr != "" {
+		creds := insecure.NewCredentials()
+		if mesh != nil {
+			creds = credentials.NewTLS(mesh.ClientTLSConfig("repair-service"))
+		}
+		conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(creds))
 		if err != nil {
-			logger.Error("Kafka consumer stopped with error", "error", err, "app", "mechanic-service")
+			span.RecordError(err)
+			logger.Error("Failed to create repair-service gRPC client, change stream catch-up and repair queries disabled", "address", addr, "error", err, "app", "mechanic-service")
+		} else {
+			svc.repairConn = conn
+			if os.Getenv("REPAIR_QUERY_MODE") == "grpc" {
+				svc.repairQuery = repairquery.New(conn, logger)
+			}
 		}
-	}()
+	}
 
-	// Start outbox processor in a separate goroutine
-	go func() {
-		logger.Info("Starting outbox processor", "app", "mechanic-service")
-		err := svc.outboxProcessor.Start(ctx)
-		if err != nil {
-			logger.Error("Outbox processor stopped with error", "error", err, "app", "mechanic-service")
+	// Bridge Kafka outages from repair-service's change stream; the Mongo event
+	// bus has no broker to lose
+	if svc.repairConn != nil && kafka.EventBus() == kafka.BusKafka {
+		window := 60 * time.Second
+		if v, err := strconv.Atoi(os.Getenv("CDC_CATCHUP_WINDOW_SECONDS")); err == nil && v > 0 {
+			window = time.Duration(v) * time.Second
+		}
+		interval := 15 * time.Second
+		if v, err := strconv.Atoi(os.Getenv("KAFKA_PROBE_INTERVAL_SECONDS")); err == nil && v > 0 {
+			interval = time.Duration(v) * time.Second
 		}
-	}()
+		logger.Info("Enabled change stream catch-up", "address", svc.repairConn.Target(), "window", window, "probeInterval", interval, "app", "mechanic-service")
+		go svc.monitorKafka(ctx, cdc.NewCatchUp(svc.repairConn, repo, logger, window), interval)
+	}
 
 	return svc
 }
 
-// Shutdown gracefully stops the service
-func (s *Service) Shutdown() {
+// LoopHealth returns the health of the supervised consumer and outbox loops
+func (s *Service) LoopHealth() []supervisor.LoopHealth {
+	return s.supervisor.Health()
+}
+
+// Ready reports whether the consumer and outbox loops are all running
+func (s *Service) Ready() bool {
+	return s.supervisor.Ready()
+}
+
+// DeliveryLatency returns per event type latency from Kafka delivery to persistence
+func (s *Service) DeliveryLatency() map[string]kafka.LatencyStats {
+	return s.outboxProcessor.PersistenceLatency()
+}
+
+// ConsumerLag returns the lag of the repair-events consumer group
+func (s *Service) ConsumerLag() (*consume.ConsumerLag, error) {
+	return s.KafkaConsumer.Lag(5 * time.Second)
+}
+
+// ConsumerMetrics returns handled, retried, failed and dead-lettered counts per topic
+func (s *Service) ConsumerMetrics() map[string]consume.Counts {
+	return s.KafkaConsumer.Metrics.Snapshot()
+}
+
+// OutboxStats returns per-worker counters of the outbox processor
+func (s *Service) OutboxStats() []kafka.WorkerStats {
+	return s.outboxProcessor.WorkerStats()
+}
+
+// Shutdown stops the Kafka consumer and outbox processor, waiting for the
+// messages in flight, then closes the consumer and the repair-service connection
+func (s *Service) Shutdown(ctx context.Context) error {
 	s.logger.Info("Shutting down service", "app", "mechanic-service")
 	s.cancel() // Cancel the context to stop consumer and outbox processor
+	err := s.supervisor.Wait(ctx)
 	s.KafkaConsumer.Close()
+	s.producer.Flush(5000)
+	s.producer.Close()
+	if s.repairConn != nil {
+		s.repairConn.Close()
+	}
+	return err
 }
 
+// nearbyRepairFields are the repair fields returned by ListNearbyRepairs
+var nearbyRepairFields = []string{"userID", "status", "repairCost", "assignedTo", "symptoms", "bundleID"}
+
+// nearbyOverfetch is how many repairs ListNearbyRepairs reads per page beyond
+// the missing ones and one per blocked user, up to MaxNearbyResults, for the
+// pinned repairs it drops
+const nearbyOverfetch = 20
+
 // haversine calculates the distance between two points in kilometers
 func (s *Service) haversine(l1, l2 domain.Location) float64 {
 	const R = 6371 // Earth's radius in km
@@ -120,8 +411,9 @@ func (s *Service) haversine(l1, l2 domain.Location) float64 {
 	return R * c
 }
 
-// ListNearbyRepairs lists repairs within 10km of a specified mechanic's location
-func (s *Service) ListNearbyRepairs(ctx context.Context, mechanicID string) ([]*domain.Repair, error) {
+// ListNearbyRepairs lists the repairs within search.RadiusKm of a mechanic's
+// location, nearest first and at most search.MaxResults of them
+func (s *Service) ListNearbyRepairs(ctx context.Context, mechanicID string, search domain.NearbySearch) ([]*domain.Repair, error) {
 	ctx, span := s.tracer.Start(ctx, "ServiceListNearbyRepairs")
 	defer span.End()
 
@@ -132,6 +424,14 @@ func (s *Service) ListNearbyRepairs(ctx context.Context, mechanicID string) ([]*
 		s.logger.Error("Mechanic ID is required", "app", "mechanic-service")
 		return nil, err
 	}
+	if err := search.Validate(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if search.RadiusKm == 0 {
+		search.RadiusKm = s.nearbyRadiusKm
+	}
 
 	// Get mechanic details
 	mechanic, err := s.repo.GetMechanicByID(ctx, mechanicID)
@@ -146,25 +446,51 @@ func (s *Service) ListNearbyRepairs(ctx context.Context, mechanicID string) ([]*
 		attribute.String("mechanicID", mechanicID),
 		attribute.Float64("mechanic.latitude", mechanicLoc.Latitude),
 		attribute.Float64("mechanic.longitude", mechanicLoc.Longitude),
+		attribute.Float64("radiusKm", search.RadiusKm),
+		attribute.Int("maxResults", search.MaxResults),
 	)
 
-	// Get all repairs
-	repairs, err := s.repo.GetAllRepairs(ctx)
+	// Users who blocked this mechanic, or were blacklisted, are never listed
+	blocked, err := s.repo.BlockedUserIDs(ctx, mechanicID)
 	if err != nil {
 		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to query repairs")
-		s.logger.Error("Failed to query repairs", "error", err, "app", "mechanic-service")
-		return nil, fmt.Errorf("failed to query repairs: %w", err)
+		span.SetStatus(codes.Error, "Failed to query blocks")
+		s.logger.Error("Failed to query blocks", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
+		return nil, fmt.Errorf("failed to query blocks: %w", err)
 	}
 
+	// Find the repairs in range, nearest first, with only the fields the
+	// nearby listing renders. Blocked and pinned repairs are dropped after the
+	// query, so each page asks for more than are missing and further pages
+	// are read only while the dropped ones leave the listing short.
 	var nearby []*domain.Repair
-	for _, repair := range repairs {
-		if repair.RepairCost != nil && repair.RepairCost.UserLocation != nil {
-			distance := s.haversine(mechanicLoc, *repair.RepairCost.UserLocation)
-			if distance <= 10 {
-				nearby = append(nearby, repair)
+	now := time.Now()
+	for skip := int64(0); len(nearby) < search.MaxResults; {
+		limit := int64(search.MaxResults - len(nearby) + min(len(blocked), domain.MaxNearbyResults) + nearbyOverfetch)
+		repairs, err := s.repo.NearbyRepairs(ctx, mechanicLoc, search.RadiusKm, &domain.QueryOptions{Fields: nearbyRepairFields, Limit: limit, Skip: skip})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to query repairs")
+			s.logger.Error("Failed to query repairs", "error", err, "app", "mechanic-service")
+			return nil, fmt.Errorf("failed to query repairs: %w", err)
+		}
+		for _, repair := range repairs {
+			if blocked[repair.UserID] {
+				continue
+			}
+			// Repairs waiting for the user's preferred mechanic are theirs alone
+			if pinned := repair.PinnedTo(now); pinned != "" && pinned != mechanicID {
+				continue
+			}
+			nearby = append(nearby, repair)
+			if len(nearby) == search.MaxResults {
+				break
 			}
 		}
+		if int64(len(repairs)) < limit {
+			break
+		}
+		skip += limit
 	}
 	span.SetAttributes(attribute.Int("nearbyRepairCount", len(nearby)))
 	s.logger.Info("Listed nearby repairs", "repairCount", len(nearby), "mechanicID", mechanicID, "app", "mechanic-service")
@@ -172,8 +498,25 @@ func (s *Service) ListNearbyRepairs(ctx context.Context, mechanicID string) ([]*
 	return nearby, nil
 }
 
-// AssignRepair assigns a mechanic to a repair
-func (s *Service) AssignRepair(ctx context.Context, repairID, mechanicID string) (*domain.Repair, error) {
+// lookupRepair reads a repair from repair-service when REPAIR_QUERY_MODE=grpc,
+// falling back to the repairs view when repair-service cannot be reached
+func (s *Service) lookupRepair(ctx context.Context, repairID string) (*domain.Repair, error) {
+	if s.repairQuery == nil {
+		return s.repo.GetRepairByID(ctx, repairID)
+	}
+	repair, err := s.repairQuery.GetRepair(ctx, repairID)
+	if err == nil || errors.Is(err, mongo.ErrNoDocuments) {
+		return repair, err
+	}
+	s.logger.Warn("Failed to query repair-service, using the repairs view", "error", err, "repairID", repairID, "app", "mechanic-service")
+	return s.repo.GetRepairByID(ctx, repairID)
+}
+
+// AssignRepair assigns a mechanic to a repair and records the ETA the
+// mechanic commits to; without one it is estimated from the distance. A
+// bundled repair takes the whole bundle: every repair in it is assigned to
+// the mechanic at once, or none is.
+func (s *Service) AssignRepair(ctx context.Context, repairID, mechanicID string, eta time.Duration) (*domain.Repair, error) {
 	ctx, span := s.tracer.Start(ctx, "ServiceAssignRepair")
 	defer span.End()
 
@@ -186,7 +529,7 @@ func (s *Service) AssignRepair(ctx context.Context, repairID, mechanicID string)
 	}
 
 	// Validate mechanic
-	_, err := s.repo.GetMechanicByID(ctx, mechanicID)
+	mechanic, err := s.repo.GetMechanicByID(ctx, mechanicID)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to find mechanic")
@@ -194,16 +537,155 @@ func (s *Service) AssignRepair(ctx context.Context, repairID, mechanicID string)
 		return nil, fmt.Errorf("failed to find mechanic: %w", err)
 	}
 
-	// Assign the repair
-	repair, err := s.repo.AssignRepair(ctx, repairID, mechanicID)
+	// Absent mechanics cannot take repairs
+	absent, err := s.repo.IsAbsent(ctx, mechanicID, time.Now())
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to check absences")
+		s.logger.Error("Failed to check absences", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
+		return nil, fmt.Errorf("failed to check absences: %w", err)
+	}
+	if absent {
+		err := fmt.Errorf("%w: repair %s cannot be assigned to mechanic %s", domain.ErrMechanicAbsent, repairID, mechanicID)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Warn("Refused assignment to absent mechanic", "repairID", repairID, "mechanicID", mechanicID, "app", "mechanic-service")
+		return nil, err
+	}
+
+	// Refuse to match a user with a mechanic they blocked
+	current, err := s.lookupRepair(ctx, repairID)
 	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find repair")
+		s.logger.Error("Failed to find repair", "error", err, "repairID", repairID, "app", "mechanic-service")
+		return nil, err
+	}
+	if current.AssignedTo != "" || !slices.Contains(domain.AssignableStatuses, current.Status) {
+		err := fmt.Errorf("%w: repair %s is %s", domain.ErrAssignmentConflict, repairID, current.Status)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Warn("Refused assignment of unassignable repair", "repairID", repairID, "status", current.Status, "assignedTo", current.AssignedTo, "app", "mechanic-service")
+		return nil, err
+	}
+	claims := []*domain.Repair{current}
+	if current.BundleID != "" {
+		claims, err = s.repo.BundledRepairs(ctx, current.BundleID)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to find bundled repairs")
+			s.logger.Error("Failed to find bundled repairs", "error", err, "bundleID", current.BundleID, "app", "mechanic-service")
+			return nil, err
+		}
+		if len(claims) == 0 {
+			// The bundle was dissolved since the repair was read
+			claims = []*domain.Repair{current}
+		}
+		span.SetAttributes(
+			attribute.String("bundleID", current.BundleID),
+			attribute.Int("bundleSize", len(claims)),
+		)
+	}
+	blocked, err := s.repo.BlockedUserIDs(ctx, mechanicID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to query blocks")
+		s.logger.Error("Failed to query blocks", "error", err, "mechanicID", mechanicID, "app", "mechanic-service")
+		return nil, fmt.Errorf("failed to query blocks: %w", err)
+	}
+	now := time.Now()
+	for _, claim := range claims {
+		if pinned := claim.PinnedTo(now); pinned != "" && pinned != mechanicID {
+			err := fmt.Errorf("%w: repair %s is held for the user's preferred mechanic", domain.ErrAssignmentConflict, claim.ID)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			s.logger.Info("Refused claim of repair held for another mechanic", "repairID", claim.ID, "mechanicID", mechanicID, "preferredMechanicID", pinned, "app", "mechanic-service")
+			return nil, err
+		}
+		if blocked[claim.UserID] {
+			err := fmt.Errorf("%w: repair %s cannot be assigned to mechanic %s", domain.ErrBlocked, claim.ID, mechanicID)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			s.logger.Warn("Refused blocked assignment", "repairID", claim.ID, "mechanicID", mechanicID, "userID", claim.UserID, "app", "mechanic-service")
+			return nil, err
+		}
+	}
+
+	// Claim the repairs and record their assignment events atomically; the
+	// conditional update makes concurrent claims for one repair conflict
+	assignedAt := time.Now().UTC()
+	if eta <= 0 {
+		// Estimate from where the mechanic is, not the last emitted location
+		mechanic.Location = s.currentLocation(ctx, mechanic)
+	}
+	commitment := s.commitETA(mechanic, current, eta, assignedAt)
+	var committedETA time.Time
+	if commitment != nil {
+		committedETA = commitment.At
+		span.SetAttributes(attribute.String("committedETA", committedETA.Format(time.RFC3339)))
+	}
+	payloads := make([][]byte, len(claims))
+	for i, claim := range claims {
+		payloads[i], err = json.Marshal(domain.RepairAssignment{
+			RepairID:     claim.ID,
+			UserID:       claim.UserID,
+			MechanicID:   mechanicID,
+			AssignedAt:   assignedAt,
+			CommittedETA: committedETA,
+		})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to marshal assignment event")
+			return nil, fmt.Errorf("failed to marshal assignment event: %w", err)
+		}
+	}
+	var repair *domain.Repair
+	err = s.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		for i, claim := range claims {
+			assigned, err := s.repo.AssignRepair(tx, claim.ID, mechanicID, commitment)
+			if err != nil {
+				return err
+			}
+			if claim.ID == repairID {
+				repair = assigned
+			}
+			if err := s.repo.SaveAssignmentEvent(tx, &domain.AssignmentEvent{
+				ID:          primitive.NewObjectID().Hex(),
+				EventType:   domain.EventRepairAssigned,
+				AggregateID: claim.ID,
+				Payload:     payloads[i],
+				CreatedAt:   time.Now(),
+				Processed:   false,
+			}); err != nil {
+				return err
+			}
+		}
+		// The counters feed repair-service's fairness rotation; a bundle is
+		// one job
+		return s.repo.IncrementAssignmentCounter(tx, mechanicID, time.Now())
+	})
+	if err != nil {
+		// A write conflict means another claim on the same repair won the race
+		var labeled mongo.LabeledError
+		if errors.As(err, &labeled) && labeled.HasErrorLabel("TransientTransactionError") {
+			err = fmt.Errorf("%w: %v", domain.ErrAssignmentConflict, err)
+		}
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to assign repair")
+		if errors.Is(err, domain.ErrAssignmentConflict) {
+			s.logger.Warn("Lost assignment race", "error", err, "repairID", repairID, "mechanicID", mechanicID, "app", "mechanic-service")
+			return nil, err
+		}
 		s.logger.Error("Failed to assign repair", "error", err, "repairID", repairID, "mechanicID", mechanicID, "app", "mechanic-service")
 		return nil, fmt.Errorf("failed to assign repair: %w", err)
 	}
 
-	s.logger.Info("Assigned repair", "repairID", repairID, "mechanicID", mechanicID, "app", "mechanic-service")
+	if s.repairQuery != nil {
+		for _, claim := range claims {
+			s.repairQuery.Invalidate(claim.ID)
+		}
+	}
+	s.logger.Info("Assigned repair", "repairID", repairID, "mechanicID", mechanicID, "bundleID", current.BundleID, "bundleSize", len(claims), "committedETA", committedETA, "app", "mechanic-service")
 	span.SetAttributes(
 		attribute.String("repairID", repairID),
 		attribute.String("mechanicID", mechanicID),
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"mechanic-service/domain"
+
+	"go.opentelemetry.io/otel"
+)
+
+// nearbyRepo serves repairs already in distance order, a page at a time
+type nearbyRepo struct {
+	domain.MechanicRepository
+	repairs []*domain.Repair
+	blocked map[string]bool
+	pages   []domain.QueryOptions
+}
+
+func (r *nearbyRepo) GetMechanicByID(ctx context.Context, id string) (*domain.Mechanic, error) {
+	return &domain.Mechanic{ID: id}, nil
+}
+
+func (r *nearbyRepo) BlockedUserIDs(ctx context.Context, mechanicID string) (map[string]bool, error) {
+	return r.blocked, nil
+}
+
+func (r *nearbyRepo) NearbyRepairs(ctx context.Context, near domain.Location, radiusKm float64, opts *domain.QueryOptions) ([]*domain.Repair, error) {
+	r.pages = append(r.pages, *opts)
+	start := min(int(opts.Skip), len(r.repairs))
+	end := len(r.repairs)
+	if opts.Limit > 0 {
+		end = min(start+int(opts.Limit), end)
+	}
+	return r.repairs[start:end], nil
+}
+
+// TestListNearbyRepairsLimit checks the query is limited, and read further
+// only while blocked and pinned repairs leave the listing short
+func TestListNearbyRepairsLimit(t *testing.T) {
+	pinned := &domain.RepairCost{Preference: &domain.MechanicPreference{MechanicID: "mechanic2", Pinned: true}}
+	var repairs []*domain.Repair
+	for i := range 100 {
+		repair := &domain.Repair{ID: fmt.Sprintf("repair%d", i), UserID: fmt.Sprintf("user%d", i)}
+		if i < 30 {
+			repair.RepairCost = pinned
+		}
+		repairs = append(repairs, repair)
+	}
+
+	tests := []struct {
+		name       string
+		blocked    map[string]bool
+		maxResults int
+		first      string // nearest repair listed
+		pages      int
+	}{
+		{"pinned page", nil, 5, "repair30", 2},
+		{"short page", nil, 40, "repair30", 2},
+		{"blocked", map[string]bool{"user30": true, "user31": true}, 5, "repair32", 2},
+		{"exhausted", nil, 90, "repair30", 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &nearbyRepo{repairs: repairs, blocked: tt.blocked}
+			svc := &Service{repo: repo, tracer: otel.Tracer("mechanic-service-test"), logger: slog.New(slog.NewTextHandler(io.Discard, nil)), nearbyRadiusKm: 10}
+			nearby, err := svc.ListNearbyRepairs(context.Background(), "mechanic1", domain.NearbySearch{MaxResults: tt.maxResults})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := min(tt.maxResults, 70-len(tt.blocked))
+			if len(nearby) != want || nearby[0].ID != tt.first {
+				t.Fatalf("listed %d repairs from %s, want %d from %s", len(nearby), nearby[0].ID, want, tt.first)
+			}
+			if len(repo.pages) != tt.pages {
+				t.Errorf("read %d pages, want %d", len(repo.pages), tt.pages)
+			}
+			for i, page := range repo.pages {
+				if page.Limit <= 0 || page.Limit > int64(tt.maxResults+len(tt.blocked)+nearbyOverfetch) {
+					t.Errorf("page %d: limit %d", i, page.Limit)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"mechanic-service/kafka"
+	"mechanic-service/projection"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// snapshotConfig sets how often the repairs view is snapshotted and how the
+// repair-events topic is cleaned up once snapshots cover it
+type snapshotConfig struct {
+	interval  time.Duration // 0 disables the periodic snapshots
+	retention kafka.RetentionPolicy
+}
+
+// runProjectionSnapshots snapshots the repairs view every interval until ctx
+// is canceled. After the first snapshot of the process the retention policy
+// of the repair-events topic is applied, when one is configured.
+func (s *Service) runProjectionSnapshots(ctx context.Context) error {
+	ticker := time.NewTicker(s.snapshot.interval)
+	defer ticker.Stop()
+	retentionApplied := !s.snapshot.retention.Enabled() || kafka.EventBus() != kafka.BusKafka
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			if _, err := s.TakeProjectionSnapshot(ctx); err != nil || retentionApplied {
+				continue
+			}
+			if err := kafka.ConfigureRetention(ctx, "kafka:9094", kafka.RepairEventsTopic, s.snapshot.retention, s.logger); err != nil {
+				s.logger.Error("Failed to configure repair-events retention", "error", err, "app", "mechanic-service")
+				continue
+			}
+			retentionApplied = true
+		}
+	}
+}
+
+// TakeProjectionSnapshot snapshots the repairs view with the offsets of the
+// events applied to it
+func (s *Service) TakeProjectionSnapshot(ctx context.Context) (*projection.Snapshot, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceTakeProjectionSnapshot")
+	defer span.End()
+
+	start := time.Now()
+	snapshot, err := s.snapshots.Take(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to take projection snapshot")
+		s.logger.Error("Failed to take projection snapshot", "error", err, "app", "mechanic-service")
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("snapshotID", snapshot.ID), attribute.Int("repairCount", snapshot.RepairCount))
+	s.logger.Info("Took projection snapshot", "snapshotID", snapshot.ID, "repairCount", snapshot.RepairCount, "offsets", snapshot.Offsets, "duration", time.Since(start), "app", "mechanic-service")
+	return snapshot, nil
+}
+
+// ProjectionSnapshots returns the newest snapshots of the repairs view
+func (s *Service) ProjectionSnapshots(ctx context.Context, limit int) ([]projection.Snapshot, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceProjectionSnapshots")
+	defer span.End()
+
+	snapshots, err := s.snapshots.List(ctx, limit)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to list projection snapshots")
+		return nil, err
+	}
+	return snapshots, nil
+}
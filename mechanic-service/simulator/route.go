@@ -0,0 +1,127 @@
+package simulator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"mechanic-service/domain"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Straight-line fallback routes assume this driving speed and road detour,
+// matching repair-service's haversine routing provider
+const (
+	fallbackSpeedMetersPerSecond = 50000.0 / 3600.0
+	fallbackDetourFactor         = 1.3
+)
+
+// route is a driving path and how long it takes in real time
+type route struct {
+	points   []domain.Location
+	cumul    []float64 // distance in meters from the start to each point
+	duration float64   // seconds
+}
+
+func newRoute(points []domain.Location, duration float64) *route {
+	r := &route{points: points, cumul: make([]float64, len(points)), duration: duration}
+	for i := 1; i < len(points); i++ {
+		r.cumul[i] = r.cumul[i-1] + haversineMeters(points[i-1], points[i])
+	}
+	return r
+}
+
+// straightRoute drives directly to the destination
+func straightRoute(from, to domain.Location) *route {
+	return newRoute([]domain.Location{from, to}, haversineMeters(from, to)*fallbackDetourFactor/fallbackSpeedMetersPerSecond)
+}
+
+// at returns the position after the given share (0 to 1) of the route,
+// assuming constant speed
+func (r *route) at(share float64) domain.Location {
+	if share <= 0 || len(r.points) == 1 {
+		return r.points[0]
+	}
+	total := r.cumul[len(r.cumul)-1]
+	if share >= 1 || total == 0 {
+		return r.points[len(r.points)-1]
+	}
+	target := share * total
+	for i := 1; i < len(r.points); i++ {
+		if r.cumul[i] < target {
+			continue
+		}
+		segment := r.cumul[i] - r.cumul[i-1]
+		f := 0.0
+		if segment > 0 {
+			f = (target - r.cumul[i-1]) / segment
+		}
+		a, b := r.points[i-1], r.points[i]
+		return domain.Location{
+			Latitude:  a.Latitude + (b.Latitude-a.Latitude)*f,
+			Longitude: a.Longitude + (b.Longitude-a.Longitude)*f,
+		}
+	}
+	return r.points[len(r.points)-1]
+}
+
+// fetchRoute asks the OSRM route service for the driving path between two points
+func fetchRoute(ctx context.Context, client *http.Client, baseURL string, from, to domain.Location) (*route, error) {
+	url := fmt.Sprintf("%s/route/v1/driving/%f,%f;%f,%f?overview=full&geometries=geojson",
+		strings.TrimRight(baseURL, "/"), from.Longitude, from.Latitude, to.Longitude, to.Latitude)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OSRM request: %w", err)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to call OSRM route service: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OSRM route service returned status %d", resp.StatusCode)
+	}
+
+	var osrmResp struct {
+		Code   string `json:"code"`
+		Routes []struct {
+			Duration float64 `json:"duration"`
+			Geometry struct {
+				Coordinates [][2]float64 `json:"coordinates"` // [longitude, latitude]
+			} `json:"geometry"`
+		} `json:"routes"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&osrmResp); err != nil {
+		return nil, fmt.Errorf("failed to decode OSRM response: %w", err)
+	}
+	if osrmResp.Code != "Ok" || len(osrmResp.Routes) == 0 || len(osrmResp.Routes[0].Geometry.Coordinates) == 0 {
+		return nil, fmt.Errorf("OSRM route service found no route: %s", osrmResp.Code)
+	}
+
+	coords := osrmResp.Routes[0].Geometry.Coordinates
+	points := make([]domain.Location, len(coords))
+	for i, c := range coords {
+		points[i] = domain.Location{Longitude: c[0], Latitude: c[1]}
+	}
+	return newRoute(points, osrmResp.Routes[0].Duration), nil
+}
+
+// haversineMeters returns the great-circle distance between two points in meters
+func haversineMeters(l1, l2 domain.Location) float64 {
+	const R = 6371000 // Earth's radius in m
+	lat1 := l1.Latitude * math.Pi / 180
+	lat2 := l2.Latitude * math.Pi / 180
+	dLat := (l2.Latitude - l1.Latitude) * math.Pi / 180
+	dLon := (l2.Longitude - l1.Longitude) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return R * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
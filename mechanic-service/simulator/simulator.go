@@ -0,0 +1,403 @@
+// Package simulator runs a fleet of virtual mechanics through the real
+// dispatch pipeline, for demos and load tests without real devices
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"mechanic-service/domain"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Dispatcher is the part of the mechanic service virtual mechanics use to
+// find and claim repairs and report their positions, the same calls real
+// mechanics make over HTTP
+type Dispatcher interface {
+	ListNearbyRepairs(ctx context.Context, mechanicID string, search domain.NearbySearch) ([]*domain.Repair, error)
+	AssignRepair(ctx context.Context, repairID, mechanicID string, eta time.Duration) (*domain.Repair, error)
+	UpdateLocation(ctx context.Context, update *domain.LocationUpdate) (*domain.LocationResult, error)
+}
+
+// Config configures the virtual fleet
+type Config struct {
+	Mechanics         int
+	Center            domain.Location // virtual mechanics spawn around it
+	SpawnRadiusKm     float64
+	AcceptProbability float64 // chance a mechanic claims a repair it is offered
+	Tick              time.Duration
+	TimeScale         float64       // simulated seconds per real second
+	RepairDuration    time.Duration // simulated time to finish a repair on site
+	RepairDurations   map[string]time.Duration
+	OSRMURL           string // empty drives straight lines
+	StatusURL         string // base URL receiving PUT /repairs/{repairID}
+	Seed              int64
+}
+
+// ConfigFromEnv reads the simulator configuration and whether it is enabled
+// (SIMULATOR_ENABLED=true)
+func ConfigFromEnv() (Config, bool) {
+	cfg := Config{
+		Mechanics:         10,
+		Center:            domain.Location{Latitude: 52.52, Longitude: 13.405},
+		SpawnRadiusKm:     5,
+		AcceptProbability: 0.7,
+		Tick:              2 * time.Second,
+		TimeScale:         10,
+		RepairDuration:    30 * time.Minute,
+		RepairDurations:   map[string]time.Duration{},
+		OSRMURL:           "http://router.project-osrm.org",
+		StatusURL:         "http://api-gateway:8085",
+		Seed:              time.Now().UnixNano(),
+	}
+	enabled, _ := strconv.ParseBool(os.Getenv("SIMULATOR_ENABLED"))
+
+	if v, err := strconv.Atoi(os.Getenv("SIMULATOR_MECHANICS")); err == nil && v > 0 {
+		cfg.Mechanics = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("SIMULATOR_CENTER_LAT"), 64); err == nil && v >= -90 && v <= 90 {
+		cfg.Center.Latitude = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("SIMULATOR_CENTER_LON"), 64); err == nil && v >= -180 && v <= 180 {
+		cfg.Center.Longitude = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("SIMULATOR_SPAWN_RADIUS_KM"), 64); err == nil && v > 0 {
+		cfg.SpawnRadiusKm = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("SIMULATOR_ACCEPT_PROBABILITY"), 64); err == nil && v >= 0 && v <= 1 {
+		cfg.AcceptProbability = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SIMULATOR_TICK_MS")); err == nil && v > 0 {
+		cfg.Tick = time.Duration(v) * time.Millisecond
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("SIMULATOR_TIME_SCALE"), 64); err == nil && v > 0 {
+		cfg.TimeScale = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SIMULATOR_REPAIR_MINUTES")); err == nil && v > 0 {
+		cfg.RepairDuration = time.Duration(v) * time.Minute
+	}
+	// Per repair type durations, e.g. "flat_tire:20,battery:15"
+	for _, pair := range strings.Split(os.Getenv("SIMULATOR_REPAIR_MINUTES_BY_TYPE"), ",") {
+		repairType, minutes, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok {
+			continue
+		}
+		if v, err := strconv.Atoi(strings.TrimSpace(minutes)); err == nil && v > 0 {
+			cfg.RepairDurations[strings.TrimSpace(repairType)] = time.Duration(v) * time.Minute
+		}
+	}
+	if v, ok := os.LookupEnv("SIMULATOR_OSRM_URL"); ok {
+		cfg.OSRMURL = v
+	}
+	if v := os.Getenv("SIMULATOR_STATUS_URL"); v != "" {
+		cfg.StatusURL = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("SIMULATOR_SEED"), 10, 64); err == nil {
+		cfg.Seed = v
+	}
+	return cfg, enabled
+}
+
+// virtualMechanic is one simulated mechanic and its private random source
+type virtualMechanic struct {
+	mechanic *domain.Mechanic
+	rng      *rand.Rand
+	declined map[string]bool // repairs already offered and turned down
+}
+
+// Simulator drives the virtual fleet. Each virtual mechanic polls its nearby
+// repairs, claims one with AcceptProbability, drives there along the OSRM
+// route while publishing its position, marks the repair in_progress on
+// arrival and completed once the repair duration has passed.
+type Simulator struct {
+	cfg        Config
+	dispatcher Dispatcher
+	repo       domain.MechanicRepository
+	httpClient *http.Client
+	tracer     trace.Tracer
+	logger     *slog.Logger
+	mechanics  []*virtualMechanic
+	cancel     context.CancelFunc
+	wg         sync.WaitGroup
+}
+
+// New creates a simulator; call Start to spawn the fleet
+func New(cfg Config, dispatcher Dispatcher, repo domain.MechanicRepository, logger *slog.Logger) *Simulator {
+	return &Simulator{
+		cfg:        cfg,
+		dispatcher: dispatcher,
+		repo:       repo,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		tracer:     otel.Tracer("mechanic-service"),
+		logger:     logger,
+	}
+}
+
+// Start registers the virtual mechanics as online and starts moving them
+func (s *Simulator) Start() error {
+	ctx, span := s.tracer.Start(context.Background(), "SimulatorStart")
+	defer span.End()
+	span.SetAttributes(attribute.Int("mechanicCount", s.cfg.Mechanics))
+
+	rng := rand.New(rand.NewSource(s.cfg.Seed))
+	mechanics := make([]*domain.Mechanic, s.cfg.Mechanics)
+	s.mechanics = make([]*virtualMechanic, s.cfg.Mechanics)
+	for i := range mechanics {
+		mechanics[i] = &domain.Mechanic{
+			ID:       fmt.Sprintf("sim-mechanic-%03d", i+1),
+			Name:     fmt.Sprintf("Simulated Mechanic %d", i+1),
+			Location: s.spawnLocation(rng),
+			Status:   domain.MechanicOnline,
+		}
+		s.mechanics[i] = &virtualMechanic{
+			mechanic: mechanics[i],
+			rng:      rand.New(rand.NewSource(rng.Int63())),
+			declined: make(map[string]bool),
+		}
+	}
+	failed, err := s.repo.UpsertMechanics(ctx, mechanics)
+	if err == nil && len(failed) > 0 {
+		err = fmt.Errorf("failed to register %d virtual mechanics", len(failed))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to register virtual mechanics")
+		s.logger.Error("Failed to register virtual mechanics", "error", err, "app", "mechanic-service")
+		return err
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	for _, m := range s.mechanics {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.run(runCtx, m)
+		}()
+	}
+	s.logger.Info("Started mechanic simulator", "mechanics", s.cfg.Mechanics, "acceptProbability", s.cfg.AcceptProbability,
+		"timeScale", s.cfg.TimeScale, "osrmURL", s.cfg.OSRMURL, "statusURL", s.cfg.StatusURL, "seed", s.cfg.Seed, "app", "mechanic-service")
+	return nil
+}
+
+// Stop halts the fleet and marks the virtual mechanics offline. Repairs in
+// flight keep their last status.
+func (s *Simulator) Stop() {
+	if s.cancel == nil {
+		return
+	}
+	s.cancel()
+	s.wg.Wait()
+
+	mechanics := make([]*domain.Mechanic, len(s.mechanics))
+	for i, m := range s.mechanics {
+		m.mechanic.Status = domain.MechanicOffline
+		mechanics[i] = m.mechanic
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := s.repo.UpsertMechanics(ctx, mechanics); err != nil {
+		s.logger.Error("Failed to mark virtual mechanics offline", "error", err, "app", "mechanic-service")
+	}
+	s.logger.Info("Stopped mechanic simulator", "app", "mechanic-service")
+}
+
+// spawnLocation picks a uniformly random point within the spawn radius
+func (s *Simulator) spawnLocation(rng *rand.Rand) domain.Location {
+	distance := s.cfg.SpawnRadiusKm * math.Sqrt(rng.Float64())
+	bearing := rng.Float64() * 2 * math.Pi
+	return domain.Location{
+		Latitude:  s.cfg.Center.Latitude + distance*math.Cos(bearing)/111.32,
+		Longitude: s.cfg.Center.Longitude + distance*math.Sin(bearing)/(111.32*math.Cos(s.cfg.Center.Latitude*math.Pi/180)),
+	}
+}
+
+// run is the life of one virtual mechanic
+func (s *Simulator) run(ctx context.Context, m *virtualMechanic) {
+	ticker := time.NewTicker(s.cfg.Tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		repair := s.claimOffer(ctx, m)
+		if repair == nil {
+			continue
+		}
+		if err := s.serve(ctx, m, repair); err != nil && ctx.Err() == nil {
+			s.logger.Error("Virtual mechanic failed to serve repair", "error", err, "mechanicID", m.mechanic.ID, "repairID", repair.ID, "app", "mechanic-service")
+		}
+	}
+}
+
+// claimOffer goes through the open nearby repairs, nearest first, and claims
+// the first one the mechanic accepts. A repair turned down or lost to another
+// mechanic is not offered to this mechanic again.
+func (s *Simulator) claimOffer(ctx context.Context, m *virtualMechanic) *domain.Repair {
+	repairs, err := s.dispatcher.ListNearbyRepairs(ctx, m.mechanic.ID, domain.NearbySearch{})
+	if err != nil {
+		s.logger.Warn("Virtual mechanic failed to list nearby repairs", "error", err, "mechanicID", m.mechanic.ID, "app", "mechanic-service")
+		return nil
+	}
+
+	var offers []*domain.Repair
+	for _, repair := range repairs {
+		if repair.AssignedTo == "" && !m.declined[repair.ID] && isAssignable(repair.Status) {
+			offers = append(offers, repair)
+		}
+	}
+	sort.Slice(offers, func(i, j int) bool {
+		return haversineMeters(m.mechanic.Location, *offers[i].RepairCost.UserLocation) <
+			haversineMeters(m.mechanic.Location, *offers[j].RepairCost.UserLocation)
+	})
+
+	for _, offer := range offers {
+		m.declined[offer.ID] = true
+		if m.rng.Float64() >= s.cfg.AcceptProbability {
+			s.logger.Info("Virtual mechanic declined repair", "mechanicID", m.mechanic.ID, "repairID", offer.ID, "app", "mechanic-service")
+			continue
+		}
+		repair, err := s.dispatcher.AssignRepair(ctx, offer.ID, m.mechanic.ID, 0)
+		if err != nil {
+			s.logger.Info("Virtual mechanic failed to claim repair", "error", err, "mechanicID", m.mechanic.ID, "repairID", offer.ID, "app", "mechanic-service")
+			continue
+		}
+		if repair.RepairCost == nil {
+			repair.RepairCost = offer.RepairCost
+		}
+		return repair
+	}
+	return nil
+}
+
+func isAssignable(status string) bool {
+	for _, s := range domain.AssignableStatuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
+
+// serve drives to the repair, works on it and completes it
+func (s *Simulator) serve(ctx context.Context, m *virtualMechanic, repair *domain.Repair) error {
+	ctx, span := s.tracer.Start(ctx, "SimulatorServeRepair")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("mechanicID", m.mechanic.ID),
+		attribute.String("repairID", repair.ID),
+	)
+
+	destination := *repair.RepairCost.UserLocation
+	r := straightRoute(m.mechanic.Location, destination)
+	if s.cfg.OSRMURL != "" {
+		osrmRoute, err := fetchRoute(ctx, s.httpClient, s.cfg.OSRMURL, m.mechanic.Location, destination)
+		if err != nil {
+			s.logger.Warn("Failed to get OSRM route, driving a straight line", "error", err, "mechanicID", m.mechanic.ID, "app", "mechanic-service")
+		} else {
+			r = osrmRoute
+		}
+	}
+	span.SetAttributes(attribute.Float64("routeSeconds", r.duration))
+	s.logger.Info("Virtual mechanic driving to repair", "mechanicID", m.mechanic.ID, "repairID", repair.ID, "routeSeconds", r.duration, "app", "mechanic-service")
+
+	if err := s.drive(ctx, m, r); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to drive to repair")
+		return err
+	}
+	if err := s.updateStatus(ctx, repair.ID, "in_progress"); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to start repair")
+		return err
+	}
+
+	duration := s.cfg.RepairDuration
+	if d, ok := s.cfg.RepairDurations[repair.RepairCost.RepairType]; ok {
+		duration = d
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Duration(float64(duration) / s.cfg.TimeScale)):
+	}
+	if err := s.updateStatus(ctx, repair.ID, "completed"); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to complete repair")
+		return err
+	}
+	s.logger.Info("Virtual mechanic completed repair", "mechanicID", m.mechanic.ID, "repairID", repair.ID, "app", "mechanic-service")
+	return nil
+}
+
+// drive moves the mechanic along the route at the scaled speed, reporting
+// its position every tick like a real device
+func (s *Simulator) drive(ctx context.Context, m *virtualMechanic, r *route) error {
+	travel := time.Duration(r.duration / s.cfg.TimeScale * float64(time.Second))
+	start := time.Now()
+	ticker := time.NewTicker(s.cfg.Tick)
+	defer ticker.Stop()
+	for {
+		share := 1.0
+		if travel > 0 {
+			share = float64(time.Since(start)) / float64(travel)
+		}
+		m.mechanic.Location = r.at(share)
+		update := &domain.LocationUpdate{MechanicID: m.mechanic.ID, Location: m.mechanic.Location}
+		if _, err := s.dispatcher.UpdateLocation(ctx, update); err != nil {
+			s.logger.Warn("Failed to report virtual mechanic position", "error", err, "mechanicID", m.mechanic.ID, "app", "mechanic-service")
+		}
+		if share >= 1 {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// updateStatus changes the repair status the way a mechanic app would, so
+// the update is broadcast to the user like any other
+func (s *Simulator) updateStatus(ctx context.Context, repairID, status string) error {
+	body, err := json.Marshal(map[string]string{"status": status})
+	if err != nil {
+		return fmt.Errorf("failed to marshal status update: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, strings.TrimRight(s.cfg.StatusURL, "/")+"/repairs/"+repairID, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create status update request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to update repair status: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to update repair status to %s: status %d", status, resp.StatusCode)
+	}
+	return nil
+}
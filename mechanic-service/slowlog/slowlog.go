@@ -0,0 +1,195 @@
+package slowlog
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Kinds of operations the recorder times
+const (
+	KindMongo   = "mongo"
+	KindHTTP    = "http_client"
+	KindHandler = "handler"
+)
+
+// SlowCount is how often one operation exceeded its threshold
+type SlowCount struct {
+	Kind      string    `json:"kind"`
+	Operation string    `json:"operation"`
+	Count     int64     `json:"count"`
+	MaxMs     int64     `json:"maxMs"`
+	LastAt    time.Time `json:"lastAt"`
+	LastTrace string    `json:"lastTraceID,omitempty"`
+}
+
+// Recorder logs and counts Mongo commands, downstream HTTP calls and HTTP
+// handlers slower than their threshold, with the trace ID, so latency
+// regressions show up in the logs without searching Jaeger
+type Recorder struct {
+	thresholds map[string]time.Duration // a zero threshold disables the kind
+	logger     *slog.Logger
+	mu         sync.Mutex
+	counts     map[string]*SlowCount // keyed by kind and operation
+	commands   sync.Map              // Mongo request ID -> operation name
+}
+
+// New creates a Recorder with thresholds from SLOW_MONGO_MS (default 100),
+// SLOW_HTTP_CLIENT_MS (default 500) and SLOW_HANDLER_MS (default 1000); 0
+// turns a kind off
+func New(logger *slog.Logger) *Recorder {
+	r := &Recorder{
+		thresholds: map[string]time.Duration{
+			KindMongo:   100 * time.Millisecond,
+			KindHTTP:    500 * time.Millisecond,
+			KindHandler: time.Second,
+		},
+		logger: logger,
+		counts: make(map[string]*SlowCount),
+	}
+	for kind, env := range map[string]string{KindMongo: "SLOW_MONGO_MS", KindHTTP: "SLOW_HTTP_CLIENT_MS", KindHandler: "SLOW_HANDLER_MS"} {
+		if v, err := strconv.Atoi(os.Getenv(env)); err == nil && v >= 0 {
+			r.thresholds[kind] = time.Duration(v) * time.Millisecond
+		}
+	}
+	logger.Info("Configured slow operation logging", "mongo", r.thresholds[KindMongo], "httpClient", r.thresholds[KindHTTP], "handler", r.thresholds[KindHandler], "app", "mechanic-service")
+	return r
+}
+
+// Observe logs and counts the operation if it took longer than the threshold of its kind
+func (r *Recorder) Observe(ctx context.Context, kind, operation string, d time.Duration) {
+	threshold := r.thresholds[kind]
+	if threshold == 0 || d < threshold {
+		return
+	}
+	traceID := ""
+	if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+		traceID = sc.TraceID().String()
+	}
+
+	r.mu.Lock()
+	c, ok := r.counts[kind+" "+operation]
+	if !ok {
+		c = &SlowCount{Kind: kind, Operation: operation}
+		r.counts[kind+" "+operation] = c
+	}
+	c.Count++
+	if ms := d.Milliseconds(); ms > c.MaxMs {
+		c.MaxMs = ms
+	}
+	c.LastAt = time.Now().UTC()
+	c.LastTrace = traceID
+	r.mu.Unlock()
+
+	r.logger.Warn("Slow operation", "kind", kind, "operation", operation, "durationMs", d.Milliseconds(), "thresholdMs", threshold.Milliseconds(), "traceID", traceID, "app", "mechanic-service")
+}
+
+// Counts returns the slow operations seen so far, most frequent first
+func (r *Recorder) Counts() []SlowCount {
+	r.mu.Lock()
+	counts := make([]SlowCount, 0, len(r.counts))
+	for _, c := range r.counts {
+		counts = append(counts, *c)
+	}
+	r.mu.Unlock()
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Kind+counts[i].Operation < counts[j].Kind+counts[j].Operation
+	})
+	return counts
+}
+
+// MongoMonitor times every command sent on a Mongo client; set it with
+// options.Client().SetMonitor. Operations are named "<command> <collection>".
+func (r *Recorder) MongoMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(_ context.Context, evt *event.CommandStartedEvent) {
+			operation := evt.CommandName
+			if elem, err := evt.Command.IndexErr(0); err == nil {
+				if coll, ok := elem.Value().StringValueOK(); ok {
+					operation += " " + coll
+				}
+			}
+			r.commands.Store(evt.RequestID, operation)
+		},
+		Succeeded: func(ctx context.Context, evt *event.CommandSucceededEvent) {
+			r.observeCommand(ctx, evt.CommandFinishedEvent)
+		},
+		Failed: func(ctx context.Context, evt *event.CommandFailedEvent) {
+			r.observeCommand(ctx, evt.CommandFinishedEvent)
+		},
+	}
+}
+
+func (r *Recorder) observeCommand(ctx context.Context, evt event.CommandFinishedEvent) {
+	operation, ok := r.commands.LoadAndDelete(evt.RequestID)
+	if !ok {
+		operation = evt.CommandName
+	}
+	r.Observe(ctx, KindMongo, operation.(string), evt.Duration)
+}
+
+// Transport times the requests sent through base (http.DefaultTransport when
+// nil). Operations are named "<method> <host>".
+func (r *Recorder) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return roundTripper{base: base, recorder: r}
+}
+
+type roundTripper struct {
+	base     http.RoundTripper
+	recorder *Recorder
+}
+
+func (t roundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	t.recorder.Observe(req.Context(), KindHTTP, req.Method+" "+req.URL.Host, time.Since(start))
+	return resp, err
+}
+
+// Middleware times HTTP handlers. Operations are named "<method> <route
+// template>"; the trace ID comes from the server span or, without one, from
+// the propagated trace context. WebSocket and Server-Sent Events streams are
+// long-lived by design and not timed.
+func (r *Recorder) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if strings.EqualFold(req.Header.Get("Upgrade"), "websocket") {
+			next.ServeHTTP(w, req)
+			return
+		}
+		start := time.Now()
+		next.ServeHTTP(w, req)
+		if strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+			return
+		}
+
+		route := req.URL.Path
+		if current := mux.CurrentRoute(req); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		ctx := req.Context()
+		if !trace.SpanContextFromContext(ctx).HasTraceID() {
+			ctx = otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(req.Header))
+		}
+		r.Observe(ctx, KindHandler, req.Method+" "+route, time.Since(start))
+	})
+}
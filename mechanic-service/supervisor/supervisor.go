@@ -0,0 +1,209 @@
+package supervisor
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Loop states reported by Health
+const (
+	StateRunning    = "running"
+	StateRestarting = "restarting"
+	StateStopped    = "stopped"
+)
+
+// LoopHealth is the health of one supervised loop
+type LoopHealth struct {
+	Name        string    `json:"name"`
+	State       string    `json:"state"`
+	Restarts    int       `json:"restarts"`
+	CrashLoop   bool      `json:"crashLoop"`
+	LastError   string    `json:"lastError,omitempty"`
+	LastErrorAt time.Time `json:"lastErrorAt,omitempty"`
+	StartedAt   time.Time `json:"startedAt"`
+}
+
+// Supervisor runs long-lived loops such as the Kafka consumer and the outbox
+// processor, restarting them with exponential backoff when they fail or
+// panic. A loop restarting too often within the crash loop window is flagged
+// and logged as a crash loop.
+type Supervisor struct {
+	backoff         time.Duration
+	maxBackoff      time.Duration
+	crashLoopAfter  int
+	crashLoopWindow time.Duration
+	logger          *slog.Logger
+	mu              sync.Mutex
+	loops           []*loop
+	wg              sync.WaitGroup // supervising goroutines
+}
+
+type loop struct {
+	health   LoopHealth
+	failures []time.Time // restarts within the crash loop window
+}
+
+// New creates a Supervisor configured from the SUPERVISOR_* environment variables
+func New(logger *slog.Logger) *Supervisor {
+	s := &Supervisor{
+		backoff:         time.Second,
+		maxBackoff:      time.Minute,
+		crashLoopAfter:  5,
+		crashLoopWindow: 5 * time.Minute,
+		logger:          logger,
+	}
+	if v, err := strconv.Atoi(os.Getenv("SUPERVISOR_BACKOFF_MS")); err == nil && v > 0 {
+		s.backoff = time.Duration(v) * time.Millisecond
+	}
+	if v, err := strconv.Atoi(os.Getenv("SUPERVISOR_MAX_BACKOFF_MS")); err == nil && v > 0 {
+		s.maxBackoff = time.Duration(v) * time.Millisecond
+	}
+	if v, err := strconv.Atoi(os.Getenv("SUPERVISOR_CRASH_LOOP_RESTARTS")); err == nil && v > 0 {
+		s.crashLoopAfter = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("SUPERVISOR_CRASH_LOOP_WINDOW_SECONDS")); err == nil && v > 0 {
+		s.crashLoopWindow = time.Duration(v) * time.Second
+	}
+	logger.Info("Configured supervisor", "backoff", s.backoff, "maxBackoff", s.maxBackoff, "crashLoopRestarts", s.crashLoopAfter, "crashLoopWindow", s.crashLoopWindow, "app", "mechanic-service")
+	return s
+}
+
+// Go runs fn in a goroutine until ctx is canceled, restarting it whenever it
+// returns or panics before that
+func (s *Supervisor) Go(ctx context.Context, name string, fn func(ctx context.Context) error) {
+	l := &loop{health: LoopHealth{Name: name, State: StateRunning}}
+	s.mu.Lock()
+	s.loops = append(s.loops, l)
+	s.mu.Unlock()
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.supervise(ctx, l, fn)
+	}()
+}
+
+// Wait blocks until every loop has stopped after its context was canceled. It
+// gives up when ctx is done.
+func (s *Supervisor) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("supervised loops did not stop: %w", ctx.Err())
+	}
+}
+
+func (s *Supervisor) supervise(ctx context.Context, l *loop, fn func(ctx context.Context) error) {
+	backoff := s.backoff
+	for {
+		startedAt := time.Now()
+		s.setState(l, StateRunning, startedAt)
+		s.logger.Info("Starting supervised loop", "loop", l.health.Name, "app", "mechanic-service")
+
+		err := run(ctx, fn)
+		if ctx.Err() != nil {
+			s.setState(l, StateStopped, startedAt)
+			s.logger.Info("Supervised loop stopped", "loop", l.health.Name, "app", "mechanic-service")
+			return
+		}
+		if err == nil {
+			err = fmt.Errorf("loop returned without error before shutdown")
+		}
+		// A loop that ran stably for a while starts over from the base backoff
+		if time.Since(startedAt) > s.maxBackoff {
+			backoff = s.backoff
+		}
+
+		restarts, crashLoop := s.recordFailure(l, err)
+		if crashLoop {
+			s.logger.Error("Supervised loop is crash looping", "loop", l.health.Name, "restarts", restarts, "window", s.crashLoopWindow, "error", err, "backoff", backoff, "app", "mechanic-service")
+		} else {
+			s.logger.Warn("Supervised loop failed, restarting", "loop", l.health.Name, "restarts", restarts, "error", err, "backoff", backoff, "app", "mechanic-service")
+		}
+
+		select {
+		case <-ctx.Done():
+			s.setState(l, StateStopped, startedAt)
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > s.maxBackoff {
+			backoff = s.maxBackoff
+		}
+	}
+}
+
+// run calls fn, turning a panic into an error
+func run(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+	return fn(ctx)
+}
+
+func (s *Supervisor) setState(l *loop, state string, startedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l.health.State = state
+	l.health.StartedAt = startedAt
+}
+
+// recordFailure counts a restart and reports whether the loop is crash looping
+func (s *Supervisor) recordFailure(l *loop, err error) (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	recent := l.failures[:0]
+	for _, t := range l.failures {
+		if now.Sub(t) < s.crashLoopWindow {
+			recent = append(recent, t)
+		}
+	}
+	l.failures = append(recent, now)
+	l.health.State = StateRestarting
+	l.health.Restarts++
+	l.health.LastError = err.Error()
+	l.health.LastErrorAt = now
+	l.health.CrashLoop = len(l.failures) >= s.crashLoopAfter
+	return l.health.Restarts, l.health.CrashLoop
+}
+
+// Health returns the health of every supervised loop
+func (s *Supervisor) Health() []LoopHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	health := make([]LoopHealth, 0, len(s.loops))
+	for _, l := range s.loops {
+		h := l.health
+		// The crash loop flag clears once the loop stays up for a full window
+		if h.State == StateRunning && h.CrashLoop && time.Since(h.StartedAt) > s.crashLoopWindow {
+			h.CrashLoop = false
+		}
+		health = append(health, h)
+	}
+	return health
+}
+
+// Ready reports whether every supervised loop is running
+func (s *Supervisor) Ready() bool {
+	for _, h := range s.Health() {
+		if h.State != StateRunning {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,125 @@
+// Package trip orders a mechanic's stops with the OSRM trip service, which
+// solves the travelling salesman problem over road travel times
+package trip
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"mechanic-service/domain"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// MaxStops is the most stops one request may order; the public OSRM server
+// accepts 100 coordinates, the start included
+const MaxStops = 99
+
+// Plan is a trip from the start through every stop, in visiting order
+type Plan struct {
+	Order []int     // indexes of the stops in visiting order
+	Legs  []float64 // seconds of driving to each stop of Order from the previous one
+}
+
+// Client calls the trip service of an OSRM server
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient creates a Client for the OSRM server at baseURL
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	return &Client{baseURL: strings.TrimRight(baseURL, "/"), httpClient: httpClient}
+}
+
+// BaseURL returns the OSRM server URL
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Optimize orders stops into the fastest trip from start that visits each one
+// once and ends at any of them
+func (c *Client) Optimize(ctx context.Context, start domain.Location, stops []domain.Location) (*Plan, error) {
+	ctx, span := otel.Tracer("mechanic-service").Start(ctx, "OSRMTripRequest")
+	defer span.End()
+	span.SetAttributes(attribute.Int("stopCount", len(stops)))
+
+	if len(stops) == 0 {
+		return &Plan{}, nil
+	}
+	if len(stops) > MaxStops {
+		err := fmt.Errorf("cannot order %d stops, at most %d", len(stops), MaxStops)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	coordinates := make([]string, 0, len(stops)+1)
+	coordinates = append(coordinates, fmt.Sprintf("%f,%f", start.Longitude, start.Latitude))
+	for _, s := range stops {
+		coordinates = append(coordinates, fmt.Sprintf("%f,%f", s.Longitude, s.Latitude))
+	}
+	tripURL := fmt.Sprintf("%s/trip/v1/driving/%s?source=first&roundtrip=false&destination=any&overview=false", c.baseURL, strings.Join(coordinates, ";"))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tripURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OSRM request: %w", err)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to call OSRM trip service")
+		return nil, fmt.Errorf("failed to call OSRM trip service: %w", err)
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("statusCode", resp.StatusCode))
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("OSRM trip service returned status %d", resp.StatusCode)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	var osrmResp struct {
+		Code      string `json:"code"`
+		Waypoints []struct {
+			WaypointIndex int `json:"waypoint_index"` // position in the trip
+			TripsIndex    int `json:"trips_index"`
+		} `json:"waypoints"`
+		Trips []struct {
+			Legs []struct {
+				Duration float64 `json:"duration"`
+			} `json:"legs"`
+		} `json:"trips"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&osrmResp); err != nil {
+		return nil, fmt.Errorf("failed to decode OSRM response: %w", err)
+	}
+	// Unroutable stops split the result into several trips
+	if osrmResp.Code != "Ok" || len(osrmResp.Trips) != 1 || len(osrmResp.Waypoints) != len(stops)+1 || len(osrmResp.Trips[0].Legs) != len(stops) {
+		err := fmt.Errorf("OSRM trip service found no single trip: %s", osrmResp.Code)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	plan := &Plan{Order: make([]int, len(stops)), Legs: make([]float64, len(stops))}
+	for i, w := range osrmResp.Waypoints[1:] {
+		position := w.WaypointIndex - 1 // the start is always first
+		if position < 0 || position >= len(stops) {
+			return nil, fmt.Errorf("OSRM trip service returned waypoint index %d for %d stops", w.WaypointIndex, len(stops))
+		}
+		plan.Order[position] = i
+	}
+	for i, leg := range osrmResp.Trips[0].Legs {
+		plan.Legs[i] = leg.Duration
+	}
+	return plan, nil
+}
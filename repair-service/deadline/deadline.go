@@ -0,0 +1,95 @@
+// Package deadline applies the time budget the gateway propagates with each
+// request, so Mongo queries and routing calls give up when the caller has
+// stopped waiting, and the request answers 504 instead of hanging
+package deadline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Header carries the time the caller has left, in milliseconds
+const Header = "X-Request-Timeout-Ms"
+
+// Middleware runs handlers with the deadline of the request's Header, if any.
+// A 5xx answered after the deadline expired, usually a Mongo or routing error
+// caused by it, is replaced with a 504 carrying the deadline_exceeded error
+// code.
+func Middleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ms, err := strconv.ParseInt(r.Header.Get(Header), 10, 64)
+			if err != nil || ms <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			timeout := time.Duration(ms) * time.Millisecond
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			dw := &deadlineWriter{ResponseWriter: w, ctx: ctx, timeout: timeout}
+			next.ServeHTTP(dw, r.WithContext(ctx))
+			if !dw.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				dw.writeDeadlineExceeded()
+			}
+			if dw.exceeded {
+				logger.Warn("Request deadline exceeded", "method", r.Method, "path", r.URL.Path, "timeout", timeout, "app", "repair-service")
+			}
+		})
+	}
+}
+
+// deadlineWriter replaces a 5xx answered after the deadline with a 504
+type deadlineWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	timeout     time.Duration
+	wroteHeader bool
+	exceeded    bool // the 504 was written; the handler's own body is dropped
+}
+
+func (w *deadlineWriter) WriteHeader(status int) {
+	if w.wroteHeader {
+		return
+	}
+	if status >= http.StatusInternalServerError && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.writeDeadlineExceeded()
+		return
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *deadlineWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.exceeded {
+		return len(p), nil
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush forwards streaming flushes to the underlying writer
+func (w *deadlineWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// writeDeadlineExceeded answers 504 with the deadline_exceeded error code
+func (w *deadlineWriter) writeDeadlineExceeded() {
+	w.wroteHeader, w.exceeded = true, true
+	w.Header().Del("X-Content-Type-Options")
+	w.Header().Set("Content-Type", "application/json")
+	w.ResponseWriter.WriteHeader(http.StatusGatewayTimeout)
+	json.NewEncoder(w.ResponseWriter).Encode(map[string]interface{}{
+		"error":     "deadline_exceeded",
+		"message":   "The request did not complete within its deadline",
+		"timeoutMs": w.timeout.Milliseconds(),
+	})
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// Amendment statuses
+const (
+	AmendmentPending  = "pending"
+	AmendmentApproved = "approved"
+	AmendmentRejected = "rejected"
+)
+
+// ErrAmendmentPending marks a completion attempted while the user has not yet
+// answered a price increase; handlers map it to 409
+var ErrAmendmentPending = errors.New("repair has a price amendment awaiting the user's approval")
+
+// Amendment is extra work (usually parts) a mechanic asks to add to a repair
+// after it started. The user approves or rejects the price increase; approved
+// amendments are billed on the receipt on top of the quoted price. Amendments
+// live in the repair_amendments collection and are never edited once decided.
+type Amendment struct {
+	ID          string     `bson:"_id,omitempty" json:"id"`
+	RepairID    string     `bson:"repairID" json:"repairID"`
+	UserID      string     `bson:"userID" json:"userID"`
+	MechanicID  string     `bson:"mechanicID" json:"mechanicID"`
+	Description string     `bson:"description" json:"description"`
+	Amount      Money      `bson:"amount" json:"amount"`
+	Status      string     `bson:"status" json:"status"`
+	RequestedAt time.Time  `bson:"requestedAt" json:"requestedAt"`
+	DecidedAt   *time.Time `bson:"decidedAt,omitempty" json:"decidedAt,omitempty"`
+}
+
+// FinalAmount is the quoted price plus every approved amendment, the amount a
+// repair must be completed with
+func FinalAmount(quoted Money, amendments []*Amendment) Money {
+	total := quoted
+	for _, a := range amendments {
+		if a.Status == AmendmentApproved {
+			total += a.Amount
+		}
+	}
+	return total
+}
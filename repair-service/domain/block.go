@@ -0,0 +1,49 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrBlacklisted marks requests from users an admin has blacklisted; handlers map it to 403
+var ErrBlacklisted = errors.New("user is blacklisted")
+
+// Who created a block relation
+const (
+	BlockedByUser  = "user"  // the user blocked one mechanic
+	BlockedByAdmin = "admin" // an admin blacklisted the user
+)
+
+// AllMechanics is the MechanicID of an admin blacklist, which blocks the user
+// from every mechanic
+const AllMechanics = "*"
+
+// Block prevents a user and a mechanic from being matched. Blocks live in the
+// shared blocks collection so mechanic-service enforces them too.
+type Block struct {
+	ID         string    `bson:"_id,omitempty" json:"id"`
+	UserID     string    `bson:"userID" json:"userID"`
+	MechanicID string    `bson:"mechanicID" json:"mechanicID"`
+	BlockedBy  string    `bson:"blockedBy" json:"blockedBy"`
+	Reason     string    `bson:"reason,omitempty" json:"reason,omitempty"`
+	CreatedAt  time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// BlockList is the set of block relations affecting one user
+type BlockList struct {
+	Blacklisted bool
+	Mechanics   map[string]bool // mechanic IDs the user blocked
+}
+
+// NewBlockList indexes the blocks of one user
+func NewBlockList(blocks []*Block) *BlockList {
+	list := &BlockList{Mechanics: make(map[string]bool, len(blocks))}
+	for _, block := range blocks {
+		if block.MechanicID == AllMechanics {
+			list.Blacklisted = true
+		} else {
+			list.Mechanics[block.MechanicID] = true
+		}
+	}
+	return list
+}
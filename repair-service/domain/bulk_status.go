@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// MaxBulkStatusRepairs bounds the repairs of one bulk status update
+const MaxBulkStatusRepairs = 5000
+
+// statusTransitions are the statuses a repair may move to from each status.
+// Completed and cancelled repairs are final.
+var statusTransitions = map[string][]string{
+	"pending":     {"accepted", "in_progress", "cancelled"},
+	"accepted":    {"in_progress", "cancelled"},
+	"in_progress": {"completed", "cancelled"},
+}
+
+// CanTransition reports whether a repair may move from one status to another
+func CanTransition(from, to string) bool {
+	return slices.Contains(statusTransitions[from], to)
+}
+
+// Outcomes of the repairs of a bulk status update
+const (
+	BulkStatusUpdated     = "updated"
+	BulkStatusWouldUpdate = "would_update" // dry run
+	BulkStatusUnchanged   = "unchanged"    // already in the target status
+	BulkStatusRejected    = "rejected"     // the transition is not allowed
+	BulkStatusNotFound    = "not_found"
+	BulkStatusFailed      = "failed" // the repair's batch could not be written
+)
+
+// BulkStatusRequest moves many repairs to one status, e.g. to cancel the
+// test repairs left behind by an incident
+type BulkStatusRequest struct {
+	RepairIDs   []string `json:"repairIDs"`
+	Status      string   `json:"status"`
+	Reason      string   `json:"reason"`              // logged with the update
+	RequestedBy string   `json:"requestedBy"`         // operator running the update, for the audit log
+	SkipEmail   bool     `json:"skipEmail,omitempty"` // do not email users about cancellations
+	DryRun      bool     `json:"dryRun,omitempty"`    // report the outcomes without writing
+}
+
+// BulkStatusResult is the outcome of one repair of a bulk status update
+type BulkStatusResult struct {
+	RepairID string `json:"repairID"`
+	From     string `json:"from,omitempty"` // status before the update
+	Outcome  string `json:"outcome"`
+	Message  string `json:"message,omitempty"`
+}
+
+// BulkStatusReport summarizes a bulk status update; repairs are written in
+// batches, each in its own transaction
+type BulkStatusReport struct {
+	Status    string             `json:"status"`
+	DryRun    bool               `json:"dryRun,omitempty"`
+	Updated   int                `json:"updated"`
+	Unchanged int                `json:"unchanged"`
+	Rejected  int                `json:"rejected"`
+	NotFound  int                `json:"notFound"`
+	Failed    int                `json:"failed"`
+	Results   []BulkStatusResult `json:"results"`
+}
+
+// BulkStatuses are the target statuses of a bulk status update. Completing a
+// repair freezes its receipt, so repairs are completed one at a time.
+var BulkStatuses = []string{"in_progress", "cancelled"}
+
+// Validate checks the request and drops blank and repeated repair IDs
+func (r *BulkStatusRequest) Validate() error {
+	ids := make([]string, 0, len(r.RepairIDs))
+	seen := make(map[string]bool, len(r.RepairIDs))
+	for _, id := range r.RepairIDs {
+		if id = strings.TrimSpace(id); id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	r.RepairIDs = ids
+	switch {
+	case len(ids) == 0:
+		return fmt.Errorf("%w: repairIDs is required", ErrInvalidInput)
+	case len(ids) > MaxBulkStatusRepairs:
+		return fmt.Errorf("%w: at most %d repairs per bulk status update", ErrInvalidInput, MaxBulkStatusRepairs)
+	case !slices.Contains(BulkStatuses, r.Status):
+		return fmt.Errorf("%w: status must be one of %s", ErrInvalidInput, strings.Join(BulkStatuses, ", "))
+	case strings.TrimSpace(r.RequestedBy) == "":
+		return fmt.Errorf("%w: requestedBy is required", ErrInvalidInput)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package domain
+
+import "time"
+
+// Bundle statuses, derived from the statuses of the bundled repairs
+const (
+	BundlePending    = "pending"     // no mechanic assigned yet
+	BundleAssigned   = "assigned"    // a mechanic took the bundle, no repair started
+	BundleInProgress = "in_progress" // some repairs started or finished, others open
+	BundleCompleted  = "completed"   // every repair completed or cancelled, at least one completed
+	BundleCancelled  = "cancelled"   // every repair cancelled
+)
+
+// RepairBundle groups pending repairs at one location, e.g. the broken bikes
+// of a bike-share station, into a job that mechanic-service dispatches to one
+// mechanic as a unit. The repairs keep their own status; each carries the
+// bundle ID and its share of the bundle discount.
+type RepairBundle struct {
+	ID              string    `bson:"_id" json:"id"`
+	Name            string    `bson:"name,omitempty" json:"name,omitempty"`
+	RepairIDs       []string  `bson:"repairIDs" json:"repairIDs"`
+	Location        Location  `bson:"location" json:"location"` // centroid of the bundled repairs
+	Region          string    `bson:"region,omitempty" json:"region,omitempty"`
+	Subtotal        Money     `bson:"subtotal" json:"subtotal"` // sum of the repairs' quoted prices
+	DiscountPercent float64   `bson:"discountPercent" json:"discountPercent"`
+	Price           Money     `bson:"price" json:"price"` // bundled price, the subtotal less the discount
+	CreatedBy       string    `bson:"createdBy,omitempty" json:"createdBy,omitempty"`
+	CreatedAt       time.Time `bson:"createdAt" json:"createdAt"`
+
+	// Filled from the bundled repairs when the bundle is read
+	Status     string         `bson:"-" json:"status,omitempty"`
+	AssignedTo string         `bson:"-" json:"assignedTo,omitempty"`
+	Statuses   map[string]int `bson:"-" json:"statuses,omitempty"` // repair count by status
+	Items      []BundleItem   `bson:"-" json:"items,omitempty"`
+}
+
+// BundleItem is one repair of a bundle and its progress
+type BundleItem struct {
+	RepairID   string `json:"repairID"`
+	UserID     string `json:"userID"`
+	RepairType string `json:"repairType"`
+	Price      Money  `json:"price"` // quoted price less the repair's share of the discount
+	Status     string `json:"status"`
+	AssignedTo string `json:"assignedTo,omitempty"`
+}
+
+// RepairBundleRequest asks for pending repairs to be bundled
+type RepairBundleRequest struct {
+	Name      string   `json:"name"`
+	RepairIDs []string `json:"repairIDs"`
+	CreatedBy string   `json:"createdBy"`
+}
+
+// BundleStatus derives a bundle's status from its repairs' statuses
+func BundleStatus(statuses map[string]int, assigned bool) string {
+	total := 0
+	for _, n := range statuses {
+		total += n
+	}
+	switch {
+	case statuses["cancelled"] == total:
+		return BundleCancelled
+	case statuses["completed"]+statuses["cancelled"] == total:
+		return BundleCompleted
+	case statuses["in_progress"] > 0 || statuses["completed"] > 0:
+		return BundleInProgress
+	case assigned:
+		return BundleAssigned
+	}
+	return BundlePending
+}
@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// EventRepairCancelled is the outbox event type published when a repair is
+// cancelled or deleted. Its payload is the repair event with status
+// cancelled, or RepairStatusDeleted once the repair is gone; mechanic-service
+// marks or removes its copy of the repair accordingly.
+const EventRepairCancelled = "RepairCancelled"
+
+// RepairStatusDeleted is the status of RepairCancelled events of deleted
+// repairs; no stored repair has it
+const RepairStatusDeleted = "deleted"
+
+// ErrRepairNotCancellable marks cancellations of completed repairs; handlers
+// map it to 409
+var ErrRepairNotCancellable = errors.New("repair is completed and can no longer be cancelled")
+
+// ErrRepairNotDeletable marks deletions of repairs that were started or are
+// bundled; handlers map it to 409
+var ErrRepairNotDeletable = errors.New("only pending or cancelled repairs outside a bundle can be deleted")
+
+// DeletableStatuses are the statuses of repairs that may be deleted; started
+// repairs are kept for their mechanic and completed ones for accounting
+var DeletableStatuses = []string{"pending", "cancelled"}
+
+// AuthorizeCancel checks the actor may cancel repair: admins, the repair's
+// user and its assigned mechanic
+func AuthorizeCancel(actor Actor, repair *RepairModel) error {
+	switch {
+	case actor.Role == ActorAdmin:
+	case actor.Role == ActorUser && actor.ID != "" && actor.ID == repair.UserID:
+	case actor.Role == ActorMechanic && actor.ID != "" && actor.ID == repair.AssignedTo:
+	default:
+		return fmt.Errorf("%w: only the repair's user, its assigned mechanic or an admin may cancel it", ErrForbidden)
+	}
+	return nil
+}
+
+// AuthorizeDelete checks the actor may delete repair, admins and the repair's
+// user, and that it is in a deletable status outside a bundle
+func AuthorizeDelete(actor Actor, repair *RepairModel) error {
+	if actor.Role != ActorAdmin && (actor.Role != ActorUser || actor.ID == "" || actor.ID != repair.UserID) {
+		return fmt.Errorf("%w: only the repair's user or an admin may delete it", ErrForbidden)
+	}
+	if repair.BundleID != "" {
+		return fmt.Errorf("%w: repair is in bundle %s", ErrRepairNotDeletable, repair.BundleID)
+	}
+	if !slices.Contains(DeletableStatuses, repair.Status) {
+		return fmt.Errorf("%w: repair is %s", ErrRepairNotDeletable, repair.Status)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// DistanceTile caches OSRM travel times from the center of one grid cell to
+// the centers of the cells around it, so estimates can approximate road
+// travel times while OSRM is unreachable. Cells are SizeDegrees squares
+// numbered from latitude and longitude 0; the tile covers Radius cells in
+// every direction.
+type DistanceTile struct {
+	ID          string    `bson:"_id" json:"id"` // "<sizeDegrees>/<row>/<col>"
+	Region      string    `bson:"region" json:"region"`
+	Row         int       `bson:"row" json:"row"`
+	Col         int       `bson:"col" json:"col"`
+	SizeDegrees float64   `bson:"sizeDegrees" json:"sizeDegrees"`
+	Radius      int       `bson:"radius" json:"radius"`
+	Durations   []float64 `bson:"durations" json:"durations"` // seconds to each neighbor cell, row-major from the south-west corner; -1 without a route
+	Provider    string    `bson:"provider" json:"provider"`   // OSRM provider the tile was built with
+	BuiltAt     time.Time `bson:"builtAt" json:"builtAt"`
+}
+
+// DistanceTileSummary counts the distance tiles of a region
+type DistanceTileSummary struct {
+	Region        string    `bson:"_id" json:"region"`
+	Tiles         int       `bson:"tiles" json:"tiles"`
+	OldestBuiltAt time.Time `bson:"oldestBuiltAt" json:"oldestBuiltAt"`
+	NewestBuiltAt time.Time `bson:"newestBuiltAt" json:"newestBuiltAt"`
+}
+
+// DistanceTileStatus describes the distance tile grid and the tiles built
+type DistanceTileStatus struct {
+	SizeDegrees float64                `json:"sizeDegrees"`
+	Radius      int                    `json:"radius"`
+	Loaded      int                    `json:"loaded"` // tiles the instance answers from
+	Regions     []*DistanceTileSummary `json:"regions"`
+}
@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"math"
+	"time"
+)
+
+// DefaultDurations are the catalog's estimated minutes per repair type, used
+// until enough completed repairs of the type have been timed
+var DefaultDurations = map[string]float64{
+	"flat_tire":         30,
+	"brake_repair":      90,
+	"chain_replacement": 45,
+}
+
+// FallbackDurationMinutes is the estimate of repair types missing from
+// DefaultDurations
+const FallbackDurationMinutes = 60
+
+// CatalogMinutes returns the catalog's estimated minutes for repairType
+func CatalogMinutes(repairType string) float64 {
+	if minutes, ok := DefaultDurations[repairType]; ok {
+		return minutes
+	}
+	return FallbackDurationMinutes
+}
+
+// RepairDurationSample is the actual duration of a completed repair, from
+// in_progress to completed, kept in repair_durations
+type RepairDurationSample struct {
+	RepairID    string    `bson:"_id" json:"repairID"`
+	RepairType  string    `bson:"repairType" json:"repairType"`
+	MechanicID  string    `bson:"mechanicID,omitempty" json:"mechanicID,omitempty"`
+	StartedAt   time.Time `bson:"startedAt" json:"startedAt"`
+	CompletedAt time.Time `bson:"completedAt" json:"completedAt"`
+	Minutes     float64   `bson:"minutes" json:"minutes"`
+}
+
+// DurationEstimate is the learned duration of a repair type, overall or for
+// one mechanic, kept in repair_duration_estimates: the rolling average of the
+// latest samples. Once Learned it replaces the catalog's estimate.
+type DurationEstimate struct {
+	ID             string    `bson:"_id" json:"-"` // the repair type, or mechanicID/repairType
+	RepairType     string    `bson:"repairType" json:"repairType"`
+	MechanicID     string    `bson:"mechanicID,omitempty" json:"mechanicID,omitempty"`
+	CatalogMinutes float64   `bson:"catalogMinutes" json:"catalogMinutes"`
+	AverageMinutes float64   `bson:"averageMinutes" json:"averageMinutes"`
+	Samples        int       `bson:"samples" json:"samples"` // samples in the average, at most the window
+	Learned        bool      `bson:"learned" json:"learned"` // Samples reached the minimum
+	UpdatedAt      time.Time `bson:"updatedAt" json:"updatedAt,omitzero"`
+}
+
+// DurationEstimateID returns the ID of the estimate of repairType, for
+// mechanicID when it is set
+func DurationEstimateID(repairType, mechanicID string) string {
+	if mechanicID == "" {
+		return repairType
+	}
+	return mechanicID + "/" + repairType
+}
+
+// NewDurationEstimate averages minutes, the latest samples of a repair type
+// or of one mechanic's repairs of it
+func NewDurationEstimate(repairType, mechanicID string, minutes []float64, minSamples int, now time.Time) *DurationEstimate {
+	e := &DurationEstimate{
+		ID:             DurationEstimateID(repairType, mechanicID),
+		RepairType:     repairType,
+		MechanicID:     mechanicID,
+		CatalogMinutes: CatalogMinutes(repairType),
+		Samples:        len(minutes),
+		Learned:        len(minutes) > 0 && len(minutes) >= minSamples,
+		UpdatedAt:      now,
+	}
+	for _, m := range minutes {
+		e.AverageMinutes += m
+	}
+	if len(minutes) > 0 {
+		e.AverageMinutes = math.Round(e.AverageMinutes/float64(len(minutes))*10) / 10
+	}
+	return e
+}
+
+// Minutes returns the learned average once there are enough samples, the
+// catalog's estimate before
+func (e *DurationEstimate) Minutes() float64 {
+	if e.Learned {
+		return e.AverageMinutes
+	}
+	return e.CatalogMinutes
+}
+
+// DurationReport lists the learned durations against the catalog's (admin)
+type DurationReport struct {
+	Window      int                 `json:"window"`     // latest samples averaged
+	MinSamples  int                 `json:"minSamples"` // samples before an average replaces the catalog
+	RepairTypes []*DurationEstimate `json:"repairTypes"`
+	Mechanics   []*DurationEstimate `json:"mechanics"`
+}
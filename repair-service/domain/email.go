@@ -0,0 +1,182 @@
+package domain
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"net/mail"
+	"sort"
+	"text/template"
+	"time"
+)
+
+// Transactional email templates, one per message the service sends
+const (
+	EmailTemplateBookingConfirmation = "booking_confirmation" // repair created
+	EmailTemplateReceipt             = "receipt"              // repair completed
+	EmailTemplateCancellation        = "cancellation"         // repair cancelled
+)
+
+// Email delivery statuses
+const (
+	EmailPending   = "pending"    // queued, or waiting for a retry
+	EmailSent      = "sent"       // accepted by the provider
+	EmailFailed    = "failed"     // gave up after the maximum attempts
+	EmailOptedOut  = "opted_out"  // not sent, the user opted out
+	EmailNoAddress = "no_address" // not sent, the user has no email address
+)
+
+// EmailVariables are the variables each template can use, e.g. {{.RepairID}}
+var EmailVariables = map[string][]string{
+	EmailTemplateBookingConfirmation: {"UserID", "RepairID", "RepairType", "TotalPrice"},
+	EmailTemplateReceipt:             {"UserID", "RepairID", "RepairType", "TotalPrice", "ReceiptNumber", "Subtotal", "Total", "Currency", "MechanicName", "CompletedAt"},
+	EmailTemplateCancellation:        {"UserID", "RepairID", "RepairType", "TotalPrice"},
+}
+
+// EmailTemplate is the subject and body of one transactional email, in Go
+// template syntax over the template's EmailVariables. Admins manage them in
+// the email_templates collection; DefaultEmailTemplates apply until then.
+type EmailTemplate struct {
+	Name      string    `bson:"_id" json:"name"`
+	Subject   string    `bson:"subject" json:"subject"`
+	Text      string    `bson:"text" json:"text"`
+	HTML      string    `bson:"html,omitempty" json:"html,omitempty"`
+	Variables []string  `bson:"-" json:"variables"`
+	UpdatedAt time.Time `bson:"updatedAt,omitempty" json:"updatedAt,omitempty"`
+}
+
+// DefaultEmailTemplates are sent until admins save their own
+var DefaultEmailTemplates = map[string]EmailTemplate{
+	EmailTemplateBookingConfirmation: {
+		Name:    EmailTemplateBookingConfirmation,
+		Subject: "Your {{.RepairType}} repair is booked",
+		Text:    "We received your {{.RepairType}} repair request {{.RepairID}} at an estimated {{.TotalPrice}}. A mechanic is on the way soon.",
+	},
+	EmailTemplateReceipt: {
+		Name:    EmailTemplateReceipt,
+		Subject: "Receipt {{.ReceiptNumber}} for your {{.RepairType}} repair",
+		Text:    "Your {{.RepairType}} repair {{.RepairID}} was completed by {{.MechanicName}} on {{.CompletedAt}}.\n\nSubtotal: {{.Subtotal}} {{.Currency}}\nTotal: {{.Total}} {{.Currency}}\n\nReceipt number: {{.ReceiptNumber}}",
+	},
+	EmailTemplateCancellation: {
+		Name:    EmailTemplateCancellation,
+		Subject: "Your {{.RepairType}} repair was cancelled",
+		Text:    "Your {{.RepairType}} repair {{.RepairID}} was cancelled. You have not been charged.",
+	},
+}
+
+// Validate checks the template is known and renders with its variables, so
+// a typo in a variable name is rejected when saving rather than when sending
+func (t *EmailTemplate) Validate() error {
+	variables, ok := EmailVariables[t.Name]
+	if !ok {
+		names := make([]string, 0, len(EmailVariables))
+		for name := range EmailVariables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return fmt.Errorf("%w: unknown email template %q, expected one of %v", ErrInvalidInput, t.Name, names)
+	}
+	if t.Subject == "" || t.Text == "" {
+		return fmt.Errorf("%w: subject and text are required", ErrInvalidInput)
+	}
+	sample := make(map[string]string, len(variables))
+	for _, v := range variables {
+		sample[v] = v
+	}
+	if _, err := t.Render(sample); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	return nil
+}
+
+// RenderedEmail is a template rendered for one recipient
+type RenderedEmail struct {
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Render executes the template with vars; a variable missing from vars fails
+func (t *EmailTemplate) Render(vars map[string]string) (*RenderedEmail, error) {
+	rendered := &RenderedEmail{}
+	for _, part := range []struct {
+		name   string
+		source string
+		out    *string
+	}{
+		{"subject", t.Subject, &rendered.Subject},
+		{"text", t.Text, &rendered.Text},
+	} {
+		tmpl, err := template.New(part.name).Option("missingkey=error").Parse(part.source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s template: %v", part.name, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, vars); err != nil {
+			return nil, fmt.Errorf("failed to render %s: %v", part.name, err)
+		}
+		*part.out = buf.String()
+	}
+	if t.HTML != "" {
+		tmpl, err := htmltemplate.New("html").Option("missingkey=error").Parse(t.HTML)
+		if err != nil {
+			return nil, fmt.Errorf("invalid html template: %v", err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, vars); err != nil {
+			return nil, fmt.Errorf("failed to render html: %v", err)
+		}
+		rendered.HTML = buf.String()
+	}
+	return rendered, nil
+}
+
+// EmailPreferences are a user's email address and whether they opted out of
+// transactional email, one document per user in email_preferences
+type EmailPreferences struct {
+	UserID    string    `bson:"_id" json:"userID"`
+	Email     string    `bson:"email" json:"email"`
+	OptOut    bool      `bson:"optOut" json:"optOut"`
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Validate checks the address is a plain email address
+func (p *EmailPreferences) Validate() error {
+	if p.Email == "" {
+		return fmt.Errorf("%w: email is required", ErrInvalidInput)
+	}
+	addr, err := mail.ParseAddress(p.Email)
+	if err != nil || addr.Address != p.Email {
+		return fmt.Errorf("%w: invalid email address %q", ErrInvalidInput, p.Email)
+	}
+	return nil
+}
+
+// EmailDelivery is one transactional email and its delivery state, in
+// email_deliveries. It is queued in the transaction of the repair change
+// that causes it and sent by the email sender loop, rendered at queue time
+// so later template edits do not change it.
+type EmailDelivery struct {
+	ID            string     `bson:"_id" json:"id"`
+	Template      string     `bson:"template" json:"template"`
+	UserID        string     `bson:"userID" json:"userID"`
+	RepairID      string     `bson:"repairID" json:"repairID"`
+	To            string     `bson:"to,omitempty" json:"to,omitempty"`
+	Subject       string     `bson:"subject,omitempty" json:"subject,omitempty"`
+	Text          string     `bson:"text,omitempty" json:"text,omitempty"`
+	HTML          string     `bson:"html,omitempty" json:"html,omitempty"`
+	Status        string     `bson:"status" json:"status"`
+	Attempts      int        `bson:"attempts" json:"attempts"`
+	LastError     string     `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	Provider      string     `bson:"provider,omitempty" json:"provider,omitempty"`
+	CreatedAt     time.Time  `bson:"createdAt" json:"createdAt"`
+	NextAttemptAt *time.Time `bson:"nextAttemptAt,omitempty" json:"nextAttemptAt,omitempty"`
+	SentAt        *time.Time `bson:"sentAt,omitempty" json:"sentAt,omitempty"`
+}
+
+// EmailDeliveryFilter narrows email delivery listings; empty fields match all
+type EmailDeliveryFilter struct {
+	UserID   string
+	RepairID string
+	Status   string
+}
@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// FairnessPolicy spreads repairs among nearby mechanics instead of always
+// offering them to the nearest one. Mechanics within ETAWindowMinutes of the
+// user are ranked by a mix of their ETA and the assignments they got in the
+// last WindowDays, so the busiest of them rotate down the offer list. It is
+// set by admins and stored in dispatch_settings.
+type FairnessPolicy struct {
+	Enabled          bool      `bson:"enabled" json:"enabled"`
+	ETAWindowMinutes float64   `bson:"etaWindowMinutes" json:"etaWindowMinutes"`
+	Weight           float64   `bson:"weight" json:"weight"` // 0 ranks by ETA only, 1 by assignments only
+	WindowDays       int       `bson:"windowDays" json:"windowDays"`
+	UpdatedAt        time.Time `bson:"updatedAt" json:"updatedAt"`
+}
+
+// AssignmentCounts are the assignments each mechanic got in the fairness
+// policy's window, as seen by the rotation
+type AssignmentCounts struct {
+	WindowDays int            `json:"windowDays"`
+	Since      time.Time      `json:"since"`
+	Counts     map[string]int `json:"counts"` // by mechanic ID
+}
+
+// MaxFairnessWindowDays bounds WindowDays; older assignment counters expire
+const MaxFairnessWindowDays = 90
+
+// Validate checks the policy can rank mechanics
+func (p *FairnessPolicy) Validate() error {
+	switch {
+	case p.ETAWindowMinutes <= 0:
+		return fmt.Errorf("%w: etaWindowMinutes must be positive", ErrInvalidInput)
+	case p.Weight < 0 || p.Weight > 1:
+		return fmt.Errorf("%w: weight must be between 0 and 1", ErrInvalidInput)
+	case p.WindowDays < 1 || p.WindowDays > MaxFairnessWindowDays:
+		return fmt.Errorf("%w: windowDays must be between 1 and %d", ErrInvalidInput, MaxFairnessWindowDays)
+	}
+	return nil
+}
+
+// RankFairly reorders mechanics sorted by distance. Those whose ETA (from
+// etaMinutes) is within the policy window move to the front, ordered by
+// (1-Weight)*ETA/window + Weight*assignments/most assignments among them;
+// ties keep distance order. The rest follow by distance. assignments holds
+// each mechanic's assignments in the policy window.
+func RankFairly(mechanics []MechanicInfo, assignments map[string]int, policy FairnessPolicy, etaMinutes func(MechanicInfo) float64) []MechanicInfo {
+	if !policy.Enabled || policy.ETAWindowMinutes <= 0 {
+		return mechanics
+	}
+	var near, far []MechanicInfo
+	most := 0
+	for _, m := range mechanics {
+		if etaMinutes(m) > policy.ETAWindowMinutes {
+			far = append(far, m)
+			continue
+		}
+		near = append(near, m)
+		if assignments[m.ID] > most {
+			most = assignments[m.ID]
+		}
+	}
+
+	score := func(m MechanicInfo) float64 {
+		s := (1 - policy.Weight) * etaMinutes(m) / policy.ETAWindowMinutes
+		if most > 0 {
+			s += policy.Weight * float64(assignments[m.ID]) / float64(most)
+		}
+		return s
+	}
+	sort.SliceStable(near, func(i, j int) bool {
+		return score(near[i]) < score(near[j])
+	})
+	return append(near, far...)
+}
@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"errors"
+	"slices"
+	"time"
+)
+
+// ErrSpendingLimit marks estimates and repairs that would take a fleet member
+// over their monthly spending limit; handlers map it to 403
+var ErrSpendingLimit = errors.New("monthly spending limit exceeded")
+
+// ErrRepairTypeNotAllowed marks estimates and repairs of a repair type the
+// member's fleet does not allow them; handlers map it to 403
+var ErrRepairTypeNotAllowed = errors.New("repair type not allowed by fleet")
+
+// FleetAccount groups users whose repairs are paid by one account, e.g. a
+// company's drivers or a family. Its admins set each member's monthly
+// spending limit and the repair types they may book. A user belongs to at
+// most one fleet.
+type FleetAccount struct {
+	ID        string        `bson:"_id" json:"id"`
+	Name      string        `bson:"name" json:"name"`
+	Admins    []string      `bson:"admins" json:"admins"` // user IDs managing the fleet
+	Members   []FleetMember `bson:"members" json:"members"`
+	CreatedAt time.Time     `bson:"createdAt" json:"createdAt"`
+	UpdatedAt time.Time     `bson:"updatedAt" json:"updatedAt"`
+}
+
+// FleetMember is a user of a fleet and the controls on their repairs
+type FleetMember struct {
+	UserID             string   `bson:"userID" json:"userID"`
+	MonthlyLimit       *Money   `bson:"monthlyLimit,omitempty" json:"monthlyLimit,omitempty"`             // none when nil
+	AllowedRepairTypes []string `bson:"allowedRepairTypes,omitempty" json:"allowedRepairTypes,omitempty"` // every type when empty
+}
+
+// FleetAccountRequest creates a fleet account
+type FleetAccountRequest struct {
+	Name   string   `json:"name"`
+	Admins []string `json:"admins"`
+}
+
+// Member returns the fleet's member userID, or nil
+func (f *FleetAccount) Member(userID string) *FleetMember {
+	for i := range f.Members {
+		if f.Members[i].UserID == userID {
+			return &f.Members[i]
+		}
+	}
+	return nil
+}
+
+// CanManage reports whether actor may change the fleet's members and see
+// their usage: platform admins and the fleet's admins
+func (f *FleetAccount) CanManage(actor Actor) bool {
+	return actor.Role == ActorAdmin || (actor.Role == ActorUser && actor.ID != "" && slices.Contains(f.Admins, actor.ID))
+}
+
+// Allows reports whether the member may book repairType
+func (m *FleetMember) Allows(repairType string) bool {
+	return len(m.AllowedRepairTypes) == 0 || slices.Contains(m.AllowedRepairTypes, repairType)
+}
+
+// MemberSpending is what a user spent on repairs since a point in time
+type MemberSpending struct {
+	Spent       Money `bson:"spent"`
+	RepairCount int   `bson:"repairCount"`
+}
+
+// FleetMemberUsage is a member's spending in a month against their limit
+type FleetMemberUsage struct {
+	UserID             string   `json:"userID"`
+	Spent              Money    `json:"spent"`
+	RepairCount        int      `json:"repairCount"`
+	MonthlyLimit       *Money   `json:"monthlyLimit,omitempty"`
+	Remaining          *Money   `json:"remaining,omitempty"` // 0 once the limit is reached
+	PercentUsed        float64  `json:"percentUsed,omitempty"`
+	AllowedRepairTypes []string `json:"allowedRepairTypes,omitempty"`
+}
+
+// FleetUsage is the spending of a fleet's members in a month
+type FleetUsage struct {
+	FleetID string             `json:"fleetID"`
+	Month   string             `json:"month"` // YYYY-MM in the pricing time zone
+	Total   Money              `json:"total"`
+	Members []FleetMemberUsage `json:"members"`
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// EventRepairAssigned is the assignment_outbox event type mechanic-service
+// writes when a mechanic claims a repair
+const EventRepairAssigned = "repair_assigned"
+
+// RepairAssignment is the JSON payload of a repair_assigned event, as far as
+// repair history needs it
+type RepairAssignment struct {
+	MechanicID string    `json:"mechanicID"`
+	AssignedAt time.Time `json:"assignedAt"`
+}
+
+// RepairHistory is what a repair's past states are rebuilt from. No separate
+// audit trail is kept: every change of a repair's status or price writes a
+// repair_outbox event in the same transaction, and processed events are kept,
+// as are mechanic-service's repair_assigned events. Both lists are oldest
+// first.
+type RepairHistory struct {
+	Events      []*OutboxEvent // the repair's RepairCreated, RepairUpdated and RepairCancelled events, redrive clones left out
+	Assignments []*RepairAssignment
+	Amendments  []*Amendment
+}
+
+// RepairSnapshot is a repair's state at a past moment, answered by
+// GET /repairs/{repairID}?asOf= for dispute resolution and support
+type RepairSnapshot struct {
+	ID         string     `json:"id"`
+	AsOf       time.Time  `json:"asOf"`
+	UserID     string     `json:"userID"`
+	Status     string     `json:"status"` // RepairStatusDeleted once the repair was deleted
+	RepairType string     `json:"repairType"`
+	AssignedTo string     `json:"assignedTo,omitempty"`
+	AssignedAt *time.Time `json:"assignedAt,omitempty"`
+	// QuotedPrice is the price the repair was booked at; TotalPrice adds the
+	// amendments approved by AsOf
+	QuotedPrice Money `json:"quotedPrice"`
+	TotalPrice  Money `json:"totalPrice"`
+	// ChangedAt is when the repair last changed by AsOf, its status or price
+	ChangedAt time.Time `json:"changedAt"`
+	// Events counts the repair events replayed; events that no longer
+	// decode, written with an older schema, are skipped
+	Events int `json:"events"`
+}
@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// EventFleetInvoiceIssued is the outbox event type published to the billing
+// topic when a fleet invoice is issued; its payload is the invoice as JSON
+const EventFleetInvoiceIssued = "FleetInvoiceIssued"
+
+// ErrInvoiceMonthOpen marks invoice requests for a month that has not ended
+// yet; handlers map it to 409
+var ErrInvoiceMonthOpen = errors.New("the month has not ended yet")
+
+// ErrInvoiceIssued marks saves of a fleet invoice that was issued already,
+// e.g. by another instance running the invoice job
+var ErrInvoiceIssued = errors.New("fleet invoice already issued")
+
+// FleetInvoiceLine bills one completed repair of a fleet member, taken from
+// the repair's receipt
+type FleetInvoiceLine struct {
+	RepairID      string    `bson:"repairID" json:"repairID"`
+	ReceiptNumber string    `bson:"receiptNumber" json:"receiptNumber"`
+	UserID        string    `bson:"userID" json:"userID"`
+	RepairType    string    `bson:"repairType" json:"repairType"`
+	CompletedAt   time.Time `bson:"completedAt" json:"completedAt"`
+	Subtotal      Money     `bson:"subtotal" json:"subtotal"`
+	Tax           Money     `bson:"tax" json:"tax"`
+	Total         Money     `bson:"total" json:"total"`
+}
+
+// FleetInvoice aggregates the receipts of the repairs a fleet's members
+// completed in a calendar month of the pricing time zone. It is issued once,
+// after the month ends, to the fleet_invoices collection and never recomputed.
+// Members are those of the fleet when the invoice is issued.
+type FleetInvoice struct {
+	ID          string             `bson:"_id" json:"id"` // <fleetID>-<YYYY-MM>
+	Number      string             `bson:"number" json:"number"`
+	FleetID     string             `bson:"fleetID" json:"fleetID"`
+	FleetName   string             `bson:"fleetName" json:"fleetName"`
+	Month       string             `bson:"month" json:"month"` // YYYY-MM
+	PeriodStart time.Time          `bson:"periodStart" json:"periodStart"`
+	PeriodEnd   time.Time          `bson:"periodEnd" json:"periodEnd"` // exclusive
+	Currency    string             `bson:"currency" json:"currency"`
+	LineItems   []FleetInvoiceLine `bson:"lineItems" json:"lineItems"`
+	RepairCount int                `bson:"repairCount" json:"repairCount"`
+	Subtotal    Money              `bson:"subtotal" json:"subtotal"`
+	Taxes       []ReceiptTax       `bson:"taxes" json:"taxes"` // summed per tax name and rate
+	Total       Money              `bson:"total" json:"total"`
+	IssuedAt    time.Time          `bson:"issuedAt" json:"issuedAt"`
+}
+
+// FleetInvoiceID returns the ID of a fleet's invoice for month (YYYY-MM)
+func FleetInvoiceID(fleetID, month string) string {
+	return fleetID + "-" + month
+}
+
+// AddTax adds a receipt's tax to the invoice's tax of the same name and rate
+func (i *FleetInvoice) AddTax(tax ReceiptTax) {
+	for j := range i.Taxes {
+		if i.Taxes[j].Name == tax.Name && i.Taxes[j].RatePercent == tax.RatePercent {
+			i.Taxes[j].Amount += tax.Amount
+			return
+		}
+	}
+	i.Taxes = append(i.Taxes, tax)
+}
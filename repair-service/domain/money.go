@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+// MinorUnitsPerMajor is the number of minor currency units (cents) in one major unit
+const MinorUnitsPerMajor = 100
+
+// Money is an amount in minor currency units, so prices add and compare
+// exactly. It crosses API boundaries (JSON, BSON, Avro, gRPC) as a major unit
+// number with at most two decimals, e.g. 49.99, for compatibility with
+// existing clients and stored documents.
+type Money int64
+
+// MoneyFromMajor converts a major unit amount to Money, rounding half away
+// from zero to the nearest minor unit (49.995 becomes 50.00)
+func MoneyFromMajor(amount float64) Money {
+	return Money(math.Round(amount * MinorUnitsPerMajor))
+}
+
+// Minor returns the amount in minor units
+func (m Money) Minor() int64 {
+	return int64(m)
+}
+
+// Major returns the amount in major units
+func (m Money) Major() float64 {
+	return float64(m) / MinorUnitsPerMajor
+}
+
+// String formats the amount in major units with two decimals
+func (m Money) String() string {
+	return strconv.FormatFloat(m.Major(), 'f', 2, 64)
+}
+
+// MarshalJSON encodes the amount as a major unit number
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.FormatFloat(m.Major(), 'f', -1, 64)), nil
+}
+
+// UnmarshalJSON decodes a major unit number, rounding it to minor units
+func (m *Money) UnmarshalJSON(data []byte) error {
+	var amount float64
+	if err := json.Unmarshal(data, &amount); err != nil {
+		return fmt.Errorf("invalid amount %s: %w", data, err)
+	}
+	*m = MoneyFromMajor(amount)
+	return nil
+}
+
+// MarshalBSONValue stores the amount as a major unit double, as documents
+// written before Money existed do
+func (m Money) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	return bson.MarshalValue(m.Major())
+}
+
+// UnmarshalBSONValue reads a major unit number, rounding it to minor units
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	value := bson.RawValue{Type: t, Value: data}
+	switch t {
+	case bson.TypeDouble:
+		*m = MoneyFromMajor(value.Double())
+	case bson.TypeInt32:
+		*m = Money(int64(value.Int32()) * MinorUnitsPerMajor)
+	case bson.TypeInt64:
+		*m = Money(value.Int64() * MinorUnitsPerMajor)
+	case bson.TypeNull:
+		*m = 0
+	default:
+		return fmt.Errorf("cannot decode %s into Money", t)
+	}
+	return nil
+}
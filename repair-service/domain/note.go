@@ -0,0 +1,17 @@
+package domain
+
+import "time"
+
+// MaxTagLength is the longest tag accepted on a repair
+const MaxTagLength = 50
+
+// RepairNote is an internal, staff-only note on a repair. Notes live in the
+// repair_notes collection rather than on the repair document, so they never
+// reach user-facing repair responses, events or streams.
+type RepairNote struct {
+	ID        string    `bson:"_id,omitempty" json:"id"`
+	RepairID  string    `bson:"repairID" json:"repairID"`
+	Author    string    `bson:"author" json:"author"`
+	Body      string    `bson:"body" json:"body"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Outbox redrive modes
+const (
+	RedriveReset = "reset" // mark the processed events unprocessed so they are published again
+	RedriveClone = "clone" // queue unprocessed copies, keeping the originals as they are
+)
+
+// MaxRedriveEvents caps the events one redrive may select, so a mistyped time
+// range cannot replay the whole outbox
+const MaxRedriveEvents = 5000
+
+// OutboxRedriveRequest selects processed outbox events to publish again after
+// a downstream consumer bug, either by ID or by created_at range, optionally
+// narrowed to one event type
+type OutboxRedriveRequest struct {
+	EventIDs    []string   `json:"eventIDs,omitempty"`
+	From        *time.Time `json:"from,omitempty"`
+	To          *time.Time `json:"to,omitempty"`
+	EventType   string     `json:"eventType,omitempty"`
+	Mode        string     `json:"mode,omitempty"` // reset (default) or clone
+	RequestedBy string     `json:"requestedBy"`
+}
+
+// Validate checks the selection and fills in the default mode
+func (r *OutboxRedriveRequest) Validate() error {
+	if r.RequestedBy == "" {
+		return fmt.Errorf("%w: requestedBy is required", ErrInvalidInput)
+	}
+	if r.Mode == "" {
+		r.Mode = RedriveReset
+	}
+	if r.Mode != RedriveReset && r.Mode != RedriveClone {
+		return fmt.Errorf("%w: mode must be %s or %s", ErrInvalidInput, RedriveReset, RedriveClone)
+	}
+	byRange := r.From != nil || r.To != nil
+	if len(r.EventIDs) == 0 && !byRange {
+		return fmt.Errorf("%w: eventIDs or from and to are required", ErrInvalidInput)
+	}
+	if len(r.EventIDs) > 0 && byRange {
+		return fmt.Errorf("%w: select events by eventIDs or by time range, not both", ErrInvalidInput)
+	}
+	if byRange && (r.From == nil || r.To == nil || !r.To.After(*r.From)) {
+		return fmt.Errorf("%w: to must be after from", ErrInvalidInput)
+	}
+	if len(r.EventIDs) > MaxRedriveEvents {
+		return fmt.Errorf("%w: at most %d events can be redriven at once", ErrInvalidInput, MaxRedriveEvents)
+	}
+	return nil
+}
+
+// Filter is the Mongo filter of the processed outbox events the request selects
+func (r *OutboxRedriveRequest) Filter() bson.M {
+	filter := bson.M{"processed": true}
+	if len(r.EventIDs) > 0 {
+		filter["_id"] = bson.M{"$in": r.EventIDs}
+	} else {
+		filter["created_at"] = bson.M{"$gte": *r.From, "$lt": *r.To}
+	}
+	if r.EventType != "" {
+		filter["event_type"] = r.EventType
+	}
+	return filter
+}
+
+// OutboxRedrive is the audit record of a redrive, stored in outbox_redrives
+type OutboxRedrive struct {
+	ID          string     `bson:"_id" json:"id"`
+	Mode        string     `bson:"mode" json:"mode"`
+	RequestedBy string     `bson:"requestedBy" json:"requestedBy"`
+	From        *time.Time `bson:"from,omitempty" json:"from,omitempty"`
+	To          *time.Time `bson:"to,omitempty" json:"to,omitempty"`
+	EventType   string     `bson:"eventType,omitempty" json:"eventType,omitempty"`
+	EventIDs    []string   `bson:"eventIDs" json:"eventIDs"`                     // processed events that were redriven
+	CloneIDs    []string   `bson:"cloneIDs,omitempty" json:"cloneIDs,omitempty"` // copies queued in clone mode
+	RequestedAt time.Time  `bson:"requestedAt" json:"requestedAt"`
+}
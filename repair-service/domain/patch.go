@@ -0,0 +1,195 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrForbidden marks changes the caller is not allowed to make; handlers map it to 403
+var ErrForbidden = errors.New("forbidden")
+
+// Repair priorities; repairs without one are normal
+const (
+	PriorityLow    = "low"
+	PriorityNormal = "normal"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
+// Roles of the callers patching a repair
+const (
+	ActorUser     = "user"     // the repair's user
+	ActorMechanic = "mechanic" // the mechanic assigned to the repair
+	ActorAdmin    = "admin"
+)
+
+// MaxRepairNotesLength bounds the notes on a repair, in characters
+const MaxRepairNotesLength = 1000
+
+// Actor is who patches a repair, as passed on by the gateway
+type Actor struct {
+	Role string
+	ID   string // user or mechanic ID; empty for admins
+}
+
+// RepairPatch is a JSON merge patch (RFC 7396) of a repair's mutable fields:
+// status, scheduledAt, notes and priority. Absent fields are left alone and
+// fields set to null are removed; status cannot be removed.
+type RepairPatch struct {
+	Status      *string
+	ScheduledAt *time.Time
+	Notes       *string
+	Priority    *string
+	Remove      []string // fields set to null
+}
+
+// repairPatchFields are the fields a RepairPatch may change
+var repairPatchFields = []string{"status", "scheduledAt", "notes", "priority"}
+
+// ParseRepairPatch decodes a merge patch, rejecting fields that are unknown
+// or not mutable
+func ParseRepairPatch(data []byte) (*RepairPatch, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		return nil, fmt.Errorf("%w: a merge patch must be a JSON object", ErrInvalidInput)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%w: the patch changes nothing", ErrInvalidInput)
+	}
+	patch := &RepairPatch{}
+	for name, raw := range fields {
+		if bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+			if name == "status" {
+				return nil, fmt.Errorf("%w: status cannot be removed", ErrInvalidInput)
+			}
+			if !isRepairPatchField(name) {
+				return nil, fmt.Errorf("%w: %s cannot be patched, only %v", ErrInvalidInput, name, repairPatchFields)
+			}
+			patch.Remove = append(patch.Remove, name)
+			continue
+		}
+		var target any
+		switch name {
+		case "status":
+			target = &patch.Status
+		case "scheduledAt":
+			target = &patch.ScheduledAt
+		case "notes":
+			target = &patch.Notes
+		case "priority":
+			target = &patch.Priority
+		default:
+			return nil, fmt.Errorf("%w: %s cannot be patched, only %v", ErrInvalidInput, name, repairPatchFields)
+		}
+		if err := json.Unmarshal(raw, target); err != nil {
+			return nil, fmt.Errorf("%w: invalid %s: %v", ErrInvalidInput, name, err)
+		}
+	}
+	return patch, nil
+}
+
+func isRepairPatchField(name string) bool {
+	for _, f := range repairPatchFields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Fields returns the names of the fields the patch sets or removes
+func (p *RepairPatch) Fields() []string {
+	var fields []string
+	for _, f := range []struct {
+		name string
+		set  bool
+	}{
+		{"status", p.Status != nil},
+		{"scheduledAt", p.ScheduledAt != nil},
+		{"notes", p.Notes != nil},
+		{"priority", p.Priority != nil},
+	} {
+		if f.set {
+			fields = append(fields, f.name)
+		}
+	}
+	return append(fields, p.Remove...)
+}
+
+// Validate checks the values the patch sets; the status transition itself is
+// checked by the status update
+func (p *RepairPatch) Validate(now time.Time) error {
+	if p.ScheduledAt != nil && !p.ScheduledAt.After(now) {
+		return fmt.Errorf("%w: scheduledAt must be in the future", ErrInvalidInput)
+	}
+	if p.Notes != nil && utf8.RuneCountInString(*p.Notes) > MaxRepairNotesLength {
+		return fmt.Errorf("%w: notes are limited to %d characters", ErrInvalidInput, MaxRepairNotesLength)
+	}
+	if p.Priority != nil {
+		switch *p.Priority {
+		case PriorityLow, PriorityNormal, PriorityHigh, PriorityUrgent:
+		default:
+			return fmt.Errorf("%w: invalid priority %q, expected low, normal, high or urgent", ErrInvalidInput, *p.Priority)
+		}
+	}
+	return nil
+}
+
+// Authorize checks the actor may change every field of the patch on repair:
+//   - status: admins and the assigned mechanic; the user may only cancel
+//   - scheduledAt: admins and the user, while the repair is pending
+//   - notes: admins, the user and the assigned mechanic
+//   - priority: admins
+func (p *RepairPatch) Authorize(actor Actor, repair *RepairModel) error {
+	if actor.Role == ActorAdmin {
+		return nil
+	}
+	isUser := actor.Role == ActorUser && actor.ID != "" && actor.ID == repair.UserID
+	isMechanic := actor.Role == ActorMechanic && actor.ID != "" && actor.ID == repair.AssignedTo
+	if !isUser && !isMechanic {
+		return fmt.Errorf("%w: only the repair's user, its assigned mechanic or an admin may patch it", ErrForbidden)
+	}
+	for _, field := range p.Fields() {
+		allowed := false
+		switch field {
+		case "status":
+			allowed = isMechanic || (isUser && *p.Status == "cancelled")
+		case "scheduledAt":
+			allowed = isUser && repair.Status == "pending"
+		case "notes":
+			allowed = true
+		}
+		if !allowed {
+			return fmt.Errorf("%w: a %s may not change %s of this repair", ErrForbidden, actor.Role, field)
+		}
+	}
+	return nil
+}
+
+// ApplyTo applies the patch's fields, status excluded, to repair
+func (p *RepairPatch) ApplyTo(repair *RepairModel) {
+	if p.ScheduledAt != nil {
+		at := p.ScheduledAt.UTC()
+		repair.ScheduledAt = &at
+	}
+	if p.Notes != nil {
+		repair.Notes = *p.Notes
+	}
+	if p.Priority != nil {
+		repair.Priority = *p.Priority
+	}
+	for _, field := range p.Remove {
+		switch field {
+		case "scheduledAt":
+			repair.ScheduledAt = nil
+		case "notes":
+			repair.Notes = ""
+		case "priority":
+			repair.Priority = ""
+		}
+	}
+}
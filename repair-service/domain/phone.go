@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrPhoneNotVerified marks repair requests from users without a verified
+// phone number; handlers map it to 403
+var ErrPhoneNotVerified = errors.New("phone number must be verified first")
+
+// ErrRateLimited marks verification codes requested or tried too often;
+// handlers map it to 429
+var ErrRateLimited = errors.New("too many verification requests")
+
+// PhoneVerification is a user's phone number and its one-time code state, one
+// document per user in the phone_verifications collection. Only a hash of the
+// current code is stored.
+type PhoneVerification struct {
+	UserID        string      `bson:"_id" json:"userID"`
+	Phone         string      `bson:"phone" json:"phone"` // E.164
+	Verified      bool        `bson:"verified" json:"verified"`
+	VerifiedAt    *time.Time  `bson:"verifiedAt,omitempty" json:"verifiedAt,omitempty"`
+	CodeHash      string      `bson:"codeHash,omitempty" json:"-"`
+	CodeExpiresAt *time.Time  `bson:"codeExpiresAt,omitempty" json:"codeExpiresAt,omitempty"`
+	Attempts      int         `bson:"attempts" json:"-"` // wrong codes entered for the current code
+	SentAt        []time.Time `bson:"sentAt" json:"-"`   // recent code sends, for rate limiting
+	UpdatedAt     time.Time   `bson:"updatedAt" json:"updatedAt"`
+}
@@ -0,0 +1,24 @@
+package domain
+
+import "time"
+
+// DemandCell is a geohash cell ranked by how many repairs were requested in it
+type DemandCell struct {
+	Geohash        string  `json:"geohash"`
+	Demand         int     `json:"demand"`    // repairs requested in this hour of day over the lookback
+	Latitude       float64 `json:"latitude"`  // centroid of those repairs
+	Longitude      float64 `json:"longitude"` // centroid of those repairs
+	DistanceMeters float64 `json:"distanceMeters"`
+}
+
+// PositioningHints suggests where an idle mechanic should wait for work: the
+// busiest demand cells within the mechanic's service area for the current hour
+type PositioningHints struct {
+	MechanicID   string       `json:"mechanicID"`
+	Idle         bool         `json:"idle"` // online with no active assignment
+	Hour         int          `json:"hour"` // hour of day (UTC) the demand was aggregated for
+	LookbackDays int          `json:"lookbackDays"`
+	RadiusMeters float64      `json:"radiusMeters"`
+	Cells        []DemandCell `json:"cells"`
+	GeneratedAt  time.Time    `json:"generatedAt"`
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrPreferredMechanicUnavailable is returned when the user's preferred
+// mechanic cannot take the repair and the preference allows no fallback
+var ErrPreferredMechanicUnavailable = errors.New("preferred mechanic is not available for this repair")
+
+// What happens when the preferred mechanic cannot take a repair, and to a
+// repair pinned to them that they have not claimed
+const (
+	// PreferenceFallbackNearby offers the repair to the nearby mechanics as
+	// usual, and to all of them once the pin's hold runs out
+	PreferenceFallbackNearby = "nearby"
+	// PreferenceFallbackNone refuses the estimate or repair, and keeps a
+	// pinned repair for the preferred mechanic alone
+	PreferenceFallbackNone = "none"
+)
+
+// Why a preference was not honored
+const (
+	PreferenceBlocked      = "blocked"      // the user blocked the mechanic
+	PreferenceAbsent       = "absent"       // the mechanic is on leave
+	PreferenceNotQualified = "not_in_range" // not qualified for the repair type or too far away
+	PreferenceNotOffered   = "not_offered"  // not among the mechanics of the estimate the repair is created from
+)
+
+// MechanicPreference is a user's wish to have a repair done by a mechanic
+// they know. When the mechanic is qualified and in range the estimate offers
+// only them and the repair is pinned to them: mechanic-service shows it to
+// and lets it be claimed by no one else until HoldUntil, or ever with the
+// none fallback.
+type MechanicPreference struct {
+	MechanicID string     `bson:"mechanicID" json:"mechanicID"`
+	Fallback   string     `bson:"fallback" json:"fallback"` // see the PreferenceFallback constants
+	Pinned     bool       `bson:"pinned" json:"pinned"`
+	Reason     string     `bson:"reason,omitempty" json:"reason,omitempty"`       // why it is not pinned
+	HoldUntil  *time.Time `bson:"holdUntil,omitempty" json:"holdUntil,omitempty"` // set on repairs pinned with the nearby fallback
+}
+
+// Validate checks the mechanic and fallback, defaulting the fallback to
+// defaultFallback
+func (p *MechanicPreference) Validate(defaultFallback string) error {
+	if p.MechanicID == "" {
+		return fmt.Errorf("%w: preferredMechanicID is required with a fallback", ErrInvalidInput)
+	}
+	if p.Fallback == "" {
+		p.Fallback = defaultFallback
+	}
+	if p.Fallback != PreferenceFallbackNearby && p.Fallback != PreferenceFallbackNone {
+		return fmt.Errorf("%w: preferredMechanicFallback must be %s or %s", ErrInvalidInput, PreferenceFallbackNearby, PreferenceFallbackNone)
+	}
+	return nil
+}
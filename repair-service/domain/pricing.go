@@ -0,0 +1,235 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+// ErrUnknownRepairType is returned when neither DefaultBasePrices nor a set
+// rule prices the repair type
+var ErrUnknownRepairType = errors.New("unknown repair type")
+
+// DefaultBasePrices are the base prices per repair type before pricing rules
+// run; a matching set rule replaces them
+var DefaultBasePrices = map[string]Money{
+	"flat_tire":         5000,
+	"brake_repair":      15000,
+	"chain_replacement": 8000,
+}
+
+// Pricing modifier types
+const (
+	ModifierSet      = "set"      // replace the price with Amount
+	ModifierMultiply = "multiply" // multiply the price by Factor
+	ModifierAdd      = "add"      // add Amount, which may be negative
+)
+
+// PricingModifier changes the price when a rule matches
+type PricingModifier struct {
+	Type   string  `bson:"type" json:"type"`
+	Factor float64 `bson:"factor,omitempty" json:"factor,omitempty"`
+	Amount Money   `bson:"amount,omitempty" json:"amount,omitempty"`
+}
+
+// HourRange is a range of local hours [From, To), wrapping past midnight when
+// From is greater than To (22 to 6 is the night)
+type HourRange struct {
+	From int `bson:"from" json:"from"`
+	To   int `bson:"to" json:"to"`
+}
+
+func (h HourRange) contains(hour int) bool {
+	if h.From < h.To {
+		return hour >= h.From && hour < h.To
+	}
+	return hour >= h.From || hour < h.To
+}
+
+// PricingZone is the bounding box a zone rule applies in
+type PricingZone struct {
+	Name         string  `bson:"name,omitempty" json:"name,omitempty"`
+	MinLongitude float64 `bson:"minLongitude" json:"minLongitude"`
+	MinLatitude  float64 `bson:"minLatitude" json:"minLatitude"`
+	MaxLongitude float64 `bson:"maxLongitude" json:"maxLongitude"`
+	MaxLatitude  float64 `bson:"maxLatitude" json:"maxLatitude"`
+}
+
+func (z PricingZone) contains(loc *Location) bool {
+	return loc != nil &&
+		loc.Longitude >= z.MinLongitude && loc.Longitude <= z.MaxLongitude &&
+		loc.Latitude >= z.MinLatitude && loc.Latitude <= z.MaxLatitude
+}
+
+// PricingConditions must all hold for a rule to match; unset conditions
+// always hold
+type PricingConditions struct {
+	RepairTypes []string     `bson:"repairTypes,omitempty" json:"repairTypes,omitempty"`
+	Hours       *HourRange   `bson:"hours,omitempty" json:"hours,omitempty"`
+	Weekend     *bool        `bson:"weekend,omitempty" json:"weekend,omitempty"`
+	Weather     []string     `bson:"weather,omitempty" json:"weather,omitempty"` // any of these weather flags
+	Zone        *PricingZone `bson:"zone,omitempty" json:"zone,omitempty"`
+}
+
+// PricingRule is an admin-managed rule in the pricing_rules collection. Rules
+// run in ascending priority, each matching rule modifying the price left by
+// the previous ones.
+type PricingRule struct {
+	ID         string            `bson:"_id" json:"id"`
+	Name       string            `bson:"name" json:"name"`
+	Priority   int               `bson:"priority" json:"priority"`
+	Enabled    bool              `bson:"enabled" json:"enabled"`
+	Conditions PricingConditions `bson:"conditions" json:"conditions"`
+	Modifier   PricingModifier   `bson:"modifier" json:"modifier"`
+	UpdatedAt  time.Time         `bson:"updatedAt" json:"updatedAt"`
+}
+
+// Validate checks the rule's conditions and modifier
+func (r *PricingRule) Validate() error {
+	if r.ID == "" || r.Name == "" {
+		return fmt.Errorf("%w: rule ID and name are required", ErrInvalidInput)
+	}
+	c := r.Conditions
+	if c.Hours != nil && (c.Hours.From < 0 || c.Hours.From > 23 || c.Hours.To < 0 || c.Hours.To > 23 || c.Hours.From == c.Hours.To) {
+		return fmt.Errorf("%w: hours must be two different hours from 0 to 23", ErrInvalidInput)
+	}
+	if c.Zone != nil && (c.Zone.MinLongitude > c.Zone.MaxLongitude || c.Zone.MinLatitude > c.Zone.MaxLatitude) {
+		return fmt.Errorf("%w: zone minimums must not exceed maximums", ErrInvalidInput)
+	}
+	switch r.Modifier.Type {
+	case ModifierSet:
+		if r.Modifier.Amount <= 0 {
+			return fmt.Errorf("%w: set modifier needs a positive amount", ErrInvalidInput)
+		}
+	case ModifierMultiply:
+		if r.Modifier.Factor <= 0 {
+			return fmt.Errorf("%w: multiply modifier needs a positive factor", ErrInvalidInput)
+		}
+	case ModifierAdd:
+		if r.Modifier.Amount == 0 {
+			return fmt.Errorf("%w: add modifier needs a non-zero amount", ErrInvalidInput)
+		}
+	default:
+		return fmt.Errorf("%w: modifier type must be %s, %s or %s", ErrInvalidInput, ModifierSet, ModifierMultiply, ModifierAdd)
+	}
+	return nil
+}
+
+// PricingContext is what the rules are evaluated against. At should be in the
+// pricing timezone, since hours and weekends are local.
+type PricingContext struct {
+	RepairType string    `json:"repairType"`
+	Location   *Location `json:"location,omitempty"`
+	At         time.Time `json:"at"`
+	Weather    string    `json:"weather,omitempty"`
+}
+
+// RuleTrace explains what one enabled rule did during an evaluation
+type RuleTrace struct {
+	RuleID      string `json:"ruleID"`
+	Name        string `json:"name"`
+	Matched     bool   `json:"matched"`
+	Reason      string `json:"reason,omitempty"` // the condition that did not hold
+	PriceBefore Money  `json:"priceBefore"`
+	PriceAfter  Money  `json:"priceAfter"`
+}
+
+// PricingResult is the price an evaluation produced with the trace of every
+// enabled rule, returned with estimates for debugging
+type PricingResult struct {
+	BasePrice Money          `json:"basePrice"`
+	Price     Money          `json:"price"`
+	Context   PricingContext `json:"context"`
+	Trace     []RuleTrace    `json:"trace"`
+}
+
+// EvaluatePricing runs the enabled rules against pc, starting from the
+// repair type's default base price
+func EvaluatePricing(rules []*PricingRule, pc PricingContext) (*PricingResult, error) {
+	sorted := make([]*PricingRule, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Enabled {
+			sorted = append(sorted, rule)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Priority != sorted[j].Priority {
+			return sorted[i].Priority < sorted[j].Priority
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	base, priced := DefaultBasePrices[pc.RepairType]
+	result := &PricingResult{BasePrice: base, Context: pc, Trace: []RuleTrace{}}
+	price := base
+	for _, rule := range sorted {
+		entry := RuleTrace{RuleID: rule.ID, Name: rule.Name, PriceBefore: price, PriceAfter: price}
+		if reason := rule.Conditions.mismatch(pc); reason != "" {
+			entry.Reason = reason
+			result.Trace = append(result.Trace, entry)
+			continue
+		}
+		entry.Matched = true
+		switch rule.Modifier.Type {
+		case ModifierSet:
+			price = rule.Modifier.Amount
+			priced = true
+		case ModifierMultiply:
+			price = Money(math.Round(float64(price) * rule.Modifier.Factor))
+		case ModifierAdd:
+			price += rule.Modifier.Amount
+		}
+		entry.PriceAfter = price
+		result.Trace = append(result.Trace, entry)
+	}
+	result.Price = price
+
+	if !priced {
+		return result, ErrUnknownRepairType
+	}
+	if price <= 0 {
+		return result, fmt.Errorf("pricing rules produced a non-positive price %s", price)
+	}
+	return result, nil
+}
+
+// mismatch returns the first condition pc does not meet, or "" when all hold
+func (c PricingConditions) mismatch(pc PricingContext) string {
+	if len(c.RepairTypes) > 0 && !listed(c.RepairTypes, pc.RepairType) {
+		return fmt.Sprintf("repair type %q not listed", pc.RepairType)
+	}
+	if c.Hours != nil && !c.Hours.contains(pc.At.Hour()) {
+		return fmt.Sprintf("hour %d outside %d-%d", pc.At.Hour(), c.Hours.From, c.Hours.To)
+	}
+	if c.Weekend != nil {
+		weekend := pc.At.Weekday() == time.Saturday || pc.At.Weekday() == time.Sunday
+		if weekend != *c.Weekend {
+			return fmt.Sprintf("weekend is %t", weekend)
+		}
+	}
+	if len(c.Weather) > 0 && !listed(c.Weather, pc.Weather) {
+		return fmt.Sprintf("weather %q not listed", pc.Weather)
+	}
+	if c.Zone != nil && !c.Zone.contains(pc.Location) {
+		return fmt.Sprintf("location outside zone %q", c.Zone.Name)
+	}
+	return ""
+}
+
+func listed(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// WeatherFlag is the current weather condition pricing rules can match on,
+// set by admins and stored in pricing_settings
+type WeatherFlag struct {
+	Flag      string    `bson:"flag" json:"flag"` // e.g. rain or snow; empty clears it
+	UpdatedAt time.Time `bson:"updatedAt" json:"updatedAt"`
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// EventRepairErased is the outbox event type of the Kafka tombstone written
+// for each repair of an erased user. Its payload is empty, so the record is
+// published with a null value keyed by the repair ID.
+const EventRepairErased = "RepairErased"
+
+// ErasedUserPrefix starts the pseudonym that replaces an erased user's ID
+const ErasedUserPrefix = "erased-"
+
+// UserDataExport bundles everything stored about a user
+type UserDataExport struct {
+	UserID            string             `json:"userID"`
+	ExportedAt        time.Time          `json:"exportedAt"`
+	Repairs           []*RepairModel     `json:"repairs"`
+	RepairCosts       []*RepairCostModel `json:"repairCosts"`
+	ClaimedQuotes     []*AnonymousQuote  `json:"claimedQuotes"`
+	Receipts          []*Receipt         `json:"receipts"`
+	Amendments        []*Amendment       `json:"amendments"`
+	Blocks            []*Block           `json:"blocks"`
+	PhoneVerification *PhoneVerification `json:"phoneVerification,omitempty"`
+	EmailPreferences  *EmailPreferences  `json:"emailPreferences,omitempty"`
+	Emails            []*EmailDelivery   `json:"emails"`
+}
+
+// AuthorizeExport checks the actor may export userID's data: the user
+// themself or an admin
+func AuthorizeExport(actor Actor, userID string) error {
+	if actor.Role != ActorAdmin && (actor.Role != ActorUser || actor.ID == "" || actor.ID != userID) {
+		return fmt.Errorf("%w: only the user or an admin may export the user's data", ErrForbidden)
+	}
+	return nil
+}
+
+// ErasureReport records a right-to-be-forgotten request. It keeps only a hash
+// of the erased user ID, enough to answer whether a given user was erased.
+type ErasureReport struct {
+	ID          string           `bson:"_id" json:"id"`
+	UserIDHash  string           `bson:"userIDHash" json:"userIDHash"` // hex SHA-256 of the user ID
+	Pseudonym   string           `bson:"pseudonym" json:"pseudonym"`   // replaces the user ID on retained records
+	RequestedBy string           `bson:"requestedBy,omitempty" json:"requestedBy,omitempty"`
+	Anonymized  map[string]int64 `bson:"anonymized" json:"anonymized"` // documents kept without PII, per collection
+	Deleted     map[string]int64 `bson:"deleted" json:"deleted"`       // documents removed, per collection
+	Tombstones  int              `bson:"tombstones" json:"tombstones"` // Kafka tombstones queued, one per repair
+	RequestedAt time.Time        `bson:"requestedAt" json:"requestedAt"`
+	CompletedAt time.Time        `bson:"completedAt" json:"completedAt"`
+}
@@ -0,0 +1,132 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+)
+
+// SortField orders query results by one document field
+type SortField struct {
+	Field      string
+	Descending bool
+}
+
+// QueryOptions narrows what a repository read returns, so callers such as list
+// endpoints can fetch only the fields they render. A nil *QueryOptions returns
+// full documents in the method's default order from the primary.
+type QueryOptions struct {
+	Fields         []string    // BSON field paths to return; empty returns full documents, _id is always returned
+	Sort           []SortField // replaces the method's default order
+	Limit          int64       // 0 returns every match
+	Skip           int64
+	ReadPreference string // a Mongo read preference mode, e.g. "secondaryPreferred"; empty reads from the primary
+}
+
+// fieldPattern accepts plain and dotted BSON field paths, never operators
+var fieldPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)*$`)
+
+// Validate rejects field paths that are not plain document fields, negative
+// paging values and unknown read preferences
+func (o *QueryOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	for _, f := range o.Fields {
+		if !fieldPattern.MatchString(f) {
+			return fmt.Errorf("%w: invalid field %q", ErrInvalidInput, f)
+		}
+	}
+	for _, s := range o.Sort {
+		if !fieldPattern.MatchString(s.Field) {
+			return fmt.Errorf("%w: invalid sort field %q", ErrInvalidInput, s.Field)
+		}
+	}
+	if o.Limit < 0 || o.Skip < 0 {
+		return fmt.Errorf("%w: limit and skip must not be negative", ErrInvalidInput)
+	}
+	if o.ReadPreference != "" {
+		if _, err := readpref.ModeFromString(o.ReadPreference); err != nil {
+			return fmt.Errorf("%w: invalid read preference %q", ErrInvalidInput, o.ReadPreference)
+		}
+	}
+	return nil
+}
+
+// findOptions turns the options into driver find options, falling back to
+// defaultSort when no sort is given
+func (o *QueryOptions) findOptions(defaultSort bson.D) *options.FindOptions {
+	opts := options.Find()
+	if o == nil {
+		if defaultSort != nil {
+			opts.SetSort(defaultSort)
+		}
+		return opts
+	}
+	if len(o.Fields) > 0 {
+		projection := bson.D{}
+		for _, f := range o.Fields {
+			projection = append(projection, bson.E{Key: f, Value: 1})
+		}
+		opts.SetProjection(projection)
+	}
+	if len(o.Sort) > 0 {
+		sort := bson.D{}
+		for _, s := range o.Sort {
+			direction := 1
+			if s.Descending {
+				direction = -1
+			}
+			sort = append(sort, bson.E{Key: s.Field, Value: direction})
+		}
+		opts.SetSort(sort)
+	} else if defaultSort != nil {
+		opts.SetSort(defaultSort)
+	}
+	if o.Limit > 0 {
+		opts.SetLimit(o.Limit)
+	}
+	if o.Skip > 0 {
+		opts.SetSkip(o.Skip)
+	}
+	return opts
+}
+
+// collection returns coll with the requested read preference applied
+func (o *QueryOptions) collection(coll *mongo.Collection) (*mongo.Collection, error) {
+	if o == nil || o.ReadPreference == "" {
+		return coll, nil
+	}
+	mode, err := readpref.ModeFromString(o.ReadPreference)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid read preference %q", ErrInvalidInput, o.ReadPreference)
+	}
+	rp, err := readpref.New(mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create read preference: %v", err)
+	}
+	return coll.Clone(options.Collection().SetReadPreference(rp))
+}
+
+// find runs a find on coll with the query options applied, up to attempts
+// times while it fails transiently; database errors are returned classified
+func (o *QueryOptions) find(ctx context.Context, attempts int, coll *mongo.Collection, filter interface{}, defaultSort bson.D) (*mongo.Cursor, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	coll, err := o.collection(coll)
+	if err != nil {
+		return nil, err
+	}
+	var cursor *mongo.Cursor
+	err = retry(ctx, attempts, isTransient, func() error {
+		cursor, err = coll.Find(ctx, filter, o.findOptions(defaultSort))
+		return err
+	})
+	return cursor, classify(err)
+}
@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// Question types supported by intake questionnaires
+const (
+	QuestionTypeYesNo  = "yes_no"
+	QuestionTypeChoice = "choice"
+	QuestionTypeText   = "text"
+)
+
+// Question is a single intake question asked when a repair is requested
+type Question struct {
+	ID       string   `bson:"id" json:"id"`
+	Text     string   `bson:"text" json:"text"`
+	Type     string   `bson:"type" json:"type"`
+	Options  []string `bson:"options,omitempty" json:"options,omitempty"`
+	Required bool     `bson:"required" json:"required"`
+}
+
+// Questionnaire is the admin-managed set of intake questions for a repair type
+type Questionnaire struct {
+	RepairType string     `bson:"_id" json:"repairType"`
+	Questions  []Question `bson:"questions" json:"questions"`
+	UpdatedAt  time.Time  `bson:"updatedAt" json:"updatedAt"`
+}
+
+// SymptomAnswer is a user's answer to an intake question, stored on the repair
+type SymptomAnswer struct {
+	QuestionID string `bson:"questionID" json:"questionID"`
+	Question   string `bson:"question" json:"question"`
+	Answer     string `bson:"answer" json:"answer"`
+}
@@ -0,0 +1,20 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrQuoteClaimed marks claims on an anonymous quote another user already
+// claimed; handlers map it to 409
+var ErrQuoteClaimed = errors.New("quote is already claimed by another user")
+
+// AnonymousQuote is an estimate requested without a userID. It lives in the
+// anonymous_quotes collection until a user claims it, which is required before
+// it can be converted into a repair.
+type AnonymousQuote struct {
+	RepairCostModel `bson:",inline"`
+	CreatedAt       time.Time  `bson:"createdAt" json:"createdAt"`
+	ClaimedBy       string     `bson:"claimedBy,omitempty" json:"claimedBy,omitempty"`
+	ClaimedAt       *time.Time `bson:"claimedAt,omitempty" json:"claimedAt,omitempty"`
+}
@@ -0,0 +1,51 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrReceiptUnavailable marks receipt requests for repairs that are not
+// completed; handlers map it to 409
+var ErrReceiptUnavailable = errors.New("receipt is only available for completed repairs")
+
+// ReceiptLineItem is one billed line of a receipt
+type ReceiptLineItem struct {
+	Description string `bson:"description" json:"description"`
+	Quantity    int    `bson:"quantity" json:"quantity"`
+	UnitPrice   Money  `bson:"unitPrice" json:"unitPrice"`
+	Amount      Money  `bson:"amount" json:"amount"`
+}
+
+// ReceiptTax is a tax charged on the receipt subtotal
+type ReceiptTax struct {
+	Name        string  `bson:"name" json:"name"`
+	RatePercent float64 `bson:"ratePercent" json:"ratePercent"`
+	Amount      Money   `bson:"amount" json:"amount"`
+}
+
+// ReceiptMechanic is the mechanic who carried out the repair, as known at completion
+type ReceiptMechanic struct {
+	ID   string `bson:"id" json:"id"`
+	Name string `bson:"name,omitempty" json:"name,omitempty"`
+}
+
+// Receipt is the finalized bill of a completed repair. It is written once to
+// the receipts collection when the repair completes and never recomputed, so
+// later price or catalog changes do not alter it.
+type Receipt struct {
+	RepairID         string            `bson:"_id" json:"repairID"`
+	Number           string            `bson:"number" json:"number"`
+	UserID           string            `bson:"userID" json:"userID"`
+	RepairType       string            `bson:"repairType" json:"repairType"`
+	Currency         string            `bson:"currency" json:"currency"`
+	LineItems        []ReceiptLineItem `bson:"lineItems" json:"lineItems"`
+	Subtotal         Money             `bson:"subtotal" json:"subtotal"`
+	Taxes            []ReceiptTax      `bson:"taxes" json:"taxes"`
+	Total            Money             `bson:"total" json:"total"`
+	PaymentReference string            `bson:"paymentReference,omitempty" json:"paymentReference,omitempty"`
+	Mechanic         *ReceiptMechanic  `bson:"mechanic,omitempty" json:"mechanic,omitempty"`
+	CreatedAt        time.Time         `bson:"createdAt,omitempty" json:"createdAt,omitempty"` // when the repair was requested
+	CompletedAt      *time.Time        `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+	IssuedAt         time.Time         `bson:"issuedAt" json:"issuedAt"`
+}
@@ -2,19 +2,59 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
+// ErrInvalidInput marks errors caused by bad client input; handlers map it to 400
+var ErrInvalidInput = errors.New("invalid input")
+
 // RepairCostModel represents the cost of a repair
 type RepairCostModel struct {
-	ID           string          `bson:"_id,omitempty" json:"id"`
-	UserID       string          `bson:"userID" json:"userID"`
-	RepairType   string          `bson:"repairType" json:"repairType"`
-	TotalPrice   float64         `bson:"totalPrice" json:"totalPrice"`
-	UserLocation *Location       `bson:"userLocation" json:"userLocation,omitempty"`
-	Mechanics    []MechanicInfo `bson:"mechanics" json:"mechanics,omitempty"`
+	ID           string           `bson:"_id,omitempty" json:"id"`
+	UserID       string           `bson:"userID" json:"userID"`
+	RepairType   string           `bson:"repairType" json:"repairType"`
+	TotalPrice   Money            `bson:"totalPrice" json:"totalPrice"`
+	UserLocation *Location        `bson:"userLocation" json:"userLocation,omitempty"`
+	Mechanics    []MechanicInfo   `bson:"mechanics" json:"mechanics,omitempty"`
+	Availability *Availability    `bson:"availability,omitempty" json:"availability,omitempty"`
+	Anonymous    bool             `bson:"anonymous,omitempty" json:"anonymous,omitempty"` // estimated without a userID
+	Accuracy     string           `bson:"accuracy,omitempty" json:"accuracy,omitempty"`   // how travel times were computed, see the Accuracy constants
+	Pricing      *PricingResult   `bson:"-" json:"pricing,omitempty"`                     // rule trace of the estimate, not stored
+	Ranking      *MechanicRanking `bson:"ranking,omitempty" json:"ranking,omitempty"`     // how Mechanics are ordered
+	// EstimatedMinutes is how long the repair takes, learned from completed
+	// repairs of the type or from the catalog, see DurationEstimate
+	EstimatedMinutes float64 `bson:"estimatedMinutes,omitempty" json:"estimatedMinutes,omitempty"`
+	// Preference is the user's preferred mechanic and whether the repair is
+	// pinned to them
+	Preference *MechanicPreference `bson:"preference,omitempty" json:"preference,omitempty"`
+}
+
+// Estimate accuracies: how the travel times to the offered mechanics were
+// computed, from road routing down to straight-line distance
+const (
+	AccuracyRouted       = "routed"        // road routing by OSRM
+	AccuracyTile         = "tile"          // precomputed distance tiles, OSRM unreachable
+	AccuracyStraightLine = "straight_line" // straight-line distance, no tile either
+)
+
+// Wait buckets reported in an estimate's availability summary
+const (
+	WaitUnder15Min  = "under_15m"
+	Wait15To30Min   = "15_30m"
+	WaitOver30Min   = "over_30m"
+	WaitUnavailable = "unavailable"
+)
+
+// Availability summarizes mechanic supply near the user at estimate time
+type Availability struct {
+	RadiusMeters       float64 `bson:"radiusMeters" json:"radiusMeters"`
+	OnlineMechanics    int     `bson:"onlineMechanics" json:"onlineMechanics"`       // online and qualified within radius
+	AvailableMechanics int     `bson:"availableMechanics" json:"availableMechanics"` // of those, without an active assignment
+	WaitBucket         string  `bson:"waitBucket" json:"waitBucket"`
+	Confidence         string  `bson:"confidence" json:"confidence"` // high, medium or low
 }
 
 // Location represents a geographic coordinate
@@ -23,11 +63,34 @@ type Location struct {
 	Latitude  float64 `bson:"latitude" json:"latitude"`
 }
 
+// GeoPoint is a GeoJSON point, its coordinates longitude first
+type GeoPoint struct {
+	Type        string    `bson:"type" json:"type"`
+	Coordinates []float64 `bson:"coordinates" json:"coordinates"`
+}
+
+// NewGeoPoint returns the GeoJSON point of loc
+func NewGeoPoint(loc Location) *GeoPoint {
+	return &GeoPoint{Type: "Point", Coordinates: []float64{loc.Longitude, loc.Latitude}}
+}
+
+// Mechanic statuses; mechanics without a status are treated as online
+const (
+	MechanicOnline  = "online"
+	MechanicOffline = "offline"
+)
+
 // MechanicModel represents a mechanic's details
 type MechanicModel struct {
 	ID       string   `bson:"_id,omitempty" json:"id"`
 	Name     string   `bson:"name" json:"name"`
 	Location Location `bson:"location" json:"location"`
+	Status   string   `bson:"status,omitempty" json:"status,omitempty"`
+	Skills   []string `bson:"skills,omitempty" json:"skills,omitempty"` // repair types; empty means all
+	Region   string   `bson:"region,omitempty" json:"region,omitempty"` // set by mechanic-service from the location
+	// Set through mechanic-service's import, nil until known
+	Rating         *float64 `bson:"rating,omitempty" json:"rating,omitempty"`                 // 0 to MaxRating
+	AcceptanceRate *float64 `bson:"acceptanceRate,omitempty" json:"acceptanceRate,omitempty"` // 0 to 1
 }
 
 // MechanicInfo represents a mechanic with distance from user
@@ -36,6 +99,14 @@ type MechanicInfo struct {
 	Name     string   `bson:"name" json:"name"`
 	Location Location `bson:"location" json:"location"`
 	Distance float64  `bson:"distance" json:"distance"` // Distance in meters
+	// Inputs and result of the weighted score, see ScoreMechanics
+	Rating         *float64       `bson:"rating,omitempty" json:"rating,omitempty"`
+	AcceptanceRate *float64       `bson:"acceptanceRate,omitempty" json:"acceptanceRate,omitempty"`
+	OpenRepairs    int            `bson:"openRepairs" json:"openRepairs"` // pending or in progress repairs assigned to the mechanic
+	Score          *MechanicScore `bson:"score,omitempty" json:"score,omitempty"`
+	// EstimatedMinutes is the repair's duration learned for the mechanic, or
+	// the repair type's when they have too few samples
+	EstimatedMinutes float64 `bson:"estimatedMinutes,omitempty" json:"estimatedMinutes,omitempty"`
 }
 
 // RepairModel represents a repair request
@@ -44,40 +115,256 @@ type RepairModel struct {
 	UserID     string           `bson:"userID" json:"userID"`
 	Status     string           `bson:"status" json:"status"`
 	RepairCost *RepairCostModel `bson:"repairCost" json:"repairCost"`
+	Symptoms   []SymptomAnswer  `bson:"symptoms,omitempty" json:"symptoms,omitempty"`
+	AssignedTo string           `bson:"assignedTo,omitempty" json:"assignedTo,omitempty"` // set by mechanic-service
+	Tags       []string         `bson:"tags,omitempty" json:"tags,omitempty"`
+	CreatedAt  time.Time        `bson:"createdAt,omitempty" json:"createdAt,omitempty"`
+	Region     string           `bson:"region,omitempty" json:"region,omitempty"` // derived from the user location at creation
+	BundleID   string           `bson:"bundleID,omitempty" json:"bundleID,omitempty"`
+	// BundleDiscount is the repair's share of its bundle's discount
+	BundleDiscount Money `bson:"bundleDiscount,omitempty" json:"bundleDiscount,omitempty"`
+	// Patched through PATCH /repairs/{repairID}, see RepairPatch
+	ScheduledAt *time.Time `bson:"scheduledAt,omitempty" json:"scheduledAt,omitempty"` // when the user wants the repair done
+	Notes       string     `bson:"notes,omitempty" json:"notes,omitempty"`
+	Priority    string     `bson:"priority,omitempty" json:"priority,omitempty"`
+	// Set on the first move to in_progress and to completed
+	StartedAt   *time.Time `bson:"startedAt,omitempty" json:"startedAt,omitempty"`
+	CompletedAt *time.Time `bson:"completedAt,omitempty" json:"completedAt,omitempty"`
+	// GeoLocation is the user location as GeoJSON, for the 2dsphere index
+	// mechanic-service finds nearby repairs through; set by CreateRepair
+	GeoLocation *GeoPoint `bson:"geoLocation,omitempty" json:"-"`
+}
+
+// QuotedPrice is the price the repair was booked at, less its share of a
+// bundle discount
+func (r *RepairModel) QuotedPrice() Money {
+	return r.RepairCost.TotalPrice - r.BundleDiscount
+}
+
+// BoundingBox is a longitude/latitude rectangle
+type BoundingBox struct {
+	MinLongitude float64
+	MinLatitude  float64
+	MaxLongitude float64
+	MaxLatitude  float64
+}
+
+// Page sizes of repair listings (GET /repairs)
+const (
+	DefaultSearchLimit = 100
+	MaxSearchLimit     = 1000
+)
+
+// RepairFilter narrows repair queries; zero-valued fields do not filter
+type RepairFilter struct {
+	Statuses    []string
+	UserID      string
+	RepairTypes []string
+	BoundingBox *BoundingBox
+	Since       time.Time
+	Tags        []string // repairs must carry all of them
+	IDs         []string
+}
+
+// RepairCluster is a map marker grouping the repairs that share a geohash cell.
+// RepairID is only set for single-repair clusters.
+type RepairCluster struct {
+	Geohash   string         `json:"geohash"`
+	Count     int            `json:"count"`
+	Latitude  float64        `json:"latitude"`  // centroid of the clustered repairs
+	Longitude float64        `json:"longitude"` // centroid of the clustered repairs
+	Statuses  map[string]int `json:"statuses"`
+	RepairID  string         `json:"repairID,omitempty"`
+}
+
+// RepairMap is the clustered view of repairs inside a bounding box
+type RepairMap struct {
+	Zoom      int             `json:"zoom"`
+	Precision int             `json:"precision"` // geohash length used for clustering
+	Total     int             `json:"total"`
+	Clusters  []RepairCluster `json:"clusters"`
+}
+
+// OutboxBacklog summarizes the outbox events not yet published
+type OutboxBacklog struct {
+	Unprocessed int64
+	Oldest      *time.Time // created_at of the oldest unprocessed event, nil when empty
 }
 
 // OutboxEvent represents an event in the outbox collection
 type OutboxEvent struct {
-	ID          string     `bson:"_id,omitempty" json:"id"`
-	EventType   string     `bson:"event_type" json:"event_type"`
-	Payload     []byte     `bson:"payload" json:"payload"`
-	CreatedAt   time.Time  `bson:"created_at" json:"created_at"`
-	Processed   bool       `bson:"processed" json:"processed"`
-	ProcessedAt *time.Time `bson:"processed_at,omitempty" json:"processed_at,omitempty"`
+	ID           string     `bson:"_id,omitempty" json:"id"`
+	EventType    string     `bson:"event_type" json:"event_type"`
+	AggregateID  string     `bson:"aggregate_id,omitempty" json:"aggregate_id,omitempty"`
+	Payload      []byte     `bson:"payload" json:"payload"`
+	CreatedAt    time.Time  `bson:"created_at" json:"created_at"`
+	Processed    bool       `bson:"processed" json:"processed"`
+	ProcessedAt  *time.Time `bson:"processed_at,omitempty" json:"processed_at,omitempty"`
+	RedriveOf    string     `bson:"redrive_of,omitempty" json:"redrive_of,omitempty"`       // event cloned by an outbox redrive
+	RedriveCount int        `bson:"redrive_count,omitempty" json:"redrive_count,omitempty"` // times a redrive reset the event
+	Region       string     `bson:"region,omitempty" json:"region,omitempty"`               // region of the aggregate; empty publishes to every region
+	Topic        string     `bson:"topic,omitempty" json:"topic,omitempty"`                 // logical topic; empty publishes to the repair events topic
 }
 
-// RepairRepository defines the data access methods for repairs
+// RepairRepository defines the data access methods for repairs. Writes that
+// must be atomic run in RunInTransaction and are passed the context it hands
+// to its function; no method depends on the storage backend's session types.
 type RepairRepository interface {
 	CreateRepair(ctx context.Context, repair *RepairModel) (*RepairModel, error)
 	SaveRepairCost(ctx context.Context, cost *RepairCostModel) error
 	GetRepairCostByID(ctx context.Context, id string) (*RepairCostModel, error)
 	GetRepairByID(ctx context.Context, id string) (*RepairModel, error)
 	UpdateRepair(ctx context.Context, repairID string, status string) error
-	GetAllMechanics(ctx context.Context) ([]*MechanicModel, error)
-	GetAllRepairs(ctx context.Context) ([]*RepairModel, error)
-	WatchRepairs(ctx context.Context) (*mongo.ChangeStream, error)
-	SaveOutboxEvent(ctx context.Context, session mongo.SessionContext, event *OutboxEvent) error
-	GetUnprocessedOutboxEvents(ctx context.Context) ([]*OutboxEvent, error)
+	DeleteRepair(ctx context.Context, repairID string) error
+	GetRepairHistory(ctx context.Context, repairID string, until time.Time) (*RepairHistory, error)
+	PatchRepair(ctx context.Context, repairID string, patch *RepairPatch) error
+	GetAllMechanics(ctx context.Context, opts *QueryOptions) ([]*MechanicModel, error)
+	FindMechanicsInBox(ctx context.Context, box BoundingBox) ([]*MechanicModel, error)
+	GetMechanicByID(ctx context.Context, id string) (*MechanicModel, error)
+	CountActiveAssignments(ctx context.Context, mechanicIDs []string) (map[string]int, error)
+	FindRepairs(ctx context.Context, filter RepairFilter, opts *QueryOptions) ([]*RepairModel, error)
+	FindRepairLocations(ctx context.Context, filter RepairFilter) ([]*RepairModel, error)
+	WatchRepairs(ctx context.Context, filter RepairFilter) (*mongo.ChangeStream, error)
+	CountRepairsByStatus(ctx context.Context) (map[string]int64, error)
+	GetOutboxBacklog(ctx context.Context) (*OutboxBacklog, error)
+	SaveOutboxEvent(ctx context.Context, event *OutboxEvent) error
+	GetUnprocessedOutboxEvents(ctx context.Context, regions []string) ([]*OutboxEvent, error)
+	WatchOutboxEvents(ctx context.Context, regions []string) (*mongo.ChangeStream, error)
 	MarkOutboxEventProcessed(ctx context.Context, eventID string) error
-	GetMongoClient(ctx context.Context) *mongo.Client
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	GetQuestionnaire(ctx context.Context, repairType string) (*Questionnaire, error)
+	UpsertQuestionnaire(ctx context.Context, questionnaire *Questionnaire) error
+	FindPricingRules(ctx context.Context) ([]*PricingRule, error)
+	UpsertPricingRule(ctx context.Context, rule *PricingRule) error
+	DeletePricingRule(ctx context.Context, ruleID string) error
+	GetWeatherFlag(ctx context.Context) (*WeatherFlag, error)
+	SaveWeatherFlag(ctx context.Context, flag *WeatherFlag) error
+	SaveBlock(ctx context.Context, block *Block) (*Block, error)
+	DeleteBlock(ctx context.Context, userID, mechanicID string) error
+	FindBlocks(ctx context.Context, userID string, opts *QueryOptions) ([]*Block, error)
+	AbsentMechanicIDs(ctx context.Context, at time.Time) (map[string]bool, error)
+	AssignmentCounts(ctx context.Context, mechanicIDs []string, since time.Time) (map[string]int, error)
+	GetFairnessPolicy(ctx context.Context) (*FairnessPolicy, error)
+	SaveFairnessPolicy(ctx context.Context, policy *FairnessPolicy) error
+	SaveAnonymousQuote(ctx context.Context, quote *AnonymousQuote) error
+	GetAnonymousQuote(ctx context.Context, id string) (*AnonymousQuote, error)
+	ClaimAnonymousQuote(ctx context.Context, id, userID string) (*AnonymousQuote, error)
+	SaveReceipt(ctx context.Context, receipt *Receipt) (*Receipt, error)
+	GetReceipt(ctx context.Context, repairID string) (*Receipt, error)
+	AddRepairTag(ctx context.Context, repairID, tag string) ([]string, error)
+	RemoveRepairTag(ctx context.Context, repairID, tag string) ([]string, error)
+	SaveRepairNote(ctx context.Context, note *RepairNote) error
+	DeleteRepairNote(ctx context.Context, repairID, noteID string) error
+	FindRepairNotes(ctx context.Context, repairID string, opts *QueryOptions) ([]*RepairNote, error)
+	SaveAmendment(ctx context.Context, amendment *Amendment) error
+	DecideAmendment(ctx context.Context, repairID, amendmentID, status string, decidedAt time.Time) (*Amendment, error)
+	FindAmendments(ctx context.Context, repairID string) ([]*Amendment, error)
+	GetPhoneVerification(ctx context.Context, userID string) (*PhoneVerification, error)
+	SavePhoneVerification(ctx context.Context, verification *PhoneVerification) error
+	ExportUserData(ctx context.Context, userID string) (*UserDataExport, error)
+	EraseUserData(ctx context.Context, userID, pseudonym string) (anonymized, deleted map[string]int64, repairIDs []string, err error)
+	SaveErasureReport(ctx context.Context, report *ErasureReport) error
+	FindErasureReports(ctx context.Context, userIDHash string) ([]*ErasureReport, error)
+	RedriveOutboxEvents(ctx context.Context, req *OutboxRedriveRequest) (eventIDs, cloneIDs []string, err error)
+	SaveOutboxRedrive(ctx context.Context, redrive *OutboxRedrive) error
+	FindOutboxRedrives(ctx context.Context, opts *QueryOptions) ([]*OutboxRedrive, error)
+	GetEmailTemplate(ctx context.Context, name string) (*EmailTemplate, error)
+	FindEmailTemplates(ctx context.Context) ([]*EmailTemplate, error)
+	SaveEmailTemplate(ctx context.Context, tmpl *EmailTemplate) error
+	GetEmailPreferences(ctx context.Context, userID string) (*EmailPreferences, error)
+	SaveEmailPreferences(ctx context.Context, prefs *EmailPreferences) error
+	SaveEmailDelivery(ctx context.Context, delivery *EmailDelivery) error
+	ClaimEmailDelivery(ctx context.Context, now time.Time, lease time.Duration) (*EmailDelivery, error)
+	UpdateEmailDelivery(ctx context.Context, delivery *EmailDelivery) error
+	FindEmailDeliveries(ctx context.Context, filter EmailDeliveryFilter, opts *QueryOptions) ([]*EmailDelivery, error)
+	CreateRepairBundle(ctx context.Context, bundle *RepairBundle, discounts map[string]Money) error
+	GetRepairBundle(ctx context.Context, id string) (*RepairBundle, error)
+	FindRepairBundles(ctx context.Context, opts *QueryOptions) ([]*RepairBundle, error)
+	DeleteRepairBundle(ctx context.Context, id string) error
+	SaveDistanceTile(ctx context.Context, tile *DistanceTile) error
+	FindDistanceTiles(ctx context.Context, sizeDegrees float64, radius int) ([]DistanceTile, error)
+	SummarizeDistanceTiles(ctx context.Context, sizeDegrees float64, radius int) ([]*DistanceTileSummary, error)
+	SaveFleetAccount(ctx context.Context, fleet *FleetAccount) error
+	GetFleetAccount(ctx context.Context, id string) (*FleetAccount, error)
+	FindFleetByMember(ctx context.Context, userID string) (*FleetAccount, error)
+	MemberSpending(ctx context.Context, userIDs []string, since, until time.Time) (map[string]MemberSpending, error)
+	FindFleetAccounts(ctx context.Context) ([]*FleetAccount, error)
+	FindCompletedReceipts(ctx context.Context, userIDs []string, since, until time.Time) ([]*Receipt, error)
+	SaveFleetInvoice(ctx context.Context, invoice *FleetInvoice) error
+	GetFleetInvoice(ctx context.Context, id string) (*FleetInvoice, error)
+	FindFleetInvoices(ctx context.Context, fleetID string, opts *QueryOptions) ([]*FleetInvoice, error)
+	SaveDurationSample(ctx context.Context, sample *RepairDurationSample) error
+	RecentDurations(ctx context.Context, repairType, mechanicID string, limit int) ([]float64, error)
+	SaveDurationEstimate(ctx context.Context, estimate *DurationEstimate) error
+	FindDurationEstimates(ctx context.Context, repairType string) ([]*DurationEstimate, error)
 }
 
 // RepairService defines the business logic methods for repairs
 type RepairService interface {
-	CreateRepair(ctx context.Context, cost *RepairCostModel) (*RepairModel, error)
-	EstimateRepairCost(ctx context.Context, repairType string, userID string, userLocation *Location) (*RepairCostModel, error)
+	CreateRepair(ctx context.Context, cost *RepairCostModel, answers []SymptomAnswer) (*RepairModel, error)
+	EstimateRepairCost(ctx context.Context, repairType string, userID string, userLocation *Location, sortBy string, preference *MechanicPreference) (*RepairCostModel, error)
 	GetAndValidateRepairCost(ctx context.Context, costID, userID string) (*RepairCostModel, error)
+	ClaimQuote(ctx context.Context, costID, userID string) (*RepairCostModel, error)
 	GetRepairByID(ctx context.Context, id string) (*RepairModel, error)
-	UpdateRepair(ctx context.Context, repairID string, status string) error
-	GetAllRepairs(ctx context.Context) ([]*RepairModel, error)
+	GetRepairAsOf(ctx context.Context, id string, asOf time.Time) (*RepairSnapshot, error)
+	UpdateRepair(ctx context.Context, repairID string, status string, paymentReference string, finalAmount *Money) (*RepairModel, error)
+	PatchRepair(ctx context.Context, repairID string, actor Actor, patch *RepairPatch) (*RepairModel, error)
+	CancelRepair(ctx context.Context, repairID string, actor Actor) (*RepairModel, error)
+	DeleteRepair(ctx context.Context, repairID string, actor Actor) error
+	GetReceipt(ctx context.Context, repairID string) (*Receipt, error)
+	GetAllRepairs(ctx context.Context, filter RepairFilter, opts *QueryOptions) ([]*RepairModel, error)
+	AddTag(ctx context.Context, repairID, tag string) ([]string, error)
+	RemoveTag(ctx context.Context, repairID, tag string) ([]string, error)
+	ListTags(ctx context.Context, repairID string) ([]string, error)
+	AddNote(ctx context.Context, repairID, author, body string) (*RepairNote, error)
+	DeleteNote(ctx context.Context, repairID, noteID string) error
+	ListNotes(ctx context.Context, repairID string) ([]*RepairNote, error)
+	RequestAmendment(ctx context.Context, repairID, mechanicID, description string, amount Money) (*Amendment, error)
+	DecideAmendment(ctx context.Context, repairID, amendmentID, status string) (*Amendment, error)
+	ListAmendments(ctx context.Context, repairID string) ([]*Amendment, error)
+	GetQuestionnaire(ctx context.Context, repairType string) (*Questionnaire, error)
+	SaveQuestionnaire(ctx context.Context, questionnaire *Questionnaire) error
+	ListPricingRules(ctx context.Context) ([]*PricingRule, error)
+	SavePricingRule(ctx context.Context, rule *PricingRule) (*PricingRule, error)
+	DeletePricingRule(ctx context.Context, ruleID string) error
+	EvaluatePricing(ctx context.Context, pc PricingContext, rules []*PricingRule) (*PricingResult, error)
+	GetWeatherFlag(ctx context.Context) (*WeatherFlag, error)
+	SetWeatherFlag(ctx context.Context, flag string) (*WeatherFlag, error)
+	GetFairnessPolicy(ctx context.Context) (*FairnessPolicy, error)
+	SetFairnessPolicy(ctx context.Context, policy *FairnessPolicy) (*FairnessPolicy, error)
+	AssignmentCounts(ctx context.Context) (*AssignmentCounts, error)
+	RepairMap(ctx context.Context, box BoundingBox, zoom int) (*RepairMap, error)
+	PositioningHints(ctx context.Context, mechanicID string) (*PositioningHints, error)
+	BlockMechanic(ctx context.Context, userID, mechanicID string) (*Block, error)
+	UnblockMechanic(ctx context.Context, userID, mechanicID string) error
+	ListBlocks(ctx context.Context, userID string) ([]*Block, error)
+	BlacklistUser(ctx context.Context, userID, reason string) (*Block, error)
+	RemoveBlacklist(ctx context.Context, userID string) error
+	StartPhoneVerification(ctx context.Context, userID, phone string) (*PhoneVerification, error)
+	ConfirmPhoneVerification(ctx context.Context, userID, code string) (*PhoneVerification, error)
+	GetPhoneVerification(ctx context.Context, userID string) (*PhoneVerification, error)
+	ExportUserData(ctx context.Context, actor Actor, userID string) (*UserDataExport, error)
+	EraseUser(ctx context.Context, userID, requestedBy string) (*ErasureReport, error)
+	FindErasureReports(ctx context.Context, userID string) ([]*ErasureReport, error)
+	RedriveOutbox(ctx context.Context, req *OutboxRedriveRequest) (*OutboxRedrive, error)
+	BulkUpdateStatus(ctx context.Context, req *BulkStatusRequest) (*BulkStatusReport, error)
+	ListOutboxRedrives(ctx context.Context, opts *QueryOptions) ([]*OutboxRedrive, error)
+	ListEmailTemplates(ctx context.Context) ([]*EmailTemplate, error)
+	SaveEmailTemplate(ctx context.Context, tmpl *EmailTemplate) (*EmailTemplate, error)
+	GetEmailPreferences(ctx context.Context, userID string) (*EmailPreferences, error)
+	SetEmailPreferences(ctx context.Context, prefs *EmailPreferences) (*EmailPreferences, error)
+	ListEmailDeliveries(ctx context.Context, filter EmailDeliveryFilter, opts *QueryOptions) ([]*EmailDelivery, error)
+	CreateRepairBundle(ctx context.Context, req *RepairBundleRequest) (*RepairBundle, error)
+	GetRepairBundle(ctx context.Context, id string) (*RepairBundle, error)
+	ListRepairBundles(ctx context.Context, opts *QueryOptions) ([]*RepairBundle, error)
+	DissolveRepairBundle(ctx context.Context, id string) error
+	CreateFleetAccount(ctx context.Context, req *FleetAccountRequest) (*FleetAccount, error)
+	GetFleetAccount(ctx context.Context, actor Actor, fleetID string) (*FleetAccount, error)
+	SetFleetMember(ctx context.Context, actor Actor, fleetID string, member FleetMember) (*FleetAccount, error)
+	RemoveFleetMember(ctx context.Context, actor Actor, fleetID, userID string) error
+	FleetUsage(ctx context.Context, actor Actor, fleetID, month string) (*FleetUsage, error)
+	ListFleetInvoices(ctx context.Context, actor Actor, fleetID string, opts *QueryOptions) ([]*FleetInvoice, error)
+	GetFleetInvoice(ctx context.Context, actor Actor, fleetID, month string) (*FleetInvoice, error)
+	IssueFleetInvoice(ctx context.Context, fleetID, month string) (*FleetInvoice, error)
+	DurationReport(ctx context.Context, repairType string) (*DurationReport, error)
 }
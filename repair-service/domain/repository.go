@@ -2,10 +2,12 @@ package domain
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.opentelemetry.io/otel"
@@ -15,39 +17,133 @@ import (
 
 // MongoRepository implements the RepairRepository interface
 type MongoRepository struct {
-	RepairCollection   *mongo.Collection
-	CostCollection     *mongo.Collection
-	MechanicCollection *mongo.Collection
-	OutboxCollection   *mongo.Collection
+	RepairCollection        *mongo.Collection
+	CostCollection          *mongo.Collection
+	MechanicCollection      *mongo.Collection
+	OutboxCollection        *mongo.Collection
+	QuestionnaireCollection *mongo.Collection
+	BlockCollection         *mongo.Collection
+	AnonymousQuotes         *mongo.Collection
+	ReceiptCollection       *mongo.Collection
+	NoteCollection          *mongo.Collection
+	AmendmentCollection     *mongo.Collection
+	PhoneCollection         *mongo.Collection
+	ErasureCollection       *mongo.Collection
+	AbsenceCollection       *mongo.Collection
+	RedriveCollection       *mongo.Collection
+	PricingRuleCollection   *mongo.Collection
+	PricingSettings         *mongo.Collection
+	DispatchSettings        *mongo.Collection
+	AssignmentCounters      *mongo.Collection
+	EmailTemplates          *mongo.Collection
+	EmailPreferences        *mongo.Collection
+	EmailDeliveries         *mongo.Collection
+	BundleCollection        *mongo.Collection
+	DistanceTiles           *mongo.Collection
+	FleetCollection         *mongo.Collection
+	DurationSamples         *mongo.Collection
+	DurationEstimates       *mongo.Collection
+	UndeliveredMessages     *mongo.Collection
+	MessageSequences        *mongo.Collection
+	FleetInvoices           *mongo.Collection
+	AssignmentOutbox        *mongo.Collection
+
+	retryAttempts int // of reads and transactions failing transiently, see NewRepository
 }
 
 // NewMongoRepository creates a new MongoRepository
 func NewMongoRepository(client *mongo.Client) *MongoRepository {
 	return &MongoRepository{
-		RepairCollection:   client.Database("repairdb").Collection("repairs"),
-		CostCollection:     client.Database("repairdb").Collection("repair_costs"),
-		MechanicCollection: client.Database("repairdb").Collection("mechanics"),
-		OutboxCollection:   client.Database("repairdb").Collection("repair_outbox"),
+		RepairCollection:        client.Database("repairdb").Collection("repairs"),
+		CostCollection:          client.Database("repairdb").Collection("repair_costs"),
+		MechanicCollection:      client.Database("repairdb").Collection("mechanics"),
+		OutboxCollection:        client.Database("repairdb").Collection("repair_outbox"),
+		QuestionnaireCollection: client.Database("repairdb").Collection("questionnaires"),
+		BlockCollection:         client.Database("repairdb").Collection("blocks"),
+		AnonymousQuotes:         client.Database("repairdb").Collection("anonymous_quotes"),
+		ReceiptCollection:       client.Database("repairdb").Collection("receipts"),
+		NoteCollection:          client.Database("repairdb").Collection("repair_notes"),
+		AmendmentCollection:     client.Database("repairdb").Collection("repair_amendments"),
+		PhoneCollection:         client.Database("repairdb").Collection("phone_verifications"),
+		ErasureCollection:       client.Database("repairdb").Collection("erasure_reports"),
+		AbsenceCollection:       client.Database("repairdb").Collection("mechanic_absences"),
+		RedriveCollection:       client.Database("repairdb").Collection("outbox_redrives"),
+		PricingRuleCollection:   client.Database("repairdb").Collection("pricing_rules"),
+		PricingSettings:         client.Database("repairdb").Collection("pricing_settings"),
+		DispatchSettings:        client.Database("repairdb").Collection("dispatch_settings"),
+		AssignmentCounters:      client.Database("repairdb").Collection("assignment_counters"),
+		EmailTemplates:          client.Database("repairdb").Collection("email_templates"),
+		EmailPreferences:        client.Database("repairdb").Collection("email_preferences"),
+		EmailDeliveries:         client.Database("repairdb").Collection("email_deliveries"),
+		BundleCollection:        client.Database("repairdb").Collection("repair_bundles"),
+		DistanceTiles:           client.Database("repairdb").Collection("distance_tiles"),
+		FleetCollection:         client.Database("repairdb").Collection("fleet_accounts"),
+		DurationSamples:         client.Database("repairdb").Collection("repair_durations"),
+		DurationEstimates:       client.Database("repairdb").Collection("repair_duration_estimates"),
+		UndeliveredMessages:     client.Database("repairdb").Collection("ws_undelivered"),
+		MessageSequences:        client.Database("repairdb").Collection("ws_sequences"),
+		FleetInvoices:           client.Database("repairdb").Collection("fleet_invoices"),
+		AssignmentOutbox:        client.Database("repairdb").Collection("assignment_outbox"),
 	}
 }
 
-// GetMongoClient returns the MongoDB client for starting sessions
+// GetMongoClient returns the MongoDB client, for the features built on
+// MongoDB itself, see MongoBacked
 func (r *MongoRepository) GetMongoClient(ctx context.Context) *mongo.Client {
 	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetMongoClient")
 	defer span.End()
 	return r.RepairCollection.Database().Client()
 }
 
+// RunInTransaction runs fn in a MongoDB transaction, committing when it
+// succeeds and aborting when it fails. The context fn gets carries the
+// session; the repository writes fn makes with it join the transaction.
+// Transactions the server aborted transiently, e.g. on a write conflict or a
+// primary election, run again from the start, so fn must not keep state
+// between runs; a commit whose outcome is unknown is retried on its own.
+func (r *MongoRepository) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := r.RepairCollection.Database().Client().StartSession()
+	if err != nil {
+		return fmt.Errorf("failed to start MongoDB session: %w", classify(err))
+	}
+	defer session.EndSession(ctx)
+
+	transientTransaction := func(err error) bool { return hasErrorLabel(err, "TransientTransactionError") }
+	unknownCommit := func(err error) bool { return hasErrorLabel(err, "UnknownTransactionCommitResult") }
+	return retry(ctx, r.retryAttempts, transientTransaction, func() error {
+		if err := session.StartTransaction(); err != nil {
+			return fmt.Errorf("failed to start transaction: %w", classify(err))
+		}
+		err := mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
+			return fn(sc)
+		})
+		if err != nil {
+			session.AbortTransaction(ctx)
+			return err
+		}
+		err = retry(ctx, r.retryAttempts, unknownCommit, func() error {
+			return session.CommitTransaction(ctx)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", classify(err))
+		}
+		return nil
+	})
+}
+
 // CreateRepair inserts a new repair
 func (r *MongoRepository) CreateRepair(ctx context.Context, repair *RepairModel) (*RepairModel, error) {
 	_, span := otel.Tracer("repair-service").Start(ctx, "MongoCreateRepair")
 	defer span.End()
 
+	if repair.RepairCost != nil && repair.RepairCost.UserLocation != nil {
+		repair.GeoLocation = NewGeoPoint(*repair.RepairCost.UserLocation)
+	}
 	_, err := r.RepairCollection.InsertOne(ctx, repair)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to insert repair")
-		return nil, err
+		return nil, classify(err)
 	}
 	span.SetAttributes(
 		attribute.String("repairID", repair.ID),
@@ -66,13 +162,13 @@ func (r *MongoRepository) SaveRepairCost(ctx context.Context, cost *RepairCostMo
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to insert repair cost")
-		return err
+		return classify(err)
 	}
 	span.SetAttributes(
 		attribute.String("costID", cost.ID),
 		attribute.String("userID", cost.UserID),
 		attribute.String("repairType", cost.RepairType),
-		attribute.Float64("totalPrice", cost.TotalPrice),
+		attribute.Int64("totalPriceMinor", cost.TotalPrice.Minor()),
 	)
 	return nil
 }
@@ -83,11 +179,13 @@ func (r *MongoRepository) GetRepairCostByID(ctx context.Context, id string) (*Re
 	defer span.End()
 
 	var cost RepairCostModel
-	err := r.CostCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&cost)
+	err := retry(ctx, r.retryAttempts, isTransient, func() error {
+		return r.CostCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&cost)
+	})
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to find repair cost")
-		return nil, err
+		return nil, classify(err)
 	}
 	span.SetAttributes(
 		attribute.String("costID", id),
@@ -102,11 +200,13 @@ func (r *MongoRepository) GetRepairByID(ctx context.Context, id string) (*Repair
 	defer span.End()
 
 	var repair RepairModel
-	err := r.RepairCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&repair)
+	err := retry(ctx, r.retryAttempts, isTransient, func() error {
+		return r.RepairCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&repair)
+	})
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to find repair")
-		return nil, err
+		return nil, classify(err)
 	}
 	span.SetAttributes(
 		attribute.String("repairID", id),
@@ -120,11 +220,19 @@ func (r *MongoRepository) UpdateRepair(ctx context.Context, repairID string, sta
 	_, span := otel.Tracer("repair-service").Start(ctx, "MongoUpdateRepair")
 	defer span.End()
 
-	_, err := r.RepairCollection.UpdateOne(ctx, bson.M{"_id": repairID}, bson.M{"$set": bson.M{"status": status}})
+	// The first start and completion are timed for the duration estimates
+	set := bson.M{"status": status}
+	switch status {
+	case "in_progress":
+		set["startedAt"] = bson.M{"$ifNull": bson.A{"$startedAt", "$$NOW"}}
+	case "completed":
+		set["completedAt"] = bson.M{"$ifNull": bson.A{"$completedAt", "$$NOW"}}
+	}
+	_, err := r.RepairCollection.UpdateOne(ctx, bson.M{"_id": repairID}, mongo.Pipeline{{{Key: "$set", Value: set}}})
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to update repair")
-		return err
+		return classify(err)
 	}
 	span.SetAttributes(
 		attribute.String("repairID", repairID),
@@ -133,13 +241,153 @@ func (r *MongoRepository) UpdateRepair(ctx context.Context, repairID string, sta
 	return nil
 }
 
+// DeleteRepair deletes a repair in a DeletableStatuses status outside a
+// bundle, with its notes and amendments; ErrRepairNotDeletable means it
+// moved on or joined a bundle since it was read
+func (r *MongoRepository) DeleteRepair(ctx context.Context, repairID string) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoDeleteRepair")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", repairID))
+
+	result, err := r.RepairCollection.DeleteOne(ctx, bson.M{
+		"_id":      repairID,
+		"status":   bson.M{"$in": DeletableStatuses},
+		"bundleID": bson.M{"$exists": false},
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to delete repair")
+		return fmt.Errorf("failed to delete repair: %w", classify(err))
+	}
+	if result.DeletedCount == 0 {
+		return ErrRepairNotDeletable
+	}
+	for _, coll := range []*mongo.Collection{r.NoteCollection, r.AmendmentCollection} {
+		if _, err := coll.DeleteMany(ctx, bson.M{"repairID": repairID}); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to delete repair "+coll.Name())
+			return fmt.Errorf("failed to delete repair %s: %w", coll.Name(), classify(err))
+		}
+	}
+	return nil
+}
+
+// GetRepairHistory reads the events a repair's states up to until are rebuilt
+// from: its repair_outbox events, the repair_assigned events mechanic-service
+// wrote to assignment_outbox and its amendments
+func (r *MongoRepository) GetRepairHistory(ctx context.Context, repairID string, until time.Time) (*RepairHistory, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetRepairHistory")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", repairID), attribute.String("until", until.Format(time.RFC3339)))
+
+	history := &RepairHistory{Events: []*OutboxEvent{}, Assignments: []*RepairAssignment{}, Amendments: []*Amendment{}}
+	byAge := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+	var assignments []*OutboxEvent
+	err := retry(ctx, r.retryAttempts, isTransient, func() error {
+		cursor, err := r.OutboxCollection.Find(ctx, bson.M{
+			"aggregate_id": repairID,
+			"event_type":   bson.M{"$in": bson.A{"RepairCreated", "RepairUpdated", EventRepairCancelled}},
+			"created_at":   bson.M{"$lte": until},
+			"redrive_of":   bson.M{"$exists": false},
+		}, byAge)
+		if err != nil {
+			return err
+		}
+		if err := cursor.All(ctx, &history.Events); err != nil {
+			return err
+		}
+		cursor, err = r.AssignmentOutbox.Find(ctx, bson.M{
+			"aggregate_id": repairID,
+			"event_type":   EventRepairAssigned,
+			"created_at":   bson.M{"$lte": until},
+		}, byAge)
+		if err != nil {
+			return err
+		}
+		if err := cursor.All(ctx, &assignments); err != nil {
+			return err
+		}
+		cursor, err = r.AmendmentCollection.Find(ctx, bson.M{"repairID": repairID, "requestedAt": bson.M{"$lte": until}},
+			options.Find().SetSort(bson.D{{Key: "requestedAt", Value: 1}}))
+		if err != nil {
+			return err
+		}
+		return cursor.All(ctx, &history.Amendments)
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to read repair history")
+		return nil, fmt.Errorf("failed to read repair history: %w", classify(err))
+	}
+
+	for _, event := range assignments {
+		var assignment RepairAssignment
+		if err := json.Unmarshal(event.Payload, &assignment); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to decode assignment event")
+			return nil, fmt.Errorf("failed to decode assignment event %s: %w", event.ID, err)
+		}
+		history.Assignments = append(history.Assignments, &assignment)
+	}
+	span.SetAttributes(
+		attribute.Int("eventCount", len(history.Events)),
+		attribute.Int("assignmentCount", len(history.Assignments)),
+	)
+	return history, nil
+}
+
+// PatchRepair sets and removes the fields of a patch, status excluded
+func (r *MongoRepository) PatchRepair(ctx context.Context, repairID string, patch *RepairPatch) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoPatchRepair")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("repairID", repairID),
+		attribute.StringSlice("fields", patch.Fields()),
+	)
+
+	set := bson.M{}
+	if patch.ScheduledAt != nil {
+		set["scheduledAt"] = patch.ScheduledAt.UTC()
+	}
+	if patch.Notes != nil {
+		set["notes"] = *patch.Notes
+	}
+	if patch.Priority != nil {
+		set["priority"] = *patch.Priority
+	}
+	unset := bson.M{}
+	for _, field := range patch.Remove {
+		unset[field] = ""
+	}
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if len(update) == 0 {
+		return nil
+	}
+	result, err := r.RepairCollection.UpdateOne(ctx, bson.M{"_id": repairID}, update)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to patch repair")
+		return fmt.Errorf("failed to patch repair: %w", classify(err))
+	}
+	if result.MatchedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
 // GetAllMechanics retrieves all mechanics
-func (r *MongoRepository) GetAllMechanics(ctx context.Context) ([]*MechanicModel, error) {
+func (r *MongoRepository) GetAllMechanics(ctx context.Context, opts *QueryOptions) ([]*MechanicModel, error) {
 	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetAllMechanics")
 	defer span.End()
 
 	var mechanics []*MechanicModel
-	cursor, err := r.MechanicCollection.Find(ctx, bson.M{})
+	cursor, err := opts.find(ctx, r.retryAttempts, r.MechanicCollection, bson.M{}, nil)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to find mechanics")
@@ -151,14 +399,14 @@ func (r *MongoRepository) GetAllMechanics(ctx context.Context) ([]*MechanicModel
 		if err := cursor.Decode(&mechanic); err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, "Failed to decode mechanic")
-			return nil, err
+			return nil, classify(err)
 		}
 		mechanics = append(mechanics, &mechanic)
 	}
 	if err := cursor.Err(); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Cursor error")
-		return nil, err
+		return nil, classify(err)
 	}
 	span.SetAttributes(
 		attribute.Int("mechanicCount", len(mechanics)),
@@ -166,69 +414,231 @@ func (r *MongoRepository) GetAllMechanics(ctx context.Context) ([]*MechanicModel
 	return mechanics, nil
 }
 
-// GetAllRepairs retrieves all repairs
-func (r *MongoRepository) GetAllRepairs(ctx context.Context) ([]*RepairModel, error) {
-	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetAllRepairs")
+// FindMechanicsInBox retrieves the mechanics located inside the bounding box
+func (r *MongoRepository) FindMechanicsInBox(ctx context.Context, box BoundingBox) ([]*MechanicModel, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindMechanicsInBox")
 	defer span.End()
 
-	var repairs []*RepairModel
-	cursor, err := r.RepairCollection.Find(ctx, bson.M{})
+	query := bson.M{
+		"location.latitude":  bson.M{"$gte": box.MinLatitude, "$lte": box.MaxLatitude},
+		"location.longitude": bson.M{"$gte": box.MinLongitude, "$lte": box.MaxLongitude},
+	}
+	cursor, err := r.MechanicCollection.Find(ctx, query)
 	if err != nil {
 		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to find repairs")
-		return nil, fmt.Errorf("failed to find repairs: %v", err)
+		span.SetStatus(codes.Error, "Failed to find mechanics")
+		return nil, fmt.Errorf("failed to find mechanics in box: %w", classify(err))
 	}
 	defer cursor.Close(ctx)
 
-	for cursor.Next(ctx) {
-		var repair RepairModel
-		if err := cursor.Decode(&repair); err != nil {
+	var mechanics []*MechanicModel
+	if err := cursor.All(ctx, &mechanics); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode mechanics")
+		return nil, fmt.Errorf("failed to decode mechanics in box: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("mechanicCount", len(mechanics)))
+	return mechanics, nil
+}
+
+// GetMechanicByID retrieves a mechanic by ID
+func (r *MongoRepository) GetMechanicByID(ctx context.Context, id string) (*MechanicModel, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetMechanicByID")
+	defer span.End()
+
+	var mechanic MechanicModel
+	if err := r.MechanicCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&mechanic); err != nil {
+		if err != mongo.ErrNoDocuments {
 			span.RecordError(err)
-			span.SetStatus(codes.Error, "Failed to decode repair")
-			return nil, fmt.Errorf("failed to decode repair: %v", err)
+			span.SetStatus(codes.Error, "Failed to find mechanic")
 		}
-		repairs = append(repairs, &repair)
+		return nil, classify(err)
 	}
-	if err := cursor.Err(); err != nil {
+	span.SetAttributes(attribute.String("mechanicID", id))
+	return &mechanic, nil
+}
+
+// CountActiveAssignments returns the number of pending or in-progress repairs
+// assigned to each of the given mechanics
+func (r *MongoRepository) CountActiveAssignments(ctx context.Context, mechanicIDs []string) (map[string]int, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoCountActiveAssignments")
+	defer span.End()
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: bson.M{
+			"assignedTo": bson.M{"$in": mechanicIDs},
+			"status":     bson.M{"$in": []string{"pending", "in_progress"}},
+		}}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": "$assignedTo", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := r.RepairCollection.Aggregate(ctx, pipeline)
+	if err != nil {
 		span.RecordError(err)
-		span.SetStatus(codes.Error, "Cursor error")
-		return nil, fmt.Errorf("cursor error: %v", err)
+		span.SetStatus(codes.Error, "Failed to count active assignments")
+		return nil, fmt.Errorf("failed to count active assignments: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		MechanicID string `bson:"_id"`
+		Count      int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode active assignments")
+		return nil, fmt.Errorf("failed to decode active assignments: %w", classify(err))
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.MechanicID] = row.Count
+	}
+	span.SetAttributes(attribute.Int("busyMechanicCount", len(counts)))
+	return counts, nil
+}
+
+// FindRepairs retrieves repairs matching the filter
+func (r *MongoRepository) FindRepairs(ctx context.Context, filter RepairFilter, opts *QueryOptions) ([]*RepairModel, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindRepairs")
+	defer span.End()
+
+	var repairs []*RepairModel
+	cursor, err := opts.find(ctx, r.retryAttempts, r.RepairCollection, repairFilterQuery(filter, ""), nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find repairs")
+		return nil, fmt.Errorf("failed to find repairs: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	if err := cursor.All(ctx, &repairs); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode repairs")
+		return nil, fmt.Errorf("failed to decode repairs: %w", classify(err))
 	}
 
 	span.SetAttributes(
 		attribute.Int("repairCount", len(repairs)),
 	)
+	return repairs, nil
+}
+
+// FindRepairLocations retrieves only the ID, status and user location of repairs
+// matching the filter, for map rendering
+func (r *MongoRepository) FindRepairLocations(ctx context.Context, filter RepairFilter) ([]*RepairModel, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindRepairLocations")
+	defer span.End()
+
+	query := repairFilterQuery(filter, "")
+	query = append(query, bson.E{Key: "repairCost.userLocation", Value: bson.M{"$ne": nil}})
+	projection := bson.M{"_id": 1, "status": 1, "createdAt": 1, "repairCost.userLocation": 1}
+	cursor, err := r.RepairCollection.Find(ctx, query, options.Find().SetProjection(projection))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find repair locations")
+		return nil, fmt.Errorf("failed to find repair locations: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
 
+	var repairs []*RepairModel
+	if err := cursor.All(ctx, &repairs); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode repair locations")
+		return nil, fmt.Errorf("failed to decode repair locations: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("repairCount", len(repairs)))
 	return repairs, nil
 }
 
-// WatchRepairs sets up a MongoDB change stream for repair insertions
-func (r *MongoRepository) WatchRepairs(ctx context.Context) (*mongo.ChangeStream, error) {
+// WatchRepairs opens a change stream for newly inserted repairs matching the filter
+func (r *MongoRepository) WatchRepairs(ctx context.Context, filter RepairFilter) (*mongo.ChangeStream, error) {
 	_, span := otel.Tracer("repair-service").Start(ctx, "MongoWatchRepairs")
 	defer span.End()
 
+	match := bson.D{{Key: "operationType", Value: "insert"}}
+	match = append(match, repairFilterQuery(filter, "fullDocument.")...)
 	pipeline := mongo.Pipeline{
-		bson.D{{Key: "$match", Value: bson.D{{Key: "operationType", Value: "insert"}}}},
+		bson.D{{Key: "$match", Value: match}},
 	}
 	changeStream, err := r.RepairCollection.Watch(ctx, pipeline, options.ChangeStream().SetFullDocument(options.UpdateLookup))
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to open change stream")
-		return nil, fmt.Errorf("failed to open change stream: %v", err)
+		return nil, fmt.Errorf("failed to open change stream: %w", classify(err))
+	}
+	return changeStream, nil
+}
+
+// WatchOutboxEvents opens a change stream for outbox events inserted for the
+// given regions (all when empty). Only the insert is signalled; the events are
+// read back with GetUnprocessedOutboxEvents.
+func (r *MongoRepository) WatchOutboxEvents(ctx context.Context, regions []string) (*mongo.ChangeStream, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoWatchOutboxEvents")
+	defer span.End()
+	span.SetAttributes(attribute.StringSlice("regions", regions))
+
+	match := bson.D{{Key: "operationType", Value: "insert"}}
+	if len(regions) > 0 {
+		in := bson.A{nil} // matches events without a region
+		for _, name := range regions {
+			in = append(in, name)
+		}
+		match = append(match, bson.E{Key: "fullDocument.region", Value: bson.M{"$in": in}})
+	}
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$project", Value: bson.M{"_id": 1, "documentKey": 1}}},
+	}
+	changeStream, err := r.OutboxCollection.Watch(ctx, pipeline)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to open outbox change stream")
+		return nil, fmt.Errorf("failed to open outbox change stream: %w", classify(err))
 	}
 	return changeStream, nil
 }
 
+// repairFilterQuery builds the Mongo match for a RepairFilter. prefix is
+// prepended to every field path, e.g. "fullDocument." inside change streams.
+func repairFilterQuery(filter RepairFilter, prefix string) bson.D {
+	query := bson.D{}
+	if len(filter.Statuses) > 0 {
+		query = append(query, bson.E{Key: prefix + "status", Value: bson.M{"$in": filter.Statuses}})
+	}
+	if filter.UserID != "" {
+		query = append(query, bson.E{Key: prefix + "userID", Value: filter.UserID})
+	}
+	if len(filter.RepairTypes) > 0 {
+		query = append(query, bson.E{Key: prefix + "repairCost.repairType", Value: bson.M{"$in": filter.RepairTypes}})
+	}
+	if box := filter.BoundingBox; box != nil {
+		query = append(query,
+			bson.E{Key: prefix + "repairCost.userLocation.longitude", Value: bson.M{"$gte": box.MinLongitude, "$lte": box.MaxLongitude}},
+			bson.E{Key: prefix + "repairCost.userLocation.latitude", Value: bson.M{"$gte": box.MinLatitude, "$lte": box.MaxLatitude}},
+		)
+	}
+	if !filter.Since.IsZero() {
+		query = append(query, bson.E{Key: prefix + "createdAt", Value: bson.M{"$gte": filter.Since}})
+	}
+	if len(filter.Tags) > 0 {
+		query = append(query, bson.E{Key: prefix + "tags", Value: bson.M{"$all": filter.Tags}})
+	}
+	if len(filter.IDs) > 0 {
+		query = append(query, bson.E{Key: prefix + "_id", Value: bson.M{"$in": filter.IDs}})
+	}
+	return query
+}
+
 // SaveOutboxEvent saves an event to the outbox collection
-func (r *MongoRepository) SaveOutboxEvent(ctx context.Context, session mongo.SessionContext, event *OutboxEvent) error {
+func (r *MongoRepository) SaveOutboxEvent(ctx context.Context, event *OutboxEvent) error {
 	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveOutboxEvent")
 	defer span.End()
 
-	_, err := r.OutboxCollection.InsertOne(session, event)
+	_, err := r.OutboxCollection.InsertOne(ctx, event)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to save outbox event")
-		return err
+		return classify(err)
 	}
 	span.SetAttributes(
 		attribute.String("eventID", event.ID),
@@ -237,17 +647,95 @@ func (r *MongoRepository) SaveOutboxEvent(ctx context.Context, session mongo.Ses
 	return nil
 }
 
-// GetUnprocessedOutboxEvents retrieves unprocessed outbox events
-func (r *MongoRepository) GetUnprocessedOutboxEvents(ctx context.Context) ([]*OutboxEvent, error) {
+// CountRepairsByStatus counts all repairs grouped by status
+func (r *MongoRepository) CountRepairsByStatus(ctx context.Context) (map[string]int64, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoCountRepairsByStatus")
+	defer span.End()
+
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$group", Value: bson.M{"_id": "$status", "count": bson.M{"$sum": 1}}}},
+	}
+	cursor, err := r.RepairCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to count repairs by status")
+		return nil, fmt.Errorf("failed to count repairs by status: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		Status string `bson:"_id"`
+		Count  int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode repair counts")
+		return nil, fmt.Errorf("failed to decode repair counts: %w", classify(err))
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	span.SetAttributes(attribute.Int("statusCount", len(counts)))
+	return counts, nil
+}
+
+// GetOutboxBacklog counts unprocessed outbox events and finds the oldest one
+func (r *MongoRepository) GetOutboxBacklog(ctx context.Context) (*OutboxBacklog, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetOutboxBacklog")
+	defer span.End()
+
+	filter := bson.M{"processed": false}
+	count, err := r.OutboxCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to count unprocessed outbox events")
+		return nil, fmt.Errorf("failed to count unprocessed outbox events: %w", classify(err))
+	}
+	backlog := &OutboxBacklog{Unprocessed: count}
+	if count == 0 {
+		return backlog, nil
+	}
+
+	var oldest OutboxEvent
+	opts := options.FindOne().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetProjection(bson.M{"created_at": 1})
+	err = r.OutboxCollection.FindOne(ctx, filter, opts).Decode(&oldest)
+	if err != nil && err != mongo.ErrNoDocuments {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find oldest outbox event")
+		return nil, fmt.Errorf("failed to find oldest outbox event: %w", classify(err))
+	}
+	if err == nil {
+		backlog.Oldest = &oldest.CreatedAt
+	}
+	span.SetAttributes(attribute.Int64("unprocessed", count))
+	return backlog, nil
+}
+
+// GetUnprocessedOutboxEvents retrieves unprocessed outbox events. Given
+// regions, it only returns events of those regions and events without one.
+func (r *MongoRepository) GetUnprocessedOutboxEvents(ctx context.Context, regions []string) ([]*OutboxEvent, error) {
 	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetUnprocessedOutboxEvents")
 	defer span.End()
+	span.SetAttributes(attribute.StringSlice("regions", regions))
+
+	filter := bson.M{"processed": false}
+	if len(regions) > 0 {
+		in := bson.A{nil} // matches events without a region
+		for _, name := range regions {
+			in = append(in, name)
+		}
+		filter["region"] = bson.M{"$in": in}
+	}
 
 	var events []*OutboxEvent
-	cursor, err := r.OutboxCollection.Find(ctx, bson.M{"processed": false})
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}})
+	cursor, err := r.OutboxCollection.Find(ctx, filter, opts)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to find unprocessed outbox events")
-		return nil, fmt.Errorf("failed to find unprocessed outbox events: %v", err)
+		return nil, fmt.Errorf("failed to find unprocessed outbox events: %w", classify(err))
 	}
 	defer cursor.Close(ctx)
 
@@ -256,14 +744,14 @@ func (r *MongoRepository) GetUnprocessedOutboxEvents(ctx context.Context) ([]*Ou
 		if err := cursor.Decode(&event); err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, "Failed to decode outbox event")
-			return nil, fmt.Errorf("failed to decode outbox event: %v", err)
+			return nil, fmt.Errorf("failed to decode outbox event: %w", classify(err))
 		}
 		events = append(events, &event)
 	}
 	if err := cursor.Err(); err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Cursor error")
-		return nil, fmt.Errorf("cursor error: %v", err)
+		return nil, fmt.Errorf("cursor error: %w", classify(err))
 	}
 
 	span.SetAttributes(
@@ -287,10 +775,1601 @@ func (r *MongoRepository) MarkOutboxEventProcessed(ctx context.Context, eventID
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to mark outbox event as processed")
-		return err
+		return classify(err)
 	}
 	span.SetAttributes(
 		attribute.String("eventID", eventID),
 	)
 	return nil
 }
+
+// RedriveOutboxEvents queues the processed outbox events selected by req for
+// publishing again: reset mode marks them unprocessed, clone mode inserts
+// unprocessed copies pointing back at them. It returns the selected event IDs
+// and, in clone mode, the IDs of the copies.
+func (r *MongoRepository) RedriveOutboxEvents(ctx context.Context, req *OutboxRedriveRequest) (eventIDs, cloneIDs []string, err error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoRedriveOutboxEvents")
+	defer span.End()
+	span.SetAttributes(attribute.String("mode", req.Mode))
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: 1}}).SetLimit(MaxRedriveEvents + 1)
+	cursor, err := r.OutboxCollection.Find(ctx, req.Filter(), opts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find outbox events to redrive")
+		return nil, nil, fmt.Errorf("failed to find outbox events to redrive: %w", classify(err))
+	}
+	events := []*OutboxEvent{}
+	if err := cursor.All(ctx, &events); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode outbox events to redrive")
+		return nil, nil, fmt.Errorf("failed to decode outbox events to redrive: %w", classify(err))
+	}
+	if len(events) == 0 {
+		return nil, nil, fmt.Errorf("no processed outbox events match: %w", mongo.ErrNoDocuments)
+	}
+	if len(events) > MaxRedriveEvents {
+		return nil, nil, fmt.Errorf("%w: more than %d events match, narrow the selection", ErrInvalidInput, MaxRedriveEvents)
+	}
+
+	eventIDs = make([]string, len(events))
+	for i, event := range events {
+		eventIDs[i] = event.ID
+	}
+	span.SetAttributes(attribute.Int("eventCount", len(eventIDs)))
+
+	if req.Mode == RedriveClone {
+		now := time.Now()
+		clones := make([]interface{}, len(events))
+		cloneIDs = make([]string, len(events))
+		for i, event := range events {
+			cloneIDs[i] = primitive.NewObjectID().Hex()
+			clones[i] = &OutboxEvent{
+				ID:          cloneIDs[i],
+				EventType:   event.EventType,
+				AggregateID: event.AggregateID,
+				Payload:     event.Payload,
+				CreatedAt:   now,
+				RedriveOf:   event.ID,
+				Topic:       event.Topic,
+			}
+		}
+		if _, err := r.OutboxCollection.InsertMany(ctx, clones); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to clone outbox events")
+			return nil, nil, fmt.Errorf("failed to clone outbox events: %w", classify(err))
+		}
+		return eventIDs, cloneIDs, nil
+	}
+
+	_, err = r.OutboxCollection.UpdateMany(ctx, bson.M{"_id": bson.M{"$in": eventIDs}, "processed": true}, bson.M{
+		"$set":   bson.M{"processed": false},
+		"$unset": bson.M{"processed_at": ""},
+		"$inc":   bson.M{"redrive_count": 1},
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to reset outbox events")
+		return nil, nil, fmt.Errorf("failed to reset outbox events: %w", classify(err))
+	}
+	return eventIDs, nil, nil
+}
+
+// SaveOutboxRedrive stores the audit record of a redrive
+func (r *MongoRepository) SaveOutboxRedrive(ctx context.Context, redrive *OutboxRedrive) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveOutboxRedrive")
+	defer span.End()
+	span.SetAttributes(attribute.String("redriveID", redrive.ID))
+
+	if _, err := r.RedriveCollection.InsertOne(ctx, redrive); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save outbox redrive")
+		return fmt.Errorf("failed to save outbox redrive: %w", classify(err))
+	}
+	return nil
+}
+
+// FindOutboxRedrives lists the audit records of past redrives, newest first
+func (r *MongoRepository) FindOutboxRedrives(ctx context.Context, opts *QueryOptions) ([]*OutboxRedrive, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindOutboxRedrives")
+	defer span.End()
+
+	cursor, err := opts.find(ctx, r.retryAttempts, r.RedriveCollection, bson.M{}, bson.D{{Key: "requestedAt", Value: -1}})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find outbox redrives")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	redrives := []*OutboxRedrive{}
+	if err := cursor.All(ctx, &redrives); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode outbox redrives")
+		return nil, classify(err)
+	}
+	span.SetAttributes(attribute.Int("redriveCount", len(redrives)))
+	return redrives, nil
+}
+
+// GetQuestionnaire retrieves the intake questionnaire for a repair type
+func (r *MongoRepository) GetQuestionnaire(ctx context.Context, repairType string) (*Questionnaire, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetQuestionnaire")
+	defer span.End()
+
+	var questionnaire Questionnaire
+	err := r.QuestionnaireCollection.FindOne(ctx, bson.M{"_id": repairType}).Decode(&questionnaire)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find questionnaire")
+		return nil, classify(err)
+	}
+	span.SetAttributes(
+		attribute.String("repairType", repairType),
+		attribute.Int("questionCount", len(questionnaire.Questions)),
+	)
+	return &questionnaire, nil
+}
+
+// UpsertQuestionnaire creates or replaces the intake questionnaire for a repair type
+func (r *MongoRepository) UpsertQuestionnaire(ctx context.Context, questionnaire *Questionnaire) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoUpsertQuestionnaire")
+	defer span.End()
+
+	_, err := r.QuestionnaireCollection.ReplaceOne(ctx, bson.M{"_id": questionnaire.RepairType}, questionnaire, options.Replace().SetUpsert(true))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to upsert questionnaire")
+		return classify(err)
+	}
+	span.SetAttributes(
+		attribute.String("repairType", questionnaire.RepairType),
+		attribute.Int("questionCount", len(questionnaire.Questions)),
+	)
+	return nil
+}
+
+// FindPricingRules returns every pricing rule, enabled or not, in evaluation order
+func (r *MongoRepository) FindPricingRules(ctx context.Context) ([]*PricingRule, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindPricingRules")
+	defer span.End()
+
+	rules := []*PricingRule{}
+	cursor, err := r.PricingRuleCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "priority", Value: 1}, {Key: "_id", Value: 1}}))
+	if err == nil {
+		defer cursor.Close(ctx)
+		err = cursor.All(ctx, &rules)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find pricing rules")
+		return nil, fmt.Errorf("failed to find pricing rules: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("ruleCount", len(rules)))
+	return rules, nil
+}
+
+// UpsertPricingRule creates or replaces a pricing rule
+func (r *MongoRepository) UpsertPricingRule(ctx context.Context, rule *PricingRule) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoUpsertPricingRule")
+	defer span.End()
+	span.SetAttributes(attribute.String("ruleID", rule.ID))
+
+	_, err := r.PricingRuleCollection.ReplaceOne(ctx, bson.M{"_id": rule.ID}, rule, options.Replace().SetUpsert(true))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to upsert pricing rule")
+		return classify(err)
+	}
+	return nil
+}
+
+// DeletePricingRule removes a pricing rule, returning mongo.ErrNoDocuments
+// when it does not exist
+func (r *MongoRepository) DeletePricingRule(ctx context.Context, ruleID string) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoDeletePricingRule")
+	defer span.End()
+	span.SetAttributes(attribute.String("ruleID", ruleID))
+
+	result, err := r.PricingRuleCollection.DeleteOne(ctx, bson.M{"_id": ruleID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to delete pricing rule")
+		return classify(err)
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// GetWeatherFlag returns the current weather flag, empty when never set
+func (r *MongoRepository) GetWeatherFlag(ctx context.Context) (*WeatherFlag, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetWeatherFlag")
+	defer span.End()
+
+	var flag WeatherFlag
+	err := r.PricingSettings.FindOne(ctx, bson.M{"_id": "weather"}).Decode(&flag)
+	if err == mongo.ErrNoDocuments {
+		return &flag, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get weather flag")
+		return nil, fmt.Errorf("failed to get weather flag: %w", classify(err))
+	}
+	return &flag, nil
+}
+
+// SaveWeatherFlag stores the current weather flag
+func (r *MongoRepository) SaveWeatherFlag(ctx context.Context, flag *WeatherFlag) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveWeatherFlag")
+	defer span.End()
+	span.SetAttributes(attribute.String("flag", flag.Flag))
+
+	_, err := r.PricingSettings.ReplaceOne(ctx, bson.M{"_id": "weather"}, flag, options.Replace().SetUpsert(true))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save weather flag")
+		return fmt.Errorf("failed to save weather flag: %w", classify(err))
+	}
+	return nil
+}
+
+// SaveBlock creates or updates the block between block.UserID and block.MechanicID
+// and returns the stored relation
+func (r *MongoRepository) SaveBlock(ctx context.Context, block *Block) (*Block, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveBlock")
+	defer span.End()
+
+	filter := bson.M{"userID": block.UserID, "mechanicID": block.MechanicID}
+	update := bson.M{
+		"$set":         bson.M{"blockedBy": block.BlockedBy, "reason": block.Reason},
+		"$setOnInsert": bson.M{"_id": primitive.NewObjectID().Hex(), "createdAt": block.CreatedAt},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var saved Block
+	if err := r.BlockCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&saved); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save block")
+		return nil, classify(err)
+	}
+	span.SetAttributes(
+		attribute.String("userID", block.UserID),
+		attribute.String("mechanicID", block.MechanicID),
+	)
+	return &saved, nil
+}
+
+// DeleteBlock removes the block between userID and mechanicID, returning
+// mongo.ErrNoDocuments if there was none
+func (r *MongoRepository) DeleteBlock(ctx context.Context, userID, mechanicID string) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoDeleteBlock")
+	defer span.End()
+
+	result, err := r.BlockCollection.DeleteOne(ctx, bson.M{"userID": userID, "mechanicID": mechanicID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to delete block")
+		return classify(err)
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	span.SetAttributes(
+		attribute.String("userID", userID),
+		attribute.String("mechanicID", mechanicID),
+	)
+	return nil
+}
+
+// FindBlocks returns every block relation of a user, including an admin blacklist
+func (r *MongoRepository) FindBlocks(ctx context.Context, userID string, opts *QueryOptions) ([]*Block, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindBlocks")
+	defer span.End()
+
+	cursor, err := opts.find(ctx, r.retryAttempts, r.BlockCollection, bson.M{"userID": userID}, bson.D{{Key: "createdAt", Value: 1}})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find blocks")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	blocks := []*Block{}
+	if err := cursor.All(ctx, &blocks); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode blocks")
+		return nil, classify(err)
+	}
+	span.SetAttributes(
+		attribute.String("userID", userID),
+		attribute.Int("blockCount", len(blocks)),
+	)
+	return blocks, nil
+}
+
+// AbsentMechanicIDs returns the mechanics with an absence covering at, read
+// from the mechanic_absences collection mechanic-service writes
+func (r *MongoRepository) AbsentMechanicIDs(ctx context.Context, at time.Time) (map[string]bool, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoAbsentMechanicIDs")
+	defer span.End()
+
+	ids, err := r.AbsenceCollection.Distinct(ctx, "mechanicID", bson.M{
+		"start": bson.M{"$lte": at},
+		"end":   bson.M{"$gt": at},
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find absent mechanics")
+		return nil, classify(err)
+	}
+	absent := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if mechanicID, ok := id.(string); ok {
+			absent[mechanicID] = true
+		}
+	}
+	span.SetAttributes(attribute.Int("absentCount", len(absent)))
+	return absent, nil
+}
+
+// AssignmentCounts sums the daily assignment counters mechanic-service keeps
+// per mechanic from the day of since on. A nil mechanicIDs counts every
+// mechanic.
+func (r *MongoRepository) AssignmentCounts(ctx context.Context, mechanicIDs []string, since time.Time) (map[string]int, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoAssignmentCounts")
+	defer span.End()
+
+	since = since.UTC()
+	match := bson.M{"day": bson.M{"$gte": time.Date(since.Year(), since.Month(), since.Day(), 0, 0, 0, 0, time.UTC)}}
+	if mechanicIDs != nil {
+		match["mechanicID"] = bson.M{"$in": mechanicIDs}
+	}
+	pipeline := mongo.Pipeline{
+		bson.D{{Key: "$match", Value: match}},
+		bson.D{{Key: "$group", Value: bson.M{"_id": "$mechanicID", "count": bson.M{"$sum": "$count"}}}},
+	}
+	cursor, err := r.AssignmentCounters.Aggregate(ctx, pipeline)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to count assignments")
+		return nil, fmt.Errorf("failed to count assignments: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		MechanicID string `bson:"_id"`
+		Count      int    `bson:"count"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode assignment counts")
+		return nil, fmt.Errorf("failed to decode assignment counts: %w", classify(err))
+	}
+
+	counts := make(map[string]int, len(rows))
+	for _, row := range rows {
+		counts[row.MechanicID] = row.Count
+	}
+	span.SetAttributes(attribute.Int("mechanicCount", len(counts)))
+	return counts, nil
+}
+
+// GetFairnessPolicy returns the stored fairness policy, nil when never set
+func (r *MongoRepository) GetFairnessPolicy(ctx context.Context) (*FairnessPolicy, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetFairnessPolicy")
+	defer span.End()
+
+	var policy FairnessPolicy
+	err := r.DispatchSettings.FindOne(ctx, bson.M{"_id": "fairness"}).Decode(&policy)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get fairness policy")
+		return nil, fmt.Errorf("failed to get fairness policy: %w", classify(err))
+	}
+	return &policy, nil
+}
+
+// SaveFairnessPolicy stores the fairness policy
+func (r *MongoRepository) SaveFairnessPolicy(ctx context.Context, policy *FairnessPolicy) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveFairnessPolicy")
+	defer span.End()
+	span.SetAttributes(attribute.Bool("enabled", policy.Enabled))
+
+	_, err := r.DispatchSettings.ReplaceOne(ctx, bson.M{"_id": "fairness"}, policy, options.Replace().SetUpsert(true))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save fairness policy")
+		return fmt.Errorf("failed to save fairness policy: %w", classify(err))
+	}
+	return nil
+}
+
+// SaveAnonymousQuote inserts an estimate made without a userID
+func (r *MongoRepository) SaveAnonymousQuote(ctx context.Context, quote *AnonymousQuote) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveAnonymousQuote")
+	defer span.End()
+
+	if _, err := r.AnonymousQuotes.InsertOne(ctx, quote); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to insert anonymous quote")
+		return fmt.Errorf("failed to insert anonymous quote: %w", classify(err))
+	}
+	span.SetAttributes(attribute.String("costID", quote.ID))
+	return nil
+}
+
+// GetAnonymousQuote retrieves an anonymous quote by ID
+func (r *MongoRepository) GetAnonymousQuote(ctx context.Context, id string) (*AnonymousQuote, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetAnonymousQuote")
+	defer span.End()
+
+	var quote AnonymousQuote
+	if err := r.AnonymousQuotes.FindOne(ctx, bson.M{"_id": id}).Decode(&quote); err != nil {
+		if err != mongo.ErrNoDocuments {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to find anonymous quote")
+		}
+		return nil, classify(err)
+	}
+	span.SetAttributes(attribute.String("costID", id))
+	return &quote, nil
+}
+
+// ClaimAnonymousQuote binds an unclaimed anonymous quote to userID. Claiming
+// a quote the same user already holds succeeds again; a quote held by another
+// user returns ErrQuoteClaimed.
+func (r *MongoRepository) ClaimAnonymousQuote(ctx context.Context, id, userID string) (*AnonymousQuote, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoClaimAnonymousQuote")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("costID", id),
+		attribute.String("userID", userID),
+	)
+
+	now := time.Now()
+	filter := bson.M{
+		"_id":       id,
+		"claimedBy": bson.M{"$in": bson.A{nil, "", userID}},
+	}
+	update := bson.M{"$set": bson.M{"claimedBy": userID, "claimedAt": now}}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var quote AnonymousQuote
+	err := r.AnonymousQuotes.FindOneAndUpdate(ctx, filter, update, opts).Decode(&quote)
+	if err == mongo.ErrNoDocuments {
+		// Tell a missing or expired quote apart from one claimed by someone else
+		if err := r.AnonymousQuotes.FindOne(ctx, bson.M{"_id": id}).Err(); err != nil {
+			return nil, classify(err)
+		}
+		span.SetStatus(codes.Error, "Quote already claimed")
+		return nil, ErrQuoteClaimed
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to claim anonymous quote")
+		return nil, fmt.Errorf("failed to claim anonymous quote: %w", classify(err))
+	}
+	return &quote, nil
+}
+
+// SaveReceipt stores a repair's receipt unless one already exists and returns
+// the stored receipt, so a receipt is never rewritten once issued
+func (r *MongoRepository) SaveReceipt(ctx context.Context, receipt *Receipt) (*Receipt, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveReceipt")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", receipt.RepairID))
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var stored Receipt
+	err := r.ReceiptCollection.FindOneAndUpdate(ctx, bson.M{"_id": receipt.RepairID}, bson.M{"$setOnInsert": receipt}, opts).Decode(&stored)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save receipt")
+		return nil, fmt.Errorf("failed to save receipt: %w", classify(err))
+	}
+	return &stored, nil
+}
+
+// GetReceipt retrieves the receipt of a repair
+func (r *MongoRepository) GetReceipt(ctx context.Context, repairID string) (*Receipt, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetReceipt")
+	defer span.End()
+
+	var receipt Receipt
+	if err := r.ReceiptCollection.FindOne(ctx, bson.M{"_id": repairID}).Decode(&receipt); err != nil {
+		if err != mongo.ErrNoDocuments {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to find receipt")
+		}
+		return nil, classify(err)
+	}
+	span.SetAttributes(attribute.String("repairID", repairID))
+	return &receipt, nil
+}
+
+// AddRepairTag adds tag to a repair unless it is already there and returns the repair's tags
+func (r *MongoRepository) AddRepairTag(ctx context.Context, repairID, tag string) ([]string, error) {
+	return r.updateRepairTags(ctx, "MongoAddRepairTag", repairID, bson.M{"$addToSet": bson.M{"tags": tag}})
+}
+
+// RemoveRepairTag removes tag from a repair and returns the repair's remaining tags
+func (r *MongoRepository) RemoveRepairTag(ctx context.Context, repairID, tag string) ([]string, error) {
+	return r.updateRepairTags(ctx, "MongoRemoveRepairTag", repairID, bson.M{"$pull": bson.M{"tags": tag}})
+}
+
+func (r *MongoRepository) updateRepairTags(ctx context.Context, spanName, repairID string, update bson.M) ([]string, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, spanName)
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", repairID))
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"tags": 1})
+	var repair RepairModel
+	if err := r.RepairCollection.FindOneAndUpdate(ctx, bson.M{"_id": repairID}, update, opts).Decode(&repair); err != nil {
+		if err != mongo.ErrNoDocuments {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to update repair tags")
+			return nil, fmt.Errorf("failed to update repair tags: %w", classify(err))
+		}
+		return nil, classify(err)
+	}
+	if repair.Tags == nil {
+		repair.Tags = []string{}
+	}
+	return repair.Tags, nil
+}
+
+// SaveRepairNote inserts an internal note
+func (r *MongoRepository) SaveRepairNote(ctx context.Context, note *RepairNote) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveRepairNote")
+	defer span.End()
+
+	if note.ID == "" {
+		note.ID = primitive.NewObjectID().Hex()
+	}
+	if _, err := r.NoteCollection.InsertOne(ctx, note); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to insert repair note")
+		return fmt.Errorf("failed to insert repair note: %w", classify(err))
+	}
+	span.SetAttributes(
+		attribute.String("repairID", note.RepairID),
+		attribute.String("noteID", note.ID),
+	)
+	return nil
+}
+
+// DeleteRepairNote removes a note from a repair. It returns
+// mongo.ErrNoDocuments if the repair has no such note.
+func (r *MongoRepository) DeleteRepairNote(ctx context.Context, repairID, noteID string) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoDeleteRepairNote")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("repairID", repairID),
+		attribute.String("noteID", noteID),
+	)
+
+	result, err := r.NoteCollection.DeleteOne(ctx, bson.M{"_id": noteID, "repairID": repairID})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to delete repair note")
+		return fmt.Errorf("failed to delete repair note: %w", classify(err))
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	return nil
+}
+
+// FindRepairNotes lists a repair's notes, oldest first
+func (r *MongoRepository) FindRepairNotes(ctx context.Context, repairID string, opts *QueryOptions) ([]*RepairNote, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindRepairNotes")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", repairID))
+
+	cursor, err := opts.find(ctx, r.retryAttempts, r.NoteCollection, bson.M{"repairID": repairID}, bson.D{{Key: "createdAt", Value: 1}})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find repair notes")
+		return nil, fmt.Errorf("failed to find repair notes: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	notes := []*RepairNote{}
+	if err := cursor.All(ctx, &notes); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode repair notes")
+		return nil, fmt.Errorf("failed to decode repair notes: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("noteCount", len(notes)))
+	return notes, nil
+}
+
+// SaveAmendment inserts a requested amendment
+func (r *MongoRepository) SaveAmendment(ctx context.Context, amendment *Amendment) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveAmendment")
+	defer span.End()
+
+	if amendment.ID == "" {
+		amendment.ID = primitive.NewObjectID().Hex()
+	}
+	if _, err := r.AmendmentCollection.InsertOne(ctx, amendment); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to insert amendment")
+		return fmt.Errorf("failed to insert amendment: %w", classify(err))
+	}
+	span.SetAttributes(
+		attribute.String("repairID", amendment.RepairID),
+		attribute.String("amendmentID", amendment.ID),
+	)
+	return nil
+}
+
+// DecideAmendment moves a pending amendment to status and returns it. It
+// returns mongo.ErrNoDocuments if the repair has no such pending amendment,
+// so an amendment is decided at most once.
+func (r *MongoRepository) DecideAmendment(ctx context.Context, repairID, amendmentID, status string, decidedAt time.Time) (*Amendment, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoDecideAmendment")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("repairID", repairID),
+		attribute.String("amendmentID", amendmentID),
+		attribute.String("status", status),
+	)
+
+	var amendment Amendment
+	err := r.AmendmentCollection.FindOneAndUpdate(ctx,
+		bson.M{"_id": amendmentID, "repairID": repairID, "status": AmendmentPending},
+		bson.M{"$set": bson.M{"status": status, "decidedAt": decidedAt}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&amendment)
+	if err != nil {
+		if err != mongo.ErrNoDocuments {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to decide amendment")
+			return nil, fmt.Errorf("failed to decide amendment: %w", classify(err))
+		}
+		return nil, classify(err)
+	}
+	return &amendment, nil
+}
+
+// FindAmendments lists a repair's amendments, oldest first
+func (r *MongoRepository) FindAmendments(ctx context.Context, repairID string) ([]*Amendment, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindAmendments")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", repairID))
+
+	cursor, err := r.AmendmentCollection.Find(ctx, bson.M{"repairID": repairID}, options.Find().SetSort(bson.D{{Key: "requestedAt", Value: 1}}))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find amendments")
+		return nil, fmt.Errorf("failed to find amendments: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	amendments := []*Amendment{}
+	if err := cursor.All(ctx, &amendments); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode amendments")
+		return nil, fmt.Errorf("failed to decode amendments: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("amendmentCount", len(amendments)))
+	return amendments, nil
+}
+
+// GetPhoneVerification retrieves a user's phone verification
+func (r *MongoRepository) GetPhoneVerification(ctx context.Context, userID string) (*PhoneVerification, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetPhoneVerification")
+	defer span.End()
+	span.SetAttributes(attribute.String("userID", userID))
+
+	var verification PhoneVerification
+	if err := r.PhoneCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&verification); err != nil {
+		if err != mongo.ErrNoDocuments {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to find phone verification")
+		}
+		return nil, classify(err)
+	}
+	return &verification, nil
+}
+
+// SavePhoneVerification creates or replaces a user's phone verification
+func (r *MongoRepository) SavePhoneVerification(ctx context.Context, verification *PhoneVerification) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSavePhoneVerification")
+	defer span.End()
+	span.SetAttributes(attribute.String("userID", verification.UserID))
+
+	_, err := r.PhoneCollection.ReplaceOne(ctx, bson.M{"_id": verification.UserID}, verification, options.Replace().SetUpsert(true))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save phone verification")
+		return fmt.Errorf("failed to save phone verification: %w", classify(err))
+	}
+	return nil
+}
+
+// findAll decodes every document of coll matching filter into out, running
+// the query up to attempts times while it fails transiently
+func findAll(ctx context.Context, attempts int, coll *mongo.Collection, filter bson.M, out interface{}) error {
+	var cursor *mongo.Cursor
+	err := retry(ctx, attempts, isTransient, func() error {
+		var err error
+		cursor, err = coll.Find(ctx, filter)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to query %s: %v", coll.Name(), err)
+	}
+	defer cursor.Close(ctx)
+	if err := cursor.All(ctx, out); err != nil {
+		return fmt.Errorf("failed to decode %s: %v", coll.Name(), err)
+	}
+	return nil
+}
+
+// ExportUserData collects a user's documents from every collection holding them
+func (r *MongoRepository) ExportUserData(ctx context.Context, userID string) (*UserDataExport, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoExportUserData")
+	defer span.End()
+	span.SetAttributes(attribute.String("userID", userID))
+
+	export := &UserDataExport{
+		UserID:        userID,
+		Repairs:       []*RepairModel{},
+		RepairCosts:   []*RepairCostModel{},
+		ClaimedQuotes: []*AnonymousQuote{},
+		Receipts:      []*Receipt{},
+		Amendments:    []*Amendment{},
+		Blocks:        []*Block{},
+		Emails:        []*EmailDelivery{},
+	}
+	queries := []struct {
+		coll   *mongo.Collection
+		filter bson.M
+		out    interface{}
+	}{
+		{r.RepairCollection, bson.M{"userID": userID}, &export.Repairs},
+		{r.CostCollection, bson.M{"userID": userID}, &export.RepairCosts},
+		{r.AnonymousQuotes, bson.M{"claimedBy": userID}, &export.ClaimedQuotes},
+		{r.ReceiptCollection, bson.M{"userID": userID}, &export.Receipts},
+		{r.AmendmentCollection, bson.M{"userID": userID}, &export.Amendments},
+		{r.BlockCollection, bson.M{"userID": userID}, &export.Blocks},
+		{r.EmailDeliveries, bson.M{"userID": userID}, &export.Emails},
+	}
+	for _, q := range queries {
+		if err := findAll(ctx, r.retryAttempts, q.coll, q.filter, q.out); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to export user data")
+			return nil, err
+		}
+	}
+
+	var phone PhoneVerification
+	err := r.PhoneCollection.FindOne(ctx, bson.M{"_id": userID}).Decode(&phone)
+	switch {
+	case err == nil:
+		export.PhoneVerification = &phone
+	case err != mongo.ErrNoDocuments:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to export phone verification")
+		return nil, fmt.Errorf("failed to query phone_verifications: %w", classify(err))
+	}
+
+	var emailPreferences EmailPreferences
+	err = r.EmailPreferences.FindOne(ctx, bson.M{"_id": userID}).Decode(&emailPreferences)
+	switch {
+	case err == nil:
+		export.EmailPreferences = &emailPreferences
+	case err != mongo.ErrNoDocuments:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to export email preferences")
+		return nil, fmt.Errorf("failed to query email_preferences: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("repairCount", len(export.Repairs)))
+	return export, nil
+}
+
+// EraseUserData removes a user's personal data within ctx's transaction.
+// Repairs, costs, receipts, amendments and fleet invoice lines are kept for
+// accounting with the user ID replaced by pseudonym and locations and intake
+// answers removed; blocks, claimed quotes, the phone number, the email
+// address and sent emails, staff notes and already published outbox payloads
+// are deleted. It returns the per collection counts
+// and the IDs of the user's repairs.
+func (r *MongoRepository) EraseUserData(ctx context.Context, userID, pseudonym string) (anonymized, deleted map[string]int64, repairIDs []string, err error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoEraseUserData")
+	defer span.End()
+	span.SetAttributes(attribute.String("pseudonym", pseudonym))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to erase user data")
+		}
+	}()
+
+	var repairs []struct {
+		ID string `bson:"_id"`
+	}
+	if err := findAll(ctx, r.retryAttempts, r.RepairCollection, bson.M{"userID": userID}, &repairs); err != nil {
+		return nil, nil, nil, err
+	}
+	repairIDs = make([]string, len(repairs))
+	for i, repair := range repairs {
+		repairIDs[i] = repair.ID
+	}
+
+	anonymized = map[string]int64{}
+	updates := []struct {
+		coll   *mongo.Collection
+		filter bson.M
+		update bson.M
+	}{
+		{r.RepairCollection, bson.M{"userID": userID}, bson.M{
+			"$set":   bson.M{"userID": pseudonym, "repairCost.userID": pseudonym, "repairCost.userLocation": nil},
+			"$unset": bson.M{"symptoms": "", "geoLocation": ""},
+		}},
+		{r.CostCollection, bson.M{"userID": userID}, bson.M{"$set": bson.M{"userID": pseudonym, "userLocation": nil}}},
+		{r.ReceiptCollection, bson.M{"userID": userID}, bson.M{"$set": bson.M{"userID": pseudonym}}},
+		{r.AmendmentCollection, bson.M{"userID": userID}, bson.M{"$set": bson.M{"userID": pseudonym}}},
+	}
+	for _, u := range updates {
+		result, err := u.coll.UpdateMany(ctx, u.filter, u.update)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to anonymize %s: %v", u.coll.Name(), err)
+		}
+		anonymized[u.coll.Name()] = result.ModifiedCount
+	}
+	// Fleet invoices keep the user's lines under the pseudonym
+	result, err := r.FleetInvoices.UpdateMany(ctx, bson.M{"lineItems.userID": userID},
+		bson.M{"$set": bson.M{"lineItems.$[line].userID": pseudonym}},
+		options.Update().SetArrayFilters(options.ArrayFilters{Filters: []interface{}{bson.M{"line.userID": userID}}}))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to anonymize %s: %v", r.FleetInvoices.Name(), err)
+	}
+	anonymized[r.FleetInvoices.Name()] = result.ModifiedCount
+
+	deleted = map[string]int64{}
+	deletes := []struct {
+		coll   *mongo.Collection
+		filter bson.M
+	}{
+		{r.BlockCollection, bson.M{"userID": userID}},
+		{r.AnonymousQuotes, bson.M{"claimedBy": userID}},
+		{r.PhoneCollection, bson.M{"_id": userID}},
+		{r.EmailPreferences, bson.M{"_id": userID}},
+		{r.EmailDeliveries, bson.M{"userID": userID}},
+		{r.UndeliveredMessages, bson.M{"userID": userID}},
+		{r.MessageSequences, bson.M{"_id": userID}},
+		{r.NoteCollection, bson.M{"repairID": bson.M{"$in": repairIDs}}},
+		{r.OutboxCollection, bson.M{"aggregate_id": bson.M{"$in": repairIDs}, "processed": true}},
+	}
+	for _, d := range deletes {
+		result, err := d.coll.DeleteMany(ctx, d.filter)
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to delete from %s: %v", d.coll.Name(), err)
+		}
+		deleted[d.coll.Name()] = result.DeletedCount
+	}
+	span.SetAttributes(attribute.Int("repairCount", len(repairIDs)))
+	return anonymized, deleted, repairIDs, nil
+}
+
+// SaveErasureReport stores the report of a completed erasure
+func (r *MongoRepository) SaveErasureReport(ctx context.Context, report *ErasureReport) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveErasureReport")
+	defer span.End()
+	span.SetAttributes(attribute.String("reportID", report.ID))
+
+	if _, err := r.ErasureCollection.InsertOne(ctx, report); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save erasure report")
+		return fmt.Errorf("failed to save erasure report: %w", classify(err))
+	}
+	return nil
+}
+
+// FindErasureReports lists the erasure reports of a user ID hash, oldest first
+func (r *MongoRepository) FindErasureReports(ctx context.Context, userIDHash string) ([]*ErasureReport, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindErasureReports")
+	defer span.End()
+
+	reports := []*ErasureReport{}
+	cursor, err := r.ErasureCollection.Find(ctx, bson.M{"userIDHash": userIDHash}, options.Find().SetSort(bson.D{{Key: "requestedAt", Value: 1}}))
+	if err == nil {
+		defer cursor.Close(ctx)
+		err = cursor.All(ctx, &reports)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find erasure reports")
+		return nil, fmt.Errorf("failed to find erasure reports: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("reportCount", len(reports)))
+	return reports, nil
+}
+
+// GetEmailTemplate returns the stored email template, nil when never saved
+func (r *MongoRepository) GetEmailTemplate(ctx context.Context, name string) (*EmailTemplate, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetEmailTemplate")
+	defer span.End()
+	span.SetAttributes(attribute.String("template", name))
+
+	var tmpl EmailTemplate
+	err := r.EmailTemplates.FindOne(ctx, bson.M{"_id": name}).Decode(&tmpl)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get email template")
+		return nil, fmt.Errorf("failed to get email template: %w", classify(err))
+	}
+	return &tmpl, nil
+}
+
+// FindEmailTemplates lists the stored email templates
+func (r *MongoRepository) FindEmailTemplates(ctx context.Context) ([]*EmailTemplate, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindEmailTemplates")
+	defer span.End()
+
+	templates := []*EmailTemplate{}
+	if err := findAll(ctx, r.retryAttempts, r.EmailTemplates, bson.M{}, &templates); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find email templates")
+		return nil, err
+	}
+	return templates, nil
+}
+
+// SaveEmailTemplate creates or replaces an email template
+func (r *MongoRepository) SaveEmailTemplate(ctx context.Context, tmpl *EmailTemplate) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveEmailTemplate")
+	defer span.End()
+	span.SetAttributes(attribute.String("template", tmpl.Name))
+
+	_, err := r.EmailTemplates.ReplaceOne(ctx, bson.M{"_id": tmpl.Name}, tmpl, options.Replace().SetUpsert(true))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save email template")
+		return fmt.Errorf("failed to save email template: %w", classify(err))
+	}
+	return nil
+}
+
+// GetEmailPreferences returns a user's email preferences, nil when never set
+func (r *MongoRepository) GetEmailPreferences(ctx context.Context, userID string) (*EmailPreferences, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetEmailPreferences")
+	defer span.End()
+	span.SetAttributes(attribute.String("userID", userID))
+
+	var prefs EmailPreferences
+	err := r.EmailPreferences.FindOne(ctx, bson.M{"_id": userID}).Decode(&prefs)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get email preferences")
+		return nil, fmt.Errorf("failed to get email preferences: %w", classify(err))
+	}
+	return &prefs, nil
+}
+
+// SaveEmailPreferences creates or replaces a user's email preferences
+func (r *MongoRepository) SaveEmailPreferences(ctx context.Context, prefs *EmailPreferences) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveEmailPreferences")
+	defer span.End()
+	span.SetAttributes(attribute.String("userID", prefs.UserID))
+
+	_, err := r.EmailPreferences.ReplaceOne(ctx, bson.M{"_id": prefs.UserID}, prefs, options.Replace().SetUpsert(true))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save email preferences")
+		return fmt.Errorf("failed to save email preferences: %w", classify(err))
+	}
+	return nil
+}
+
+// SaveEmailDelivery queues an email within ctx's transaction
+func (r *MongoRepository) SaveEmailDelivery(ctx context.Context, delivery *EmailDelivery) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveEmailDelivery")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("deliveryID", delivery.ID),
+		attribute.String("template", delivery.Template),
+	)
+
+	if _, err := r.EmailDeliveries.InsertOne(ctx, delivery); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save email delivery")
+		return fmt.Errorf("failed to save email delivery: %w", classify(err))
+	}
+	return nil
+}
+
+// ClaimEmailDelivery takes the oldest pending email whose next attempt is
+// due and leases it until now+lease, so other instances skip it while it is
+// being sent. It returns nil when no email is due.
+func (r *MongoRepository) ClaimEmailDelivery(ctx context.Context, now time.Time, lease time.Duration) (*EmailDelivery, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoClaimEmailDelivery")
+	defer span.End()
+
+	filter := bson.M{
+		"status": EmailPending,
+		"$or": bson.A{
+			bson.M{"nextAttemptAt": bson.M{"$exists": false}},
+			bson.M{"nextAttemptAt": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"nextAttemptAt": now.Add(lease)}}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{Key: "createdAt", Value: 1}}).SetReturnDocument(options.After)
+
+	var delivery EmailDelivery
+	err := r.EmailDeliveries.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery)
+	if err == mongo.ErrNoDocuments {
+		return nil, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to claim email delivery")
+		return nil, fmt.Errorf("failed to claim email delivery: %w", classify(err))
+	}
+	span.SetAttributes(attribute.String("deliveryID", delivery.ID))
+	return &delivery, nil
+}
+
+// UpdateEmailDelivery stores the outcome of a delivery attempt
+func (r *MongoRepository) UpdateEmailDelivery(ctx context.Context, delivery *EmailDelivery) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoUpdateEmailDelivery")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("deliveryID", delivery.ID),
+		attribute.String("status", delivery.Status),
+	)
+
+	_, err := r.EmailDeliveries.ReplaceOne(ctx, bson.M{"_id": delivery.ID}, delivery)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to update email delivery")
+		return fmt.Errorf("failed to update email delivery: %w", classify(err))
+	}
+	return nil
+}
+
+// FindEmailDeliveries lists emails matching the filter, newest first
+func (r *MongoRepository) FindEmailDeliveries(ctx context.Context, filter EmailDeliveryFilter, opts *QueryOptions) ([]*EmailDelivery, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindEmailDeliveries")
+	defer span.End()
+
+	query := bson.M{}
+	if filter.UserID != "" {
+		query["userID"] = filter.UserID
+	}
+	if filter.RepairID != "" {
+		query["repairID"] = filter.RepairID
+	}
+	if filter.Status != "" {
+		query["status"] = filter.Status
+	}
+	cursor, err := opts.find(ctx, r.retryAttempts, r.EmailDeliveries, query, bson.D{{Key: "createdAt", Value: -1}})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find email deliveries")
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	deliveries := []*EmailDelivery{}
+	if err := cursor.All(ctx, &deliveries); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode email deliveries")
+		return nil, classify(err)
+	}
+	span.SetAttributes(attribute.Int("deliveryCount", len(deliveries)))
+	return deliveries, nil
+}
+
+// CreateRepairBundle inserts a bundle and marks its repairs with the bundle ID
+// and their share of the discount, within ctx's transaction. A repair
+// that is no longer pending, unassigned and unbundled fails it with
+// ErrInvalidInput.
+func (r *MongoRepository) CreateRepairBundle(ctx context.Context, bundle *RepairBundle, discounts map[string]Money) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoCreateRepairBundle")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("bundleID", bundle.ID),
+		attribute.Int("repairCount", len(bundle.RepairIDs)),
+	)
+
+	if _, err := r.BundleCollection.InsertOne(ctx, bundle); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to insert repair bundle")
+		return fmt.Errorf("failed to insert repair bundle: %w", classify(err))
+	}
+	for _, repairID := range bundle.RepairIDs {
+		filter := bson.M{
+			"_id":        repairID,
+			"status":     "pending",
+			"assignedTo": bson.M{"$in": bson.A{nil, ""}},
+			"bundleID":   bson.M{"$in": bson.A{nil, ""}},
+		}
+		set := bson.M{"bundleID": bundle.ID}
+		if discount := discounts[repairID]; discount != 0 {
+			set["bundleDiscount"] = discount
+		}
+		result, err := r.RepairCollection.UpdateOne(ctx, filter, bson.M{"$set": set})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to bundle repair")
+			return fmt.Errorf("failed to bundle repair %s: %v", repairID, err)
+		}
+		if result.MatchedCount == 0 {
+			err := fmt.Errorf("%w: repair %s is no longer pending and unassigned, or is already bundled", ErrInvalidInput, repairID)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+	}
+	return nil
+}
+
+// GetRepairBundle retrieves a bundle by ID
+func (r *MongoRepository) GetRepairBundle(ctx context.Context, id string) (*RepairBundle, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetRepairBundle")
+	defer span.End()
+	span.SetAttributes(attribute.String("bundleID", id))
+
+	var bundle RepairBundle
+	if err := r.BundleCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&bundle); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find repair bundle")
+		return nil, classify(err)
+	}
+	return &bundle, nil
+}
+
+// FindRepairBundles lists bundles, newest first
+func (r *MongoRepository) FindRepairBundles(ctx context.Context, opts *QueryOptions) ([]*RepairBundle, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindRepairBundles")
+	defer span.End()
+
+	cursor, err := opts.find(ctx, r.retryAttempts, r.BundleCollection, bson.M{}, bson.D{{Key: "createdAt", Value: -1}})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find repair bundles")
+		return nil, fmt.Errorf("failed to find repair bundles: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	bundles := []*RepairBundle{}
+	if err := cursor.All(ctx, &bundles); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode repair bundles")
+		return nil, fmt.Errorf("failed to decode repair bundles: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("bundleCount", len(bundles)))
+	return bundles, nil
+}
+
+// DeleteRepairBundle deletes a bundle and releases its repairs, which get
+// their quoted price back, within ctx's transaction. Bundles with an
+// assigned repair fail with ErrInvalidInput.
+func (r *MongoRepository) DeleteRepairBundle(ctx context.Context, id string) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoDeleteRepairBundle")
+	defer span.End()
+	span.SetAttributes(attribute.String("bundleID", id))
+
+	assigned, err := r.RepairCollection.CountDocuments(ctx, bson.M{
+		"bundleID":   id,
+		"assignedTo": bson.M{"$nin": bson.A{nil, ""}},
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to check bundle assignment")
+		return fmt.Errorf("failed to check bundle assignment: %w", classify(err))
+	}
+	if assigned > 0 {
+		err := fmt.Errorf("%w: bundle %s is assigned to a mechanic", ErrInvalidInput, id)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	result, err := r.BundleCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to delete repair bundle")
+		return fmt.Errorf("failed to delete repair bundle: %w", classify(err))
+	}
+	if result.DeletedCount == 0 {
+		return mongo.ErrNoDocuments
+	}
+	if _, err := r.RepairCollection.UpdateMany(ctx, bson.M{"bundleID": id}, bson.M{
+		"$unset": bson.M{"bundleID": "", "bundleDiscount": ""},
+	}); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to release bundled repairs")
+		return fmt.Errorf("failed to release bundled repairs: %w", classify(err))
+	}
+	return nil
+}
+
+// SaveDistanceTile inserts or replaces a distance tile
+func (r *MongoRepository) SaveDistanceTile(ctx context.Context, tile *DistanceTile) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveDistanceTile")
+	defer span.End()
+	span.SetAttributes(attribute.String("tileID", tile.ID), attribute.String("region", tile.Region))
+
+	if _, err := r.DistanceTiles.ReplaceOne(ctx, bson.M{"_id": tile.ID}, tile, options.Replace().SetUpsert(true)); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save distance tile")
+		return fmt.Errorf("failed to save distance tile: %w", classify(err))
+	}
+	return nil
+}
+
+// FindDistanceTiles returns the distance tiles of a grid
+func (r *MongoRepository) FindDistanceTiles(ctx context.Context, sizeDegrees float64, radius int) ([]DistanceTile, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindDistanceTiles")
+	defer span.End()
+
+	cursor, err := r.DistanceTiles.Find(ctx, bson.M{"sizeDegrees": sizeDegrees, "radius": radius})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find distance tiles")
+		return nil, fmt.Errorf("failed to find distance tiles: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	tiles := []DistanceTile{}
+	if err := cursor.All(ctx, &tiles); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode distance tiles")
+		return nil, fmt.Errorf("failed to decode distance tiles: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("tileCount", len(tiles)))
+	return tiles, nil
+}
+
+// SummarizeDistanceTiles counts the distance tiles of a grid per region
+func (r *MongoRepository) SummarizeDistanceTiles(ctx context.Context, sizeDegrees float64, radius int) ([]*DistanceTileSummary, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSummarizeDistanceTiles")
+	defer span.End()
+
+	cursor, err := r.DistanceTiles.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"sizeDegrees": sizeDegrees, "radius": radius}}},
+		{{Key: "$group", Value: bson.M{
+			"_id":           "$region",
+			"tiles":         bson.M{"$sum": 1},
+			"oldestBuiltAt": bson.M{"$min": "$builtAt"},
+			"newestBuiltAt": bson.M{"$max": "$builtAt"},
+		}}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to summarize distance tiles")
+		return nil, fmt.Errorf("failed to summarize distance tiles: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	summaries := []*DistanceTileSummary{}
+	if err := cursor.All(ctx, &summaries); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode distance tile summary")
+		return nil, fmt.Errorf("failed to decode distance tile summary: %w", classify(err))
+	}
+	return summaries, nil
+}
+
+// SaveFleetAccount inserts or replaces a fleet account. Members of another
+// fleet fail with ErrInvalidInput, through the unique members.userID index.
+func (r *MongoRepository) SaveFleetAccount(ctx context.Context, fleet *FleetAccount) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveFleetAccount")
+	defer span.End()
+	span.SetAttributes(attribute.String("fleetID", fleet.ID), attribute.Int("memberCount", len(fleet.Members)))
+
+	_, err := r.FleetCollection.ReplaceOne(ctx, bson.M{"_id": fleet.ID}, fleet, options.Replace().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: a member already belongs to another fleet", ErrInvalidInput)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save fleet account")
+		return fmt.Errorf("failed to save fleet account: %w", classify(err))
+	}
+	return nil
+}
+
+// GetFleetAccount retrieves a fleet account by ID
+func (r *MongoRepository) GetFleetAccount(ctx context.Context, id string) (*FleetAccount, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetFleetAccount")
+	defer span.End()
+	span.SetAttributes(attribute.String("fleetID", id))
+
+	var fleet FleetAccount
+	if err := r.FleetCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&fleet); err != nil {
+		if err != mongo.ErrNoDocuments {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to find fleet account")
+		}
+		return nil, fmt.Errorf("failed to find fleet account: %w", classify(err))
+	}
+	return &fleet, nil
+}
+
+// FindFleetByMember retrieves the fleet account a user is a member of; users
+// outside every fleet get mongo.ErrNoDocuments
+func (r *MongoRepository) FindFleetByMember(ctx context.Context, userID string) (*FleetAccount, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindFleetByMember")
+	defer span.End()
+	span.SetAttributes(attribute.String("userID", userID))
+
+	var fleet FleetAccount
+	if err := r.FleetCollection.FindOne(ctx, bson.M{"members.userID": userID}).Decode(&fleet); err != nil {
+		if err != mongo.ErrNoDocuments {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to find fleet account")
+		}
+		return nil, fmt.Errorf("failed to find fleet account: %w", classify(err))
+	}
+	return &fleet, nil
+}
+
+// MemberSpending sums the quoted prices of the users' repairs created from
+// since until until, cancelled ones excluded
+func (r *MongoRepository) MemberSpending(ctx context.Context, userIDs []string, since, until time.Time) (map[string]MemberSpending, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoMemberSpending")
+	defer span.End()
+	span.SetAttributes(attribute.Int("userCount", len(userIDs)))
+
+	cursor, err := r.RepairCollection.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{
+			"userID":    bson.M{"$in": userIDs},
+			"createdAt": bson.M{"$gte": since, "$lt": until},
+			"status":    bson.M{"$ne": "cancelled"},
+		}}},
+		{{Key: "$group", Value: bson.M{
+			"_id": "$userID",
+			"spent": bson.M{"$sum": bson.M{"$subtract": bson.A{
+				bson.M{"$ifNull": bson.A{"$repairCost.totalPrice", 0}},
+				bson.M{"$ifNull": bson.A{"$bundleDiscount", 0}},
+			}}},
+			"repairCount": bson.M{"$sum": 1},
+		}}},
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to sum member spending")
+		return nil, fmt.Errorf("failed to sum member spending: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	var rows []struct {
+		UserID         string `bson:"_id"`
+		MemberSpending `bson:",inline"`
+	}
+	if err := cursor.All(ctx, &rows); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode member spending")
+		return nil, fmt.Errorf("failed to decode member spending: %w", classify(err))
+	}
+	spending := make(map[string]MemberSpending, len(rows))
+	for _, row := range rows {
+		spending[row.UserID] = row.MemberSpending
+	}
+	return spending, nil
+}
+
+// FindFleetAccounts returns every fleet account
+func (r *MongoRepository) FindFleetAccounts(ctx context.Context) ([]*FleetAccount, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindFleetAccounts")
+	defer span.End()
+
+	cursor, err := r.FleetCollection.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find fleet accounts")
+		return nil, fmt.Errorf("failed to find fleet accounts: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	fleets := []*FleetAccount{}
+	if err := cursor.All(ctx, &fleets); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode fleet accounts")
+		return nil, fmt.Errorf("failed to decode fleet accounts: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("fleetCount", len(fleets)))
+	return fleets, nil
+}
+
+// FindCompletedReceipts returns the receipts of the users' repairs completed
+// from since until until, oldest completion first
+func (r *MongoRepository) FindCompletedReceipts(ctx context.Context, userIDs []string, since, until time.Time) ([]*Receipt, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindCompletedReceipts")
+	defer span.End()
+	span.SetAttributes(attribute.Int("userCount", len(userIDs)))
+
+	cursor, err := r.ReceiptCollection.Find(ctx, bson.M{
+		"userID":      bson.M{"$in": userIDs},
+		"completedAt": bson.M{"$gte": since, "$lt": until},
+	}, options.Find().SetSort(bson.D{{Key: "completedAt", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find receipts")
+		return nil, fmt.Errorf("failed to find receipts: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	receipts := []*Receipt{}
+	if err := cursor.All(ctx, &receipts); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode receipts")
+		return nil, fmt.Errorf("failed to decode receipts: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("receiptCount", len(receipts)))
+	return receipts, nil
+}
+
+// SaveFleetInvoice inserts a fleet invoice within ctx's transaction; an
+// invoice issued already fails with ErrInvoiceIssued
+func (r *MongoRepository) SaveFleetInvoice(ctx context.Context, invoice *FleetInvoice) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveFleetInvoice")
+	defer span.End()
+	span.SetAttributes(attribute.String("invoiceID", invoice.ID), attribute.Int("repairCount", invoice.RepairCount))
+
+	_, err := r.FleetInvoices.InsertOne(ctx, invoice)
+	if mongo.IsDuplicateKeyError(err) {
+		return fmt.Errorf("%w: %s", ErrInvoiceIssued, invoice.ID)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save fleet invoice")
+		return fmt.Errorf("failed to save fleet invoice: %w", classify(err))
+	}
+	return nil
+}
+
+// GetFleetInvoice retrieves a fleet invoice by ID
+func (r *MongoRepository) GetFleetInvoice(ctx context.Context, id string) (*FleetInvoice, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoGetFleetInvoice")
+	defer span.End()
+	span.SetAttributes(attribute.String("invoiceID", id))
+
+	var invoice FleetInvoice
+	if err := r.FleetInvoices.FindOne(ctx, bson.M{"_id": id}).Decode(&invoice); err != nil {
+		if err != mongo.ErrNoDocuments {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to find fleet invoice")
+		}
+		return nil, fmt.Errorf("failed to find fleet invoice: %w", classify(err))
+	}
+	return &invoice, nil
+}
+
+// FindFleetInvoices returns a fleet's invoices, latest month first
+func (r *MongoRepository) FindFleetInvoices(ctx context.Context, fleetID string, opts *QueryOptions) ([]*FleetInvoice, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindFleetInvoices")
+	defer span.End()
+	span.SetAttributes(attribute.String("fleetID", fleetID))
+
+	cursor, err := opts.find(ctx, r.retryAttempts, r.FleetInvoices, bson.M{"fleetID": fleetID}, bson.D{{Key: "month", Value: -1}})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find fleet invoices")
+		return nil, fmt.Errorf("failed to find fleet invoices: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	invoices := []*FleetInvoice{}
+	if err := cursor.All(ctx, &invoices); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode fleet invoices")
+		return nil, fmt.Errorf("failed to decode fleet invoices: %w", classify(err))
+	}
+	span.SetAttributes(attribute.Int("invoiceCount", len(invoices)))
+	return invoices, nil
+}
+
+// SaveDurationSample records a completed repair's duration within the
+// transaction of ctx
+func (r *MongoRepository) SaveDurationSample(ctx context.Context, sample *RepairDurationSample) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveDurationSample")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("repairID", sample.RepairID),
+		attribute.String("repairType", sample.RepairType),
+	)
+
+	opts := options.Replace().SetUpsert(true)
+	if _, err := r.DurationSamples.ReplaceOne(ctx, bson.M{"_id": sample.RepairID}, sample, opts); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save duration sample")
+		return fmt.Errorf("failed to save duration sample: %w", classify(err))
+	}
+	return nil
+}
+
+// RecentDurations returns the minutes of the latest limit completed repairs
+// of repairType, only mechanicID's when it is set
+func (r *MongoRepository) RecentDurations(ctx context.Context, repairType, mechanicID string, limit int) ([]float64, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoRecentDurations")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairType", repairType), attribute.String("mechanicID", mechanicID))
+
+	filter := bson.M{"repairType": repairType}
+	if mechanicID != "" {
+		filter["mechanicID"] = mechanicID
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "completedAt", Value: -1}}).SetLimit(int64(limit))
+	cursor, err := r.DurationSamples.Find(ctx, filter, opts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find duration samples")
+		return nil, fmt.Errorf("failed to find duration samples: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	var samples []RepairDurationSample
+	if err := cursor.All(ctx, &samples); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode duration samples")
+		return nil, fmt.Errorf("failed to decode duration samples: %w", classify(err))
+	}
+	minutes := make([]float64, len(samples))
+	for i, sample := range samples {
+		minutes[i] = sample.Minutes
+	}
+	return minutes, nil
+}
+
+// SaveDurationEstimate inserts or replaces a learned duration
+func (r *MongoRepository) SaveDurationEstimate(ctx context.Context, estimate *DurationEstimate) error {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoSaveDurationEstimate")
+	defer span.End()
+	span.SetAttributes(attribute.String("estimateID", estimate.ID))
+
+	opts := options.Replace().SetUpsert(true)
+	if _, err := r.DurationEstimates.ReplaceOne(ctx, bson.M{"_id": estimate.ID}, estimate, opts); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save duration estimate")
+		return fmt.Errorf("failed to save duration estimate: %w", classify(err))
+	}
+	return nil
+}
+
+// FindDurationEstimates retrieves the learned durations of repairType, of
+// every type when it is empty, ordered by type then mechanic
+func (r *MongoRepository) FindDurationEstimates(ctx context.Context, repairType string) ([]*DurationEstimate, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "MongoFindDurationEstimates")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairType", repairType))
+
+	filter := bson.M{}
+	if repairType != "" {
+		filter["repairType"] = repairType
+	}
+	opts := options.Find().SetSort(bson.D{{Key: "repairType", Value: 1}, {Key: "mechanicID", Value: 1}})
+	cursor, err := r.DurationEstimates.Find(ctx, filter, opts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find duration estimates")
+		return nil, fmt.Errorf("failed to find duration estimates: %w", classify(err))
+	}
+	defer cursor.Close(ctx)
+
+	estimates := []*DurationEstimate{}
+	if err := cursor.All(ctx, &estimates); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decode duration estimates")
+		return nil, fmt.Errorf("failed to decode duration estimates: %w", classify(err))
+	}
+	return estimates, nil
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// Orders of the mechanics in an estimate, chosen by the client with sort
+const (
+	SortDistance   = "distance"   // nearest first, rotated by the fairness policy
+	SortScore      = "score"      // highest weighted score first
+	SortRating     = "rating"     // best rated first
+	SortAcceptance = "acceptance" // highest acceptance rate first
+	SortLoad       = "load"       // fewest open repairs first
+)
+
+// EstimateSorts are the accepted values of an estimate's sort
+var EstimateSorts = []string{SortDistance, SortScore, SortRating, SortAcceptance, SortLoad}
+
+// MaxRating is the best rating a mechanic can have
+const MaxRating = 5.0
+
+// NeutralComponent is the rating and acceptance component of mechanics
+// without a rating or acceptance rate yet, so new mechanics are neither
+// favored nor buried
+const NeutralComponent = 0.5
+
+// ScoreWeights weigh the components of a mechanic's score; only their
+// ratios matter
+type ScoreWeights struct {
+	Distance   float64 `json:"distance"`
+	Rating     float64 `json:"rating"`
+	Acceptance float64 `json:"acceptance"`
+	Load       float64 `json:"load"`
+}
+
+// Validate checks the weights are not negative and not all zero
+func (w ScoreWeights) Validate() error {
+	if w.Distance < 0 || w.Rating < 0 || w.Acceptance < 0 || w.Load < 0 {
+		return fmt.Errorf("%w: score weights must not be negative", ErrInvalidInput)
+	}
+	if w.Distance+w.Rating+w.Acceptance+w.Load == 0 {
+		return fmt.Errorf("%w: at least one score weight must be positive", ErrInvalidInput)
+	}
+	return nil
+}
+
+// MechanicScore is a mechanic's weighted score between 0 and 1 and its
+// components, each between 0 and 1 with 1 the best
+type MechanicScore struct {
+	Score      float64 `bson:"score" json:"score"`
+	Distance   float64 `bson:"distance" json:"distance"`     // 1 for the nearest candidate, 0 for the farthest
+	Rating     float64 `bson:"rating" json:"rating"`         // rating / MaxRating
+	Acceptance float64 `bson:"acceptance" json:"acceptance"` // share of offered repairs accepted
+	Load       float64 `bson:"load" json:"load"`             // 1 / (1 + open repairs)
+}
+
+// MechanicRanking records how an estimate's mechanics were ordered
+type MechanicRanking struct {
+	Sort    string       `bson:"sort" json:"sort"`
+	Weights ScoreWeights `bson:"weights" json:"weights"`
+}
+
+// ScoreMechanics sets the score of each mechanic, relative to the other
+// candidates of the same estimate
+func ScoreMechanics(mechanics []MechanicInfo, weights ScoreWeights) {
+	nearest, farthest := 0.0, 0.0
+	for i, m := range mechanics {
+		if i == 0 || m.Distance < nearest {
+			nearest = m.Distance
+		}
+		farthest = max(farthest, m.Distance)
+	}
+	total := weights.Distance + weights.Rating + weights.Acceptance + weights.Load
+	for i := range mechanics {
+		m := &mechanics[i]
+		score := MechanicScore{
+			Distance:   1,
+			Rating:     NeutralComponent,
+			Acceptance: NeutralComponent,
+			Load:       1 / (1 + float64(m.OpenRepairs)),
+		}
+		if farthest > nearest {
+			score.Distance = (farthest - m.Distance) / (farthest - nearest)
+		}
+		if m.Rating != nil {
+			score.Rating = min(max(*m.Rating/MaxRating, 0), 1)
+		}
+		if m.AcceptanceRate != nil {
+			score.Acceptance = min(max(*m.AcceptanceRate, 0), 1)
+		}
+		if total > 0 {
+			score.Score = (weights.Distance*score.Distance + weights.Rating*score.Rating +
+				weights.Acceptance*score.Acceptance + weights.Load*score.Load) / total
+		}
+		m.Score = &score
+	}
+}
+
+// SortMechanics orders scored mechanics by sortBy; ties keep their order.
+// SortDistance leaves the order unchanged, the caller already sorted by
+// distance.
+func SortMechanics(mechanics []MechanicInfo, sortBy string) {
+	key := func(m MechanicInfo) float64 {
+		switch sortBy {
+		case SortScore:
+			return m.Score.Score
+		case SortRating:
+			return m.Score.Rating
+		case SortAcceptance:
+			return m.Score.Acceptance
+		case SortLoad:
+			return m.Score.Load
+		}
+		return 0
+	}
+	if sortBy == SortDistance || !slices.Contains(EstimateSorts, sortBy) {
+		return
+	}
+	sort.SliceStable(mechanics, func(i, j int) bool {
+		return key(mechanics[i]) > key(mechanics[j])
+	})
+}
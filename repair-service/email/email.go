@@ -0,0 +1,92 @@
+// Package email sends transactional email through a pluggable provider
+package email
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// Built-in provider names, selected with EMAIL_PROVIDER
+const (
+	ProviderLog     = "log"
+	ProviderWebhook = "webhook"
+	ProviderSMTP    = "smtp"
+)
+
+// Message is a rendered email. HTML is optional; Text is always sent.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Provider delivers an email
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, msg *Message) error
+}
+
+// NewProviderFromEnv builds the provider named by EMAIL_PROVIDER from its
+// EMAIL_* environment variables; mail is sent from EMAIL_FROM. Without a
+// provider, or when its settings are missing, emails are only logged.
+func NewProviderFromEnv(httpClient *http.Client, logger *slog.Logger) Provider {
+	from := os.Getenv("EMAIL_FROM")
+	if from == "" {
+		from = "RoadRide <no-reply@roadride.local>"
+	}
+	var provider Provider
+	switch name := os.Getenv("EMAIL_PROVIDER"); name {
+	case ProviderWebhook:
+		if u := os.Getenv("EMAIL_WEBHOOK_URL"); u != "" {
+			provider = NewWebhookProvider(u, os.Getenv("EMAIL_WEBHOOK_TOKEN"), from, httpClient)
+		}
+	case ProviderSMTP:
+		if host := os.Getenv("EMAIL_SMTP_HOST"); host != "" {
+			port := 587
+			if v, err := strconv.Atoi(os.Getenv("EMAIL_SMTP_PORT")); err == nil && v > 0 {
+				port = v
+			}
+			provider = NewSMTPProvider(host, port, os.Getenv("EMAIL_SMTP_USERNAME"), os.Getenv("EMAIL_SMTP_PASSWORD"), from)
+		}
+	case "", ProviderLog:
+	default:
+		logger.Warn("Unknown email provider, logging emails instead", "provider", name, "app", "repair-service")
+	}
+	if provider == nil {
+		provider = NewLogProvider(logger)
+	}
+	logger.Info("Configured email provider", "provider", provider.Name(), "from", from, "app", "repair-service")
+	return provider
+}
+
+// LogProvider writes emails to the service log instead of sending them. It
+// is meant for development.
+type LogProvider struct {
+	logger *slog.Logger
+}
+
+// NewLogProvider creates a LogProvider
+func NewLogProvider(logger *slog.Logger) *LogProvider {
+	return &LogProvider{logger: logger}
+}
+
+// Name returns the provider name
+func (p *LogProvider) Name() string {
+	return ProviderLog
+}
+
+// Send logs the email
+func (p *LogProvider) Send(ctx context.Context, msg *Message) error {
+	p.logger.Info("Email not sent, log provider", "to", msg.To, "subject", msg.Subject, "text", msg.Text, "app", "repair-service")
+	return nil
+}
+
+// statusError reports a non-2xx response from a provider
+func statusError(provider string, resp *http.Response) error {
+	return fmt.Errorf("%s email provider returned status %d", provider, resp.StatusCode)
+}
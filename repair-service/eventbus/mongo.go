@@ -0,0 +1,162 @@
+// Package eventbus is a lightweight event bus on the shared MongoDB for
+// single-node installs that run without Kafka and schema-registry
+// (EVENT_BUS=mongo). Records are stored in the event_bus collection with a
+// per-topic offset, and consumer groups poll for the records they have not
+// committed yet. Like Kafka keys, records of one aggregate keep their order
+// because the outbox processor publishes them one after another.
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"repair-service/domain"
+	"repair-service/kafka"
+	"repair-service/region"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Collections of the event bus
+const (
+	RecordCollection  = "event_bus"
+	OffsetsCollection = "event_bus_offsets" // last offset per topic
+)
+
+// Header is a record header
+type Header struct {
+	Key   string `bson:"key"`
+	Value string `bson:"value"`
+}
+
+// Record is one published event, shaped like a Kafka record
+type Record struct {
+	ID          string    `bson:"_id"` // <topic>/<offset>
+	Topic       string    `bson:"topic"`
+	Offset      int64     `bson:"offset"`
+	Key         []byte    `bson:"key,omitempty"`
+	Value       []byte    `bson:"value"` // empty for tombstones
+	Headers     []Header  `bson:"headers"`
+	Timestamp   time.Time `bson:"timestamp"`
+	CommittedBy []string  `bson:"committedBy"` // consumer groups that handled the record
+}
+
+// Publisher publishes outbox events to the Mongo event bus
+type Publisher struct {
+	records  *mongo.Collection
+	offsets  *mongo.Collection
+	topic    string
+	logger   *slog.Logger
+	tracer   trace.Tracer
+	delivery *kafka.DeliveryMetrics // outbox creation to insertion into event_bus
+}
+
+// NewPublisher creates a Publisher writing to topic in db
+func NewPublisher(db *mongo.Database, topic string, logger *slog.Logger) *Publisher {
+	return &Publisher{
+		records:  db.Collection(RecordCollection),
+		offsets:  db.Collection(OffsetsCollection),
+		topic:    topic,
+		logger:   logger,
+		tracer:   otel.Tracer("repair-service"),
+		delivery: kafka.NewDeliveryMetrics(),
+	}
+}
+
+// PublishOutboxEvent appends an outbox event to the topic, or the topic the
+// event names, with the same key and headers the Kafka producer sets
+func (p *Publisher) PublishOutboxEvent(ctx context.Context, event *domain.OutboxEvent) error {
+	topic := p.topic
+	if event.Topic != "" {
+		topic = kafka.TopicName(event.Topic)
+	}
+	ctx, span := p.tracer.Start(ctx, "PublishOutboxEvent",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(kafka.PublishAttributes(kafka.BusMongo, topic, event)...),
+		trace.WithAttributes(semconv.PeerService("mongodb")),
+	)
+	defer span.End()
+
+	var counter struct {
+		Offset int64 `bson:"offset"`
+	}
+	err := p.offsets.FindOneAndUpdate(ctx,
+		bson.M{"_id": topic},
+		bson.M{"$inc": bson.M{"offset": 1}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&counter)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to allocate offset")
+		p.logger.Error("Failed to allocate event bus offset", "eventID", event.ID, "error", err, "app", "repair-service")
+		kafka.ProducedMessages.WithLabelValues(kafka.BusMongo, topic, event.EventType, "error").Inc()
+		return fmt.Errorf("failed to allocate offset: %w", err)
+	}
+
+	record := &Record{
+		ID:     fmt.Sprintf("%s/%d", topic, counter.Offset),
+		Topic:  topic,
+		Offset: counter.Offset,
+		Value:  event.Payload,
+		Headers: []Header{
+			{Key: kafka.EventTypeHeader, Value: event.EventType},
+			{Key: kafka.EventIDHeader, Value: kafka.EventID(event)},
+		},
+		Timestamp:   time.Now(),
+		CommittedBy: []string{},
+	}
+	if event.Region != "" {
+		record.Headers = append(record.Headers, Header{Key: region.Header, Value: event.Region})
+	}
+	for key, value := range kafka.TraceHeaders(ctx) {
+		record.Headers = append(record.Headers, Header{Key: key, Value: value})
+	}
+	if event.AggregateID != "" {
+		record.Key = []byte(event.AggregateID)
+	}
+	if _, err := p.records.InsertOne(ctx, record); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to insert record")
+		p.logger.Error("Failed to publish to event bus", "eventID", event.ID, "error", err, "app", "repair-service")
+		kafka.ProducedMessages.WithLabelValues(kafka.BusMongo, topic, event.EventType, "error").Inc()
+		return fmt.Errorf("failed to insert event bus record: %w", err)
+	}
+
+	latency := time.Since(event.CreatedAt)
+	p.delivery.Observe(event.EventType, latency)
+	kafka.ProducedMessages.WithLabelValues(kafka.BusMongo, topic, event.EventType, "ok").Inc()
+	p.logger.Info("Published outbox event",
+		"eventID", event.ID,
+		"deliveryLatencyMs", latency.Milliseconds(),
+		"topic", topic,
+		"offset", record.Offset,
+		"bus", kafka.BusMongo,
+		"app", "repair-service")
+	span.SetAttributes(attribute.Int64("offset", record.Offset))
+	return nil
+}
+
+// SchemaID returns kafka.LocalSchemaID; there is no schema-registry
+func (p *Publisher) SchemaID() int {
+	return kafka.LocalSchemaID
+}
+
+// DeliveryLatency returns, per event type, the latency from outbox event
+// creation to the record being stored
+func (p *Publisher) DeliveryLatency() map[string]kafka.LatencyStats {
+	return p.delivery.Snapshot()
+}
+
+// Close is a no-op; the MongoDB client is closed by main
+func (p *Publisher) Close() {
+	p.logger.Info("Closing event bus publisher", "bus", kafka.BusMongo, "app", "repair-service")
+}
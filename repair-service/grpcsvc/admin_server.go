@@ -0,0 +1,104 @@
+package grpcsvc
+
+import (
+	"context"
+	"log/slog"
+	"repair-service/domain"
+	"repair-service/proto"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// changeStreamProbeTimeout bounds the change stream check done by GetStats
+const changeStreamProbeTimeout = 3 * time.Second
+
+// AdminServer serves fleet-wide repair state to the admin console and CLI
+type AdminServer struct {
+	proto.UnimplementedAdminServiceServer
+	repo    domain.RepairRepository
+	repairs *RepairServer
+	logger  *slog.Logger
+}
+
+// NewAdminServer creates an AdminServer reporting on the change streams of repairs
+func NewAdminServer(repo domain.RepairRepository, repairs *RepairServer, logger *slog.Logger) *AdminServer {
+	return &AdminServer{
+		repo:    repo,
+		repairs: repairs,
+		logger:  logger,
+	}
+}
+
+// GetStats returns repair counts by status, the outbox backlog and change stream health
+func (s *AdminServer) GetStats(ctx context.Context, req *proto.GetStatsRequest) (*proto.Stats, error) {
+	ctx, span := otel.Tracer("repair-service").Start(ctx, "GetStats")
+	defer span.End()
+
+	byStatus, err := s.repo.CountRepairsByStatus(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to count repairs")
+		s.logger.Error("Failed to count repairs", "error", err)
+		return nil, status.Error(grpccodes.Unavailable, err.Error())
+	}
+	var total int64
+	for _, count := range byStatus {
+		total += count
+	}
+
+	backlog, err := s.repo.GetOutboxBacklog(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get outbox backlog")
+		s.logger.Error("Failed to get outbox backlog", "error", err)
+		return nil, status.Error(grpccodes.Unavailable, err.Error())
+	}
+	outbox := &proto.OutboxBacklog{Unprocessed: backlog.Unprocessed}
+	if backlog.Oldest != nil {
+		outbox.OldestAgeSeconds = int64(time.Since(*backlog.Oldest).Seconds())
+	}
+
+	changeStream := s.changeStreamHealth(ctx)
+	span.SetAttributes(
+		attribute.Int64("totalRepairs", total),
+		attribute.Int64("outboxUnprocessed", outbox.Unprocessed),
+		attribute.Bool("changeStreamHealthy", changeStream.Healthy),
+	)
+	return &proto.Stats{
+		TotalRepairs:      total,
+		RepairsByStatus:   byStatus,
+		Outbox:            outbox,
+		ChangeStream:      changeStream,
+		GeneratedAtUnixMs: time.Now().UnixMilli(),
+	}, nil
+}
+
+// changeStreamHealth probes the repairs change stream and adds the state of
+// the streams StreamAllRepairs currently serves
+func (s *AdminServer) changeStreamHealth(ctx context.Context) *proto.ChangeStreamHealth {
+	health := &proto.ChangeStreamHealth{Healthy: true}
+
+	probeCtx, cancel := context.WithTimeout(ctx, changeStreamProbeTimeout)
+	defer cancel()
+	changeStream, err := s.repo.WatchRepairs(probeCtx, domain.RepairFilter{})
+	if err != nil {
+		health.Healthy = false
+		health.Error = err.Error()
+		s.logger.Warn("Repairs change stream probe failed", "error", err)
+	} else {
+		changeStream.Close(probeCtx)
+	}
+
+	active, lastErr, lastErrAt := s.repairs.streamState()
+	health.ActiveStreams = active
+	if lastErr != "" {
+		health.LastStreamError = lastErr
+		health.LastStreamErrorUnixMs = lastErrAt.UnixMilli()
+	}
+	return health
+}
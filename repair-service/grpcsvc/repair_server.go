@@ -1,19 +1,34 @@
 package grpcsvc
 
 import (
+	"context"
+	"errors"
 	"log/slog"
 	"repair-service/domain"
 	"repair-service/proto"
+	"sync"
+	"time"
 
+	"go.mongodb.org/mongo-driver/mongo"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+var errInvalidBoundingBox = errors.New("bounding box minimums must not exceed maximums")
+
 type RepairServer struct {
 	proto.UnimplementedRepairServiceServer
 	repo   domain.RepairRepository
 	logger *slog.Logger
+
+	// change stream state reported by AdminService.GetStats
+	mu              sync.Mutex
+	activeStreams   int32
+	lastStreamErr   string
+	lastStreamErrAt time.Time
 }
 
 func NewRepairServer(repo domain.RepairRepository, logger *slog.Logger) *RepairServer {
@@ -23,12 +38,26 @@ func NewRepairServer(repo domain.RepairRepository, logger *slog.Logger) *RepairS
 	}
 }
 
-func (s *RepairServer) StreamAllRepairs(_ *proto.Empty, stream proto.RepairService_StreamAllRepairsServer) error {
+func (s *RepairServer) StreamAllRepairs(req *proto.StreamRepairsRequest, stream proto.RepairService_StreamAllRepairsServer) error {
 	ctx, span := otel.Tracer("repair-service").Start(stream.Context(), "StreamAllRepairs")
 	defer span.End()
 
-	// Get all existing repairs
-	repairs, err := s.repo.GetAllRepairs(ctx)
+	filter, err := repairFilterFromRequest(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Invalid stream filter")
+		s.logger.Error("Invalid stream filter", "error", err)
+		return status.Error(grpccodes.InvalidArgument, err.Error())
+	}
+	span.SetAttributes(
+		attribute.StringSlice("filter.statuses", filter.Statuses),
+		attribute.String("filter.userID", filter.UserID),
+		attribute.Bool("filter.bbox", filter.BoundingBox != nil),
+		attribute.Int64("filter.sinceUnixMs", req.GetSinceUnixMs()),
+	)
+
+	// Get existing repairs matching the filter, oldest first like the change stream
+	repairs, err := s.repo.FindRepairs(ctx, filter, &domain.QueryOptions{Sort: []domain.SortField{{Field: "createdAt"}}})
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to get initial repairs")
@@ -49,15 +78,18 @@ func (s *RepairServer) StreamAllRepairs(_ *proto.Empty, stream proto.RepairServi
 	span.SetAttributes(attribute.Int("initialRepairCount", len(repairs)))
 	s.logger.Info("Sent initial repairs", "count", len(repairs))
 
-	// Set up MongoDB change stream to watch for new repairs
-	changeStream, err := s.repo.WatchRepairs(ctx)
+	// Set up MongoDB change stream to watch for new repairs matching the filter
+	changeStream, err := s.repo.WatchRepairs(ctx, filter)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to open change stream")
 		s.logger.Error("Failed to open change stream", "error", err)
+		s.recordStreamError(err)
 		return err
 	}
 	defer changeStream.Close(ctx)
+	s.streamOpened()
+	defer s.streamClosed()
 
 	// Stream new repairs
 	for changeStream.Next(ctx) {
@@ -86,19 +118,117 @@ func (s *RepairServer) StreamAllRepairs(_ *proto.Empty, stream proto.RepairServi
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Change stream error")
 		s.logger.Error("Change stream error", "error", err)
+		s.recordStreamError(err)
 		return err
 	}
 
 	return nil
 }
 
+// GetRepair returns one repair, for services that validate against
+// repair-service instead of their own copy
+func (s *RepairServer) GetRepair(ctx context.Context, req *proto.GetRepairRequest) (*proto.Repair, error) {
+	ctx, span := otel.Tracer("repair-service").Start(ctx, "GetRepair")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", req.GetId()))
+
+	if req.GetId() == "" {
+		span.SetStatus(codes.Error, "Missing repair ID")
+		return nil, status.Error(grpccodes.InvalidArgument, "repair ID is required")
+	}
+	repair, err := s.repo.GetRepairByID(ctx, req.GetId())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		span.SetStatus(codes.Error, "Repair not found")
+		return nil, status.Errorf(grpccodes.NotFound, "repair %s not found", req.GetId())
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get repair")
+		s.logger.Error("Failed to get repair", "error", err, "repairID", req.GetId(), "app", "repair-service")
+		if errors.Is(err, domain.ErrTransient) {
+			return nil, status.Error(grpccodes.Unavailable, "failed to get repair")
+		}
+		return nil, status.Error(grpccodes.Internal, "failed to get repair")
+	}
+	return convertToProtoRepair(repair), nil
+}
+
+func (s *RepairServer) streamOpened() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.activeStreams++
+}
+
+func (s *RepairServer) streamClosed() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.activeStreams--
+}
+
+// recordStreamError remembers the latest change stream failure; clients
+// disconnecting are not failures of the stream
+func (s *RepairServer) recordStreamError(err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastStreamErr = err.Error()
+	s.lastStreamErrAt = time.Now()
+}
+
+// streamState returns the number of open change streams and the latest failure
+func (s *RepairServer) streamState() (int32, string, time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.activeStreams, s.lastStreamErr, s.lastStreamErrAt
+}
+
+// repairFilterFromRequest converts the stream request into a repository filter
+func repairFilterFromRequest(req *proto.StreamRepairsRequest) (domain.RepairFilter, error) {
+	filter := domain.RepairFilter{
+		Statuses: req.GetStatuses(),
+		UserID:   req.GetUserId(),
+	}
+	if box := req.GetBbox(); box != nil {
+		if box.MinLongitude > box.MaxLongitude || box.MinLatitude > box.MaxLatitude {
+			return filter, errInvalidBoundingBox
+		}
+		filter.BoundingBox = &domain.BoundingBox{
+			MinLongitude: box.MinLongitude,
+			MinLatitude:  box.MinLatitude,
+			MaxLongitude: box.MaxLongitude,
+			MaxLatitude:  box.MaxLatitude,
+		}
+	}
+	if ms := req.GetSinceUnixMs(); ms > 0 {
+		filter.Since = time.UnixMilli(ms)
+	}
+	return filter, nil
+}
+
+// protoPreference converts the preferred mechanic of a repair, if any
+func protoPreference(preference *domain.MechanicPreference) *proto.PreferredMechanic {
+	if preference == nil {
+		return nil
+	}
+	msg := &proto.PreferredMechanic{MechanicId: preference.MechanicID, Pinned: preference.Pinned}
+	if preference.HoldUntil != nil {
+		msg.HoldUntilUnixMs = preference.HoldUntil.UnixMilli()
+	}
+	return msg
+}
+
 // convertToProtoRepair converts domain.RepairModel to proto.Repair
 func convertToProtoRepair(repair *domain.RepairModel) *proto.Repair {
 	if repair == nil || repair.RepairCost == nil {
 		return &proto.Repair{
-			Id:      repair.ID,
-			UserId:  repair.UserID,
-			Status:  repair.Status,
+			Id:     repair.ID,
+			UserId: repair.UserID,
+			Status:     repair.Status,
+			Region:     repair.Region,
+			AssignedTo: repair.AssignedTo,
+			BundleId:   repair.BundleID,
 		}
 	}
 
@@ -121,16 +251,21 @@ func convertToProtoRepair(repair *domain.RepairModel) *proto.Repair {
 	}
 
 	return &proto.Repair{
-		Id:      repair.ID,
-		UserId:  repair.UserID,
-		Status:  repair.Status,
+		Id:     repair.ID,
+		UserId: repair.UserID,
+		Status:     repair.Status,
+		Region:     repair.Region,
+		AssignedTo: repair.AssignedTo,
+		BundleId:   repair.BundleID,
+		PreferredMechanic: protoPreference(repair.RepairCost.Preference),
 		RepairCost: &proto.RepairCost{
-			Id:         repair.RepairCost.ID,
-			UserId:     repair.RepairCost.UserID,
-			RepairType: repair.RepairCost.RepairType,
-			TotalPrice: repair.RepairCost.TotalPrice,
-			UserLocation: userLocation,
-			Mechanics:  protoMechanics,
+			Id:              repair.RepairCost.ID,
+			UserId:          repair.RepairCost.UserID,
+			RepairType:      repair.RepairCost.RepairType,
+			TotalPrice:      repair.RepairCost.TotalPrice.Major(),
+			TotalPriceMinor: repair.RepairCost.TotalPrice.Minor(),
+			UserLocation:    userLocation,
+			Mechanics:       protoMechanics,
 		},
 	}
 }
@@ -0,0 +1,81 @@
+package kafka
+
+import (
+	"context"
+	"os"
+
+	"repair-service/domain"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+)
+
+// Event bus implementations, selected with EVENT_BUS
+const (
+	BusKafka = "kafka" // Kafka with schema-registry, the default
+	BusMongo = "mongo" // the event_bus collection of the shared MongoDB, for single-node installs
+)
+
+// LocalSchemaID frames Avro payloads published without schema-registry.
+// Registry IDs start at 1, so consumers decode it with their local schema.
+const LocalSchemaID = 0
+
+// EventBus returns the configured event bus: BusMongo when EVENT_BUS is
+// mongo, otherwise BusKafka
+func EventBus() string {
+	if os.Getenv("EVENT_BUS") == BusMongo {
+		return BusMongo
+	}
+	return BusKafka
+}
+
+// Publisher publishes outbox events to the event bus, e.g. the Kafka Producer
+// or the Mongo event bus publisher
+type Publisher interface {
+	PublishOutboxEvent(ctx context.Context, event *domain.OutboxEvent) error
+	// SchemaID is the schema ID framing Avro payloads, LocalSchemaID without a registry
+	SchemaID() int
+	// DeliveryLatency returns per event type latency from outbox creation to delivery
+	DeliveryLatency() map[string]LatencyStats
+	Close()
+}
+
+// ProducedMessages counts the outbox events published to the event bus, by
+// result: ok or error
+var ProducedMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "kafka_messages_produced_total",
+	Help: "Outbox events published, by event bus (kafka, or mongo on single-node installs), topic, event type and result",
+}, []string{"bus", "topic", "event_type", "result"})
+
+// TraceHeaders returns the trace context of ctx as record headers, so the
+// consumer's span continues the publishing trace
+func TraceHeaders(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// PublishAttributes are the messaging.* attributes of a publish span; the
+// bus adds the ones it knows after delivery, e.g. partition and offset
+func PublishAttributes(system, topic string, event *domain.OutboxEvent) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystemKey.String(system),
+		semconv.MessagingOperationTypePublish,
+		semconv.MessagingOperationName("publish"),
+		semconv.MessagingDestinationName(topic),
+		semconv.MessagingMessageID(EventID(event)),
+		semconv.MessagingMessageBodySize(len(event.Payload)),
+		attribute.String("eventID", event.ID),
+	}
+	if event.AggregateID != "" {
+		attrs = append(attrs, semconv.MessagingKafkaMessageKey(event.AggregateID))
+	}
+	if event.Region != "" {
+		attrs = append(attrs, attribute.String("region", event.Region))
+	}
+	return attrs
+}
@@ -0,0 +1,121 @@
+package kafka
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// latencyBucketsMs are the upper bounds of the delivery latency histogram;
+// slower observations fall into a final +Inf bucket
+var latencyBucketsMs = []float64{10, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000, 300000}
+
+// LatencyBucket is a cumulative histogram bucket
+type LatencyBucket struct {
+	Le    string `json:"le"`
+	Count int64  `json:"count"`
+}
+
+// LatencyStats summarizes the latency distribution of one event type.
+// Percentiles are estimated as the upper bound of the bucket they fall in.
+type LatencyStats struct {
+	Count   int64           `json:"count"`
+	SumMs   float64         `json:"sumMs"`
+	MaxMs   float64         `json:"maxMs"`
+	P50Ms   float64         `json:"p50Ms"`
+	P95Ms   float64         `json:"p95Ms"`
+	P99Ms   float64         `json:"p99Ms"`
+	Buckets []LatencyBucket `json:"buckets"`
+}
+
+// latencyHistogram holds per-bucket (non-cumulative) counts
+type latencyHistogram struct {
+	counts []int64 // len(latencyBucketsMs)+1, last is +Inf
+	count  int64
+	sumMs  float64
+	maxMs  float64
+}
+
+// DeliveryMetrics records event propagation latencies per event type
+type DeliveryMetrics struct {
+	mu     sync.Mutex
+	byType map[string]*latencyHistogram
+}
+
+// NewDeliveryMetrics creates an empty DeliveryMetrics
+func NewDeliveryMetrics() *DeliveryMetrics {
+	return &DeliveryMetrics{byType: make(map[string]*latencyHistogram)}
+}
+
+// Observe records one latency for eventType; negative values from clock skew
+// are clamped to zero
+func (m *DeliveryMetrics) Observe(eventType string, latency time.Duration) {
+	ms := math.Max(float64(latency)/float64(time.Millisecond), 0)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.byType[eventType]
+	if !ok {
+		h = &latencyHistogram{counts: make([]int64, len(latencyBucketsMs)+1)}
+		m.byType[eventType] = h
+	}
+	i := 0
+	for i < len(latencyBucketsMs) && ms > latencyBucketsMs[i] {
+		i++
+	}
+	h.counts[i]++
+	h.count++
+	h.sumMs += ms
+	h.maxMs = math.Max(h.maxMs, ms)
+}
+
+// Snapshot returns the latency distribution of every observed event type
+func (m *DeliveryMetrics) Snapshot() map[string]LatencyStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make(map[string]LatencyStats, len(m.byType))
+	for eventType, h := range m.byType {
+		s := LatencyStats{
+			Count:   h.count,
+			SumMs:   h.sumMs,
+			MaxMs:   h.maxMs,
+			Buckets: make([]LatencyBucket, len(h.counts)),
+		}
+		var cumulative int64
+		for i, c := range h.counts {
+			cumulative += c
+			le := "+Inf"
+			if i < len(latencyBucketsMs) {
+				le = strconv.FormatFloat(latencyBucketsMs[i], 'f', -1, 64)
+			}
+			s.Buckets[i] = LatencyBucket{Le: le, Count: cumulative}
+		}
+		s.P50Ms = h.quantile(0.50)
+		s.P95Ms = h.quantile(0.95)
+		s.P99Ms = h.quantile(0.99)
+		stats[eventType] = s
+	}
+	return stats
+}
+
+// quantile returns the upper bound of the bucket holding the q-th observation,
+// or the maximum for the +Inf bucket
+func (h *latencyHistogram) quantile(q float64) float64 {
+	if h.count == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(h.count)))
+	var cumulative int64
+	for i, c := range h.counts {
+		cumulative += c
+		if cumulative >= rank {
+			if i < len(latencyBucketsMs) {
+				return math.Min(latencyBucketsMs[i], h.maxMs)
+			}
+			break
+		}
+	}
+	return h.maxMs
+}
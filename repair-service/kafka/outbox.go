@@ -2,10 +2,12 @@ package kafka
 
 import (
 	"context"
+	"log/slog"
 	"time"
 
 	"repair-service/domain"
-	"log/slog"
+	"repair-service/region"
+
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -13,17 +15,30 @@ import (
 
 // OutboxProcessor processes events from the outbox collection
 type OutboxProcessor struct {
-	repo     domain.RepairRepository
-	producer *Producer
-	logger   *slog.Logger
+	repo         domain.RepairRepository
+	producer     Publisher
+	logger       *slog.Logger
+	pool         *keyedWorkerPool
+	scope        region.Scope  // regions whose events this instance publishes
+	pollInterval time.Duration // catch-up polling period
+	watch        bool          // publish on outbox inserts from a change stream
 }
 
-// NewOutboxProcessor creates a new OutboxProcessor
-func NewOutboxProcessor(repo domain.RepairRepository, producer *Producer, logger *slog.Logger) *OutboxProcessor {
+// NewOutboxProcessor creates a new OutboxProcessor that publishes events with
+// the given number of parallel workers and per-worker queue depth. With
+// REGION_SCOPE set it only publishes events of those regions, leaving the
+// rest to the instances of their region. With watch set, events are published
+// as soon as a change stream reports their insert, and polling every
+// pollInterval only catches up on events missed while the stream was down.
+func NewOutboxProcessor(repo domain.RepairRepository, producer Publisher, logger *slog.Logger, concurrency, queueDepth int, pollInterval time.Duration, watch bool) *OutboxProcessor {
 	return &OutboxProcessor{
-		repo:     repo,
-		producer: producer,
-		logger:   logger,
+		repo:         repo,
+		producer:     producer,
+		logger:       logger,
+		pool:         newKeyedWorkerPool(concurrency, queueDepth),
+		scope:        region.ScopeFromEnv(),
+		pollInterval: pollInterval,
+		watch:        watch,
 	}
 }
 
@@ -32,14 +47,34 @@ func (p *OutboxProcessor) Start(ctx context.Context) error {
 	_, span := otel.Tracer("repair-service").Start(ctx, "OutboxProcessorStart")
 	defer span.End()
 
-	ticker := time.NewTicker(5 * time.Second)
+	p.pool.start()
+	defer p.pool.stop()
+	p.logger.Info("Outbox processor started", "workers", len(p.pool.workers), "regions", p.scope.Names(), "pollInterval", p.pollInterval, "changeStream", p.watch, "app", "repair-service")
+
+	// Inserts reported by the change stream are coalesced into one pending
+	// signal, so a burst of events is read back in a single batch. Batches are
+	// only processed by this loop, never concurrently with polling.
+	inserted := make(chan struct{}, 1)
+	if p.watch {
+		go p.watchInserts(ctx, inserted)
+	}
+
+	ticker := time.NewTicker(p.pollInterval)
 	defer ticker.Stop()
 
+	// Catch up on events left unpublished while the service was down
+	if err := p.processOutboxEvents(ctx); err != nil {
+		p.logger.Error("Failed to process outbox events", "error", err, "app", "repair-service")
+	}
 	for {
 		select {
 		case <-ctx.Done():
 			p.logger.Info("Stopping outbox processor", "app", "repair-service")
 			return ctx.Err()
+		case <-inserted:
+			if err := p.processOutboxEvents(ctx); err != nil {
+				p.logger.Error("Failed to process outbox events", "error", err, "app", "repair-service")
+			}
 		case <-ticker.C:
 			if err := p.processOutboxEvents(ctx); err != nil {
 				p.logger.Error("Failed to process outbox events", "error", err, "app", "repair-service")
@@ -48,12 +83,63 @@ func (p *OutboxProcessor) Start(ctx context.Context) error {
 	}
 }
 
-// processOutboxEvents retrieves and publishes unprocessed outbox events
+// watchInserts signals inserted for every outbox insert until ctx is done. A
+// failed or closed change stream is reopened after a backoff; meanwhile
+// polling keeps publishing, and the first signal after reopening catches up.
+func (p *OutboxProcessor) watchInserts(ctx context.Context, inserted chan<- struct{}) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		err := p.watchOnce(ctx, inserted)
+		if ctx.Err() != nil {
+			return
+		}
+		p.logger.Warn("Outbox change stream stopped, falling back to polling", "error", err, "retryIn", backoff, "app", "repair-service")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		if backoff < p.pollInterval {
+			backoff *= 2
+		}
+	}
+}
+
+// watchOnce follows one outbox change stream until it fails
+func (p *OutboxProcessor) watchOnce(ctx context.Context, inserted chan<- struct{}) error {
+	changeStream, err := p.repo.WatchOutboxEvents(ctx, p.scope.Names())
+	if err != nil {
+		return err
+	}
+	defer changeStream.Close(context.Background())
+	p.logger.Info("Watching outbox inserts", "app", "repair-service")
+
+	signal := func() {
+		select {
+		case inserted <- struct{}{}:
+		default:
+		}
+	}
+	// Events inserted before the stream opened are not reported by it
+	signal()
+	for changeStream.Next(ctx) {
+		signal()
+	}
+	return changeStream.Err()
+}
+
+// WorkerStats returns per-worker processing counters
+func (p *OutboxProcessor) WorkerStats() []WorkerStats {
+	return p.pool.Stats()
+}
+
+// processOutboxEvents retrieves unprocessed outbox events and publishes them in
+// parallel, serializing events that belong to the same aggregate
 func (p *OutboxProcessor) processOutboxEvents(ctx context.Context) error {
-	_, span := otel.Tracer("repair-service").Start(ctx, "ProcessOutboxEvents")
+	ctx, span := otel.Tracer("repair-service").Start(ctx, "ProcessOutboxEvents")
 	defer span.End()
 
-	events, err := p.repo.GetUnprocessedOutboxEvents(ctx)
+	events, err := p.repo.GetUnprocessedOutboxEvents(ctx, p.scope.Names())
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to get unprocessed outbox events")
@@ -64,24 +150,45 @@ func (p *OutboxProcessor) processOutboxEvents(ctx context.Context) error {
 	}
 
 	for _, event := range events {
-		if err := p.producer.PublishOutboxEvent(ctx, event); err != nil {
-			span.RecordError(err)
-			span.SetStatus(codes.Error, "Failed to publish outbox event")
-			p.logger.Error("Failed to publish outbox event", "eventID", event.ID, "error", err, "app", "repair-service")
-			continue
-		}
-
-		if err := p.repo.MarkOutboxEventProcessed(ctx, event.ID); err != nil {
-			span.RecordError(err)
-			span.SetStatus(codes.Error, "Failed to mark outbox event as processed")
-			p.logger.Error("Failed to mark outbox event as processed", "eventID", event.ID, "error", err, "app", "repair-service")
-			continue
+		key := event.AggregateID
+		if key == "" {
+			key = event.ID
 		}
-		p.logger.Info("Processed outbox event", "eventID", event.ID, "app", "repair-service")
+		p.pool.submit(key, func() error {
+			return p.processEvent(ctx, event)
+		})
 	}
+	p.pool.wait()
 
 	span.SetAttributes(
 		attribute.Int("processedEventCount", len(events)),
 	)
 	return nil
 }
+
+// processEvent publishes a single outbox event and marks it processed
+func (p *OutboxProcessor) processEvent(ctx context.Context, event *domain.OutboxEvent) error {
+	ctx, span := otel.Tracer("repair-service").Start(ctx, "ProcessOutboxEvent")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("eventID", event.ID),
+		attribute.String("eventType", event.EventType),
+		attribute.String("aggregateID", event.AggregateID),
+	)
+
+	if err := p.producer.PublishOutboxEvent(ctx, event); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to publish outbox event")
+		p.logger.Error("Failed to publish outbox event", "eventID", event.ID, "error", err, "app", "repair-service")
+		return err
+	}
+
+	if err := p.repo.MarkOutboxEventProcessed(ctx, event.ID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to mark outbox event as processed")
+		p.logger.Error("Failed to mark outbox event as processed", "eventID", event.ID, "error", err, "app", "repair-service")
+		return err
+	}
+	p.logger.Info("Processed outbox event", "eventID", event.ID, "app", "repair-service")
+	return nil
+}
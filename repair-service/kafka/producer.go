@@ -3,8 +3,10 @@ package kafka
 import (
 	"context"
 	"fmt"
-	"os"
 	"repair-service/domain"
+	"repair-service/region"
+	"strconv"
+	"time"
 
 	"log/slog"
 
@@ -12,20 +14,23 @@ import (
 	"github.com/hamba/avro/v2"
 	"github.com/riferrei/srclient"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
 	"go.opentelemetry.io/otel/trace"
 )
 
 // RepairEvent mirrors the Avro schema
 type RepairEvent struct {
-	ID          string               `avro:"id"`
-	UserID      string               `avro:"user_id"`
-	Status      string               `avro:"status"`
-	RepairType  string               `avro:"repair_type"`
-	TotalPrice  float64              `avro:"total_price"`
-	UserLocation *Location           `avro:"user_location"`
-	Mechanics   []MechanicInfo       `avro:"mechanics"`
+	ID              string         `avro:"id"`
+	UserID          string         `avro:"user_id"`
+	Status          string         `avro:"status"`
+	RepairType      string         `avro:"repair_type"`
+	TotalPrice      float64        `avro:"total_price"` // major units, rounded to the minor unit
+	TotalPriceMinor int64          `avro:"total_price_minor"`
+	UserLocation    *Location      `avro:"user_location"`
+	Mechanics       []MechanicInfo `avro:"mechanics"`
+	Symptoms        []Symptom      `avro:"symptoms"`
+	Region          string         `avro:"region"`
 }
 
 type Location struct {
@@ -40,21 +45,47 @@ type MechanicInfo struct {
 	Distance float64  `avro:"distance"`
 }
 
+// Symptom is a structured intake answer attached to a repair
+type Symptom struct {
+	QuestionID string `avro:"question_id"`
+	Question   string `avro:"question"`
+	Answer     string `avro:"answer"`
+}
+
 type Producer struct {
 	kafkaProducer *kafka.Producer
 	srClient      *srclient.SchemaRegistryClient
 	schema        avro.Schema
-	SchemaID      int
+	schemaID      int
 	topic         string
 	logger        *slog.Logger
 	tracer        trace.Tracer
+	delivery      *DeliveryMetrics // outbox creation to broker acknowledgement
+}
+
+// EventTypeHeader carries the outbox event type so consumers can tell events
+// apart, e.g. RepairCancelled ones, and report per-type metrics without
+// decoding the payload
+const EventTypeHeader = "event_type"
+
+// EventIDHeader carries a unique ID per published event, which consumers use
+// to drop duplicate deliveries. It is the outbox event ID, suffixed with the
+// redrive count once a redrive reset the event so the redelivery is applied.
+const EventIDHeader = "event_id"
+
+// EventID is the EventIDHeader value of an outbox event
+func EventID(event *domain.OutboxEvent) string {
+	if event.RedriveCount > 0 {
+		return fmt.Sprintf("%s-redrive-%d", event.ID, event.RedriveCount)
+	}
+	return event.ID
 }
 
 func NewProducer(bootstrapServers, schemaRegistryURL, topic string, logger *slog.Logger) (*Producer, error) {
 	// Initialize Kafka producer
-	config := &kafka.ConfigMap{
-		"bootstrap.servers": bootstrapServers,
-		"compression.type":  "snappy",
+	config, err := ClientConfig(bootstrapServers, kafka.ConfigMap{"compression.type": "snappy"})
+	if err != nil {
+		return nil, err
 	}
 	p, err := kafka.NewProducer(config)
 	if err != nil {
@@ -64,50 +95,74 @@ func NewProducer(bootstrapServers, schemaRegistryURL, topic string, logger *slog
 	// Initialize Schema Registry client
 	srClient := srclient.CreateSchemaRegistryClient(schemaRegistryURL)
 
-	// Load Avro schema
-	schemaBytes, err := os.ReadFile("repair_event.avsc")
+	// The embedded schema, with the ID of the subject's matching version
+	schema, err := RepairEventSchema()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read schema file: %w", err)
+		return nil, err
 	}
-	schemaStr := string(schemaBytes)
-	schema, err := avro.Parse(schemaStr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse schema: %w", err)
-	}
-
-	// Register schema
-	schemaObj, err := srClient.CreateSchema(topic+"-value", schemaStr, srclient.Avro)
+	schemaID, source, err := RegisterSchema(srClient, topic+"-value")
 	if err != nil {
-		return nil, fmt.Errorf("failed to register schema: %w", err)
+		return nil, err
 	}
-	logger.Info("Schema registered", "schemaID", schemaObj.ID(), "app", "repair-service")
+	logger.Info("Schema registered", "schemaID", schemaID, "source", source, "app", "repair-service")
 
 	return &Producer{
 		kafkaProducer: p,
 		srClient:      srClient,
 		schema:        schema,
-		SchemaID:      schemaObj.ID(),
+		schemaID:      schemaID,
 		topic:         topic,
 		logger:        logger,
 		tracer:        otel.Tracer("repair-service"),
+		delivery:      NewDeliveryMetrics(),
 	}, nil
 }
 
-// PublishOutboxEvent publishes an outbox event to Kafka
+// PublishOutboxEvent publishes an outbox event to Kafka, to the producer's
+// topic unless the event names another one
 func (p *Producer) PublishOutboxEvent(ctx context.Context, event *domain.OutboxEvent) error {
-	_, span := p.tracer.Start(ctx, "PublishOutboxEvent")
+	topic := p.topic
+	if event.Topic != "" {
+		topic = TopicName(event.Topic)
+	}
+	ctx, span := p.tracer.Start(ctx, "PublishOutboxEvent",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(PublishAttributes(BusKafka, topic, event)...),
+		trace.WithAttributes(semconv.PeerService("kafka")),
+	)
 	defer span.End()
 
 	// Publish to Kafka
 	deliveryChan := make(chan kafka.Event)
-	err := p.kafkaProducer.Produce(&kafka.Message{
-		TopicPartition: kafka.TopicPartition{Topic: &p.topic, Partition: kafka.PartitionAny},
+	msg := &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic, Partition: kafka.PartitionAny},
 		Value:          event.Payload,
-	}, deliveryChan)
+		Headers: []kafka.Header{
+			{Key: EventTypeHeader, Value: []byte(event.EventType)},
+			{Key: EventIDHeader, Value: []byte(EventID(event))},
+		},
+	}
+	if event.Region != "" {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: region.Header, Value: []byte(event.Region)})
+	}
+	for key, value := range TraceHeaders(ctx) {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: key, Value: []byte(value)})
+	}
+	// Key by aggregate so all events for a repair land on the same partition
+	if event.AggregateID != "" {
+		msg.Key = []byte(event.AggregateID)
+	}
+	// Events without a payload are tombstones: a null value tells compacting
+	// brokers and downstream consumers to forget the key
+	if len(event.Payload) == 0 {
+		msg.Value = nil
+	}
+	err := p.kafkaProducer.Produce(msg, deliveryChan)
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to produce message")
 		p.logger.Error("Failed to produce message", "eventID", event.ID, "error", err, "app", "repair-service")
+		ProducedMessages.WithLabelValues(BusKafka, topic, event.EventType, "error").Inc()
 		return fmt.Errorf("failed to produce message: %w", err)
 	}
 
@@ -118,25 +173,39 @@ func (p *Producer) PublishOutboxEvent(ctx context.Context, event *domain.OutboxE
 		span.RecordError(m.TopicPartition.Error)
 		span.SetStatus(codes.Error, "Delivery failed")
 		p.logger.Error("Delivery failed", "eventID", event.ID, "error", m.TopicPartition.Error, "app", "repair-service")
+		ProducedMessages.WithLabelValues(BusKafka, topic, event.EventType, "error").Inc()
 		return fmt.Errorf("delivery failed: %w", m.TopicPartition.Error)
 	}
+	latency := time.Since(event.CreatedAt)
+	p.delivery.Observe(event.EventType, latency)
+	ProducedMessages.WithLabelValues(BusKafka, topic, event.EventType, "ok").Inc()
 	p.logger.Info("Published outbox event",
 		"eventID", event.ID,
+		"deliveryLatencyMs", latency.Milliseconds(),
 		"topic", *m.TopicPartition.Topic,
 		"partition", m.TopicPartition.Partition,
 		"offset", m.TopicPartition.Offset,
 		"app", "repair-service")
 	span.SetAttributes(
-		attribute.String("eventID", event.ID),
-		attribute.String("topic", *m.TopicPartition.Topic),
-		attribute.Int("partition", int(m.TopicPartition.Partition)),
-		attribute.Int64("offset", int64(m.TopicPartition.Offset)),
+		semconv.MessagingDestinationPartitionID(strconv.Itoa(int(m.TopicPartition.Partition))),
+		semconv.MessagingKafkaMessageOffset(int(m.TopicPartition.Offset)),
 	)
 
 	close(deliveryChan)
 	return nil
 }
 
+// SchemaID returns the Schema Registry ID of the registered repair event schema
+func (p *Producer) SchemaID() int {
+	return p.schemaID
+}
+
+// DeliveryLatency returns, per event type, the latency from outbox event
+// creation to the broker acknowledging delivery
+func (p *Producer) DeliveryLatency() map[string]LatencyStats {
+	return p.delivery.Snapshot()
+}
+
 // Close shuts down the Kafka producer
 func (p *Producer) Close() {
 	p.logger.Info("Closing Kafka producer", "app", "repair-service")
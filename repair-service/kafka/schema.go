@@ -0,0 +1,138 @@
+package kafka
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hamba/avro/v2"
+	"github.com/riferrei/srclient"
+)
+
+// Sources RegisterSchema reports the schema ID came from
+const (
+	SchemaFromRegistry = "registry"
+	SchemaRegistered   = "registered" // the embedded schema was new to the subject
+	SchemaFromCache    = "cache"      // the copy of the last registry lookup in SCHEMA_CACHE_DIR
+)
+
+//go:embed repair_event.avsc
+var embeddedSchema string
+
+var parseEmbeddedSchema = sync.OnceValues(func() (avro.Schema, error) {
+	schema, err := avro.Parse(embeddedSchema)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse embedded schema: %w", err)
+	}
+	return schema, nil
+})
+
+// RepairEventSchema returns the repair event schema built into the binary, the
+// schema every repair event is written with
+func RepairEventSchema() (avro.Schema, error) {
+	return parseEmbeddedSchema()
+}
+
+// DecodeRepairEvent decodes an outbox payload in the Schema Registry wire
+// format (magic byte, 4-byte schema ID, Avro) with the embedded schema.
+// Payloads written with an older schema version may not decode.
+func DecodeRepairEvent(payload []byte) (*RepairEvent, error) {
+	if len(payload) < 5 || payload[0] != 0 {
+		return nil, fmt.Errorf("payload of %d bytes is not in the wire format", len(payload))
+	}
+	schema, err := RepairEventSchema()
+	if err != nil {
+		return nil, err
+	}
+	var event RepairEvent
+	if err := avro.Unmarshal(schema, payload[5:], &event); err != nil {
+		return nil, fmt.Errorf("failed to decode repair event: %w", err)
+	}
+	return &event, nil
+}
+
+// cachedSchema is the latest version of a subject as cached on disk
+type cachedSchema struct {
+	ID     int    `json:"id"`
+	Schema string `json:"schema"`
+}
+
+// RegisterSchema returns the schema-registry ID of the embedded repair event
+// schema under subject. The subject's latest version is fetched first and its
+// ID reused when it is the embedded schema; otherwise the embedded schema is
+// registered. The latest version is cached in SCHEMA_CACHE_DIR, so while the
+// registry is unreachable the producer still starts when the cached version
+// is the embedded schema.
+func RegisterSchema(client *srclient.SchemaRegistryClient, subject string) (int, string, error) {
+	schema, err := RepairEventSchema()
+	if err != nil {
+		return 0, "", err
+	}
+
+	latest, err := client.GetLatestSchema(subject)
+	if err == nil && sameSchema(schema, latest.Schema()) {
+		storeLatestSchema(subject, cachedSchema{ID: latest.ID(), Schema: latest.Schema()})
+		return latest.ID(), SchemaFromRegistry, nil
+	}
+	registered, regErr := client.CreateSchema(subject, embeddedSchema, srclient.Avro)
+	if regErr == nil {
+		storeLatestSchema(subject, cachedSchema{ID: registered.ID(), Schema: embeddedSchema})
+		return registered.ID(), SchemaRegistered, nil
+	}
+
+	if cached, ok := loadLatestSchema(subject); ok && sameSchema(schema, cached.Schema) {
+		return cached.ID, SchemaFromCache, nil
+	}
+	return 0, "", fmt.Errorf("failed to register schema: %w", regErr)
+}
+
+// sameSchema reports whether text parses to schema, compared by canonical form
+func sameSchema(schema avro.Schema, text string) bool {
+	other, err := avro.Parse(text)
+	return err == nil && other.Fingerprint() == schema.Fingerprint()
+}
+
+func latestSchemaPath(subject string) string {
+	dir := os.Getenv("SCHEMA_CACHE_DIR")
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, subject+".latest.json")
+}
+
+func loadLatestSchema(subject string) (cachedSchema, bool) {
+	var cached cachedSchema
+	path := latestSchemaPath(subject)
+	if path == "" {
+		return cached, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || json.Unmarshal(data, &cached) != nil || cached.ID <= 0 {
+		return cached, false
+	}
+	return cached, true
+}
+
+// storeLatestSchema writes the latest version of subject to the disk cache;
+// failures only cost starting without the registry, so they are ignored
+func storeLatestSchema(subject string, cached cachedSchema) {
+	path := latestSchemaPath(subject)
+	if path == "" {
+		return
+	}
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return
+	}
+	os.Rename(tmp, path)
+}
@@ -0,0 +1,131 @@
+package kafka
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// ClientConfig returns the librdkafka configuration for bootstrapServers with
+// the cluster's security settings and the client's own settings. Security is
+// read from the environment:
+//
+//   - KAFKA_SECURITY_PROTOCOL: PLAINTEXT (default), SSL, SASL_PLAINTEXT or SASL_SSL
+//   - KAFKA_SASL_MECHANISM: PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512, with
+//     KAFKA_SASL_USERNAME and KAFKA_SASL_PASSWORD
+//   - KAFKA_SSL_CA_LOCATION, KAFKA_SSL_CERTIFICATE_LOCATION and
+//     KAFKA_SSL_KEY_LOCATION (PEM files; the certificate and key for mutual
+//     TLS), KAFKA_SSL_KEY_PASSWORD, and KAFKA_SSL_ENDPOINT_IDENTIFICATION
+//     (https, the default, or none to skip the hostname check)
+//
+// Every credential may instead be read from the file named by the variable
+// with a _FILE suffix, e.g. a mounted secret in KAFKA_SASL_PASSWORD_FILE.
+func ClientConfig(bootstrapServers string, settings kafka.ConfigMap) (*kafka.ConfigMap, error) {
+	config := kafka.ConfigMap{"bootstrap.servers": bootstrapServers}
+	security, err := securityConfig()
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range security {
+		config[k] = v
+	}
+	for k, v := range settings {
+		config[k] = v
+	}
+	return &config, nil
+}
+
+// SecurityProtocol returns KAFKA_SECURITY_PROTOCOL, PLAINTEXT when unset
+func SecurityProtocol() string {
+	if v := strings.ToUpper(strings.TrimSpace(os.Getenv("KAFKA_SECURITY_PROTOCOL"))); v != "" {
+		return v
+	}
+	return "PLAINTEXT"
+}
+
+// securityConfig returns the security settings of KAFKA_SECURITY_PROTOCOL
+func securityConfig() (kafka.ConfigMap, error) {
+	protocol := SecurityProtocol()
+	config := kafka.ConfigMap{}
+	switch protocol {
+	case "PLAINTEXT":
+		return config, nil
+	case "SSL", "SASL_PLAINTEXT", "SASL_SSL":
+		config["security.protocol"] = strings.ToLower(protocol)
+	default:
+		return nil, fmt.Errorf("unsupported KAFKA_SECURITY_PROTOCOL %q", protocol)
+	}
+
+	if strings.HasPrefix(protocol, "SASL_") {
+		mechanism := strings.ToUpper(strings.TrimSpace(os.Getenv("KAFKA_SASL_MECHANISM")))
+		if mechanism == "" {
+			mechanism = "PLAIN"
+		}
+		switch mechanism {
+		case "PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512":
+		default:
+			return nil, fmt.Errorf("unsupported KAFKA_SASL_MECHANISM %q", mechanism)
+		}
+		username, err := secret("KAFKA_SASL_USERNAME")
+		if err != nil {
+			return nil, err
+		}
+		password, err := secret("KAFKA_SASL_PASSWORD")
+		if err != nil {
+			return nil, err
+		}
+		if username == "" || password == "" {
+			return nil, fmt.Errorf("KAFKA_SASL_USERNAME and KAFKA_SASL_PASSWORD are required with %s", protocol)
+		}
+		config["sasl.mechanism"] = mechanism
+		config["sasl.username"] = username
+		config["sasl.password"] = password
+	}
+
+	if protocol == "SSL" || protocol == "SASL_SSL" {
+		for env, key := range map[string]string{
+			"KAFKA_SSL_CA_LOCATION":          "ssl.ca.location",
+			"KAFKA_SSL_CERTIFICATE_LOCATION": "ssl.certificate.location",
+			"KAFKA_SSL_KEY_LOCATION":         "ssl.key.location",
+		} {
+			if v := os.Getenv(env); v != "" {
+				config[key] = v
+			}
+		}
+		keyPassword, err := secret("KAFKA_SSL_KEY_PASSWORD")
+		if err != nil {
+			return nil, err
+		}
+		if keyPassword != "" {
+			config["ssl.key.password"] = keyPassword
+		}
+		switch v := strings.ToLower(os.Getenv("KAFKA_SSL_ENDPOINT_IDENTIFICATION")); v {
+		case "", "https":
+			config["ssl.endpoint.identification.algorithm"] = "https"
+		case "none":
+			config["ssl.endpoint.identification.algorithm"] = "none"
+		default:
+			return nil, fmt.Errorf("unsupported KAFKA_SSL_ENDPOINT_IDENTIFICATION %q", v)
+		}
+	}
+	return config, nil
+}
+
+// secret returns the value of the environment variable name, or the content
+// of the file named by name_FILE without its trailing newline
+func secret(name string) (string, error) {
+	if v := os.Getenv(name); v != "" {
+		return v, nil
+	}
+	path := os.Getenv(name + "_FILE")
+	if path == "" {
+		return "", nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s_FILE: %w", name, err)
+	}
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
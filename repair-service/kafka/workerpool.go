@@ -0,0 +1,134 @@
+package kafka
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// WorkerStats holds processing counters for a single outbox worker. Skipped
+// counts jobs not run because an earlier job for the same key failed in the
+// same batch.
+type WorkerStats struct {
+	Worker        int           `json:"worker"`
+	Processed     int64         `json:"processed"`
+	Failed        int64         `json:"failed"`
+	Skipped       int64         `json:"skipped"`
+	TotalDuration time.Duration `json:"totalDuration"`
+	LastEventAt   time.Time     `json:"lastEventAt,omitempty"`
+}
+
+// keyedJob is a unit of work routed to a worker by its key
+type keyedJob struct {
+	key string
+	run func() error
+}
+
+// keyedWorkerPool runs jobs in parallel while jobs sharing a key always land on
+// the same worker, so events for one aggregate are handled in submission order.
+// Once a job fails, the remaining jobs for its key are skipped until wait
+// returns, so a later event is never applied ahead of an earlier failed one.
+type keyedWorkerPool struct {
+	workers    []chan keyedJob
+	queueDepth int
+	stats      []WorkerStats
+	failedKeys map[string]bool
+	mu         sync.Mutex
+	wg         sync.WaitGroup
+}
+
+// newKeyedWorkerPool creates a pool with the given number of workers, each
+// buffering up to queueDepth pending jobs
+func newKeyedWorkerPool(concurrency, queueDepth int) *keyedWorkerPool {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if queueDepth < 1 {
+		queueDepth = 1
+	}
+	p := &keyedWorkerPool{
+		workers:    make([]chan keyedJob, concurrency),
+		queueDepth: queueDepth,
+		stats:      make([]WorkerStats, concurrency),
+		failedKeys: make(map[string]bool),
+	}
+	for i := range p.workers {
+		p.stats[i].Worker = i
+	}
+	return p
+}
+
+// start launches the worker goroutines
+func (p *keyedWorkerPool) start() {
+	for i := range p.workers {
+		// Fresh queues on every start, so a restarted processor can reuse the pool
+		p.workers[i] = make(chan keyedJob, p.queueDepth)
+		go p.runWorker(i, p.workers[i])
+	}
+}
+
+// stop closes the worker queues; no jobs may be submitted until the next start
+func (p *keyedWorkerPool) stop() {
+	for _, ch := range p.workers {
+		close(ch)
+	}
+}
+
+// submit queues a job on the worker owning its key
+func (p *keyedWorkerPool) submit(key string, run func() error) {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	idx := int(h.Sum32() % uint32(len(p.workers)))
+	p.wg.Add(1)
+	p.workers[idx] <- keyedJob{key: key, run: run}
+}
+
+// wait blocks until every submitted job has finished and resets the failed
+// keys for the next batch
+func (p *keyedWorkerPool) wait() {
+	p.wg.Wait()
+	p.mu.Lock()
+	p.failedKeys = make(map[string]bool)
+	p.mu.Unlock()
+}
+
+// Stats returns a snapshot of per-worker counters
+func (p *keyedWorkerPool) Stats() []WorkerStats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]WorkerStats, len(p.stats))
+	copy(out, p.stats)
+	return out
+}
+
+func (p *keyedWorkerPool) runWorker(idx int, jobs <-chan keyedJob) {
+	for job := range jobs {
+		p.mu.Lock()
+		skip := p.failedKeys[job.key]
+		if skip {
+			p.stats[idx].Skipped++
+		}
+		p.mu.Unlock()
+		if skip {
+			p.wg.Done()
+			continue
+		}
+
+		start := time.Now()
+		err := job.run()
+		elapsed := time.Since(start)
+
+		p.mu.Lock()
+		if err != nil {
+			p.stats[idx].Failed++
+			p.failedKeys[job.key] = true
+		} else {
+			p.stats[idx].Processed++
+		}
+		p.stats[idx].TotalDuration += elapsed
+		p.stats[idx].LastEventAt = time.Now()
+		p.mu.Unlock()
+
+		p.wg.Done()
+	}
+}
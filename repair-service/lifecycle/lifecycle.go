@@ -0,0 +1,119 @@
+// Package lifecycle starts the parts of a service together and stops them in
+// dependency order on shutdown
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Manager runs registered components as a group: they start in registration
+// order, and when the context is done or any of them stops on its own, all of
+// them are stopped in reverse order, so a component is stopped before the
+// ones it depends on. A component that does not stop within the stop timeout
+// is abandoned, logged as force-stopped, and shutdown moves on.
+type Manager struct {
+	components  []*component
+	stopTimeout time.Duration
+	logger      *slog.Logger
+}
+
+type component struct {
+	name  string
+	start func() error
+	stop  func(ctx context.Context) error
+	done  chan struct{} // closed when start returns
+	err   error
+}
+
+// New creates a Manager whose per-component stop timeout is
+// LIFECYCLE_STOP_TIMEOUT_SECONDS (default 10)
+func New(logger *slog.Logger) *Manager {
+	m := &Manager{stopTimeout: 10 * time.Second, logger: logger}
+	if v, err := strconv.Atoi(os.Getenv("LIFECYCLE_STOP_TIMEOUT_SECONDS")); err == nil && v > 0 {
+		m.stopTimeout = time.Duration(v) * time.Second
+	}
+	return m
+}
+
+// Add registers a component after the ones it depends on. start runs it and
+// blocks until it stops; a nil start registers a resource that only needs
+// releasing, such as a client connection. stop asks the component to stop
+// and returns once it has; its context expires with the stop timeout.
+func (m *Manager) Add(name string, start func() error, stop func(ctx context.Context) error) {
+	m.components = append(m.components, &component{name: name, start: start, stop: stop, done: make(chan struct{})})
+}
+
+// Run starts every component and blocks until ctx is done or a component's
+// start returns, then stops them all. It returns the error of the component
+// that stopped first, if any.
+func (m *Manager) Run(ctx context.Context) error {
+	stopped := make(chan *component, len(m.components))
+	for _, c := range m.components {
+		if c.start == nil {
+			close(c.done)
+			continue
+		}
+		m.logger.Info("Starting component", "component", c.name, "app", "repair-service")
+		go func(c *component) {
+			c.err = c.start()
+			close(c.done)
+			stopped <- c
+		}(c)
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+		m.logger.Info("Shutting down components", "app", "repair-service")
+	case c := <-stopped:
+		err = c.err
+		if err == nil {
+			err = errors.New(c.name + " stopped unexpectedly")
+		}
+		m.logger.Error("Component stopped, shutting down the others", "component", c.name, "error", err, "app", "repair-service")
+	}
+
+	for i := len(m.components) - 1; i >= 0; i-- {
+		m.stop(m.components[i])
+	}
+	m.logger.Info("All components stopped", "app", "repair-service")
+	return err
+}
+
+// stop stops one component and waits for its start to return, for at most the
+// stop timeout
+func (m *Manager) stop(c *component) {
+	ctx, cancel := context.WithTimeout(context.Background(), m.stopTimeout)
+	defer cancel()
+
+	started := time.Now()
+	stopErr := make(chan error, 1)
+	go func() {
+		if c.stop == nil {
+			stopErr <- nil
+			return
+		}
+		stopErr <- c.stop(ctx)
+	}()
+
+	select {
+	case err := <-stopErr:
+		if err != nil {
+			m.logger.Error("Failed to stop component", "component", c.name, "error", err, "app", "repair-service")
+		}
+	case <-ctx.Done():
+		m.logger.Error("Component did not stop in time, force-stopping", "component", c.name, "timeout", m.stopTimeout, "app", "repair-service")
+		return
+	}
+	select {
+	case <-c.done:
+		m.logger.Info("Stopped component", "component", c.name, "duration", time.Since(started), "app", "repair-service")
+	case <-ctx.Done():
+		m.logger.Error("Component did not stop in time, force-stopping", "component", c.name, "timeout", m.stopTimeout, "app", "repair-service")
+	}
+}
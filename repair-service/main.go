@@ -3,24 +3,43 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
+	"repair-service/connect"
+	"repair-service/deadline"
 	"repair-service/domain"
 	"repair-service/grpcsvc"
+	"repair-service/lifecycle"
 	"repair-service/logging"
+	"repair-service/metrics"
+	"repair-service/presence"
 	"repair-service/proto"
+	"repair-service/readonly"
+	"repair-service/receipt"
 	"repair-service/service"
+	"repair-service/slowlog"
+	"repair-service/telemetry"
 
 	"log/slog"
 
 	"github.com/gorilla/mux"
 	"github.com/hashicorp/consul/api"  // Add this import
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
@@ -32,7 +51,9 @@ import (
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/reflection"
 )
 
@@ -85,13 +106,13 @@ func initTracer(logger *slog.Logger) (func(), error) {
 	}, nil
 }
 
-func connectToMongoDB(uri string, retries int, delay time.Duration, logger *slog.Logger) (*mongo.Client, error) {
+func connectToMongoDB(uri string, retries int, delay time.Duration, monitor *event.CommandMonitor, logger *slog.Logger) (*mongo.Client, error) {
 	var client *mongo.Client
 	var err error
 
 	for i := range retries {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-		client, err = mongo.Connect(ctx, options.Client().ApplyURI(uri))
+		client, err = mongo.Connect(ctx, options.Client().ApplyURI(uri).SetMonitor(monitor))
 		if err == nil {
 			err = client.Ping(ctx, nil)
 			if err == nil {
@@ -120,6 +141,12 @@ func connectToMongoDB(uri string, retries int, delay time.Duration, logger *slog
 }
 
 func main() {
+	selfTest := flag.Bool("selftest", false, "check connectivity to dependencies, print a JSON report and exit")
+	flag.Parse()
+	if *selfTest {
+		os.Exit(runSelfTest())
+	}
+
 	// Initialize structured logging
 	logger, logFile, err := logging.NewLogger()
 	if err != nil {
@@ -132,6 +159,9 @@ func main() {
 	// Log startup
 	logger.Info("Starting repair-service", "app", "repair-service", "timestamp", time.Now().Unix())
 
+	// Components registered below are stopped in reverse order on shutdown
+	components := lifecycle.New(logger)
+
 	// Initialize Consul client and register service
 	consulAddr := os.Getenv("CONSUL_ADDRESS")
 	if consulAddr == "" {
@@ -160,17 +190,37 @@ func main() {
 		Name:    serviceName,
 		Port:    8087,
 		Address: "repair-service",
+		Meta:    map[string]string{"region": os.Getenv("SERVICE_REGION"), "zone": os.Getenv("SERVICE_ZONE")}, // used by the gateway to prefer nearby instances
 		Check: &api.AgentServiceCheck{
 			HTTP:     "http://repair-service:8087/health",
 			Interval: "10s",
 			Timeout:  "5s",
 		},
 	}
+	// Connect native (CONNECT_NATIVE=true): services reach this one over mTLS
+	// on CONNECT_PORT as Consul intentions allow; the health check stays on
+	// the plain port, which serves nothing else
+	connectPort := 9087
+	if v, err := strconv.Atoi(os.Getenv("CONNECT_PORT")); err == nil && v > 0 {
+		connectPort = v
+	}
+	if connect.Enabled() {
+		registration.Port = connectPort
+		registration.Connect = &api.AgentServiceConnect{Native: true}
+	}
 	if err := consulClient.Agent().ServiceRegister(registration); err != nil {
 		logger.Error("Failed to register with Consul", "error", err, "app", "repair-service")
 		os.Exit(1)
 	}
 	logger.Info("Registered with Consul", "serviceID", serviceID, "app", "repair-service")
+	var mesh *connect.Service
+	if connect.Enabled() {
+		if mesh, err = connect.New(consulClient, serviceName, logger); err != nil {
+			logger.Error("Failed to set up Consul Connect", "error", err, "app", "repair-service")
+			os.Exit(1)
+		}
+		logger.Info("Consul Connect native enabled", "port", connectPort, "app", "repair-service")
+	}
 
 	// Initialize tracer
 	shutdown, err := initTracer(logger)
@@ -178,28 +228,47 @@ func main() {
 		logger.Error("Failed to initialize tracer", "error", err, "app", "repair-service")
 		os.Exit(1)
 	}
-	defer shutdown()
+	components.Add("tracer", nil, func(ctx context.Context) error {
+		shutdown()
+		return nil
+	})
 
 	// Connect to MongoDB with retries
-	client, err := connectToMongoDB("mongodb://mongodb:27017/repairdb?replicaSet=rs0", 5, 2*time.Second, logger)
+	// Log and count slow Mongo commands, downstream calls and handlers
+	slow := slowlog.New(logger)
+
+	client, err := connectToMongoDB("mongodb://mongodb:27017/repairdb?replicaSet=rs0", 5, 2*time.Second, telemetry.MongoMonitor(slow.MongoMonitor()), logger)
 	if err != nil {
 		logger.Error("Failed to connect to MongoDB", "error", err, "app", "repair-service")
 		os.Exit(1)
 	}
-	defer func() {
-		if err := client.Disconnect(context.Background()); err != nil {
-			logger.Error("Failed to disconnect from MongoDB", "error", err, "app", "repair-service")
-		}
-	}()
+	components.Add("mongodb", nil, client.Disconnect)
 	logger.Info("Connected to MongoDB", "uri", "mongodb://mongodb:27017/repairdb?replicaSet=rs0", "app", "repair-service")
 
 	// Initialize repository and service
-	repo := domain.NewMongoRepository(client)
-	svc := service.NewService(repo, logger)
+	// STORAGE_RETRY_ATTEMPTS caps the attempts of reads and transactions
+	// failing transiently (default 3; 1 disables retries)
+	retryAttempts, _ := strconv.Atoi(os.Getenv("STORAGE_RETRY_ATTEMPTS"))
+	repo, err := domain.NewRepository(domain.StorageConfig{Backend: os.Getenv("STORAGE_BACKEND"), MongoClient: client, RetryAttempts: retryAttempts})
+	if err != nil {
+		logger.Error("Failed to create repository", "error", err, "backend", os.Getenv("STORAGE_BACKEND"), "app", "repair-service")
+		os.Exit(1)
+	}
+	svc := service.NewService(repo, slow, logger)
+	components.Add("service", nil, svc.Shutdown)
+	presenceClient := presence.NewClient(consulClient, logger)
+	// Serve only reads while the read-only switch is set in Consul KV
+	readOnly := readonly.New(consulClient, logger)
 
 	// Initialize router
 	r := mux.NewRouter()
 	r.Use(otelmux.Middleware("repair-service"))
+	r.Use(slow.Middleware)
+	// Count and time requests per route for Prometheus
+	r.Use(metrics.Middleware)
+	// Honor the time budget the gateway propagates
+	r.Use(deadline.Middleware(logger))
+	r.Use(readOnly.Middleware)
 
 	// Health check endpoint for Consul
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -207,17 +276,114 @@ func main() {
 		defer span.End()
 		logger.Info("Health check requested", "app", "repair-service")
 		w.WriteHeader(http.StatusOK)
+		if readOnly.State() != nil {
+			// Reads are still served, so stay healthy for Consul
+			fmt.Fprintln(w, "OK (read-only)")
+			return
+		}
 		fmt.Fprintln(w, "OK")
 	}).Methods("GET")
 
+	// Readiness endpoint: 503 while the outbox processor is down or restarting;
+	// read-only mode is reported but does not make the instance unready
+	r.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		_, span := otel.Tracer("repair-service").Start(r.Context(), "Readiness")
+		defer span.End()
+
+		ready := svc.Ready()
+		span.SetAttributes(attribute.Bool("ready", ready))
+		w.Header().Set("Content-Type", "application/json")
+		if !ready {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		body := map[string]interface{}{"ready": ready, "loops": svc.LoopHealth(), "readOnly": false}
+		if state := readOnly.State(); state != nil {
+			body["readOnly"], body["readOnlyMode"] = true, state
+		}
+		json.NewEncoder(w).Encode(body)
+	}).Methods("GET")
+
+	// Prometheus metrics: requests per route, published events and the
+	// outbox backlog, read when scraped
+	metrics.RegisterOutboxBacklog(repo, logger)
+	r.Handle("/metrics", promhttp.Handler()).Methods("GET")
+
+	// Outbox worker statistics endpoint
+	r.HandleFunc("/outbox/stats", func(w http.ResponseWriter, r *http.Request) {
+		_, span := otel.Tracer("repair-service").Start(r.Context(), "OutboxStats")
+		defer span.End()
+
+		stats := svc.OutboxStats()
+		span.SetAttributes(attribute.Int("workerCount", len(stats)))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+	}).Methods("GET")
+
+	// Operations that exceeded their SLOW_* threshold, most frequent first
+	r.HandleFunc("/metrics/slow", func(w http.ResponseWriter, r *http.Request) {
+		_, span := otel.Tracer("repair-service").Start(r.Context(), "SlowOperations")
+		defer span.End()
+
+		counts := slow.Counts()
+		span.SetAttributes(attribute.Int("operationCount", len(counts)))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(counts)
+	}).Methods("GET")
+
+	// Event delivery latency endpoint: outbox creation to Kafka delivery per event type
+	r.HandleFunc("/metrics/delivery", func(w http.ResponseWriter, r *http.Request) {
+		_, span := otel.Tracer("repair-service").Start(r.Context(), "DeliveryMetrics")
+		defer span.End()
+
+		stats := svc.DeliveryLatency()
+		span.SetAttributes(attribute.Int("eventTypeCount", len(stats)))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+	}).Methods("GET")
+
+	// Routing provider health: retries, failures and cooldowns per provider
+	r.HandleFunc("/routing/providers", func(w http.ResponseWriter, r *http.Request) {
+		_, span := otel.Tracer("repair-service").Start(r.Context(), "RoutingProviders")
+		defer span.End()
+
+		health := svc.RoutingHealth()
+		span.SetAttributes(attribute.Int("providerCount", len(health)))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(health)
+	}).Methods("GET")
+
+	// Distance tiles estimates fall back to while OSRM is unreachable
+	r.HandleFunc("/routing/tiles", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "RoutingTiles")
+		defer span.End()
+
+		w.Header().Set("Content-Type", "application/json")
+		status, err := svc.DistanceTiles(ctx)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		span.SetAttributes(attribute.Int("loadedTiles", status.Loaded))
+		json.NewEncoder(w).Encode(status)
+	}).Methods("GET")
+
 	// Create repair endpoint
 	r.HandleFunc("/repairs", func(w http.ResponseWriter, r *http.Request) {
 		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "CreateRepair")
 		defer span.End()
 
 		logger.Info("Received POST /repairs request", "app", "repair-service")
-		var cost domain.RepairCostModel
-		if err := json.NewDecoder(r.Body).Decode(&cost); err != nil {
+		var input struct {
+			domain.RepairCostModel
+			IntakeAnswers []domain.SymptomAnswer `json:"intakeAnswers"`
+			// Override the preference of the estimate, if any
+			PreferredMechanicID       string `json:"preferredMechanicID"`
+			PreferredMechanicFallback string `json:"preferredMechanicFallback"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, "Invalid request body")
 			logger.Error("Failed to decode request body", "error", err, "app", "repair-service")
@@ -226,24 +392,37 @@ func main() {
 			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
 			return
 		}
+		cost := input.RepairCostModel
+		if input.PreferredMechanicID != "" || input.PreferredMechanicFallback != "" {
+			cost.Preference = &domain.MechanicPreference{MechanicID: input.PreferredMechanicID, Fallback: input.PreferredMechanicFallback}
+		}
 		logger.Info("Decoded cost", "cost", cost, "app", "repair-service")
 		span.SetAttributes(
 			attribute.String("userID", cost.UserID),
 			attribute.String("repairType", cost.RepairType),
-			attribute.Float64("totalPrice", cost.TotalPrice),
+			attribute.Int64("totalPriceMinor", cost.TotalPrice.Minor()),
 		)
 		if cost.ID == "" {
 			cost.ID = primitive.NewObjectID().Hex()
 			logger.Info("Generated new ID for cost", "costID", cost.ID, "app", "repair-service")
 			span.SetAttributes(attribute.String("costID", cost.ID))
 		}
-		repair, err := svc.CreateRepair(ctx, &cost)
+		repair, err := svc.CreateRepair(ctx, &cost, input.IntakeAnswers)
 		if err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, "Failed to create repair")
 			logger.Error("Failed to create repair", "error", err, "app", "repair-service")
 			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusInternalServerError)
+			if errors.Is(err, domain.ErrInvalidInput) {
+				w.WriteHeader(http.StatusBadRequest)
+			} else if errors.Is(err, domain.ErrBlacklisted) || errors.Is(err, domain.ErrPhoneNotVerified) ||
+				errors.Is(err, domain.ErrSpendingLimit) || errors.Is(err, domain.ErrRepairTypeNotAllowed) {
+				w.WriteHeader(http.StatusForbidden)
+			} else if errors.Is(err, domain.ErrPreferredMechanicUnavailable) {
+				w.WriteHeader(http.StatusConflict)
+			} else {
+				w.WriteHeader(storageErrorStatus(w, err))
+			}
 			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to create repair: " + err.Error()})
 			return
 		}
@@ -268,6 +447,11 @@ func main() {
 			RepairType string          `json:"repairType"`
 			UserID     string          `json:"userID"`
 			Location   domain.Location `json:"location"`
+			Sort       string          `json:"sort"` // see domain.EstimateSorts
+			// A mechanic to pin the estimate to, and what to do when they
+			// cannot take it, see domain.MechanicPreference
+			PreferredMechanicID       string `json:"preferredMechanicID"`
+			PreferredMechanicFallback string `json:"preferredMechanicFallback"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 			span.RecordError(err)
@@ -284,13 +468,23 @@ func main() {
 			attribute.Float64("location.longitude", input.Location.Longitude),
 			attribute.Float64("location.latitude", input.Location.Latitude),
 		)
-		cost, err := svc.EstimateRepairCost(ctx, input.RepairType, input.UserID, &input.Location)
+		var preference *domain.MechanicPreference
+		if input.PreferredMechanicID != "" || input.PreferredMechanicFallback != "" {
+			preference = &domain.MechanicPreference{MechanicID: input.PreferredMechanicID, Fallback: input.PreferredMechanicFallback}
+		}
+		cost, err := svc.EstimateRepairCost(ctx, input.RepairType, input.UserID, &input.Location, input.Sort, preference)
 		if err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, "Failed to estimate repair cost")
 			logger.Error("Failed to estimate repair cost", "error", err, "app", "repair-service")
 			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
+			if errors.Is(err, domain.ErrBlacklisted) || errors.Is(err, domain.ErrSpendingLimit) || errors.Is(err, domain.ErrRepairTypeNotAllowed) {
+				w.WriteHeader(http.StatusForbidden)
+			} else if errors.Is(err, domain.ErrPreferredMechanicUnavailable) {
+				w.WriteHeader(http.StatusConflict)
+			} else {
+				w.WriteHeader(http.StatusBadRequest)
+			}
 			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to estimate repair cost: " + err.Error()})
 			return
 		}
@@ -305,13 +499,85 @@ func main() {
 		}
 	}).Methods("POST")
 
-	// Get all repairs endpoint
+	// Claim an anonymous quote for a registered user before it becomes a repair
+	r.HandleFunc("/repairs/cost/{costID}/claim", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "ClaimQuote")
+		defer span.End()
+
+		costID := mux.Vars(r)["costID"]
+		var input struct {
+			UserID string `json:"userID"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Invalid request body")
+			logger.Error("Failed to decode request body", "error", err, "app", "repair-service")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		span.SetAttributes(
+			attribute.String("costID", costID),
+			attribute.String("userID", input.UserID),
+		)
+
+		cost, err := svc.ClaimQuote(ctx, costID, input.UserID)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to claim quote")
+			logger.Error("Failed to claim quote", "error", err, "costID", costID, "app", "repair-service")
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case errors.Is(err, domain.ErrInvalidInput):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, domain.ErrBlacklisted):
+				w.WriteHeader(http.StatusForbidden)
+			case errors.Is(err, mongo.ErrNoDocuments):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, domain.ErrQuoteClaimed):
+				w.WriteHeader(http.StatusConflict)
+			default:
+				w.WriteHeader(storageErrorStatus(w, err))
+			}
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to claim quote: " + err.Error()})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(cost)
+	}).Methods("POST")
+
+	// List repairs a page at a time: limit (default 100, at most 1000) from
+	// offset (or skip), in ID order unless sort orders them. ?status= and
+	// ?repairType= (comma separated), ?userID=, ?tag= (repeatable, repairs
+	// carrying every tag) and ?bbox=minLon,minLat,maxLon,maxLat of the user
+	// location filter them; fields and readPreference narrow the result.
 	r.HandleFunc("/repairs", func(w http.ResponseWriter, r *http.Request) {
 		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "GetAllRepairs")
 		defer span.End()
 
-		logger.Info("Received GET /repairs request", "app", "repair-service")
-		repairs, err := svc.GetAllRepairs(ctx)
+		query := r.URL.Query()
+		logger.Info("Received GET /repairs request", "tags", query["tag"], "bbox", query.Get("bbox"), "status", query.Get("status"), "repairType", query.Get("repairType"), "app", "repair-service")
+		if offset := query.Get("offset"); offset != "" && query.Get("skip") == "" {
+			query.Set("skip", offset)
+		}
+		var repairs []*domain.RepairModel
+		filter := domain.RepairFilter{
+			Statuses:    splitList(query.Get("status")),
+			UserID:      query.Get("userID"),
+			RepairTypes: splitList(query.Get("repairType")),
+			Tags:        query["tag"],
+		}
+		opts, err := parseQueryOptions(query)
+		if bbox := query.Get("bbox"); err == nil && bbox != "" {
+			var box domain.BoundingBox
+			if box, err = parseBoundingBox(bbox); err == nil {
+				filter.BoundingBox = &box
+			}
+		}
+		if err == nil {
+			repairs, err = svc.GetAllRepairs(ctx, filter, opts)
+		}
 		if err != nil {
 			span.RecordError(err)
 			span.SetStatus(codes.Error, "Failed to get repairs")
@@ -336,36 +602,1476 @@ func main() {
 		logger.Info("Successfully sent response for GET /repairs", "app", "repair-service")
 	}).Methods("GET")
 
-	// Start gRPC server in a separate goroutine
-	go func() {
-		grpcPort := os.Getenv("GRPC_PORT")
-		if grpcPort == "" {
-			grpcPort = "50051"
+	// Get repair by ID endpoint
+	r.HandleFunc("/repairs/{repairID}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "GetRepairByID")
+		defer span.End()
+
+		repairID := mux.Vars(r)["repairID"]
+		span.SetAttributes(attribute.String("repairID", repairID))
+		logger.Info("Received GET /repairs/{repairID} request", "repairID", repairID, "app", "repair-service")
+
+		// ?asOf=<RFC 3339 time> answers the repair's state at that moment
+		if v := r.URL.Query().Get("asOf"); v != "" {
+			asOf, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeServiceError(w, span, logger, "Invalid asOf", fmt.Errorf("%w: asOf must be an RFC 3339 time, e.g. 2024-05-01T14:30:00Z", domain.ErrInvalidInput))
+				return
+			}
+			snapshot, err := svc.GetRepairAsOf(ctx, repairID, asOf)
+			if err != nil {
+				writeServiceError(w, span, logger, "Failed to get repair state", err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(snapshot)
+			return
 		}
-		lis, err := net.Listen("tcp", ":"+grpcPort)
+
+		repair, err := svc.GetRepairByID(ctx, repairID)
 		if err != nil {
-			logger.Error("Failed to listen for gRPC", "error", err, "app", "repair-service")
-			os.Exit(1)
+			writeServiceError(w, span, logger, "Failed to get repair", err)
+			return
 		}
-		grpcServer := grpc.NewServer()
-		proto.RegisterRepairServiceServer(grpcServer, grpcsvc.NewRepairServer(repo, logger))
-		reflection.Register(grpcServer)
-		logger.Info("Starting gRPC server", "port", grpcPort, "app", "repair-service")
-		if err := grpcServer.Serve(lis); err != nil {
-			logger.Error("Failed to start gRPC server", "error", err, "app", "repair-service")
-			os.Exit(1)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(repair); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to encode response")
+			logger.Error("Failed to encode response", "error", err, "app", "repair-service")
+			return
 		}
-	}()
+	}).Methods("GET")
 
-	// Start server
-	port := os.Getenv("SERVICE_PORT")
-	if port == "" {
-		port = "8087"
-	}
-	logger.Info("Starting repair-service", "port", port, "app", "repair-service")
-	if err := http.ListenAndServe(":"+port, r); err != nil {
-		logger.Error("Failed to start server", "error", err, "app", "repair-service")
-		svc.KafkaProducer.Close()
-		os.Exit(1)
+	// Update repair status endpoint, responds with the updated repair
+	r.HandleFunc("/repairs/{repairID}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "UpdateRepair")
+		defer span.End()
+
+		repairID := mux.Vars(r)["repairID"]
+		span.SetAttributes(attribute.String("repairID", repairID))
+		logger.Info("Received PUT /repairs/{repairID} request", "repairID", repairID, "app", "repair-service")
+
+		var input struct {
+			Status           string        `json:"status"`
+			PaymentReference string        `json:"paymentReference"` // printed on the receipt when completing
+			FinalAmount      *domain.Money `json:"finalAmount"`      // confirms the approved total when completing with amendments
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Invalid request body")
+			logger.Error("Failed to decode request body", "error", err, "app", "repair-service")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		span.SetAttributes(attribute.String("status", input.Status))
+
+		repair, err := svc.UpdateRepair(ctx, repairID, input.Status, input.PaymentReference, input.FinalAmount)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to update repair")
+			logger.Error("Failed to update repair", "error", err, "repairID", repairID, "app", "repair-service")
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case errors.Is(err, mongo.ErrNoDocuments):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, domain.ErrInvalidInput):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, domain.ErrAmendmentPending):
+				w.WriteHeader(http.StatusConflict)
+			default:
+				w.WriteHeader(storageErrorStatus(w, err))
+			}
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to update repair: " + err.Error()})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(repair); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to encode response")
+			logger.Error("Failed to encode response", "error", err, "app", "repair-service")
+			return
+		}
+		// Status changes reach connected users through the gateway WebSocket;
+		// others need a push notification
+		channel := presenceClient.DeliveryChannel(ctx, repair.UserID)
+		span.SetAttributes(attribute.String("deliveryChannel", channel))
+		logger.Info("Selected status update delivery channel", "repairID", repairID, "userID", repair.UserID, "channel", channel, "app", "repair-service")
+		logger.Info("Successfully sent response for PUT /repairs/{repairID}", "repairID", repairID, "app", "repair-service")
+	}).Methods("PUT")
+
+	// Patch a repair's mutable fields with a JSON merge patch; the gateway
+	// passes the caller as X-Actor-Role and X-Actor-ID
+	r.HandleFunc("/repairs/{repairID}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "PatchRepair")
+		defer span.End()
+
+		repairID := mux.Vars(r)["repairID"]
+		span.SetAttributes(attribute.String("repairID", repairID))
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, 64<<10))
+		if err != nil {
+			writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+			return
+		}
+		patch, err := domain.ParseRepairPatch(body)
+		if err != nil {
+			writeServiceError(w, span, logger, "Invalid merge patch", err)
+			return
+		}
+		actor := domain.Actor{Role: r.Header.Get("X-Actor-Role"), ID: r.Header.Get("X-Actor-ID")}
+
+		repair, err := svc.PatchRepair(ctx, repairID, actor, patch)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to patch repair", err)
+			return
+		}
+		if patch.Status != nil {
+			channel := presenceClient.DeliveryChannel(ctx, repair.UserID)
+			span.SetAttributes(attribute.String("deliveryChannel", channel))
+			logger.Info("Selected status update delivery channel", "repairID", repairID, "userID", repair.UserID, "channel", channel, "app", "repair-service")
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(repair)
+	}).Methods("PATCH")
+
+	// Cancel a repair that is not completed; the gateway passes the caller as
+	// X-Actor-Role and X-Actor-ID
+	r.HandleFunc("/repairs/{repairID}/cancel", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "CancelRepair")
+		defer span.End()
+
+		repairID := mux.Vars(r)["repairID"]
+		actor := domain.Actor{Role: r.Header.Get("X-Actor-Role"), ID: r.Header.Get("X-Actor-ID")}
+		span.SetAttributes(attribute.String("repairID", repairID), attribute.String("actorRole", actor.Role))
+		logger.Info("Received POST /repairs/{repairID}/cancel request", "repairID", repairID, "actorRole", actor.Role, "app", "repair-service")
+
+		repair, err := svc.CancelRepair(ctx, repairID, actor)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to cancel repair", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(repair)
+	}).Methods("POST")
+
+	// Delete a pending or cancelled repair outside a bundle; the gateway
+	// passes the caller as X-Actor-Role and X-Actor-ID
+	r.HandleFunc("/repairs/{repairID}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "DeleteRepair")
+		defer span.End()
+
+		repairID := mux.Vars(r)["repairID"]
+		actor := domain.Actor{Role: r.Header.Get("X-Actor-Role"), ID: r.Header.Get("X-Actor-ID")}
+		span.SetAttributes(attribute.String("repairID", repairID), attribute.String("actorRole", actor.Role))
+		logger.Info("Received DELETE /repairs/{repairID} request", "repairID", repairID, "actorRole", actor.Role, "app", "repair-service")
+
+		if err := svc.DeleteRepair(ctx, repairID, actor); err != nil {
+			writeServiceError(w, span, logger, "Failed to delete repair", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("DELETE")
+
+	// Get the receipt of a completed repair as JSON, or as PDF with ?format=pdf
+	r.HandleFunc("/repairs/{repairID}/receipt", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "GetReceipt")
+		defer span.End()
+
+		repairID := mux.Vars(r)["repairID"]
+		asPDF := r.URL.Query().Get("format") == "pdf" || r.Header.Get("Accept") == "application/pdf"
+		span.SetAttributes(
+			attribute.String("repairID", repairID),
+			attribute.Bool("pdf", asPDF),
+		)
+		logger.Info("Received GET /repairs/{repairID}/receipt request", "repairID", repairID, "pdf", asPDF, "app", "repair-service")
+
+		rcpt, err := svc.GetReceipt(ctx, repairID)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to get receipt")
+			logger.Error("Failed to get receipt", "error", err, "repairID", repairID, "app", "repair-service")
+			w.Header().Set("Content-Type", "application/json")
+			switch {
+			case errors.Is(err, mongo.ErrNoDocuments):
+				w.WriteHeader(http.StatusNotFound)
+			case errors.Is(err, domain.ErrInvalidInput):
+				w.WriteHeader(http.StatusBadRequest)
+			case errors.Is(err, domain.ErrReceiptUnavailable):
+				w.WriteHeader(http.StatusConflict)
+			default:
+				w.WriteHeader(storageErrorStatus(w, err))
+			}
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to get receipt: " + err.Error()})
+			return
+		}
+		if asPDF {
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", rcpt.Number+".pdf"))
+			if _, err := w.Write(receipt.RenderPDF(rcpt)); err != nil {
+				span.RecordError(err)
+				logger.Error("Failed to write receipt PDF", "error", err, "app", "repair-service")
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(rcpt); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to encode response")
+			logger.Error("Failed to encode response", "error", err, "app", "repair-service")
+		}
+	}).Methods("GET")
+
+	// List a repair's amendments
+	r.HandleFunc("/repairs/{repairID}/amendments", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "ListAmendments")
+		defer span.End()
+
+		repairID := mux.Vars(r)["repairID"]
+		span.SetAttributes(attribute.String("repairID", repairID))
+
+		amendments, err := svc.ListAmendments(ctx, repairID)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to list amendments", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(amendments)
+	}).Methods("GET")
+
+	// Request extra work on an in-progress repair (mechanic); the user must
+	// approve the price increase before the repair can complete
+	r.HandleFunc("/repairs/{repairID}/amendments", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "RequestAmendment")
+		defer span.End()
+
+		repairID := mux.Vars(r)["repairID"]
+		span.SetAttributes(attribute.String("repairID", repairID))
+
+		var input struct {
+			MechanicID  string       `json:"mechanicID"`
+			Description string       `json:"description"`
+			Amount      domain.Money `json:"amount"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+			return
+		}
+
+		amendment, err := svc.RequestAmendment(ctx, repairID, input.MechanicID, input.Description, input.Amount)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to request amendment", err)
+			return
+		}
+		// Like status changes, the approval request reaches connected users
+		// through the gateway WebSocket and others by push
+		channel := presenceClient.DeliveryChannel(ctx, amendment.UserID)
+		span.SetAttributes(attribute.String("deliveryChannel", channel))
+		logger.Info("Selected amendment delivery channel", "repairID", repairID, "amendmentID", amendment.ID, "channel", channel, "app", "repair-service")
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(amendment)
+	}).Methods("POST")
+
+	// Approve or reject a pending amendment (user)
+	r.HandleFunc("/repairs/{repairID}/amendments/{amendmentID}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "DecideAmendment")
+		defer span.End()
+
+		vars := mux.Vars(r)
+		span.SetAttributes(
+			attribute.String("repairID", vars["repairID"]),
+			attribute.String("amendmentID", vars["amendmentID"]),
+		)
+
+		var input struct {
+			Status string `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+			return
+		}
+
+		amendment, err := svc.DecideAmendment(ctx, vars["repairID"], vars["amendmentID"], input.Status)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to decide amendment", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(amendment)
+	}).Methods("PUT")
+
+	// Get intake questionnaire for a repair type
+	r.HandleFunc("/questionnaires/{repairType}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "GetQuestionnaire")
+		defer span.End()
+
+		repairType := mux.Vars(r)["repairType"]
+		span.SetAttributes(attribute.String("repairType", repairType))
+
+		questionnaire, err := svc.GetQuestionnaire(ctx, repairType)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to get questionnaire", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(questionnaire); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to encode response")
+			logger.Error("Failed to encode response", "error", err, "app", "repair-service")
+			return
+		}
+	}).Methods("GET")
+
+	// Clustered repair markers for the operations map (admin)
+	r.HandleFunc("/admin/repairs/map", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "RepairMap")
+		defer span.End()
+
+		logger.Info("Received GET /admin/repairs/map request", "app", "repair-service")
+		box, zoom, err := parseMapQuery(r.URL.Query().Get("bbox"), r.URL.Query().Get("zoom"))
+		if err == nil {
+			var repairMap *domain.RepairMap
+			repairMap, err = svc.RepairMap(ctx, box, zoom)
+			if err == nil {
+				w.Header().Set("Content-Type", "application/json")
+				json.NewEncoder(w).Encode(repairMap)
+				logger.Info("Successfully sent response for GET /admin/repairs/map", "clusters", len(repairMap.Clusters), "app", "repair-service")
+				return
+			}
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to build repair map")
+		logger.Error("Failed to build repair map", "error", err, "app", "repair-service")
+		w.Header().Set("Content-Type", "application/json")
+		if errors.Is(err, domain.ErrInvalidInput) {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			w.WriteHeader(storageErrorStatus(w, err))
+		}
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	}).Methods("GET")
+
+	// Positioning hints: the busiest demand cells in a mechanic's service area
+	// for the current hour
+	r.HandleFunc("/mechanics/{mechanicID}/positioning", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "PositioningHints")
+		defer span.End()
+
+		mechanicID := mux.Vars(r)["mechanicID"]
+		span.SetAttributes(attribute.String("mechanicID", mechanicID))
+
+		hints, err := svc.PositioningHints(ctx, mechanicID)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to get positioning hints", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hints)
+	}).Methods("GET")
+
+	// List the pricing rules in evaluation order (admin)
+	r.HandleFunc("/admin/pricing/rules", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "ListPricingRules")
+		defer span.End()
+
+		rules, err := svc.ListPricingRules(ctx)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to list pricing rules", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rules)
+	}).Methods("GET")
+
+	// Create or replace a pricing rule (admin)
+	r.HandleFunc("/admin/pricing/rules/{ruleID}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "SavePricingRule")
+		defer span.End()
+
+		var rule domain.PricingRule
+		if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+			writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+			return
+		}
+		rule.ID = mux.Vars(r)["ruleID"]
+
+		saved, err := svc.SavePricingRule(ctx, &rule)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to save pricing rule", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(saved)
+	}).Methods("PUT")
+
+	// Delete a pricing rule (admin)
+	r.HandleFunc("/admin/pricing/rules/{ruleID}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "DeletePricingRule")
+		defer span.End()
+
+		if err := svc.DeletePricingRule(ctx, mux.Vars(r)["ruleID"]); err != nil {
+			writeServiceError(w, span, logger, "Failed to delete pricing rule", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("DELETE")
+
+	// Dry-run the pricing rules for a repair type, location, time and weather,
+	// optionally with candidate rules instead of the stored ones (admin)
+	r.HandleFunc("/admin/pricing/evaluate", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "EvaluatePricing")
+		defer span.End()
+
+		var input struct {
+			domain.PricingContext
+			Rules []*domain.PricingRule `json:"rules"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+			return
+		}
+
+		result, err := svc.EvaluatePricing(ctx, input.PricingContext, input.Rules)
+		if err != nil && result == nil {
+			writeServiceError(w, span, logger, "Failed to evaluate pricing", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err != nil {
+			// Return the trace with the failure so the rules can be fixed
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Pricing rules failed")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": err.Error(), "pricing": result})
+			return
+		}
+		json.NewEncoder(w).Encode(result)
+	}).Methods("POST")
+
+	// Get or set the weather flag pricing rules match on (admin)
+	r.HandleFunc("/admin/pricing/weather", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "WeatherFlag")
+		defer span.End()
+
+		var (
+			flag *domain.WeatherFlag
+			err  error
+		)
+		if r.Method == http.MethodPut {
+			var input struct {
+				Flag string `json:"flag"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+				return
+			}
+			flag, err = svc.SetWeatherFlag(ctx, input.Flag)
+		} else {
+			flag, err = svc.GetWeatherFlag(ctx)
+		}
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to handle weather flag", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(flag)
+	}).Methods("GET", "PUT")
+
+	// Get or set the fairness policy that rotates offers among nearby mechanics (admin)
+	r.HandleFunc("/admin/dispatch/fairness", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "FairnessPolicy")
+		defer span.End()
+
+		var (
+			policy *domain.FairnessPolicy
+			err    error
+		)
+		if r.Method == http.MethodPut {
+			var input domain.FairnessPolicy
+			if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+				writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+				return
+			}
+			policy, err = svc.SetFairnessPolicy(ctx, &input)
+		} else {
+			policy, err = svc.GetFairnessPolicy(ctx)
+		}
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to handle fairness policy", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(policy)
+	}).Methods("GET", "PUT")
+
+	// Assignments per mechanic in the fairness policy's window (admin)
+	r.HandleFunc("/admin/dispatch/assignments", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "AssignmentCounts")
+		defer span.End()
+
+		counts, err := svc.AssignmentCounts(ctx)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to count assignments", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(counts)
+	}).Methods("GET")
+
+	// Learned repair durations against the catalog's, ?repairType= narrows (admin)
+	r.HandleFunc("/admin/repair-durations", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "DurationReport")
+		defer span.End()
+
+		report, err := svc.DurationReport(ctx, r.URL.Query().Get("repairType"))
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to report repair durations", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}).Methods("GET")
+
+	// Create or replace the intake questionnaire for a repair type (admin)
+	r.HandleFunc("/admin/questionnaires/{repairType}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "SaveQuestionnaire")
+		defer span.End()
+
+		repairType := mux.Vars(r)["repairType"]
+		span.SetAttributes(attribute.String("repairType", repairType))
+
+		var questionnaire domain.Questionnaire
+		if err := json.NewDecoder(r.Body).Decode(&questionnaire); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Invalid request body")
+			logger.Error("Failed to decode request body", "error", err, "app", "repair-service")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+		questionnaire.RepairType = repairType
+
+		if err := svc.SaveQuestionnaire(ctx, &questionnaire); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to save questionnaire")
+			logger.Error("Failed to save questionnaire", "error", err, "repairType", repairType, "app", "repair-service")
+			w.Header().Set("Content-Type", "application/json")
+			if errors.Is(err, domain.ErrInvalidInput) {
+				w.WriteHeader(http.StatusBadRequest)
+			} else {
+				w.WriteHeader(storageErrorStatus(w, err))
+			}
+			json.NewEncoder(w).Encode(map[string]string{"error": "Failed to save questionnaire: " + err.Error()})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(questionnaire)
+		logger.Info("Successfully saved questionnaire", "repairType", repairType, "app", "repair-service")
+	}).Methods("PUT")
+
+	// List the mechanics a user blocked, plus any admin blacklist
+	r.HandleFunc("/users/{userID}/blocks", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "ListBlocks")
+		defer span.End()
+
+		userID := mux.Vars(r)["userID"]
+		span.SetAttributes(attribute.String("userID", userID))
+
+		blocks, err := svc.ListBlocks(ctx, userID)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to list blocks", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(blocks)
+	}).Methods("GET")
+
+	// Block a mechanic so the user is never matched with them again
+	r.HandleFunc("/users/{userID}/blocks/{mechanicID}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "BlockMechanic")
+		defer span.End()
+
+		vars := mux.Vars(r)
+		span.SetAttributes(
+			attribute.String("userID", vars["userID"]),
+			attribute.String("mechanicID", vars["mechanicID"]),
+		)
+
+		block, err := svc.BlockMechanic(ctx, vars["userID"], vars["mechanicID"])
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to block mechanic", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(block)
+	}).Methods("PUT")
+
+	// Unblock a mechanic
+	r.HandleFunc("/users/{userID}/blocks/{mechanicID}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "UnblockMechanic")
+		defer span.End()
+
+		vars := mux.Vars(r)
+		span.SetAttributes(
+			attribute.String("userID", vars["userID"]),
+			attribute.String("mechanicID", vars["mechanicID"]),
+		)
+
+		if err := svc.UnblockMechanic(ctx, vars["userID"], vars["mechanicID"]); err != nil {
+			writeServiceError(w, span, logger, "Failed to unblock mechanic", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("DELETE")
+
+	// Get whether a user's phone number is verified
+	r.HandleFunc("/users/{userID}/phone", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "GetPhoneVerification")
+		defer span.End()
+
+		userID := mux.Vars(r)["userID"]
+		span.SetAttributes(attribute.String("userID", userID))
+
+		verification, err := svc.GetPhoneVerification(ctx, userID)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to get phone verification", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(verification)
+	}).Methods("GET")
+
+	// Text a one-time verification code to a user's phone number
+	r.HandleFunc("/users/{userID}/phone/verification", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "StartPhoneVerification")
+		defer span.End()
+
+		userID := mux.Vars(r)["userID"]
+		span.SetAttributes(attribute.String("userID", userID))
+
+		var input struct {
+			Phone string `json:"phone"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+			return
+		}
+		verification, err := svc.StartPhoneVerification(ctx, userID, input.Phone)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to send verification code", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(verification)
+	}).Methods("POST")
+
+	// Confirm a user's phone number with the code texted to it
+	r.HandleFunc("/users/{userID}/phone/verification/confirm", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "ConfirmPhoneVerification")
+		defer span.End()
+
+		userID := mux.Vars(r)["userID"]
+		span.SetAttributes(attribute.String("userID", userID))
+
+		var input struct {
+			Code string `json:"code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+			return
+		}
+		verification, err := svc.ConfirmPhoneVerification(ctx, userID, input.Code)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to verify phone number", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(verification)
+	}).Methods("POST")
+
+	// List a repair's tags (staff)
+	r.HandleFunc("/admin/repairs/{repairID}/tags", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "ListRepairTags")
+		defer span.End()
+
+		repairID := mux.Vars(r)["repairID"]
+		span.SetAttributes(attribute.String("repairID", repairID))
+
+		tags, err := svc.ListTags(ctx, repairID)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to list tags", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]string{"tags": tags})
+	}).Methods("GET")
+
+	// Add (PUT) or remove (DELETE) a repair tag (staff)
+	r.HandleFunc("/admin/repairs/{repairID}/tags/{tag}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "UpdateRepairTags")
+		defer span.End()
+
+		vars := mux.Vars(r)
+		span.SetAttributes(
+			attribute.String("repairID", vars["repairID"]),
+			attribute.String("tag", vars["tag"]),
+			attribute.String("method", r.Method),
+		)
+
+		var tags []string
+		var err error
+		if r.Method == http.MethodDelete {
+			tags, err = svc.RemoveTag(ctx, vars["repairID"], vars["tag"])
+		} else {
+			tags, err = svc.AddTag(ctx, vars["repairID"], vars["tag"])
+		}
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to update tags", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string][]string{"tags": tags})
+	}).Methods("PUT", "DELETE")
+
+	// List a repair's internal notes (staff)
+	r.HandleFunc("/admin/repairs/{repairID}/notes", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "ListRepairNotes")
+		defer span.End()
+
+		repairID := mux.Vars(r)["repairID"]
+		span.SetAttributes(attribute.String("repairID", repairID))
+
+		notes, err := svc.ListNotes(ctx, repairID)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to list notes", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(notes)
+	}).Methods("GET")
+
+	// Add an internal note to a repair (staff)
+	r.HandleFunc("/admin/repairs/{repairID}/notes", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "AddRepairNote")
+		defer span.End()
+
+		repairID := mux.Vars(r)["repairID"]
+		span.SetAttributes(attribute.String("repairID", repairID))
+
+		var input struct {
+			Author string `json:"author"`
+			Body   string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+			return
+		}
+
+		note, err := svc.AddNote(ctx, repairID, input.Author, input.Body)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to add note", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(note)
+	}).Methods("POST")
+
+	// Delete an internal note (staff)
+	r.HandleFunc("/admin/repairs/{repairID}/notes/{noteID}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "DeleteRepairNote")
+		defer span.End()
+
+		vars := mux.Vars(r)
+		span.SetAttributes(
+			attribute.String("repairID", vars["repairID"]),
+			attribute.String("noteID", vars["noteID"]),
+		)
+
+		if err := svc.DeleteNote(ctx, vars["repairID"], vars["noteID"]); err != nil {
+			writeServiceError(w, span, logger, "Failed to delete note", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("DELETE")
+
+	// Blacklist an abusive user from every mechanic (admin)
+	r.HandleFunc("/admin/users/{userID}/blacklist", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "BlacklistUser")
+		defer span.End()
+
+		userID := mux.Vars(r)["userID"]
+		span.SetAttributes(attribute.String("userID", userID))
+
+		var input struct {
+			Reason string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Invalid request body")
+			logger.Error("Failed to decode request body", "error", err, "app", "repair-service")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "Invalid request body: " + err.Error()})
+			return
+		}
+
+		block, err := svc.BlacklistUser(ctx, userID, input.Reason)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to blacklist user", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(block)
+	}).Methods("PUT")
+
+	// Lift an admin blacklist
+	r.HandleFunc("/admin/users/{userID}/blacklist", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "RemoveBlacklist")
+		defer span.End()
+
+		userID := mux.Vars(r)["userID"]
+		span.SetAttributes(attribute.String("userID", userID))
+
+		if err := svc.RemoveBlacklist(ctx, userID); err != nil {
+			writeServiceError(w, span, logger, "Failed to remove blacklist", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("DELETE")
+
+	// Export everything stored about a user as a JSON bundle, to the user or an
+	// admin; the gateway passes the caller as X-Actor-Role and X-Actor-ID
+	r.HandleFunc("/users/{userID}/export", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "ExportUserData")
+		defer span.End()
+
+		userID := mux.Vars(r)["userID"]
+		actor := domain.Actor{Role: r.Header.Get("X-Actor-Role"), ID: r.Header.Get("X-Actor-ID")}
+		span.SetAttributes(attribute.String("userID", userID), attribute.String("actorRole", actor.Role))
+
+		export, err := svc.ExportUserData(ctx, actor, userID)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to export user data", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Content-Disposition", `attachment; filename="user-data-export.json"`)
+		json.NewEncoder(w).Encode(export)
+	}).Methods("GET")
+
+	// Erase a user's personal data (right to be forgotten) and return the report
+	r.HandleFunc("/admin/users/{userID}/erasure", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "EraseUser")
+		defer span.End()
+
+		userID := mux.Vars(r)["userID"]
+		var input struct {
+			RequestedBy string `json:"requestedBy"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+			return
+		}
+
+		report, err := svc.EraseUser(ctx, userID, input.RequestedBy)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to erase user", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}).Methods("POST")
+
+	// List the erasure reports of a user ID
+	r.HandleFunc("/admin/users/{userID}/erasure", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "ListErasureReports")
+		defer span.End()
+
+		reports, err := svc.FindErasureReports(ctx, mux.Vars(r)["userID"])
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to list erasure reports", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(reports)
+	}).Methods("GET")
+
+	// Publish processed outbox events again, by ID or created_at range, and
+	// return the audit record of the redrive
+	r.HandleFunc("/admin/outbox/redrive", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "RedriveOutbox")
+		defer span.End()
+
+		var input domain.OutboxRedriveRequest
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+			return
+		}
+
+		redrive, err := svc.RedriveOutbox(ctx, &input)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to redrive outbox events", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(redrive)
+	}).Methods("POST")
+
+	// Move many repairs to one status, e.g. cancel stuck test repairs after an
+	// incident; every repair's transition is checked and reported
+	r.HandleFunc("/admin/repairs/bulk-status", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "BulkUpdateStatus")
+		defer span.End()
+
+		var input domain.BulkStatusRequest
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+			return
+		}
+
+		report, err := svc.BulkUpdateStatus(ctx, &input)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to update repair statuses", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(report)
+	}).Methods("POST")
+
+	// List past outbox redrives, newest first
+	r.HandleFunc("/admin/outbox/redrives", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "ListOutboxRedrives")
+		defer span.End()
+
+		opts, err := parseQueryOptions(r.URL.Query())
+		if err != nil {
+			writeServiceError(w, span, logger, "Invalid query options", err)
+			return
+		}
+		redrives, err := svc.ListOutboxRedrives(ctx, opts)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to list outbox redrives", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(redrives)
+	}).Methods("GET")
+
+	// Bundle pending repairs at one location into one job (admin)
+	r.HandleFunc("/admin/bundles", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "CreateRepairBundle")
+		defer span.End()
+
+		var req domain.RepairBundleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+			return
+		}
+		bundle, err := svc.CreateRepairBundle(ctx, &req)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to create repair bundle", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(bundle)
+	}).Methods("POST")
+
+	// List repair bundles, newest first (admin)
+	r.HandleFunc("/admin/bundles", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "ListRepairBundles")
+		defer span.End()
+
+		opts, err := parseQueryOptions(r.URL.Query())
+		if err != nil {
+			writeServiceError(w, span, logger, "Invalid query options", err)
+			return
+		}
+		bundles, err := svc.ListRepairBundles(ctx, opts)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to list repair bundles", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(bundles)
+	}).Methods("GET")
+
+	// Dissolve a bundle no mechanic took yet (admin)
+	r.HandleFunc("/admin/bundles/{bundleID}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "DissolveRepairBundle")
+		defer span.End()
+
+		if err := svc.DissolveRepairBundle(ctx, mux.Vars(r)["bundleID"]); err != nil {
+			writeServiceError(w, span, logger, "Failed to dissolve repair bundle", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("DELETE")
+
+	// Get a bundle with the status of each of its repairs
+	r.HandleFunc("/bundles/{bundleID}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "GetRepairBundle")
+		defer span.End()
+
+		bundle, err := svc.GetRepairBundle(ctx, mux.Vars(r)["bundleID"])
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to get repair bundle", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(bundle)
+	}).Methods("GET")
+
+	// Create a fleet account whose admins control their members' repairs (admin)
+	r.HandleFunc("/admin/fleets", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "CreateFleetAccount")
+		defer span.End()
+
+		var req domain.FleetAccountRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+			return
+		}
+		fleet, err := svc.CreateFleetAccount(ctx, &req)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to create fleet account", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(fleet)
+	}).Methods("POST")
+
+	// Get a fleet account; the gateway passes the caller as X-Actor-Role and
+	// X-Actor-ID, who must be one of its admins or a platform admin
+	r.HandleFunc("/fleets/{fleetID}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "GetFleetAccount")
+		defer span.End()
+
+		actor := domain.Actor{Role: r.Header.Get("X-Actor-Role"), ID: r.Header.Get("X-Actor-ID")}
+		fleet, err := svc.GetFleetAccount(ctx, actor, mux.Vars(r)["fleetID"])
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to get fleet account", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(fleet)
+	}).Methods("GET")
+
+	// Add a fleet member or replace their monthly limit and allowed repair types
+	r.HandleFunc("/fleets/{fleetID}/members/{userID}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "SetFleetMember")
+		defer span.End()
+
+		var member domain.FleetMember
+		if err := json.NewDecoder(r.Body).Decode(&member); err != nil {
+			writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+			return
+		}
+		vars := mux.Vars(r)
+		member.UserID = vars["userID"]
+		actor := domain.Actor{Role: r.Header.Get("X-Actor-Role"), ID: r.Header.Get("X-Actor-ID")}
+		fleet, err := svc.SetFleetMember(ctx, actor, vars["fleetID"], member)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to set fleet member", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(fleet)
+	}).Methods("PUT")
+
+	// Remove a member from a fleet
+	r.HandleFunc("/fleets/{fleetID}/members/{userID}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "RemoveFleetMember")
+		defer span.End()
+
+		vars := mux.Vars(r)
+		actor := domain.Actor{Role: r.Header.Get("X-Actor-Role"), ID: r.Header.Get("X-Actor-ID")}
+		if err := svc.RemoveFleetMember(ctx, actor, vars["fleetID"], vars["userID"]); err != nil {
+			writeServiceError(w, span, logger, "Failed to remove fleet member", err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods("DELETE")
+
+	// Each member's spending against their limit in ?month=YYYY-MM, the
+	// current month by default
+	r.HandleFunc("/fleets/{fleetID}/usage", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "FleetUsage")
+		defer span.End()
+
+		actor := domain.Actor{Role: r.Header.Get("X-Actor-Role"), ID: r.Header.Get("X-Actor-ID")}
+		usage, err := svc.FleetUsage(ctx, actor, mux.Vars(r)["fleetID"], r.URL.Query().Get("month"))
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to get fleet usage", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(usage)
+	}).Methods("GET")
+
+	// List a fleet's monthly invoices, latest month first
+	r.HandleFunc("/fleets/{fleetID}/invoices", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "ListFleetInvoices")
+		defer span.End()
+
+		opts, err := parseQueryOptions(r.URL.Query())
+		if err != nil {
+			writeServiceError(w, span, logger, "Invalid query options", err)
+			return
+		}
+		actor := domain.Actor{Role: r.Header.Get("X-Actor-Role"), ID: r.Header.Get("X-Actor-ID")}
+		invoices, err := svc.ListFleetInvoices(ctx, actor, mux.Vars(r)["fleetID"], opts)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to list fleet invoices", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(invoices)
+	}).Methods("GET")
+
+	// Get a fleet's invoice for a month (YYYY-MM)
+	r.HandleFunc("/fleets/{fleetID}/invoices/{month}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "GetFleetInvoice")
+		defer span.End()
+
+		vars := mux.Vars(r)
+		actor := domain.Actor{Role: r.Header.Get("X-Actor-Role"), ID: r.Header.Get("X-Actor-ID")}
+		invoice, err := svc.GetFleetInvoice(ctx, actor, vars["fleetID"], vars["month"])
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to get fleet invoice", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(invoice)
+	}).Methods("GET")
+
+	// Issue a fleet's invoice for an ended month now, e.g. a month before the
+	// invoice job ran; an issued invoice is returned unchanged (admin)
+	r.HandleFunc("/admin/fleets/{fleetID}/invoices/{month}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "IssueFleetInvoice")
+		defer span.End()
+
+		vars := mux.Vars(r)
+		invoice, err := svc.IssueFleetInvoice(ctx, vars["fleetID"], vars["month"])
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to issue fleet invoice", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(invoice)
+	}).Methods("POST")
+
+	// List the transactional email templates and the variables each can use
+	r.HandleFunc("/admin/email/templates", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "ListEmailTemplates")
+		defer span.End()
+
+		templates, err := svc.ListEmailTemplates(ctx)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to list email templates", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(templates)
+	}).Methods("GET")
+
+	// Replace a transactional email template
+	r.HandleFunc("/admin/email/templates/{name}", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "SaveEmailTemplate")
+		defer span.End()
+
+		var tmpl domain.EmailTemplate
+		if err := json.NewDecoder(r.Body).Decode(&tmpl); err != nil {
+			writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+			return
+		}
+		tmpl.Name = mux.Vars(r)["name"]
+		span.SetAttributes(attribute.String("template", tmpl.Name))
+
+		saved, err := svc.SaveEmailTemplate(ctx, &tmpl)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to save email template", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(saved)
+	}).Methods("PUT")
+
+	// List transactional emails and their delivery status, newest first
+	r.HandleFunc("/admin/email/deliveries", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "ListEmailDeliveries")
+		defer span.End()
+
+		query := r.URL.Query()
+		filter := domain.EmailDeliveryFilter{
+			UserID:   query.Get("userID"),
+			RepairID: query.Get("repairID"),
+			Status:   query.Get("status"),
+		}
+		opts, err := parseQueryOptions(query)
+		if err != nil {
+			writeServiceError(w, span, logger, "Invalid query options", err)
+			return
+		}
+		deliveries, err := svc.ListEmailDeliveries(ctx, filter, opts)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to list email deliveries", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(deliveries)
+	}).Methods("GET")
+
+	// Get a user's email address and opt-out
+	r.HandleFunc("/users/{userID}/email", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "GetEmailPreferences")
+		defer span.End()
+
+		userID := mux.Vars(r)["userID"]
+		span.SetAttributes(attribute.String("userID", userID))
+
+		prefs, err := svc.GetEmailPreferences(ctx, userID)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to get email preferences", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(prefs)
+	}).Methods("GET")
+
+	// Set a user's email address and opt-out
+	r.HandleFunc("/users/{userID}/email", func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := otel.Tracer("repair-service").Start(r.Context(), "SetEmailPreferences")
+		defer span.End()
+
+		userID := mux.Vars(r)["userID"]
+		span.SetAttributes(attribute.String("userID", userID))
+
+		var prefs domain.EmailPreferences
+		if err := json.NewDecoder(r.Body).Decode(&prefs); err != nil {
+			writeServiceError(w, span, logger, "Invalid request body", fmt.Errorf("%w: %v", domain.ErrInvalidInput, err))
+			return
+		}
+		prefs.UserID = userID
+
+		saved, err := svc.SetEmailPreferences(ctx, &prefs)
+		if err != nil {
+			writeServiceError(w, span, logger, "Failed to set email preferences", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(saved)
+	}).Methods("PUT")
+
+	// gRPC server
+	grpcPort := os.Getenv("GRPC_PORT")
+	if grpcPort == "" {
+		grpcPort = "50051"
+	}
+	lis, err := net.Listen("tcp", ":"+grpcPort)
+	if err != nil {
+		logger.Error("Failed to listen for gRPC", "error", err, "app", "repair-service")
+		os.Exit(1)
+	}
+	var grpcOptions []grpc.ServerOption
+	if mesh != nil {
+		grpcOptions = append(grpcOptions, grpc.Creds(credentials.NewTLS(mesh.ServerTLSConfig())))
+	}
+	grpcServer := grpc.NewServer(grpcOptions...)
+	repairServer := grpcsvc.NewRepairServer(repo, logger)
+	proto.RegisterRepairServiceServer(grpcServer, repairServer)
+	proto.RegisterAdminServiceServer(grpcServer, grpcsvc.NewAdminServer(repo, repairServer, logger))
+	reflection.Register(grpcServer)
+	components.Add("grpc-server", func() error {
+		logger.Info("Starting gRPC server", "port", grpcPort, "app", "repair-service")
+		return grpcServer.Serve(lis)
+	}, func(ctx context.Context) error {
+		// Repair streams never end on their own, so cut them off when
+		// draining takes too long
+		drained := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(drained)
+		}()
+		select {
+		case <-drained:
+		case <-time.After(5 * time.Second):
+			logger.Warn("gRPC streams still open, closing them", "app", "repair-service")
+			grpcServer.Stop()
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+		return nil
+	})
+
+	// HTTP server
+	port := os.Getenv("SERVICE_PORT")
+	if port == "" {
+		port = "8087"
+	}
+	server := &http.Server{Addr: ":" + port, Handler: r}
+	if mesh != nil {
+		plain := http.NewServeMux()
+		plain.Handle("/health", r)
+		server.Handler = plain
+
+		connectServer := &http.Server{Addr: fmt.Sprintf(":%d", connectPort), Handler: r, TLSConfig: mesh.ServerTLSConfig()}
+		components.Add("connect-server", func() error {
+			logger.Info("Starting repair-service Connect listener", "port", connectPort, "app", "repair-service")
+			if err := connectServer.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
+				return err
+			}
+			return nil
+		}, connectServer.Shutdown)
+	}
+	components.Add("http-server", func() error {
+		logger.Info("Starting repair-service", "port", port, "app", "repair-service")
+		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	}, server.Shutdown)
+
+	// Leave Consul first so the gateway stops routing here while requests drain
+	components.Add("consul-registration", nil, func(ctx context.Context) error {
+		return consulClient.Agent().ServiceDeregister(serviceID)
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := components.Run(ctx); err != nil {
+		logger.Error("repair-service stopped", "error", err, "app", "repair-service")
+		logFile.Close()
+		os.Exit(1)
+	}
+	logger.Info("Service shutdown complete", "app", "repair-service")
+}
+
+// parseMapQuery parses the map bbox ("minLon,minLat,maxLon,maxLat", default
+// the whole world) and zoom (default 2) query parameters
+func parseMapQuery(bbox, zoom string) (domain.BoundingBox, int, error) {
+	box := domain.BoundingBox{MinLongitude: -180, MinLatitude: -90, MaxLongitude: 180, MaxLatitude: 90}
+	if bbox != "" {
+		var err error
+		if box, err = parseBoundingBox(bbox); err != nil {
+			return box, 0, err
+		}
+	}
+	z := 2
+	if zoom != "" {
+		v, err := strconv.Atoi(zoom)
+		if err != nil {
+			return box, 0, fmt.Errorf("%w: invalid zoom %q", domain.ErrInvalidInput, zoom)
+		}
+		z = v
+	}
+	return box, z, nil
+}
+
+// parseBoundingBox parses a "minLon,minLat,maxLon,maxLat" bbox parameter
+func parseBoundingBox(bbox string) (domain.BoundingBox, error) {
+	parts := strings.Split(bbox, ",")
+	if len(parts) != 4 {
+		return domain.BoundingBox{}, fmt.Errorf("%w: bbox must be minLon,minLat,maxLon,maxLat", domain.ErrInvalidInput)
+	}
+	var values [4]float64
+	for i, part := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil {
+			return domain.BoundingBox{}, fmt.Errorf("%w: invalid bbox value %q", domain.ErrInvalidInput, part)
+		}
+		values[i] = v
+	}
+	return domain.BoundingBox{MinLongitude: values[0], MinLatitude: values[1], MaxLongitude: values[2], MaxLatitude: values[3]}, nil
+}
+
+// splitList returns the non-empty items of a comma separated query value
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// parseQueryOptions reads list query options: fields and sort are comma
+// separated field paths, sort fields prefixed with "-" sort descending, e.g.
+// ?fields=status,createdAt&sort=-createdAt&limit=50&skip=100. It returns nil
+// when none are given.
+func parseQueryOptions(query url.Values) (*domain.QueryOptions, error) {
+	opts := &domain.QueryOptions{ReadPreference: query.Get("readPreference")}
+	for _, f := range strings.Split(query.Get("fields"), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			opts.Fields = append(opts.Fields, f)
+		}
+	}
+	for _, f := range strings.Split(query.Get("sort"), ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			opts.Sort = append(opts.Sort, domain.SortField{Field: strings.TrimPrefix(f, "-"), Descending: strings.HasPrefix(f, "-")})
+		}
+	}
+	for name, dst := range map[string]*int64{"limit": &opts.Limit, "skip": &opts.Skip} {
+		if v := query.Get(name); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid %s %q", domain.ErrInvalidInput, name, v)
+			}
+			*dst = n
+		}
+	}
+	if len(opts.Fields) == 0 && len(opts.Sort) == 0 && opts.Limit == 0 && opts.Skip == 0 && opts.ReadPreference == "" {
+		return nil, nil
+	}
+	return opts, opts.Validate()
+}
+
+// writeServiceError records err on span and writes the JSON error response of the
+// block, blacklist, tag and note endpoints
+func writeServiceError(w http.ResponseWriter, span trace.Span, logger *slog.Logger, msg string, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, msg)
+	logger.Error(msg, "error", err, "app", "repair-service")
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case errors.Is(err, domain.ErrInvalidInput):
+		w.WriteHeader(http.StatusBadRequest)
+	case errors.Is(err, domain.ErrForbidden), errors.Is(err, domain.ErrSpendingLimit), errors.Is(err, domain.ErrRepairTypeNotAllowed):
+		w.WriteHeader(http.StatusForbidden)
+	case errors.Is(err, domain.ErrAmendmentPending), errors.Is(err, domain.ErrInvoiceMonthOpen),
+		errors.Is(err, domain.ErrRepairNotCancellable), errors.Is(err, domain.ErrRepairNotDeletable):
+		w.WriteHeader(http.StatusConflict)
+	case errors.Is(err, mongo.ErrNoDocuments):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, domain.ErrRateLimited):
+		w.WriteHeader(http.StatusTooManyRequests)
+	default:
+		w.WriteHeader(storageErrorStatus(w, err))
+	}
+	json.NewEncoder(w).Encode(map[string]string{"error": msg + ": " + err.Error()})
+}
+
+// storageErrorStatus is the status to answer for an error the handlers map
+// no other way, from its storage error class: 404 for a missing document,
+// 409 for a conflicting write, 503 with Retry-After for a transient failure
+// the repository's retries did not overcome, and 500 otherwise
+func storageErrorStatus(w http.ResponseWriter, err error) int {
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrTransient):
+		w.Header().Set("Retry-After", "1")
+		return http.StatusServiceUnavailable
 	}
+	return http.StatusInternalServerError
 }
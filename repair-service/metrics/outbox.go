@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"repair-service/domain"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// scrapeTimeout bounds the queries collectors run when scraped
+const scrapeTimeout = 5 * time.Second
+
+// BacklogSource reads the outbox backlog
+type BacklogSource interface {
+	GetOutboxBacklog(ctx context.Context) (*domain.OutboxBacklog, error)
+}
+
+var (
+	outboxUnprocessed = prometheus.NewDesc("repair_outbox_unprocessed_events",
+		"Outbox events not yet published to the event bus", nil, nil)
+	outboxOldestAge = prometheus.NewDesc("repair_outbox_oldest_unprocessed_age_seconds",
+		"Age of the oldest outbox event not yet published, 0 without any", nil, nil)
+)
+
+// outboxCollector reports the outbox backlog, read once per scrape; a failed
+// read leaves both gauges out of the scrape
+type outboxCollector struct {
+	source BacklogSource
+	logger *slog.Logger
+}
+
+// RegisterOutboxBacklog exposes the backlog of source to Prometheus
+func RegisterOutboxBacklog(source BacklogSource, logger *slog.Logger) {
+	prometheus.MustRegister(&outboxCollector{source: source, logger: logger})
+}
+
+func (c *outboxCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- outboxUnprocessed
+	ch <- outboxOldestAge
+}
+
+func (c *outboxCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), scrapeTimeout)
+	defer cancel()
+	backlog, err := c.source.GetOutboxBacklog(ctx)
+	if err != nil {
+		c.logger.Error("Failed to get outbox backlog for metrics", "error", err, "app", "repair-service")
+		return
+	}
+	age := 0.0
+	if backlog.Oldest != nil {
+		age = time.Since(*backlog.Oldest).Seconds()
+	}
+	ch <- prometheus.MustNewConstMetric(outboxUnprocessed, prometheus.GaugeValue, float64(backlog.Unprocessed))
+	ch <- prometheus.MustNewConstMetric(outboxOldestAge, prometheus.GaugeValue, age)
+}
@@ -0,0 +1,65 @@
+package presence
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+
+	"github.com/hashicorp/consul/api"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// kvPrefix must match the api-gateway's PresenceKVPrefix
+const kvPrefix = "presence/"
+
+// Delivery channels for user notifications
+const (
+	ChannelWebSocket = "websocket"
+	ChannelPush      = "push"
+)
+
+// Client reads the WebSocket presence the api-gateway publishes to Consul KV
+type Client struct {
+	kv     *api.KV
+	logger *slog.Logger
+}
+
+// NewClient creates a presence Client backed by the given Consul client
+func NewClient(consul *api.Client, logger *slog.Logger) *Client {
+	return &Client{kv: consul.KV(), logger: logger}
+}
+
+// IsConnected reports whether userID has a WebSocket connection on any gateway
+func (c *Client) IsConnected(ctx context.Context, userID string) (bool, error) {
+	_, span := otel.Tracer("repair-service").Start(ctx, "PresenceIsConnected")
+	defer span.End()
+
+	keys, _, err := c.kv.Keys(kvPrefix+url.PathEscape(userID)+"/", "", (&api.QueryOptions{}).WithContext(ctx))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to read presence")
+		return false, fmt.Errorf("failed to read presence: %w", err)
+	}
+	span.SetAttributes(
+		attribute.String("userID", userID),
+		attribute.Int("gatewayCount", len(keys)),
+	)
+	return len(keys) > 0, nil
+}
+
+// DeliveryChannel picks WebSocket delivery for connected users and push
+// otherwise, including when presence cannot be read
+func (c *Client) DeliveryChannel(ctx context.Context, userID string) string {
+	connected, err := c.IsConnected(ctx, userID)
+	if err != nil {
+		c.logger.Warn("Presence unavailable, falling back to push delivery", "error", err, "userID", userID, "app", "repair-service")
+		return ChannelPush
+	}
+	if connected {
+		return ChannelWebSocket
+	}
+	return ChannelPush
+}
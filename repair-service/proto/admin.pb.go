@@ -0,0 +1,354 @@
+// proto/admin.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.8
+// 	protoc        v3.21.12
+// source: proto/admin.proto
+
+package proto
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type GetStatsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatsRequest) Reset() {
+	*x = GetStatsRequest{}
+	mi := &file_proto_admin_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatsRequest) ProtoMessage() {}
+
+func (x *GetStatsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatsRequest.ProtoReflect.Descriptor instead.
+func (*GetStatsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{0}
+}
+
+type Stats struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TotalRepairs    int64                  `protobuf:"varint,1,opt,name=total_repairs,json=totalRepairs,proto3" json:"total_repairs,omitempty"`
+	RepairsByStatus map[string]int64       `protobuf:"bytes,2,rep,name=repairs_by_status,json=repairsByStatus,proto3" json:"repairs_by_status,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	Outbox          *OutboxBacklog         `protobuf:"bytes,3,opt,name=outbox,proto3" json:"outbox,omitempty"`
+	ChangeStream    *ChangeStreamHealth    `protobuf:"bytes,4,opt,name=change_stream,json=changeStream,proto3" json:"change_stream,omitempty"`
+	// Time the stats were collected (Unix milliseconds)
+	GeneratedAtUnixMs int64 `protobuf:"varint,5,opt,name=generated_at_unix_ms,json=generatedAtUnixMs,proto3" json:"generated_at_unix_ms,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *Stats) Reset() {
+	*x = Stats{}
+	mi := &file_proto_admin_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Stats) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Stats) ProtoMessage() {}
+
+func (x *Stats) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Stats.ProtoReflect.Descriptor instead.
+func (*Stats) Descriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Stats) GetTotalRepairs() int64 {
+	if x != nil {
+		return x.TotalRepairs
+	}
+	return 0
+}
+
+func (x *Stats) GetRepairsByStatus() map[string]int64 {
+	if x != nil {
+		return x.RepairsByStatus
+	}
+	return nil
+}
+
+func (x *Stats) GetOutbox() *OutboxBacklog {
+	if x != nil {
+		return x.Outbox
+	}
+	return nil
+}
+
+func (x *Stats) GetChangeStream() *ChangeStreamHealth {
+	if x != nil {
+		return x.ChangeStream
+	}
+	return nil
+}
+
+func (x *Stats) GetGeneratedAtUnixMs() int64 {
+	if x != nil {
+		return x.GeneratedAtUnixMs
+	}
+	return 0
+}
+
+// OutboxBacklog describes repair_outbox events not yet published to Kafka
+type OutboxBacklog struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Unprocessed int64                  `protobuf:"varint,1,opt,name=unprocessed,proto3" json:"unprocessed,omitempty"`
+	// Age of the oldest unprocessed event in seconds, 0 when there is none
+	OldestAgeSeconds int64 `protobuf:"varint,2,opt,name=oldest_age_seconds,json=oldestAgeSeconds,proto3" json:"oldest_age_seconds,omitempty"`
+	unknownFields    protoimpl.UnknownFields
+	sizeCache        protoimpl.SizeCache
+}
+
+func (x *OutboxBacklog) Reset() {
+	*x = OutboxBacklog{}
+	mi := &file_proto_admin_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OutboxBacklog) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OutboxBacklog) ProtoMessage() {}
+
+func (x *OutboxBacklog) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OutboxBacklog.ProtoReflect.Descriptor instead.
+func (*OutboxBacklog) Descriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *OutboxBacklog) GetUnprocessed() int64 {
+	if x != nil {
+		return x.Unprocessed
+	}
+	return 0
+}
+
+func (x *OutboxBacklog) GetOldestAgeSeconds() int64 {
+	if x != nil {
+		return x.OldestAgeSeconds
+	}
+	return 0
+}
+
+// ChangeStreamHealth describes the repairs change stream behind StreamAllRepairs
+type ChangeStreamHealth struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Whether a change stream on repairs could be opened just now
+	Healthy         bool   `protobuf:"varint,1,opt,name=healthy,proto3" json:"healthy,omitempty"`
+	Error           string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	ActiveStreams   int32  `protobuf:"varint,3,opt,name=active_streams,json=activeStreams,proto3" json:"active_streams,omitempty"`
+	LastStreamError string `protobuf:"bytes,4,opt,name=last_stream_error,json=lastStreamError,proto3" json:"last_stream_error,omitempty"`
+	// Time of last_stream_error (Unix milliseconds), 0 when there is none
+	LastStreamErrorUnixMs int64 `protobuf:"varint,5,opt,name=last_stream_error_unix_ms,json=lastStreamErrorUnixMs,proto3" json:"last_stream_error_unix_ms,omitempty"`
+	unknownFields         protoimpl.UnknownFields
+	sizeCache             protoimpl.SizeCache
+}
+
+func (x *ChangeStreamHealth) Reset() {
+	*x = ChangeStreamHealth{}
+	mi := &file_proto_admin_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ChangeStreamHealth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ChangeStreamHealth) ProtoMessage() {}
+
+func (x *ChangeStreamHealth) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_admin_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ChangeStreamHealth.ProtoReflect.Descriptor instead.
+func (*ChangeStreamHealth) Descriptor() ([]byte, []int) {
+	return file_proto_admin_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ChangeStreamHealth) GetHealthy() bool {
+	if x != nil {
+		return x.Healthy
+	}
+	return false
+}
+
+func (x *ChangeStreamHealth) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ChangeStreamHealth) GetActiveStreams() int32 {
+	if x != nil {
+		return x.ActiveStreams
+	}
+	return 0
+}
+
+func (x *ChangeStreamHealth) GetLastStreamError() string {
+	if x != nil {
+		return x.LastStreamError
+	}
+	return ""
+}
+
+func (x *ChangeStreamHealth) GetLastStreamErrorUnixMs() int64 {
+	if x != nil {
+		return x.LastStreamErrorUnixMs
+	}
+	return 0
+}
+
+var File_proto_admin_proto protoreflect.FileDescriptor
+
+const file_proto_admin_proto_rawDesc = "" +
+	"\n" +
+	"\x11proto/admin.proto\x12\x06repair\"\x11\n" +
+	"\x0fGetStatsRequest\"\xe1\x02\n" +
+	"\x05Stats\x12#\n" +
+	"\rtotal_repairs\x18\x01 \x01(\x03R\ftotalRepairs\x12N\n" +
+	"\x11repairs_by_status\x18\x02 \x03(\v2\".repair.Stats.RepairsByStatusEntryR\x0frepairsByStatus\x12-\n" +
+	"\x06outbox\x18\x03 \x01(\v2\x15.repair.OutboxBacklogR\x06outbox\x12?\n" +
+	"\rchange_stream\x18\x04 \x01(\v2\x1a.repair.ChangeStreamHealthR\fchangeStream\x12/\n" +
+	"\x14generated_at_unix_ms\x18\x05 \x01(\x03R\x11generatedAtUnixMs\x1aB\n" +
+	"\x14RepairsByStatusEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"_\n" +
+	"\rOutboxBacklog\x12 \n" +
+	"\vunprocessed\x18\x01 \x01(\x03R\vunprocessed\x12,\n" +
+	"\x12oldest_age_seconds\x18\x02 \x01(\x03R\x10oldestAgeSeconds\"\xd1\x01\n" +
+	"\x12ChangeStreamHealth\x12\x18\n" +
+	"\ahealthy\x18\x01 \x01(\bR\ahealthy\x12\x14\n" +
+	"\x05error\x18\x02 \x01(\tR\x05error\x12%\n" +
+	"\x0eactive_streams\x18\x03 \x01(\x05R\ractiveStreams\x12*\n" +
+	"\x11last_stream_error\x18\x04 \x01(\tR\x0flastStreamError\x128\n" +
+	"\x19last_stream_error_unix_ms\x18\x05 \x01(\x03R\x15lastStreamErrorUnixMs2D\n" +
+	"\fAdminService\x124\n" +
+	"\bGetStats\x12\x17.repair.GetStatsRequest\x1a\r.repair.Stats\"\x00B\tZ\a./protob\x06proto3"
+
+var (
+	file_proto_admin_proto_rawDescOnce sync.Once
+	file_proto_admin_proto_rawDescData []byte
+)
+
+func file_proto_admin_proto_rawDescGZIP() []byte {
+	file_proto_admin_proto_rawDescOnce.Do(func() {
+		file_proto_admin_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_admin_proto_rawDesc), len(file_proto_admin_proto_rawDesc)))
+	})
+	return file_proto_admin_proto_rawDescData
+}
+
+var file_proto_admin_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_proto_admin_proto_goTypes = []any{
+	(*GetStatsRequest)(nil),    // 0: repair.GetStatsRequest
+	(*Stats)(nil),              // 1: repair.Stats
+	(*OutboxBacklog)(nil),      // 2: repair.OutboxBacklog
+	(*ChangeStreamHealth)(nil), // 3: repair.ChangeStreamHealth
+	nil,                        // 4: repair.Stats.RepairsByStatusEntry
+}
+var file_proto_admin_proto_depIdxs = []int32{
+	4, // 0: repair.Stats.repairs_by_status:type_name -> repair.Stats.RepairsByStatusEntry
+	2, // 1: repair.Stats.outbox:type_name -> repair.OutboxBacklog
+	3, // 2: repair.Stats.change_stream:type_name -> repair.ChangeStreamHealth
+	0, // 3: repair.AdminService.GetStats:input_type -> repair.GetStatsRequest
+	1, // 4: repair.AdminService.GetStats:output_type -> repair.Stats
+	4, // [4:5] is the sub-list for method output_type
+	3, // [3:4] is the sub-list for method input_type
+	3, // [3:3] is the sub-list for extension type_name
+	3, // [3:3] is the sub-list for extension extendee
+	0, // [0:3] is the sub-list for field type_name
+}
+
+func init() { file_proto_admin_proto_init() }
+func file_proto_admin_proto_init() {
+	if File_proto_admin_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_admin_proto_rawDesc), len(file_proto_admin_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   5,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_proto_admin_proto_goTypes,
+		DependencyIndexes: file_proto_admin_proto_depIdxs,
+		MessageInfos:      file_proto_admin_proto_msgTypes,
+	}.Build()
+	File_proto_admin_proto = out.File
+	file_proto_admin_proto_goTypes = nil
+	file_proto_admin_proto_depIdxs = nil
+}
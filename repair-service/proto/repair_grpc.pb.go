@@ -22,14 +22,18 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	RepairService_StreamAllRepairs_FullMethodName = "/repair.RepairService/StreamAllRepairs"
+	RepairService_GetRepair_FullMethodName        = "/repair.RepairService/GetRepair"
 )
 
 // RepairServiceClient is the client API for RepairService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type RepairServiceClient interface {
-	// Server-streaming RPC to get all repairs and stream new ones
-	StreamAllRepairs(ctx context.Context, in *Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Repair], error)
+	// Server-streaming RPC to get all repairs and stream new ones. Filters apply
+	// to both the initial snapshot and newly inserted repairs.
+	StreamAllRepairs(ctx context.Context, in *StreamRepairsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Repair], error)
+	// Looks up one repair by ID; NOT_FOUND when it does not exist
+	GetRepair(ctx context.Context, in *GetRepairRequest, opts ...grpc.CallOption) (*Repair, error)
 }
 
 type repairServiceClient struct {
@@ -40,13 +44,13 @@ func NewRepairServiceClient(cc grpc.ClientConnInterface) RepairServiceClient {
 	return &repairServiceClient{cc}
 }
 
-func (c *repairServiceClient) StreamAllRepairs(ctx context.Context, in *Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Repair], error) {
+func (c *repairServiceClient) StreamAllRepairs(ctx context.Context, in *StreamRepairsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Repair], error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
 	stream, err := c.cc.NewStream(ctx, &RepairService_ServiceDesc.Streams[0], RepairService_StreamAllRepairs_FullMethodName, cOpts...)
 	if err != nil {
 		return nil, err
 	}
-	x := &grpc.GenericClientStream[Empty, Repair]{ClientStream: stream}
+	x := &grpc.GenericClientStream[StreamRepairsRequest, Repair]{ClientStream: stream}
 	if err := x.ClientStream.SendMsg(in); err != nil {
 		return nil, err
 	}
@@ -59,12 +63,25 @@ func (c *repairServiceClient) StreamAllRepairs(ctx context.Context, in *Empty, o
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type RepairService_StreamAllRepairsClient = grpc.ServerStreamingClient[Repair]
 
+func (c *repairServiceClient) GetRepair(ctx context.Context, in *GetRepairRequest, opts ...grpc.CallOption) (*Repair, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Repair)
+	err := c.cc.Invoke(ctx, RepairService_GetRepair_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // RepairServiceServer is the server API for RepairService service.
 // All implementations must embed UnimplementedRepairServiceServer
 // for forward compatibility.
 type RepairServiceServer interface {
-	// Server-streaming RPC to get all repairs and stream new ones
-	StreamAllRepairs(*Empty, grpc.ServerStreamingServer[Repair]) error
+	// Server-streaming RPC to get all repairs and stream new ones. Filters apply
+	// to both the initial snapshot and newly inserted repairs.
+	StreamAllRepairs(*StreamRepairsRequest, grpc.ServerStreamingServer[Repair]) error
+	// Looks up one repair by ID; NOT_FOUND when it does not exist
+	GetRepair(context.Context, *GetRepairRequest) (*Repair, error)
 	mustEmbedUnimplementedRepairServiceServer()
 }
 
@@ -75,9 +92,12 @@ type RepairServiceServer interface {
 // pointer dereference when methods are called.
 type UnimplementedRepairServiceServer struct{}
 
-func (UnimplementedRepairServiceServer) StreamAllRepairs(*Empty, grpc.ServerStreamingServer[Repair]) error {
+func (UnimplementedRepairServiceServer) StreamAllRepairs(*StreamRepairsRequest, grpc.ServerStreamingServer[Repair]) error {
 	return status.Errorf(codes.Unimplemented, "method StreamAllRepairs not implemented")
 }
+func (UnimplementedRepairServiceServer) GetRepair(context.Context, *GetRepairRequest) (*Repair, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRepair not implemented")
+}
 func (UnimplementedRepairServiceServer) mustEmbedUnimplementedRepairServiceServer() {}
 func (UnimplementedRepairServiceServer) testEmbeddedByValue()                       {}
 
@@ -100,23 +120,46 @@ func RegisterRepairServiceServer(s grpc.ServiceRegistrar, srv RepairServiceServe
 }
 
 func _RepairService_StreamAllRepairs_Handler(srv interface{}, stream grpc.ServerStream) error {
-	m := new(Empty)
+	m := new(StreamRepairsRequest)
 	if err := stream.RecvMsg(m); err != nil {
 		return err
 	}
-	return srv.(RepairServiceServer).StreamAllRepairs(m, &grpc.GenericServerStream[Empty, Repair]{ServerStream: stream})
+	return srv.(RepairServiceServer).StreamAllRepairs(m, &grpc.GenericServerStream[StreamRepairsRequest, Repair]{ServerStream: stream})
 }
 
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type RepairService_StreamAllRepairsServer = grpc.ServerStreamingServer[Repair]
 
+func _RepairService_GetRepair_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRepairRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(RepairServiceServer).GetRepair(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: RepairService_GetRepair_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(RepairServiceServer).GetRepair(ctx, req.(*GetRepairRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // RepairService_ServiceDesc is the grpc.ServiceDesc for RepairService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
 var RepairService_ServiceDesc = grpc.ServiceDesc{
 	ServiceName: "repair.RepairService",
 	HandlerType: (*RepairServiceServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetRepair",
+			Handler:    _RepairService_GetRepair_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "StreamAllRepairs",
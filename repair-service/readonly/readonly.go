@@ -0,0 +1,186 @@
+package readonly
+
+import (
+	"encoding/json"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/hashicorp/consul/api"
+)
+
+// DefaultKVKey is the Consul KV key that puts repair-service in read-only
+// mode while it exists
+const DefaultKVKey = "repair-service/read-only"
+
+// Header marks the 503 answers of read-only mode, so callers such as the
+// gateway's circuit breaker can tell them from an unhealthy service
+const Header = "X-Read-Only"
+
+// State describes an active read-only mode. The KV value is a JSON State;
+// any other value but "false" enables the mode with the default message.
+type State struct {
+	Message   string    `json:"message,omitempty"`
+	Until     time.Time `json:"until,omitempty"` // expected end, used for Retry-After when set
+	EnabledBy string    `json:"enabledBy,omitempty"`
+	EnabledAt time.Time `json:"enabledAt,omitempty"`
+}
+
+// Mode serves reads and rejects writes with 503 while its Consul KV key is
+// set, e.g. during database migrations. Every instance follows the key with
+// blocking queries and keeps the last known state while Consul is unreachable.
+type Mode struct {
+	kv         *api.KV
+	key        string
+	retryAfter int             // default Retry-After in seconds
+	allowed    map[string]bool // "METHOD /route" of writes that stay allowed
+	logger     *slog.Logger
+	mu         sync.RWMutex
+	state      *State
+}
+
+// New creates the read-only mode and starts following Consul KV:
+//   - READ_ONLY_KV_KEY: the switch, default "repair-service/read-only"
+//   - READ_ONLY_RETRY_AFTER_SECONDS: Retry-After when the state sets no until, default 300
+//   - READ_ONLY_ALLOWED_ROUTES: non-GET routes that only read, default "POST /admin/pricing/evaluate"
+func New(consul *api.Client, logger *slog.Logger) *Mode {
+	m := &Mode{
+		kv:         consul.KV(),
+		key:        os.Getenv("READ_ONLY_KV_KEY"),
+		retryAfter: 300,
+		allowed:    make(map[string]bool),
+		logger:     logger,
+	}
+	if m.key == "" {
+		m.key = DefaultKVKey
+	}
+	if v, err := strconv.Atoi(os.Getenv("READ_ONLY_RETRY_AFTER_SECONDS")); err == nil && v > 0 {
+		m.retryAfter = v
+	}
+	routes, ok := os.LookupEnv("READ_ONLY_ALLOWED_ROUTES")
+	if !ok {
+		routes = "POST /admin/pricing/evaluate"
+	}
+	for _, route := range strings.Split(routes, ",") {
+		if route = strings.TrimSpace(route); route != "" {
+			m.allowed[route] = true
+		}
+	}
+	go m.watch()
+	return m
+}
+
+// Key returns the Consul KV key of the switch
+func (m *Mode) Key() string {
+	return m.key
+}
+
+// State returns the active read-only state, nil when writes are served
+func (m *Mode) State() *State {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// watch reloads the state whenever the key changes
+func (m *Mode) watch() {
+	var index uint64
+	for {
+		pair, meta, err := m.kv.Get(m.key, &api.QueryOptions{WaitIndex: index, WaitTime: 5 * time.Minute})
+		if err != nil {
+			m.logger.Error("Failed to watch read-only switch, keeping the last state", "error", err, "key", m.key, "app", "repair-service")
+			time.Sleep(2 * time.Second)
+			continue
+		}
+		if meta.LastIndex < index {
+			// Consul's index went backwards (e.g. a restore); start over
+			index = 0
+			continue
+		}
+		index = meta.LastIndex
+		m.load(pair)
+	}
+}
+
+// load sets the state from the key's pair, nil when it is deleted
+func (m *Mode) load(pair *api.KVPair) {
+	var state *State
+	if pair != nil {
+		value := strings.TrimSpace(string(pair.Value))
+		if value != "false" {
+			state = &State{}
+			if err := json.Unmarshal(pair.Value, state); err != nil {
+				state = &State{}
+			}
+		}
+	}
+
+	m.mu.Lock()
+	was := m.state != nil
+	m.state = state
+	m.mu.Unlock()
+
+	switch {
+	case state != nil && !was:
+		m.logger.Warn("Entered read-only mode, rejecting writes", "message", state.Message, "until", state.Until, "enabledBy", state.EnabledBy, "app", "repair-service")
+	case state == nil && was:
+		m.logger.Warn("Left read-only mode", "app", "repair-service")
+	}
+}
+
+// retryAfterSeconds returns the Retry-After delay of state in seconds
+func (m *Mode) retryAfterSeconds(state *State) int {
+	if !state.Until.IsZero() {
+		if left := int(math.Ceil(time.Until(state.Until).Seconds())); left > 0 {
+			return left
+		}
+	}
+	return m.retryAfter
+}
+
+// Middleware answers 503 to writes while read-only mode is on. GET, HEAD and
+// OPTIONS requests and the allowed routes are served as usual.
+func (m *Mode) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		state := m.State()
+		if state == nil || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+			next.ServeHTTP(w, r)
+			return
+		}
+		route := r.URL.Path
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		if m.allowed[r.Method+" "+route] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		retryAfter := m.retryAfterSeconds(state)
+		message := state.Message
+		if message == "" {
+			message = "repair-service is read-only during maintenance"
+		}
+		body := map[string]interface{}{
+			"error":             "read_only",
+			"message":           message,
+			"retryAfterSeconds": retryAfter,
+		}
+		if !state.Until.IsZero() {
+			body["until"] = state.Until
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		w.Header().Set(Header, "true")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(body)
+	})
+}
@@ -0,0 +1,109 @@
+// Package receipt renders repair receipts for download
+package receipt
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"repair-service/domain"
+)
+
+// RenderPDF renders a receipt as a single page A4 PDF using the standard
+// Helvetica font, so no font files or PDF libraries are needed
+func RenderPDF(r *domain.Receipt) []byte {
+	var lines []string
+	add := func(format string, args ...any) {
+		lines = append(lines, fmt.Sprintf(format, args...))
+	}
+	amount := func(m domain.Money) string {
+		return m.String() + " " + r.Currency
+	}
+
+	add("Receipt %s", r.Number)
+	add("")
+	add("Repair: %s", r.RepairID)
+	add("Customer: %s", r.UserID)
+	if r.Mechanic != nil {
+		if r.Mechanic.Name != "" {
+			add("Mechanic: %s (%s)", r.Mechanic.Name, r.Mechanic.ID)
+		} else {
+			add("Mechanic: %s", r.Mechanic.ID)
+		}
+	}
+	if !r.CreatedAt.IsZero() {
+		add("Requested: %s", r.CreatedAt.UTC().Format(time.RFC1123))
+	}
+	if r.CompletedAt != nil {
+		add("Completed: %s", r.CompletedAt.UTC().Format(time.RFC1123))
+	}
+	add("Issued: %s", r.IssuedAt.UTC().Format(time.RFC1123))
+	add("")
+	for _, item := range r.LineItems {
+		add("%d x %s @ %s = %s", item.Quantity, item.Description, amount(item.UnitPrice), amount(item.Amount))
+	}
+	add("")
+	add("Subtotal: %s", amount(r.Subtotal))
+	for _, tax := range r.Taxes {
+		add("%s (%g%%): %s", tax.Name, tax.RatePercent, amount(tax.Amount))
+	}
+	add("Total: %s", amount(r.Total))
+	if r.PaymentReference != "" {
+		add("Payment reference: %s", r.PaymentReference)
+	}
+
+	// Content stream: one text object, 16pt leading, starting near the top left
+	var content bytes.Buffer
+	content.WriteString("BT\n/F1 11 Tf\n16 TL\n56 780 Td\n")
+	for i, line := range lines {
+		if i == 0 {
+			content.WriteString("/F1 16 Tf\n")
+		} else if i == 1 {
+			content.WriteString("/F1 11 Tf\n")
+		}
+		fmt.Fprintf(&content, "(%s) Tj T*\n", escapeText(line))
+	}
+	content.WriteString("ET\n")
+
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Resources << /Font << /F1 4 0 R >> >> /Contents 5 0 R >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()),
+	}
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, obj := range objects {
+		offsets[i] = pdf.Len()
+		fmt.Fprintf(&pdf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := pdf.Len()
+	fmt.Fprintf(&pdf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&pdf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&pdf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return pdf.Bytes()
+}
+
+// escapeText escapes a PDF string literal and replaces characters outside
+// printable ASCII, which the standard font encoding cannot show reliably
+func escapeText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteRune('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			b.WriteRune('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,133 @@
+// Package region derives the deployment region of repairs and mechanics from
+// their coordinates, and tells region-scoped instances which events are theirs,
+// so one codebase can run per region against a shared Kafka cluster
+package region
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Header is the event header carrying the region of a published event, so
+// consumers can skip other regions without decoding the payload
+const Header = "region"
+
+// box is a named longitude/latitude rectangle
+type box struct {
+	name                                                 string
+	minLongitude, minLatitude, maxLongitude, maxLatitude float64
+}
+
+// Resolver maps coordinates to regions. Boxes are checked in order and the
+// first containing the point wins; points outside every box get the fallback.
+type Resolver struct {
+	boxes    []box
+	fallback string
+}
+
+// Parse reads regions written as name:minLon,minLat,maxLon,maxLat separated by
+// ';', e.g. "eu:-25,34,45,72;us:-170,15,-50,72"
+func Parse(spec, fallback string) (*Resolver, error) {
+	r := &Resolver{fallback: fallback}
+	for _, entry := range strings.Split(spec, ";") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, coords, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		parts := strings.Split(coords, ",")
+		if !ok || name == "" || len(parts) != 4 {
+			return nil, fmt.Errorf("region %q must be name:minLon,minLat,maxLon,maxLat", entry)
+		}
+		var v [4]float64
+		for i, part := range parts {
+			f, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid coordinate %q in region %q", part, name)
+			}
+			v[i] = f
+		}
+		if v[0] > v[2] || v[1] > v[3] {
+			return nil, fmt.Errorf("region %q minimums must not exceed maximums", name)
+		}
+		r.boxes = append(r.boxes, box{name: name, minLongitude: v[0], minLatitude: v[1], maxLongitude: v[2], maxLatitude: v[3]})
+	}
+	return r, nil
+}
+
+// Of returns the region containing the point
+func (r *Resolver) Of(longitude, latitude float64) string {
+	for _, b := range r.boxes {
+		if longitude >= b.minLongitude && longitude <= b.maxLongitude && latitude >= b.minLatitude && latitude <= b.maxLatitude {
+			return b.name
+		}
+	}
+	return r.fallback
+}
+
+// Fallback is the region of points outside every configured region
+func (r *Resolver) Fallback() string {
+	return r.fallback
+}
+
+var (
+	defaultOnce     sync.Once
+	defaultResolver *Resolver
+)
+
+// Default returns the resolver configured by REGIONS, with REGION_DEFAULT
+// (default "default") as the fallback. An invalid REGIONS is logged and
+// ignored, placing everything in the fallback region.
+func Default() *Resolver {
+	defaultOnce.Do(func() {
+		fallback := os.Getenv("REGION_DEFAULT")
+		if fallback == "" {
+			fallback = "default"
+		}
+		r, err := Parse(os.Getenv("REGIONS"), fallback)
+		if err != nil {
+			slog.Error("Invalid REGIONS, using the default region only", "error", err, "app", "repair-service")
+			r = &Resolver{fallback: fallback}
+		}
+		defaultResolver = r
+	})
+	return defaultResolver
+}
+
+// Scope is the set of regions an instance handles; an empty Scope handles
+// every region
+type Scope map[string]bool
+
+// ScopeFromEnv reads REGION_SCOPE, a comma separated list of regions
+func ScopeFromEnv() Scope {
+	scope := Scope{}
+	for _, name := range strings.Split(os.Getenv("REGION_SCOPE"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			scope[name] = true
+		}
+	}
+	return scope
+}
+
+// Allows reports whether the instance handles region. Events without a
+// region predate regions and are handled everywhere.
+func (s Scope) Allows(region string) bool {
+	return len(s) == 0 || region == "" || s[region]
+}
+
+// Names returns the regions in the scope, nil when it handles every region
+func (s Scope) Names() []string {
+	if len(s) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	return names
+}
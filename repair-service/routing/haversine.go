@@ -0,0 +1,51 @@
+package routing
+
+import (
+	"context"
+	"math"
+
+	"repair-service/domain"
+)
+
+// Haversine estimates assume this average driving speed and road detour over
+// the straight-line distance
+const (
+	haversineSpeedMetersPerSecond = 50000.0 / 3600.0
+	haversineDetourFactor         = 1.3
+)
+
+// HaversineProvider estimates durations from straight-line distance. It needs
+// no network and never fails, so it is the last resort of every class.
+type HaversineProvider struct{}
+
+// NewHaversineProvider creates a HaversineProvider
+func NewHaversineProvider() *HaversineProvider {
+	return &HaversineProvider{}
+}
+
+// Name returns the provider name
+func (p *HaversineProvider) Name() string {
+	return ProviderHaversine
+}
+
+// Durations converts straight-line distances to travel times
+func (p *HaversineProvider) Durations(ctx context.Context, origin domain.Location, destinations []domain.Location) ([]float64, error) {
+	durations := make([]float64, len(destinations))
+	for i, d := range destinations {
+		durations[i] = haversineMeters(origin, d) * haversineDetourFactor / haversineSpeedMetersPerSecond
+	}
+	return durations, nil
+}
+
+// haversineMeters returns the great-circle distance between two points in meters
+func haversineMeters(l1, l2 domain.Location) float64 {
+	const R = 6371000 // Earth's radius in m
+	lat1 := l1.Latitude * math.Pi / 180
+	lat2 := l2.Latitude * math.Pi / 180
+	dLat := (l2.Latitude - l1.Latitude) * math.Pi / 180
+	dLon := (l2.Longitude - l1.Longitude) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return R * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
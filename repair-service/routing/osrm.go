@@ -0,0 +1,110 @@
+package routing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"repair-service/domain"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// OSRMProvider queries the table service of an OSRM server
+type OSRMProvider struct {
+	name       string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewOSRMProvider creates an OSRM provider for the server at baseURL
+func NewOSRMProvider(name, baseURL string, httpClient *http.Client) *OSRMProvider {
+	return &OSRMProvider{name: name, baseURL: strings.TrimRight(baseURL, "/"), httpClient: httpClient}
+}
+
+// Name returns the provider name
+func (p *OSRMProvider) Name() string {
+	return p.name
+}
+
+// BaseURL returns the OSRM server URL
+func (p *OSRMProvider) BaseURL() string {
+	return p.baseURL
+}
+
+// Durations calls /table/v1/driving with the origin as the only source. It
+// fails when OSRM finds no route to a destination.
+func (p *OSRMProvider) Durations(ctx context.Context, origin domain.Location, destinations []domain.Location) ([]float64, error) {
+	durations, err := p.Table(ctx, origin, destinations)
+	if err != nil {
+		return nil, err
+	}
+	for i, d := range durations {
+		if d < 0 {
+			return nil, fmt.Errorf("OSRM found no route to destination %d", i)
+		}
+	}
+	return durations, nil
+}
+
+// Table calls /table/v1/driving with the origin as the only source, returning
+// -1 for destinations OSRM finds no route to
+func (p *OSRMProvider) Table(ctx context.Context, origin domain.Location, destinations []domain.Location) ([]float64, error) {
+	ctx, span := otel.Tracer("repair-service").Start(ctx, "OSRMTableRequest")
+	defer span.End()
+	span.SetAttributes(attribute.String("provider", p.name))
+
+	coordinates := make([]string, 0, len(destinations)+1)
+	coordinates = append(coordinates, fmt.Sprintf("%f,%f", origin.Longitude, origin.Latitude))
+	for _, d := range destinations {
+		coordinates = append(coordinates, fmt.Sprintf("%f,%f", d.Longitude, d.Latitude))
+	}
+	osrmURL := fmt.Sprintf("%s/table/v1/driving/%s?sources=0", p.baseURL, strings.Join(coordinates, ";"))
+	req, err := http.NewRequestWithContext(ctx, "GET", osrmURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OSRM request: %w", err)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, retryableError{fmt.Errorf("failed to call OSRM table service: %w", err)}
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("statusCode", resp.StatusCode))
+
+	if resp.StatusCode != http.StatusOK {
+		err := fmt.Errorf("OSRM table service returned status %d", resp.StatusCode)
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return nil, retryableError{err}
+		}
+		return nil, err
+	}
+
+	var osrmResp struct {
+		Code      string       `json:"code"`
+		Durations [][]*float64 `json:"durations"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&osrmResp); err != nil {
+		return nil, fmt.Errorf("failed to decode OSRM response: %w", err)
+	}
+	if osrmResp.Code != "Ok" {
+		return nil, fmt.Errorf("OSRM table service returned code: %s", osrmResp.Code)
+	}
+	if len(osrmResp.Durations) == 0 || len(osrmResp.Durations[0]) != len(destinations)+1 {
+		return nil, fmt.Errorf("OSRM table service returned an incomplete table")
+	}
+
+	durations := make([]float64, len(destinations))
+	for i, d := range osrmResp.Durations[0][1:] {
+		durations[i] = -1
+		if d != nil {
+			durations[i] = *d
+		}
+	}
+	return durations, nil
+}
@@ -0,0 +1,292 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"repair-service/domain"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Request classes select which providers serve a routing request, in order
+const (
+	ClassEstimate = "estimate" // cost estimates: accuracy matters more than latency
+	ClassLiveETA  = "live_eta" // frequent ETA refreshes: avoid rate-limited providers
+)
+
+// Built-in provider names
+const (
+	ProviderSelfHosted = "osrm-self-hosted"
+	ProviderPublic     = "osrm-public"
+	ProviderHaversine  = "haversine"
+)
+
+// Provider computes travel durations from one origin to many destinations
+type Provider interface {
+	Name() string
+	// Durations returns the travel time in seconds to each destination, in order
+	Durations(ctx context.Context, origin domain.Location, destinations []domain.Location) ([]float64, error)
+}
+
+// Result is the outcome of a routing request
+type Result struct {
+	Durations []float64 // seconds, one per destination
+	Provider  string    // provider that answered
+}
+
+// Client answers routing requests for a request class
+type Client interface {
+	Durations(ctx context.Context, class string, origin domain.Location, destinations []domain.Location) (*Result, error)
+}
+
+// retryableError marks provider failures worth retrying (timeouts, 5xx, 429)
+type retryableError struct{ err error }
+
+func (e retryableError) Error() string { return e.err.Error() }
+func (e retryableError) Unwrap() error { return e.err }
+
+// ProviderHealth is the health of one provider as tracked by the Router
+type ProviderHealth struct {
+	Name                string    `json:"name"`
+	Healthy             bool      `json:"healthy"`
+	ConsecutiveFailures int       `json:"consecutiveFailures"`
+	Successes           int64     `json:"successes"`
+	Failures            int64     `json:"failures"`
+	LastError           string    `json:"lastError,omitempty"`
+	UnhealthyUntil      time.Time `json:"unhealthyUntil,omitempty"`
+}
+
+// Router tries the providers of a request class in order, retrying each one
+// and skipping providers that recently failed repeatedly
+type Router struct {
+	providers      map[string]Provider
+	classes        map[string][]string
+	maxAttempts    int
+	retryBackoff   time.Duration
+	unhealthyAfter int
+	cooldown       time.Duration
+	logger         *slog.Logger
+	tiles          *TileProvider // nil unless built from env
+
+	mu     sync.Mutex
+	health map[string]*ProviderHealth
+}
+
+// NewRouter creates a Router over providers with the given provider order per class
+func NewRouter(providers []Provider, classes map[string][]string, maxAttempts, unhealthyAfter int, retryBackoff, cooldown time.Duration, logger *slog.Logger) *Router {
+	r := &Router{
+		providers:      make(map[string]Provider, len(providers)),
+		classes:        classes,
+		maxAttempts:    maxAttempts,
+		retryBackoff:   retryBackoff,
+		unhealthyAfter: unhealthyAfter,
+		cooldown:       cooldown,
+		logger:         logger,
+		health:         make(map[string]*ProviderHealth, len(providers)),
+	}
+	for _, p := range providers {
+		r.providers[p.Name()] = p
+		r.health[p.Name()] = &ProviderHealth{Name: p.Name(), Healthy: true}
+	}
+	return r
+}
+
+// NewRouterFromEnv builds a Router from the ROUTING_* and OSRM_* environment
+// variables. OSRM_SELF_HOSTED_URL is optional; the public OSRM, distance tile
+// and haversine providers are always available, the tiles once loaded.
+func NewRouterFromEnv(httpClient *http.Client, logger *slog.Logger) *Router {
+	providers := []Provider{}
+	if u := os.Getenv("OSRM_SELF_HOSTED_URL"); u != "" {
+		providers = append(providers, NewOSRMProvider(ProviderSelfHosted, u, httpClient))
+	}
+	publicURL := os.Getenv("OSRM_PUBLIC_URL")
+	if publicURL == "" {
+		publicURL = "http://router.project-osrm.org"
+	}
+	tiles := NewTileProvider(TileGridFromEnv())
+	providers = append(providers, NewOSRMProvider(ProviderPublic, publicURL, httpClient), tiles, NewHaversineProvider())
+
+	classes := map[string][]string{
+		ClassEstimate: providerList("ROUTING_ESTIMATE_PROVIDERS", ProviderSelfHosted+","+ProviderPublic+","+ProviderTiles+","+ProviderHaversine),
+		ClassLiveETA:  providerList("ROUTING_LIVE_ETA_PROVIDERS", ProviderSelfHosted+","+ProviderTiles+","+ProviderHaversine),
+	}
+
+	maxAttempts := 2
+	if v, err := strconv.Atoi(os.Getenv("ROUTING_MAX_ATTEMPTS")); err == nil && v > 0 {
+		maxAttempts = v
+	}
+	unhealthyAfter := 3
+	if v, err := strconv.Atoi(os.Getenv("ROUTING_UNHEALTHY_AFTER")); err == nil && v > 0 {
+		unhealthyAfter = v
+	}
+	cooldown := 30 * time.Second
+	if v, err := strconv.Atoi(os.Getenv("ROUTING_COOLDOWN_SECONDS")); err == nil && v > 0 {
+		cooldown = time.Duration(v) * time.Second
+	}
+
+	names := make([]string, 0, len(providers))
+	for _, p := range providers {
+		names = append(names, p.Name())
+	}
+	logger.Info("Configured routing providers", "providers", names, "estimate", classes[ClassEstimate], "liveETA", classes[ClassLiveETA], "maxAttempts", maxAttempts, "app", "repair-service")
+	r := NewRouter(providers, classes, maxAttempts, unhealthyAfter, 200*time.Millisecond, cooldown, logger)
+	r.tiles = tiles
+	return r
+}
+
+// providerList reads a comma separated provider order from env, or def
+func providerList(env, def string) []string {
+	value := os.Getenv(env)
+	if value == "" {
+		value = def
+	}
+	var names []string
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Durations answers a routing request with the first provider of class that
+// succeeds. Providers in cooldown are only tried after every healthy one failed.
+func (r *Router) Durations(ctx context.Context, class string, origin domain.Location, destinations []domain.Location) (*Result, error) {
+	ctx, span := otel.Tracer("repair-service").Start(ctx, "RoutingDurations")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("class", class),
+		attribute.Int("destinationCount", len(destinations)),
+	)
+
+	order, ok := r.classes[class]
+	if !ok {
+		err := fmt.Errorf("unknown routing request class %q", class)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	var healthy, cooling []Provider
+	for _, name := range order {
+		p, ok := r.providers[name]
+		if !ok {
+			continue // not configured, e.g. no self-hosted OSRM
+		}
+		if r.isHealthy(name) {
+			healthy = append(healthy, p)
+		} else {
+			cooling = append(cooling, p)
+		}
+	}
+
+	var errs []error
+	for _, p := range append(healthy, cooling...) {
+		durations, err := r.tryProvider(ctx, p, origin, destinations)
+		if err == nil {
+			span.SetAttributes(attribute.String("provider", p.Name()))
+			return &Result{Durations: durations, Provider: p.Name()}, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+		r.logger.Warn("Routing provider failed, falling back", "provider", p.Name(), "class", class, "error", err, "app", "repair-service")
+	}
+
+	err := fmt.Errorf("all routing providers failed for %s: %w", class, errors.Join(errs...))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, "All routing providers failed")
+	return nil, err
+}
+
+// tryProvider calls p up to maxAttempts times, retrying only retryable errors
+func (r *Router) tryProvider(ctx context.Context, p Provider, origin domain.Location, destinations []domain.Location) ([]float64, error) {
+	var err error
+	for attempt := 1; attempt <= r.maxAttempts; attempt++ {
+		var durations []float64
+		durations, err = p.Durations(ctx, origin, destinations)
+		if err == nil && len(durations) != len(destinations) {
+			err = fmt.Errorf("returned %d durations for %d destinations", len(durations), len(destinations))
+		}
+		if err == nil {
+			r.recordSuccess(p.Name())
+			return durations, nil
+		}
+		var retryable retryableError
+		if !errors.As(err, &retryable) || attempt == r.maxAttempts || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(r.retryBackoff * time.Duration(attempt)):
+		}
+	}
+	r.recordFailure(p.Name(), err)
+	return nil, err
+}
+
+// isHealthy reports whether name is outside its failure cooldown
+func (r *Router) isHealthy(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := r.health[name]
+	return h.Healthy || time.Now().After(h.UnhealthyUntil)
+}
+
+func (r *Router) recordSuccess(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := r.health[name]
+	h.Successes++
+	h.ConsecutiveFailures = 0
+	h.Healthy = true
+	h.UnhealthyUntil = time.Time{}
+}
+
+func (r *Router) recordFailure(name string, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h := r.health[name]
+	h.Failures++
+	h.ConsecutiveFailures++
+	h.LastError = err.Error()
+	if h.ConsecutiveFailures >= r.unhealthyAfter {
+		if h.Healthy {
+			r.logger.Error("Routing provider marked unhealthy", "provider", name, "failures", h.ConsecutiveFailures, "cooldown", r.cooldown, "app", "repair-service")
+		}
+		h.Healthy = false
+		h.UnhealthyUntil = time.Now().Add(r.cooldown)
+	}
+}
+
+// Provider returns the configured provider called name, or nil
+func (r *Router) Provider(name string) Provider {
+	return r.providers[name]
+}
+
+// Tiles returns the distance tile provider, nil when the router has none
+func (r *Router) Tiles() *TileProvider {
+	return r.tiles
+}
+
+// Health returns the health of every configured provider
+func (r *Router) Health() []ProviderHealth {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	health := make([]ProviderHealth, 0, len(r.health))
+	for _, h := range r.health {
+		health = append(health, *h)
+	}
+	sort.Slice(health, func(i, j int) bool { return health[i].Name < health[j].Name })
+	return health
+}
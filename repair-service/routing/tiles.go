@@ -0,0 +1,169 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+
+	"repair-service/domain"
+)
+
+// ProviderTiles answers from distance tiles precomputed against OSRM
+const ProviderTiles = "distance-tiles"
+
+// ErrNoTile is returned for origins outside every loaded distance tile
+var ErrNoTile = errors.New("no distance tile covers the origin")
+
+// TileGrid divides the map into square cells of SizeDegrees; a tile holds
+// the travel times from its cell's center to the centers of the cells up to
+// Radius cells away in each direction
+type TileGrid struct {
+	SizeDegrees float64
+	Radius      int
+}
+
+// TileGridFromEnv reads ROUTING_TILE_DEGREES (default 0.02, about 2 km) and
+// ROUTING_TILE_RADIUS (default 4, a 9x9 table per tile, within the public
+// OSRM's 100 coordinate limit)
+func TileGridFromEnv() TileGrid {
+	grid := TileGrid{SizeDegrees: 0.02, Radius: 4}
+	if v, err := strconv.ParseFloat(os.Getenv("ROUTING_TILE_DEGREES"), 64); err == nil && v > 0 {
+		grid.SizeDegrees = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("ROUTING_TILE_RADIUS")); err == nil && v > 0 {
+		grid.Radius = v
+	}
+	return grid
+}
+
+// Cell returns the row and column of the cell containing loc
+func (g TileGrid) Cell(loc domain.Location) (row, col int) {
+	return int(math.Floor(loc.Latitude / g.SizeDegrees)), int(math.Floor(loc.Longitude / g.SizeDegrees))
+}
+
+// Center returns the center of a cell
+func (g TileGrid) Center(row, col int) domain.Location {
+	return domain.Location{Latitude: (float64(row) + 0.5) * g.SizeDegrees, Longitude: (float64(col) + 0.5) * g.SizeDegrees}
+}
+
+// Neighbors returns the centers of the cells a tile covers, row-major from the
+// south-west corner, the order of domain.DistanceTile.Durations
+func (g TileGrid) Neighbors(row, col int) []domain.Location {
+	side := 2*g.Radius + 1
+	centers := make([]domain.Location, 0, side*side)
+	for dr := -g.Radius; dr <= g.Radius; dr++ {
+		for dc := -g.Radius; dc <= g.Radius; dc++ {
+			centers = append(centers, g.Center(row+dr, col+dc))
+		}
+	}
+	return centers
+}
+
+// tilePace is a tile reduced to seconds per straight-line meter from its
+// center to each neighbor, NaN where OSRM found no route
+type tilePace struct {
+	paces   []float64
+	average float64
+}
+
+// TileProvider estimates durations from distance tiles: the straight-line
+// distance times the road pace OSRM measured from the origin's cell towards
+// the destination's cell, or the cell's average pace beyond the tile. It
+// needs no network; origins without a tile fail so the next provider answers.
+type TileProvider struct {
+	grid  TileGrid
+	mu    sync.RWMutex
+	tiles map[[2]int]*tilePace
+}
+
+// NewTileProvider creates a TileProvider for grid with no tiles loaded
+func NewTileProvider(grid TileGrid) *TileProvider {
+	return &TileProvider{grid: grid, tiles: map[[2]int]*tilePace{}}
+}
+
+// Name returns the provider name
+func (p *TileProvider) Name() string {
+	return ProviderTiles
+}
+
+// Grid returns the grid the provider's tiles are built on
+func (p *TileProvider) Grid() TileGrid {
+	return p.grid
+}
+
+// Load replaces the loaded tiles; tiles of another grid are skipped. It
+// returns the number of tiles loaded.
+func (p *TileProvider) Load(tiles []domain.DistanceTile) int {
+	side := 2*p.grid.Radius + 1
+	loaded := make(map[[2]int]*tilePace, len(tiles))
+	for _, tile := range tiles {
+		if tile.SizeDegrees != p.grid.SizeDegrees || tile.Radius != p.grid.Radius || len(tile.Durations) != side*side {
+			continue
+		}
+		center := p.grid.Center(tile.Row, tile.Col)
+		pace := &tilePace{paces: make([]float64, len(tile.Durations))}
+		sum, n := 0.0, 0
+		for i, neighbor := range p.grid.Neighbors(tile.Row, tile.Col) {
+			meters := haversineMeters(center, neighbor)
+			if tile.Durations[i] < 0 || meters == 0 {
+				pace.paces[i] = math.NaN()
+				continue
+			}
+			pace.paces[i] = tile.Durations[i] / meters
+			sum += pace.paces[i]
+			n++
+		}
+		if n == 0 {
+			continue // nothing routable around the cell, e.g. open water
+		}
+		pace.average = sum / float64(n)
+		loaded[[2]int{tile.Row, tile.Col}] = pace
+	}
+	p.mu.Lock()
+	p.tiles = loaded
+	p.mu.Unlock()
+	return len(loaded)
+}
+
+// Count returns the number of loaded tiles
+func (p *TileProvider) Count() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.tiles)
+}
+
+// Durations scales straight-line distances by the origin tile's road pace
+func (p *TileProvider) Durations(ctx context.Context, origin domain.Location, destinations []domain.Location) ([]float64, error) {
+	row, col := p.grid.Cell(origin)
+	p.mu.RLock()
+	tile := p.tiles[[2]int{row, col}]
+	p.mu.RUnlock()
+	if tile == nil {
+		return nil, ErrNoTile
+	}
+
+	side := 2*p.grid.Radius + 1
+	durations := make([]float64, len(destinations))
+	for i, d := range destinations {
+		pace := tile.average
+		dRow, dCol := p.grid.Cell(d)
+		dr, dc := dRow-row, dCol-col
+		if (dr != 0 || dc != 0) && abs(dr) <= p.grid.Radius && abs(dc) <= p.grid.Radius {
+			if v := tile.paces[(dr+p.grid.Radius)*side+dc+p.grid.Radius]; !math.IsNaN(v) {
+				pace = v
+			}
+		}
+		durations[i] = haversineMeters(origin, d) * pace
+	}
+	return durations, nil
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
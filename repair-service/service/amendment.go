@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"repair-service/domain"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// RequestAmendment records extra work the assigned mechanic wants to add to an
+// in-progress repair. It stays pending until the user approves or rejects it.
+func (s *service) RequestAmendment(ctx context.Context, repairID, mechanicID, description string, amount domain.Money) (*domain.Amendment, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceRequestAmendment")
+	defer span.End()
+
+	description = strings.TrimSpace(description)
+	var err error
+	switch {
+	case repairID == "" || mechanicID == "" || description == "":
+		err = fmt.Errorf("%w: repair ID, mechanic ID and description are required", domain.ErrInvalidInput)
+	case amount <= 0:
+		err = fmt.Errorf("%w: amount must be positive", domain.ErrInvalidInput)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid input for amendment", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	span.SetAttributes(
+		attribute.String("repairID", repairID),
+		attribute.String("mechanicID", mechanicID),
+		attribute.String("amount", amount.String()),
+	)
+
+	repair, err := s.repo.GetRepairByID(ctx, repairID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get repair")
+		s.logger.Error("Failed to get repair for amendment", "error", err, "repairID", repairID, "app", "repair-service")
+		return nil, err
+	}
+	switch {
+	case repair.Status != "in_progress":
+		err = fmt.Errorf("%w: amendments can only be requested while the repair is in progress", domain.ErrInvalidInput)
+	case repair.AssignedTo != "" && repair.AssignedTo != mechanicID:
+		err = fmt.Errorf("%w: only the assigned mechanic can request an amendment", domain.ErrInvalidInput)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Amendment rejected", "error", err, "repairID", repairID, "mechanicID", mechanicID, "app", "repair-service")
+		return nil, err
+	}
+
+	amendment := &domain.Amendment{
+		RepairID:    repairID,
+		UserID:      repair.UserID,
+		MechanicID:  mechanicID,
+		Description: description,
+		Amount:      amount,
+		Status:      domain.AmendmentPending,
+		RequestedAt: time.Now(),
+	}
+	if err := s.repo.SaveAmendment(ctx, amendment); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save amendment")
+		s.logger.Error("Failed to save amendment", "error", err, "repairID", repairID, "app", "repair-service")
+		return nil, err
+	}
+	s.logger.Info("Requested repair amendment", "repairID", repairID, "amendmentID", amendment.ID, "amount", amount.String(), "app", "repair-service")
+	return amendment, nil
+}
+
+// DecideAmendment records the user's answer to a pending amendment. status is
+// approved or rejected; a decided amendment cannot be changed.
+func (s *service) DecideAmendment(ctx context.Context, repairID, amendmentID, status string) (*domain.Amendment, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceDecideAmendment")
+	defer span.End()
+
+	var err error
+	switch {
+	case repairID == "" || amendmentID == "":
+		err = fmt.Errorf("%w: repair ID and amendment ID are required", domain.ErrInvalidInput)
+	case status != domain.AmendmentApproved && status != domain.AmendmentRejected:
+		err = fmt.Errorf("%w: status must be %q or %q", domain.ErrInvalidInput, domain.AmendmentApproved, domain.AmendmentRejected)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid input for amendment decision", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	span.SetAttributes(
+		attribute.String("repairID", repairID),
+		attribute.String("amendmentID", amendmentID),
+		attribute.String("status", status),
+	)
+
+	amendment, err := s.repo.DecideAmendment(ctx, repairID, amendmentID, status, time.Now())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// Tell an amendment that was already decided apart from a missing one
+		amendments, findErr := s.repo.FindAmendments(ctx, repairID)
+		if findErr != nil {
+			err = findErr
+		}
+		for _, a := range amendments {
+			if a.ID == amendmentID {
+				err = fmt.Errorf("%w: amendment is already %s", domain.ErrInvalidInput, a.Status)
+			}
+		}
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to decide amendment")
+		s.logger.Error("Failed to decide amendment", "error", err, "repairID", repairID, "amendmentID", amendmentID, "app", "repair-service")
+		return nil, err
+	}
+	s.logger.Info("Decided repair amendment", "repairID", repairID, "amendmentID", amendmentID, "status", status, "app", "repair-service")
+	return amendment, nil
+}
+
+// ListAmendments returns a repair's amendments, oldest first
+func (s *service) ListAmendments(ctx context.Context, repairID string) ([]*domain.Amendment, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceListAmendments")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", repairID))
+
+	// Amendments are stored apart from the repair, so check it exists
+	if _, err := s.repo.GetRepairByID(ctx, repairID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get repair")
+		s.logger.Error("Failed to get repair for amendments", "error", err, "repairID", repairID, "app", "repair-service")
+		return nil, err
+	}
+	amendments, err := s.repo.FindAmendments(ctx, repairID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to list amendments")
+		s.logger.Error("Failed to list amendments", "error", err, "repairID", repairID, "app", "repair-service")
+		return nil, err
+	}
+	return amendments, nil
+}
+
+// completionAmendments checks a repair may complete and returns the
+// amendments to bill. No amendment may still await the user, and when any
+// was approved the caller must confirm the final amount it adds up to.
+func (s *service) completionAmendments(ctx context.Context, repair *domain.RepairModel, finalAmount *domain.Money) ([]*domain.Amendment, error) {
+	amendments, err := s.repo.FindAmendments(ctx, repair.ID)
+	if err != nil {
+		return nil, err
+	}
+	approved := false
+	for _, a := range amendments {
+		switch a.Status {
+		case domain.AmendmentPending:
+			return nil, domain.ErrAmendmentPending
+		case domain.AmendmentApproved:
+			approved = true
+		}
+	}
+
+	final := domain.FinalAmount(repair.QuotedPrice(), amendments)
+	switch {
+	case finalAmount == nil && approved:
+		return nil, fmt.Errorf("%w: finalAmount %s is required to complete a repair with approved amendments", domain.ErrInvalidInput, final)
+	case finalAmount != nil && *finalAmount != final:
+		return nil, fmt.Errorf("%w: finalAmount %s does not match the approved amount %s", domain.ErrInvalidInput, finalAmount, final)
+	}
+	return amendments, nil
+}
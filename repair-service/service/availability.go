@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+
+	"repair-service/domain"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// averageSpeedMetersPerMinute matches the 50 km/h used to derive mechanic distances
+const averageSpeedMetersPerMinute = 50000.0 / 60.0
+
+// summarizeAvailability reports how many online mechanics qualified for the
+// repair type are within the availability radius, how many of them are free,
+// and the expected wait bucket based on the nearest free mechanic
+func (s *service) summarizeAvailability(ctx context.Context, repairType string, mechanics []*domain.MechanicModel, infos []domain.MechanicInfo) *domain.Availability {
+	ctx, span := s.tracer.Start(ctx, "ServiceSummarizeAvailability")
+	defer span.End()
+
+	byID := make(map[string]*domain.MechanicModel, len(mechanics))
+	for _, m := range mechanics {
+		byID[m.ID] = m
+	}
+
+	var candidates []domain.MechanicInfo
+	var candidateIDs []string
+	for _, info := range infos {
+		m, ok := byID[info.ID]
+		if !ok || info.Distance > s.availabilityRadius || !isOnline(m) || !isQualified(m, repairType) {
+			continue
+		}
+		candidates = append(candidates, info)
+		candidateIDs = append(candidateIDs, info.ID)
+	}
+
+	availability := &domain.Availability{
+		RadiusMeters:    s.availabilityRadius,
+		OnlineMechanics: len(candidates),
+		WaitBucket:      domain.WaitUnavailable,
+		Confidence:      "low",
+	}
+	if len(candidates) == 0 {
+		span.SetAttributes(attribute.Int("onlineMechanics", 0))
+		return availability
+	}
+
+	busy, err := s.repo.CountActiveAssignments(ctx, candidateIDs)
+	if err != nil {
+		// Without assignment data every nearby mechanic may be busy; report a
+		// conservative wait and low confidence rather than failing the estimate
+		span.RecordError(err)
+		s.logger.Warn("Failed to count active assignments, reporting low confidence", "error", err, "app", "repair-service")
+		availability.WaitBucket = domain.WaitOver30Min
+		return availability
+	}
+
+	nearestFree := -1.0
+	for _, c := range candidates {
+		if busy[c.ID] > 0 {
+			continue
+		}
+		availability.AvailableMechanics++
+		if nearestFree < 0 || c.Distance < nearestFree {
+			nearestFree = c.Distance
+		}
+	}
+
+	switch {
+	case nearestFree < 0:
+		availability.WaitBucket = domain.WaitOver30Min
+	case nearestFree/averageSpeedMetersPerMinute < 15:
+		availability.WaitBucket = domain.WaitUnder15Min
+	case nearestFree/averageSpeedMetersPerMinute < 30:
+		availability.WaitBucket = domain.Wait15To30Min
+	default:
+		availability.WaitBucket = domain.WaitOver30Min
+	}
+
+	switch {
+	case availability.AvailableMechanics >= 3:
+		availability.Confidence = "high"
+	case availability.AvailableMechanics >= 1:
+		availability.Confidence = "medium"
+	}
+
+	span.SetAttributes(
+		attribute.Int("onlineMechanics", availability.OnlineMechanics),
+		attribute.Int("availableMechanics", availability.AvailableMechanics),
+		attribute.String("waitBucket", availability.WaitBucket),
+		attribute.String("confidence", availability.Confidence),
+	)
+	return availability
+}
+
+// isOnline treats mechanics without a status as online for older records
+func isOnline(m *domain.MechanicModel) bool {
+	return m.Status == "" || m.Status == domain.MechanicOnline
+}
+
+// isQualified reports whether the mechanic handles the repair type; mechanics
+// without listed skills handle every type
+func isQualified(m *domain.MechanicModel, repairType string) bool {
+	if len(m.Skills) == 0 {
+		return true
+	}
+	for _, skill := range m.Skills {
+		if skill == repairType {
+			return true
+		}
+	}
+	return false
+}
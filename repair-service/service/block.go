@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"repair-service/domain"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// BlockMechanic stops a user from ever being matched with a mechanic again
+func (s *service) BlockMechanic(ctx context.Context, userID, mechanicID string) (*domain.Block, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceBlockMechanic")
+	defer span.End()
+
+	if userID == "" || mechanicID == "" || mechanicID == domain.AllMechanics {
+		err := fmt.Errorf("%w: user ID and mechanic ID are required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid input for block", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	span.SetAttributes(
+		attribute.String("userID", userID),
+		attribute.String("mechanicID", mechanicID),
+	)
+
+	block, err := s.repo.SaveBlock(ctx, &domain.Block{
+		UserID:     userID,
+		MechanicID: mechanicID,
+		BlockedBy:  domain.BlockedByUser,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save block")
+		s.logger.Error("Failed to save block", "error", err, "userID", userID, "mechanicID", mechanicID, "app", "repair-service")
+		return nil, fmt.Errorf("failed to save block: %w", err)
+	}
+	s.logger.Info("User blocked mechanic", "userID", userID, "mechanicID", mechanicID, "app", "repair-service")
+	return block, nil
+}
+
+// UnblockMechanic removes a block a user placed on a mechanic
+func (s *service) UnblockMechanic(ctx context.Context, userID, mechanicID string) error {
+	ctx, span := s.tracer.Start(ctx, "ServiceUnblockMechanic")
+	defer span.End()
+
+	if userID == "" || mechanicID == "" || mechanicID == domain.AllMechanics {
+		err := fmt.Errorf("%w: user ID and mechanic ID are required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid input for unblock", "error", err, "app", "repair-service")
+		return err
+	}
+	span.SetAttributes(
+		attribute.String("userID", userID),
+		attribute.String("mechanicID", mechanicID),
+	)
+
+	if err := s.repo.DeleteBlock(ctx, userID, mechanicID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to delete block")
+		s.logger.Error("Failed to delete block", "error", err, "userID", userID, "mechanicID", mechanicID, "app", "repair-service")
+		return err
+	}
+	s.logger.Info("User unblocked mechanic", "userID", userID, "mechanicID", mechanicID, "app", "repair-service")
+	return nil
+}
+
+// ListBlocks returns the block relations of a user, including an admin blacklist
+func (s *service) ListBlocks(ctx context.Context, userID string) ([]*domain.Block, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceListBlocks")
+	defer span.End()
+
+	if userID == "" {
+		err := fmt.Errorf("%w: user ID is required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid input for list blocks", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("userID", userID))
+
+	blocks, err := s.repo.FindBlocks(ctx, userID, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find blocks")
+		s.logger.Error("Failed to find blocks", "error", err, "userID", userID, "app", "repair-service")
+		return nil, fmt.Errorf("failed to find blocks: %w", err)
+	}
+	return blocks, nil
+}
+
+// BlacklistUser blocks an abusive user from every mechanic (admin)
+func (s *service) BlacklistUser(ctx context.Context, userID, reason string) (*domain.Block, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceBlacklistUser")
+	defer span.End()
+
+	if userID == "" || reason == "" {
+		err := fmt.Errorf("%w: user ID and reason are required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid input for blacklist", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("userID", userID))
+
+	block, err := s.repo.SaveBlock(ctx, &domain.Block{
+		UserID:     userID,
+		MechanicID: domain.AllMechanics,
+		BlockedBy:  domain.BlockedByAdmin,
+		Reason:     reason,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save blacklist")
+		s.logger.Error("Failed to save blacklist", "error", err, "userID", userID, "app", "repair-service")
+		return nil, fmt.Errorf("failed to save blacklist: %w", err)
+	}
+	s.logger.Info("Blacklisted user", "userID", userID, "app", "repair-service")
+	return block, nil
+}
+
+// RemoveBlacklist lifts an admin blacklist; blocks the user placed stay in force
+func (s *service) RemoveBlacklist(ctx context.Context, userID string) error {
+	ctx, span := s.tracer.Start(ctx, "ServiceRemoveBlacklist")
+	defer span.End()
+
+	if userID == "" {
+		err := fmt.Errorf("%w: user ID is required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid input for remove blacklist", "error", err, "app", "repair-service")
+		return err
+	}
+	span.SetAttributes(attribute.String("userID", userID))
+
+	if err := s.repo.DeleteBlock(ctx, userID, domain.AllMechanics); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to remove blacklist")
+		s.logger.Error("Failed to remove blacklist", "error", err, "userID", userID, "app", "repair-service")
+		return err
+	}
+	s.logger.Info("Removed user from blacklist", "userID", userID, "app", "repair-service")
+	return nil
+}
+
+// userBlocks loads the block relations of userID and rejects blacklisted users
+func (s *service) userBlocks(ctx context.Context, userID string) (*domain.BlockList, error) {
+	blocks, err := s.repo.FindBlocks(ctx, userID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find blocks: %w", err)
+	}
+	list := domain.NewBlockList(blocks)
+	if list.Blacklisted {
+		return nil, fmt.Errorf("%w: %s", domain.ErrBlacklisted, userID)
+	}
+	return list, nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"repair-service/domain"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// errDryRun rolls back the transaction of a dry-run batch
+var errDryRun = errors.New("dry run")
+
+// BulkUpdateStatus moves many repairs to one status. Each repair's
+// transition is checked against the status state machine; the allowed ones
+// are written in batches of BULK_STATUS_BATCH_SIZE, each batch in one
+// transaction with a RepairUpdated outbox event per repair. A failed batch
+// marks its repairs failed and the next batches still run.
+func (s *service) BulkUpdateStatus(ctx context.Context, req *domain.BulkStatusRequest) (*domain.BulkStatusReport, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceBulkUpdateStatus")
+	defer span.End()
+
+	if err := req.Validate(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(
+		attribute.String("status", req.Status),
+		attribute.Int("repairCount", len(req.RepairIDs)),
+		attribute.String("requestedBy", req.RequestedBy),
+		attribute.Bool("dryRun", req.DryRun),
+	)
+
+	report := &domain.BulkStatusReport{Status: req.Status, DryRun: req.DryRun, Results: make([]domain.BulkStatusResult, len(req.RepairIDs))}
+	for i, id := range req.RepairIDs {
+		report.Results[i].RepairID = id
+	}
+	for start := 0; start < len(report.Results); start += s.bulkBatchSize {
+		end := min(start+s.bulkBatchSize, len(report.Results))
+		if err := s.bulkStatusBatch(ctx, req, report.Results[start:end]); err != nil {
+			span.RecordError(err)
+			s.logger.Error("Failed to write bulk status batch", "error", err, "batchStart", start, "batchSize", end-start, "app", "repair-service")
+		}
+	}
+
+	for _, result := range report.Results {
+		switch result.Outcome {
+		case domain.BulkStatusUpdated, domain.BulkStatusWouldUpdate:
+			report.Updated++
+		case domain.BulkStatusUnchanged:
+			report.Unchanged++
+		case domain.BulkStatusRejected:
+			report.Rejected++
+		case domain.BulkStatusNotFound:
+			report.NotFound++
+		case domain.BulkStatusFailed:
+			report.Failed++
+		}
+	}
+	span.SetAttributes(
+		attribute.Int("updated", report.Updated),
+		attribute.Int("rejected", report.Rejected),
+		attribute.Int("failed", report.Failed),
+	)
+	s.logger.Warn("Bulk status update", "status", req.Status, "requestedBy", req.RequestedBy, "reason", req.Reason, "dryRun", req.DryRun,
+		"updated", report.Updated, "unchanged", report.Unchanged, "rejected", report.Rejected, "notFound", report.NotFound, "failed", report.Failed, "app", "repair-service")
+	return report, nil
+}
+
+// bulkStatusBatch reads and updates the repairs of results in one
+// transaction and records each repair's outcome. When the transaction fails
+// the repairs it would have updated are marked failed.
+func (s *service) bulkStatusBatch(ctx context.Context, req *domain.BulkStatusRequest, results []domain.BulkStatusResult) (err error) {
+	defer func() {
+		if err == nil {
+			return
+		}
+		for i := range results {
+			if results[i].Outcome == "" || results[i].Outcome == domain.BulkStatusUpdated {
+				results[i].Outcome, results[i].Message = domain.BulkStatusFailed, err.Error()
+			}
+		}
+	}()
+
+	err = s.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		ids := make([]string, len(results))
+		for i, result := range results {
+			ids[i] = result.RepairID
+		}
+		repairs, err := s.repo.FindRepairs(tx, domain.RepairFilter{IDs: ids}, nil)
+		if err != nil {
+			return err
+		}
+		byID := make(map[string]*domain.RepairModel, len(repairs))
+		for _, repair := range repairs {
+			byID[repair.ID] = repair
+		}
+
+		for i := range results {
+			result := &results[i]
+			repair := byID[result.RepairID]
+			if repair == nil {
+				result.Outcome = domain.BulkStatusNotFound
+				continue
+			}
+			result.From = repair.Status
+			switch {
+			case repair.Status == req.Status:
+				result.Outcome = domain.BulkStatusUnchanged
+				continue
+			case !domain.CanTransition(repair.Status, req.Status):
+				result.Outcome = domain.BulkStatusRejected
+				result.Message = fmt.Sprintf("a %s repair cannot become %s", repair.Status, req.Status)
+				continue
+			case req.DryRun:
+				result.Outcome = domain.BulkStatusWouldUpdate
+				continue
+			}
+
+			if err := s.repo.UpdateRepair(tx, repair.ID, req.Status); err != nil {
+				return fmt.Errorf("failed to update repair %s: %w", repair.ID, err)
+			}
+			repair.Status = req.Status
+			outboxEvent, err := s.repairUpdatedEvent(repair)
+			if err != nil {
+				return err
+			}
+			if err := s.repo.SaveOutboxEvent(tx, outboxEvent); err != nil {
+				return fmt.Errorf("failed to save outbox event: %w", err)
+			}
+			if req.Status == "cancelled" && !req.SkipEmail {
+				if delivery := s.prepareEmail(ctx, domain.EmailTemplateCancellation, repair, repairEmailVariables(repair)); delivery != nil {
+					if err := s.repo.SaveEmailDelivery(tx, delivery); err != nil {
+						return fmt.Errorf("failed to queue email: %w", err)
+					}
+				}
+			}
+			result.Outcome = domain.BulkStatusUpdated
+		}
+		if req.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+	return err
+}
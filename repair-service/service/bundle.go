@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"repair-service/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// bundleConfig limits which repairs can be bundled and prices bundles
+type bundleConfig struct {
+	radius          float64 // meters every bundled repair may be from the bundle's centroid
+	maxRepairs      int
+	discountPercent float64 // taken off the sum of the bundled repairs' prices
+}
+
+// CreateRepairBundle bundles pending, unassigned repairs at one location so
+// they are dispatched to one mechanic together, at the bundled price
+func (s *service) CreateRepairBundle(ctx context.Context, req *domain.RepairBundleRequest) (*domain.RepairBundle, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceCreateRepairBundle")
+	defer span.End()
+
+	repairIDs := make([]string, 0, len(req.RepairIDs))
+	seen := make(map[string]bool, len(req.RepairIDs))
+	for _, id := range req.RepairIDs {
+		if id = strings.TrimSpace(id); id != "" && !seen[id] {
+			seen[id] = true
+			repairIDs = append(repairIDs, id)
+		}
+	}
+	span.SetAttributes(attribute.Int("repairCount", len(repairIDs)))
+	if len(repairIDs) < 2 || len(repairIDs) > s.bundles.maxRepairs {
+		err := fmt.Errorf("%w: a bundle takes 2 to %d repairs", domain.ErrInvalidInput, s.bundles.maxRepairs)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid repair bundle", "error", err, "app", "repair-service")
+		return nil, err
+	}
+
+	repairs, err := s.repo.FindRepairs(ctx, domain.RepairFilter{IDs: repairIDs}, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get repairs")
+		s.logger.Error("Failed to get repairs to bundle", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	if err := s.checkBundleable(repairIDs, repairs); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Repairs cannot be bundled", "error", err, "app", "repair-service")
+		return nil, err
+	}
+
+	bundle := &domain.RepairBundle{
+		ID:              primitive.NewObjectID().Hex(),
+		Name:            strings.TrimSpace(req.Name),
+		RepairIDs:       repairIDs,
+		Location:        bundleCentroid(repairs),
+		Region:          repairs[0].Region,
+		DiscountPercent: s.bundles.discountPercent,
+		CreatedBy:       req.CreatedBy,
+		CreatedAt:       time.Now(),
+	}
+	for _, repair := range repairs {
+		bundle.Subtotal += repair.RepairCost.TotalPrice
+	}
+	for _, repair := range repairs {
+		if d := haversineMeters(bundle.Location, *repair.RepairCost.UserLocation); d > s.bundles.radius {
+			err := fmt.Errorf("%w: repair %s is %.0fm from the bundle's location, at most %.0fm are allowed", domain.ErrInvalidInput, repair.ID, d, s.bundles.radius)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			s.logger.Error("Repairs cannot be bundled", "error", err, "app", "repair-service")
+			return nil, err
+		}
+	}
+	discounts := splitBundleDiscount(repairs, domain.Money(math.Round(float64(bundle.Subtotal)*bundle.DiscountPercent/100)))
+	bundle.Price = bundle.Subtotal
+	for _, d := range discounts {
+		bundle.Price -= d
+	}
+	span.SetAttributes(
+		attribute.String("bundleID", bundle.ID),
+		attribute.Int64("priceMinor", bundle.Price.Minor()),
+	)
+
+	err = s.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		return s.repo.CreateRepairBundle(tx, bundle, discounts)
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to create repair bundle")
+		s.logger.Error("Failed to create repair bundle", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	for _, repair := range repairs {
+		repair.BundleID = bundle.ID
+		repair.BundleDiscount = discounts[repair.ID]
+	}
+	fillBundle(bundle, repairs)
+	s.logger.Info("Created repair bundle", "bundleID", bundle.ID, "repairCount", len(repairIDs), "price", bundle.Price.String(), "app", "repair-service")
+	return bundle, nil
+}
+
+// checkBundleable checks every requested repair exists and can still be
+// bundled; the bundle transaction checks again against concurrent changes
+func (s *service) checkBundleable(repairIDs []string, repairs []*domain.RepairModel) error {
+	found := make(map[string]*domain.RepairModel, len(repairs))
+	for _, repair := range repairs {
+		found[repair.ID] = repair
+	}
+	for _, id := range repairIDs {
+		repair, ok := found[id]
+		switch {
+		case !ok:
+			return fmt.Errorf("%w: repair %s not found", domain.ErrInvalidInput, id)
+		case repair.Status != "pending" || repair.AssignedTo != "":
+			return fmt.Errorf("%w: repair %s is %s, only pending unassigned repairs can be bundled", domain.ErrInvalidInput, id, repair.Status)
+		case repair.BundleID != "":
+			return fmt.Errorf("%w: repair %s is already in bundle %s", domain.ErrInvalidInput, id, repair.BundleID)
+		case repair.RepairCost == nil || repair.RepairCost.UserLocation == nil:
+			return fmt.Errorf("%w: repair %s has no location", domain.ErrInvalidInput, id)
+		}
+	}
+	return nil
+}
+
+// bundleCentroid is the mean location of the repairs
+func bundleCentroid(repairs []*domain.RepairModel) domain.Location {
+	var centroid domain.Location
+	for _, repair := range repairs {
+		centroid.Latitude += repair.RepairCost.UserLocation.Latitude
+		centroid.Longitude += repair.RepairCost.UserLocation.Longitude
+	}
+	centroid.Latitude /= float64(len(repairs))
+	centroid.Longitude /= float64(len(repairs))
+	return centroid
+}
+
+// splitBundleDiscount shares the discount among the repairs in proportion to
+// their price; rounding leftovers go to the last repair, so the shares add up
+// to the discount exactly
+func splitBundleDiscount(repairs []*domain.RepairModel, discount domain.Money) map[string]domain.Money {
+	var subtotal domain.Money
+	for _, repair := range repairs {
+		subtotal += repair.RepairCost.TotalPrice
+	}
+	shares := make(map[string]domain.Money, len(repairs))
+	if discount <= 0 || subtotal <= 0 {
+		return shares
+	}
+	left := discount
+	for i, repair := range repairs {
+		share := left
+		if i < len(repairs)-1 {
+			share = domain.Money(int64(discount) * int64(repair.RepairCost.TotalPrice) / int64(subtotal))
+		}
+		shares[repair.ID] = share
+		left -= share
+	}
+	return shares
+}
+
+// fillBundle sets a bundle's items and derived status from its repairs
+func fillBundle(bundle *domain.RepairBundle, repairs []*domain.RepairModel) {
+	byID := make(map[string]*domain.RepairModel, len(repairs))
+	for _, repair := range repairs {
+		byID[repair.ID] = repair
+	}
+	bundle.Items = make([]domain.BundleItem, 0, len(bundle.RepairIDs))
+	bundle.Statuses = map[string]int{}
+	for _, id := range bundle.RepairIDs {
+		repair, ok := byID[id]
+		if !ok {
+			continue
+		}
+		item := domain.BundleItem{
+			RepairID:   repair.ID,
+			UserID:     repair.UserID,
+			Status:     repair.Status,
+			AssignedTo: repair.AssignedTo,
+		}
+		if repair.RepairCost != nil {
+			item.RepairType = repair.RepairCost.RepairType
+			item.Price = repair.QuotedPrice()
+		}
+		if repair.AssignedTo != "" {
+			bundle.AssignedTo = repair.AssignedTo
+		}
+		bundle.Items = append(bundle.Items, item)
+		bundle.Statuses[repair.Status]++
+	}
+	bundle.Status = domain.BundleStatus(bundle.Statuses, bundle.AssignedTo != "")
+}
+
+// GetRepairBundle returns a bundle with the status of each of its repairs
+func (s *service) GetRepairBundle(ctx context.Context, id string) (*domain.RepairBundle, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceGetRepairBundle")
+	defer span.End()
+	span.SetAttributes(attribute.String("bundleID", id))
+
+	bundle, err := s.repo.GetRepairBundle(ctx, id)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get repair bundle")
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			s.logger.Error("Failed to get repair bundle", "error", err, "bundleID", id, "app", "repair-service")
+		}
+		return nil, err
+	}
+	repairs, err := s.repo.FindRepairs(ctx, domain.RepairFilter{IDs: bundle.RepairIDs}, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get bundled repairs")
+		s.logger.Error("Failed to get bundled repairs", "error", err, "bundleID", id, "app", "repair-service")
+		return nil, err
+	}
+	fillBundle(bundle, repairs)
+	span.SetAttributes(attribute.String("status", bundle.Status))
+	return bundle, nil
+}
+
+// ListRepairBundles lists bundles, newest first, without their items
+func (s *service) ListRepairBundles(ctx context.Context, opts *domain.QueryOptions) ([]*domain.RepairBundle, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceListRepairBundles")
+	defer span.End()
+
+	bundles, err := s.repo.FindRepairBundles(ctx, opts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to list repair bundles")
+		s.logger.Error("Failed to list repair bundles", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	return bundles, nil
+}
+
+// DissolveRepairBundle deletes a bundle nobody was assigned yet; its repairs
+// are dispatched on their own again, at their quoted prices
+func (s *service) DissolveRepairBundle(ctx context.Context, id string) error {
+	ctx, span := s.tracer.Start(ctx, "ServiceDissolveRepairBundle")
+	defer span.End()
+	span.SetAttributes(attribute.String("bundleID", id))
+
+	err := s.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		return s.repo.DeleteRepairBundle(tx, id)
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to dissolve repair bundle")
+		s.logger.Error("Failed to dissolve repair bundle", "error", err, "bundleID", id, "app", "repair-service")
+		return err
+	}
+	s.logger.Info("Dissolved repair bundle", "bundleID", id, "app", "repair-service")
+	return nil
+}
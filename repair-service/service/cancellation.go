@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"repair-service/domain"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// CancelRepair cancels a repair that is not completed for its user, its
+// assigned mechanic or an admin, emailing the user and publishing a
+// RepairCancelled event in the same transaction. Cancelling a cancelled
+// repair returns it unchanged.
+func (s *service) CancelRepair(ctx context.Context, repairID string, actor domain.Actor) (*domain.RepairModel, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceCancelRepair")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", repairID), attribute.String("actorRole", actor.Role))
+
+	repair, err := s.cancellableRepair(ctx, repairID, actor, domain.AuthorizeCancel)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if repair.Status == "cancelled" {
+		return repair, nil
+	}
+	if !domain.CanTransition(repair.Status, "cancelled") {
+		err := fmt.Errorf("%w: repair is %s", domain.ErrRepairNotCancellable, repair.Status)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	from := repair.Status
+	repair.Status = "cancelled"
+	event, err := s.repairCancelledEvent(repair)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to build cancellation event")
+		return nil, err
+	}
+	email := s.prepareEmail(ctx, domain.EmailTemplateCancellation, repair, repairEmailVariables(repair))
+	err = s.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		if err := s.repo.UpdateRepair(tx, repairID, repair.Status); err != nil {
+			return fmt.Errorf("failed to update repair: %w", err)
+		}
+		if err := s.repo.SaveOutboxEvent(tx, event); err != nil {
+			return fmt.Errorf("failed to save outbox event: %w", err)
+		}
+		if email != nil {
+			if err := s.repo.SaveEmailDelivery(tx, email); err != nil {
+				return fmt.Errorf("failed to queue email: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to cancel repair")
+		s.logger.Error("Failed to cancel repair", "error", err, "repairID", repairID, "app", "repair-service")
+		return nil, err
+	}
+	s.logger.Info("Cancelled repair", "repairID", repairID, "from", from, "actorRole", actor.Role, "actorID", actor.ID, "app", "repair-service")
+	return repair, nil
+}
+
+// DeleteRepair deletes a pending or cancelled repair outside a bundle, with
+// its notes and amendments, for its user or an admin, publishing a
+// RepairCancelled event with status deleted in the same transaction
+func (s *service) DeleteRepair(ctx context.Context, repairID string, actor domain.Actor) error {
+	ctx, span := s.tracer.Start(ctx, "ServiceDeleteRepair")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", repairID), attribute.String("actorRole", actor.Role))
+
+	repair, err := s.cancellableRepair(ctx, repairID, actor, domain.AuthorizeDelete)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	from := repair.Status
+	repair.Status = domain.RepairStatusDeleted
+	event, err := s.repairCancelledEvent(repair)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to build cancellation event")
+		return err
+	}
+	err = s.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		if err := s.repo.DeleteRepair(tx, repairID); err != nil {
+			return err
+		}
+		if err := s.repo.SaveOutboxEvent(tx, event); err != nil {
+			return fmt.Errorf("failed to save outbox event: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to delete repair")
+		if !errors.Is(err, domain.ErrRepairNotDeletable) {
+			s.logger.Error("Failed to delete repair", "error", err, "repairID", repairID, "app", "repair-service")
+		}
+		return err
+	}
+	s.logger.Info("Deleted repair", "repairID", repairID, "from", from, "actorRole", actor.Role, "actorID", actor.ID, "app", "repair-service")
+	return nil
+}
+
+// cancellableRepair reads a repair and checks authorize lets the actor
+// cancel or delete it
+func (s *service) cancellableRepair(ctx context.Context, repairID string, actor domain.Actor, authorize func(domain.Actor, *domain.RepairModel) error) (*domain.RepairModel, error) {
+	repair, err := s.repo.GetRepairByID(ctx, repairID)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			s.logger.Error("Failed to get repair", "error", err, "repairID", repairID, "app", "repair-service")
+		}
+		return nil, err
+	}
+	if err := authorize(actor, repair); err != nil {
+		s.logger.Warn("Refused repair cancellation", "error", err, "repairID", repairID, "actorRole", actor.Role, "actorID", actor.ID, "app", "repair-service")
+		return nil, err
+	}
+	return repair, nil
+}
+
+// repairCancelledEvent builds the RepairCancelled outbox event carrying
+// repair's state, its status cancelled or deleted
+func (s *service) repairCancelledEvent(repair *domain.RepairModel) (*domain.OutboxEvent, error) {
+	event, err := s.repairUpdatedEvent(repair)
+	if err != nil {
+		return nil, err
+	}
+	event.EventType = domain.EventRepairCancelled
+	return event, nil
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"math"
+	"sort"
+
+	"repair-service/domain"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// candidatePrefilter bounds how many mechanics an estimate routes to, so the
+// routing matrix stays the same size however many mechanics there are
+type candidatePrefilter struct {
+	limit     int     // mechanics routed per estimate; 0 routes to every mechanic
+	radius    float64 // meters of the first search box around the user
+	maxRadius float64 // meters the search box may grow to while too few mechanics are found
+}
+
+// boxAround returns the bounding box of a circle of radius meters around loc
+func boxAround(loc domain.Location, radius float64) domain.BoundingBox {
+	const metersPerDegree = 111320.0
+	dLat := radius / metersPerDegree
+	dLon := 180.0
+	if cos := math.Cos(loc.Latitude * math.Pi / 180); cos > 1e-6 {
+		dLon = math.Min(radius/(metersPerDegree*cos), 180)
+	}
+	return domain.BoundingBox{
+		MinLatitude:  math.Max(loc.Latitude-dLat, -90),
+		MaxLatitude:  math.Min(loc.Latitude+dLat, 90),
+		MinLongitude: math.Max(loc.Longitude-dLon, -180),
+		MaxLongitude: math.Min(loc.Longitude+dLon, 180),
+	}
+}
+
+// nearbyMechanics returns at most limit mechanics near the user, preferring
+// online mechanics qualified for the repair type, then the nearest in a
+// straight line. The search box starts at radius and doubles up to maxRadius
+// until it holds limit mechanics.
+func (s *service) nearbyMechanics(ctx context.Context, repairType string, userLocation *domain.Location) ([]*domain.MechanicModel, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceNearbyMechanics")
+	defer span.End()
+
+	cfg := s.prefilter
+	if cfg.limit <= 0 {
+		return s.repo.GetAllMechanics(ctx, nil)
+	}
+
+	var mechanics []*domain.MechanicModel
+	radius := cfg.radius
+	for {
+		found, err := s.repo.FindMechanicsInBox(ctx, boxAround(*userLocation, radius))
+		if err != nil {
+			return nil, err
+		}
+		mechanics = found
+		if len(mechanics) >= cfg.limit || radius >= cfg.maxRadius {
+			break
+		}
+		radius = math.Min(radius*2, cfg.maxRadius)
+	}
+
+	distances := make(map[string]float64, len(mechanics))
+	for _, m := range mechanics {
+		distances[m.ID] = haversineMeters(*userLocation, m.Location)
+	}
+	sort.SliceStable(mechanics, func(i, j int) bool {
+		ei := isOnline(mechanics[i]) && isQualified(mechanics[i], repairType)
+		ej := isOnline(mechanics[j]) && isQualified(mechanics[j], repairType)
+		if ei != ej {
+			return ei
+		}
+		return distances[mechanics[i].ID] < distances[mechanics[j].ID]
+	})
+	found := len(mechanics)
+	if len(mechanics) > cfg.limit {
+		mechanics = mechanics[:cfg.limit]
+	}
+	span.SetAttributes(
+		attribute.Float64("searchRadiusMeters", radius),
+		attribute.Int("mechanicsInBox", found),
+		attribute.Int("mechanicCount", len(mechanics)),
+	)
+	return mechanics, nil
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"repair-service/domain"
+	"repair-service/region"
+	"repair-service/routing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// distanceTileConfig sets how the distance tiles estimates fall back to
+// while OSRM is unreachable are built
+type distanceTileConfig struct {
+	interval    time.Duration // tiles older than this are rebuilt; 0 only loads tiles
+	regions     region.Scope  // regions to build tiles for, empty for all
+	lookback    time.Duration // repairs created this recently mark cells worth a tile
+	maxCells    int           // tiles built and kept fresh at most, busiest cells first
+	delay       time.Duration // pause between OSRM requests
+	maxFailures int           // consecutive OSRM failures that end a build
+}
+
+// routingAccuracy maps the routing provider of an estimate to its accuracy
+func routingAccuracy(provider string) string {
+	switch provider {
+	case routing.ProviderTiles:
+		return domain.AccuracyTile
+	case routing.ProviderHaversine:
+		return domain.AccuracyStraightLine
+	default:
+		return domain.AccuracyRouted
+	}
+}
+
+// runDistanceTiles rebuilds stale distance tiles every interval and reloads
+// the tiles estimates answer from, including those other instances built. It
+// runs under the supervisor.
+func (s *service) runDistanceTiles(ctx context.Context) error {
+	tiles := s.router.Tiles()
+	if tiles == nil {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	reload := s.tiles.interval
+	if reload == 0 {
+		reload = time.Hour
+	}
+	s.logger.Info("Distance tile builder started", "sizeDegrees", tiles.Grid().SizeDegrees, "radius", tiles.Grid().Radius, "interval", s.tiles.interval, "maxCells", s.tiles.maxCells, "app", "repair-service")
+	for {
+		if s.tiles.interval > 0 {
+			if err := s.buildDistanceTiles(ctx, tiles.Grid()); err != nil && ctx.Err() == nil {
+				s.logger.Error("Failed to build distance tiles", "error", err, "app", "repair-service")
+			}
+		}
+		if err := s.loadDistanceTiles(ctx, tiles); err != nil && ctx.Err() == nil {
+			s.logger.Error("Failed to load distance tiles", "error", err, "app", "repair-service")
+		}
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Stopping distance tile builder", "app", "repair-service")
+			return ctx.Err()
+		case <-time.After(reload):
+		}
+	}
+}
+
+// loadDistanceTiles hands the stored tiles of the grid to the tile provider
+func (s *service) loadDistanceTiles(ctx context.Context, tiles *routing.TileProvider) error {
+	grid := tiles.Grid()
+	stored, err := s.repo.FindDistanceTiles(ctx, grid.SizeDegrees, grid.Radius)
+	if err != nil {
+		return err
+	}
+	loaded := tiles.Load(stored)
+	s.logger.Info("Loaded distance tiles", "stored", len(stored), "loaded", loaded, "app", "repair-service")
+	return nil
+}
+
+// buildDistanceTiles asks OSRM for the travel times of every cell holding a
+// mechanic or a recent repair whose tile is missing or older than the
+// interval. It stops after maxFailures consecutive OSRM failures, keeping the
+// tiles built so far and the stale ones it could not replace.
+func (s *service) buildDistanceTiles(ctx context.Context, grid routing.TileGrid) error {
+	ctx, span := s.tracer.Start(ctx, "ServiceBuildDistanceTiles")
+	defer span.End()
+
+	osrm, ok := s.router.Provider(routing.ProviderSelfHosted).(*routing.OSRMProvider)
+	if !ok {
+		osrm, ok = s.router.Provider(routing.ProviderPublic).(*routing.OSRMProvider)
+	}
+	if !ok {
+		err := errors.New("no OSRM provider to build distance tiles with")
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	span.SetAttributes(attribute.String("provider", osrm.Name()))
+
+	cells, err := s.distanceTileCells(ctx, grid)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find cells")
+		return err
+	}
+	stored, err := s.repo.FindDistanceTiles(ctx, grid.SizeDegrees, grid.Radius)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find distance tiles")
+		return err
+	}
+	builtAt := make(map[[2]int]time.Time, len(stored))
+	for _, tile := range stored {
+		builtAt[[2]int{tile.Row, tile.Col}] = tile.BuiltAt
+	}
+
+	start := time.Now()
+	built, failures := 0, 0
+	for _, cell := range cells {
+		if time.Since(builtAt[cell]) < s.tiles.interval {
+			continue
+		}
+		if built > 0 || failures > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(s.tiles.delay):
+			}
+		}
+
+		center := grid.Center(cell[0], cell[1])
+		durations, err := osrm.Table(ctx, center, grid.Neighbors(cell[0], cell[1]))
+		if err != nil {
+			failures++
+			s.logger.Warn("Failed to build distance tile", "row", cell[0], "col", cell[1], "provider", osrm.Name(), "error", err, "app", "repair-service")
+			if failures >= s.tiles.maxFailures {
+				err = fmt.Errorf("OSRM failed %d times in a row, built %d tiles: %w", failures, built, err)
+				span.RecordError(err)
+				span.SetStatus(codes.Error, "OSRM unreachable")
+				return err
+			}
+			continue
+		}
+		failures = 0
+		tile := &domain.DistanceTile{
+			ID:          fmt.Sprintf("%g/%d/%d", grid.SizeDegrees, cell[0], cell[1]),
+			Region:      region.Default().Of(center.Longitude, center.Latitude),
+			Row:         cell[0],
+			Col:         cell[1],
+			SizeDegrees: grid.SizeDegrees,
+			Radius:      grid.Radius,
+			Durations:   durations,
+			Provider:    osrm.Name(),
+			BuiltAt:     time.Now(),
+		}
+		if err := s.repo.SaveDistanceTile(ctx, tile); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to save distance tile")
+			return err
+		}
+		built++
+	}
+	span.SetAttributes(attribute.Int("cellCount", len(cells)), attribute.Int("tileCount", built))
+	s.logger.Info("Built distance tiles", "cells", len(cells), "built", built, "provider", osrm.Name(), "duration", time.Since(start), "app", "repair-service")
+	return nil
+}
+
+// distanceTileCells returns the cells of the configured regions holding
+// mechanics or repairs created within the lookback, busiest first, at most
+// maxCells
+func (s *service) distanceTileCells(ctx context.Context, grid routing.TileGrid) ([][2]int, error) {
+	counts := map[[2]int]int{}
+	mechanics, err := s.repo.GetAllMechanics(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range mechanics {
+		row, col := grid.Cell(m.Location)
+		counts[[2]int{row, col}]++
+	}
+	repairs, err := s.repo.FindRepairLocations(ctx, domain.RepairFilter{Since: time.Now().Add(-s.tiles.lookback)})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range repairs {
+		if r.RepairCost == nil || r.RepairCost.UserLocation == nil {
+			continue
+		}
+		row, col := grid.Cell(*r.RepairCost.UserLocation)
+		counts[[2]int{row, col}]++
+	}
+
+	cells := make([][2]int, 0, len(counts))
+	for cell := range counts {
+		center := grid.Center(cell[0], cell[1])
+		if len(s.tiles.regions) == 0 || s.tiles.regions[region.Default().Of(center.Longitude, center.Latitude)] {
+			cells = append(cells, cell)
+		}
+	}
+	sort.Slice(cells, func(i, j int) bool {
+		if counts[cells[i]] != counts[cells[j]] {
+			return counts[cells[i]] > counts[cells[j]]
+		}
+		if cells[i][0] != cells[j][0] {
+			return cells[i][0] < cells[j][0]
+		}
+		return cells[i][1] < cells[j][1]
+	})
+	if len(cells) > s.tiles.maxCells {
+		cells = cells[:s.tiles.maxCells]
+	}
+	return cells, nil
+}
+
+// DistanceTiles reports the distance tile grid, the tiles loaded by this
+// instance and the stored tiles per region
+func (s *service) DistanceTiles(ctx context.Context) (*domain.DistanceTileStatus, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceDistanceTiles")
+	defer span.End()
+
+	tiles := s.router.Tiles()
+	if tiles == nil {
+		return &domain.DistanceTileStatus{Regions: []*domain.DistanceTileSummary{}}, nil
+	}
+	grid := tiles.Grid()
+	summaries, err := s.repo.SummarizeDistanceTiles(ctx, grid.SizeDegrees, grid.Radius)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to summarize distance tiles")
+		s.logger.Error("Failed to summarize distance tiles", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	return &domain.DistanceTileStatus{SizeDegrees: grid.SizeDegrees, Radius: grid.Radius, Loaded: tiles.Count(), Regions: summaries}, nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"repair-service/domain"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// durationConfig sets how completed repairs are turned into duration estimates
+type durationConfig struct {
+	window     int           // latest samples averaged
+	minSamples int           // samples before an average replaces the catalog
+	maxSample  time.Duration // longer repairs were left open and are not timed
+}
+
+// durationSample returns the actual duration of repair, completed at
+// completedAt, or nil when it was never started or took implausibly long
+func (s *service) durationSample(repair *domain.RepairModel, completedAt time.Time) *domain.RepairDurationSample {
+	if repair.StartedAt == nil || repair.RepairCost == nil {
+		return nil
+	}
+	elapsed := completedAt.Sub(*repair.StartedAt)
+	if elapsed <= 0 || elapsed > s.durations.maxSample {
+		return nil
+	}
+	return &domain.RepairDurationSample{
+		RepairID:    repair.ID,
+		RepairType:  repair.RepairCost.RepairType,
+		MechanicID:  repair.AssignedTo,
+		StartedAt:   *repair.StartedAt,
+		CompletedAt: completedAt,
+		Minutes:     elapsed.Minutes(),
+	}
+}
+
+// learnDuration updates the rolling averages of sample's repair type and of
+// its mechanic's repairs of the type. A failure only delays the update to the
+// next completed repair.
+func (s *service) learnDuration(ctx context.Context, sample *domain.RepairDurationSample) {
+	ctx, span := s.tracer.Start(ctx, "ServiceLearnDuration")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairType", sample.RepairType), attribute.String("mechanicID", sample.MechanicID))
+
+	mechanicIDs := []string{""}
+	if sample.MechanicID != "" {
+		mechanicIDs = append(mechanicIDs, sample.MechanicID)
+	}
+	for _, mechanicID := range mechanicIDs {
+		minutes, err := s.repo.RecentDurations(ctx, sample.RepairType, mechanicID, s.durations.window)
+		if err == nil {
+			estimate := domain.NewDurationEstimate(sample.RepairType, mechanicID, minutes, s.durations.minSamples, time.Now())
+			if err = s.repo.SaveDurationEstimate(ctx, estimate); err == nil {
+				s.logger.Info("Updated duration estimate", "repairType", sample.RepairType, "mechanicID", mechanicID, "averageMinutes", estimate.AverageMinutes, "samples", estimate.Samples, "learned", estimate.Learned, "app", "repair-service")
+				continue
+			}
+		}
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to update duration estimate")
+		s.logger.Error("Failed to update duration estimate", "error", err, "repairType", sample.RepairType, "mechanicID", mechanicID, "app", "repair-service")
+	}
+}
+
+// estimateDurations sets the estimated minutes of cost and of each of its
+// mechanics: a mechanic's own learned duration, else the repair type's,
+// else the catalog's
+func (s *service) estimateDurations(ctx context.Context, cost *domain.RepairCostModel) {
+	estimates, err := s.repo.FindDurationEstimates(ctx, cost.RepairType)
+	if err != nil {
+		s.logger.Warn("Failed to load duration estimates, using the catalog", "error", err, "repairType", cost.RepairType, "app", "repair-service")
+	}
+	typeMinutes := domain.CatalogMinutes(cost.RepairType)
+	byMechanic := map[string]float64{}
+	for _, e := range estimates {
+		switch {
+		case e.MechanicID == "":
+			typeMinutes = e.Minutes()
+		case e.Learned:
+			byMechanic[e.MechanicID] = e.AverageMinutes
+		}
+	}
+	cost.EstimatedMinutes = typeMinutes
+	for i := range cost.Mechanics {
+		cost.Mechanics[i].EstimatedMinutes = typeMinutes
+		if minutes, ok := byMechanic[cost.Mechanics[i].ID]; ok {
+			cost.Mechanics[i].EstimatedMinutes = minutes
+		}
+	}
+}
+
+// DurationReport returns the learned durations of repairType, of every type
+// when it is empty, with the catalog's estimate of types not timed yet
+func (s *service) DurationReport(ctx context.Context, repairType string) (*domain.DurationReport, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceDurationReport")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairType", repairType))
+
+	estimates, err := s.repo.FindDurationEstimates(ctx, repairType)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find duration estimates")
+		s.logger.Error("Failed to find duration estimates", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	report := &domain.DurationReport{
+		Window:      s.durations.window,
+		MinSamples:  s.durations.minSamples,
+		RepairTypes: []*domain.DurationEstimate{},
+		Mechanics:   []*domain.DurationEstimate{},
+	}
+	timed := map[string]bool{}
+	for _, e := range estimates {
+		if e.MechanicID == "" {
+			report.RepairTypes = append(report.RepairTypes, e)
+			timed[e.RepairType] = true
+		} else {
+			report.Mechanics = append(report.Mechanics, e)
+		}
+	}
+	for t := range domain.DefaultDurations {
+		if !timed[t] && (repairType == "" || repairType == t) {
+			report.RepairTypes = append(report.RepairTypes, domain.NewDurationEstimate(t, "", nil, s.durations.minSamples, time.Time{}))
+		}
+	}
+	sort.Slice(report.RepairTypes, func(i, j int) bool { return report.RepairTypes[i].RepairType < report.RepairTypes[j].RepairType })
+	span.SetAttributes(attribute.Int("repairTypeCount", len(report.RepairTypes)), attribute.Int("mechanicCount", len(report.Mechanics)))
+	return report, nil
+}
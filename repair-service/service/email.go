@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"repair-service/domain"
+	"repair-service/email"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// emailConfig is how queued transactional emails are sent
+type emailConfig struct {
+	provider     email.Provider
+	pollInterval time.Duration // how often due emails are looked up
+	maxAttempts  int           // sends tried before an email is marked failed
+	retryBackoff time.Duration // delay before the second attempt, doubled per attempt
+	batchSize    int           // emails sent per poll
+}
+
+// maskEmail hides the local part of an address but its first letter for logs
+func maskEmail(address string) string {
+	at := strings.LastIndex(address, "@")
+	if at <= 1 {
+		return address
+	}
+	return address[:1] + strings.Repeat("*", at-1) + address[at:]
+}
+
+// emailTemplate returns the template admins saved, or its default
+func (s *service) emailTemplate(ctx context.Context, name string) (*domain.EmailTemplate, error) {
+	tmpl, err := s.repo.GetEmailTemplate(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if tmpl == nil {
+		def, ok := domain.DefaultEmailTemplates[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown email template %q", domain.ErrInvalidInput, name)
+		}
+		tmpl = &def
+	}
+	tmpl.Variables = domain.EmailVariables[name]
+	return tmpl, nil
+}
+
+// repairEmailVariables are the variables every repair email can use
+func repairEmailVariables(repair *domain.RepairModel) map[string]string {
+	vars := map[string]string{
+		"UserID":   repair.UserID,
+		"RepairID": repair.ID,
+	}
+	if repair.RepairCost != nil {
+		vars["RepairType"] = repair.RepairCost.RepairType
+		vars["TotalPrice"] = repair.RepairCost.TotalPrice.String()
+	}
+	return vars
+}
+
+// receiptEmailVariables adds a receipt's variables to a repair's
+func receiptEmailVariables(repair *domain.RepairModel, receipt *domain.Receipt) map[string]string {
+	vars := repairEmailVariables(repair)
+	vars["ReceiptNumber"] = receipt.Number
+	vars["Subtotal"] = receipt.Subtotal.String()
+	vars["Total"] = receipt.Total.String()
+	vars["Currency"] = receipt.Currency
+	vars["MechanicName"] = ""
+	if receipt.Mechanic != nil {
+		vars["MechanicName"] = receipt.Mechanic.Name
+	}
+	vars["CompletedAt"] = ""
+	if receipt.CompletedAt != nil {
+		vars["CompletedAt"] = receipt.CompletedAt.UTC().Format("2006-01-02 15:04 MST")
+	}
+	return vars
+}
+
+// prepareEmail renders a repair email for its user, to be queued in the
+// transaction of the change that causes it. Users who opted out or have no
+// address get a delivery recording why nothing was sent. Email is best effort:
+// when the template or preferences cannot be read the change goes ahead
+// without it, so nil is returned.
+func (s *service) prepareEmail(ctx context.Context, templateName string, repair *domain.RepairModel, vars map[string]string) *domain.EmailDelivery {
+	ctx, span := s.tracer.Start(ctx, "ServicePrepareEmail")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("template", templateName),
+		attribute.String("repairID", repair.ID),
+	)
+
+	delivery := &domain.EmailDelivery{
+		ID:        primitive.NewObjectID().Hex(),
+		Template:  templateName,
+		UserID:    repair.UserID,
+		RepairID:  repair.ID,
+		Status:    domain.EmailPending,
+		CreatedAt: time.Now(),
+	}
+	prefs, err := s.repo.GetEmailPreferences(ctx, repair.UserID)
+	if err != nil {
+		span.RecordError(err)
+		s.logger.Warn("Failed to get email preferences, not sending email", "error", err, "template", templateName, "repairID", repair.ID, "app", "repair-service")
+		return nil
+	}
+	switch {
+	case prefs == nil || prefs.Email == "":
+		delivery.Status = domain.EmailNoAddress
+		return delivery
+	case prefs.OptOut:
+		delivery.Status = domain.EmailOptedOut
+		return delivery
+	}
+
+	tmpl, err := s.emailTemplate(ctx, templateName)
+	if err != nil {
+		span.RecordError(err)
+		s.logger.Warn("Failed to get email template, not sending email", "error", err, "template", templateName, "repairID", repair.ID, "app", "repair-service")
+		return nil
+	}
+	rendered, err := tmpl.Render(vars)
+	if err != nil {
+		span.RecordError(err)
+		s.logger.Warn("Failed to render email, not sending email", "error", err, "template", templateName, "repairID", repair.ID, "app", "repair-service")
+		return nil
+	}
+	delivery.To = prefs.Email
+	delivery.Subject = rendered.Subject
+	delivery.Text = rendered.Text
+	delivery.HTML = rendered.HTML
+	return delivery
+}
+
+// runEmailSender sends queued emails until ctx is done; it runs under the
+// supervisor
+func (s *service) runEmailSender(ctx context.Context) error {
+	s.logger.Info("Email sender started", "provider", s.email.provider.Name(), "pollInterval", s.email.pollInterval, "maxAttempts", s.email.maxAttempts, "app", "repair-service")
+	ticker := time.NewTicker(s.email.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Stopping email sender", "app", "repair-service")
+			return ctx.Err()
+		case <-ticker.C:
+			if err := s.sendDueEmails(ctx); err != nil {
+				s.logger.Error("Failed to send emails", "error", err, "app", "repair-service")
+			}
+		}
+	}
+}
+
+// sendDueEmails sends up to batchSize due emails. Each is claimed first, so
+// instances polling together never send one twice. A failed send is retried
+// with exponential backoff until maxAttempts, then marked failed.
+func (s *service) sendDueEmails(ctx context.Context) error {
+	ctx, span := s.tracer.Start(ctx, "ServiceSendDueEmails")
+	defer span.End()
+
+	sent := 0
+	defer func() { span.SetAttributes(attribute.Int("emailCount", sent)) }()
+	for ; sent < s.email.batchSize; sent++ {
+		delivery, err := s.repo.ClaimEmailDelivery(ctx, time.Now(), emailSendTimeout+time.Minute)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to claim email")
+			return err
+		}
+		if delivery == nil {
+			return nil
+		}
+		if err := s.sendEmail(ctx, delivery); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to update email delivery")
+			return err
+		}
+	}
+	return nil
+}
+
+// emailSendTimeout bounds one attempt to send an email
+const emailSendTimeout = 30 * time.Second
+
+// sendEmail makes one attempt to send a claimed email and stores the outcome
+func (s *service) sendEmail(ctx context.Context, delivery *domain.EmailDelivery) error {
+	sendCtx, cancel := context.WithTimeout(ctx, emailSendTimeout)
+	defer cancel()
+
+	delivery.Attempts++
+	delivery.Provider = s.email.provider.Name()
+	err := s.email.provider.Send(sendCtx, &email.Message{To: delivery.To, Subject: delivery.Subject, Text: delivery.Text, HTML: delivery.HTML})
+	now := time.Now()
+	switch {
+	case err == nil:
+		delivery.Status = domain.EmailSent
+		delivery.SentAt = &now
+		delivery.NextAttemptAt = nil
+		delivery.LastError = ""
+		s.logger.Info("Sent email", "deliveryID", delivery.ID, "template", delivery.Template, "to", maskEmail(delivery.To), "provider", delivery.Provider, "app", "repair-service")
+	case delivery.Attempts >= s.email.maxAttempts:
+		delivery.Status = domain.EmailFailed
+		delivery.NextAttemptAt = nil
+		delivery.LastError = err.Error()
+		s.logger.Error("Giving up on email", "deliveryID", delivery.ID, "template", delivery.Template, "attempts", delivery.Attempts, "error", err, "app", "repair-service")
+	default:
+		next := now.Add(s.email.retryBackoff << (delivery.Attempts - 1))
+		delivery.NextAttemptAt = &next
+		delivery.LastError = err.Error()
+		s.logger.Warn("Failed to send email, retrying", "deliveryID", delivery.ID, "template", delivery.Template, "attempts", delivery.Attempts, "nextAttemptAt", next, "error", err, "app", "repair-service")
+	}
+	return s.repo.UpdateEmailDelivery(ctx, delivery)
+}
+
+// ListEmailTemplates returns every transactional email template, the saved
+// version or else the default
+func (s *service) ListEmailTemplates(ctx context.Context) ([]*domain.EmailTemplate, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceListEmailTemplates")
+	defer span.End()
+
+	saved, err := s.repo.FindEmailTemplates(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to list email templates")
+		s.logger.Error("Failed to list email templates", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	byName := make(map[string]*domain.EmailTemplate, len(saved))
+	for _, tmpl := range saved {
+		byName[tmpl.Name] = tmpl
+	}
+	templates := make([]*domain.EmailTemplate, 0, len(domain.EmailVariables))
+	for name := range domain.EmailVariables {
+		tmpl, ok := byName[name]
+		if !ok {
+			def := domain.DefaultEmailTemplates[name]
+			tmpl = &def
+		}
+		tmpl.Variables = domain.EmailVariables[name]
+		templates = append(templates, tmpl)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+	return templates, nil
+}
+
+// SaveEmailTemplate replaces a transactional email template; it applies to
+// emails queued from then on
+func (s *service) SaveEmailTemplate(ctx context.Context, tmpl *domain.EmailTemplate) (*domain.EmailTemplate, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceSaveEmailTemplate")
+	defer span.End()
+	span.SetAttributes(attribute.String("template", tmpl.Name))
+
+	if err := tmpl.Validate(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid email template", "error", err, "template", tmpl.Name, "app", "repair-service")
+		return nil, err
+	}
+	tmpl.UpdatedAt = time.Now()
+	if err := s.repo.SaveEmailTemplate(ctx, tmpl); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save email template")
+		s.logger.Error("Failed to save email template", "error", err, "template", tmpl.Name, "app", "repair-service")
+		return nil, err
+	}
+	tmpl.Variables = domain.EmailVariables[tmpl.Name]
+	s.logger.Info("Saved email template", "template", tmpl.Name, "app", "repair-service")
+	return tmpl, nil
+}
+
+// GetEmailPreferences returns a user's email preferences; users who never set
+// an address get empty ones
+func (s *service) GetEmailPreferences(ctx context.Context, userID string) (*domain.EmailPreferences, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceGetEmailPreferences")
+	defer span.End()
+	span.SetAttributes(attribute.String("userID", userID))
+
+	prefs, err := s.repo.GetEmailPreferences(ctx, userID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get email preferences")
+		s.logger.Error("Failed to get email preferences", "error", err, "userID", userID, "app", "repair-service")
+		return nil, err
+	}
+	if prefs == nil {
+		prefs = &domain.EmailPreferences{UserID: userID}
+	}
+	return prefs, nil
+}
+
+// SetEmailPreferences sets a user's email address and opt-out
+func (s *service) SetEmailPreferences(ctx context.Context, prefs *domain.EmailPreferences) (*domain.EmailPreferences, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceSetEmailPreferences")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("userID", prefs.UserID),
+		attribute.Bool("optOut", prefs.OptOut),
+	)
+
+	prefs.Email = strings.TrimSpace(prefs.Email)
+	if prefs.UserID == "" {
+		err := fmt.Errorf("%w: userID is required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if err := prefs.Validate(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid email preferences", "error", err, "userID", prefs.UserID, "app", "repair-service")
+		return nil, err
+	}
+	prefs.UpdatedAt = time.Now()
+	if err := s.repo.SaveEmailPreferences(ctx, prefs); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save email preferences")
+		s.logger.Error("Failed to save email preferences", "error", err, "userID", prefs.UserID, "app", "repair-service")
+		return nil, err
+	}
+	s.logger.Info("Set email preferences", "userID", prefs.UserID, "email", maskEmail(prefs.Email), "optOut", prefs.OptOut, "app", "repair-service")
+	return prefs, nil
+}
+
+// ListEmailDeliveries lists transactional emails and their delivery status
+func (s *service) ListEmailDeliveries(ctx context.Context, filter domain.EmailDeliveryFilter, opts *domain.QueryOptions) ([]*domain.EmailDelivery, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceListEmailDeliveries")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("userID", filter.UserID),
+		attribute.String("repairID", filter.RepairID),
+		attribute.String("status", filter.Status),
+	)
+
+	deliveries, err := s.repo.FindEmailDeliveries(ctx, filter, opts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to list email deliveries")
+		s.logger.Error("Failed to list email deliveries", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	return deliveries, nil
+}
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"repair-service/domain"
+	"repair-service/routing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"golang.org/x/sync/singleflight"
+)
+
+// candidateMechanics are the mechanics who can take a repair near a location,
+// with the travel duration from that location to each of them
+type candidateMechanics struct {
+	mechanics []*domain.MechanicModel
+	durations []float64 // seconds, by mechanic index
+	provider  string    // routing provider that computed the durations
+}
+
+// estimateCoalescing shares the mechanic query and the routing call between
+// concurrent estimates for the same repair type in the same geohash cell
+type estimateCoalescing struct {
+	precision int // geohash length of the cells; 0 disables coalescing
+	group     singleflight.Group
+}
+
+// candidateMechanics returns the available mechanics and their travel
+// durations from userLocation. Concurrent calls with the same repair type
+// whose locations share a geohash cell wait for the first one and use its
+// result, durations included: within a cell of the configured precision the
+// difference is a few seconds of driving. The result is shared and must not
+// be modified.
+func (s *service) candidateMechanics(ctx context.Context, repairType string, userLocation *domain.Location) (*candidateMechanics, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceCandidateMechanics")
+	defer span.End()
+
+	if s.coalescing.precision <= 0 {
+		return s.findCandidateMechanics(ctx, repairType, userLocation)
+	}
+
+	key := encodeGeohash(userLocation.Latitude, userLocation.Longitude, s.coalescing.precision) + "|" + repairType
+	span.SetAttributes(attribute.String("coalescingKey", key))
+	// The first caller's cancellation must not fail the others waiting on it
+	result := s.coalescing.group.DoChan(key, func() (interface{}, error) {
+		return s.findCandidateMechanics(context.WithoutCancel(ctx), repairType, userLocation)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case r := <-result:
+		span.SetAttributes(attribute.Bool("coalesced", r.Shared))
+		if r.Err != nil {
+			span.RecordError(r.Err)
+			span.SetStatus(codes.Error, "Failed to find candidate mechanics")
+			return nil, r.Err
+		}
+		if r.Shared {
+			s.logger.Debug("Coalesced estimate with a concurrent one", "key", key, "app", "repair-service")
+		}
+		return r.Val.(*candidateMechanics), nil
+	}
+}
+
+// findCandidateMechanics queries the mechanics near userLocation who are not
+// on leave and routes from userLocation to each of them
+func (s *service) findCandidateMechanics(ctx context.Context, repairType string, userLocation *domain.Location) (*candidateMechanics, error) {
+	mechanics, err := s.nearbyMechanics(ctx, repairType, userLocation)
+	if err != nil {
+		s.logger.Error("Failed to get mechanics", "error", err, "app", "repair-service")
+		return nil, fmt.Errorf("failed to get mechanics: %v", err)
+	}
+
+	// Absent mechanics are on leave and cannot take the repair
+	absent, err := s.repo.AbsentMechanicIDs(ctx, time.Now())
+	if err != nil {
+		s.logger.Error("Failed to check absences", "error", err, "app", "repair-service")
+		return nil, fmt.Errorf("failed to check absences: %w", err)
+	}
+	available := make([]*domain.MechanicModel, 0, len(mechanics))
+	for _, mechanic := range mechanics {
+		if !absent[mechanic.ID] {
+			available = append(available, mechanic)
+		}
+	}
+
+	if len(available) == 0 {
+		return &candidateMechanics{}, nil
+	}
+
+	// Travel durations from the user to each mechanic
+	destinations := make([]domain.Location, len(available))
+	for i, mechanic := range available {
+		destinations[i] = mechanic.Location
+	}
+	route, err := s.router.Durations(ctx, routing.ClassEstimate, *userLocation, destinations)
+	if err != nil {
+		s.logger.Error("Failed to compute travel durations", "error", err, "app", "repair-service")
+		return nil, fmt.Errorf("failed to compute travel durations: %w", err)
+	}
+	return &candidateMechanics{mechanics: available, durations: route.Durations, provider: route.Provider}, nil
+}
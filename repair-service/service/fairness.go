@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"repair-service/domain"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// fairnessPolicy returns the policy set by admins, or the FAIRNESS_* defaults
+// when none was set
+func (s *service) fairnessPolicy(ctx context.Context) (*domain.FairnessPolicy, error) {
+	policy, err := s.repo.GetFairnessPolicy(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if policy == nil {
+		defaults := s.fairness
+		return &defaults, nil
+	}
+	return policy, nil
+}
+
+// rankMechanics applies the fairness policy to mechanics sorted by distance.
+// Ranking is best effort: when the policy or the counters cannot be read the
+// distance order is kept rather than failing the estimate.
+func (s *service) rankMechanics(ctx context.Context, mechanics []domain.MechanicInfo) []domain.MechanicInfo {
+	ctx, span := s.tracer.Start(ctx, "ServiceRankMechanics")
+	defer span.End()
+
+	policy, err := s.fairnessPolicy(ctx)
+	if err != nil {
+		span.RecordError(err)
+		s.logger.Warn("Failed to get fairness policy, ranking by distance", "error", err, "app", "repair-service")
+		return mechanics
+	}
+	span.SetAttributes(attribute.Bool("fairness.enabled", policy.Enabled))
+	if !policy.Enabled || len(mechanics) < 2 {
+		return mechanics
+	}
+
+	ids := make([]string, len(mechanics))
+	for i, m := range mechanics {
+		ids[i] = m.ID
+	}
+	since := time.Now().AddDate(0, 0, -(policy.WindowDays - 1))
+	counts, err := s.repo.AssignmentCounts(ctx, ids, since)
+	if err != nil {
+		span.RecordError(err)
+		s.logger.Warn("Failed to count assignments, ranking by distance", "error", err, "app", "repair-service")
+		return mechanics
+	}
+
+	ranked := domain.RankFairly(mechanics, counts, *policy, func(m domain.MechanicInfo) float64 {
+		return m.Distance / averageSpeedMetersPerMinute
+	})
+	span.SetAttributes(
+		attribute.Float64("fairness.etaWindowMinutes", policy.ETAWindowMinutes),
+		attribute.Float64("fairness.weight", policy.Weight),
+		attribute.String("firstMechanicID", ranked[0].ID),
+	)
+	if ranked[0].ID != mechanics[0].ID {
+		s.logger.Info("Fairness rotation moved a mechanic ahead of the nearest", "mechanicID", ranked[0].ID, "nearestMechanicID", mechanics[0].ID, "app", "repair-service")
+	}
+	return ranked
+}
+
+// GetFairnessPolicy returns the fairness policy estimates currently use
+func (s *service) GetFairnessPolicy(ctx context.Context) (*domain.FairnessPolicy, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceGetFairnessPolicy")
+	defer span.End()
+
+	policy, err := s.fairnessPolicy(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get fairness policy")
+		s.logger.Error("Failed to get fairness policy", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	return policy, nil
+}
+
+// SetFairnessPolicy replaces the fairness policy; it applies to the next
+// estimate on every instance
+func (s *service) SetFairnessPolicy(ctx context.Context, policy *domain.FairnessPolicy) (*domain.FairnessPolicy, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceSetFairnessPolicy")
+	defer span.End()
+
+	if err := policy.Validate(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid fairness policy", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	policy.UpdatedAt = time.Now()
+	span.SetAttributes(
+		attribute.Bool("enabled", policy.Enabled),
+		attribute.Float64("etaWindowMinutes", policy.ETAWindowMinutes),
+		attribute.Float64("weight", policy.Weight),
+		attribute.Int("windowDays", policy.WindowDays),
+	)
+	if err := s.repo.SaveFairnessPolicy(ctx, policy); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to set fairness policy")
+		s.logger.Error("Failed to set fairness policy", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	s.logger.Info("Set fairness policy", "enabled", policy.Enabled, "etaWindowMinutes", policy.ETAWindowMinutes, "weight", policy.Weight, "windowDays", policy.WindowDays, "app", "repair-service")
+	return policy, nil
+}
+
+// AssignmentCounts returns every mechanic's assignments in the fairness
+// policy's window
+func (s *service) AssignmentCounts(ctx context.Context) (*domain.AssignmentCounts, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceAssignmentCounts")
+	defer span.End()
+
+	policy, err := s.fairnessPolicy(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get fairness policy")
+		s.logger.Error("Failed to get fairness policy", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	now := time.Now().UTC()
+	since := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -(policy.WindowDays - 1))
+	counts, err := s.repo.AssignmentCounts(ctx, nil, since)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to count assignments")
+		s.logger.Error("Failed to count assignments", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	return &domain.AssignmentCounts{WindowDays: policy.WindowDays, Since: since, Counts: counts}, nil
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"repair-service/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// CreateFleetAccount creates a fleet account without members (admin)
+func (s *service) CreateFleetAccount(ctx context.Context, req *domain.FleetAccountRequest) (*domain.FleetAccount, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceCreateFleetAccount")
+	defer span.End()
+
+	admins := make([]string, 0, len(req.Admins))
+	for _, id := range req.Admins {
+		if id = strings.TrimSpace(id); id != "" && !slices.Contains(admins, id) {
+			admins = append(admins, id)
+		}
+	}
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(admins) == 0 {
+		err := fmt.Errorf("%w: a fleet needs a name and at least one admin", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid fleet account", "error", err, "app", "repair-service")
+		return nil, err
+	}
+
+	now := time.Now()
+	fleet := &domain.FleetAccount{
+		ID:        primitive.NewObjectID().Hex(),
+		Name:      name,
+		Admins:    admins,
+		Members:   []domain.FleetMember{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	span.SetAttributes(attribute.String("fleetID", fleet.ID))
+	if err := s.repo.SaveFleetAccount(ctx, fleet); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save fleet account")
+		s.logger.Error("Failed to save fleet account", "error", err, "fleetID", fleet.ID, "app", "repair-service")
+		return nil, err
+	}
+	s.logger.Info("Created fleet account", "fleetID", fleet.ID, "name", fleet.Name, "admins", fleet.Admins, "app", "repair-service")
+	return fleet, nil
+}
+
+// GetFleetAccount returns a fleet account to its admins or a platform admin
+func (s *service) GetFleetAccount(ctx context.Context, actor domain.Actor, fleetID string) (*domain.FleetAccount, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceGetFleetAccount")
+	defer span.End()
+	span.SetAttributes(attribute.String("fleetID", fleetID), attribute.String("actorRole", actor.Role))
+
+	return s.managedFleet(ctx, actor, fleetID)
+}
+
+// SetFleetMember adds a user to a fleet or replaces their limit and allowed
+// repair types. Users belong to at most one fleet.
+func (s *service) SetFleetMember(ctx context.Context, actor domain.Actor, fleetID string, member domain.FleetMember) (*domain.FleetAccount, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceSetFleetMember")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("fleetID", fleetID),
+		attribute.String("userID", member.UserID),
+		attribute.String("actorRole", actor.Role),
+	)
+
+	member.UserID = strings.TrimSpace(member.UserID)
+	types := make([]string, 0, len(member.AllowedRepairTypes))
+	for _, t := range member.AllowedRepairTypes {
+		if t = strings.TrimSpace(t); t != "" && !slices.Contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	member.AllowedRepairTypes = types
+	if member.UserID == "" || (member.MonthlyLimit != nil && *member.MonthlyLimit < 0) {
+		err := fmt.Errorf("%w: a member needs a user ID and a monthly limit of at least 0", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid fleet member", "error", err, "app", "repair-service")
+		return nil, err
+	}
+
+	fleet, err := s.managedFleet(ctx, actor, fleetID)
+	if err != nil {
+		return nil, err
+	}
+	other, err := s.repo.FindFleetByMember(ctx, member.UserID)
+	switch {
+	case err == nil && other.ID != fleet.ID:
+		err := fmt.Errorf("%w: user %s already belongs to fleet %s", domain.ErrInvalidInput, member.UserID, other.ID)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("User belongs to another fleet", "error", err, "app", "repair-service")
+		return nil, err
+	case err != nil && !errors.Is(err, mongo.ErrNoDocuments):
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to check fleet membership")
+		s.logger.Error("Failed to check fleet membership", "error", err, "userID", member.UserID, "app", "repair-service")
+		return nil, err
+	}
+
+	if existing := fleet.Member(member.UserID); existing != nil {
+		*existing = member
+	} else {
+		fleet.Members = append(fleet.Members, member)
+	}
+	fleet.UpdatedAt = time.Now()
+	if err := s.repo.SaveFleetAccount(ctx, fleet); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save fleet account")
+		s.logger.Error("Failed to save fleet account", "error", err, "fleetID", fleet.ID, "app", "repair-service")
+		return nil, err
+	}
+	s.logger.Info("Set fleet member", "fleetID", fleet.ID, "userID", member.UserID, "hasLimit", member.MonthlyLimit != nil, "allowedRepairTypes", member.AllowedRepairTypes, "actorRole", actor.Role, "actorID", actor.ID, "app", "repair-service")
+	return fleet, nil
+}
+
+// RemoveFleetMember removes a user from a fleet, lifting its controls
+func (s *service) RemoveFleetMember(ctx context.Context, actor domain.Actor, fleetID, userID string) error {
+	ctx, span := s.tracer.Start(ctx, "ServiceRemoveFleetMember")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("fleetID", fleetID),
+		attribute.String("userID", userID),
+		attribute.String("actorRole", actor.Role),
+	)
+
+	fleet, err := s.managedFleet(ctx, actor, fleetID)
+	if err != nil {
+		return err
+	}
+	if fleet.Member(userID) == nil {
+		err := fmt.Errorf("user %s is not a member of fleet %s: %w", userID, fleetID, mongo.ErrNoDocuments)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	fleet.Members = slices.DeleteFunc(fleet.Members, func(m domain.FleetMember) bool { return m.UserID == userID })
+	fleet.UpdatedAt = time.Now()
+	if err := s.repo.SaveFleetAccount(ctx, fleet); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save fleet account")
+		s.logger.Error("Failed to save fleet account", "error", err, "fleetID", fleet.ID, "app", "repair-service")
+		return err
+	}
+	s.logger.Info("Removed fleet member", "fleetID", fleet.ID, "userID", userID, "actorRole", actor.Role, "actorID", actor.ID, "app", "repair-service")
+	return nil
+}
+
+// FleetUsage returns each member's spending in month (YYYY-MM, the current
+// month when empty) against their monthly limit
+func (s *service) FleetUsage(ctx context.Context, actor domain.Actor, fleetID, month string) (*domain.FleetUsage, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceFleetUsage")
+	defer span.End()
+	span.SetAttributes(attribute.String("fleetID", fleetID), attribute.String("month", month))
+
+	start := monthStart(time.Now().In(s.pricingLocation))
+	if month != "" {
+		t, err := time.ParseInLocation("2006-01", month, s.pricingLocation)
+		if err != nil {
+			err := fmt.Errorf("%w: month must be YYYY-MM", domain.ErrInvalidInput)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+		start = t
+	}
+	end := start.AddDate(0, 1, 0)
+
+	fleet, err := s.managedFleet(ctx, actor, fleetID)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]string, len(fleet.Members))
+	for i, m := range fleet.Members {
+		userIDs[i] = m.UserID
+	}
+	spending, err := s.repo.MemberSpending(ctx, userIDs, start, end)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to sum member spending")
+		s.logger.Error("Failed to sum member spending", "error", err, "fleetID", fleet.ID, "app", "repair-service")
+		return nil, err
+	}
+
+	usage := &domain.FleetUsage{FleetID: fleet.ID, Month: start.Format("2006-01"), Members: make([]domain.FleetMemberUsage, 0, len(fleet.Members))}
+	for _, m := range fleet.Members {
+		spent := spending[m.UserID]
+		member := domain.FleetMemberUsage{
+			UserID:             m.UserID,
+			Spent:              spent.Spent,
+			RepairCount:        spent.RepairCount,
+			MonthlyLimit:       m.MonthlyLimit,
+			AllowedRepairTypes: m.AllowedRepairTypes,
+		}
+		if m.MonthlyLimit != nil {
+			remaining := max(*m.MonthlyLimit-spent.Spent, 0)
+			member.Remaining = &remaining
+			if *m.MonthlyLimit > 0 {
+				member.PercentUsed = float64(spent.Spent) / float64(*m.MonthlyLimit) * 100
+			} else if spent.Spent > 0 {
+				member.PercentUsed = 100
+			}
+		}
+		usage.Total += spent.Spent
+		usage.Members = append(usage.Members, member)
+	}
+	span.SetAttributes(attribute.Int("memberCount", len(usage.Members)), attribute.Int64("totalMinor", usage.Total.Minor()))
+	return usage, nil
+}
+
+// managedFleet loads a fleet account actor may manage
+func (s *service) managedFleet(ctx context.Context, actor domain.Actor, fleetID string) (*domain.FleetAccount, error) {
+	fleet, err := s.repo.GetFleetAccount(ctx, fleetID)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			s.logger.Error("Failed to get fleet account", "error", err, "fleetID", fleetID, "app", "repair-service")
+		}
+		return nil, err
+	}
+	if !fleet.CanManage(actor) {
+		return nil, fmt.Errorf("%w: only the fleet's admins may manage fleet %s", domain.ErrForbidden, fleetID)
+	}
+	return fleet, nil
+}
+
+// checkFleetControls refuses repairs of fleet members that their fleet does
+// not allow or that would take them over their monthly limit. Users outside
+// every fleet pass.
+func (s *service) checkFleetControls(ctx context.Context, userID, repairType string, price domain.Money) error {
+	fleet, err := s.repo.FindFleetByMember(ctx, userID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check fleet controls: %w", err)
+	}
+	member := fleet.Member(userID)
+	if !member.Allows(repairType) {
+		return fmt.Errorf("%w: fleet %s allows %s only %s repairs", domain.ErrRepairTypeNotAllowed, fleet.Name, userID, strings.Join(member.AllowedRepairTypes, ", "))
+	}
+	if member.MonthlyLimit == nil {
+		return nil
+	}
+
+	start := monthStart(time.Now().In(s.pricingLocation))
+	spending, err := s.repo.MemberSpending(ctx, []string{userID}, start, start.AddDate(0, 1, 0))
+	if err != nil {
+		return fmt.Errorf("failed to check fleet controls: %w", err)
+	}
+	spent := spending[userID].Spent
+	if spent+price > *member.MonthlyLimit {
+		return fmt.Errorf("%w: %s spent %s of their %s limit for %s and this repair costs %s", domain.ErrSpendingLimit,
+			userID, spent.String(), member.MonthlyLimit.String(), start.Format("2006-01"), price.String())
+	}
+	return nil
+}
+
+// monthStart returns midnight of the first day of t's month, in t's location
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"repair-service/domain"
+	"repair-service/kafka"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// GetRepairAsOf rebuilds a repair's state at asOf from its history: the
+// status and price of the last repair event written by then, the mechanic
+// last assigned by then and the amendments approved by then. A repair with
+// no event by asOf did not exist yet, or its history was erased; that is
+// ErrNotFound.
+func (s *service) GetRepairAsOf(ctx context.Context, id string, asOf time.Time) (*domain.RepairSnapshot, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceGetRepairAsOf")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", id), attribute.String("asOf", asOf.Format(time.RFC3339)))
+
+	if asOf.After(time.Now()) {
+		err := fmt.Errorf("%w: asOf must not be in the future", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	history, err := s.repo.GetRepairHistory(ctx, id, asOf)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to read repair history")
+		s.logger.Error("Failed to read repair history", "error", err, "repairID", id, "app", "repair-service")
+		return nil, err
+	}
+
+	snapshot := &domain.RepairSnapshot{ID: id, AsOf: asOf.UTC()}
+	for _, event := range history.Events {
+		state, err := kafka.DecodeRepairEvent(event.Payload)
+		if err != nil {
+			s.logger.Warn("Skipped undecodable repair event", "error", err, "eventID", event.ID, "repairID", id, "app", "repair-service")
+			continue
+		}
+		snapshot.UserID = state.UserID
+		snapshot.Status = state.Status
+		snapshot.RepairType = state.RepairType
+		snapshot.QuotedPrice = domain.Money(state.TotalPriceMinor)
+		if state.TotalPriceMinor == 0 {
+			snapshot.QuotedPrice = domain.MoneyFromMajor(state.TotalPrice)
+		}
+		snapshot.ChangedAt = event.CreatedAt
+		snapshot.Events++
+	}
+	if snapshot.Events == 0 {
+		err := fmt.Errorf("%w: repair %s has no recorded state at %s", domain.ErrNotFound, id, asOf.UTC().Format(time.RFC3339))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	if n := len(history.Assignments); n > 0 {
+		last := history.Assignments[n-1]
+		snapshot.AssignedTo = last.MechanicID
+		snapshot.AssignedAt = &last.AssignedAt
+	}
+
+	var approved []*domain.Amendment
+	for _, a := range history.Amendments {
+		if a.Status == domain.AmendmentApproved && a.DecidedAt != nil && !a.DecidedAt.After(asOf) {
+			approved = append(approved, a)
+			if a.DecidedAt.After(snapshot.ChangedAt) {
+				snapshot.ChangedAt = *a.DecidedAt
+			}
+		}
+	}
+	snapshot.TotalPrice = domain.FinalAmount(snapshot.QuotedPrice, approved)
+
+	span.SetAttributes(attribute.String("status", snapshot.Status), attribute.Int("eventCount", snapshot.Events))
+	s.logger.Info("Rebuilt repair state", "repairID", id, "asOf", snapshot.AsOf, "status", snapshot.Status, "events", snapshot.Events, "app", "repair-service")
+	return snapshot, nil
+}
@@ -0,0 +1,269 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"repair-service/domain"
+	"repair-service/kafka"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// invoiceConfig sets when fleet invoices are issued
+type invoiceConfig struct {
+	interval time.Duration // between checks for fleets missing last month's invoice; 0 only issues on request
+}
+
+// runFleetInvoices issues last month's invoice of every fleet that has none
+// yet, checking every interval so invoices go out shortly after a month ends
+// and fleets missed while the service was down are caught up. It runs under
+// the supervisor on every instance; the invoice ID keeps one per fleet and
+// month.
+func (s *service) runFleetInvoices(ctx context.Context) error {
+	if s.invoices.interval == 0 {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	s.logger.Info("Fleet invoice job started", "interval", s.invoices.interval, "app", "repair-service")
+	for {
+		if err := s.issueDueFleetInvoices(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("Failed to issue fleet invoices", "error", err, "app", "repair-service")
+		}
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Stopping fleet invoice job", "app", "repair-service")
+			return ctx.Err()
+		case <-time.After(s.invoices.interval):
+		}
+	}
+}
+
+// issueDueFleetInvoices issues last month's invoice of the fleets without one
+func (s *service) issueDueFleetInvoices(ctx context.Context) error {
+	ctx, span := s.tracer.Start(ctx, "ServiceIssueDueFleetInvoices")
+	defer span.End()
+
+	start := monthStart(time.Now().In(s.pricingLocation)).AddDate(0, -1, 0)
+	month := start.Format("2006-01")
+	span.SetAttributes(attribute.String("month", month))
+
+	fleets, err := s.repo.FindFleetAccounts(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find fleet accounts")
+		return err
+	}
+	issued, failed := 0, 0
+	for _, fleet := range fleets {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// Fleets created after the month ended had no members in it
+		if !fleet.CreatedAt.Before(start.AddDate(0, 1, 0)) {
+			continue
+		}
+		_, err := s.repo.GetFleetInvoice(ctx, domain.FleetInvoiceID(fleet.ID, month))
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			_, err = s.issueFleetInvoice(ctx, fleet, start)
+		}
+		if err != nil {
+			failed++
+			s.logger.Error("Failed to issue fleet invoice", "error", err, "fleetID", fleet.ID, "month", month, "app", "repair-service")
+			continue
+		}
+		issued++
+	}
+	span.SetAttributes(attribute.Int("fleetCount", len(fleets)), attribute.Int("issued", issued), attribute.Int("failed", failed))
+	if issued > 0 || failed > 0 {
+		s.logger.Info("Issued fleet invoices", "month", month, "issued", issued, "failed", failed, "app", "repair-service")
+	}
+	if failed > 0 {
+		err := fmt.Errorf("%d of %d fleet invoices for %s failed", failed, issued+failed, month)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
+
+// issueFleetInvoice bills the receipts of the repairs the fleet's members
+// completed in the month starting at start, one line per repair, and saves
+// the invoice with its FleetInvoiceIssued event in one transaction. An
+// invoice issued already is returned as it is.
+func (s *service) issueFleetInvoice(ctx context.Context, fleet *domain.FleetAccount, start time.Time) (*domain.FleetInvoice, error) {
+	month := start.Format("2006-01")
+	end := start.AddDate(0, 1, 0)
+	userIDs := make([]string, len(fleet.Members))
+	for i, m := range fleet.Members {
+		userIDs[i] = m.UserID
+	}
+	receipts, err := s.repo.FindCompletedReceipts(ctx, userIDs, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := &domain.FleetInvoice{
+		ID:          domain.FleetInvoiceID(fleet.ID, month),
+		Number:      "INV-" + fleet.ID + "-" + start.Format("200601"),
+		FleetID:     fleet.ID,
+		FleetName:   fleet.Name,
+		Month:       month,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		Currency:    s.receipts.currency,
+		LineItems:   make([]domain.FleetInvoiceLine, 0, len(receipts)),
+		Taxes:       []domain.ReceiptTax{},
+		IssuedAt:    time.Now(),
+	}
+	for _, receipt := range receipts {
+		if receipt.Currency != invoice.Currency {
+			s.logger.Warn("Invoicing receipt issued in another currency", "repairID", receipt.RepairID, "receiptCurrency", receipt.Currency, "invoiceCurrency", invoice.Currency, "app", "repair-service")
+		}
+		line := domain.FleetInvoiceLine{
+			RepairID:      receipt.RepairID,
+			ReceiptNumber: receipt.Number,
+			UserID:        receipt.UserID,
+			RepairType:    receipt.RepairType,
+			CompletedAt:   *receipt.CompletedAt,
+			Subtotal:      receipt.Subtotal,
+			Total:         receipt.Total,
+		}
+		for _, tax := range receipt.Taxes {
+			line.Tax += tax.Amount
+			invoice.AddTax(tax)
+		}
+		invoice.LineItems = append(invoice.LineItems, line)
+		invoice.Subtotal += receipt.Subtotal
+		invoice.Total += receipt.Total
+	}
+	invoice.RepairCount = len(invoice.LineItems)
+
+	payload, err := json.Marshal(invoice)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode invoice event: %w", err)
+	}
+	err = s.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		if err := s.repo.SaveFleetInvoice(tx, invoice); err != nil {
+			return err
+		}
+		return s.repo.SaveOutboxEvent(tx, &domain.OutboxEvent{
+			ID:          primitive.NewObjectID().Hex(),
+			EventType:   domain.EventFleetInvoiceIssued,
+			AggregateID: invoice.ID,
+			Payload:     payload,
+			CreatedAt:   time.Now(),
+			Processed:   false,
+			Topic:       kafka.BillingEventsTopic,
+		})
+	})
+	if errors.Is(err, domain.ErrInvoiceIssued) {
+		return s.repo.GetFleetInvoice(ctx, invoice.ID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("Issued fleet invoice", "invoiceID", invoice.ID, "fleetID", fleet.ID, "month", month, "repairCount", invoice.RepairCount, "total", invoice.Total.String(), "app", "repair-service")
+	return invoice, nil
+}
+
+// ListFleetInvoices returns a fleet's invoices to its admins or a platform
+// admin, latest month first
+func (s *service) ListFleetInvoices(ctx context.Context, actor domain.Actor, fleetID string, opts *domain.QueryOptions) ([]*domain.FleetInvoice, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceListFleetInvoices")
+	defer span.End()
+	span.SetAttributes(attribute.String("fleetID", fleetID), attribute.String("actorRole", actor.Role))
+
+	if _, err := s.managedFleet(ctx, actor, fleetID); err != nil {
+		return nil, err
+	}
+	invoices, err := s.repo.FindFleetInvoices(ctx, fleetID, opts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find fleet invoices")
+		s.logger.Error("Failed to find fleet invoices", "error", err, "fleetID", fleetID, "app", "repair-service")
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("invoiceCount", len(invoices)))
+	return invoices, nil
+}
+
+// GetFleetInvoice returns a fleet's invoice for month (YYYY-MM) to its admins
+// or a platform admin
+func (s *service) GetFleetInvoice(ctx context.Context, actor domain.Actor, fleetID, month string) (*domain.FleetInvoice, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceGetFleetInvoice")
+	defer span.End()
+	span.SetAttributes(attribute.String("fleetID", fleetID), attribute.String("month", month), attribute.String("actorRole", actor.Role))
+
+	if _, err := s.invoiceMonth(month); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if _, err := s.managedFleet(ctx, actor, fleetID); err != nil {
+		return nil, err
+	}
+	invoice, err := s.repo.GetFleetInvoice(ctx, domain.FleetInvoiceID(fleetID, month))
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to get fleet invoice")
+			s.logger.Error("Failed to get fleet invoice", "error", err, "fleetID", fleetID, "month", month, "app", "repair-service")
+		}
+		return nil, err
+	}
+	return invoice, nil
+}
+
+// IssueFleetInvoice issues a fleet's invoice for an ended month (YYYY-MM) now,
+// e.g. for months before the invoice job ran; an invoice issued already is
+// returned unchanged (admin)
+func (s *service) IssueFleetInvoice(ctx context.Context, fleetID, month string) (*domain.FleetInvoice, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceIssueFleetInvoice")
+	defer span.End()
+	span.SetAttributes(attribute.String("fleetID", fleetID), attribute.String("month", month))
+
+	start, err := s.invoiceMonth(month)
+	if err == nil && start.AddDate(0, 1, 0).After(time.Now()) {
+		err = fmt.Errorf("%w: %s ends %s", domain.ErrInvoiceMonthOpen, month, start.AddDate(0, 1, 0).Format(time.RFC3339))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	fleet, err := s.repo.GetFleetAccount(ctx, fleetID)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			s.logger.Error("Failed to get fleet account", "error", err, "fleetID", fleetID, "app", "repair-service")
+		}
+		return nil, err
+	}
+	invoice, err := s.issueFleetInvoice(ctx, fleet, start)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to issue fleet invoice")
+		s.logger.Error("Failed to issue fleet invoice", "error", err, "fleetID", fleetID, "month", month, "app", "repair-service")
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("invoiceID", invoice.ID), attribute.Int("repairCount", invoice.RepairCount))
+	return invoice, nil
+}
+
+// invoiceMonth parses a YYYY-MM month in the pricing time zone
+func (s *service) invoiceMonth(month string) (time.Time, error) {
+	start, err := time.ParseInLocation("2006-01", month, s.pricingLocation)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: month must be YYYY-MM", domain.ErrInvalidInput)
+	}
+	return start, nil
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"repair-service/domain"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// normalizeTag trims and lowercases a tag so "Fleet " and "fleet" are the same tag
+func normalizeTag(tag string) (string, error) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if tag == "" || len(tag) > domain.MaxTagLength {
+		return "", fmt.Errorf("%w: tags must be 1 to %d characters", domain.ErrInvalidInput, domain.MaxTagLength)
+	}
+	return tag, nil
+}
+
+// AddTag tags a repair and returns its tags
+func (s *service) AddTag(ctx context.Context, repairID, tag string) ([]string, error) {
+	return s.updateTags(ctx, "ServiceAddTag", repairID, tag, s.repo.AddRepairTag)
+}
+
+// RemoveTag removes a tag from a repair and returns its remaining tags
+func (s *service) RemoveTag(ctx context.Context, repairID, tag string) ([]string, error) {
+	return s.updateTags(ctx, "ServiceRemoveTag", repairID, tag, s.repo.RemoveRepairTag)
+}
+
+func (s *service) updateTags(ctx context.Context, spanName, repairID, tag string, update func(ctx context.Context, repairID, tag string) ([]string, error)) ([]string, error) {
+	ctx, span := s.tracer.Start(ctx, spanName)
+	defer span.End()
+
+	tag, err := normalizeTag(tag)
+	if err == nil && repairID == "" {
+		err = fmt.Errorf("%w: repair ID is required", domain.ErrInvalidInput)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid input for repair tag", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	span.SetAttributes(
+		attribute.String("repairID", repairID),
+		attribute.String("tag", tag),
+	)
+
+	tags, err := update(ctx, repairID, tag)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to update tags")
+		s.logger.Error("Failed to update repair tags", "error", err, "repairID", repairID, "tag", tag, "app", "repair-service")
+		return nil, err
+	}
+	s.logger.Info("Updated repair tags", "repairID", repairID, "tags", tags, "app", "repair-service")
+	return tags, nil
+}
+
+// ListTags returns a repair's tags
+func (s *service) ListTags(ctx context.Context, repairID string) ([]string, error) {
+	repair, err := s.GetRepairByID(ctx, repairID)
+	if err != nil {
+		return nil, err
+	}
+	if repair.Tags == nil {
+		return []string{}, nil
+	}
+	return repair.Tags, nil
+}
+
+// AddNote attaches an internal staff note to a repair
+func (s *service) AddNote(ctx context.Context, repairID, author, body string) (*domain.RepairNote, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceAddNote")
+	defer span.End()
+
+	body = strings.TrimSpace(body)
+	if repairID == "" || author == "" || body == "" {
+		err := fmt.Errorf("%w: repair ID, author and body are required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid input for repair note", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	span.SetAttributes(
+		attribute.String("repairID", repairID),
+		attribute.String("author", author),
+	)
+
+	// Notes are stored apart from the repair, so check it exists
+	if _, err := s.repo.GetRepairByID(ctx, repairID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get repair")
+		s.logger.Error("Failed to get repair for note", "error", err, "repairID", repairID, "app", "repair-service")
+		return nil, err
+	}
+
+	note := &domain.RepairNote{RepairID: repairID, Author: author, Body: body, CreatedAt: time.Now()}
+	if err := s.repo.SaveRepairNote(ctx, note); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save note")
+		s.logger.Error("Failed to save repair note", "error", err, "repairID", repairID, "app", "repair-service")
+		return nil, err
+	}
+	s.logger.Info("Added repair note", "repairID", repairID, "noteID", note.ID, "author", author, "app", "repair-service")
+	return note, nil
+}
+
+// DeleteNote removes an internal note from a repair
+func (s *service) DeleteNote(ctx context.Context, repairID, noteID string) error {
+	ctx, span := s.tracer.Start(ctx, "ServiceDeleteNote")
+	defer span.End()
+
+	if repairID == "" || noteID == "" {
+		err := fmt.Errorf("%w: repair ID and note ID are required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	span.SetAttributes(
+		attribute.String("repairID", repairID),
+		attribute.String("noteID", noteID),
+	)
+
+	if err := s.repo.DeleteRepairNote(ctx, repairID, noteID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to delete note")
+		s.logger.Error("Failed to delete repair note", "error", err, "repairID", repairID, "noteID", noteID, "app", "repair-service")
+		return err
+	}
+	s.logger.Info("Deleted repair note", "repairID", repairID, "noteID", noteID, "app", "repair-service")
+	return nil
+}
+
+// ListNotes returns a repair's internal notes, oldest first
+func (s *service) ListNotes(ctx context.Context, repairID string) ([]*domain.RepairNote, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceListNotes")
+	defer span.End()
+	span.SetAttributes(attribute.String("repairID", repairID))
+
+	notes, err := s.repo.FindRepairNotes(ctx, repairID, nil)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to list notes")
+		s.logger.Error("Failed to list repair notes", "error", err, "repairID", repairID, "app", "repair-service")
+		return nil, err
+	}
+	return notes, nil
+}
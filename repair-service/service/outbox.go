@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"repair-service/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// RedriveOutbox publishes processed outbox events again, for recovery after a
+// downstream consumer bug. The events are reset or cloned in one transaction
+// with the audit record of who redrove which events.
+func (s *service) RedriveOutbox(ctx context.Context, req *domain.OutboxRedriveRequest) (*domain.OutboxRedrive, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceRedriveOutbox")
+	defer span.End()
+
+	if err := req.Validate(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	redrive := &domain.OutboxRedrive{
+		ID:          primitive.NewObjectID().Hex(),
+		Mode:        req.Mode,
+		RequestedBy: req.RequestedBy,
+		From:        req.From,
+		To:          req.To,
+		EventType:   req.EventType,
+		RequestedAt: time.Now(),
+	}
+	span.SetAttributes(
+		attribute.String("redriveID", redrive.ID),
+		attribute.String("mode", redrive.Mode),
+		attribute.String("requestedBy", redrive.RequestedBy),
+	)
+
+	err := s.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		eventIDs, cloneIDs, err := s.repo.RedriveOutboxEvents(tx, req)
+		if err != nil {
+			return err
+		}
+		redrive.EventIDs, redrive.CloneIDs = eventIDs, cloneIDs
+		return s.repo.SaveOutboxRedrive(tx, redrive)
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to redrive outbox events")
+		s.logger.Error("Failed to redrive outbox events", "error", err, "redriveID", redrive.ID, "requestedBy", redrive.RequestedBy, "app", "repair-service")
+		return nil, fmt.Errorf("failed to redrive outbox events: %w", err)
+	}
+
+	span.SetAttributes(attribute.Int("eventCount", len(redrive.EventIDs)))
+	s.logger.Warn("Redrove outbox events", "redriveID", redrive.ID, "mode", redrive.Mode, "requestedBy", redrive.RequestedBy,
+		"eventCount", len(redrive.EventIDs), "eventIDs", redrive.EventIDs, "cloneIDs", redrive.CloneIDs,
+		"from", redrive.From, "to", redrive.To, "eventType", redrive.EventType, "app", "repair-service")
+	return redrive, nil
+}
+
+// ListOutboxRedrives returns the audit records of past redrives, newest first
+func (s *service) ListOutboxRedrives(ctx context.Context, opts *domain.QueryOptions) ([]*domain.OutboxRedrive, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceListOutboxRedrives")
+	defer span.End()
+
+	redrives, err := s.repo.FindOutboxRedrives(ctx, opts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to list outbox redrives")
+		s.logger.Error("Failed to list outbox redrives", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	return redrives, nil
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"repair-service/domain"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// PatchRepair applies a merge patch of a repair's mutable fields after
+// checking the actor may change each of them. A status change goes through
+// the same transition as UpdateRepair, with the other fields in its
+// transaction.
+func (s *service) PatchRepair(ctx context.Context, repairID string, actor domain.Actor, patch *domain.RepairPatch) (*domain.RepairModel, error) {
+	ctx, span := s.tracer.Start(ctx, "ServicePatchRepair")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("repairID", repairID),
+		attribute.String("actorRole", actor.Role),
+		attribute.StringSlice("fields", patch.Fields()),
+	)
+
+	if err := patch.Validate(time.Now()); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid repair patch", "error", err, "repairID", repairID, "app", "repair-service")
+		return nil, err
+	}
+	repair, err := s.repo.GetRepairByID(ctx, repairID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get repair")
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			s.logger.Error("Failed to get repair to patch", "error", err, "repairID", repairID, "app", "repair-service")
+		}
+		return nil, err
+	}
+	if err := patch.Authorize(actor, repair); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Warn("Refused repair patch", "error", err, "repairID", repairID, "actorRole", actor.Role, "actorID", actor.ID, "app", "repair-service")
+		return nil, err
+	}
+
+	if patch.Status != nil && *patch.Status != repair.Status {
+		return s.updateRepair(ctx, repairID, *patch.Status, "", nil, patch)
+	}
+	if err := s.repo.PatchRepair(ctx, repairID, patch); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to patch repair")
+		s.logger.Error("Failed to patch repair", "error", err, "repairID", repairID, "app", "repair-service")
+		return nil, err
+	}
+	patch.ApplyTo(repair)
+	s.logger.Info("Patched repair", "repairID", repairID, "fields", patch.Fields(), "actorRole", actor.Role, "app", "repair-service")
+	return repair, nil
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"regexp"
+	"strings"
+	"time"
+
+	"repair-service/domain"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// e164 matches phone numbers in E.164 format, e.g. +4915112345678
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+
+// phoneConfig is the one-time code setup for phone verification
+type phoneConfig struct {
+	required       bool          // repairs need a verified phone number
+	codeTTL        time.Duration // how long a code can be entered
+	maxAttempts    int           // wrong entries allowed per code
+	sendLimit      int           // codes sent per user within sendWindow
+	sendWindow     time.Duration
+	resendInterval time.Duration // minimum time between two codes
+}
+
+// maskPhone hides all but the last two digits of a phone number for logs
+func maskPhone(phone string) string {
+	if len(phone) <= 2 {
+		return phone
+	}
+	return strings.Repeat("*", len(phone)-2) + phone[len(phone)-2:]
+}
+
+// hashCode binds a code to its user so equal codes of two users hash differently
+func hashCode(userID, code string) string {
+	sum := sha256.Sum256([]byte(userID + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
+
+// StartPhoneVerification texts a new 6-digit code to the phone number. Sends
+// are limited per user; changing the number clears an earlier verification.
+func (s *service) StartPhoneVerification(ctx context.Context, userID, phone string) (*domain.PhoneVerification, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceStartPhoneVerification")
+	defer span.End()
+
+	phone = strings.ReplaceAll(strings.TrimSpace(phone), " ", "")
+	if userID == "" || !e164.MatchString(phone) {
+		err := fmt.Errorf("%w: userID and a phone number in E.164 format (e.g. +4915112345678) are required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid input for phone verification", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("userID", userID))
+
+	verification, err := s.repo.GetPhoneVerification(ctx, userID)
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		verification = &domain.PhoneVerification{UserID: userID}
+	case err != nil:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get phone verification")
+		s.logger.Error("Failed to get phone verification", "error", err, "userID", userID, "app", "repair-service")
+		return nil, err
+	case verification.Verified && verification.Phone == phone:
+		return verification, nil
+	}
+
+	now := time.Now()
+	recent := verification.SentAt[:0]
+	for _, sent := range verification.SentAt {
+		if now.Sub(sent) < s.phone.sendWindow {
+			recent = append(recent, sent)
+		}
+	}
+	if len(recent) >= s.phone.sendLimit || (len(recent) > 0 && now.Sub(recent[len(recent)-1]) < s.phone.resendInterval) {
+		err := fmt.Errorf("%w: wait before requesting another code", domain.ErrRateLimited)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Warn("Rate limited phone verification code", "userID", userID, "recentSends", len(recent), "app", "repair-service")
+		return nil, err
+	}
+
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to generate code")
+		return nil, fmt.Errorf("failed to generate verification code: %w", err)
+	}
+	code := fmt.Sprintf("%06d", n.Int64())
+	expiresAt := now.Add(s.phone.codeTTL)
+
+	if verification.Phone != phone {
+		verification.Verified = false
+		verification.VerifiedAt = nil
+	}
+	verification.Phone = phone
+	verification.CodeHash = hashCode(userID, code)
+	verification.CodeExpiresAt = &expiresAt
+	verification.Attempts = 0
+	verification.SentAt = append(recent, now)
+	verification.UpdatedAt = now
+	// Saved before sending so failed sends still count against the limit
+	if err := s.repo.SavePhoneVerification(ctx, verification); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save phone verification")
+		s.logger.Error("Failed to save phone verification", "error", err, "userID", userID, "app", "repair-service")
+		return nil, err
+	}
+
+	message := fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", code, int(s.phone.codeTTL.Minutes()))
+	if err := s.sms.Send(ctx, phone, message); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to send verification code")
+		s.logger.Error("Failed to send verification code", "error", err, "userID", userID, "phone", maskPhone(phone), "provider", s.sms.Name(), "app", "repair-service")
+		return nil, fmt.Errorf("failed to send verification code: %w", err)
+	}
+	s.logger.Info("Sent phone verification code", "userID", userID, "phone", maskPhone(phone), "provider", s.sms.Name(), "app", "repair-service")
+	return verification, nil
+}
+
+// ConfirmPhoneVerification checks a code sent by StartPhoneVerification and
+// marks the phone number verified
+func (s *service) ConfirmPhoneVerification(ctx context.Context, userID, code string) (*domain.PhoneVerification, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceConfirmPhoneVerification")
+	defer span.End()
+
+	code = strings.TrimSpace(code)
+	if userID == "" || code == "" {
+		err := fmt.Errorf("%w: userID and code are required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("userID", userID))
+
+	verification, err := s.repo.GetPhoneVerification(ctx, userID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		err = fmt.Errorf("%w: no verification code was requested", domain.ErrInvalidInput)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get phone verification")
+		s.logger.Error("Failed to get phone verification", "error", err, "userID", userID, "app", "repair-service")
+		return nil, err
+	}
+	if verification.Verified && verification.CodeHash == "" {
+		return verification, nil
+	}
+
+	now := time.Now()
+	switch {
+	case verification.CodeHash == "" || verification.CodeExpiresAt == nil || now.After(*verification.CodeExpiresAt):
+		err = fmt.Errorf("%w: verification code expired, request a new one", domain.ErrInvalidInput)
+	case verification.Attempts >= s.phone.maxAttempts:
+		err = fmt.Errorf("%w: too many wrong codes, request a new one", domain.ErrRateLimited)
+	case subtle.ConstantTimeCompare([]byte(hashCode(userID, code)), []byte(verification.CodeHash)) != 1:
+		verification.Attempts++
+		verification.UpdatedAt = now
+		if saveErr := s.repo.SavePhoneVerification(ctx, verification); saveErr != nil {
+			err = saveErr
+			break
+		}
+		err = fmt.Errorf("%w: incorrect verification code", domain.ErrInvalidInput)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Warn("Phone verification failed", "error", err, "userID", userID, "attempts", verification.Attempts, "app", "repair-service")
+		return nil, err
+	}
+
+	verification.Verified = true
+	verification.VerifiedAt = &now
+	verification.CodeHash = ""
+	verification.CodeExpiresAt = nil
+	verification.Attempts = 0
+	verification.UpdatedAt = now
+	if err := s.repo.SavePhoneVerification(ctx, verification); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save phone verification")
+		s.logger.Error("Failed to save phone verification", "error", err, "userID", userID, "app", "repair-service")
+		return nil, err
+	}
+	s.logger.Info("Verified phone number", "userID", userID, "phone", maskPhone(verification.Phone), "app", "repair-service")
+	return verification, nil
+}
+
+// GetPhoneVerification returns a user's phone verification state; users who
+// never started one are reported unverified
+func (s *service) GetPhoneVerification(ctx context.Context, userID string) (*domain.PhoneVerification, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceGetPhoneVerification")
+	defer span.End()
+	span.SetAttributes(attribute.String("userID", userID))
+
+	verification, err := s.repo.GetPhoneVerification(ctx, userID)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return &domain.PhoneVerification{UserID: userID}, nil
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get phone verification")
+		s.logger.Error("Failed to get phone verification", "error", err, "userID", userID, "app", "repair-service")
+		return nil, err
+	}
+	return verification, nil
+}
+
+// requireVerifiedPhone fails with ErrPhoneNotVerified unless the user has a
+// verified phone number or verification is not required
+func (s *service) requireVerifiedPhone(ctx context.Context, userID string) error {
+	if !s.phone.required {
+		return nil
+	}
+	verification, err := s.repo.GetPhoneVerification(ctx, userID)
+	if errors.Is(err, mongo.ErrNoDocuments) || (err == nil && !verification.Verified) {
+		return domain.ErrPhoneNotVerified
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check phone verification: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"repair-service/domain"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// positioningConfig is how demand is aggregated for positioning hints
+type positioningConfig struct {
+	radius       float64 // service area around the mechanic, in meters
+	lookbackDays int     // days of repair history aggregated per hour of day
+	precision    int     // geohash length of the demand cells
+	topCells     int     // cells returned per hint
+}
+
+// metersPerDegreeLatitude converts the service radius into a bounding box
+const metersPerDegreeLatitude = 111320.0
+
+// PositioningHints ranks the geohash cells within the mechanic's service area
+// by how many repairs were requested there in the current hour of day over the
+// lookback period, so idle mechanics can wait where demand usually appears
+func (s *service) PositioningHints(ctx context.Context, mechanicID string) (*domain.PositioningHints, error) {
+	ctx, span := s.tracer.Start(ctx, "ServicePositioningHints")
+	defer span.End()
+
+	if mechanicID == "" {
+		err := fmt.Errorf("%w: mechanic ID is required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("mechanicID", mechanicID))
+
+	mechanic, err := s.repo.GetMechanicByID(ctx, mechanicID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get mechanic")
+		s.logger.Error("Failed to get mechanic for positioning hints", "error", err, "mechanicID", mechanicID, "app", "repair-service")
+		return nil, err
+	}
+	idle := isOnline(mechanic)
+	if idle {
+		busy, err := s.repo.CountActiveAssignments(ctx, []string{mechanicID})
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to count active assignments")
+			s.logger.Error("Failed to count active assignments", "error", err, "mechanicID", mechanicID, "app", "repair-service")
+			return nil, fmt.Errorf("failed to count active assignments: %w", err)
+		}
+		idle = busy[mechanicID] == 0
+	}
+
+	now := time.Now().UTC()
+	cfg := s.positioning
+	center := mechanic.Location
+	latDelta := cfg.radius / metersPerDegreeLatitude
+	lonDelta := cfg.radius / (metersPerDegreeLatitude * math.Max(math.Cos(center.Latitude*math.Pi/180), 0.01))
+	box := domain.BoundingBox{
+		MinLongitude: center.Longitude - lonDelta,
+		MinLatitude:  center.Latitude - latDelta,
+		MaxLongitude: center.Longitude + lonDelta,
+		MaxLatitude:  center.Latitude + latDelta,
+	}
+	repairs, err := s.repo.FindRepairLocations(ctx, domain.RepairFilter{
+		BoundingBox: &box,
+		Since:       now.AddDate(0, 0, -cfg.lookbackDays),
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find repair locations")
+		s.logger.Error("Failed to find repair locations for positioning hints", "error", err, "mechanicID", mechanicID, "app", "repair-service")
+		return nil, fmt.Errorf("failed to find repair locations: %w", err)
+	}
+
+	cells := make(map[string]*domain.DemandCell)
+	for _, repair := range repairs {
+		if repair.RepairCost == nil || repair.RepairCost.UserLocation == nil || repair.CreatedAt.UTC().Hour() != now.Hour() {
+			continue
+		}
+		loc := *repair.RepairCost.UserLocation
+		if haversineMeters(center, loc) > cfg.radius {
+			continue
+		}
+		hash := encodeGeohash(loc.Latitude, loc.Longitude, cfg.precision)
+		cell, ok := cells[hash]
+		if !ok {
+			cell = &domain.DemandCell{Geohash: hash}
+			cells[hash] = cell
+		}
+		cell.Demand++
+		cell.Latitude += (loc.Latitude - cell.Latitude) / float64(cell.Demand)
+		cell.Longitude += (loc.Longitude - cell.Longitude) / float64(cell.Demand)
+	}
+
+	ranked := make([]domain.DemandCell, 0, len(cells))
+	for _, cell := range cells {
+		cell.DistanceMeters = math.Round(haversineMeters(center, domain.Location{Latitude: cell.Latitude, Longitude: cell.Longitude}))
+		ranked = append(ranked, *cell)
+	}
+	// Busiest first; closer cells win ties so the hint moves the mechanic least
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Demand != ranked[j].Demand {
+			return ranked[i].Demand > ranked[j].Demand
+		}
+		return ranked[i].DistanceMeters < ranked[j].DistanceMeters
+	})
+	if len(ranked) > cfg.topCells {
+		ranked = ranked[:cfg.topCells]
+	}
+
+	span.SetAttributes(
+		attribute.Bool("idle", idle),
+		attribute.Int("hour", now.Hour()),
+		attribute.Int("cellCount", len(ranked)),
+	)
+	return &domain.PositioningHints{
+		MechanicID:   mechanicID,
+		Idle:         idle,
+		Hour:         now.Hour(),
+		LookbackDays: cfg.lookbackDays,
+		RadiusMeters: cfg.radius,
+		Cells:        ranked,
+		GeneratedAt:  now,
+	}, nil
+}
+
+// haversineMeters returns the great-circle distance between two points in meters
+func haversineMeters(l1, l2 domain.Location) float64 {
+	const R = 6371000 // Earth's radius in m
+	lat1 := l1.Latitude * math.Pi / 180
+	lat2 := l2.Latitude * math.Pi / 180
+	dLat := (l2.Latitude - l1.Latitude) * math.Pi / 180
+	dLon := (l2.Longitude - l1.Longitude) * math.Pi / 180
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return R * 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"repair-service/domain"
+)
+
+// preferenceConfig sets how users' preferred mechanics are honored
+type preferenceConfig struct {
+	fallback string        // fallback of preferences that name none
+	hold     time.Duration // how long a repair pinned with the nearby fallback waits for the preferred mechanic
+}
+
+// preferredMechanic checks whether the preferred mechanic is qualified for
+// repairType and within the availability radius of the estimate, and returns
+// why not otherwise
+func (s *service) preferredMechanic(preference *domain.MechanicPreference, repairType string, mechanics []*domain.MechanicModel, infos []domain.MechanicInfo, blocks *domain.BlockList) string {
+	if blocks.Mechanics[preference.MechanicID] {
+		return domain.PreferenceBlocked
+	}
+	for _, m := range mechanics {
+		if m.ID != preference.MechanicID {
+			continue
+		}
+		for _, info := range infos {
+			if info.ID == m.ID && info.Distance <= s.availabilityRadius && isQualified(m, repairType) {
+				return ""
+			}
+		}
+	}
+	return domain.PreferenceNotQualified
+}
+
+// pinPreferred narrows mechanics to the preferred mechanic when reason is
+// empty. Otherwise it records why the preference was not honored and keeps
+// mechanics, or fails when the preference allows no fallback.
+func pinPreferred(preference *domain.MechanicPreference, mechanics []domain.MechanicInfo, reason string) ([]domain.MechanicInfo, error) {
+	preference.Pinned, preference.Reason, preference.HoldUntil = false, reason, nil
+	if reason == "" {
+		for _, m := range mechanics {
+			if m.ID == preference.MechanicID {
+				preference.Pinned = true
+				return []domain.MechanicInfo{m}, nil
+			}
+		}
+		preference.Reason = domain.PreferenceNotOffered
+	}
+	if preference.Fallback == domain.PreferenceFallbackNone {
+		return nil, fmt.Errorf("%w: %s", domain.ErrPreferredMechanicUnavailable, preference.Reason)
+	}
+	return mechanics, nil
+}
@@ -0,0 +1,181 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"repair-service/domain"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// priceRepair evaluates the stored pricing rules for an estimate made now
+func (s *service) priceRepair(ctx context.Context, repairType string, location *domain.Location) (*domain.PricingResult, error) {
+	rules, err := s.repo.FindPricingRules(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pricing rules: %w", err)
+	}
+	weather, err := s.repo.GetWeatherFlag(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load weather flag: %w", err)
+	}
+	return domain.EvaluatePricing(rules, domain.PricingContext{
+		RepairType: repairType,
+		Location:   location,
+		At:         time.Now().In(s.pricingLocation),
+		Weather:    weather.Flag,
+	})
+}
+
+// ListPricingRules returns every pricing rule in evaluation order
+func (s *service) ListPricingRules(ctx context.Context) ([]*domain.PricingRule, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceListPricingRules")
+	defer span.End()
+
+	rules, err := s.repo.FindPricingRules(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to list pricing rules")
+		s.logger.Error("Failed to list pricing rules", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	return rules, nil
+}
+
+// SavePricingRule validates and creates or replaces a pricing rule
+func (s *service) SavePricingRule(ctx context.Context, rule *domain.PricingRule) (*domain.PricingRule, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceSavePricingRule")
+	defer span.End()
+	span.SetAttributes(attribute.String("ruleID", rule.ID))
+
+	if err := rule.Validate(); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	for i, flag := range rule.Conditions.Weather {
+		rule.Conditions.Weather[i] = strings.ToLower(strings.TrimSpace(flag))
+	}
+	rule.UpdatedAt = time.Now()
+	if err := s.repo.UpsertPricingRule(ctx, rule); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save pricing rule")
+		s.logger.Error("Failed to save pricing rule", "error", err, "ruleID", rule.ID, "app", "repair-service")
+		return nil, fmt.Errorf("failed to save pricing rule: %w", err)
+	}
+	s.logger.Info("Saved pricing rule", "ruleID", rule.ID, "name", rule.Name, "priority", rule.Priority, "enabled", rule.Enabled, "modifier", rule.Modifier.Type, "app", "repair-service")
+	return rule, nil
+}
+
+// DeletePricingRule removes a pricing rule
+func (s *service) DeletePricingRule(ctx context.Context, ruleID string) error {
+	ctx, span := s.tracer.Start(ctx, "ServiceDeletePricingRule")
+	defer span.End()
+	span.SetAttributes(attribute.String("ruleID", ruleID))
+
+	if err := s.repo.DeletePricingRule(ctx, ruleID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to delete pricing rule")
+		s.logger.Error("Failed to delete pricing rule", "error", err, "ruleID", ruleID, "app", "repair-service")
+		return err
+	}
+	s.logger.Info("Deleted pricing rule", "ruleID", ruleID, "app", "repair-service")
+	return nil
+}
+
+// EvaluatePricing is a dry run of the pricing rules: it prices pc without
+// creating an estimate. A zero pc.At means now and an empty pc.Weather the
+// current weather flag; rules, when given, are evaluated instead of the
+// stored ones so a change can be tried before saving it.
+func (s *service) EvaluatePricing(ctx context.Context, pc domain.PricingContext, rules []*domain.PricingRule) (*domain.PricingResult, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceEvaluatePricing")
+	defer span.End()
+	span.SetAttributes(
+		attribute.String("repairType", pc.RepairType),
+		attribute.Bool("candidateRules", rules != nil),
+	)
+
+	if pc.RepairType == "" {
+		err := fmt.Errorf("%w: repair type is required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if rules == nil {
+		stored, err := s.repo.FindPricingRules(ctx)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to load pricing rules")
+			s.logger.Error("Failed to load pricing rules", "error", err, "app", "repair-service")
+			return nil, err
+		}
+		rules = stored
+	} else {
+		for _, rule := range rules {
+			if err := rule.Validate(); err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+				return nil, err
+			}
+		}
+	}
+	if pc.At.IsZero() {
+		pc.At = time.Now()
+	}
+	pc.At = pc.At.In(s.pricingLocation)
+	if pc.Weather == "" {
+		weather, err := s.repo.GetWeatherFlag(ctx)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to load weather flag")
+			s.logger.Error("Failed to load weather flag", "error", err, "app", "repair-service")
+			return nil, err
+		}
+		pc.Weather = weather.Flag
+	}
+
+	result, err := domain.EvaluatePricing(rules, pc)
+	if err != nil {
+		// The trace explains the failure, so return it as a bad request
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return result, fmt.Errorf("%w: %v", domain.ErrInvalidInput, err)
+	}
+	span.SetAttributes(attribute.Int64("priceMinor", result.Price.Minor()))
+	return result, nil
+}
+
+// GetWeatherFlag returns the weather flag pricing rules currently match on
+func (s *service) GetWeatherFlag(ctx context.Context) (*domain.WeatherFlag, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceGetWeatherFlag")
+	defer span.End()
+
+	flag, err := s.repo.GetWeatherFlag(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get weather flag")
+		s.logger.Error("Failed to get weather flag", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	return flag, nil
+}
+
+// SetWeatherFlag sets the weather flag, e.g. rain or snow; an empty flag clears it
+func (s *service) SetWeatherFlag(ctx context.Context, flag string) (*domain.WeatherFlag, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceSetWeatherFlag")
+	defer span.End()
+
+	weather := &domain.WeatherFlag{Flag: strings.ToLower(strings.TrimSpace(flag)), UpdatedAt: time.Now()}
+	span.SetAttributes(attribute.String("flag", weather.Flag))
+	if err := s.repo.SaveWeatherFlag(ctx, weather); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to set weather flag")
+		s.logger.Error("Failed to set weather flag", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	s.logger.Info("Set weather flag", "flag", weather.Flag, "app", "repair-service")
+	return weather, nil
+}
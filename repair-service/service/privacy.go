@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"repair-service/domain"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// hashUserID identifies a user in erasure reports without storing the ID
+func hashUserID(userID string) string {
+	sum := sha256.Sum256([]byte(userID))
+	return hex.EncodeToString(sum[:])
+}
+
+// ExportUserData returns everything stored about a user as one bundle, to
+// the user themself or an admin
+func (s *service) ExportUserData(ctx context.Context, actor domain.Actor, userID string) (*domain.UserDataExport, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceExportUserData")
+	defer span.End()
+	span.SetAttributes(attribute.String("actorRole", actor.Role))
+
+	if userID == "" {
+		err := fmt.Errorf("%w: user ID is required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if err := domain.AuthorizeExport(actor, userID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Warn("Refused user data export", "actorRole", actor.Role, "app", "repair-service")
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("userID", userID))
+
+	export, err := s.repo.ExportUserData(ctx, userID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to export user data")
+		s.logger.Error("Failed to export user data", "error", err, "userID", userID, "app", "repair-service")
+		return nil, err
+	}
+	export.ExportedAt = time.Now()
+	s.logger.Info("Exported user data", "userID", userID, "repairCount", len(export.Repairs), "app", "repair-service")
+	return export, nil
+}
+
+// EraseUser carries out a right-to-be-forgotten request: the user's personal
+// data is anonymized or deleted, a Kafka tombstone is queued for each of the
+// user's repairs so downstream consumers drop their copies, and a report is
+// stored, all in one transaction.
+func (s *service) EraseUser(ctx context.Context, userID, requestedBy string) (*domain.ErasureReport, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceEraseUser")
+	defer span.End()
+
+	if userID == "" || requestedBy == "" {
+		err := fmt.Errorf("%w: user ID and requestedBy are required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	report := &domain.ErasureReport{
+		ID:          primitive.NewObjectID().Hex(),
+		UserIDHash:  hashUserID(userID),
+		Pseudonym:   domain.ErasedUserPrefix + primitive.NewObjectID().Hex(),
+		RequestedBy: requestedBy,
+		RequestedAt: time.Now(),
+	}
+	span.SetAttributes(
+		attribute.String("reportID", report.ID),
+		attribute.String("pseudonym", report.Pseudonym),
+	)
+
+	err := s.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		anonymized, deleted, repairIDs, err := s.repo.EraseUserData(tx, userID, report.Pseudonym)
+		if err != nil {
+			return err
+		}
+		report.Anonymized, report.Deleted = anonymized, deleted
+
+		for _, repairID := range repairIDs {
+			if err := s.repo.SaveOutboxEvent(tx, &domain.OutboxEvent{
+				ID:          primitive.NewObjectID().Hex(),
+				EventType:   domain.EventRepairErased,
+				AggregateID: repairID,
+				Payload:     []byte{},
+				CreatedAt:   time.Now(),
+				Processed:   false,
+			}); err != nil {
+				return fmt.Errorf("failed to save tombstone event: %w", err)
+			}
+		}
+		report.Tombstones = len(repairIDs)
+		report.CompletedAt = time.Now()
+		return s.repo.SaveErasureReport(tx, report)
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to erase user")
+		s.logger.Error("Failed to erase user", "error", err, "reportID", report.ID, "app", "repair-service")
+		return nil, fmt.Errorf("failed to erase user: %w", err)
+	}
+
+	// The user ID is not logged, only the report that replaces it
+	s.logger.Info("Erased user data", "reportID", report.ID, "pseudonym", report.Pseudonym, "tombstones", report.Tombstones,
+		"anonymized", report.Anonymized, "deleted", report.Deleted, "requestedBy", requestedBy, "app", "repair-service")
+	return report, nil
+}
+
+// FindErasureReports lists the erasure reports of a user ID
+func (s *service) FindErasureReports(ctx context.Context, userID string) ([]*domain.ErasureReport, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceFindErasureReports")
+	defer span.End()
+
+	reports, err := s.repo.FindErasureReports(ctx, hashUserID(userID))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find erasure reports")
+		s.logger.Error("Failed to find erasure reports", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	return reports, nil
+}
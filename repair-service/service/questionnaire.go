@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"repair-service/domain"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// GetQuestionnaire retrieves the intake questionnaire for a repair type
+func (s *service) GetQuestionnaire(ctx context.Context, repairType string) (*domain.Questionnaire, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceGetQuestionnaire")
+	defer span.End()
+
+	if repairType == "" {
+		err := fmt.Errorf("%w: repair type is required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid input for get questionnaire", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("repairType", repairType))
+
+	questionnaire, err := s.repo.GetQuestionnaire(ctx, repairType)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get questionnaire")
+		s.logger.Error("Failed to get questionnaire", "error", err, "repairType", repairType, "app", "repair-service")
+		return nil, err
+	}
+	return questionnaire, nil
+}
+
+// SaveQuestionnaire validates and stores the intake questionnaire for a repair type
+func (s *service) SaveQuestionnaire(ctx context.Context, questionnaire *domain.Questionnaire) error {
+	ctx, span := s.tracer.Start(ctx, "ServiceSaveQuestionnaire")
+	defer span.End()
+
+	if err := validateQuestionnaire(questionnaire); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid questionnaire", "error", err, "app", "repair-service")
+		return err
+	}
+	span.SetAttributes(
+		attribute.String("repairType", questionnaire.RepairType),
+		attribute.Int("questionCount", len(questionnaire.Questions)),
+	)
+
+	questionnaire.UpdatedAt = time.Now()
+	if err := s.repo.UpsertQuestionnaire(ctx, questionnaire); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to save questionnaire")
+		s.logger.Error("Failed to save questionnaire", "error", err, "repairType", questionnaire.RepairType, "app", "repair-service")
+		return fmt.Errorf("failed to save questionnaire: %w", err)
+	}
+	s.logger.Info("Saved questionnaire", "repairType", questionnaire.RepairType, "questionCount", len(questionnaire.Questions), "app", "repair-service")
+	return nil
+}
+
+// validateQuestionnaire checks question IDs are unique and types are supported
+func validateQuestionnaire(questionnaire *domain.Questionnaire) error {
+	if questionnaire == nil || questionnaire.RepairType == "" {
+		return fmt.Errorf("%w: repair type is required", domain.ErrInvalidInput)
+	}
+	seen := make(map[string]bool)
+	for _, q := range questionnaire.Questions {
+		if q.ID == "" || q.Text == "" {
+			return fmt.Errorf("%w: question ID and text are required", domain.ErrInvalidInput)
+		}
+		if seen[q.ID] {
+			return fmt.Errorf("%w: duplicate question ID: %s", domain.ErrInvalidInput, q.ID)
+		}
+		seen[q.ID] = true
+		switch q.Type {
+		case domain.QuestionTypeYesNo, domain.QuestionTypeText:
+		case domain.QuestionTypeChoice:
+			if len(q.Options) == 0 {
+				return fmt.Errorf("%w: choice question %s requires options", domain.ErrInvalidInput, q.ID)
+			}
+		default:
+			return fmt.Errorf("%w: unsupported question type %q for question %s", domain.ErrInvalidInput, q.Type, q.ID)
+		}
+	}
+	return nil
+}
+
+// resolveSymptoms validates intake answers against the repair type's questionnaire
+// and fills in the question text so mechanics see the full context
+func (s *service) resolveSymptoms(ctx context.Context, repairType string, answers []domain.SymptomAnswer) ([]domain.SymptomAnswer, error) {
+	questionnaire, err := s.repo.GetQuestionnaire(ctx, repairType)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		if len(answers) > 0 {
+			return nil, fmt.Errorf("%w: no questionnaire defined for repair type %s", domain.ErrInvalidInput, repairType)
+		}
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get questionnaire: %w", err)
+	}
+
+	questions := make(map[string]domain.Question, len(questionnaire.Questions))
+	for _, q := range questionnaire.Questions {
+		questions[q.ID] = q
+	}
+
+	answered := make(map[string]bool, len(answers))
+	resolved := make([]domain.SymptomAnswer, 0, len(answers))
+	for _, a := range answers {
+		q, ok := questions[a.QuestionID]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown question ID: %s", domain.ErrInvalidInput, a.QuestionID)
+		}
+		if answered[a.QuestionID] {
+			return nil, fmt.Errorf("%w: question %s answered more than once", domain.ErrInvalidInput, a.QuestionID)
+		}
+		answer := strings.TrimSpace(a.Answer)
+		switch q.Type {
+		case domain.QuestionTypeYesNo:
+			answer = strings.ToLower(answer)
+			if answer != "yes" && answer != "no" {
+				return nil, fmt.Errorf("%w: question %s expects yes or no", domain.ErrInvalidInput, q.ID)
+			}
+		case domain.QuestionTypeChoice:
+			valid := false
+			for _, opt := range q.Options {
+				if opt == answer {
+					valid = true
+					break
+				}
+			}
+			if !valid {
+				return nil, fmt.Errorf("%w: invalid option %q for question %s", domain.ErrInvalidInput, answer, q.ID)
+			}
+		}
+		if answer == "" {
+			continue
+		}
+		answered[a.QuestionID] = true
+		resolved = append(resolved, domain.SymptomAnswer{
+			QuestionID: q.ID,
+			Question:   q.Text,
+			Answer:     answer,
+		})
+	}
+
+	for _, q := range questionnaire.Questions {
+		if q.Required && !answered[q.ID] {
+			return nil, fmt.Errorf("%w: required question %s is unanswered", domain.ErrInvalidInput, q.ID)
+		}
+	}
+	return resolved, nil
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"repair-service/domain"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// ClaimQuote binds an anonymous quote to a registered user so it can be
+// converted into a repair. Blacklisted users cannot claim quotes, and mechanics
+// the user blocked are dropped from the claimed quote.
+func (s *service) ClaimQuote(ctx context.Context, costID, userID string) (*domain.RepairCostModel, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceClaimQuote")
+	defer span.End()
+
+	if costID == "" || userID == "" {
+		err := fmt.Errorf("%w: cost ID and user ID are required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid input for claim quote", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	span.SetAttributes(
+		attribute.String("costID", costID),
+		attribute.String("userID", userID),
+	)
+
+	blocks, err := s.userBlocks(ctx, userID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to check blocks")
+		s.logger.Error("Failed to check blocks", "error", err, "userID", userID, "app", "repair-service")
+		return nil, err
+	}
+
+	quote, err := s.repo.ClaimAnonymousQuote(ctx, costID, userID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to claim quote")
+		s.logger.Error("Failed to claim quote", "error", err, "costID", costID, "userID", userID, "app", "repair-service")
+		return nil, err
+	}
+
+	cost := quote.RepairCostModel
+	cost.UserID = userID
+	allowed := make([]domain.MechanicInfo, 0, len(cost.Mechanics))
+	for _, m := range cost.Mechanics {
+		if !blocks.Mechanics[m.ID] {
+			allowed = append(allowed, m)
+		}
+	}
+	cost.Mechanics = allowed
+	s.logger.Info("User claimed anonymous quote", "costID", costID, "userID", userID, "app", "repair-service")
+	return &cost, nil
+}
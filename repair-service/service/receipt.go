@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"repair-service/domain"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// receiptConfig is the billing setup applied to receipts when they are issued
+type receiptConfig struct {
+	currency   string
+	taxName    string
+	taxPercent float64 // added on top of the repair price; 0 issues receipts without taxes
+}
+
+// buildReceipt bills the price quoted for the repair as a single line item,
+// one line per approved amendment and the configured tax. The mechanic is
+// looked up now so later changes to the mechanic's profile do not reach the
+// receipt.
+func (s *service) buildReceipt(ctx context.Context, repair *domain.RepairModel, amendments []*domain.Amendment, completedAt *time.Time, paymentReference string) (*domain.Receipt, error) {
+	price := repair.RepairCost.TotalPrice
+	receipt := &domain.Receipt{
+		RepairID:   repair.ID,
+		Number:     "RCPT-" + strings.ToUpper(repair.ID),
+		UserID:     repair.UserID,
+		RepairType: repair.RepairCost.RepairType,
+		Currency:   s.receipts.currency,
+		LineItems: []domain.ReceiptLineItem{{
+			Description: strings.ReplaceAll(repair.RepairCost.RepairType, "_", " ") + " repair",
+			Quantity:    1,
+			UnitPrice:   price,
+			Amount:      price,
+		}},
+		Subtotal:         domain.FinalAmount(repair.QuotedPrice(), amendments),
+		Taxes:            []domain.ReceiptTax{},
+		PaymentReference: paymentReference,
+		CreatedAt:        repair.CreatedAt,
+		CompletedAt:      completedAt,
+		IssuedAt:         time.Now(),
+	}
+	if repair.BundleDiscount != 0 {
+		receipt.LineItems = append(receipt.LineItems, domain.ReceiptLineItem{
+			Description: "Bundle discount",
+			Quantity:    1,
+			UnitPrice:   -repair.BundleDiscount,
+			Amount:      -repair.BundleDiscount,
+		})
+	}
+	for _, a := range amendments {
+		if a.Status == domain.AmendmentApproved {
+			receipt.LineItems = append(receipt.LineItems, domain.ReceiptLineItem{
+				Description: a.Description,
+				Quantity:    1,
+				UnitPrice:   a.Amount,
+				Amount:      a.Amount,
+			})
+		}
+	}
+	receipt.Total = receipt.Subtotal
+	if s.receipts.taxPercent > 0 {
+		tax := domain.Money(math.Round(float64(receipt.Subtotal) * s.receipts.taxPercent / 100))
+		receipt.Taxes = append(receipt.Taxes, domain.ReceiptTax{Name: s.receipts.taxName, RatePercent: s.receipts.taxPercent, Amount: tax})
+		receipt.Total += tax
+	}
+
+	if repair.AssignedTo != "" {
+		receipt.Mechanic = &domain.ReceiptMechanic{ID: repair.AssignedTo}
+		mechanic, err := s.repo.GetMechanicByID(ctx, repair.AssignedTo)
+		switch {
+		case err == nil:
+			receipt.Mechanic.Name = mechanic.Name
+		case !errors.Is(err, mongo.ErrNoDocuments):
+			return nil, fmt.Errorf("failed to get mechanic for receipt: %w", err)
+		}
+	}
+	return receipt, nil
+}
+
+// GetReceipt returns the receipt frozen when the repair completed. Repairs
+// completed before receipts existed get one issued on first request.
+func (s *service) GetReceipt(ctx context.Context, repairID string) (*domain.Receipt, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceGetReceipt")
+	defer span.End()
+
+	if repairID == "" {
+		err := fmt.Errorf("%w: repair ID is required", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Invalid repair ID", "error", err, "app", "repair-service")
+		return nil, err
+	}
+	span.SetAttributes(attribute.String("repairID", repairID))
+
+	receipt, err := s.repo.GetReceipt(ctx, repairID)
+	if err == nil {
+		return receipt, nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get receipt")
+		s.logger.Error("Failed to get receipt", "error", err, "repairID", repairID, "app", "repair-service")
+		return nil, err
+	}
+
+	repair, err := s.repo.GetRepairByID(ctx, repairID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to get repair")
+		s.logger.Error("Failed to get repair for receipt", "error", err, "repairID", repairID, "app", "repair-service")
+		return nil, err
+	}
+	if repair.Status != "completed" {
+		span.SetStatus(codes.Error, domain.ErrReceiptUnavailable.Error())
+		return nil, domain.ErrReceiptUnavailable
+	}
+
+	amendments, err := s.repo.FindAmendments(ctx, repairID)
+	if err == nil {
+		receipt, err = s.buildReceipt(ctx, repair, amendments, nil, "")
+	}
+	if err == nil {
+		receipt, err = s.repo.SaveReceipt(ctx, receipt)
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to issue receipt")
+		s.logger.Error("Failed to issue receipt", "error", err, "repairID", repairID, "app", "repair-service")
+		return nil, err
+	}
+	s.logger.Info("Issued receipt for repair completed before receipts", "repairID", repairID, "app", "repair-service")
+	return receipt, nil
+}
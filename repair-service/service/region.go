@@ -0,0 +1,19 @@
+package service
+
+import (
+	"repair-service/domain"
+	"repair-service/region"
+)
+
+// repairRegion returns the region of a repair, deriving it from the user
+// location for repairs created before regions existed
+func repairRegion(repair *domain.RepairModel) string {
+	if repair.Region != "" {
+		return repair.Region
+	}
+	if repair.RepairCost != nil && repair.RepairCost.UserLocation != nil {
+		loc := repair.RepairCost.UserLocation
+		return region.Default().Of(loc.Longitude, loc.Latitude)
+	}
+	return region.Default().Fallback()
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"repair-service/domain"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// geohashAlphabet is the base32 alphabet used by geohashes
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// MaxMapZoom is the highest web map zoom level accepted by RepairMap
+const MaxMapZoom = 22
+
+// geohashPrecision maps a web map zoom level to a geohash length whose cells
+// are roughly a few dozen pixels wide at that zoom
+func geohashPrecision(zoom int) int {
+	switch {
+	case zoom <= 2:
+		return 1
+	case zoom <= 5:
+		return 2
+	case zoom <= 7:
+		return 3
+	case zoom <= 10:
+		return 4
+	case zoom <= 12:
+		return 5
+	case zoom <= 15:
+		return 6
+	case zoom <= 17:
+		return 7
+	default:
+		return 8
+	}
+}
+
+// encodeGeohash returns the geohash of a coordinate with the given length
+func encodeGeohash(latitude, longitude float64, precision int) string {
+	latRange := [2]float64{-90, 90}
+	lonRange := [2]float64{-180, 180}
+	hash := make([]byte, 0, precision)
+	bit, ch := 0, 0
+	even := true // geohash interleaves bits starting with longitude
+	for len(hash) < precision {
+		if even {
+			mid := (lonRange[0] + lonRange[1]) / 2
+			if longitude >= mid {
+				ch |= 1 << (4 - bit)
+				lonRange[0] = mid
+			} else {
+				lonRange[1] = mid
+			}
+		} else {
+			mid := (latRange[0] + latRange[1]) / 2
+			if latitude >= mid {
+				ch |= 1 << (4 - bit)
+				latRange[0] = mid
+			} else {
+				latRange[1] = mid
+			}
+		}
+		even = !even
+		if bit < 4 {
+			bit++
+		} else {
+			hash = append(hash, geohashAlphabet[ch])
+			bit, ch = 0, 0
+		}
+	}
+	return string(hash)
+}
+
+// RepairMap clusters the repairs inside box by geohash cell, with the cell
+// size chosen from the map zoom level
+func (s *service) RepairMap(ctx context.Context, box domain.BoundingBox, zoom int) (*domain.RepairMap, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceRepairMap")
+	defer span.End()
+
+	if box.MinLongitude > box.MaxLongitude || box.MinLatitude > box.MaxLatitude {
+		err := fmt.Errorf("%w: bounding box minimums must not exceed maximums", domain.ErrInvalidInput)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+	if zoom < 0 || zoom > MaxMapZoom {
+		err := fmt.Errorf("%w: zoom must be between 0 and %d", domain.ErrInvalidInput, MaxMapZoom)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
+	}
+
+	repairs, err := s.repo.FindRepairLocations(ctx, domain.RepairFilter{BoundingBox: &box})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find repair locations")
+		s.logger.Error("Failed to find repair locations", "error", err, "app", "repair-service")
+		return nil, fmt.Errorf("failed to find repair locations: %w", err)
+	}
+
+	precision := geohashPrecision(zoom)
+	clusters := make(map[string]*domain.RepairCluster)
+	total := 0
+	for _, repair := range repairs {
+		if repair.RepairCost == nil || repair.RepairCost.UserLocation == nil {
+			continue
+		}
+		loc := repair.RepairCost.UserLocation
+		hash := encodeGeohash(loc.Latitude, loc.Longitude, precision)
+		cluster, ok := clusters[hash]
+		if !ok {
+			cluster = &domain.RepairCluster{Geohash: hash, Statuses: make(map[string]int), RepairID: repair.ID}
+			clusters[hash] = cluster
+		}
+		// Running mean keeps the centroid without a second pass
+		cluster.Count++
+		cluster.Latitude += (loc.Latitude - cluster.Latitude) / float64(cluster.Count)
+		cluster.Longitude += (loc.Longitude - cluster.Longitude) / float64(cluster.Count)
+		cluster.Statuses[repair.Status]++
+		total++
+	}
+
+	result := &domain.RepairMap{
+		Zoom:      zoom,
+		Precision: precision,
+		Total:     total,
+		Clusters:  make([]domain.RepairCluster, 0, len(clusters)),
+	}
+	for _, cluster := range clusters {
+		if cluster.Count > 1 {
+			cluster.RepairID = ""
+		}
+		result.Clusters = append(result.Clusters, *cluster)
+	}
+	sort.Slice(result.Clusters, func(i, j int) bool {
+		return result.Clusters[i].Geohash < result.Clusters[j].Geohash
+	})
+
+	span.SetAttributes(
+		attribute.Int("zoom", zoom),
+		attribute.Int("precision", precision),
+		attribute.Int("repairCount", total),
+		attribute.Int("clusterCount", len(result.Clusters)),
+	)
+	s.logger.Info("Built repair map", "zoom", zoom, "repairs", total, "clusters", len(result.Clusters), "app", "repair-service")
+	return result, nil
+}
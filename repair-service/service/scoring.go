@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"repair-service/domain"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// scoringConfig sets how an estimate's mechanics are scored and, unless the
+// client asks for another sort, ordered
+type scoringConfig struct {
+	weights     domain.ScoreWeights
+	defaultSort string
+}
+
+// estimateSort returns the sort of an estimate, the configured default when
+// the client did not choose one
+func (s *service) estimateSort(sortBy string) (string, error) {
+	sortBy = strings.ToLower(strings.TrimSpace(sortBy))
+	if sortBy == "" {
+		return s.scoring.defaultSort, nil
+	}
+	if !slices.Contains(domain.EstimateSorts, sortBy) {
+		return "", fmt.Errorf("%w: sort must be one of %s", domain.ErrInvalidInput, strings.Join(domain.EstimateSorts, ", "))
+	}
+	return sortBy, nil
+}
+
+// scoreMechanics scores mechanics sorted by distance and the fairness policy,
+// then orders them by sortBy. Like the fairness rotation it is best effort:
+// when the assigned repairs cannot be counted they are scored without load.
+func (s *service) scoreMechanics(ctx context.Context, mechanics []domain.MechanicInfo, sortBy string) *domain.MechanicRanking {
+	ctx, span := s.tracer.Start(ctx, "ServiceScoreMechanics")
+	defer span.End()
+	span.SetAttributes(attribute.String("sort", sortBy), attribute.Int("mechanicCount", len(mechanics)))
+
+	ranking := &domain.MechanicRanking{Sort: sortBy, Weights: s.scoring.weights}
+	if len(mechanics) == 0 {
+		return ranking
+	}
+	ids := make([]string, len(mechanics))
+	for i, m := range mechanics {
+		ids[i] = m.ID
+	}
+	counts, err := s.repo.CountActiveAssignments(ctx, ids)
+	if err != nil {
+		span.RecordError(err)
+		s.logger.Warn("Failed to count active assignments, scoring without load", "error", err, "app", "repair-service")
+	}
+	for i := range mechanics {
+		mechanics[i].OpenRepairs = counts[mechanics[i].ID]
+	}
+
+	domain.ScoreMechanics(mechanics, s.scoring.weights)
+	domain.SortMechanics(mechanics, sortBy)
+	span.SetAttributes(attribute.String("firstMechanicID", mechanics[0].ID), attribute.Float64("firstScore", mechanics[0].Score.Score))
+	return ranking
+}
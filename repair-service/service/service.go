@@ -3,14 +3,23 @@ package service
 import (
 	"context"
 	"encoding/binary"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"repair-service/domain"
+	"repair-service/email"
+	"repair-service/eventbus"
 	"repair-service/kafka"
+	"repair-service/region"
+	"repair-service/routing"
+	"repair-service/slowlog"
+	"repair-service/sms"
+	"repair-service/supervisor"
+	"repair-service/telemetry"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,69 +32,424 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
-	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
 // service implements the RepairService interface
 type service struct {
-	repo           domain.RepairRepository
-	httpClient     *http.Client
-	tracer         trace.Tracer
-	logger         *slog.Logger
-	KafkaProducer  *kafka.Producer
-	outboxProcessor *kafka.OutboxProcessor
+	repo               domain.RepairRepository
+	tracer             trace.Tracer
+	logger             *slog.Logger
+	Publisher          kafka.Publisher // Kafka producer or Mongo event bus, see EVENT_BUS
+	outboxProcessor    *kafka.OutboxProcessor
+	supervisor         *supervisor.Supervisor // restarts the outbox processor
+	availabilityRadius float64
+	router             *routing.Router
+	receipts           receiptConfig
+	positioning        positioningConfig
+	phone              phoneConfig
+	sms                sms.Provider
+	email              emailConfig           // sends queued transactional email
+	pricingLocation    *time.Location        // local time of the pricing rules' hours and weekends
+	fairness           domain.FairnessPolicy // used until admins set a policy
+	coalescing         estimateCoalescing    // shares work between concurrent estimates
+	prefilter          candidatePrefilter    // limits the mechanics routed per estimate
+	bundles            bundleConfig          // groups repairs at one location into one job
+	tiles              distanceTileConfig    // travel times estimates fall back to without OSRM
+	scoring            scoringConfig         // weighs distance, rating, acceptance and load of offered mechanics
+	bulkBatchSize      int                   // repairs written per transaction by bulk status updates
+	durations          durationConfig        // turns completed repairs into duration estimates
+	preference         preferenceConfig      // how preferred mechanics are honored
+	invoices           invoiceConfig         // when fleet invoices are issued
+	cancel             context.CancelFunc    // stops the supervised loops
 }
 
 // NewService creates a new instance of the repair service
-func NewService(repo domain.RepairRepository, logger *slog.Logger) *service {
+func NewService(repo domain.RepairRepository, slow *slowlog.Recorder, logger *slog.Logger) *service {
 	_, span := otel.Tracer("repair-service").Start(context.Background(), "InitializeService")
 	defer span.End()
 
-	// Use hardcoded Kafka bootstrap servers
-	bootstrapServers := "kafka:9094"
-	span.SetAttributes(
-		attribute.String("kafkaServiceName", "kafka"),
-		attribute.String("bootstrapServers", bootstrapServers),
-	)
-	logger.Info("Using Kafka bootstrap servers", "bootstrapServers", bootstrapServers, "app", "repair-service")
+	topic := kafka.TopicName(kafka.RepairEventsTopic)
+	var publisher kafka.Publisher
+	if kafka.EventBus() == kafka.BusMongo {
+		// Single-node installs publish to the event_bus collection instead of Kafka
+		span.SetAttributes(
+			attribute.String("eventBus", kafka.BusMongo),
+			attribute.String("topic", topic),
+		)
+		logger.Info("Using Mongo event bus", "topic", topic, "app", "repair-service")
+		mongoRepo, ok := repo.(domain.MongoBacked)
+		if !ok {
+			panic("the Mongo event bus needs the mongo storage backend")
+		}
+		publisher = eventbus.NewPublisher(mongoRepo.GetMongoClient(context.Background()).Database("repairdb"), topic, logger)
+	} else {
+		// Use hardcoded Kafka bootstrap servers
+		bootstrapServers := "kafka:9094"
+		span.SetAttributes(
+			attribute.String("kafkaServiceName", "kafka"),
+			attribute.String("bootstrapServers", bootstrapServers),
+			attribute.String("topic", topic),
+		)
+		logger.Info("Using Kafka bootstrap servers", "bootstrapServers", bootstrapServers, "topic", topic, "app", "repair-service")
+
+		// Create the topics of this environment before the first publish; brokers
+		// that auto-create topics still work when this fails
+		provisionCtx, cancelProvision := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := kafka.EnsureTopics(provisionCtx, bootstrapServers, []string{kafka.RepairEventsTopic, kafka.BillingEventsTopic}, logger); err != nil {
+			logger.Warn("Failed to provision Kafka topics", "error", err, "app", "repair-service")
+		}
+		cancelProvision()
 
-	// Initialize Kafka producer with bootstrap servers
-	kafkaProducer, err := kafka.NewProducer(bootstrapServers, "http://schema-registry:8081", "repair-events", logger)
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to initialize Kafka producer")
-		logger.Error("Failed to initialize Kafka producer", "error", err, "app", "repair-service")
-		panic(fmt.Sprintf("failed to initialize Kafka producer: %v", err))
+		// Initialize Kafka producer with bootstrap servers
+		kafkaProducer, err := kafka.NewProducer(bootstrapServers, "http://schema-registry:8081", topic, logger)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to initialize Kafka producer")
+			logger.Error("Failed to initialize Kafka producer", "error", err, "app", "repair-service")
+			panic(fmt.Sprintf("failed to initialize Kafka producer: %v", err))
+		}
+		publisher = kafkaProducer
 	}
 
-	svc := &service{
-		repo:          repo,
-		httpClient:    &http.Client{Timeout: 10 * time.Second},
-		tracer:        otel.Tracer("repair-service"),
-		logger:        logger,
-		KafkaProducer: kafkaProducer,
-		outboxProcessor: kafka.NewOutboxProcessor(repo, kafkaProducer, logger),
+	// Number of parallel outbox workers and pending events buffered per worker;
+	// events for the same repair stay ordered
+	outboxConcurrency := 4
+	if v, err := strconv.Atoi(os.Getenv("OUTBOX_CONCURRENCY")); err == nil && v > 0 {
+		outboxConcurrency = v
 	}
+	outboxQueueDepth := 64
+	if v, err := strconv.Atoi(os.Getenv("OUTBOX_QUEUE_DEPTH")); err == nil && v > 0 {
+		outboxQueueDepth = v
+	}
+	// Outbox inserts are published as a change stream reports them unless
+	// OUTBOX_CHANGE_STREAM=false; polling catches up on events the stream
+	// missed, e.g. while it was reconnecting
+	outboxPollInterval := 5 * time.Second
+	if v, err := strconv.Atoi(os.Getenv("OUTBOX_POLL_INTERVAL_SECONDS")); err == nil && v > 0 {
+		outboxPollInterval = time.Duration(v) * time.Second
+	}
+	outboxChangeStream := true
+	if v, err := strconv.ParseBool(os.Getenv("OUTBOX_CHANGE_STREAM")); err == nil {
+		outboxChangeStream = v
+	}
+	logger.Info("Configured outbox processor", "concurrency", outboxConcurrency, "queueDepth", outboxQueueDepth, "pollInterval", outboxPollInterval, "changeStream", outboxChangeStream, "app", "repair-service")
 
-	// Start outbox processor in a separate goroutine
-	go func() {
-		err := svc.outboxProcessor.Start(context.Background())
-		if err != nil {
-			logger.Error("Outbox processor stopped with error", "error", err, "app", "repair-service")
+	// Radius in meters used for the availability summary returned with estimates
+	availabilityRadius := 10000.0
+	if v, err := strconv.ParseFloat(os.Getenv("ESTIMATE_AVAILABILITY_RADIUS_METERS"), 64); err == nil && v > 0 {
+		availabilityRadius = v
+	}
+
+	// Demand aggregation behind the positioning hints for idle mechanics
+	positioning := positioningConfig{radius: availabilityRadius, lookbackDays: 28, precision: 6, topCells: 3}
+	if v, err := strconv.ParseFloat(os.Getenv("POSITIONING_RADIUS_METERS"), 64); err == nil && v > 0 {
+		positioning.radius = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("POSITIONING_LOOKBACK_DAYS")); err == nil && v > 0 {
+		positioning.lookbackDays = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("POSITIONING_GEOHASH_PRECISION")); err == nil && v > 0 && v <= 12 {
+		positioning.precision = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("POSITIONING_TOP_CELLS")); err == nil && v > 0 {
+		positioning.topCells = v
+	}
+
+	// Billing setup for receipts issued when repairs complete
+	receipts := receiptConfig{currency: "USD", taxName: "Sales tax"}
+	if v := os.Getenv("RECEIPT_CURRENCY"); v != "" {
+		receipts.currency = v
+	}
+	if v := os.Getenv("RECEIPT_TAX_NAME"); v != "" {
+		receipts.taxName = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("RECEIPT_TAX_RATE_PERCENT"), 64); err == nil && v >= 0 {
+		receipts.taxPercent = v
+	}
+
+	// One-time codes for phone verification, required before creating repairs
+	// unless PHONE_VERIFICATION_REQUIRED=false
+	phone := phoneConfig{
+		required:       true,
+		codeTTL:        5 * time.Minute,
+		maxAttempts:    5,
+		sendLimit:      5,
+		sendWindow:     time.Hour,
+		resendInterval: 60 * time.Second,
+	}
+	if v, err := strconv.ParseBool(os.Getenv("PHONE_VERIFICATION_REQUIRED")); err == nil {
+		phone.required = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("PHONE_CODE_TTL_SECONDS")); err == nil && v > 0 {
+		phone.codeTTL = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("PHONE_CODE_MAX_ATTEMPTS")); err == nil && v > 0 {
+		phone.maxAttempts = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("PHONE_CODE_SEND_LIMIT")); err == nil && v > 0 {
+		phone.sendLimit = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("PHONE_CODE_SEND_WINDOW_SECONDS")); err == nil && v > 0 {
+		phone.sendWindow = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("PHONE_CODE_RESEND_SECONDS")); err == nil && v >= 0 {
+		phone.resendInterval = time.Duration(v) * time.Second
+	}
+
+	// Pricing rules match hours and weekends in PRICING_TIMEZONE
+	pricingLocation := time.UTC
+	if v := os.Getenv("PRICING_TIMEZONE"); v != "" {
+		if loc, err := time.LoadLocation(v); err == nil {
+			pricingLocation = loc
+		} else {
+			logger.Warn("Invalid PRICING_TIMEZONE, using UTC", "timezone", v, "error", err, "app", "repair-service")
 		}
-	}()
+	}
+
+	// Fairness rotation among nearby mechanics, until admins set a policy
+	fairness := domain.FairnessPolicy{ETAWindowMinutes: 15, Weight: 0.5, WindowDays: 7}
+	if v, err := strconv.ParseBool(os.Getenv("FAIRNESS_ENABLED")); err == nil {
+		fairness.Enabled = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("FAIRNESS_ETA_WINDOW_MINUTES"), 64); err == nil && v > 0 {
+		fairness.ETAWindowMinutes = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("FAIRNESS_WEIGHT"), 64); err == nil && v >= 0 && v <= 1 {
+		fairness.Weight = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("FAIRNESS_WINDOW_DAYS")); err == nil && v > 0 && v <= domain.MaxFairnessWindowDays {
+		fairness.WindowDays = v
+	}
+
+	// Concurrent estimates for one repair type within a geohash cell of
+	// ESTIMATE_COALESCING_PRECISION characters (default 7, about 150m; 0
+	// disables) share one mechanic query and routing call
+	coalescingPrecision := 7
+	if v, err := strconv.Atoi(os.Getenv("ESTIMATE_COALESCING_PRECISION")); err == nil && v >= 0 && v <= 12 {
+		coalescingPrecision = v
+	}
+
+	// Transactional email queued with repair changes and sent in the background
+	emailCfg := emailConfig{
+		provider:     email.NewProviderFromEnv(&http.Client{Timeout: 10 * time.Second, Transport: telemetry.Transport(slow.Transport(nil), telemetry.PeerService("email"))}, logger),
+		pollInterval: 5 * time.Second,
+		maxAttempts:  5,
+		retryBackoff: 30 * time.Second,
+		batchSize:    50,
+	}
+	if v, err := strconv.Atoi(os.Getenv("EMAIL_POLL_INTERVAL_SECONDS")); err == nil && v > 0 {
+		emailCfg.pollInterval = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("EMAIL_MAX_ATTEMPTS")); err == nil && v > 0 {
+		emailCfg.maxAttempts = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("EMAIL_RETRY_BACKOFF_SECONDS")); err == nil && v > 0 {
+		emailCfg.retryBackoff = time.Duration(v) * time.Second
+	}
+	if v, err := strconv.Atoi(os.Getenv("EMAIL_BATCH_SIZE")); err == nil && v > 0 {
+		emailCfg.batchSize = v
+	}
+
+	// Estimates route to the ESTIMATE_CANDIDATE_LIMIT nearest mechanics (0
+	// routes to all), searched in a box of ESTIMATE_CANDIDATE_RADIUS_METERS
+	// that doubles up to ESTIMATE_CANDIDATE_MAX_RADIUS_METERS while too few
+	// are found
+	prefilter := candidatePrefilter{limit: 25, radius: 20000, maxRadius: 200000}
+	if v, err := strconv.Atoi(os.Getenv("ESTIMATE_CANDIDATE_LIMIT")); err == nil && v >= 0 {
+		prefilter.limit = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("ESTIMATE_CANDIDATE_RADIUS_METERS"), 64); err == nil && v > 0 {
+		prefilter.radius = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("ESTIMATE_CANDIDATE_MAX_RADIUS_METERS"), 64); err == nil && v > 0 {
+		prefilter.maxRadius = v
+	}
+	if prefilter.maxRadius < prefilter.radius {
+		prefilter.maxRadius = prefilter.radius
+	}
+
+	// Repairs at one location can be bundled into one job, e.g. a bike-share
+	// station's broken bikes, at a discount on their summed prices
+	bundles := bundleConfig{radius: 50, maxRepairs: 20, discountPercent: 10}
+	if v, err := strconv.ParseFloat(os.Getenv("BUNDLE_RADIUS_METERS"), 64); err == nil && v > 0 {
+		bundles.radius = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("BUNDLE_MAX_REPAIRS")); err == nil && v >= 2 {
+		bundles.maxRepairs = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("BUNDLE_DISCOUNT_PERCENT"), 64); err == nil && v >= 0 && v < 100 {
+		bundles.discountPercent = v
+	}
+
+	// Distance tiles of the busiest cells are rebuilt against OSRM every
+	// ROUTING_TILE_BUILD_INTERVAL_HOURS (0 only loads tiles other instances
+	// built), so estimates degrade to tile lookups when OSRM is unreachable
+	tiles := distanceTileConfig{interval: 24 * time.Hour, regions: region.Scope{}, lookback: 30 * 24 * time.Hour, maxCells: 500, delay: time.Second, maxFailures: 5}
+	if v, err := strconv.Atoi(os.Getenv("ROUTING_TILE_BUILD_INTERVAL_HOURS")); err == nil && v >= 0 {
+		tiles.interval = time.Duration(v) * time.Hour
+	}
+	for _, name := range strings.Split(os.Getenv("ROUTING_TILE_REGIONS"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			tiles.regions[name] = true
+		}
+	}
+	if v, err := strconv.Atoi(os.Getenv("ROUTING_TILE_LOOKBACK_DAYS")); err == nil && v > 0 {
+		tiles.lookback = time.Duration(v) * 24 * time.Hour
+	}
+	if v, err := strconv.Atoi(os.Getenv("ROUTING_TILE_MAX_CELLS")); err == nil && v > 0 {
+		tiles.maxCells = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("ROUTING_TILE_BUILD_DELAY_MS")); err == nil && v >= 0 {
+		tiles.delay = time.Duration(v) * time.Millisecond
+	}
+
+	// Offered mechanics are scored by MECHANIC_SCORE_WEIGHT_DISTANCE, _RATING,
+	// _ACCEPTANCE and _LOAD and ordered by ESTIMATE_DEFAULT_SORT unless the
+	// client sends a sort; the default keeps the distance order
+	scoring := scoringConfig{weights: domain.ScoreWeights{Distance: 0.6, Rating: 0.2, Acceptance: 0.1, Load: 0.1}, defaultSort: domain.SortDistance}
+	for name, weight := range map[string]*float64{
+		"MECHANIC_SCORE_WEIGHT_DISTANCE":   &scoring.weights.Distance,
+		"MECHANIC_SCORE_WEIGHT_RATING":     &scoring.weights.Rating,
+		"MECHANIC_SCORE_WEIGHT_ACCEPTANCE": &scoring.weights.Acceptance,
+		"MECHANIC_SCORE_WEIGHT_LOAD":       &scoring.weights.Load,
+	} {
+		if v, err := strconv.ParseFloat(os.Getenv(name), 64); err == nil && v >= 0 {
+			*weight = v
+		}
+	}
+	if err := scoring.weights.Validate(); err != nil {
+		logger.Warn("Invalid MECHANIC_SCORE_WEIGHT_*, using the defaults", "error", err, "app", "repair-service")
+		scoring.weights = domain.ScoreWeights{Distance: 0.6, Rating: 0.2, Acceptance: 0.1, Load: 0.1}
+	}
+	if v := strings.ToLower(os.Getenv("ESTIMATE_DEFAULT_SORT")); v != "" {
+		if slices.Contains(domain.EstimateSorts, v) {
+			scoring.defaultSort = v
+		} else {
+			logger.Warn("Invalid ESTIMATE_DEFAULT_SORT, sorting by distance", "sort", v, "app", "repair-service")
+		}
+	}
+
+	// Bulk status updates write BULK_STATUS_BATCH_SIZE repairs per transaction
+	bulkBatchSize := 100
+	if v, err := strconv.Atoi(os.Getenv("BULK_STATUS_BATCH_SIZE")); err == nil && v > 0 {
+		bulkBatchSize = v
+	}
+
+	// Users may name a preferred mechanic; PREFERRED_MECHANIC_FALLBACK (nearby
+	// or none) applies when they do not say what to do if the mechanic cannot
+	// take the repair, and a pinned repair waits PREFERRED_MECHANIC_HOLD_MINUTES
+	// for them before the nearby mechanics may claim it
+	preference := preferenceConfig{fallback: domain.PreferenceFallbackNearby, hold: 15 * time.Minute}
+	if v := os.Getenv("PREFERRED_MECHANIC_FALLBACK"); v == domain.PreferenceFallbackNearby || v == domain.PreferenceFallbackNone {
+		preference.fallback = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("PREFERRED_MECHANIC_HOLD_MINUTES")); err == nil && v >= 0 {
+		preference.hold = time.Duration(v) * time.Minute
+	}
+
+	// Duration estimates average the last REPAIR_DURATION_WINDOW completed
+	// repairs once there are REPAIR_DURATION_MIN_SAMPLES; repairs open longer
+	// than REPAIR_DURATION_MAX_HOURS are not timed
+	durations := durationConfig{window: 20, minSamples: 3, maxSample: 24 * time.Hour}
+	if v, err := strconv.Atoi(os.Getenv("REPAIR_DURATION_WINDOW")); err == nil && v > 0 {
+		durations.window = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("REPAIR_DURATION_MIN_SAMPLES")); err == nil && v > 0 {
+		durations.minSamples = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("REPAIR_DURATION_MAX_HOURS")); err == nil && v > 0 {
+		durations.maxSample = time.Duration(v) * time.Hour
+	}
+
+	// Last month's fleet invoices are issued by a job checking every
+	// FLEET_INVOICE_CHECK_MINUTES; 0 only issues them on request
+	invoices := invoiceConfig{interval: time.Hour}
+	if v, err := strconv.Atoi(os.Getenv("FLEET_INVOICE_CHECK_MINUTES")); err == nil && v >= 0 {
+		invoices.interval = time.Duration(v) * time.Minute
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	svc := &service{
+		repo:               repo,
+		tracer:             otel.Tracer("repair-service"),
+		logger:             logger,
+		Publisher:          publisher,
+		outboxProcessor:    kafka.NewOutboxProcessor(repo, publisher, logger, outboxConcurrency, outboxQueueDepth, outboxPollInterval, outboxChangeStream),
+		supervisor:         supervisor.New(logger),
+		availabilityRadius: availabilityRadius,
+		router:             routing.NewRouterFromEnv(&http.Client{Timeout: 10 * time.Second, Transport: telemetry.Transport(slow.Transport(nil), telemetry.PeerService("osrm"))}, logger),
+		receipts:           receipts,
+		positioning:        positioning,
+		phone:              phone,
+		sms:                sms.NewProviderFromEnv(&http.Client{Timeout: 10 * time.Second, Transport: telemetry.Transport(slow.Transport(nil), telemetry.PeerService("sms"))}, logger),
+		email:              emailCfg,
+		pricingLocation:    pricingLocation,
+		fairness:           fairness,
+		coalescing:         estimateCoalescing{precision: coalescingPrecision},
+		prefilter:          prefilter,
+		bundles:            bundles,
+		tiles:              tiles,
+		scoring:            scoring,
+		bulkBatchSize:      bulkBatchSize,
+		durations:          durations,
+		preference:         preference,
+		invoices:           invoices,
+		cancel:             cancel,
+	}
+
+	// Run the outbox processor under the supervisor, which restarts it with
+	// backoff if it fails
+	svc.supervisor.Go(ctx, "outbox-processor", svc.outboxProcessor.Start)
+	svc.supervisor.Go(ctx, "email-sender", svc.runEmailSender)
+	svc.supervisor.Go(ctx, "distance-tiles", svc.runDistanceTiles)
+	svc.supervisor.Go(ctx, "fleet-invoices", svc.runFleetInvoices)
 
 	return svc
 }
 
-// CreateRepair creates a new repair request with the provided cost
-func (s *service) CreateRepair(ctx context.Context, cost *domain.RepairCostModel) (*domain.RepairModel, error) {
-	_, span := s.tracer.Start(ctx, "ServiceCreateRepair")
+// Shutdown stops the outbox processor, waits for the event in flight and
+// closes the publisher
+func (s *service) Shutdown(ctx context.Context) error {
+	s.logger.Info("Shutting down service", "app", "repair-service")
+	s.cancel()
+	err := s.supervisor.Wait(ctx)
+	s.Publisher.Close()
+	return err
+}
+
+// RoutingHealth returns the health of every routing provider
+func (s *service) RoutingHealth() []routing.ProviderHealth {
+	return s.router.Health()
+}
+
+// LoopHealth returns the health of the supervised outbox loop
+func (s *service) LoopHealth() []supervisor.LoopHealth {
+	return s.supervisor.Health()
+}
+
+// Ready reports whether the outbox loop is running
+func (s *service) Ready() bool {
+	return s.supervisor.Ready()
+}
+
+// OutboxStats returns per-worker counters of the outbox processor
+func (s *service) OutboxStats() []kafka.WorkerStats {
+	return s.outboxProcessor.WorkerStats()
+}
+
+// DeliveryLatency returns per event type latency from outbox creation to event bus delivery
+func (s *service) DeliveryLatency() map[string]kafka.LatencyStats {
+	return s.Publisher.DeliveryLatency()
+}
+
+// CreateRepair creates a new repair request with the provided cost and intake answers
+func (s *service) CreateRepair(ctx context.Context, cost *domain.RepairCostModel, answers []domain.SymptomAnswer) (*domain.RepairModel, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceCreateRepair")
 	defer span.End()
 
 	if cost == nil || cost.UserID == "" || cost.RepairType == "" || cost.TotalPrice <= 0 {
-		err := errors.New("invalid repair cost data")
+		err := fmt.Errorf("%w: invalid repair cost data", domain.ErrInvalidInput)
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		s.logger.Error("Invalid repair cost data", "error", err, "app", "repair-service")
@@ -94,24 +458,138 @@ func (s *service) CreateRepair(ctx context.Context, cost *domain.RepairCostModel
 	span.SetAttributes(
 		attribute.String("userID", cost.UserID),
 		attribute.String("repairType", cost.RepairType),
-		attribute.Float64("totalPrice", cost.TotalPrice),
+		attribute.Int64("totalPriceMinor", cost.TotalPrice.Minor()),
 	)
 
+	// Every repair is paid, so the user must have verified a phone number
+	if err := s.requireVerifiedPhone(ctx, cost.UserID); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Warn("Refused repair from user without verified phone", "error", err, "userID", cost.UserID, "app", "repair-service")
+		return nil, err
+	}
+
+	// Anonymous quotes become repairs only once claimed by this user, and at
+	// the quoted price rather than whatever the client sent back
+	quote, err := s.repo.GetAnonymousQuote(ctx, cost.ID)
+	switch {
+	case err == nil:
+		if quote.ClaimedBy != cost.UserID {
+			err := fmt.Errorf("%w: anonymous quote %s must be claimed by the user before creating a repair", domain.ErrInvalidInput, cost.ID)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			s.logger.Error("Unclaimed anonymous quote", "costID", cost.ID, "userID", cost.UserID, "app", "repair-service")
+			return nil, err
+		}
+		cost.RepairType = quote.RepairType
+		cost.TotalPrice = quote.TotalPrice
+		cost.Anonymous = true
+	case errors.Is(err, mongo.ErrNoDocuments):
+		if cost.Anonymous {
+			err := fmt.Errorf("%w: anonymous quote %s not found or expired", domain.ErrInvalidInput, cost.ID)
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			s.logger.Error("Anonymous quote not found", "costID", cost.ID, "app", "repair-service")
+			return nil, err
+		}
+	default:
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to check anonymous quote")
+		s.logger.Error("Failed to check anonymous quote", "error", err, "costID", cost.ID, "app", "repair-service")
+		return nil, fmt.Errorf("failed to check anonymous quote: %w", err)
+	}
+
+	// Fleet members are held to their fleet's controls at the final price
+	if err := s.checkFleetControls(ctx, cost.UserID, cost.RepairType, cost.TotalPrice); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Warn("Refused repair by fleet controls", "error", err, "userID", cost.UserID, "app", "repair-service")
+		return nil, err
+	}
+
+	// The cost comes from the client, so drop blocked mechanics again here
+	blocks, err := s.userBlocks(ctx, cost.UserID)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to check blocks")
+		s.logger.Error("Failed to check blocks", "error", err, "userID", cost.UserID, "app", "repair-service")
+		return nil, err
+	}
+	// Mechanics may have started an absence since the estimate
+	absent, err := s.repo.AbsentMechanicIDs(ctx, time.Now())
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to check absences")
+		s.logger.Error("Failed to check absences", "error", err, "app", "repair-service")
+		return nil, fmt.Errorf("failed to check absences: %w", err)
+	}
+	allowed := cost.Mechanics[:0]
+	for _, m := range cost.Mechanics {
+		if !blocks.Mechanics[m.ID] && !absent[m.ID] {
+			allowed = append(allowed, m)
+		}
+	}
+	cost.Mechanics = allowed
+
+	// Pin the repair to the preferred mechanic if they are still among the
+	// offered ones; the hold lets others claim it should they not
+	if preference := cost.Preference; preference != nil {
+		err := preference.Validate(s.preference.fallback)
+		if err == nil {
+			reason := ""
+			switch {
+			case blocks.Mechanics[preference.MechanicID]:
+				reason = domain.PreferenceBlocked
+			case absent[preference.MechanicID]:
+				reason = domain.PreferenceAbsent
+			}
+			cost.Mechanics, err = pinPreferred(preference, cost.Mechanics, reason)
+		}
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			s.logger.Info("Refused repair for unavailable preferred mechanic", "error", err, "mechanicID", preference.MechanicID, "userID", cost.UserID, "app", "repair-service")
+			return nil, err
+		}
+		if preference.Pinned && preference.Fallback == domain.PreferenceFallbackNearby {
+			holdUntil := time.Now().UTC().Add(s.preference.hold)
+			preference.HoldUntil = &holdUntil
+		}
+		span.SetAttributes(attribute.String("preferredMechanicID", preference.MechanicID), attribute.Bool("preferencePinned", preference.Pinned))
+	}
+
+	symptoms, err := s.resolveSymptoms(ctx, cost.RepairType, answers)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Invalid intake answers")
+		s.logger.Error("Invalid intake answers", "error", err, "repairType", cost.RepairType, "app", "repair-service")
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("symptomCount", len(symptoms)))
+
 	repair := &domain.RepairModel{
 		ID:         primitive.NewObjectID().Hex(),
 		UserID:     cost.UserID,
 		Status:     "pending",
 		RepairCost: cost,
+		Symptoms:   symptoms,
+		CreatedAt:  time.Now(),
 	}
-	span.SetAttributes(attribute.String("repairID", repair.ID))
+	repair.Region = repairRegion(repair)
+	span.SetAttributes(
+		attribute.String("repairID", repair.ID),
+		attribute.String("region", repair.Region),
+	)
 
 	// Convert domain.RepairModel to kafka.RepairEvent
 	event := &kafka.RepairEvent{
-		ID:         repair.ID,
-		UserID:     repair.UserID,
-		Status:     repair.Status,
-		RepairType: repair.RepairCost.RepairType,
-		TotalPrice: repair.RepairCost.TotalPrice,
+		ID:              repair.ID,
+		UserID:          repair.UserID,
+		Status:          repair.Status,
+		RepairType:      repair.RepairCost.RepairType,
+		TotalPrice:      repair.RepairCost.TotalPrice.Major(),
+		TotalPriceMinor: repair.RepairCost.TotalPrice.Minor(),
+		Region:          repair.Region,
 	}
 	if repair.RepairCost.UserLocation != nil {
 		event.UserLocation = &kafka.Location{
@@ -130,21 +608,21 @@ func (s *service) CreateRepair(ctx context.Context, cost *domain.RepairCostModel
 			Distance: m.Distance,
 		})
 	}
+	for _, sym := range repair.Symptoms {
+		event.Symptoms = append(event.Symptoms, kafka.Symptom{
+			QuestionID: sym.QuestionID,
+			Question:   sym.Question,
+			Answer:     sym.Answer,
+		})
+	}
 
 	// Serialize to Avro
-	schemaBytes, err := os.ReadFile("repair_event.avsc")
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to read schema file")
-		s.logger.Error("Failed to read schema file", "error", err, "app", "repair-service")
-		return nil, fmt.Errorf("failed to read schema file: %w", err)
-	}
-	schema, err := avro.Parse(string(schemaBytes))
+	schema, err := kafka.RepairEventSchema()
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to parse schema")
 		s.logger.Error("Failed to parse schema", "error", err, "app", "repair-service")
-		return nil, fmt.Errorf("failed to parse schema: %w", err)
+		return nil, err
 	}
 	payload, err := avro.Marshal(schema, event)
 	if err != nil {
@@ -157,79 +635,68 @@ func (s *service) CreateRepair(ctx context.Context, cost *domain.RepairCostModel
 	// Add Schema Registry wire format: magic byte (0) + 4-byte schema ID
 	encodedPayload := make([]byte, 5+len(payload))
 	encodedPayload[0] = 0 // Magic byte
-	binary.BigEndian.PutUint32(encodedPayload[1:5], uint32(s.KafkaProducer.SchemaID))
+	binary.BigEndian.PutUint32(encodedPayload[1:5], uint32(s.Publisher.SchemaID()))
 	copy(encodedPayload[5:], payload)
 
-	// Save repair cost, repair, and outbox event in a transaction
-	session, err := s.repo.GetMongoClient(ctx).StartSession()
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to start MongoDB session")
-		s.logger.Error("Failed to start MongoDB session", "error", err, "app", "repair-service")
-		return nil, fmt.Errorf("failed to start MongoDB session: %w", err)
-	}
-	defer session.EndSession(ctx)
-
-	err = session.StartTransaction()
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to start transaction")
-		s.logger.Error("Failed to start transaction", "error", err, "app", "repair-service")
-		return nil, fmt.Errorf("failed to start transaction: %w", err)
-	}
+	// The booking confirmation is queued with the repair
+	emailDelivery := s.prepareEmail(ctx, domain.EmailTemplateBookingConfirmation, repair, repairEmailVariables(repair))
 
-	err = mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
-		if err := s.repo.SaveRepairCost(sc, cost); err != nil {
+	// Save repair cost, repair, outbox event and email in a transaction
+	err = s.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		if err := s.repo.SaveRepairCost(tx, cost); err != nil {
 			return fmt.Errorf("failed to save repair cost: %w", err)
 		}
 		s.logger.Info("Saved repair cost in transaction", "costID", cost.ID, "app", "repair-service")
 
-		if _, err := s.repo.CreateRepair(sc, repair); err != nil {
+		if _, err := s.repo.CreateRepair(tx, repair); err != nil {
 			return fmt.Errorf("failed to create repair: %w", err)
 		}
 		s.logger.Info("Created repair in transaction", "repairID", repair.ID, "app", "repair-service")
 
 		outboxEvent := &domain.OutboxEvent{
-			ID:        primitive.NewObjectID().Hex(),
-			EventType: "RepairCreated",
-			Payload:   encodedPayload,
-			CreatedAt: time.Now(),
-			Processed: false,
+			ID:          primitive.NewObjectID().Hex(),
+			EventType:   "RepairCreated",
+			AggregateID: repair.ID,
+			Payload:     encodedPayload,
+			CreatedAt:   time.Now(),
+			Processed:   false,
+			Region:      repair.Region,
 		}
-		if err := s.repo.SaveOutboxEvent(ctx, sc, outboxEvent); err != nil {
+		if err := s.repo.SaveOutboxEvent(tx, outboxEvent); err != nil {
 			return fmt.Errorf("failed to save outbox event: %w", err)
 		}
 		s.logger.Info("Saved outbox event in transaction", "eventID", outboxEvent.ID, "app", "repair-service")
 
+		if emailDelivery != nil {
+			if err := s.repo.SaveEmailDelivery(tx, emailDelivery); err != nil {
+				return fmt.Errorf("failed to queue email: %w", err)
+			}
+		}
+
 		return nil
 	})
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Transaction failed")
 		s.logger.Error("Transaction failed", "error", err, "app", "repair-service")
-		session.AbortTransaction(ctx)
 		return nil, err
 	}
 
-	if err := session.CommitTransaction(ctx); err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to commit transaction")
-		s.logger.Error("Failed to commit transaction", "error", err, "app", "repair-service")
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
 	s.logger.Info("Committed transaction for repair creation", "repairID", repair.ID, "app", "repair-service")
 	return repair, nil
 }
 
 // EstimateRepairCost generates an estimated cost and mechanic distances
-func (s *service) EstimateRepairCost(ctx context.Context, repairType string, userID string, userLocation *domain.Location) (*domain.RepairCostModel, error) {
+// and orders the mechanics by sortBy, see domain.EstimateSorts. With a
+// preference naming a qualified mechanic in range, only they are offered.
+func (s *service) EstimateRepairCost(ctx context.Context, repairType string, userID string, userLocation *domain.Location, sortBy string, preference *domain.MechanicPreference) (*domain.RepairCostModel, error) {
 	ctx, span := s.tracer.Start(ctx, "ServiceEstimateRepairCost")
 	defer span.End()
 
 	// Validate input
-	if repairType == "" || userID == "" || userLocation == nil {
-		err := errors.New("repair type, user ID, and location are required")
+	// userID is optional: prospective users without an account get an anonymous quote
+	if repairType == "" || userLocation == nil {
+		err := errors.New("repair type and location are required")
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		s.logger.Error("Invalid input for estimate", "error", err, "app", "repair-service")
@@ -241,114 +708,96 @@ func (s *service) EstimateRepairCost(ctx context.Context, repairType string, use
 		attribute.Float64("location.longitude", userLocation.Longitude),
 		attribute.Float64("location.latitude", userLocation.Latitude),
 	)
-
-	// Simple cost estimation logic based on repair type
-	totalPrice := 0.0
-	switch repairType {
-	case "flat_tire":
-		totalPrice = 50.0
-	case "brake_repair":
-		totalPrice = 150.0
-	case "chain_replacement":
-		totalPrice = 80.0
-	default:
-		err := errors.New("unknown repair type")
+	sortBy, err := s.estimateSort(sortBy)
+	if err == nil && preference != nil {
+		err = preference.Validate(s.preference.fallback)
+	}
+	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
-		s.logger.Error("Unknown repair type", "repairType", repairType, "app", "repair-service")
 		return nil, err
 	}
-	span.SetAttributes(attribute.Float64("totalPrice", totalPrice))
-	s.logger.Info("Estimated total price", "repairType", repairType, "totalPrice", totalPrice, "app", "repair-service")
+	span.SetAttributes(attribute.String("sort", sortBy))
 
-	// Get all mechanics
-	mechanics, err := s.repo.GetAllMechanics(ctx)
+	// Price the repair with the pricing rules
+	pricing, err := s.priceRepair(ctx, repairType, userLocation)
 	if err != nil {
 		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to get mechanics")
-		s.logger.Error("Failed to get mechanics", "error", err, "app", "repair-service")
-		return nil, fmt.Errorf("failed to get mechanics: %v", err)
-	}
-	span.SetAttributes(attribute.Int("mechanicCount", len(mechanics)))
-	s.logger.Info("Retrieved mechanics", "count", len(mechanics), "app", "repair-service")
-
-	// Prepare coordinates for OSRM table request
-	coordinates := []string{
-		fmt.Sprintf("%f,%f", userLocation.Longitude, userLocation.Latitude),
+		span.SetStatus(codes.Error, err.Error())
+		s.logger.Error("Failed to price repair", "error", err, "repairType", repairType, "app", "repair-service")
+		return nil, err
 	}
-	for _, mechanic := range mechanics {
-		coordinates = append(coordinates, fmt.Sprintf("%f,%f", mechanic.Location.Longitude, mechanic.Location.Latitude))
+	totalPrice := pricing.Price
+	span.SetAttributes(attribute.Int64("totalPriceMinor", totalPrice.Minor()))
+	s.logger.Info("Estimated total price", "repairType", repairType, "totalPrice", totalPrice.String(), "basePrice", pricing.BasePrice.String(), "app", "repair-service")
+
+	// Never offer mechanics the user blocked; blacklisted users get no estimate
+	blocks := domain.NewBlockList(nil)
+	if userID != "" {
+		blocks, err = s.userBlocks(ctx, userID)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to check blocks")
+			s.logger.Error("Failed to check blocks", "error", err, "userID", userID, "app", "repair-service")
+			return nil, err
+		}
+		if err := s.checkFleetControls(ctx, userID, repairType, totalPrice); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			s.logger.Warn("Refused estimate by fleet controls", "error", err, "userID", userID, "app", "repair-service")
+			return nil, err
+		}
 	}
 
-	// Call OSRM table service
-	osrmURL := fmt.Sprintf("http://router.project-osrm.org/table/v1/driving/%s?sources=0", strings.Join(coordinates, ";"))
-	req, err := http.NewRequestWithContext(ctx, "GET", osrmURL, nil)
+	// Mechanics not on leave and their travel durations, shared with
+	// concurrent estimates nearby
+	candidates, err := s.candidateMechanics(ctx, repairType, userLocation)
 	if err != nil {
 		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to create OSRM request")
-		s.logger.Error("Failed to create OSRM request", "error", err, "app", "repair-service")
-		return nil, fmt.Errorf("failed to create OSRM request: %v", err)
-	}
-	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
-	_, osrmSpan := s.tracer.Start(ctx, "OSRMTableRequest")
-	resp, err := s.httpClient.Do(req)
-	osrmSpan.End()
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to call OSRM table service")
-		s.logger.Error("Failed to call OSRM table service", "error", err, "url", osrmURL, "app", "repair-service")
-		return nil, fmt.Errorf("failed to call OSRM table service: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		err := fmt.Errorf("OSRM table service returned status %d", resp.StatusCode)
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
-		s.logger.Error("OSRM table service error", "status_code", resp.StatusCode, "url", osrmURL, "app", "repair-service")
-		return nil, err
-	}
-
-	var osrmResp struct {
-		Code      string      `json:"code"`
-		Durations [][]float64 `json:"durations"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&osrmResp); err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to decode OSRM response")
-		s.logger.Error("Failed to decode OSRM response", "error", err, "app", "repair-service")
-		return nil, fmt.Errorf("failed to decode OSRM response: %v", err)
-	}
-	if osrmResp.Code != "Ok" {
-		err := fmt.Errorf("OSRM table service returned code: %s", osrmResp.Code)
-		span.RecordError(err)
-		span.SetStatus(codes.Error, err.Error())
-		s.logger.Error("OSRM table service returned non-OK code", "code", osrmResp.Code, "app", "repair-service")
+		span.SetStatus(codes.Error, "Failed to find mechanics")
 		return nil, err
 	}
+	span.SetAttributes(attribute.String("routingProvider", candidates.provider))
 
 	// Create mechanic info with distances (convert duration in seconds to distance in meters, assuming average speed of 50 km/h)
+	var mechanics []*domain.MechanicModel
 	var mechanicInfos []domain.MechanicInfo
-	for i, mechanic := range mechanics {
-		if i+1 >= len(osrmResp.Durations[0]) {
-			s.logger.Warn("Skipping mechanic due to missing duration data", "mechanicID", mechanic.ID, "app", "repair-service")
+	for i, mechanic := range candidates.mechanics {
+		if blocks.Mechanics[mechanic.ID] {
 			continue
 		}
-		duration := osrmResp.Durations[0][i+1]
-		distance := duration * (50000.0 / 3600.0)
+		mechanics = append(mechanics, mechanic)
+		distance := candidates.durations[i] * (50000.0 / 3600.0)
 		mechanicInfos = append(mechanicInfos, domain.MechanicInfo{
-			ID:       mechanic.ID,
-			Name:     mechanic.Name,
-			Location: mechanic.Location,
-			Distance: distance,
+			ID:             mechanic.ID,
+			Name:           mechanic.Name,
+			Location:       mechanic.Location,
+			Distance:       distance,
+			Rating:         mechanic.Rating,
+			AcceptanceRate: mechanic.AcceptanceRate,
 		})
 	}
-	s.logger.Info("Calculated distances for mechanics", "count", len(mechanicInfos), "app", "repair-service")
+	span.SetAttributes(attribute.Int("mechanicCount", len(mechanics)))
+	s.logger.Info("Calculated distances for mechanics", "count", len(mechanicInfos), "provider", candidates.provider, "app", "repair-service")
 
-	// Sort mechanics by distance
+	// Sort mechanics by distance, then rotate nearby ones by the fairness policy
 	sort.Slice(mechanicInfos, func(i, j int) bool {
 		return mechanicInfos[i].Distance < mechanicInfos[j].Distance
 	})
+	mechanicInfos = s.rankMechanics(ctx, mechanicInfos)
+	ranking := s.scoreMechanics(ctx, mechanicInfos, sortBy)
+
+	// Pin the estimate to the user's preferred mechanic when they can take it
+	if preference != nil {
+		reason := s.preferredMechanic(preference, repairType, mechanics, mechanicInfos, blocks)
+		if mechanicInfos, err = pinPreferred(preference, mechanicInfos, reason); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			s.logger.Info("Preferred mechanic unavailable", "mechanicID", preference.MechanicID, "reason", preference.Reason, "app", "repair-service")
+			return nil, err
+		}
+		span.SetAttributes(attribute.String("preferredMechanicID", preference.MechanicID), attribute.Bool("preferencePinned", preference.Pinned))
+	}
 
 	// Create repair cost model
 	cost := &domain.RepairCostModel{
@@ -358,8 +807,30 @@ func (s *service) EstimateRepairCost(ctx context.Context, repairType string, use
 		TotalPrice:   totalPrice,
 		UserLocation: userLocation,
 		Mechanics:    mechanicInfos,
+		Availability: s.summarizeAvailability(ctx, repairType, mechanics, mechanicInfos),
+		Anonymous:    userID == "",
+		Accuracy:     routingAccuracy(candidates.provider),
+		Pricing:      pricing,
+		Ranking:      ranking,
+		Preference:   preference,
+	}
+	s.estimateDurations(ctx, cost)
+	span.SetAttributes(
+		attribute.String("costID", cost.ID),
+		attribute.String("waitBucket", cost.Availability.WaitBucket),
+		attribute.Bool("anonymous", cost.Anonymous),
+		attribute.String("accuracy", cost.Accuracy),
+	)
+
+	// Keep anonymous quotes so they can be claimed and converted later
+	if cost.Anonymous {
+		if err := s.repo.SaveAnonymousQuote(ctx, &domain.AnonymousQuote{RepairCostModel: *cost, CreatedAt: time.Now()}); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to save anonymous quote")
+			s.logger.Error("Failed to save anonymous quote", "error", err, "costID", cost.ID, "app", "repair-service")
+			return nil, fmt.Errorf("failed to save anonymous quote: %w", err)
+		}
 	}
-	span.SetAttributes(attribute.String("costID", cost.ID))
 	s.logger.Info("Created repair cost model", "costID", cost.ID, "app", "repair-service")
 
 	return cost, nil
@@ -433,40 +904,78 @@ func (s *service) GetRepairByID(ctx context.Context, id string) (*domain.RepairM
 	return repair, nil
 }
 
-// GetAllRepairs retrieves all repairs
-func (s *service) GetAllRepairs(ctx context.Context) ([]*domain.RepairModel, error) {
-	_, span := s.tracer.Start(ctx, "ServiceGetAllRepairs")
+// GetAllRepairs returns a page of the repairs matching filter, e.g. a user's
+// repairs of some types and statuses, or those whose user location lies in a
+// bounding box. Pages hold opts.Limit repairs (DefaultSearchLimit when unset,
+// at most MaxSearchLimit) from opts.Skip, in ID order unless opts sorts them.
+func (s *service) GetAllRepairs(ctx context.Context, filter domain.RepairFilter, opts *domain.QueryOptions) ([]*domain.RepairModel, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceGetAllRepairs")
 	defer span.End()
+	span.SetAttributes(
+		attribute.StringSlice("statuses", filter.Statuses),
+		attribute.StringSlice("repairTypes", filter.RepairTypes),
+		attribute.Bool("filter.userID", filter.UserID != ""),
+		attribute.Bool("filter.bbox", filter.BoundingBox != nil),
+	)
 
-	// Retrieve all repairs
-	repairs, err := s.repo.GetAllRepairs(ctx)
+	if opts == nil {
+		opts = &domain.QueryOptions{}
+	}
+	if opts.Limit == 0 {
+		opts.Limit = domain.DefaultSearchLimit
+	}
+	if len(opts.Sort) == 0 {
+		opts.Sort = []domain.SortField{{Field: "_id"}}
+	}
+	err := opts.Validate()
+	if err == nil && opts.Limit > domain.MaxSearchLimit {
+		err = fmt.Errorf("%w: limit must not exceed %d", domain.ErrInvalidInput, domain.MaxSearchLimit)
+	}
+	if box := filter.BoundingBox; err == nil && box != nil && (box.MinLongitude > box.MaxLongitude || box.MinLatitude > box.MaxLatitude) {
+		err = fmt.Errorf("%w: bounding box minimums must not exceed maximums", domain.ErrInvalidInput)
+	}
+	for i, tag := range filter.Tags {
+		if err != nil {
+			break
+		}
+		filter.Tags[i], err = normalizeTag(tag)
+	}
 	if err != nil {
 		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to find repairs")
-		s.logger.Error("Failed to find repairs", "error", err, "app", "repair-service")
-		return nil, fmt.Errorf("failed to find repairs: %v", err)
+		span.SetStatus(codes.Error, err.Error())
+		return nil, err
 	}
-	s.logger.Info("Retrieved all repairs", "count", len(repairs), "app", "repair-service")
-
-	span.SetAttributes(
-		attribute.Int("repairCount", len(repairs)),
-	)
 
+	repairs, err := s.repo.FindRepairs(ctx, filter, opts)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to find repairs")
+		s.logger.Error("Failed to find repairs", "error", err, "statuses", filter.Statuses, "repairTypes", filter.RepairTypes, "app", "repair-service")
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("repairCount", len(repairs)), attribute.Int64("skip", opts.Skip), attribute.Int64("limit", opts.Limit))
 	return repairs, nil
 }
 
-// UpdateRepair updates the status of a repair
-func (s *service) UpdateRepair(ctx context.Context, repairID string, status string) error {
-	_, span := s.tracer.Start(ctx, "ServiceUpdateRepair")
+// UpdateRepair updates the status of a repair and returns the updated repair.
+// Completing a repair issues its receipt with the optional payment reference.
+func (s *service) UpdateRepair(ctx context.Context, repairID string, status string, paymentReference string, finalAmount *domain.Money) (*domain.RepairModel, error) {
+	return s.updateRepair(ctx, repairID, status, paymentReference, finalAmount, nil)
+}
+
+// updateRepair changes a repair's status and, in the same transaction, the
+// other fields of patch when it is not nil
+func (s *service) updateRepair(ctx context.Context, repairID string, status string, paymentReference string, finalAmount *domain.Money, patch *domain.RepairPatch) (*domain.RepairModel, error) {
+	ctx, span := s.tracer.Start(ctx, "ServiceUpdateRepair")
 	defer span.End()
 
 	// Validate input
 	if repairID == "" || status == "" {
-		err := errors.New("repair ID and status are required")
+		err := fmt.Errorf("%w: repair ID and status are required", domain.ErrInvalidInput)
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		s.logger.Error("Invalid input for update repair", "error", err, "app", "repair-service")
-		return err
+		return nil, err
 	}
 	span.SetAttributes(
 		attribute.String("repairID", repairID),
@@ -481,11 +990,11 @@ func (s *service) UpdateRepair(ctx context.Context, repairID string, status stri
 		"cancelled":   true,
 	}
 	if !validStatuses[status] {
-		err := errors.New("invalid status")
+		err := fmt.Errorf("%w: invalid status %q", domain.ErrInvalidInput, status)
 		span.RecordError(err)
 		span.SetStatus(codes.Error, err.Error())
 		s.logger.Error("Invalid status", "status", status, "app", "repair-service")
-		return err
+		return nil, err
 	}
 
 	// Retrieve the repair to prepare the event
@@ -494,111 +1003,163 @@ func (s *service) UpdateRepair(ctx context.Context, repairID string, status stri
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Failed to get repair for event")
 		s.logger.Error("Failed to get repair for event", "error", err, "app", "repair-service")
-		return err
+		return nil, err
 	}
 
-	// Update repair status and save outbox event in a transaction
-	session, err := s.repo.GetMongoClient(ctx).StartSession()
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to start MongoDB session")
-		s.logger.Error("Failed to start MongoDB session", "error", err, "app", "repair-service")
-		return fmt.Errorf("failed to start MongoDB session: %w", err)
+	// Freeze the receipt now; it is stored with the status change, and time
+	// the repair for the duration estimates
+	var receipt *domain.Receipt
+	var durationSample *domain.RepairDurationSample
+	if status == "completed" {
+		amendments, err := s.completionAmendments(ctx, repair, finalAmount)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			s.logger.Error("Repair cannot be completed", "error", err, "repairID", repairID, "app", "repair-service")
+			return nil, err
+		}
+		completedAt := time.Now()
+		if repair.Status == "in_progress" {
+			durationSample = s.durationSample(repair, completedAt)
+		}
+		receipt, err = s.buildReceipt(ctx, repair, amendments, &completedAt, paymentReference)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, "Failed to build receipt")
+			s.logger.Error("Failed to build receipt", "error", err, "repairID", repairID, "app", "repair-service")
+			return nil, err
+		}
 	}
-	defer session.EndSession(ctx)
 
-	err = session.StartTransaction()
-	if err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to start transaction")
-		s.logger.Error("Failed to start transaction", "error", err, "app", "repair-service")
-		return fmt.Errorf("failed to start transaction: %w", err)
+	// Completing or cancelling a repair emails its user, once per transition
+	var emailDelivery *domain.EmailDelivery
+	if repair.Status != status {
+		switch status {
+		case "completed":
+			emailDelivery = s.prepareEmail(ctx, domain.EmailTemplateReceipt, repair, receiptEmailVariables(repair, receipt))
+		case "cancelled":
+			emailDelivery = s.prepareEmail(ctx, domain.EmailTemplateCancellation, repair, repairEmailVariables(repair))
+		}
 	}
 
-	err = mongo.WithSession(ctx, session, func(sc mongo.SessionContext) error {
-		if err := s.repo.UpdateRepair(sc, repairID, status); err != nil {
+	// Update repair status and save outbox event and email in a transaction
+	err = s.repo.RunInTransaction(ctx, func(tx context.Context) error {
+		if err := s.repo.UpdateRepair(tx, repairID, status); err != nil {
 			return fmt.Errorf("failed to update repair: %w", err)
 		}
 		s.logger.Info("Updated repair in transaction", "repairID", repairID, "status", status, "app", "repair-service")
-
-		// Update repair object for event
-		repair.Status = status
-
-		// Convert domain.RepairModel to kafka.RepairEvent
-		event := &kafka.RepairEvent{
-			ID:         repair.ID,
-			UserID:     repair.UserID,
-			Status:     repair.Status,
-			RepairType: repair.RepairCost.RepairType,
-			TotalPrice: repair.RepairCost.TotalPrice,
-		}
-		if repair.RepairCost.UserLocation != nil {
-			event.UserLocation = &kafka.Location{
-				Longitude: repair.RepairCost.UserLocation.Longitude,
-				Latitude:  repair.RepairCost.UserLocation.Latitude,
+		if patch != nil {
+			if err := s.repo.PatchRepair(tx, repairID, patch); err != nil {
+				return err
 			}
+			patch.ApplyTo(repair)
 		}
-		for _, m := range repair.RepairCost.Mechanics {
-			event.Mechanics = append(event.Mechanics, kafka.MechanicInfo{
-				ID:   m.ID,
-				Name: m.Name,
-				Location: kafka.Location{
-					Longitude: m.Location.Longitude,
-					Latitude:  m.Location.Latitude,
-				},
-				Distance: m.Distance,
-			})
-		}
-
-		// Serialize to Avro
-		schemaBytes, err := os.ReadFile("repair_event.avsc")
-		if err != nil {
-			return fmt.Errorf("failed to read schema file: %w", err)
-		}
-		schema, err := avro.Parse(string(schemaBytes))
-		if err != nil {
-			return fmt.Errorf("failed to parse schema: %w", err)
+		if receipt != nil {
+			if _, err := s.repo.SaveReceipt(tx, receipt); err != nil {
+				return err
+			}
+			s.logger.Info("Issued receipt in transaction", "repairID", repairID, "total", receipt.Total.String(), "app", "repair-service")
 		}
-		payload, err := avro.Marshal(schema, event)
-		if err != nil {
-			return fmt.Errorf("failed to serialize event: %w", err)
+		if durationSample != nil {
+			if err := s.repo.SaveDurationSample(tx, durationSample); err != nil {
+				return err
+			}
 		}
 
-		// Add Schema Registry wire format: magic byte (0) + 4-byte schema ID
-		encodedPayload := make([]byte, 5+len(payload))
-		encodedPayload[0] = 0 // Magic byte
-		binary.BigEndian.PutUint32(encodedPayload[1:5], uint32(s.KafkaProducer.SchemaID))
-		copy(encodedPayload[5:], payload)
+		// Update repair object for event
+		repair.Status = status
 
-		outboxEvent := &domain.OutboxEvent{
-			ID:        primitive.NewObjectID().Hex(),
-			EventType: "RepairUpdated",
-			Payload:   encodedPayload,
-			CreatedAt: time.Now(),
-			Processed: false,
+		outboxEvent, err := s.repairUpdatedEvent(repair)
+		if err != nil {
+			return err
 		}
-		if err := s.repo.SaveOutboxEvent(ctx, sc, outboxEvent); err != nil {
+		if err := s.repo.SaveOutboxEvent(tx, outboxEvent); err != nil {
 			return fmt.Errorf("failed to save outbox event: %w", err)
 		}
 		s.logger.Info("Saved outbox event in transaction", "eventID", outboxEvent.ID, "app", "repair-service")
 
+		if emailDelivery != nil {
+			if err := s.repo.SaveEmailDelivery(tx, emailDelivery); err != nil {
+				return fmt.Errorf("failed to queue email: %w", err)
+			}
+		}
+
 		return nil
 	})
 	if err != nil {
 		span.RecordError(err)
 		span.SetStatus(codes.Error, "Transaction failed")
 		s.logger.Error("Transaction failed", "error", err, "app", "repair-service")
-		session.AbortTransaction(ctx)
-		return err
+		return nil, err
 	}
 
-	if err := session.CommitTransaction(ctx); err != nil {
-		span.RecordError(err)
-		span.SetStatus(codes.Error, "Failed to commit transaction")
-		s.logger.Error("Failed to commit transaction", "error", err, "app", "repair-service")
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	s.logger.Info("Committed transaction for repair update", "repairID", repairID, "status", status, "app", "repair-service")
+	if durationSample != nil {
+		s.learnDuration(ctx, durationSample)
 	}
+	return repair, nil
+}
 
-	s.logger.Info("Committed transaction for repair update", "repairID", repairID, "status", status, "app", "repair-service")
-	return nil
+// repairUpdatedEvent builds the RepairUpdated outbox event carrying repair's
+// current state
+func (s *service) repairUpdatedEvent(repair *domain.RepairModel) (*domain.OutboxEvent, error) {
+	event := &kafka.RepairEvent{
+		ID:              repair.ID,
+		UserID:          repair.UserID,
+		Status:          repair.Status,
+		RepairType:      repair.RepairCost.RepairType,
+		TotalPrice:      repair.RepairCost.TotalPrice.Major(),
+		TotalPriceMinor: repair.RepairCost.TotalPrice.Minor(),
+		Region:          repairRegion(repair),
+	}
+	if repair.RepairCost.UserLocation != nil {
+		event.UserLocation = &kafka.Location{
+			Longitude: repair.RepairCost.UserLocation.Longitude,
+			Latitude:  repair.RepairCost.UserLocation.Latitude,
+		}
+	}
+	for _, m := range repair.RepairCost.Mechanics {
+		event.Mechanics = append(event.Mechanics, kafka.MechanicInfo{
+			ID:   m.ID,
+			Name: m.Name,
+			Location: kafka.Location{
+				Longitude: m.Location.Longitude,
+				Latitude:  m.Location.Latitude,
+			},
+			Distance: m.Distance,
+		})
+	}
+	for _, sym := range repair.Symptoms {
+		event.Symptoms = append(event.Symptoms, kafka.Symptom{
+			QuestionID: sym.QuestionID,
+			Question:   sym.Question,
+			Answer:     sym.Answer,
+		})
+	}
+
+	// Serialize to Avro
+	schema, err := kafka.RepairEventSchema()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := avro.Marshal(schema, event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize event: %w", err)
+	}
+
+	// Add Schema Registry wire format: magic byte (0) + 4-byte schema ID
+	encodedPayload := make([]byte, 5+len(payload))
+	encodedPayload[0] = 0 // Magic byte
+	binary.BigEndian.PutUint32(encodedPayload[1:5], uint32(s.Publisher.SchemaID()))
+	copy(encodedPayload[5:], payload)
+
+	return &domain.OutboxEvent{
+		ID:          primitive.NewObjectID().Hex(),
+		EventType:   "RepairUpdated",
+		AggregateID: repair.ID,
+		Payload:     encodedPayload,
+		CreatedAt:   time.Now(),
+		Processed:   false,
+		Region:      event.Region,
+	}, nil
 }
@@ -0,0 +1,76 @@
+// Package sms sends text messages through a pluggable provider
+package sms
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+// Built-in provider names, selected with SMS_PROVIDER
+const (
+	ProviderLog     = "log"
+	ProviderWebhook = "webhook"
+	ProviderTwilio  = "twilio"
+)
+
+// Provider delivers a text message to a phone number in E.164 format
+type Provider interface {
+	Name() string
+	Send(ctx context.Context, phone, message string) error
+}
+
+// NewProviderFromEnv builds the provider named by SMS_PROVIDER from its SMS_*
+// environment variables. Without a provider, or when its settings are
+// missing, messages are only logged.
+func NewProviderFromEnv(httpClient *http.Client, logger *slog.Logger) Provider {
+	var provider Provider
+	switch name := os.Getenv("SMS_PROVIDER"); name {
+	case ProviderWebhook:
+		if u := os.Getenv("SMS_WEBHOOK_URL"); u != "" {
+			provider = NewWebhookProvider(u, os.Getenv("SMS_WEBHOOK_TOKEN"), httpClient)
+		}
+	case ProviderTwilio:
+		sid, token, from := os.Getenv("SMS_TWILIO_ACCOUNT_SID"), os.Getenv("SMS_TWILIO_AUTH_TOKEN"), os.Getenv("SMS_TWILIO_FROM")
+		if sid != "" && token != "" && from != "" {
+			provider = NewTwilioProvider(sid, token, from, httpClient)
+		}
+	case "", ProviderLog:
+	default:
+		logger.Warn("Unknown SMS provider, logging messages instead", "provider", name, "app", "repair-service")
+	}
+	if provider == nil {
+		provider = NewLogProvider(logger)
+	}
+	logger.Info("Configured SMS provider", "provider", provider.Name(), "app", "repair-service")
+	return provider
+}
+
+// LogProvider writes messages to the service log instead of sending them. It
+// is meant for development, where codes are read from the logs.
+type LogProvider struct {
+	logger *slog.Logger
+}
+
+// NewLogProvider creates a LogProvider
+func NewLogProvider(logger *slog.Logger) *LogProvider {
+	return &LogProvider{logger: logger}
+}
+
+// Name returns the provider name
+func (p *LogProvider) Name() string {
+	return ProviderLog
+}
+
+// Send logs the message
+func (p *LogProvider) Send(ctx context.Context, phone, message string) error {
+	p.logger.Info("SMS not sent, log provider", "phone", phone, "message", message, "app", "repair-service")
+	return nil
+}
+
+// statusError reports a non-2xx response from a provider
+func statusError(provider string, resp *http.Response) error {
+	return fmt.Errorf("%s SMS provider returned status %d", provider, resp.StatusCode)
+}
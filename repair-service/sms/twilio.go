@@ -0,0 +1,69 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// TwilioProvider sends messages with the Twilio Messages API
+type TwilioProvider struct {
+	accountSID string
+	authToken  string
+	from       string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewTwilioProvider creates a TwilioProvider sending from the given number
+func NewTwilioProvider(accountSID, authToken, from string, httpClient *http.Client) *TwilioProvider {
+	return &TwilioProvider{
+		accountSID: accountSID,
+		authToken:  authToken,
+		from:       from,
+		baseURL:    "https://api.twilio.com",
+		httpClient: httpClient,
+	}
+}
+
+// Name returns the provider name
+func (p *TwilioProvider) Name() string {
+	return ProviderTwilio
+}
+
+// Send creates a Twilio message
+func (p *TwilioProvider) Send(ctx context.Context, phone, message string) error {
+	ctx, span := otel.Tracer("repair-service").Start(ctx, "SMSTwilioSend")
+	defer span.End()
+
+	form := url.Values{"To": {phone}, "From": {p.from}, "Body": {message}}
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", p.baseURL, url.PathEscape(p.accountSID))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("failed to create SMS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(p.accountSID, p.authToken)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to call Twilio")
+		return fmt.Errorf("failed to call Twilio: %w", err)
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("statusCode", resp.StatusCode))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := statusError(ProviderTwilio, resp)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// WebhookProvider posts messages as JSON {"to","message"} to an HTTP endpoint,
+// for SMS gateways without a dedicated provider
+type WebhookProvider struct {
+	url        string
+	token      string
+	httpClient *http.Client
+}
+
+// NewWebhookProvider creates a WebhookProvider; a non-empty token is sent as a
+// bearer token
+func NewWebhookProvider(url, token string, httpClient *http.Client) *WebhookProvider {
+	return &WebhookProvider{url: url, token: token, httpClient: httpClient}
+}
+
+// Name returns the provider name
+func (p *WebhookProvider) Name() string {
+	return ProviderWebhook
+}
+
+// Send posts the message to the webhook
+func (p *WebhookProvider) Send(ctx context.Context, phone, message string) error {
+	ctx, span := otel.Tracer("repair-service").Start(ctx, "SMSWebhookSend")
+	defer span.End()
+
+	body, err := json.Marshal(map[string]string{"to": phone, "message": message})
+	if err != nil {
+		return fmt.Errorf("failed to marshal SMS: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create SMS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "Failed to call SMS webhook")
+		return fmt.Errorf("failed to call SMS webhook: %w", err)
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("statusCode", resp.StatusCode))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := statusError(ProviderWebhook, resp)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
@@ -11,6 +11,11 @@ KAFKA_CONTAINER="roadride_mechanic-kafka-1"
 KSQLDB_CONTAINER="roadride_mechanic-ksqldb-server-1"
 KAFKA_CONNECT_CONTAINER="roadride_mechanic-kafka-connect-1"
 ELASTICSEARCH_URL="http://localhost:9200"
+# Topic prefix of the environment, the services' KAFKA_TOPIC_PREFIX (e.g. "staging.acme.")
+TOPIC_PREFIX="${TOPIC_PREFIX:-}"
+REPAIR_EVENTS_TOPIC="${TOPIC_PREFIX}repair-events"
+# The repair event schema the services embed
+SCHEMA_FILE="${SCHEMA_FILE:-$(dirname "$0")/repair-service/kafka/repair_event.avsc}"
 
 # Function to wait for a service to be healthy
 wait_for_service() {
@@ -41,15 +46,15 @@ wait_for_service "Kafka Connect" "$KAFKA_CONNECT_URL/connectors" 30 5
 wait_for_service "Elasticsearch" "$ELASTICSEARCH_URL/_cluster/health" 30 5
 
 # Step 1: Register Avro schema
-echo "Registering Avro schema for repair-events..."
-SCHEMA=$(jq -c -r 'tojson | tojson' repair_event.avsc)
+echo "Registering Avro schema for $REPAIR_EVENTS_TOPIC..."
+SCHEMA=$(jq -c -r 'tojson | tojson' "$SCHEMA_FILE")
 curl -X POST -H "Content-Type: application/vnd.schemaregistry.v1+json" \
   --data "{\"schema\":$SCHEMA}" \
-  $SCHEMA_REGISTRY_URL/subjects/repair-events-value/versions
+  $SCHEMA_REGISTRY_URL/subjects/$REPAIR_EVENTS_TOPIC-value/versions
 
 # Verify schema registration
 echo "Verifying schema registration..."
-curl -s $SCHEMA_REGISTRY_URL/subjects/repair-events-value/versions/1 | jq .
+curl -s $SCHEMA_REGISTRY_URL/subjects/$REPAIR_EVENTS_TOPIC-value/versions/1 | jq .
 if [ $? -ne 0 ]; then
   echo "Error: Schema registration failed"
   exit 1
@@ -67,7 +72,7 @@ CREATE STREAM REPAIR_EVENTS (
   user_location STRUCT<longitude DOUBLE, latitude DOUBLE>,
   mechanics ARRAY<STRUCT<id STRING, name STRING, location STRUCT<longitude DOUBLE, latitude DOUBLE>, distance DOUBLE>>
 ) WITH (
-  KAFKA_TOPIC='repair-events',
+  KAFKA_TOPIC='$REPAIR_EVENTS_TOPIC',
   VALUE_FORMAT='AVRO',
   PARTITIONS=1
 );
@@ -115,7 +120,7 @@ if [ $? -ne 0 ]; then
 fi
 
 # Step 4: Insert sample data(optional)
-echo "Inserting sample data into repair-events topic..."
+echo "Inserting sample data into $REPAIR_EVENTS_TOPIC topic..."
 cat <<EOF > sample_data.json
 {"id":"event1","user_id":"user123","status":"PENDING","repair_type":"ENGINE","total_price":500.0,"user_location":{"longitude":40.7128,"latitude":-74.0060},"mechanics":[{"id":"mech1","name":"John Doe","location":{"longitude":40.7110,"latitude":-74.0050},"distance":1.2}]}
 {"id":"event2","user_id":"user456","status":"COMPLETED","repair_type":"BRAKES","total_price":300.0,"user_location":{"longitude":34.0522,"latitude":-118.2437},"mechanics":[{"id":"mech2","name":"Jane Smith","location":{"longitude":34.0510,"latitude":-118.2400},"distance":0.8}]}
@@ -126,9 +131,9 @@ EOF
 while IFS= read -r line; do
   echo "$line" | docker exec -i $KAFKA_CONNECT_CONTAINER kafka-avro-console-producer \
     --broker-list kafka:9094 \
-    --topic repair-events \
+    --topic $REPAIR_EVENTS_TOPIC \
     --property schema.registry.url=http://schema-registry:8081 \
-    --property value.schema="$(cat repair_event.avsc)"
+    --property value.schema="$(cat "$SCHEMA_FILE")"
 done < sample_data.json
 
 # Step 5: Verify data in Elasticsearch